@@ -1,11 +1,11 @@
 package main
 
 import (
-	"errors"
+	"fmt"
 	"os"
 
 	"github.com/Azure/gocover/pkg/cmd"
-	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/redact"
 )
 
 var (
@@ -21,12 +21,13 @@ var (
 
 func main() {
 	command := cmd.NewGoCoverCommand(version, commit, date)
-	if err := command.Execute(); err != nil {
-		exitCode := gocover.GeneralErrorExitCode
-		var e *gocover.GoCoverError
-		if errors.As(err, &e) {
-			exitCode = e.ExitCode
-		}
-		os.Exit(exitCode)
+	err := command.Execute()
+	cmd.StopTelemetry(err)
+	if stopErr := cmd.StopProfiling(); stopErr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", stopErr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", redact.String(err.Error()))
+		os.Exit(cmd.ExitCode(err))
 	}
 }
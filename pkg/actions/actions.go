@@ -0,0 +1,186 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxAnnotations is the max annotations of the uncovered sections, GitHub shows up to 10 annotations
+// of each level of a step.
+const DefaultMaxAnnotations = 10
+
+// The levels of the workflow commands.
+const (
+	NoticeLevel = "notice"
+	ErrorLevel  = "error"
+)
+
+// Option contains the input for the GitHub Actions report generator.
+type Option struct {
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root, the annotated files are relative to the root.
+	ModuleDir string
+	// CoverageBaseline decides the level of the annotations, they're errors if the coverage is lower than the baseline.
+	CoverageBaseline float64
+	// MaxAnnotations is the max annotations of the uncovered sections, DefaultMaxAnnotations is used if it's zero.
+	MaxAnnotations int
+	// OutputFile is the file of the step outputs, $GITHUB_OUTPUT, no outputs are written if it's empty.
+	OutputFile string
+	// SummaryFile is the file of the job summary, $GITHUB_STEP_SUMMARY, no summary is written if it's empty.
+	SummaryFile string
+}
+
+// NewReportGenerator creates a report generator that writes the workflow commands to w, the runner reads them
+// from the stdout and the stderr of the step, and appends the outputs and the summary to their files.
+func NewReportGenerator(w io.Writer, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{w: w, option: o, logger: logger.WithField("source", "GitHubActions")}
+}
+
+type reportGenerator struct {
+	w      io.Writer
+	option *Option
+	logger logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	if err := WriteCommands(g.w, statistics, g.option); err != nil {
+		return fmt.Errorf("write workflow commands: %w", err)
+	}
+	if g.option.OutputFile != "" {
+		if err := appendFile(g.option.OutputFile, FormatOutputs(statistics)); err != nil {
+			return fmt.Errorf("write step outputs: %w", err)
+		}
+	}
+	if g.option.SummaryFile != "" {
+		if err := appendFile(g.option.SummaryFile, report.FormatMarkdown(statistics, g.option.CoverageBaseline, 0)); err != nil {
+			return fmt.Errorf("write job summary: %w", err)
+		}
+	}
+	g.logger.Info("write github actions output")
+	return nil
+}
+
+// FormatOutputs returns the step outputs of the statistics in the format of $GITHUB_OUTPUT,
+// such as ${{ steps.gocover.outputs.coverage_percent }}.
+func FormatOutputs(statistics *report.Statistics) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "coverage_percent=%.2f\n", statistics.TotalCoveragePercent)
+	fmt.Fprintf(&b, "violation_count=%d\n", statistics.TotalViolationLines)
+	fmt.Fprintf(&b, "effective_lines=%d\n", statistics.TotalEffectiveLines)
+	fmt.Fprintf(&b, "ignored_lines=%d\n", statistics.TotalIgnoredLines)
+	fmt.Fprintf(&b, "coverage_type=%s\n", statistics.StatisticsType)
+	return b.String()
+}
+
+// WriteCommands writes a workflow command of the coverage and of each range of the uncovered lines, the ranges are errors
+// if the coverage gate fails and it isn't bypassed, otherwise they're notices.
+// The ranges beyond the max annotations are counted in a notice.
+func WriteCommands(w io.Writer, statistics *report.Statistics, o *Option) error {
+	level := NoticeLevel
+	if !statistics.GatePassed(o.CoverageBaseline) {
+		level = ErrorLevel
+	}
+	what := "Diff"
+	if statistics.StatisticsType == report.FullStatisticsType {
+		what = "Full"
+	}
+	message := fmt.Sprintf("%s coverage %.2f%% of %d lines, %d lines not covered (baseline %.2f%%)",
+		what, statistics.TotalCoveragePercent, statistics.TotalEffectiveLines, statistics.TotalViolationLines, o.CoverageBaseline)
+	if _, err := io.WriteString(w, command(level, nil, message)); err != nil {
+		return err
+	}
+
+	maxAnnotations := o.MaxAnnotations
+	if maxAnnotations <= 0 {
+		maxAnnotations = DefaultMaxAnnotations
+	}
+	annotations, omitted := 0, 0
+	for _, profile := range statistics.CoverageProfile {
+		relative := strings.TrimPrefix(strings.TrimPrefix(profile.FileName, o.ModulePath), "/")
+		file := path.Join(filepath.ToSlash(o.ModuleDir), relative)
+		for _, r := range ranges(profile.TotalViolationLines) {
+			if annotations == maxAnnotations {
+				omitted++
+				continue
+			}
+			annotations++
+			properties := [][2]string{{"file", file}, {"line", fmt.Sprint(r[0])}, {"endLine", fmt.Sprint(r[1])}, {"title", "Uncovered lines"}}
+			message := fmt.Sprintf("Lines %d-%d are not covered by tests.", r[0], r[1])
+			if r[0] == r[1] {
+				message = fmt.Sprintf("Line %d is not covered by tests.", r[0])
+			}
+			if _, err := io.WriteString(w, command(level, properties, message)); err != nil {
+				return err
+			}
+		}
+	}
+	if omitted > 0 {
+		_, err := io.WriteString(w, command(NoticeLevel, nil, fmt.Sprintf("%d more ranges of uncovered lines are not annotated, see the job summary", omitted)))
+		return err
+	}
+	return nil
+}
+
+// ranges returns the ranges of the consecutive lines in order.
+func ranges(lines []int) [][2]int {
+	sorted := append([]int(nil), lines...)
+	sort.Ints(sorted)
+	var result [][2]int
+	for _, l := range sorted {
+		if n := len(result); n > 0 && (l == result[n-1][1] || l == result[n-1][1]+1) {
+			result[n-1][1] = l
+			continue
+		}
+		result = append(result, [2]int{l, l})
+	}
+	return result
+}
+
+// command returns the workflow command line, such as ::error file=foo.go,line=3::message.
+// See https://docs.github.com/actions/using-workflows/workflow-commands-for-github-actions.
+func command(level string, properties [][2]string, message string) string {
+	var b strings.Builder
+	b.WriteString("::" + level)
+	for i, p := range properties {
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(p[0] + "=" + escapeProperty(p[1]))
+	}
+	b.WriteString("::" + escapeData(message) + "\n")
+	return b.String()
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// appendFile appends the data to the file, the files of the runner are shared by the steps of the job.
+func appendFile(filename, data string) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
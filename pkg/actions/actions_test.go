@@ -0,0 +1,111 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func testStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		ComparedBranch:       "origin/main",
+		TotalEffectiveLines:  10,
+		TotalViolationLines:  4,
+		TotalCoveragePercent: 60,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/services/foo/foo.go", TotalEffectiveLines: 6, CoveredLines: 3, TotalViolationLines: []int{9, 3, 4}},
+			{FileName: "github.com/Azure/services/bar/bar.go", TotalEffectiveLines: 4, CoveredLines: 3, TotalViolationLines: []int{7}},
+		},
+	}
+}
+
+func TestWriteCommands(t *testing.T) {
+	o := &Option{ModulePath: "github.com/Azure/services", ModuleDir: "services", CoverageBaseline: 80}
+	b := &strings.Builder{}
+	if err := WriteCommands(b, testStatistics(), o); err != nil {
+		t.Fatal(err)
+	}
+	expect := `::error::Diff coverage 60.00%25 of 10 lines, 4 lines not covered (baseline 80.00%25)
+::error file=services/foo/foo.go,line=3,endLine=4,title=Uncovered lines::Lines 3-4 are not covered by tests.
+::error file=services/foo/foo.go,line=9,endLine=9,title=Uncovered lines::Line 9 is not covered by tests.
+::error file=services/bar/bar.go,line=7,endLine=7,title=Uncovered lines::Line 7 is not covered by tests.
+`
+	if b.String() != expect {
+		t.Errorf("expect:\n%s\nbut get:\n%s", expect, b.String())
+	}
+
+	// the ranges are notices if the gate passes, and the ranges beyond the max annotations are counted.
+	b.Reset()
+	o.CoverageBaseline = 50
+	o.MaxAnnotations = 1
+	if err := WriteCommands(b, testStatistics(), o); err != nil {
+		t.Fatal(err)
+	}
+	expect = `::notice::Diff coverage 60.00%25 of 10 lines, 4 lines not covered (baseline 50.00%25)
+::notice file=services/foo/foo.go,line=3,endLine=4,title=Uncovered lines::Lines 3-4 are not covered by tests.
+::notice::2 more ranges of uncovered lines are not annotated, see the job summary
+`
+	if b.String() != expect {
+		t.Errorf("expect:\n%s\nbut get:\n%s", expect, b.String())
+	}
+
+	// the coverage meets the baseline, but the file gate fails.
+	b.Reset()
+	statistics := testStatistics()
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, File: "github.com/Azure/services/foo/foo.go", Actual: 40, Limit: 60, Message: "the coverage of file github.com/Azure/services/foo/foo.go is 40.00, lower than the file baseline 60.00"}}}
+	if err := WriteCommands(b, statistics, o); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "::error::Diff coverage 60.00%25") {
+		t.Errorf("expect the errors of the failed file gate, but get:\n%s", b.String())
+	}
+}
+
+func TestCommand(t *testing.T) {
+	got := command(ErrorLevel, [][2]string{{"file", "a,b:c.go"}, {"title", "100%"}}, "line 1\nline 2")
+	expect := "::error file=a%2Cb%3Ac.go,title=100%25::line 1%0Aline 2\n"
+	if got != expect {
+		t.Errorf("expect %q, but get %q", expect, got)
+	}
+}
+
+func TestReportGenerator(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "output")
+	summaryFile := filepath.Join(dir, "summary")
+	// the files are shared by the steps, the earlier contents are kept.
+	if err := os.WriteFile(outputFile, []byte("previous=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &strings.Builder{}
+	g := NewReportGenerator(b, &Option{ModulePath: "github.com/Azure/services", CoverageBaseline: 80, OutputFile: outputFile, SummaryFile: summaryFile}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "::error file=foo/foo.go,line=3,endLine=4") {
+		t.Errorf("unexpected commands:\n%s", b.String())
+	}
+
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "previous=1\ncoverage_percent=60.00\nviolation_count=4\neffective_lines=10\nignored_lines=0\ncoverage_type=diff\n"
+	if string(output) != expect {
+		t.Errorf("expect outputs:\n%s\nbut get:\n%s", expect, output)
+	}
+	summary, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(summary), "### Diff Coverage") {
+		t.Errorf("unexpected summary:\n%s", summary)
+	}
+}
@@ -0,0 +1,3 @@
+// Package actions writes the coverage of a run as the native output of GitHub Actions: the workflow commands
+// that annotate the uncovered lines, the step outputs for the later steps, and the job summary in markdown.
+package actions
@@ -0,0 +1,180 @@
+package annotation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"golang.org/x/tools/cover"
+)
+
+var ErrUnexpectedResponse = errors.New("unexpected exemptions response")
+
+// Exemption is an ignore decision supplied by an ignore provider rather than an annotation in the file,
+// such as an exemption approved in a review system.
+type Exemption struct {
+	// FileName is the file name in the cover profile, such as github.com/Azure/gocover/pkg/foo/foo.go.
+	FileName string `json:"fileName"`
+	// StartLine and EndLine are the lines of the exemption, the cover profile blocks overlap the lines are ignored.
+	// The whole file is ignored if both are zero.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	// Reason is why the code is exempted, such as the link of the approval, it's the comments of the ignorance.
+	Reason string `json:"reason"`
+	// Source is the system that approves the exemption, it's shown in the annotation of the ignorance.
+	Source string `json:"source,omitempty"`
+}
+
+// annotation returns the pattern of the ignorance shown in the reports and stored into the database.
+func (e *Exemption) annotation() string {
+	if e.Source == "" {
+		return "exemption"
+	}
+	return "exemption from " + e.Source
+}
+
+// IgnoreProvider supplies the ignore decisions from an external system, such as a service listing the approved exemptions,
+// they're applied besides the ignore annotations in the files.
+type IgnoreProvider interface {
+	// Exemptions returns the exemptions of the file in the cover profile, it's empty if the file is not exempted.
+	Exemptions(fileName string) ([]*Exemption, error)
+}
+
+// exemptionList is the json of the exemptions, which is {"exemptions": [{"fileName": "...", "reason": "..."}]}.
+type exemptionList struct {
+	Exemptions []*Exemption `json:"exemptions"`
+}
+
+// NewStaticIgnoreProvider creates the ignore provider of the exemptions.
+func NewStaticIgnoreProvider(exemptions []*Exemption) IgnoreProvider {
+	p := make(staticIgnoreProvider)
+	for _, e := range exemptions {
+		p[e.FileName] = append(p[e.FileName], e)
+	}
+	return p
+}
+
+type staticIgnoreProvider map[string][]*Exemption
+
+var _ IgnoreProvider = (staticIgnoreProvider)(nil)
+
+func (p staticIgnoreProvider) Exemptions(fileName string) ([]*Exemption, error) {
+	return p[fileName], nil
+}
+
+// LoadIgnoreProvider loads the exemptions from the json file or the http(s) url once, the token is sent as the bearer token
+// of the url if it's not nil. The exemptions without reasons are rejected, as the ignore annotations without comments.
+func LoadIgnoreProvider(ctx context.Context, source string, token credential.Provider, httpClient *http.Client) (IgnoreProvider, error) {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		body, err := fetchExemptions(ctx, source, token, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		r = body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	list := &exemptionList{}
+	if err := json.NewDecoder(r).Decode(list); err != nil {
+		return nil, fmt.Errorf("decode exemptions of %s: %w", source, err)
+	}
+	for _, e := range list.Exemptions {
+		if strings.TrimSpace(e.Reason) == "" {
+			return nil, fmt.Errorf("%w for the exemption of %s", ErrCommentsRequired, e.FileName)
+		}
+		if e.StartLine > e.EndLine || e.StartLine < 0 {
+			return nil, fmt.Errorf("%w: lines %d-%d of the exemption of %s", ErrWrongAnnotationFormat, e.StartLine, e.EndLine, e.FileName)
+		}
+	}
+	return NewStaticIgnoreProvider(list.Exemptions), nil
+}
+
+func fetchExemptions(ctx context.Context, url string, token credential.Provider, httpClient *http.Client) (io.ReadCloser, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != nil {
+		t, err := token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get exemptions token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%w: %d %s", ErrUnexpectedResponse, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+// ApplyExemptions adds the exemptions to the ignore profile of the file, the profile is created if it's nil,
+// such as the file without ignore annotations. The file is read for the contents of the ignored blocks.
+func ApplyExemptions(profile *IgnoreProfile, fileName string, coverProfile *cover.Profile, exemptions []*Exemption) (*IgnoreProfile, error) {
+	if len(exemptions) == 0 {
+		return profile, nil
+	}
+	if profile == nil {
+		profile = &IgnoreProfile{Type: BLOCK_IGNORE, Filename: fileName, IgnoreBlocks: make(map[cover.ProfileBlock]*IgnoreBlock)}
+	}
+	if profile.Type == FILE_IGNORE {
+		return profile, nil
+	}
+
+	for _, e := range exemptions {
+		if e.StartLine == 0 && e.EndLine == 0 {
+			profile.Type = FILE_IGNORE
+			profile.Annotation = e.annotation()
+			profile.Comments = e.Reason
+			profile.IgnoreBlocks = nil
+			return profile, nil
+		}
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	fileLines := strings.Split(string(data), "\n")
+	for _, e := range exemptions {
+		for _, b := range coverProfile.Blocks {
+			if b.EndLine < e.StartLine || b.StartLine > e.EndLine {
+				continue
+			}
+			if _, ok := profile.IgnoreBlocks[b]; ok {
+				continue
+			}
+			ignoreBlock := &IgnoreBlock{Annotation: e.annotation(), Comments: e.Reason, AnnotationLineNumber: e.StartLine}
+			for i := b.StartLine; i <= b.EndLine && i <= len(fileLines); i++ {
+				ignoreBlock.Lines = append(ignoreBlock.Lines, i)
+				ignoreBlock.Contents = append(ignoreBlock.Contents, fileLines[i-1])
+			}
+			profile.IgnoreBlocks[b] = ignoreBlock
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,95 @@
+package annotation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+type staticToken string
+
+func (t staticToken) Token(ctx context.Context) (string, error) { return string(t), nil }
+
+func TestLoadIgnoreProvider(t *testing.T) {
+	exemptions := `{"exemptions": [{"fileName": "example.com/foo/foo.go", "startLine": 3, "endLine": 5, "reason": "approved in #42"}]}`
+
+	t.Run("file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "exemptions.json")
+		assert.NoError(t, os.WriteFile(file, []byte(exemptions), 0644))
+
+		provider, err := LoadIgnoreProvider(context.Background(), file, nil, nil)
+		assert.NoError(t, err)
+		result, err := provider.Exemptions("example.com/foo/foo.go")
+		assert.NoError(t, err)
+		if assert.Len(t, result, 1) {
+			assert.Equal(t, "approved in #42", result[0].Reason)
+		}
+		result, err = provider.Exemptions("example.com/foo/bar.go")
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(exemptions))
+		}))
+		defer server.Close()
+
+		provider, err := LoadIgnoreProvider(context.Background(), server.URL, staticToken("secret"), server.Client())
+		assert.NoError(t, err)
+		result, _ := provider.Exemptions("example.com/foo/foo.go")
+		assert.Len(t, result, 1)
+
+		_, err = LoadIgnoreProvider(context.Background(), server.URL, nil, server.Client())
+		assert.ErrorIs(t, err, ErrUnexpectedResponse)
+	})
+
+	t.Run("reason required", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "exemptions.json")
+		assert.NoError(t, os.WriteFile(file, []byte(`{"exemptions": [{"fileName": "example.com/foo/foo.go"}]}`), 0644))
+
+		_, err := LoadIgnoreProvider(context.Background(), file, nil, nil)
+		assert.ErrorIs(t, err, ErrCommentsRequired)
+	})
+}
+
+func TestApplyExemptions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "foo.go")
+	assert.NoError(t, os.WriteFile(file, []byte("package foo\n\nfunc Foo() int {\n\treturn 1\n}\n"), 0644))
+	coverProfile := &cover.Profile{Blocks: []cover.ProfileBlock{{StartLine: 3, StartCol: 16, EndLine: 5, EndCol: 2, NumStmt: 1}}}
+
+	t.Run("no exemptions", func(t *testing.T) {
+		profile, err := ApplyExemptions(nil, file, coverProfile, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, profile)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		profile, err := ApplyExemptions(nil, file, coverProfile, []*Exemption{{StartLine: 4, EndLine: 4, Reason: "approved"}})
+		assert.NoError(t, err)
+		assert.Equal(t, BLOCK_IGNORE, profile.Type)
+		block := profile.IgnoreBlocks[coverProfile.Blocks[0]]
+		if assert.NotNil(t, block) {
+			assert.Equal(t, "exemption", block.Annotation)
+			assert.Equal(t, []string{"func Foo() int {", "\treturn 1", "}"}, block.Contents)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		profile, err := ApplyExemptions(nil, file, coverProfile, []*Exemption{{Reason: "generated", Source: "review"}})
+		assert.NoError(t, err)
+		assert.Equal(t, FILE_IGNORE, profile.Type)
+		assert.Equal(t, "exemption from review", profile.Annotation)
+		assert.Equal(t, "generated", profile.Comments)
+	})
+}
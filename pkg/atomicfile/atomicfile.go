@@ -0,0 +1,157 @@
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+)
+
+// SyncMode decides how the written data is flushed to the disk.
+type SyncMode string
+
+const (
+	// SyncNone leaves flushing to the operating system, the rename is still atomic but the data may be lost on power failure.
+	SyncNone SyncMode = "none"
+	// SyncFile flushes the temporary file before it's renamed, it's the default.
+	SyncFile SyncMode = "file"
+	// SyncAll flushes the temporary file and the parent directory after the rename, so the rename itself is durable.
+	SyncAll SyncMode = "all"
+)
+
+var ErrUnknownSyncMode = errors.New("unknown sync mode, one of: none, file, all")
+
+// Validate validates the sync mode.
+func (m SyncMode) Validate() error {
+	switch m {
+	case SyncNone, SyncFile, SyncAll:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownSyncMode, m)
+	}
+}
+
+var defaultSyncMode atomic.Value
+
+func init() {
+	defaultSyncMode.Store(SyncFile)
+}
+
+// SetSyncMode sets the sync mode of the files created afterwards.
+func SetSyncMode(m SyncMode) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	defaultSyncMode.Store(m)
+	return nil
+}
+
+// CurrentSyncMode returns the sync mode of the files created.
+func CurrentSyncMode() SyncMode {
+	return defaultSyncMode.Load().(SyncMode)
+}
+
+// File is a temporary file in the directory of the target file,
+// it replaces the target file when it's committed, and it's removed if it's aborted.
+type File struct {
+	*os.File
+	target string
+	mode   SyncMode
+	closed bool
+}
+
+// Create creates the temporary file of the target file.
+func Create(target string) (*File, error) {
+	f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	// the temporary file is created with 0600, keeps the same permission as os.Create.
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &File{File: f, target: target, mode: CurrentSyncMode()}, nil
+}
+
+// Commit flushes the temporary file and renames it to the target file.
+func (f *File) Commit() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+
+	if f.mode != SyncNone {
+		if err := f.File.Sync(); err != nil {
+			f.File.Close()
+			os.Remove(f.Name())
+			return fmt.Errorf("sync %s: %w", f.target, err)
+		}
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.target); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if f.mode == SyncAll {
+		return syncDir(filepath.Dir(f.target))
+	}
+	return nil
+}
+
+// Abort removes the temporary file, the target file is untouched. It's a no-op if the file is committed,
+// so it's safe to defer.
+func (f *File) Abort() {
+	if f.closed {
+		return
+	}
+	f.closed = true
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFile writes the target file by the write function, the target file is replaced only if the write succeeds.
+func WriteFile(target string, write func(w io.Writer) error) error {
+	f, err := Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// SyncDir flushes the directory entries, so the renames in the directory are durable when the mode is SyncAll.
+// Windows does not support syncing directories, it's a no-op there.
+func SyncDir(dir string) error {
+	if CurrentSyncMode() != SyncAll {
+		return nil
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	for _, mode := range []SyncMode{SyncNone, SyncFile, SyncAll} {
+		t.Run(string(mode), func(t *testing.T) {
+			defer SetSyncMode(SyncFile)
+			if err := SetSyncMode(mode); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			target := filepath.Join(dir, "report.json")
+			err := WriteFile(target, func(w io.Writer) error {
+				_, err := io.WriteString(w, "{}")
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "{}" {
+				t.Errorf("expect {}, but get %s", data)
+			}
+			info, err := os.Stat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0644 {
+				t.Errorf("expect mode 0644, but get %s", info.Mode().Perm())
+			}
+			assertNoTemporaryFiles(t, dir, 1)
+		})
+	}
+
+	t.Run("keep the previous file if write fails", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "report.json")
+		if err := os.WriteFile(target, []byte("previous"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := errors.New("interrupted")
+		err := WriteFile(target, func(w io.Writer) error {
+			_, _ = io.WriteString(w, `{"trunc`)
+			return expected
+		})
+		if !errors.Is(err, expected) {
+			t.Fatalf("expect %s, but get %v", expected, err)
+		}
+
+		data, err := os.ReadFile(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "previous" {
+			t.Errorf("previous file should be kept, but get %s", data)
+		}
+		assertNoTemporaryFiles(t, dir, 1)
+	})
+
+	t.Run("directory not exist", func(t *testing.T) {
+		err := WriteFile("/not/exist/report.json", func(w io.Writer) error { return nil })
+		if err == nil {
+			t.Error("should return error")
+		}
+	})
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "test-results.json")
+
+	f, err := Create(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("{}\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(target); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("target should not exist before commit, but get %v", err)
+	}
+
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// abort after commit is a no-op.
+	f.Abort()
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("target should exist after commit: %s", err)
+	}
+	if err := f.Commit(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expect ErrClosed, but get %v", err)
+	}
+	assertNoTemporaryFiles(t, dir, 1)
+}
+
+func TestSetSyncMode(t *testing.T) {
+	defer SetSyncMode(SyncFile)
+
+	if err := SetSyncMode("always"); !errors.Is(err, ErrUnknownSyncMode) {
+		t.Errorf("expect ErrUnknownSyncMode, but get %v", err)
+	}
+	if mode := CurrentSyncMode(); mode != SyncFile {
+		t.Errorf("sync mode should not be changed, but get %s", mode)
+	}
+	if err := SetSyncMode(SyncAll); err != nil || CurrentSyncMode() != SyncAll {
+		t.Errorf("expect sync mode all, but get %s, %v", CurrentSyncMode(), err)
+	}
+}
+
+func assertNoTemporaryFiles(t *testing.T, dir string, expected int) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != expected {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expect %d files, but get %v", expected, names)
+	}
+}
@@ -0,0 +1,3 @@
+// Package atomicfile writes files through a temporary file that is renamed to the target,
+// so readers never see a truncated file even if the process is interrupted.
+package atomicfile
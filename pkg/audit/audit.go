@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+// ActionType indicates what kind of external action is taken.
+type ActionType string
+
+const (
+	CommentAction ActionType = "comment"
+	StatusAction  ActionType = "status"
+	UploadAction  ActionType = "upload"
+	BypassAction  ActionType = "bypass"
+	WebhookAction ActionType = "webhook"
+	IssueAction   ActionType = "issue"
+	CheckAction   ActionType = "check"
+	ReviewAction  ActionType = "review"
+)
+
+// SchemaVersion is the version of the audit artifact layout.
+const SchemaVersion = 1
+
+// Action represents a single external action taken by gocover.
+type Action struct {
+	Timestamp  time.Time         `json:"timestamp"`            // time when the action is taken
+	Type       ActionType        `json:"type"`                 // type of the action
+	Target     string            `json:"target"`               // the object the action applies to, such as a pull request or a table
+	ResponseID string            `json:"responseId,omitempty"` // identifier returned by the remote system, if any
+	Succeeded  bool              `json:"succeeded"`            // whether the action succeeded
+	Error      string            `json:"error,omitempty"`      // error message when the action failed
+	Details    map[string]string `json:"details,omitempty"`    // extra information about the action
+}
+
+// Log is the content of the audit artifact.
+type Log struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Actions       []*Action `json:"actions"`
+}
+
+// Recorder records external actions and persists them.
+type Recorder interface {
+	// Record adds the action to the audit log, the timestamp is filled if it's empty.
+	Record(action *Action)
+	// Actions returns all the recorded actions.
+	Actions() []*Action
+	// Flush persists the recorded actions.
+	Flush() error
+}
+
+// NewRecorder creates a recorder that writes the audit log to the file.
+// If the file name is empty, the actions are only kept in memory.
+func NewRecorder(filename string) Recorder {
+	return &recorder{filename: filename}
+}
+
+// NewAction creates an action of the specified type on the target,
+// the result of the action is determined by err.
+func NewAction(actionType ActionType, target string, responseID string, err error) *Action {
+	action := &Action{
+		Type:       actionType,
+		Target:     target,
+		ResponseID: responseID,
+		Succeeded:  err == nil,
+	}
+	if err != nil {
+		action.Error = err.Error()
+	}
+	return action
+}
+
+type recorder struct {
+	filename string
+	mu       sync.Mutex
+	actions  []*Action
+}
+
+var _ Recorder = (*recorder)(nil)
+
+func (r *recorder) Record(action *Action) {
+	if action == nil {
+		return
+	}
+	if action.Timestamp.IsZero() {
+		action.Timestamp = time.Now().UTC()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions = append(r.actions, action)
+}
+
+func (r *recorder) Actions() []*Action {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]*Action, len(r.actions))
+	copy(result, r.actions)
+	return result
+}
+
+func (r *recorder) Flush() error {
+	if r.filename == "" {
+		return nil
+	}
+
+	actions := r.Actions()
+	for i, action := range actions {
+		actions[i] = redactAction(action)
+	}
+
+	data, err := json.MarshalIndent(&Log{
+		SchemaVersion: SchemaVersion,
+		Actions:       actions,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal audit log: %w", err)
+	}
+
+	err = atomicfile.WriteFile(r.filename, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// redactAction returns a copy of the action that the credentials are scrubbed.
+func redactAction(action *Action) *Action {
+	result := *action
+	result.Target = redact.String(action.Target)
+	result.Error = redact.String(action.Error)
+	if action.Details != nil {
+		result.Details = make(map[string]string, len(action.Details))
+		for k, v := range action.Details {
+			result.Details[k] = redact.String(v)
+		}
+	}
+	return &result
+}
@@ -0,0 +1,4 @@
+// Package audit records the external actions taken by gocover,
+// such as comments posted, statuses set and data uploaded,
+// into a JSON artifact so that the behavior of gocover can be traced afterwards.
+package audit
@@ -0,0 +1,92 @@
+package chatops
+
+import (
+	"strings"
+)
+
+// Prefix is the leading word of a gocover command.
+const Prefix = "/gocover"
+
+// Command represents a gocover command in a comment.
+// For example, `/gocover skip reason=legacy code` is parsed as
+// Command{Name: "skip", Params: {"reason": "legacy code"}}.
+type Command struct {
+	// Name is the name of the command.
+	Name string
+	// Args are the positional arguments after the name.
+	Args []string
+	// Params are the key=value arguments, a value lasts until the next key=value argument.
+	Params map[string]string
+	// Raw is the original line of the command.
+	Raw string
+}
+
+// Parse parses all the gocover commands in the text, each command occupies a single line.
+func Parse(text string) []*Command {
+	var commands []*Command
+	for _, line := range strings.Split(text, "\n") {
+		if command := parseLine(line); command != nil {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+func parseLine(line string) *Command {
+	trimmed := strings.TrimSpace(line)
+	tokens := tokenize(trimmed)
+	if len(tokens) < 2 || tokens[0] != Prefix {
+		return nil
+	}
+
+	command := &Command{
+		Name:   strings.ToLower(tokens[1]),
+		Params: make(map[string]string),
+		Raw:    trimmed,
+	}
+
+	lastKey := ""
+	for _, token := range tokens[2:] {
+		if key, value, ok := strings.Cut(token, "="); ok && key != "" {
+			lastKey = strings.ToLower(key)
+			command.Params[lastKey] = value
+			continue
+		}
+		if lastKey != "" {
+			command.Params[lastKey] = strings.TrimSpace(command.Params[lastKey] + " " + token)
+			continue
+		}
+		command.Args = append(command.Args, token)
+	}
+	return command
+}
+
+// tokenize splits the line by white spaces, contents quoted by double quotes are kept as a whole.
+func tokenize(line string) []string {
+	var (
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+		started bool
+	)
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
@@ -0,0 +1,49 @@
+package chatops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testSuites := []struct {
+		input  string
+		expect []*Command
+	}{
+		{input: "LGTM", expect: nil},
+		{input: "/gocover", expect: nil},
+		{input: "please /gocover rerun", expect: nil},
+		{
+			input: "/gocover rerun",
+			expect: []*Command{
+				{Name: "rerun", Params: map[string]string{}, Raw: "/gocover rerun"},
+			},
+		},
+		{
+			input: "thanks\n  /gocover skip reason=legacy code, tracked by #12  \n",
+			expect: []*Command{
+				{Name: "skip", Params: map[string]string{"reason": "legacy code, tracked by #12"}, Raw: "/gocover skip reason=legacy code, tracked by #12"},
+			},
+		},
+		{
+			input: `/gocover Report full format="html page" x=1`,
+			expect: []*Command{
+				{Name: "report", Args: []string{"full"}, Params: map[string]string{"format": "html page", "x": "1"}, Raw: `/gocover Report full format="html page" x=1`},
+			},
+		},
+		{
+			input: "/gocover explain pkg/foo.go:42\n/gocover rerun",
+			expect: []*Command{
+				{Name: "explain", Args: []string{"pkg/foo.go:42"}, Params: map[string]string{}, Raw: "/gocover explain pkg/foo.go:42"},
+				{Name: "rerun", Params: map[string]string{}, Raw: "/gocover rerun"},
+			},
+		},
+	}
+
+	for _, testCase := range testSuites {
+		actual := Parse(testCase.input)
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("for input %q, expect %+v, but get %+v", testCase.input, testCase.expect, actual)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package chatops parses the gocover commands from pull request comments,
+// such as `/gocover skip reason=legacy code`.
+package chatops
@@ -0,0 +1,139 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultName is the name of the check run.
+	DefaultName = "gocover"
+
+	// maxSummaryLength is the max length of the summary of a check run that GitHub accepts.
+	maxSummaryLength = 65535
+	// publishTimeout bounds the time of creating the check run.
+	publishTimeout = time.Minute
+)
+
+// Option contains the input for the check run report generator.
+type Option struct {
+	// Name is the name of the check run, DefaultName is used if it's empty.
+	Name string
+	// Owner is the owner of the repository.
+	Owner string
+	// Repository is the name of the repository.
+	Repository string
+	// HeadSHA is the commit that the check run is attached to, HEAD of RepositoryPath is resolved if it's empty.
+	HeadSHA string
+	// RepositoryPath is the root directory of the git repository.
+	RepositoryPath string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root.
+	ModuleDir string
+	// CoverageBaseline decides the conclusion of the check run.
+	CoverageBaseline float64
+}
+
+// NewReportGenerator creates a report generator that publishes the statistics as a check run,
+// the failed publishing is logged rather than failing the command, as the other reports are written.
+func NewReportGenerator(publisher scm.CheckPublisher, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{publisher: publisher, option: o, logger: logger.WithField("source", "CheckRun")}
+}
+
+type reportGenerator struct {
+	publisher scm.CheckPublisher
+	option    *Option
+	logger    logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport creates the check run of the statistics.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	headSHA := g.option.HeadSHA
+	if headSHA == "" {
+		sha, err := gittool.ResolveCommit(ctx, g.option.RepositoryPath, "HEAD")
+		if err != nil {
+			g.logger.WithError(err).Error("resolve the commit of the check run")
+			return nil
+		}
+		headSHA = sha
+	}
+
+	run := NewCheckRun(statistics, g.option)
+	run.HeadSHA = headSHA
+	id, err := g.publisher.CreateCheckRun(ctx, g.option.Owner, g.option.Repository, run)
+	if err != nil {
+		g.logger.WithError(err).Errorf("create check run %s of %s", run.Name, headSHA)
+		return nil
+	}
+	g.logger.Infof("create check run %s of %s with %d annotations: %s", run.Name, headSHA, len(run.Annotations), id)
+	return nil
+}
+
+// NewCheckRun returns the check run of the statistics without the head sha. The conclusion is failure
+// if the coverage gate fails, or neutral if the failing gate is bypassed.
+// Each uncovered line of the violation sections is annotated.
+func NewCheckRun(statistics *report.Statistics, o *Option) *scm.CheckRun {
+	name := o.Name
+	if name == "" {
+		name = DefaultName
+	}
+
+	conclusion := scm.SuccessConclusion
+	switch {
+	case !statistics.GateFailed(o.CoverageBaseline):
+	case statistics.Bypass != nil:
+		conclusion = scm.NeutralConclusion
+	default:
+		conclusion = scm.FailureConclusion
+	}
+
+	what := "Diff"
+	if statistics.StatisticsType == report.FullStatisticsType {
+		what = "Full"
+	}
+	summary := report.FormatMarkdown(statistics, o.CoverageBaseline, 0)
+	if len(summary) > maxSummaryLength {
+		summary = summary[:maxSummaryLength]
+	}
+	run := &scm.CheckRun{
+		Name:       name,
+		Conclusion: conclusion,
+		Title: fmt.Sprintf("%s coverage %.2f%% of %d lines (baseline %.2f%%)",
+			what, statistics.TotalCoveragePercent, statistics.TotalEffectiveLines, o.CoverageBaseline),
+		Summary:         summary,
+		CoveragePercent: statistics.TotalCoveragePercent,
+	}
+
+	for _, profile := range statistics.CoverageProfile {
+		relative := strings.TrimPrefix(strings.TrimPrefix(profile.FileName, o.ModulePath), "/")
+		file := path.Join(filepath.ToSlash(o.ModuleDir), relative)
+		for _, section := range profile.ViolationSections {
+			for _, line := range section.ViolationLines {
+				run.Annotations = append(run.Annotations, &scm.CheckAnnotation{
+					Path:      file,
+					StartLine: line,
+					EndLine:   line,
+					Level:     scm.WarningAnnotationLevel,
+					Title:     "Uncovered line",
+					Message:   "This line is not covered by tests.",
+				})
+			}
+		}
+	}
+	return run
+}
@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+type mockPublisher struct {
+	runs []*scm.CheckRun
+}
+
+func (p *mockPublisher) CreateCheckRun(ctx context.Context, owner, repository string, run *scm.CheckRun) (string, error) {
+	p.runs = append(p.runs, run)
+	return "1", nil
+}
+
+func testStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		ComparedBranch:       "origin/main",
+		TotalCoveragePercent: 50,
+		TotalEffectiveLines:  4,
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName:            "github.com/Azure/modulea/foo/foo.go",
+				TotalEffectiveLines: 4,
+				CoveredLines:        2,
+				TotalViolationLines: []int{5, 9},
+				ViolationSections: []*report.ViolationSection{
+					{StartLine: 3, EndLine: 6, ViolationLines: []int{5}},
+					{StartLine: 8, EndLine: 10, ViolationLines: []int{9}},
+				},
+			},
+		},
+	}
+}
+
+func TestNewCheckRun(t *testing.T) {
+	o := &Option{ModulePath: "github.com/Azure/modulea", ModuleDir: "modulea", CoverageBaseline: 80}
+	run := NewCheckRun(testStatistics(), o)
+	if run.Name != DefaultName || run.Conclusion != scm.FailureConclusion || run.Title != "Diff coverage 50.00% of 4 lines (baseline 80.00%)" || run.Summary == "" {
+		t.Errorf("unexpected check run %+v", run)
+	}
+	if len(run.Annotations) != 2 {
+		t.Fatalf("expect 2 annotations, but get %d", len(run.Annotations))
+	}
+	if a := run.Annotations[1]; a.Path != "modulea/foo/foo.go" || a.StartLine != 9 || a.EndLine != 9 || a.Level != scm.WarningAnnotationLevel {
+		t.Errorf("unexpected annotation %+v", a)
+	}
+
+	statistics := testStatistics()
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "legacy"}
+	if run := NewCheckRun(statistics, o); run.Conclusion != scm.NeutralConclusion {
+		t.Errorf("expect neutral conclusion of the bypassed gate, but get %s", run.Conclusion)
+	}
+	o.CoverageBaseline = 50
+	if run := NewCheckRun(testStatistics(), o); run.Conclusion != scm.SuccessConclusion {
+		t.Errorf("expect success conclusion, but get %s", run.Conclusion)
+	}
+
+	// the coverage meets the baseline, but the file gate fails.
+	statistics = testStatistics()
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, File: "github.com/Azure/modulea/foo/foo.go", Actual: 40, Limit: 60, Message: "the coverage of file github.com/Azure/modulea/foo/foo.go is 40.00, lower than the file baseline 60.00"}}}
+	if run := NewCheckRun(statistics, o); run.Conclusion != scm.FailureConclusion || !strings.Contains(run.Summary, "lower than the file baseline 60.00") {
+		t.Errorf("expect failure conclusion of the failed file gate, but get %+v", run)
+	}
+}
+
+func TestReportGenerator(t *testing.T) {
+	publisher := &mockPublisher{}
+	g := NewReportGenerator(publisher, &Option{Name: "coverage", Owner: "Azure", Repository: "modulea", HeadSHA: "abc"}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.runs) != 1 || publisher.runs[0].HeadSHA != "abc" || publisher.runs[0].Name != "coverage" {
+		t.Errorf("unexpected check runs %+v", publisher.runs)
+	}
+
+	// the commit can't be resolved out of a git repository, it's logged rather than failing the command.
+	g = NewReportGenerator(publisher, &Option{Owner: "Azure", Repository: "modulea", RepositoryPath: t.TempDir()}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil || len(publisher.runs) != 1 {
+		t.Errorf("expect no check run and no error, but get %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package checks publishes the coverage of a run as a check run of the commit, with an inline annotation
+// at each uncovered line, so the reviewers see the uncovered lines in the files changed of a pull request.
+package checks
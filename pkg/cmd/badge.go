@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	badgeLong = `Render an svg badge of the coverage in a json report, such as the diff coverage of a pull request.
+
+The coverage is read from the json report of --format json or the json document of --json-output, or set by --coverage.
+The color of the badge is the color of the highest --threshold that the coverage reaches, and red below all of them.
+The badge can be committed into the repository or uploaded to a storage, and shown in the README.
+`
+
+	badgeExample = `# Render the badge of the diff coverage.
+gocover diff --cover-profile coverage.out --format json --outputdir reports
+gocover badge --report reports/coverage.json --label "diff coverage" --output badge.svg
+
+# Render a badge that is green at 90% and yellow at 75%.
+gocover badge --coverage 82.5 --threshold 90=#4c1 --threshold 75=#dfb317 --output badge.svg
+`
+)
+
+type badgeOption struct {
+	reportFile string
+	coverage   float64
+	output     string
+	thresholds []string
+	option     report.BadgeOption
+}
+
+func newBadgeCommand() *cobra.Command {
+	o := &badgeOption{}
+
+	cmd := &cobra.Command{
+		Use:     "badge",
+		Short:   "render an svg badge of the coverage",
+		Long:    badgeLong,
+		Example: badgeExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := createLogger(cmd)
+
+			percent := o.coverage
+			switch {
+			case o.reportFile != "":
+				statistics, err := readStatistics(o.reportFile)
+				if err != nil {
+					return err
+				}
+				percent = statistics.TotalCoveragePercent
+			case !cmd.Flags().Changed("coverage"):
+				return errors.New("either --report or --coverage is required")
+			}
+			thresholds, err := report.ParseBadgeThresholds(o.thresholds)
+			if err != nil {
+				return err
+			}
+			o.option.Thresholds = thresholds
+
+			if o.output == report.StdoutOutput {
+				return report.WriteBadgeWithOption(cmd.OutOrStdout(), percent, &o.option)
+			}
+			err = atomicfile.WriteFile(o.output, func(w io.Writer) error {
+				return report.WriteBadgeWithOption(w, percent, &o.option)
+			})
+			if err != nil {
+				return fmt.Errorf("write badge: %w", err)
+			}
+			logger.Infof("generate coverage badge: %s", o.output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.reportFile, "report", "", "json report or json document that the coverage is read from")
+	cmd.Flags().Float64Var(&o.coverage, "coverage", 0, "coverage percent of the badge, rather than reading it from a report")
+	cmd.Flags().StringVar(&o.output, "output", "badge.svg", "file that the badge is written into, - writes it to stdout")
+	cmd.Flags().StringVar(&o.option.Label, "label", report.DefaultBadgeLabel, "label on the left of the badge")
+	cmd.Flags().StringArrayVar(&o.thresholds, "threshold", []string{}, "color of the coverage at or above a percent in the format of percent=color, such as 80=#97ca00 or 80=green, the colors of shields.io from 50% to 90% are used if it's not set")
+	cmd.MarkFlagsMutuallyExclusive("report", "coverage")
+
+	return cmd
+}
+
+// readStatistics reads the statistics from a json report or a json document.
+func readStatistics(file string) (*report.Statistics, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	statistics, err := report.ReadStatistics(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+	return statistics, nil
+}
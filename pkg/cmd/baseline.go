@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	baselineWriteLong = `Write the full coverage of each package into the baseline file, which is committed into the repository.
+The full and test commands with --baseline-file fail if a package drops below its baseline by more than --baseline-slack,
+so the coverage improves gradually without a fixed global threshold.
+
+A baseline in the existing file is only raised, so a package keeps its best coverage, unless --reset is set.
+The packages removed from the module are removed from the file, and the new packages are added.
+`
+
+	baselineWriteExample = `# Write the baseline of the packages on the main branch, and commit it.
+go test ./... -coverprofile coverage.out
+gocover baseline write --cover-profile coverage.out
+git add .gocover-baseline.json
+
+# Gate the coverage of each package by the baseline, a package may drop 0.5% below it.
+gocover full --cover-profile coverage.out --baseline-file .gocover-baseline.json --baseline-slack 0.5
+`
+)
+
+func newBaselineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "manage the coverage baseline file that gates the coverage of each package",
+	}
+	cmd.AddCommand(newBaselineWriteCommand())
+	return cmd
+}
+
+func newBaselineWriteCommand() *cobra.Command {
+	o := &gocover.RatchetWriteOption{}
+
+	cmd := &cobra.Command{
+		Use:     "write",
+		Short:   "write the full coverage of each package into the baseline file",
+		Long:    baselineWriteLong,
+		Example: baselineWriteExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.StdOut = cmd.OutOrStdout()
+			o.Logger = createLogger(cmd)
+
+			ctx, cancel := commandContext()
+			defer cancel()
+			return gocover.RunRatchetWrite(ctx, o)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profile produced by 'go test'`)
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for coverage calucation")
+	cmd.Flags().StringVar(&o.File, "baseline-file", gocover.DefaultRatchetFile, "baseline file that is written")
+	cmd.Flags().BoolVar(&o.Reset, "reset", false, "replace the baselines by the current coverage, even if they go down")
+
+	cmd.MarkFlagRequired("cover-profile")
+	return cmd
+}
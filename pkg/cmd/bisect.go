@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bisectLong = `Answer whether the commit checked out by git bisect passes the diff coverage gate, as a git bisect run script.
+
+The tests run with coverage on HEAD, and the diff coverage against --compare-branch is checked by --coverage-baseline.
+The command exits with code 0 if the coverage passes, 1 if it's lower than the baseline, and 125 to skip the commit
+if the coverage can't be calculated, such as the code doesn't build. The failed tests don't skip the commit.
+Compare with HEAD~1 to find the commit whose own changes are not covered, or with the good commit to find the commit
+since which the changes are not covered.
+`
+
+	bisectExample = `# Find the commit that adds the uncovered code between v1.0.0 and main.
+git bisect start main v1.0.0
+git bisect run gocover bisect --coverage-baseline 80
+git bisect reset
+
+# Find the commit since which the changes from v1.0.0 are not covered.
+git bisect start main v1.0.0
+git bisect run gocover bisect --compare-branch v1.0.0 --coverage-baseline 80
+`
+)
+
+func newBisectCommand() *cobra.Command {
+	o := gocover.NewBisectOption()
+
+	cmd := &cobra.Command{
+		Use:     "bisect",
+		Short:   "answer whether the diff coverage of HEAD passes the gate, as a git bisect run script",
+		Long:    bisectLong,
+		Example: bisectExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = createLogger(cmd)
+			o.StdOut = cmd.ErrOrStderr()
+			o.StdErr = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			result, err := gocover.RunBisect(ctx, o)
+			if err != nil {
+				return gocover.WrapErrorWithCode(err, gocover.BisectSkipExitCode, "")
+			}
+			s := result.Statistics
+			if !result.Passed() {
+				err := fmt.Errorf("%s: %w, diff coverage %.1f%% against %s fails the gate: %s", result.Commit, gocover.ErrBisectBad, s.TotalCoveragePercent, o.CompareBranch, s.Gate.Summary())
+				return gocover.WrapErrorWithCode(err, gocover.BisectBadExitCode, "")
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: diff coverage %.1f%% against %s passes %.1f%%\n", result.Commit, s.TotalCoveragePercent, o.CompareBranch, o.CoverageBaseline)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.CompareBranch, "compare-branch", o.CompareBranch, "revision that HEAD is compared with, HEAD~1 checks the changes of each commit")
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringSliceVar(&o.GoFlags, "go-flags", []string{}, "go flags")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "coverage that the changes need for the commit to be good")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("diff coverage report of the commit"))
+	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "diff coverage report name")
+	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", "", "directory that the report of the commit is written into, the report is discarded if it's empty")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+	addLimitFlags(cmd, &o.Limits)
+
+	return cmd
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	blameLong = `Print the files in the format of git blame, each line has the commit and the author that changed it last,
+and its diff coverage status: covered, uncovered, ignored, or - if it doesn't count for the diff coverage,
+such as the unchanged lines. The leads can review who owns the uncovered lines of the changes.
+
+The authors are of the git blame of HEAD, the lines that are not committed are of Not Committed Yet.
+`
+
+	blameExample = `# Print the coverage blame of a file changed since origin/main.
+gocover blame pkg/foo/foo.go --cover-profile coverage.out --compare-branch origin/main
+
+# Print only the uncovered lines of the files, and the uncovered lines of each author.
+gocover blame pkg/foo/foo.go pkg/bar/bar.go --cover-profile coverage.out --uncovered-only --summary
+`
+)
+
+func newBlameCommand() *cobra.Command {
+	o := &gocover.CoverageBlameOption{}
+
+	cmd := &cobra.Command{
+		Use:     "blame file...",
+		Short:   "print the files in the format of git blame with the diff coverage status of each line",
+		Long:    blameLong,
+		Example: blameExample,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Files = args
+			o.StdOut = cmd.OutOrStdout()
+			o.Logger = createLogger(cmd)
+
+			ctx, cancel := commandContext()
+			defer cancel()
+			return gocover.RunCoverageBlame(ctx, o)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profile produced by 'go test'`)
+	cmd.Flags().StringVar(&o.CompareBranch, "compare-branch", gocover.DefaultCompareBranch, `branch to compare`)
+	cmd.Flags().StringVar(&o.DiffTarget, "diff-target", gittool.HEADTarget, `what compares with the branch, one of: HEAD, worktree (uncommitted and untracked changes included), or a revision such as a stash like stash@{0}`)
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().BoolVar(&o.Blame.UncoveredOnly, "uncovered-only", false, "print only the uncovered lines")
+	cmd.Flags().BoolVar(&o.Blame.Summary, "summary", false, "print the number of the uncovered lines of each author after each file")
+
+	cmd.MarkFlagRequired("cover-profile")
+	return cmd
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	browseLong = `Browse a coverage report in the terminal, rather than opening the html report in a browser.
+
+The screen is the coverage tree of the directories and the files of the module, an opened file shows its violation
+sections with the uncovered lines marked and the source highlighted. The report is a json report of --format json
+or the json document of --json-output, the source of a section is read from the module directory if the report
+doesn't keep it. Colors are disabled if NO_COLOR environment is set.
+
+Keys: up/down or j/k move, enter/right or l opens, left/backspace/esc or h goes back,
+n/p jump to the next/previous violation section of a file, and q quits.
+`
+
+	browseExample = `# Browse the diff coverage of the changes.
+go test -coverprofile=coverage.out ./...
+gocover diff --cover-profile coverage.out --compare-branch origin/main --json-output reports/diff.json
+gocover browse --report reports/diff.json
+
+# Browse without colors.
+gocover browse --report reports/diff.json --color=false
+`
+)
+
+type browseOption struct {
+	report         string
+	repositoryPath string
+	moduleDir      string
+	modulePath     string
+	style          string
+	color          bool
+}
+
+func newBrowseCommand() *cobra.Command {
+	o := &browseOption{color: true}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		o.color = false
+	}
+
+	cmd := &cobra.Command{
+		Use:     "browse",
+		Short:   "browse a coverage report in the terminal",
+		Long:    browseLong,
+		Example: browseExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			moduleDir := filepath.Join(o.repositoryPath, o.moduleDir)
+			modulePath, err := gocover.ModulePath(moduleDir, o.modulePath)
+			if err != nil {
+				return fmt.Errorf("resolve module path: %w", err)
+			}
+			statistics, err := readStatistics(o.report)
+			if err != nil {
+				return err
+			}
+			if statistics == nil {
+				return fmt.Errorf("read %s: %w", o.report, errors.New("no statistics in the report"))
+			}
+
+			browser := tui.NewBrowser(statistics, &tui.Option{
+				ModulePath: modulePath,
+				ModuleDir:  moduleDir,
+				Style:      o.style,
+				Color:      o.color,
+			})
+			return browser.RunTerminal(os.Stdin, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.report, "report", "", "json report or json document that is browsed")
+	cmd.Flags().StringVar(&o.repositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.moduleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.modulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringVar(&o.style, "style", "monokai", "code style of the highlighted source, refer to https://pygments.org/docs/styles for more information")
+	cmd.Flags().BoolVar(&o.color, "color", o.color, "color the coverage and highlight the source with ANSI escape codes")
+
+	cmd.MarkFlagRequired("report")
+
+	return cmd
+}
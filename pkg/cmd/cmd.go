@@ -2,11 +2,42 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Azure/gocover/pkg/actions"
+	"github.com/Azure/gocover/pkg/annotation"
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/checks"
+	"github.com/Azure/gocover/pkg/credential"
 	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
 	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/httpclient"
+	"github.com/Azure/gocover/pkg/mergequeue"
+	"github.com/Azure/gocover/pkg/metrics"
+	"github.com/Azure/gocover/pkg/notify"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/profiling"
+	"github.com/Azure/gocover/pkg/prstatus"
+	"github.com/Azure/gocover/pkg/redact"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/review"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/Azure/gocover/pkg/storage"
+	"github.com/Azure/gocover/pkg/telemetry"
+	"github.com/Azure/gocover/pkg/upload"
+	"github.com/Azure/gocover/pkg/webhook"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -69,6 +100,14 @@ gocover test --coverage-mode full --outputdir /tmp
 var (
 	dbOption         = &dbclient.DBOption{}
 	timeoutInSeconds int
+	auditFile        string
+	syncMode         string
+	httpOption       = &httpclient.Option{}
+	httpClient       *http.Client
+	profilingOption  = &profiling.Option{}
+	profiler         *profiling.Profiler
+	telemetryOption  = &telemetry.Option{}
+	telemetryRec     *telemetry.Recorder
 )
 
 const (
@@ -79,33 +118,106 @@ const (
 
 func createLogger(cmd *cobra.Command) *logrus.Logger {
 	logger := logrus.New()
+	logger.AddHook(redact.NewHook())
+	if isVerbose(cmd) {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+	return logger
+}
+
+// isVerbose returns the value of the verbose flag.
+func isVerbose(cmd *cobra.Command) bool {
 	verbose, err := cmd.Flags().GetBool(FlagVerbose)
 	if err != nil {
 		// no verbose flag on the command, It's OK.
-		verbose = false
+		return false
 	}
-	if verbose {
-		logger.SetLevel(logrus.DebugLevel)
+	return verbose
+}
+
+// isTerminal reports whether w is a terminal, such as the stdout of an interactive shell.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// commandContext returns the context of a command, which is canceled after the seconds of the --timeout flag.
+func commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Duration(timeoutInSeconds)*time.Second)
+}
+
+// StopProfiling stops the profiling started by the --pprof and --trace flags,
+// it should be called after the command is executed.
+func StopProfiling() error {
+	return profiler.Stop()
+}
+
+// StopTelemetry sends the usage metrics of the command with the class of its error if the telemetry is on,
+// it should be called after the command is executed.
+func StopTelemetry(err error) {
+	telemetryRec.Finish(errorClass(err))
+}
+
+// addTelemetryGenerator records the size of the coverage of the command if the telemetry is on.
+func addTelemetryGenerator(generators *[]report.ReportGenerator) {
+	if telemetryRec != nil {
+		*generators = append(*generators, telemetryRec)
 	}
-	return logger
 }
 
 // NewGoCoverCommand creates a command object for generating diff coverage reporter.
 func NewGoCoverCommand(version, commit, date string) *cobra.Command {
+	// the artifacts are stamped with the version, so the fleets of mixed versions can tell them apart.
+	report.ToolVersion = version
 
 	cmd := &cobra.Command{
 		Use:          "gocover",
 		Short:        "coverage tool for go code",
 		SilenceUsage: true,
+		// errors are printed by the caller after the secrets are redacted.
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the config file sets the flags that are not set on the command line before they're used.
+			if err := applyConfigFile(cmd); err != nil {
+				return err
+			}
+			if err := processExitCodes.validate(); err != nil {
+				// the invalid exit codes are not used for the error itself.
+				processExitCodes.lowCoverage, processExitCodes.toolError = gocover.LowCoverageErrorExitCode, gocover.GeneralErrorExitCode
+				return err
+			}
 			if err := dbOption.Validate(); err != nil {
 				return err
 			}
+			if err := atomicfile.SetSyncMode(atomicfile.SyncMode(syncMode)); err != nil {
+				return err
+			}
+			client, err := httpclient.New(httpOption)
+			if err != nil {
+				return fmt.Errorf("http client: %w", err)
+			}
+			httpClient = client
+			dbOption.KustoOption.HTTPClient = client
+
+			profiler, err = profiling.Start(profilingOption, createLogger(cmd))
+			if err != nil {
+				return fmt.Errorf("start profiling: %w", err)
+			}
+			if err := telemetryOption.Validate(); err != nil {
+				return err
+			}
+			command := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+			telemetryRec = telemetry.Start(telemetryOption, command, version, client, createLogger(cmd))
 			return nil
 		},
 	}
 
 	cmd.PersistentFlags().BoolP(FlagVerbose, FlagVerboseShort, false, "verbose output")
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "config file that sets the flags not set on the command line, .gocover.yml in the root directory of --repository-path is used by default")
 
 	cmd.PersistentFlags().BoolVar(&dbOption.DataCollectionEnabled, "data-collection-enabled", false, "whether or not enable collecting coverage data")
 	cmd.PersistentFlags().StringVar((*string)(&dbOption.DbType), "store-type", string(dbclient.None), "db client type")
@@ -113,18 +225,66 @@ func NewGoCoverCommand(version, commit, date string) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&dbOption.KustoOption.Database, "database", "", "kusto database")
 	cmd.PersistentFlags().StringVar(&dbOption.KustoOption.CoverageEvent, "coverage-event", "", "kusto event for coverage")
 	cmd.PersistentFlags().StringVar(&dbOption.KustoOption.IgnoreEvent, "ignore-event", "", "kusto event for ignore information")
+	cmd.PersistentFlags().StringVar(&dbOption.KustoOption.BypassEvent, "bypass-event", "", "kusto event for the bypasses of the coverage gate, they're not sent if it's empty")
 	cmd.PersistentFlags().StringSliceVar(&dbOption.KustoOption.CustomColumns, "custom-columns", []string{}, "custom kusto columns, format: {column}:{datatype}:{value}")
 	cmd.PersistentFlags().IntVar(&timeoutInSeconds, "timeout", defaultTimeoutInSeconds, "execute timeout in seconds")
+	cmd.PersistentFlags().StringVar(&httpOption.Proxy, "proxy", "", "proxy url for http integrations, HTTPS_PROXY and NO_PROXY environments are used if it's empty")
+	cmd.PersistentFlags().StringVar(&httpOption.CABundle, "ca-bundle", "", "PEM file contains extra CA certificates to trust for http integrations")
+	cmd.PersistentFlags().StringVar(&httpOption.ClientCert, "client-cert", "", "PEM file contains the client certificate for mTLS")
+	cmd.PersistentFlags().StringVar(&httpOption.ClientKey, "client-key", "", "PEM file contains the private key of the client certificate for mTLS")
+	cmd.PersistentFlags().StringVar(&auditFile, "audit-file", "", "file that records the external actions taken by gocover in JSON format")
+	cmd.PersistentFlags().StringVar(&profilingOption.PprofAddr, "pprof", "", "address that the pprof endpoints of gocover itself are served on during the run, such as localhost:6060")
+	cmd.PersistentFlags().StringVar(&telemetryOption.Mode, "telemetry", telemetry.ModeOff, "send the anonymous usage metrics of the command to --telemetry-endpoint, one of: off, on, the metrics are the command, the version, the platform, the duration, the size buckets of the coverage and the error class")
+	cmd.PersistentFlags().StringVar(&telemetryOption.Endpoint, "telemetry-endpoint", "", "http or https url that the usage metrics are posted to as json with --telemetry=on")
+	cmd.PersistentFlags().StringVar(&profilingOption.TraceFile, "trace", "", "file that the execution trace of gocover itself is written into, view it by 'go tool trace'")
+	addExitCodeFlags(cmd, processExitCodes)
+	cmd.PersistentFlags().StringVar(&syncMode, "fsync", string(atomicfile.SyncFile), "how the written reports are flushed to the disk before they replace the previous ones, one of: none, file, all")
 
 	cmd.AddCommand(newDiffCoverageCommand())
 	cmd.AddCommand(newFullCoverageCommand())
 	cmd.AddCommand(newGoCoverTestCommand())
+	cmd.AddCommand(newOverlayCommand())
+	cmd.AddCommand(newBadgeCommand())
+	cmd.AddCommand(newMatrixCommand())
+	cmd.AddCommand(newMergeCommand())
+	cmd.AddCommand(newDaemonCommand())
+	cmd.AddCommand(newBlameCommand())
+	cmd.AddCommand(newBaselineCommand())
+	cmd.AddCommand(newBisectCommand())
+	cmd.AddCommand(newRevertCommand())
+	cmd.AddCommand(newDeployGateCommand())
+	cmd.AddCommand(newDebtCommand())
+	cmd.AddCommand(newDigestCommand())
+	cmd.AddCommand(newTrendCommand())
+	cmd.AddCommand(newServeCommand())
+	cmd.AddCommand(newBrowseCommand())
+	cmd.AddCommand(newWatchCommand())
+	cmd.AddCommand(newWebhookCommand())
+	cmd.AddCommand(newWebhookJobCommand())
+	cmd.AddCommand(newSelfUpdateCommand(version))
 	cmd.AddCommand(newVersionCommand(version, commit, date))
 	return cmd
 }
 
 func newDiffCoverageCommand() *cobra.Command {
 	o := gocover.NewDiffOption()
+	layers := &layerFlags{}
+	packageBaselines := &packageBaselineFlags{}
+	gracePeriods := &gracePeriodFlags{}
+	exemptions := &exemptionFlags{}
+	reportStorage := &storageFlags{}
+	resultWebhooks := &resultWebhookFlags{}
+	slack := &chatFlags{chat: slackChat}
+	teams := &chatFlags{chat: teamsChat}
+	gitNotes := &gitNotesFlags{}
+	checkRun := &checkRunFlags{}
+	azureDevOps := &azureDevOpsFlags{}
+	bitbucket := &bitbucketFlags{}
+	gerrit := &gerritFlags{}
+	uploads := &uploadFlags{}
+	metricsExport := &metricsFlags{}
+	githubActions := &githubActionsFlags{}
+	mergeQueue := &mergeQueueFlags{github: checkRun}
 	cmd := &cobra.Command{
 		Use:     "diff",
 		Short:   "generate diff coverage for go code unit test",
@@ -133,13 +293,67 @@ func newDiffCoverageCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			o.Logger = createLogger(cmd)
 			o.DbOption = dbOption
+			o.AuditFile = auditFile
+			o.Verbose = isVerbose(cmd)
+			o.Rollout.Enabled = cmd.Flags().Changed("rollout-percent")
+			if err := layers.parse(&o.Layers); err != nil {
+				return err
+			}
+			if err := packageBaselines.parse(&o.PackageBaselines); err != nil {
+				return err
+			}
+			if err := gracePeriods.parse(&o.GracePeriods); err != nil {
+				return err
+			}
+			if err := exemptions.load(&o.IgnoreProviders); err != nil {
+				return err
+			}
+			// the reports are uploaded first, so the comments and the messages link to them.
+			if err := reportStorage.apply(&o.ReportGenerators, &o.Annotators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.Style, o.Verbose, &o.Theme, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := resultWebhooks.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := slack.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := teams.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			gitNotes.apply(&o.ReportGenerators, o.RepositoryPath, o.Logger)
+			if err := checkRun.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := mergeQueue.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := azureDevOps.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := bitbucket.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := gerrit.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := uploads.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.Logger); err != nil {
+				return err
+			}
+			if err := metricsExport.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := githubActions.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, cmd.ErrOrStderr(), o.Logger); err != nil {
+				return err
+			}
+			addTelemetryGenerator(&o.ReportGenerators)
 
 			diff, err := gocover.NewDiffCover(o)
 			if err != nil {
 				return fmt.Errorf("NewDiffCover: %w", err)
 			}
 
-			ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInSeconds*time.Second)
+			ctx, cancel := commandContext()
 			defer cancel()
 
 			if err := diff.Run(ctx); err != nil {
@@ -152,14 +366,61 @@ func newDiffCoverageCommand() *cobra.Command {
 
 	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profile produced by 'go test'`)
 	cmd.Flags().StringVar(&o.CompareBranch, "compare-branch", o.CompareBranch, `branch to compare`)
+	cmd.Flags().StringVar(&o.DiffTarget, "diff-target", gittool.HEADTarget, `what compares with the branch, one of: HEAD, worktree (uncommitted and untracked changes included), or a revision such as a stash like stash@{0}`)
+	cmd.Flags().BoolVar(&o.AgainstTag, "against-tag", false, "compare with the latest semver tag reachable from HEAD rather than the compare branch, the tags of a nested module are prefixed by the module directory such as modulea/v1.2.0")
 	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
 	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
-	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, "format of the diff coverage report, one of: html, json, markdown")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default, or derived from GOPATH for the repositories without go.mod")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("diff coverage report"))
 	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().BoolVar(&o.GitAttributes, "git-attributes", o.GitAttributes, "exclude the files marked linguist-generated or export-ignore in the .gitattributes files of the repository")
+	cmd.Flags().StringVar(&o.SkipListFile, "skip-list", "", "yaml file of the files whose coverage collection is flaky, each entry has a pattern, an owner, an expiry and a reason, the files are not counted for coverage until the entry expires and the entries are shown in the reports")
 	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", o.OutputDir, "diff coverage output directory")
+	cmd.Flags().StringVar(&o.ArtifactsDir, "output-dir", "", "directory that report.json, report.html, cobertura.xml, badge.svg and metadata.json are written into in one run, it's replaced by each run")
+	cmd.Flags().StringVar(&o.JSONOutput, "json-output", "", "file that the json document of the statistics and the coverage tree is written into in a versioned schema, - writes it to the stdout")
 	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "returns an error code if coverage or quality score is less than coverage baseline")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "fail-under", o.CoverageBaseline, "alias of --coverage-baseline, returns the low coverage exit code if the overall coverage is less than it")
+	cmd.Flags().Float64Var(&o.FileBaseline, "fail-under-file", 0, "returns the low coverage exit code if the coverage of any counted file is less than it, the files are not gated if it's 0")
+	cmd.MarkFlagsMutuallyExclusive("coverage-baseline", "fail-under")
 	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "diff coverage report name")
 	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+	cmd.Flags().StringSliceVar(&o.TestResults, "test-json", []string{}, "output files of 'go test -json', failed tests are reported and coverage of the failed packages is flagged as unreliable")
+	cmd.Flags().StringVar((*string)(&o.FailedTestPolicy), "failed-test-policy", string(o.FailedTestPolicy), "how the coverage of the packages whose tests failed is treated, one of: fail, warn, exclude")
+	cmd.Flags().StringVar(&o.Shard, "shard", "", "analyze only the packages of a shard such as 2/4, the packages are assigned to the shards by the hash of the import path, the json documents of all the shards are merged by gocover merge")
+
+	cmd.MarkFlagsMutuallyExclusive("compare-branch", "against-tag")
+	addBypassFlags(cmd, &o.Bypass)
+
+	addLimitFlags(cmd, &o.Limits)
+	addSARIFFlags(cmd, &o.SARIF)
+	addThemeFlags(cmd, &o.Theme)
+	cmd.Flags().StringSliceVar(&o.SonarQubePathMappings, "sonarqube-path-map", []string{}, "map the repository relative paths of the sonarqube report to the paths that sonarqube expects, in the format of from=to such as services/api/= for the project in services/api, the first matched prefix is replaced")
+	addStrictParseFlag(cmd, &o.StrictParse)
+	addLayerFlags(cmd, layers)
+	addPackageBaselineFlags(cmd, packageBaselines)
+	addGracePeriodFlags(cmd, gracePeriods)
+	addExemptionFlags(cmd, exemptions)
+	addTestHelperFlags(cmd, &o.TestHelpers)
+	addStorageFlags(cmd, reportStorage)
+	addResultWebhookFlags(cmd, resultWebhooks)
+	addChatFlags(cmd, slack, "Slack", "mrkdwn text", "SLACK_WEBHOOK_URL")
+	addChatFlags(cmd, teams, "Microsoft Teams", "markdown text", "TEAMS_WEBHOOK_URL")
+	addGitNotesFlags(cmd, gitNotes)
+	addCheckRunFlags(cmd, checkRun)
+	addMergeQueueFlags(cmd, mergeQueue)
+	addAzureDevOpsFlags(cmd, azureDevOps)
+	addBitbucketFlags(cmd, bitbucket)
+	addGerritFlags(cmd, gerrit)
+	addUploadFlags(cmd, uploads)
+	addMetricsFlags(cmd, metricsExport)
+	addGitHubActionsFlags(cmd, githubActions)
+	addTodoFlags(cmd, &o.Todos)
+	addLineMappingFlag(cmd, &o.LineMapping)
+	cmd.Flags().BoolVar(&o.DiffView, "diff-view", false, "render the annotated report as a unified diff, which has the deleted lines and the added lines colored by coverage, the diff sections are also in the json report")
+	addHunkFlags(cmd, &o.Hunks)
+	addDiffFlags(cmd, &o.DiffAlgorithm, &o.DiffContext)
+	addRegressionFlags(cmd, &o.Regression)
+	addRolloutFlags(cmd, &o.Rollout)
 
 	cmd.MarkFlagRequired("cover-profile")
 
@@ -169,6 +430,22 @@ func newDiffCoverageCommand() *cobra.Command {
 func newFullCoverageCommand() *cobra.Command {
 	o := gocover.NewFullOption()
 
+	layers := &layerFlags{}
+	packageBaselines := &packageBaselineFlags{}
+	gracePeriods := &gracePeriodFlags{}
+	exemptions := &exemptionFlags{}
+	reportStorage := &storageFlags{}
+	resultWebhooks := &resultWebhookFlags{}
+	slack := &chatFlags{chat: slackChat}
+	teams := &chatFlags{chat: teamsChat}
+	gitNotes := &gitNotesFlags{}
+	checkRun := &checkRunFlags{}
+	azureDevOps := &azureDevOpsFlags{}
+	bitbucket := &bitbucketFlags{}
+	gerrit := &gerritFlags{}
+	uploads := &uploadFlags{}
+	metricsExport := &metricsFlags{}
+	githubActions := &githubActionsFlags{}
 	cmd := &cobra.Command{
 		Use:     "full",
 		Short:   "generate coverage for go code unit test",
@@ -177,13 +454,63 @@ func newFullCoverageCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			o.Logger = createLogger(cmd)
 			o.DbOption = dbOption
+			o.AuditFile = auditFile
+			o.Verbose = isVerbose(cmd)
+			if err := layers.parse(&o.Layers); err != nil {
+				return err
+			}
+			if err := packageBaselines.parse(&o.PackageBaselines); err != nil {
+				return err
+			}
+			if err := gracePeriods.parse(&o.GracePeriods); err != nil {
+				return err
+			}
+			if err := exemptions.load(&o.IgnoreProviders); err != nil {
+				return err
+			}
+			// the reports are uploaded first, so the comments and the messages link to them.
+			if err := reportStorage.apply(&o.ReportGenerators, &o.Annotators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.Style, o.Verbose, &o.Theme, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := resultWebhooks.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := slack.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := teams.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			gitNotes.apply(&o.ReportGenerators, o.RepositoryPath, o.Logger)
+			if err := checkRun.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := azureDevOps.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := bitbucket.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := gerrit.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := uploads.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.Logger); err != nil {
+				return err
+			}
+			if err := metricsExport.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := githubActions.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, cmd.ErrOrStderr(), o.Logger); err != nil {
+				return err
+			}
+			addTelemetryGenerator(&o.ReportGenerators)
 
 			full, err := gocover.NewFullCover(o)
 			if err != nil {
 				return fmt.Errorf("NewFullCover: %w", err)
 			}
 
-			ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInSeconds*time.Second)
+			ctx, cancel := commandContext()
 			defer cancel()
 
 			if err := full.Run(ctx); err != nil {
@@ -197,12 +524,47 @@ func newFullCoverageCommand() *cobra.Command {
 	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profiles produced by 'go test'`)
 	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
 	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
-	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, "format of the diff coverage report, one of: html, json, markdown")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default, or derived from GOPATH for the repositories without go.mod")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("full coverage report"))
 	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().BoolVar(&o.GitAttributes, "git-attributes", o.GitAttributes, "exclude the files marked linguist-generated or export-ignore in the .gitattributes files of the repository")
+	cmd.Flags().StringVar(&o.SkipListFile, "skip-list", "", "yaml file of the files whose coverage collection is flaky, each entry has a pattern, an owner, an expiry and a reason, the files are not counted for coverage until the entry expires and the entries are shown in the reports")
 	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", o.OutputDir, "diff coverage output directory")
+	cmd.Flags().StringVar(&o.ArtifactsDir, "output-dir", "", "directory that report.json, report.html, cobertura.xml, badge.svg and metadata.json are written into in one run, it's replaced by each run")
+	cmd.Flags().StringVar(&o.JSONOutput, "json-output", "", "file that the json document of the statistics and the coverage tree is written into in a versioned schema, - writes it to the stdout")
 	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "returns an error code if coverage or quality score is less than coverage baseline")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "fail-under", o.CoverageBaseline, "alias of --coverage-baseline, returns the low coverage exit code if the overall coverage is less than it")
+	cmd.Flags().Float64Var(&o.FileBaseline, "fail-under-file", 0, "returns the low coverage exit code if the coverage of any counted file is less than it, the files are not gated if it's 0")
+	addRatchetFlags(cmd, &o.Ratchet)
+	cmd.MarkFlagsMutuallyExclusive("coverage-baseline", "fail-under")
 	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "diff coverage report name")
 	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+	cmd.Flags().StringSliceVar(&o.TestResults, "test-json", []string{}, "output files of 'go test -json', failed tests are reported and coverage of the failed packages is flagged as unreliable")
+	cmd.Flags().StringVar((*string)(&o.FailedTestPolicy), "failed-test-policy", string(o.FailedTestPolicy), "how the coverage of the packages whose tests failed is treated, one of: fail, warn, exclude")
+	cmd.Flags().StringVar(&o.Shard, "shard", "", "analyze only the packages of a shard such as 2/4, the packages are assigned to the shards by the hash of the import path, the json documents of all the shards are merged by gocover merge")
+
+	addLimitFlags(cmd, &o.Limits)
+	addSARIFFlags(cmd, &o.SARIF)
+	addThemeFlags(cmd, &o.Theme)
+	cmd.Flags().StringSliceVar(&o.SonarQubePathMappings, "sonarqube-path-map", []string{}, "map the repository relative paths of the sonarqube report to the paths that sonarqube expects, in the format of from=to such as services/api/= for the project in services/api, the first matched prefix is replaced")
+	addStrictParseFlag(cmd, &o.StrictParse)
+	addLayerFlags(cmd, layers)
+	addPackageBaselineFlags(cmd, packageBaselines)
+	addGracePeriodFlags(cmd, gracePeriods)
+	addExemptionFlags(cmd, exemptions)
+	addTestHelperFlags(cmd, &o.TestHelpers)
+	addStorageFlags(cmd, reportStorage)
+	addResultWebhookFlags(cmd, resultWebhooks)
+	addChatFlags(cmd, slack, "Slack", "mrkdwn text", "SLACK_WEBHOOK_URL")
+	addChatFlags(cmd, teams, "Microsoft Teams", "markdown text", "TEAMS_WEBHOOK_URL")
+	addGitNotesFlags(cmd, gitNotes)
+	addCheckRunFlags(cmd, checkRun)
+	addAzureDevOpsFlags(cmd, azureDevOps)
+	addBitbucketFlags(cmd, bitbucket)
+	addGerritFlags(cmd, gerrit)
+	addUploadFlags(cmd, uploads)
+	addMetricsFlags(cmd, metricsExport)
+	addGitHubActionsFlags(cmd, githubActions)
 
 	cmd.MarkFlagRequired("cover-profile")
 
@@ -212,6 +574,22 @@ func newFullCoverageCommand() *cobra.Command {
 func newGoCoverTestCommand() *cobra.Command {
 	o := gocover.NewGoCoverTestOption()
 
+	layers := &layerFlags{}
+	packageBaselines := &packageBaselineFlags{}
+	gracePeriods := &gracePeriodFlags{}
+	exemptions := &exemptionFlags{}
+	reportStorage := &storageFlags{}
+	resultWebhooks := &resultWebhookFlags{}
+	slack := &chatFlags{chat: slackChat}
+	teams := &chatFlags{chat: teamsChat}
+	gitNotes := &gitNotesFlags{}
+	checkRun := &checkRunFlags{}
+	azureDevOps := &azureDevOpsFlags{}
+	bitbucket := &bitbucketFlags{}
+	gerrit := &gerritFlags{}
+	uploads := &uploadFlags{}
+	metricsExport := &metricsFlags{}
+	githubActions := &githubActionsFlags{}
 	cmd := &cobra.Command{
 		Use:     "test",
 		Short:   "run tests and coverage calculation on the module",
@@ -220,10 +598,71 @@ func newGoCoverTestCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			o.Logger = createLogger(cmd)
 			o.DbOption = dbOption
+			o.AuditFile = auditFile
+			o.Verbose = isVerbose(cmd)
+			o.Rollout.Enabled = cmd.Flags().Changed("rollout-percent")
+			if err := layers.parse(&o.Layers); err != nil {
+				return err
+			}
+			if err := packageBaselines.parse(&o.PackageBaselines); err != nil {
+				return err
+			}
+			if err := gracePeriods.parse(&o.GracePeriods); err != nil {
+				return err
+			}
+			if o.Ratchet.File != "" && o.CoverageMode != gocover.FullCoverage {
+				return fmt.Errorf("%w: it gates the full coverage of the packages, not --coverage-mode %s", gocover.ErrInvalidRatchet, o.CoverageMode)
+			}
+			if o.Regression.Enabled && o.CoverageMode != gocover.DiffCoverage {
+				return fmt.Errorf("%w: it compares the files touched by the diff, not --coverage-mode %s", gocover.ErrInvalidRegression, o.CoverageMode)
+			}
+			if o.Rollout.Enabled && o.CoverageMode != gocover.DiffCoverage {
+				return fmt.Errorf("%w: it enforces the gate of the pull requests, not --coverage-mode %s", gocover.ErrInvalidRollout, o.CoverageMode)
+			}
+			if err := exemptions.load(&o.IgnoreProviders); err != nil {
+				return err
+			}
+			// the reports are uploaded first, so the comments and the messages link to them.
+			if err := reportStorage.apply(&o.ReportGenerators, &o.Annotators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.Style, o.Verbose, &o.Theme, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := resultWebhooks.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := slack.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := teams.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			gitNotes.apply(&o.ReportGenerators, o.RepositoryPath, o.Logger)
+			if err := checkRun.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := azureDevOps.apply(&o.ReportGenerators, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := bitbucket.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := gerrit.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := uploads.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.Logger); err != nil {
+				return err
+			}
+			if err := metricsExport.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, o.Logger); err != nil {
+				return err
+			}
+			if err := githubActions.apply(&o.ReportGenerators, o.RepositoryPath, o.ModuleDir, o.ModulePath, o.CoverageBaseline, cmd.ErrOrStderr(), o.Logger); err != nil {
+				return err
+			}
+			addTelemetryGenerator(&o.ReportGenerators)
 			o.StdOut = cmd.OutOrStdout()
 			o.StdErr = cmd.ErrOrStderr()
+			o.Progress.Interactive = isTerminal(o.StdOut)
 
-			ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInSeconds*time.Second)
+			ctx, cancel := commandContext()
 			defer cancel()
 
 			t, err := gocover.NewGoCoverTestExecutor(o)
@@ -236,17 +675,1111 @@ func newGoCoverTestCommand() *cobra.Command {
 
 	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profile produced by 'go test'`)
 	cmd.Flags().StringVar(&o.CompareBranch, "compare-branch", o.CompareBranch, `branch to compare`)
+	cmd.Flags().StringVar(&o.DiffTarget, "diff-target", gittool.HEADTarget, `what compares with the branch, one of: HEAD, worktree (uncommitted and untracked changes included), or a revision such as a stash like stash@{0}`)
+	cmd.Flags().BoolVar(&o.AgainstTag, "against-tag", false, "compare with the latest semver tag reachable from HEAD rather than the compare branch, the tags of a nested module are prefixed by the module directory such as modulea/v1.2.0")
 	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
 	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
-	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, "format of the diff coverage report, one of: html, json, markdown")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default, or derived from GOPATH for the repositories without go.mod")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("coverage report"))
 	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().BoolVar(&o.GitAttributes, "git-attributes", o.GitAttributes, "exclude the files marked linguist-generated or export-ignore in the .gitattributes files of the repository")
+	cmd.Flags().StringVar(&o.SkipListFile, "skip-list", "", "yaml file of the files whose coverage collection is flaky, each entry has a pattern, an owner, an expiry and a reason, the files are not counted for coverage until the entry expires and the entries are shown in the reports")
 	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", o.OutputDir, "diff coverage output directory")
+	cmd.Flags().StringVar(&o.ArtifactsDir, "output-dir", "", "directory that report.json, report.html, cobertura.xml, badge.svg and metadata.json are written into in one run, it's replaced by each run")
+	cmd.Flags().StringVar(&o.JSONOutput, "json-output", "", "file that the json document of the statistics and the coverage tree is written into in a versioned schema, - writes it to the stdout")
 	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "returns an error code if coverage or quality score is less than coverage baseline")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "fail-under", o.CoverageBaseline, "alias of --coverage-baseline, returns the low coverage exit code if the overall coverage is less than it")
+	cmd.Flags().Float64Var(&o.FileBaseline, "fail-under-file", 0, "returns the low coverage exit code if the coverage of any counted file is less than it, the files are not gated if it's 0")
+	addRatchetFlags(cmd, &o.Ratchet)
+	cmd.MarkFlagsMutuallyExclusive("coverage-baseline", "fail-under")
 	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "diff coverage report name")
 	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
 	cmd.Flags().StringVar((*string)(&o.CoverageMode), "coverage-mode", string(gocover.FullCoverage), `mode for coverage, "full" or "diff"`)
 	cmd.Flags().StringVar((*string)(&o.ExecutorMode), "executor-mode", string(gocover.GoExecutor), `unit test mode, "go" or "ginkgo"`)
 	cmd.Flags().StringSliceVar(&o.GinkgoFlags, "ginkgo-flags", []string{"-r", "-trace", "-cover", "-coverpkg=./..."}, "ginkgo flags")
-	cmd.Flags().StringSliceVar(&o.GoFlags, "go-flags", []string{}, "go flags")
+	cmd.Flags().StringSliceVar(&o.GoFlags, "go-flags", []string{}, "flags of go test with go executor, such as -race,-count=1")
+	cmd.Flags().BoolVar(&o.ChangedPackages, "changed-packages", false, "only run the tests of the packages whose go files are changed against the compare branch with go executor in diff coverage mode")
+	cmd.Flags().StringVar((*string)(&o.FailedTestPolicy), "failed-test-policy", string(o.FailedTestPolicy), "how the coverage of the packages whose tests failed is treated with go executor, one of: fail, warn, exclude")
+	cmd.Flags().StringVar(&o.Shard, "shard", "", "analyze only the packages of a shard such as 2/4, the packages are assigned to the shards by the hash of the import path, the json documents of all the shards are merged by gocover merge")
+	cmd.Flags().BoolVar(&o.Progress.Enabled, "progress", false, "render the live progress of the tests with go executor rather than the test output, the failed tests and the packages failed to build are printed as soon as they fail, and the uncovered lines of each file are printed after the coverage calculation")
+	cmd.MarkFlagsMutuallyExclusive("compare-branch", "against-tag")
+	addBypassFlags(cmd, &o.Bypass)
+	addLimitFlags(cmd, &o.Limits)
+	addSARIFFlags(cmd, &o.SARIF)
+	addThemeFlags(cmd, &o.Theme)
+	cmd.Flags().StringSliceVar(&o.SonarQubePathMappings, "sonarqube-path-map", []string{}, "map the repository relative paths of the sonarqube report to the paths that sonarqube expects, in the format of from=to such as services/api/= for the project in services/api, the first matched prefix is replaced")
+	addStrictParseFlag(cmd, &o.StrictParse)
+	addLayerFlags(cmd, layers)
+	addPackageBaselineFlags(cmd, packageBaselines)
+	addGracePeriodFlags(cmd, gracePeriods)
+	addExemptionFlags(cmd, exemptions)
+	addTestHelperFlags(cmd, &o.TestHelpers)
+	addStorageFlags(cmd, reportStorage)
+	addResultWebhookFlags(cmd, resultWebhooks)
+	addChatFlags(cmd, slack, "Slack", "mrkdwn text", "SLACK_WEBHOOK_URL")
+	addChatFlags(cmd, teams, "Microsoft Teams", "markdown text", "TEAMS_WEBHOOK_URL")
+	addGitNotesFlags(cmd, gitNotes)
+	addCheckRunFlags(cmd, checkRun)
+	addAzureDevOpsFlags(cmd, azureDevOps)
+	addBitbucketFlags(cmd, bitbucket)
+	addGerritFlags(cmd, gerrit)
+	addUploadFlags(cmd, uploads)
+	addMetricsFlags(cmd, metricsExport)
+	addGitHubActionsFlags(cmd, githubActions)
+	addTodoFlags(cmd, &o.Todos)
+	addLineMappingFlag(cmd, &o.LineMapping)
+	cmd.Flags().BoolVar(&o.DiffView, "diff-view", false, "render the annotated report as a unified diff, which has the deleted lines and the added lines colored by coverage, the diff sections are also in the json report")
+	addHunkFlags(cmd, &o.Hunks)
+	addDiffFlags(cmd, &o.DiffAlgorithm, &o.DiffContext)
+	addRegressionFlags(cmd, &o.Regression)
+	addRolloutFlags(cmd, &o.Rollout)
 	return cmd
 }
+
+// reportFormatUsage returns the usage of the --format flag of a coverage report, the formats are described in the README.
+func reportFormatUsage(report string) string {
+	return fmt.Sprintf("format of the %s, one of: %s", report, strings.Join(gocover.ReportFormats, ", "))
+}
+
+// addLimitFlags adds the flags that bound the memory used by pathological files.
+func addLimitFlags(cmd *cobra.Command, o *gocover.Limits) {
+	cmd.Flags().Int64Var(&o.MaxFileSize, "max-file-size", o.MaxFileSize, "max size in bytes of a file that counts for coverage, 0 means no limit")
+	cmd.Flags().IntVar(&o.MaxSectionLines, "max-section-lines", o.MaxSectionLines, "max source lines retained by an uncovered section in the reports, the rest lines are replaced by a truncation marker, 0 means no limit")
+	cmd.Flags().StringVar((*string)(&o.TruncationPolicy), "truncation-policy", string(o.TruncationPolicy), "how the files exceed the max file size are treated, one of: skip, fail")
+}
+
+// addTodoFlags adds the flags that count and gate the TODO and FIXME comments added in the changed lines.
+func addTodoFlags(cmd *cobra.Command, o *gocover.TodoOption) {
+	cmd.Flags().BoolVar(&o.Enabled, "todos", o.Enabled, "count the TODO and FIXME comments added in the changed lines of the go files and report them alongside the coverage")
+	cmd.Flags().IntVar(&o.MaxTodos, "max-added-todos", o.MaxTodos, "returns an error code if more TODO and FIXME comments are added with --todos, negative means no limit")
+	cmd.Flags().Float64Var(&o.MaxDensity, "max-todo-density", o.MaxDensity, "returns an error code if more TODO and FIXME comments per 100 added lines are added with --todos, negative means no limit")
+}
+
+// addTestHelperFlags adds the flags that recognize the test helper packages, whose missing tests are not reported.
+func addTestHelperFlags(cmd *cobra.Command, o *gocover.TestHelperOption) {
+	cmd.Flags().StringSliceVar(&o.Patterns, "test-helpers", o.Patterns, "doublestar patterns of the import paths of the test helper packages, such as **/testutil, their missing tests and cover profiles are not reported")
+	cmd.Flags().BoolVar(&o.Detect, "detect-test-helpers", o.Detect, "regard the packages of the module only imported by the _test.go files, or by other test helpers, as test helpers")
+}
+
+// addLineMappingFlag adds the flag that decides which changed lines make a multi-line statement changed.
+func addLineMappingFlag(cmd *cobra.Command, m *parser.LineMapping) {
+	cmd.Flags().StringVar((*string)(m), "line-mapping", string(parser.SpanLineMapping), "which changed lines make a multi-line statement changed, one of: span (any code line of the statement), first-line (the first line of the statement only, so rewrapping a statement doesn't count it)")
+}
+
+// addRatchetFlags adds the flags that gate the coverage of each package by the baseline file.
+func addRatchetFlags(cmd *cobra.Command, o *gocover.RatchetOption) {
+	cmd.Flags().StringVar(&o.File, "baseline-file", "", "baseline file written by gocover baseline write, such as "+gocover.DefaultRatchetFile+", returns the low coverage exit code if any package in it drops below its baseline")
+	cmd.Flags().Float64Var(&o.Slack, "baseline-slack", 0, "coverage percent that a package may drop below its baseline in --baseline-file")
+}
+
+// addDiffFlags adds the flags that decide how the changed lines are found and how many unchanged lines are shown around them.
+func addDiffFlags(cmd *cobra.Command, algorithm *gittool.DiffAlgorithm, context *int) {
+	cmd.Flags().StringVar((*string)(algorithm), "diff-algorithm", string(gittool.MyersDiff), "algorithm that finds the changed lines of the modified files, one of: myers, patience, histogram (as git diff --histogram), patience and histogram don't attribute the unchanged braces and blank lines around the rewritten blocks as changed")
+	cmd.Flags().IntVar(context, "diff-context", report.DefaultContextLines, "number of the unchanged lines shown around the changed lines of the annotated report and the diff view, it doesn't change which lines are counted")
+}
+
+// addThemeFlags adds the flags that decide the look of the html and the annotated reports.
+// addRegressionFlags adds the flags that compare the full coverage of the touched files with the compared branch.
+func addRegressionFlags(cmd *cobra.Command, o *gocover.RegressionOption) {
+	cmd.Flags().BoolVar(&o.Enabled, "regression", false, "calculate the full coverage of the compared branch as well, and return the low coverage exit code if the coverage of a touched file drops, even if the diff coverage passes, --cover-profile should cover the whole module")
+	cmd.Flags().StringSliceVar(&o.BaseProfiles, "base-cover-profile", []string{}, "stored coverage profile of the compared branch for --regression, the tests of the compared branch run in a temporary worktree if it's not set")
+	cmd.Flags().Float64Var(&o.Tolerance, "regression-tolerance", 0, "coverage percent that a touched file may drop with --regression")
+}
+
+// addRolloutFlags adds the flags that enforce the coverage gate on a percent of the pull requests,
+// the default key is the pull request number or the branch of the CI variables.
+func addRolloutFlags(cmd *cobra.Command, o *gocover.RolloutOption) {
+	metadata := upload.DetectMetadata(os.Getenv)
+	key := metadata.PullRequest
+	if key == "" {
+		key = metadata.Branch
+	}
+	cmd.Flags().Float64Var(&o.Percent, "rollout-percent", 100, "percent of the pull requests that the coverage gate is enforced on, the failing gate of the other pull requests is bypassed and only warns, the pull requests are chosen by the hash of --rollout-key")
+	cmd.Flags().StringVar(&o.Key, "rollout-key", key, "key that the pull request is chosen by for --rollout-percent, it's the pull request number or the branch detected from the CI variables, the current branch is used if it's empty, and it fails if HEAD is detached")
+}
+
+// addHunkFlags adds the flags that print the changed hunks after the reports, colors are disabled by default
+// if NO_COLOR environment is set.
+func addHunkFlags(cmd *cobra.Command, o *gocover.HunkOption) {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	cmd.Flags().BoolVar(&o.Enabled, "hunks", o.Enabled, "print the added lines of the changed hunks of the counted files after the reports, marked by coverage status: + covered, ! uncovered, ~ ignored")
+	cmd.Flags().BoolVar(&o.Color, "color", !noColor, "color the lines of --hunks with ANSI escape codes, uncovered lines are red, covered lines are green and ignored lines are gray")
+}
+
+func addThemeFlags(cmd *cobra.Command, o *report.ThemeSettings) {
+	cmd.Flags().StringVar(&o.Theme, "theme", report.LightTheme, "theme of the html and the annotated reports, one of: light, dark")
+	cmd.Flags().StringVar(&o.Palette, "palette", report.DefaultPalette, "palette of the covered and uncovered lines of the html and the annotated reports, one of: default (green and red), color-blind (blue and orange)")
+	cmd.Flags().StringVar(&o.CSSFile, "theme-css", "", "css file appended to the style of the html and the annotated reports, such as the colors and the fonts of a portal")
+	cmd.Flags().StringVar(&o.Logo, "logo", "", "url or image file of the logo shown in the html and the annotated reports, the image file is embedded into the report")
+}
+
+// addSARIFFlags adds the flags that decide the results of the sarif report.
+func addSARIFFlags(cmd *cobra.Command, o *report.SARIFSettings) {
+	cmd.Flags().StringVar(&o.RuleID, "sarif-rule-id", report.DefaultSARIFRuleID, "rule id of the results of the sarif report")
+	cmd.Flags().StringVar(&o.Level, "sarif-level", report.DefaultSARIFLevel, "level of the results of the sarif report, one of: error, warning, note, none")
+	cmd.Flags().StringVar(&o.Granularity, "sarif-granularity", report.SARIFLineGranularity, "results of the sarif report, line reports each uncovered line, section reports each uncovered section")
+}
+
+// layerFlags are the values of the layer flags, they are parsed when the command runs.
+type layerFlags struct {
+	layers    []string
+	baselines []string
+}
+
+// parse parses the layer flags into the layers.
+func (f *layerFlags) parse(layers *[]gocover.Layer) error {
+	result, err := gocover.ParseLayers(f.layers, f.baselines)
+	if err != nil {
+		return err
+	}
+	*layers = result
+	return nil
+}
+
+// addLayerFlags adds the flags that label the files with architecture layers.
+func addLayerFlags(cmd *cobra.Command, f *layerFlags) {
+	cmd.Flags().StringArrayVar(&f.layers, "layer", []string{}, "label the files with an architecture layer in the format of name=pattern[,pattern...], such as domain=**/domain/**, a file belongs to the first layer it matches")
+	cmd.Flags().StringArrayVar(&f.baselines, "layer-baseline", []string{}, "coverage baseline of a layer in the format of name=percent, such as domain=90")
+}
+
+// packageBaselineFlags are the values of the package baseline flags.
+type packageBaselineFlags struct {
+	baselines []string
+}
+
+// parse parses the package baseline flags into the package baselines.
+func (f *packageBaselineFlags) parse(baselines *[]gocover.PackageBaseline) error {
+	result, err := gocover.ParsePackageBaselines(f.baselines)
+	if err != nil {
+		return err
+	}
+	*baselines = result
+	return nil
+}
+
+// addPackageBaselineFlags adds the flags that gate the coverage of each package.
+func addPackageBaselineFlags(cmd *cobra.Command, f *packageBaselineFlags) {
+	cmd.Flags().StringArrayVar(&f.baselines, "package-baseline", []string{}, "coverage baseline of the packages in the format of pattern=percent, such as **/internal/auth=90, the pattern matches the import path and a package is gated by the first pattern it matches")
+}
+
+// gracePeriodFlags are the values of the grace period flags.
+type gracePeriodFlags struct {
+	periods []string
+}
+
+// parse parses the grace period flags into the grace periods.
+func (f *gracePeriodFlags) parse(periods *[]gocover.GracePeriod) error {
+	result, err := gocover.ParseGracePeriods(f.periods)
+	if err != nil {
+		return err
+	}
+	*periods = result
+	return nil
+}
+
+// addGracePeriodFlags adds the flags that let the legacy files miss the coverage gate until a date.
+func addGracePeriodFlags(cmd *cobra.Command, f *gracePeriodFlags) {
+	cmd.Flags().StringArrayVar(&f.periods, "grace-period", []string{}, "let the files miss the coverage gate until a date in the format of pattern=YYYY-MM-DD, such as example.com/foo/legacy/**=2026-12-31, the uncovered lines are still reported and the files are enforced after the date")
+}
+
+// exemptionFlags are the values of the exemption flags, the exemptions are loaded when the command runs.
+type exemptionFlags struct {
+	sources   []string
+	tokenSpec string
+}
+
+// load loads the exemptions of each source as an ignore provider.
+func (f *exemptionFlags) load(providers *[]annotation.IgnoreProvider) error {
+	if len(f.sources) == 0 {
+		return nil
+	}
+	var token credential.Provider
+	if f.tokenSpec != "" {
+		p, err := credential.NewProvider(f.tokenSpec, httpClient)
+		if err != nil {
+			return fmt.Errorf("exemptions token: %w", err)
+		}
+		token = p
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	for _, source := range f.sources {
+		provider, err := annotation.LoadIgnoreProvider(ctx, source, token, httpClient)
+		if err != nil {
+			return fmt.Errorf("load exemptions: %w", redact.Error(err))
+		}
+		*providers = append(*providers, provider)
+	}
+	return nil
+}
+
+// addExemptionFlags adds the flags that load the exemptions approved in the external systems.
+func addExemptionFlags(cmd *cobra.Command, f *exemptionFlags) {
+	cmd.Flags().StringArrayVar(&f.sources, "exemptions", []string{}, `json file or http(s) url of the exemptions in the format of {"exemptions": [{"fileName": "github.com/org/repo/foo.go", "startLine": 10, "endLine": 20, "reason": "...", "source": "..."}]}, the lines are ignored as the ignore annotations, the whole file is ignored without lines`)
+	cmd.Flags().StringVar(&f.tokenSpec, "exemptions-token", "", "credential spec of the bearer token of the exemptions urls, such as env:EXEMPTIONS_TOKEN, no token is sent if it's empty")
+}
+
+// resultWebhookFlags are the values of the result webhook flags, they are parsed when the command runs.
+type resultWebhookFlags struct {
+	urls       []string
+	secretSpec string
+}
+
+// apply adds the report generator that posts the result of the run to the result webhooks.
+func (f *resultWebhookFlags) apply(generators *[]report.ReportGenerator, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if len(f.urls) == 0 {
+		return nil
+	}
+	n, err := newNotifier(f.configs(), nil, logger)
+	if err != nil {
+		return err
+	}
+	*generators = append(*generators, notify.NewReportGenerator(n, coverageBaseline))
+	return nil
+}
+
+// configs returns the result webhooks of the flags, they share the secret.
+func (f *resultWebhookFlags) configs() []*webhook.ResultWebhookConfig {
+	configs := make([]*webhook.ResultWebhookConfig, 0, len(f.urls))
+	for _, u := range f.urls {
+		configs = append(configs, &webhook.ResultWebhookConfig{URL: u, Secret: f.secretSpec})
+	}
+	return configs
+}
+
+// gitNotesFlags are the values of the git notes flags.
+type gitNotesFlags struct {
+	enabled bool
+	ref     string
+	branch  string
+}
+
+// apply adds the report generator that saves the summary of the run as a git note on HEAD of the repository,
+// the run is saved with the branch of the flag or the checked out branch, so gocover trend follows the branch.
+func (f *gitNotesFlags) apply(generators *[]report.ReportGenerator, repositoryPath string, logger logrus.FieldLogger) {
+	if !f.enabled {
+		return
+	}
+	branch := f.branch
+	if branch == "" {
+		b, err := gittool.CurrentBranch(context.Background(), repositoryPath)
+		if err != nil {
+			logger.WithError(err).Warn("resolve the branch of the git note")
+		}
+		branch = b
+	}
+	store := history.NewGitNotesStore(gittool.NewNotes(repositoryPath, f.ref), "", "")
+	*generators = append(*generators, history.NewReportGenerator(store, &history.Run{HeadSHA: "HEAD", Branch: branch}))
+}
+
+// addGitNotesFlags adds the flags that save the runs as git notes.
+func addGitNotesFlags(cmd *cobra.Command, f *gitNotesFlags) {
+	cmd.Flags().BoolVar(&f.enabled, "git-notes", false, "save the summary of the run as a git note on HEAD, push refs/notes/{ref} to share the notes")
+	cmd.Flags().StringVar(&f.ref, "git-notes-ref", gittool.DefaultNotesRef, "notes ref that the runs are saved in, the notes are in refs/notes/{ref}")
+	cmd.Flags().StringVar(&f.branch, "git-notes-branch", upload.DetectMetadata(os.Getenv).Branch, "branch that the run is saved with in the git note, it's detected from the CI variables, the checked out branch is used if it's empty")
+}
+
+// checkRunFlags are the values of the flags that publish the run as a GitHub check run.
+type checkRunFlags struct {
+	enabled      bool
+	name         string
+	repository   string
+	headSHA      string
+	tokenSpec    string
+	githubAPIURL string
+}
+
+// apply adds the report generator that publishes the run as a check run with the annotations of the uncovered lines.
+func (f *checkRunFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	owner, repository, err := parseGitHubRepository(f.repository)
+	if err != nil {
+		return err
+	}
+	modulePath, err = gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	token, err := credential.NewProvider(f.tokenSpec, httpClient)
+	if err != nil {
+		return fmt.Errorf("github token: %w", err)
+	}
+
+	publisher := scm.NewGitHubCheckPublisher(f.githubAPIURL, token, httpClient, nil)
+	*generators = append(*generators, checks.NewReportGenerator(publisher, &checks.Option{
+		Name:             f.name,
+		Owner:            owner,
+		Repository:       repository,
+		HeadSHA:          f.headSHA,
+		RepositoryPath:   repositoryPath,
+		ModulePath:       modulePath,
+		ModuleDir:        moduleDir,
+		CoverageBaseline: coverageBaseline,
+	}, logger))
+	return nil
+}
+
+// addCheckRunFlags adds the flags that publish the run as a GitHub check run.
+func addCheckRunFlags(cmd *cobra.Command, f *checkRunFlags) {
+	cmd.Flags().BoolVar(&f.enabled, "github-check", false, "publish the run as a GitHub check run with an annotation at each uncovered line, it fails if the coverage gate fails")
+	cmd.Flags().StringVar(&f.name, "github-check-name", checks.DefaultName, "name of the GitHub check run")
+	cmd.Flags().StringVar(&f.repository, "github-repository", os.Getenv("GITHUB_REPOSITORY"), "repository of the GitHub check run and the merge queue comments in the format of owner/name, it's $GITHUB_REPOSITORY by default")
+	cmd.Flags().StringVar(&f.headSHA, "github-sha", "", "commit of the GitHub check run, such as the head sha of the pull request, HEAD of the repository is used if it's empty")
+	cmd.Flags().StringVar(&f.tokenSpec, "github-token", "env:GITHUB_TOKEN", "credential spec of the GitHub token that creates the check run, it needs the checks write permission, and the pull requests write permission for --merge-queue")
+	cmd.Flags().StringVar(&f.githubAPIURL, "github-api-url", scm.DefaultGitHubAPIURL, "GitHub api url, use https://{host}/api/v3 for GitHub Enterprise Server")
+}
+
+// parseGitHubRepository splits the GitHub repository in the format of owner/name.
+func parseGitHubRepository(s string) (string, string, error) {
+	owner, repository, ok := strings.Cut(s, "/")
+	if !ok || owner == "" || repository == "" || strings.Contains(repository, "/") {
+		return "", "", fmt.Errorf("wrong github repository '%s', the format is owner/name", s)
+	}
+	return owner, repository, nil
+}
+
+// mergeQueueFlags are the values of the flags that attribute a merge queue batch to its pull requests,
+// the repository and the token are the flags of the GitHub check run.
+type mergeQueueFlags struct {
+	github  *checkRunFlags
+	enabled bool
+	base    string
+	dryRun  bool
+}
+
+// apply adds the report generator that posts the coverage of each pull request of the batch as a comment.
+func (f *mergeQueueFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	owner, repository, err := parseGitHubRepository(f.github.repository)
+	if err != nil {
+		return err
+	}
+	modulePath, err = gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	token, err := credential.NewProvider(f.github.tokenSpec, httpClient)
+	if err != nil {
+		return fmt.Errorf("github token: %w", err)
+	}
+
+	*generators = append(*generators, mergequeue.NewReportGenerator(scm.NewGitHubClient(f.github.githubAPIURL, token, httpClient, nil), &mergequeue.Option{
+		Owner:            owner,
+		Repository:       repository,
+		Base:             f.base,
+		RepositoryPath:   repositoryPath,
+		ModulePath:       modulePath,
+		ModuleDir:        moduleDir,
+		CoverageBaseline: coverageBaseline,
+		DryRun:           f.dryRun,
+	}, logger))
+	return nil
+}
+
+// addMergeQueueFlags adds the flags of the merge queue batches.
+func addMergeQueueFlags(cmd *cobra.Command, f *mergeQueueFlags) {
+	cmd.Flags().BoolVar(&f.enabled, "merge-queue", false, "the run is a merge queue batch, such as a GitHub merge queue group or a bors batch, the changed lines are attributed to the pull requests of the batch by the commit messages and git blame, and the coverage of each pull request is posted as a comment of it")
+	cmd.Flags().StringVar(&f.base, "merge-queue-base", "", "branch that the batch merges into, the pull requests are found in the commits of {base}..HEAD, it's the compare branch by default")
+	cmd.Flags().BoolVar(&f.dryRun, "merge-queue-dry-run", false, "log the coverage of each pull request of the batch rather than posting the comments")
+}
+
+// azureDevOpsFlags are the values of the flags that publish the run to an Azure DevOps pull request.
+type azureDevOpsFlags struct {
+	enabled         bool
+	organizationURL string
+	project         string
+	repository      string
+	number          int
+	headSHA         string
+	targetURL       string
+	statusName      string
+	noComment       bool
+	tokenSpec       string
+	auth            string
+}
+
+// apply adds the report generator that sets the status of the pull request and posts the comment thread of the coverage.
+func (f *azureDevOpsFlags) apply(generators *[]report.ReportGenerator, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	if f.organizationURL == "" || f.project == "" || f.repository == "" || f.number <= 0 {
+		return errors.New("--azure-devops-url, --azure-devops-project, --azure-devops-repository and --azure-devops-pull-request are required, they are set by the pipelines of the pull requests")
+	}
+	token, err := credential.NewProvider(f.tokenSpec, httpClient)
+	if err != nil {
+		return fmt.Errorf("azure devops token: %w", err)
+	}
+	client, err := scm.NewAzureDevOpsClient(f.organizationURL, f.auth, token, httpClient, nil)
+	if err != nil {
+		return err
+	}
+	statuses, err := scm.NewAzureDevOpsStatusPublisher(f.organizationURL, f.auth, token, httpClient, nil)
+	if err != nil {
+		return err
+	}
+
+	*generators = append(*generators, prstatus.NewReportGenerator(client, statuses, &prstatus.Option{
+		Name:             f.statusName,
+		Owner:            f.project,
+		Repository:       f.repository,
+		Number:           f.number,
+		HeadSHA:          f.headSHA,
+		TargetURL:        f.targetURL,
+		CoverageBaseline: coverageBaseline,
+		NoComment:        f.noComment,
+	}, logger))
+	return nil
+}
+
+// addAzureDevOpsFlags adds the flags that publish the run to an Azure DevOps pull request,
+// the defaults are the predefined variables of Azure Pipelines.
+func addAzureDevOpsFlags(cmd *cobra.Command, f *azureDevOpsFlags) {
+	number, _ := strconv.Atoi(os.Getenv("SYSTEM_PULLREQUEST_PULLREQUESTID"))
+	targetURL := ""
+	if collection, project, build := os.Getenv("SYSTEM_COLLECTIONURI"), os.Getenv("SYSTEM_TEAMPROJECT"), os.Getenv("BUILD_BUILDID"); collection != "" && project != "" && build != "" {
+		targetURL = fmt.Sprintf("%s/%s/_build/results?buildId=%s", strings.TrimSuffix(collection, "/"), url.PathEscape(project), build)
+	}
+
+	cmd.Flags().BoolVar(&f.enabled, "azure-devops", false, "set the status of the Azure DevOps pull request, which fails if the coverage gate fails, and post a comment thread with the coverage of each file")
+	cmd.Flags().StringVar(&f.organizationURL, "azure-devops-url", os.Getenv("SYSTEM_COLLECTIONURI"), "url of the Azure DevOps organization, such as https://dev.azure.com/{organization}, it's $SYSTEM_COLLECTIONURI by default")
+	cmd.Flags().StringVar(&f.project, "azure-devops-project", os.Getenv("SYSTEM_TEAMPROJECT"), "project of the Azure DevOps repository, it's $SYSTEM_TEAMPROJECT by default")
+	cmd.Flags().StringVar(&f.repository, "azure-devops-repository", os.Getenv("BUILD_REPOSITORY_NAME"), "name or id of the Azure DevOps repository, it's $BUILD_REPOSITORY_NAME by default")
+	cmd.Flags().IntVar(&f.number, "azure-devops-pull-request", number, "id of the Azure DevOps pull request, it's $SYSTEM_PULLREQUEST_PULLREQUESTID by default")
+	cmd.Flags().StringVar(&f.headSHA, "azure-devops-sha", os.Getenv("SYSTEM_PULLREQUEST_SOURCECOMMITID"), "commit shown in the comment thread, it's $SYSTEM_PULLREQUEST_SOURCECOMMITID by default")
+	cmd.Flags().StringVar(&f.targetURL, "azure-devops-target-url", targetURL, "url that the status links to, it's the results of the build of the pipeline by default")
+	cmd.Flags().StringVar(&f.statusName, "azure-devops-status-name", prstatus.DefaultName, "name of the pull request status, its genre is gocover")
+	cmd.Flags().BoolVar(&f.noComment, "azure-devops-no-comment", false, "only set the pull request status, no comment thread is posted")
+	cmd.Flags().StringVar(&f.tokenSpec, "azure-devops-token", "env:SYSTEM_ACCESSTOKEN", "credential spec of the Azure DevOps token, such as env:SYSTEM_ACCESSTOKEN, env:AZURE_DEVOPS_PAT with --azure-devops-auth pat, or azure-pipelines-oidc:{service connection id}")
+	cmd.Flags().StringVar(&f.auth, "azure-devops-auth", scm.BearerAzureDevOpsAuth, "how the Azure DevOps token is sent, one of: bearer (the pipeline token or a Microsoft Entra token), pat (a personal access token)")
+}
+
+// bitbucketFlags are the values of the flags that publish the run as a Bitbucket Code Insights report.
+type bitbucketFlags struct {
+	enabled    bool
+	server     bool
+	apiURL     string
+	repository string
+	headSHA    string
+	reportName string
+	tokenSpec  string
+	auth       string
+}
+
+// apply adds the report generator that publishes the run as a coverage report of the commit with the annotations of the uncovered lines.
+func (f *bitbucketFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	if f.server && f.apiURL == scm.DefaultBitbucketCloudAPIURL {
+		return errors.New("--bitbucket-url is required for Bitbucket Server, such as https://bitbucket.example.com")
+	}
+	owner, repository, ok := strings.Cut(f.repository, "/")
+	if !ok || owner == "" || repository == "" || strings.Contains(repository, "/") {
+		return fmt.Errorf("wrong bitbucket repository '%s', the format is workspace/name, or project/name for Bitbucket Server", f.repository)
+	}
+	modulePath, err := gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	token, err := credential.NewProvider(f.tokenSpec, httpClient)
+	if err != nil {
+		return fmt.Errorf("bitbucket token: %w", err)
+	}
+	publisher, err := scm.NewBitbucketCheckPublisher(f.apiURL, f.server, f.auth, token, httpClient, nil)
+	if err != nil {
+		return err
+	}
+
+	*generators = append(*generators, checks.NewReportGenerator(publisher, &checks.Option{
+		Name:             f.reportName,
+		Owner:            owner,
+		Repository:       repository,
+		HeadSHA:          f.headSHA,
+		RepositoryPath:   repositoryPath,
+		ModulePath:       modulePath,
+		ModuleDir:        moduleDir,
+		CoverageBaseline: coverageBaseline,
+	}, logger))
+	return nil
+}
+
+// addBitbucketFlags adds the flags that publish the run as a Bitbucket Code Insights report, the defaults are the variables of Bitbucket Pipelines.
+func addBitbucketFlags(cmd *cobra.Command, f *bitbucketFlags) {
+	cmd.Flags().BoolVar(&f.enabled, "bitbucket", false, "publish the run as a Bitbucket Code Insights coverage report of the commit with an annotation at each uncovered line, it fails if the coverage gate fails")
+	cmd.Flags().BoolVar(&f.server, "bitbucket-server", false, "use the api of Bitbucket Server and Data Center rather than Bitbucket Cloud")
+	cmd.Flags().StringVar(&f.apiURL, "bitbucket-url", scm.DefaultBitbucketCloudAPIURL, "api url of Bitbucket Cloud, or the base url of Bitbucket Server with --bitbucket-server")
+	cmd.Flags().StringVar(&f.repository, "bitbucket-repository", os.Getenv("BITBUCKET_REPO_FULL_NAME"), "repository of the report in the format of workspace/name, or project/name for Bitbucket Server, it's $BITBUCKET_REPO_FULL_NAME by default")
+	cmd.Flags().StringVar(&f.headSHA, "bitbucket-sha", os.Getenv("BITBUCKET_COMMIT"), "commit of the report, it's $BITBUCKET_COMMIT by default, HEAD of the repository is used if it's empty")
+	cmd.Flags().StringVar(&f.reportName, "bitbucket-report-name", checks.DefaultName, "id and title of the report, a report replaces the previous report of the same name")
+	cmd.Flags().StringVar(&f.tokenSpec, "bitbucket-token", "env:BITBUCKET_TOKEN", "credential spec of the Bitbucket token, it needs the repository read permission")
+	cmd.Flags().StringVar(&f.auth, "bitbucket-auth", scm.BearerBitbucketAuth, "how the Bitbucket token is sent, one of: bearer (an access token), basic (the token is {username}:{app password})")
+}
+
+// gerritFlags are the values of the flags that post the run as a review of a Gerrit change.
+type gerritFlags struct {
+	enabled    bool
+	url        string
+	change     string
+	revision   string
+	label      string
+	passedVote int
+	failedVote int
+	robotID    string
+	targetURL  string
+	tokenSpec  string
+}
+
+// apply adds the report generator that posts the run as a review with a label vote and the robot comments of the uncovered lines.
+func (f *gerritFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	if f.url == "" {
+		return errors.New("--gerrit-url is required, such as https://review.example.com")
+	}
+	if f.change == "" {
+		return errors.New("--gerrit-change is required, such as 12345 or project~12345")
+	}
+	modulePath, err := gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	token, err := credential.NewProvider(f.tokenSpec, httpClient)
+	if err != nil {
+		return fmt.Errorf("gerrit token: %w", err)
+	}
+
+	*generators = append(*generators, review.NewReportGenerator(scm.NewGerritReviewPublisher(f.url, token, httpClient, nil), &review.Option{
+		RobotID:          f.robotID,
+		Change:           f.change,
+		Revision:         f.revision,
+		Label:            f.label,
+		PassedVote:       f.passedVote,
+		FailedVote:       f.failedVote,
+		URL:              f.targetURL,
+		ModulePath:       modulePath,
+		ModuleDir:        moduleDir,
+		CoverageBaseline: coverageBaseline,
+	}, logger))
+	return nil
+}
+
+// gerritChange returns the change of the Gerrit Trigger variables, it's empty out of a triggered build.
+func gerritChange() string {
+	number := os.Getenv("GERRIT_CHANGE_NUMBER")
+	if number == "" {
+		return ""
+	}
+	if project := os.Getenv("GERRIT_PROJECT"); project != "" {
+		return project + "~" + number
+	}
+	return number
+}
+
+// addGerritFlags adds the flags that post the run as a review of a Gerrit change, the defaults are the variables of Gerrit Trigger.
+func addGerritFlags(cmd *cobra.Command, f *gerritFlags) {
+	cmd.Flags().BoolVar(&f.enabled, "gerrit", false, "post the run as a review of the Gerrit change with a label vote and a robot comment at each uncovered line")
+	cmd.Flags().StringVar(&f.url, "gerrit-url", "", "base url of Gerrit, such as https://review.example.com")
+	cmd.Flags().StringVar(&f.change, "gerrit-change", gerritChange(), "change of the review, the change number or project~number, it's $GERRIT_PROJECT~$GERRIT_CHANGE_NUMBER by default")
+	cmd.Flags().StringVar(&f.revision, "gerrit-patchset", os.Getenv("GERRIT_PATCHSET_NUMBER"), "patch set number or commit of the review, it's $GERRIT_PATCHSET_NUMBER by default, the current patch set is used if it's empty")
+	cmd.Flags().StringVar(&f.label, "gerrit-label", review.DefaultLabel, "label voted by the review, there is no vote if it's empty")
+	cmd.Flags().IntVar(&f.passedVote, "gerrit-passed-vote", 1, "vote on the label if the coverage gate passes")
+	cmd.Flags().IntVar(&f.failedVote, "gerrit-failed-vote", -1, "vote on the label if the coverage gate fails, 0 is voted if the gate is bypassed")
+	cmd.Flags().StringVar(&f.robotID, "gerrit-robot-id", review.DefaultRobotID, "robot id of the robot comments")
+	cmd.Flags().StringVar(&f.targetURL, "gerrit-target-url", os.Getenv("BUILD_URL"), "url of the details of the run shown in the review, it's $BUILD_URL by default")
+	cmd.Flags().StringVar(&f.tokenSpec, "gerrit-token", "env:GERRIT_TOKEN", "credential spec of the Gerrit token in the format of {username}:{http password}, the user needs to vote on the label")
+}
+
+// The backends of the report storage.
+const (
+	localStorageBackend     = "local"
+	s3StorageBackend        = "s3"
+	gcsStorageBackend       = "gcs"
+	azureBlobStorageBackend = "azure-blob"
+)
+
+// storageFlags are the values of the flags that archive the reports in a storage backend.
+type storageFlags struct {
+	backend    string
+	publicURL  string
+	repository string
+	branch     string
+	commit     string
+
+	dir string
+
+	s3Bucket          string
+	s3Region          string
+	s3Endpoint        string
+	s3AccessKeyID     string
+	s3SecretAccessKey string
+	s3SessionToken    string
+
+	gcsURL       string
+	gcsBucket    string
+	gcsTokenSpec string
+
+	// azureBlob is the same as --report-storage azure-blob.
+	azureBlob    bool
+	containerURL string
+	auth         string
+	tokenSpec    string
+
+	uncoveredSince           bool
+	uncoveredSinceBaseBranch string
+}
+
+// storage creates the storage of the backend, it returns nil if no backend is selected.
+func (f *storageFlags) storage() (storage.Storage, error) {
+	backend := f.backend
+	if backend == "" && f.azureBlob {
+		backend = azureBlobStorageBackend
+	}
+	switch backend {
+	case "":
+		return nil, nil
+	case localStorageBackend:
+		if f.dir == "" {
+			return nil, errors.New("--report-storage-dir is required by the local report storage")
+		}
+		return storage.NewLocalStorage(f.dir, f.publicURL), nil
+	case s3StorageBackend:
+		if f.s3Bucket == "" {
+			return nil, errors.New("--s3-bucket is required by the s3 report storage")
+		}
+		o := &storage.S3Option{Bucket: f.s3Bucket, Region: f.s3Region, Endpoint: f.s3Endpoint, PublicURL: f.publicURL}
+		var err error
+		if o.AccessKeyID, err = credential.NewProvider(f.s3AccessKeyID, httpClient); err != nil {
+			return nil, fmt.Errorf("s3 access key id: %w", err)
+		}
+		if o.SecretAccessKey, err = credential.NewProvider(f.s3SecretAccessKey, httpClient); err != nil {
+			return nil, fmt.Errorf("s3 secret access key: %w", err)
+		}
+		if f.s3SessionToken != "" {
+			if o.SessionToken, err = credential.NewProvider(f.s3SessionToken, httpClient); err != nil {
+				return nil, fmt.Errorf("s3 session token: %w", err)
+			}
+		}
+		return storage.NewS3Storage(o, httpClient, nil), nil
+	case gcsStorageBackend:
+		if f.gcsBucket == "" {
+			return nil, errors.New("--gcs-bucket is required by the gcs report storage")
+		}
+		token, err := credential.NewProvider(f.gcsTokenSpec, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("gcs token: %w", err)
+		}
+		return storage.NewGCSStorage(f.gcsURL, f.gcsBucket, f.publicURL, token, httpClient, nil), nil
+	case azureBlobStorageBackend:
+		if f.containerURL == "" {
+			return nil, errors.New("--azure-blob-url is required, such as https://{account}.blob.core.windows.net/{container}")
+		}
+		token, err := credential.NewProvider(f.tokenSpec, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("azure blob token: %w", err)
+		}
+		return storage.NewAzureBlobStorage(f.containerURL, f.publicURL, f.auth, token, httpClient, nil)
+	default:
+		return nil, fmt.Errorf("unknown report storage %s, one of: local, s3, gcs, azure-blob", backend)
+	}
+}
+
+// apply adds the report generator that uploads the artifacts of the run, such as report.html and report.json,
+// to the storage with the key of the repository, the branch and the commit, and the annotator of the first uncovered runs
+// of the uncovered lines if it's enabled.
+func (f *storageFlags) apply(generators *[]report.ReportGenerator, annotators *[]report.Annotator, repositoryPath, moduleDir, modulePath, style string, verbose bool, theme *report.ThemeSettings, coverageBaseline float64, logger logrus.FieldLogger) error {
+	s, err := f.storage()
+	if err != nil || s == nil {
+		return err
+	}
+	modulePath, err = gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	t, err := theme.Load()
+	if err != nil {
+		return err
+	}
+
+	if f.uncoveredSince {
+		*annotators = append(*annotators, storage.NewUncoveredSinceAnnotator(s, &storage.SinceOption{
+			Repository:     f.repository,
+			Branch:         f.branch,
+			BaseBranch:     f.uncoveredSinceBaseBranch,
+			Commit:         f.commit,
+			RepositoryPath: repositoryPath,
+		}, logger))
+	}
+	*generators = append(*generators, storage.NewReportGenerator(s, &storage.Option{
+		Repository:     f.repository,
+		Branch:         f.branch,
+		Commit:         f.commit,
+		RepositoryPath: repositoryPath,
+		Artifacts: report.ArtifactsOption{
+			Style:            style,
+			Verbose:          verbose,
+			ModulePath:       modulePath,
+			CoverageBaseline: coverageBaseline,
+			Theme:            t,
+		},
+	}, logger))
+	return nil
+}
+
+// baseBranch returns the target branch of the pull request of the CI services, it's empty if the build isn't of a pull request.
+func baseBranch(getenv func(string) string) string {
+	for _, name := range []string{"GITHUB_BASE_REF", "SYSTEM_PULLREQUEST_TARGETBRANCH", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "CHANGE_TARGET"} {
+		if branch := getenv(name); branch != "" {
+			return strings.TrimPrefix(branch, "refs/heads/")
+		}
+	}
+	return ""
+}
+
+// addStorageFlags adds the flags that archive the reports in a storage backend,
+// the defaults of the repository, the branch and the commit are the predefined variables of the CI services.
+func addStorageFlags(cmd *cobra.Command, f *storageFlags) {
+	metadata := upload.DetectMetadata(os.Getenv)
+	cmd.Flags().StringVar(&f.backend, "report-storage", "", "upload report.html, report.json and the other artifacts to the storage at {repository}/{branch}/{commit}/, the comments and the messages link to the html report, one of: local, s3, gcs, azure-blob")
+	cmd.Flags().StringVar(&f.publicURL, "report-storage-public-url", "", "url that the reports are read from, such as a static website or a CDN of the storage, it's the url of the bucket or the container by default")
+	cmd.Flags().StringVar(&f.repository, "report-storage-repository", metadata.Slug, "repository of the key of the reports in the format of owner/name, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.branch, "report-storage-branch", metadata.Branch, "branch of the key of the reports, it's detected from the CI variables, the checked out branch is used if it's empty")
+	cmd.Flags().StringVar(&f.commit, "report-storage-commit", metadata.Commit, "commit of the key of the reports, it's detected from the CI variables, HEAD of the repository is used if it's empty")
+
+	cmd.Flags().StringVar(&f.dir, "report-storage-dir", "", "directory of the local report storage, such as a mounted network share")
+	cmd.Flags().BoolVar(&f.uncoveredSince, "uncovered-since", false, "annotate each uncovered line with the commit and the time of the first run that it's uncovered in, by the history of the branch in the report storage, so the fresh regressions stand out from the long-standing debt")
+	cmd.Flags().StringVar(&f.uncoveredSinceBaseBranch, "uncovered-since-base-branch", baseBranch(os.Getenv), "branch that the history of the uncovered lines is read from if the branch has no history yet, such as the target branch of a pull request, it's detected from the CI variables")
+
+	cmd.Flags().StringVar(&f.s3Bucket, "s3-bucket", "", "bucket of the s3 report storage")
+	cmd.Flags().StringVar(&f.s3Region, "s3-region", os.Getenv("AWS_REGION"), "region of the s3 bucket, it's us-east-1 if it's empty")
+	cmd.Flags().StringVar(&f.s3Endpoint, "s3-endpoint", "", "url of an S3 compatible service, such as MinIO, the bucket is in the path of the url, it's the virtual-hosted url of AWS by default")
+	cmd.Flags().StringVar(&f.s3AccessKeyID, "s3-access-key-id", "env:AWS_ACCESS_KEY_ID", "credential spec of the access key id, it needs s3:PutObject, s3:GetObject and s3:ListBucket of the bucket")
+	cmd.Flags().StringVar(&f.s3SecretAccessKey, "s3-secret-access-key", "env:AWS_SECRET_ACCESS_KEY", "credential spec of the secret access key")
+	sessionToken := ""
+	if os.Getenv("AWS_SESSION_TOKEN") != "" {
+		sessionToken = "env:AWS_SESSION_TOKEN"
+	}
+	cmd.Flags().StringVar(&f.s3SessionToken, "s3-session-token", sessionToken, "credential spec of the session token of the temporary credentials, it's env:AWS_SESSION_TOKEN if the environment is set")
+
+	cmd.Flags().StringVar(&f.gcsBucket, "gcs-bucket", "", "bucket of the gcs report storage")
+	cmd.Flags().StringVar(&f.gcsURL, "gcs-url", storage.DefaultGCSURL, "url of the json api of Google Cloud Storage")
+	cmd.Flags().StringVar(&f.gcsTokenSpec, "gcs-token", "env:GOOGLE_OAUTH_ACCESS_TOKEN", "credential spec of the OAuth 2.0 access token, such as the output of gcloud auth print-access-token, the principal needs the Storage Object User role")
+
+	cmd.Flags().BoolVar(&f.azureBlob, "azure-blob", false, "the same as --report-storage azure-blob")
+	cmd.Flags().StringVar(&f.containerURL, "azure-blob-url", "", "url of the container, such as https://{account}.blob.core.windows.net/{container}")
+	cmd.Flags().StringVar(&f.auth, "azure-blob-auth", storage.SASAzureBlobAuth, "how the token is sent, one of: sas (a shared access signature with the create and write permissions), bearer (a Microsoft Entra token, such as azure-msi:https://storage.azure.com/)")
+	cmd.Flags().StringVar(&f.tokenSpec, "azure-blob-token", "env:AZURE_STORAGE_SAS_TOKEN", "credential spec of the shared access signature, or the Microsoft Entra token with --azure-blob-auth bearer")
+
+	// the flags of the azure blob storage before the other backends.
+	cmd.Flags().StringVar(&f.publicURL, "azure-blob-public-url", "", "url that the reports are read from")
+	cmd.Flags().StringVar(&f.repository, "azure-blob-repository", metadata.Slug, "repository of the key of the reports")
+	cmd.Flags().StringVar(&f.branch, "azure-blob-branch", metadata.Branch, "branch of the key of the reports")
+	cmd.Flags().StringVar(&f.commit, "azure-blob-commit", metadata.Commit, "commit of the key of the reports")
+	for _, name := range []string{"azure-blob-public-url", "azure-blob-repository", "azure-blob-branch", "azure-blob-commit"} {
+		cmd.Flags().MarkDeprecated(name, "use --"+strings.Replace(name, "azure-blob", "report-storage", 1)+" instead")
+	}
+}
+
+// uploadFlags are the values of the flags that upload the coverage to Codecov and Coveralls.
+type uploadFlags struct {
+	codecov           bool
+	codecovURL        string
+	codecovToken      string
+	coveralls         bool
+	coverallsURL      string
+	coverallsToken    string
+	coverallsParallel bool
+	metadata          upload.Metadata
+}
+
+// apply adds a report generator of each enabled coverage service, which uploads the coverage with the commit and the branch.
+func (f *uploadFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, logger logrus.FieldLogger) error {
+	if !f.codecov && !f.coveralls {
+		return nil
+	}
+	modulePath, err := gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	o := &upload.Option{
+		RepositoryPath: repositoryPath,
+		ModulePath:     modulePath,
+		ModuleDir:      moduleDir,
+		Metadata:       f.metadata,
+	}
+
+	if f.codecov {
+		var token credential.Provider
+		// the uploads of the public repositories don't need a token.
+		if f.codecovToken != "" {
+			if token, err = credential.NewProvider(f.codecovToken, httpClient); err != nil {
+				return fmt.Errorf("codecov token: %w", err)
+			}
+		}
+		*generators = append(*generators, upload.NewReportGenerator(upload.NewCodecovUploader(f.codecovURL, token, httpClient, nil), o, logger))
+	}
+	if f.coveralls {
+		token, err := credential.NewProvider(f.coverallsToken, httpClient)
+		if err != nil {
+			return fmt.Errorf("coveralls token: %w", err)
+		}
+		*generators = append(*generators, upload.NewReportGenerator(upload.NewCoverallsUploader(f.coverallsURL, f.coverallsParallel, token, httpClient, nil), o, logger))
+	}
+	return nil
+}
+
+// addUploadFlags adds the flags that upload the coverage to Codecov and Coveralls,
+// the defaults of the metadata are the predefined variables of GitHub Actions, Azure Pipelines, GitLab CI and Jenkins.
+func addUploadFlags(cmd *cobra.Command, f *uploadFlags) {
+	metadata := upload.DetectMetadata(os.Getenv)
+	cmd.Flags().BoolVar(&f.codecov, "codecov", false, "upload the coverage to Codecov as a lcov tracefile, the coverage of a diff run only has the changed lines")
+	cmd.Flags().StringVar(&f.codecovURL, "codecov-url", upload.DefaultCodecovURL, "url of Codecov, or the self-hosted Codecov")
+	cmd.Flags().StringVar(&f.codecovToken, "codecov-token", "env:CODECOV_TOKEN", "credential spec of the upload token of the repository, it can be empty for the public repositories")
+	cmd.Flags().BoolVar(&f.coveralls, "coveralls", false, "upload the coverage to Coveralls as a job, the coverage of a diff run only has the changed lines")
+	cmd.Flags().StringVar(&f.coverallsURL, "coveralls-url", upload.DefaultCoverallsURL, "url of Coveralls, or Coveralls Enterprise")
+	cmd.Flags().StringVar(&f.coverallsToken, "coveralls-token", "env:COVERALLS_REPO_TOKEN", "credential spec of the repo token of the repository, such as env:GITHUB_TOKEN on GitHub Actions")
+	cmd.Flags().BoolVar(&f.coverallsParallel, "coveralls-parallel", false, "upload the job to a parallel build, which is shown when it's closed by the parallel webhook of Coveralls")
+	cmd.Flags().StringVar(&f.metadata.Commit, "upload-commit", metadata.Commit, "commit of the uploaded coverage, it's detected from the CI variables, HEAD of the repository is used if it's empty")
+	cmd.Flags().StringVar(&f.metadata.Branch, "upload-branch", metadata.Branch, "branch of the uploaded coverage, it's detected from the CI variables, the checked out branch is used if it's empty")
+	cmd.Flags().StringVar(&f.metadata.PullRequest, "upload-pull-request", metadata.PullRequest, "number of the pull request of the uploaded coverage, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.metadata.Slug, "upload-slug", metadata.Slug, "repository of the uploaded coverage in the format of owner/name, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.metadata.Service, "upload-service", metadata.Service, "CI service of the build, such as github-actions, azure_pipelines, gitlab or jenkins, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.metadata.Build, "upload-build", metadata.Build, "number or id of the build, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.metadata.BuildURL, "upload-build-url", metadata.BuildURL, "url of the build, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.metadata.Job, "upload-job", metadata.Job, "id of the job of the build, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.metadata.Flag, "upload-flag", "", "flag of the uploaded coverage, such as unit, which separates the uploads of the same commit")
+}
+
+// metricsFlags are the values of the flags that export the coverage as Prometheus metrics.
+type metricsFlags struct {
+	file             string
+	pushgatewayURL   string
+	pushgatewayJob   string
+	pushgatewayToken string
+	repository       string
+	branch           string
+}
+
+// apply adds a report generator of the metrics file and of the Pushgateway if they're set, the metrics are labeled
+// by the repository, the branch and the module.
+func (f *metricsFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if f.file == "" && f.pushgatewayURL == "" {
+		return nil
+	}
+	modulePath, err := gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	o := &metrics.Option{
+		RepositoryPath:   repositoryPath,
+		Repository:       f.repository,
+		Branch:           f.branch,
+		Module:           modulePath,
+		CoverageBaseline: coverageBaseline,
+	}
+
+	if f.file != "" {
+		*generators = append(*generators, metrics.NewReportGenerator(metrics.NewFileExporter(f.file), o, logger))
+	}
+	if f.pushgatewayURL != "" {
+		var token credential.Provider
+		if f.pushgatewayToken != "" {
+			if token, err = credential.NewProvider(f.pushgatewayToken, httpClient); err != nil {
+				return fmt.Errorf("pushgateway token: %w", err)
+			}
+		}
+		exporter := metrics.NewPushgatewayExporter(f.pushgatewayURL, f.pushgatewayJob, token, httpClient, nil)
+		*generators = append(*generators, metrics.NewReportGenerator(exporter, o, logger))
+	}
+	return nil
+}
+
+// addMetricsFlags adds the flags that export the coverage as Prometheus metrics,
+// the defaults of the labels are the predefined variables of the CI services.
+func addMetricsFlags(cmd *cobra.Command, f *metricsFlags) {
+	metadata := upload.DetectMetadata(os.Getenv)
+	cmd.Flags().StringVar(&f.file, "metrics-file", "", "OpenMetrics text file that the coverage metrics are written into, such as a .prom file of the textfile collector of the node exporter")
+	cmd.Flags().StringVar(&f.pushgatewayURL, "pushgateway-url", "", "url of the Prometheus Pushgateway that the coverage metrics are pushed to, each module of each branch is a group that is replaced by the push")
+	cmd.Flags().StringVar(&f.pushgatewayJob, "pushgateway-job", metrics.DefaultPushgatewayJob, "job label of the metrics pushed to the Pushgateway")
+	cmd.Flags().StringVar(&f.pushgatewayToken, "pushgateway-token", "", "credential spec of the bearer token of the Pushgateway, such as env:PUSHGATEWAY_TOKEN, no token is sent if it's empty")
+	cmd.Flags().StringVar(&f.repository, "metrics-repository", metadata.Slug, "repository label of the metrics in the format of owner/name, it's detected from the CI variables")
+	cmd.Flags().StringVar(&f.branch, "metrics-branch", metadata.Branch, "branch label of the metrics, it's detected from the CI variables, the checked out branch is used if it's empty")
+}
+
+// githubActionsFlags are the values of the flags that write the native output of GitHub Actions.
+type githubActionsFlags struct {
+	enabled        bool
+	maxAnnotations int
+}
+
+// apply adds the report generator of GitHub Actions if it's enabled, the workflow commands are written to w,
+// and the outputs and the summary are appended to the files of $GITHUB_OUTPUT and $GITHUB_STEP_SUMMARY.
+func (f *githubActionsFlags) apply(generators *[]report.ReportGenerator, repositoryPath, moduleDir, modulePath string, coverageBaseline float64, w io.Writer, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	modulePath, err := gocover.ModulePath(filepath.Join(repositoryPath, moduleDir), modulePath)
+	if err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	*generators = append(*generators, actions.NewReportGenerator(w, &actions.Option{
+		ModulePath:       modulePath,
+		ModuleDir:        moduleDir,
+		CoverageBaseline: coverageBaseline,
+		MaxAnnotations:   f.maxAnnotations,
+		OutputFile:       os.Getenv("GITHUB_OUTPUT"),
+		SummaryFile:      os.Getenv("GITHUB_STEP_SUMMARY"),
+	}, logger))
+	return nil
+}
+
+// addGitHubActionsFlags adds the flags of the native output of GitHub Actions, it's enabled in the GitHub Actions runners.
+func addGitHubActionsFlags(cmd *cobra.Command, f *githubActionsFlags) {
+	cmd.Flags().BoolVar(&f.enabled, "github-actions", os.Getenv("GITHUB_ACTIONS") == "true", "write the workflow commands that annotate the uncovered lines, the coverage_percent and violation_count outputs to $GITHUB_OUTPUT, and the markdown summary to $GITHUB_STEP_SUMMARY, it's enabled in the GitHub Actions runners")
+	cmd.Flags().IntVar(&f.maxAnnotations, "github-actions-max-annotations", actions.DefaultMaxAnnotations, "max annotations of the uncovered lines, the rest are counted in a notice, GitHub shows up to 10 annotations of each level of a step")
+}
+
+// historyFlags are the values of the flags that locate the stored runs, in a directory or in the git notes of a repository.
+type historyFlags struct {
+	dir      string
+	notesDir string
+	notesRef string
+}
+
+// open opens the store of the runs, the runs in the git notes belong to the repository.
+func (f *historyFlags) open(owner, repository string) (history.Store, error) {
+	switch {
+	case f.notesDir != "":
+		return history.NewGitNotesStore(gittool.NewNotes(f.notesDir, f.notesRef), owner, repository), nil
+	case f.dir != "":
+		return history.NewFileStore(f.dir)
+	}
+	return nil, errors.New("either --history-dir or --history-git-notes is required")
+}
+
+// addHistoryFlags adds the flags that locate the stored runs.
+func addHistoryFlags(cmd *cobra.Command, f *historyFlags) {
+	cmd.Flags().StringVar(&f.dir, "history-dir", "", "directory of the runs stored by the webhook server")
+	cmd.Flags().StringVar(&f.notesDir, "history-git-notes", "", "repository whose git notes store the runs saved by --git-notes, fetch refs/notes/{ref} before")
+	cmd.Flags().StringVar(&f.notesRef, "git-notes-ref", gittool.DefaultNotesRef, "notes ref that the runs are read from, the notes are in refs/notes/{ref}")
+	cmd.MarkFlagsMutuallyExclusive("history-dir", "history-git-notes")
+}
+
+// newNotifier creates the notifier of the result webhooks, it's nil if there is no result webhook.
+func newNotifier(configs []*webhook.ResultWebhookConfig, recorder audit.Recorder, logger logrus.FieldLogger) (*notify.Notifier, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	endpoints := make([]*notify.Endpoint, 0, len(configs))
+	for _, c := range configs {
+		e := &notify.Endpoint{URL: c.URL}
+		if c.Secret != "" {
+			p, err := credential.NewProvider(c.Secret, httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("result webhook secret: %w", err)
+			}
+			e.Secret = p
+		}
+		endpoints = append(endpoints, e)
+	}
+	return notify.NewNotifier(endpoints, httpClient, recorder, logger)
+}
+
+// addResultWebhookFlags adds the flags that post the result of the run to the outbound webhooks.
+func addResultWebhookFlags(cmd *cobra.Command, f *resultWebhookFlags) {
+	cmd.Flags().StringSliceVar(&f.urls, "result-webhook", []string{}, "url that the result of the run is posted to as a run.completed event, the failed deliveries are logged and don't fail the command")
+	cmd.Flags().StringVar(&f.secretSpec, "result-webhook-secret", "", "credential spec of the secret that signs the result webhook payloads in the X-Gocover-Signature-256 header, such as env:GOCOVER_RESULT_WEBHOOK_SECRET")
+}
+
+// chatFlags are the values of the flags that post the summary of the run to an incoming webhook of a chat, such as Slack or Teams.
+type chatFlags struct {
+	// chat is the name of the chat, it prefixes the flags.
+	chat         string
+	enabled      bool
+	webhookSpec  string
+	templateFile string
+	worstFiles   int
+	reportURL    string
+}
+
+// apply adds the report generator that posts the message of the run to the incoming webhook of the chat.
+func (f *chatFlags) apply(generators *[]report.ReportGenerator, coverageBaseline float64, logger logrus.FieldLogger) error {
+	if !f.enabled {
+		return nil
+	}
+	var text string
+	if f.templateFile != "" {
+		data, err := os.ReadFile(f.templateFile)
+		if err != nil {
+			return fmt.Errorf("read %s template: %w", f.chat, err)
+		}
+		text = string(data)
+	}
+	// the url of an incoming webhook is the secret itself, so it's a credential rather than a plain url.
+	provider, err := credential.NewProvider(f.webhookSpec, httpClient)
+	if err != nil {
+		return fmt.Errorf("%s webhook: %w", f.chat, err)
+	}
+	webhookURL, err := provider.Token(context.Background())
+	if err != nil {
+		return fmt.Errorf("%s webhook: %w", f.chat, err)
+	}
+	n, err := notify.NewNotifier([]*notify.Endpoint{{URL: webhookURL, Name: f.chat}}, httpClient, nil, logger)
+	if err != nil {
+		return err
+	}
+
+	o := &notify.SummaryOption{
+		Template:         text,
+		WorstFiles:       f.worstFiles,
+		ReportURL:        f.reportURL,
+		CoverageBaseline: coverageBaseline,
+	}
+	newGenerator := notify.NewSlackReportGenerator
+	if f.chat == teamsChat {
+		newGenerator = notify.NewTeamsReportGenerator
+	}
+	g, err := newGenerator(n, o)
+	if err != nil {
+		return err
+	}
+	*generators = append(*generators, g)
+	return nil
+}
+
+// The chats that the summaries are posted to.
+const (
+	slackChat = "slack"
+	teamsChat = "teams"
+)
+
+// addChatFlags adds the flags that post the summary of the run to an incoming webhook of the chat, the url of the webhook
+// is read from the environment variable by default.
+func addChatFlags(cmd *cobra.Command, f *chatFlags, title, markup, env string) {
+	cmd.Flags().BoolVar(&f.enabled, f.chat, false, fmt.Sprintf("post the coverage, the files with the lowest coverage and the link of the full report to a %s incoming webhook, the failed deliveries are logged and don't fail the command", title))
+	cmd.Flags().StringVar(&f.webhookSpec, f.chat+"-webhook", "env:"+env, fmt.Sprintf("credential spec of the url of the %s incoming webhook", title))
+	cmd.Flags().StringVar(&f.templateFile, f.chat+"-template", "", fmt.Sprintf("file of the text/template of the %s of the message, the data is notify.Summary, the built-in template is used if it's empty", markup))
+	cmd.Flags().IntVar(&f.worstFiles, f.chat+"-worst-files", notify.DefaultWorstFiles, "number of the files with the lowest coverage in the message, no file is listed if it's negative")
+	cmd.Flags().StringVar(&f.reportURL, f.chat+"-report-url", "", "url of the full report that the message links to, such as the artifacts of the build")
+}
+
+// addStrictParseFlag adds the flag that aborts the run on the first file that fails to parse.
+func addStrictParseFlag(cmd *cobra.Command, strict *bool) {
+	cmd.Flags().BoolVar(strict, "strict-parse", false, "abort on the first file that fails to parse, otherwise the file is reported and the run continues")
+}
+
+// addBypassFlags adds the flags that allow bypassing a failing coverage gate.
+func addBypassFlags(cmd *cobra.Command, o *gocover.BypassOption) {
+	cmd.Flags().StringVar(&o.Label, "bypass-label", "", "pull request label that downgrades a failing coverage gate to neutral, disabled if it's empty")
+	cmd.Flags().StringSliceVar(&o.Labels, "pr-labels", []string{}, "labels of the pull request")
+	cmd.Flags().StringArrayVar(&o.Comments, "pr-comments", []string{}, "comments of the pull request in the format of login:comment, '/gocover skip reason=...' of --bypass-approvers downgrades a failing coverage gate to neutral")
+	cmd.Flags().StringVar(&o.EventPath, "event-path", "", "GitHub event payload file that labels and comment of the pull request are read from, such as $GITHUB_EVENT_PATH")
+	cmd.Flags().StringSliceVar(&o.Approvers, "bypass-approvers", []string{}, "logins whose skip command is accepted, besides the owners, members and collaborators of the comment of --event-path")
+	cmd.Flags().StringVar(&o.Author, "pr-author", "", "login of the pull request author, whose skip command is never accepted, read from --event-path if it's empty")
+}
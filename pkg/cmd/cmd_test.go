@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gocover"
+)
+
+func TestCommandContextTimeout(t *testing.T) {
+	defer func(timeout int) { timeoutInSeconds = timeout }(timeoutInSeconds)
+
+	root := NewGoCoverCommand("", "", "")
+	for i, name := range []string{"diff", "full", "test"} {
+		t.Run(name, func(t *testing.T) {
+			cmd, _, err := root.Find([]string{name})
+			if err != nil {
+				t.Fatal(err)
+			}
+			timeout := 100 + i
+			if err := cmd.ParseFlags([]string{"--timeout", strconv.Itoa(timeout)}); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := commandContext()
+			defer cancel()
+			deadline, ok := ctx.Deadline()
+			if remaining := time.Until(deadline); !ok || remaining > time.Duration(timeout)*time.Second || remaining < time.Duration(timeout-1)*time.Second {
+				t.Errorf("expect the %s command is canceled after %d seconds of --timeout, but get %s", name, timeout, remaining)
+			}
+		})
+	}
+}
+
+func TestReportFormatUsage(t *testing.T) {
+	root := NewGoCoverCommand("", "", "")
+	formats := "one of: " + strings.Join(gocover.ReportFormats, ", ")
+	for _, name := range []string{"diff", "full", "test", "merge", "watch", "bisect", "matrix"} {
+		cmd, _, err := root.Find([]string{name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		flag := cmd.Flags().Lookup("format")
+		if flag == nil || !strings.HasSuffix(flag.Usage, formats) {
+			t.Errorf("expect the usage of --format of %s ends with %q, but get %v", name, formats, flag)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidConfig = errors.New("invalid config file")
+
+// defaultConfigFiles are the config files looked up in the root directory of the repository in order.
+var defaultConfigFiles = []string{".gocover.yml", ".gocover.yaml"}
+
+// commandsConfigKey is the key of the settings of each command, which override the settings of all the commands.
+const commandsConfigKey = "commands"
+
+var configFile string
+
+// config is the settings of the config file, the keys are the flag names, such as compare-branch and coverage-baseline.
+// The settings of all the commands are at the top level, and the settings of a command are under commands by the path
+// of the command, such as full or daemon query. A setting of a flag that the running command doesn't have is ignored.
+type config struct {
+	file     string
+	settings []*configSetting
+	commands map[string][]*configSetting
+}
+
+// configSetting is the value of a flag in the config file.
+type configSetting struct {
+	flag   string
+	values []string
+	// list indicates the value is a sequence or a mapping, which is only valid for the list flags.
+	list bool
+	line int
+}
+
+// findConfigFile returns the config file of --config, or the default config file in the repository root
+// of --repository-path if the command has it. It's empty if there is no default config file.
+func findConfigFile(cmd *cobra.Command) (string, error) {
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+		return configFile, nil
+	}
+	root := "./"
+	if f := cmd.Flags().Lookup("repository-path"); f != nil && f.Changed {
+		root = f.Value.String()
+	}
+	for _, name := range defaultConfigFiles {
+		file := filepath.Join(root, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", nil
+}
+
+// applyConfigFile sets the flags of the command from the config file if there is one.
+func applyConfigFile(cmd *cobra.Command) error {
+	file, err := findConfigFile(cmd)
+	if err != nil || file == "" {
+		return err
+	}
+	c, err := loadConfig(file)
+	if err != nil {
+		return err
+	}
+	if err := c.validate(cmd.Root()); err != nil {
+		return err
+	}
+	return c.apply(cmd)
+}
+
+// loadConfig reads the config file, the settings keep the order of the file.
+func loadConfig(file string) (*config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	c := &config{file: file, commands: make(map[string][]*configSetting)}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrInvalidConfig, file, err)
+	}
+	if len(document.Content) == 0 {
+		return c, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w %s: line %d: expect a mapping of the flags", ErrInvalidConfig, file, root.Line)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != commandsConfigKey {
+			setting, err := c.parseSetting(key, value)
+			if err != nil {
+				return nil, err
+			}
+			c.settings = append(c.settings, setting)
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%w %s: line %d: expect a mapping of the commands", ErrInvalidConfig, file, value.Line)
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			command, flags := value.Content[j], value.Content[j+1]
+			if flags.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%w %s: line %d: expect a mapping of the flags of %s", ErrInvalidConfig, file, flags.Line, command.Value)
+			}
+			for k := 0; k+1 < len(flags.Content); k += 2 {
+				setting, err := c.parseSetting(flags.Content[k], flags.Content[k+1])
+				if err != nil {
+					return nil, err
+				}
+				c.commands[command.Value] = append(c.commands[command.Value], setting)
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseSetting parses the value of a flag: a scalar, a sequence of the scalars, or a mapping whose entries are
+// in the format of key=value, such as the package baselines, a sequence value of an entry is joined by commas.
+func (c *config) parseSetting(key, value *yaml.Node) (*configSetting, error) {
+	setting := &configSetting{flag: key.Value, line: key.Line}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		setting.values = []string{value.Value}
+	case yaml.SequenceNode:
+		setting.list = true
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%w %s: line %d: expect a list of the values of %s", ErrInvalidConfig, c.file, item.Line, key.Value)
+			}
+			setting.values = append(setting.values, item.Value)
+		}
+	case yaml.MappingNode:
+		setting.list = true
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			name, item := value.Content[i], value.Content[i+1]
+			var parts []string
+			if item.Kind == yaml.SequenceNode {
+				for _, part := range item.Content {
+					parts = append(parts, part.Value)
+				}
+			} else {
+				parts = []string{item.Value}
+			}
+			setting.values = append(setting.values, name.Value+"="+strings.Join(parts, ","))
+		}
+	default:
+		return nil, fmt.Errorf("%w %s: line %d: unsupported value of %s", ErrInvalidConfig, c.file, value.Line, key.Value)
+	}
+	return setting, nil
+}
+
+// validate checks the flags and the commands of the settings exist, so a typo doesn't go unnoticed.
+func (c *config) validate(root *cobra.Command) error {
+	flags := make(map[string]bool)
+	commands := make(map[string]*cobra.Command)
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		commands[strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), root.Name()), " ")] = cmd
+		for _, set := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags()} {
+			set.VisitAll(func(f *pflag.Flag) {
+				flags[f.Name] = true
+			})
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+
+	check := func(settings []*configSetting) error {
+		for _, s := range settings {
+			if s.flag == "config" || !flags[s.flag] {
+				return fmt.Errorf("%w %s: line %d: unknown flag %s", ErrInvalidConfig, c.file, s.line, s.flag)
+			}
+		}
+		return nil
+	}
+	if err := check(c.settings); err != nil {
+		return err
+	}
+	for name, settings := range c.commands {
+		if _, ok := commands[name]; !ok || name == "" {
+			return fmt.Errorf("%w %s: unknown command %q", ErrInvalidConfig, c.file, name)
+		}
+		if err := check(settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply sets the flags of the command from the settings of the command and of all the commands.
+// The flags set on the command line or by the environment variables take precedence, they're the flags
+// that are changed or are not the defaults, such as --coverage-baseline set by its alias --fail-under.
+// The required flags set by the config file are regarded as set, such as --cover-profile.
+func (c *config) apply(cmd *cobra.Command) error {
+	commandPath := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+	overridden := make(map[string]bool)
+	for _, s := range c.commands[commandPath] {
+		overridden[s.flag] = true
+	}
+	settings := append([]*configSetting{}, c.commands[commandPath]...)
+	for _, s := range c.settings {
+		if !overridden[s.flag] {
+			settings = append(settings, s)
+		}
+	}
+
+	for _, s := range settings {
+		f := cmd.Flags().Lookup(s.flag)
+		if f == nil || f.Changed || f.Value.String() != f.DefValue {
+			continue
+		}
+		if err := setConfigValue(f, s); err != nil {
+			return fmt.Errorf("%w %s: line %d: set --%s: %s", ErrInvalidConfig, c.file, s.line, s.flag, err)
+		}
+		if required, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok && len(required) != 0 && required[0] == "true" {
+			f.Changed = true
+		}
+	}
+	return nil
+}
+
+// setConfigValue sets the value of the setting, a list replaces the defaults of a list flag,
+// and a scalar of a list flag is comma separated as the environment variables.
+func setConfigValue(f *pflag.Flag, s *configSetting) error {
+	if !s.list {
+		return setFlagValue(f, s.values[0])
+	}
+	slice, ok := f.Value.(pflag.SliceValue)
+	if !ok {
+		return errors.New("it isn't a list flag")
+	}
+	return slice.Replace(s.values)
+}
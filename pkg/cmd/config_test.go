@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+type configTestOption struct {
+	compareBranch    string
+	coverageBaseline float64
+	fileBaseline     float64
+	coverProfiles    []string
+	excludes         []string
+	packageBaselines []string
+	layers           []string
+	githubCheck      bool
+	githubToken      string
+}
+
+func newConfigTestCommand(o *configTestOption) (*cobra.Command, *cobra.Command) {
+	root := &cobra.Command{Use: "gocover"}
+	diff := &cobra.Command{Use: "diff", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+	diff.Flags().StringVar(&o.compareBranch, "compare-branch", "origin/master", "")
+	diff.Flags().Float64Var(&o.coverageBaseline, "coverage-baseline", 0, "")
+	diff.Flags().Float64Var(&o.coverageBaseline, "fail-under", 0, "")
+	diff.Flags().Float64Var(&o.fileBaseline, "fail-under-file", 0, "")
+	diff.Flags().StringSliceVar(&o.coverProfiles, "cover-profile", []string{}, "")
+	diff.Flags().StringSliceVar(&o.excludes, "excludes", []string{}, "")
+	diff.Flags().StringArrayVar(&o.packageBaselines, "package-baseline", []string{}, "")
+	diff.Flags().StringArrayVar(&o.layers, "layer", []string{}, "")
+	diff.Flags().BoolVar(&o.githubCheck, "github-check", false, "")
+	diff.Flags().StringVar(&o.githubToken, "github-token", "", "")
+	diff.Flags().String("repository-path", "./", "")
+	diff.MarkFlagRequired("cover-profile")
+	full := &cobra.Command{Use: "full", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+	full.Flags().Float64("coverage-baseline", 0, "")
+	root.AddCommand(diff, full)
+	return root, diff
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), ".gocover.yml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestConfigApply(t *testing.T) {
+	file := writeConfig(t, `
+compare-branch: origin/main
+coverage-baseline: 80
+fail-under-file: 50
+cover-profile: coverage.out
+excludes:
+  - "**/mock/**"
+  - "**/zz_generated.go"
+package-baseline:
+  "**/internal/auth": 95
+  "github.com/Azure/foo/**": 60
+layer:
+  domain: ["**/domain/**", "**/model/**"]
+github-check: true
+commands:
+  diff:
+    coverage-baseline: 70
+  full:
+    coverage-baseline: 60
+`)
+	o := &configTestOption{}
+	root, diff := newConfigTestCommand(o)
+	if err := diff.ParseFlags([]string{"--compare-branch", "origin/release"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := loadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.validate(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.apply(diff); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &configTestOption{
+		compareBranch:    "origin/release",
+		coverageBaseline: 70,
+		fileBaseline:     50,
+		coverProfiles:    []string{"coverage.out"},
+		excludes:         []string{"**/mock/**", "**/zz_generated.go"},
+		packageBaselines: []string{"**/internal/auth=95", "github.com/Azure/foo/**=60"},
+		layers:           []string{"domain=**/domain/**,**/model/**"},
+		githubCheck:      true,
+	}
+	if !reflect.DeepEqual(o, expected) {
+		t.Errorf("expect %+v, but get %+v", expected, o)
+	}
+	if !diff.Flags().Lookup("cover-profile").Changed {
+		t.Error("expect the required flag set by the config is regarded as set")
+	}
+	if diff.Flags().Lookup("coverage-baseline").Changed {
+		t.Error("expect the flag set by the config is not changed, so the exclusive flags are not violated")
+	}
+}
+
+func TestConfigApplyAlias(t *testing.T) {
+	file := writeConfig(t, "coverage-baseline: 80\n")
+	o := &configTestOption{}
+	_, diff := newConfigTestCommand(o)
+	if err := diff.ParseFlags([]string{"--fail-under", "65"}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.apply(diff); err != nil {
+		t.Fatal(err)
+	}
+	if o.coverageBaseline != 65 {
+		t.Errorf("expect --fail-under on the command line takes precedence, but get %f", o.coverageBaseline)
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	repository := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repository, ".gocover.yml"), []byte("fail-under: 80\nfail-under-file: 50\ngithub-token: env:GITHUB_TOKEN\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	explicit := writeConfig(t, "fail-under: 90\n")
+	defer func(file string) { configFile = file }(configFile)
+
+	t.Run("config file of the repository", func(t *testing.T) {
+		configFile = ""
+		o := &configTestOption{}
+		_, diff := newConfigTestCommand(o)
+		if err := diff.ParseFlags([]string{"--repository-path", repository}); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyConfigFile(diff); err != nil {
+			t.Fatal(err)
+		}
+		if o.coverageBaseline != 80 || o.fileBaseline != 50 || o.githubToken != "env:GITHUB_TOKEN" {
+			t.Errorf("expect the gate and the integration are set by the config file of the repository, but get %+v", o)
+		}
+	})
+
+	t.Run("config file of --config", func(t *testing.T) {
+		configFile = explicit
+		o := &configTestOption{}
+		_, diff := newConfigTestCommand(o)
+		if err := diff.ParseFlags([]string{"--repository-path", repository}); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyConfigFile(diff); err != nil {
+			t.Fatal(err)
+		}
+		if o.coverageBaseline != 90 || o.fileBaseline != 0 || o.githubToken != "" {
+			t.Errorf("expect the config file of the repository isn't read with --config, but get %+v", o)
+		}
+	})
+}
+
+func TestConfigInvalid(t *testing.T) {
+	testSuites := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown flag", content: "coverage-basline: 80\n"},
+		{name: "unknown command", content: "commands:\n  dif:\n    coverage-baseline: 80\n"},
+		{name: "not a mapping", content: "- coverage-baseline\n"},
+		{name: "list of a scalar flag", content: "compare-branch: [origin/main]\n"},
+		{name: "invalid value", content: "coverage-baseline: high\n"},
+	}
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, diff := newConfigTestCommand(&configTestOption{})
+			c, err := loadConfig(writeConfig(t, testCase.content))
+			if err == nil {
+				if err = c.validate(root); err == nil {
+					err = c.apply(diff)
+				}
+			}
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expect invalid config, but get %v", err)
+			}
+		})
+	}
+}
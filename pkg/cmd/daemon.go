@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Azure/gocover/pkg/daemon"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+const defaultDaemonSocket = ".gocover.sock"
+
+var (
+	daemonLong = `Keep the parsed cover profiles, the git repository, and the annotations and the functions of the source files
+warm in memory, and answer the repeated diff coverage queries over a unix socket.
+
+Only the cover profiles and the source files that changed since the last query are parsed again, and the changes
+between the commits are calculated once, so the editor and the pre-commit integrations get the coverage of the changes
+in a fraction of a second. Each request and response is a json object on a line, see 'gocover daemon query --format json'.
+The queries are answered one by one.
+`
+
+	daemonExample = `# Start the daemon in the module directory, and stop it after 30 minutes without a query.
+gocover daemon --idle-timeout 30m &
+
+# Query the coverage of the uncommitted changes in a pre-commit hook.
+go test -coverprofile=coverage.out ./... && gocover daemon query --cover-profile coverage.out --diff-target worktree --coverage-baseline 80
+
+# Query the coverage of the file open in the editor.
+gocover daemon query --cover-profile coverage.out --file pkg/foo/foo.go --format json
+
+# Stop the daemon.
+gocover daemon stop
+`
+)
+
+func newDaemonCommand() *cobra.Command {
+	o := &daemon.Option{}
+
+	cmd := &cobra.Command{
+		Use:     "daemon",
+		Short:   "answer the repeated diff coverage queries over a unix socket with warm caches",
+		Long:    daemonLong,
+		Example: daemonExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := daemon.NewServer(o, createLogger(cmd))
+			if err != nil {
+				return fmt.Errorf("NewServer: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return server.Serve(ctx)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Socket, "socket", defaultDaemonSocket, "path of the unix socket that the daemon listens on")
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().DurationVar(&o.IdleTimeout, "idle-timeout", 0, "stops the daemon when no query is received for the duration, such as 30m, it never stops by itself if it's 0")
+
+	cmd.AddCommand(newDaemonQueryCommand())
+	cmd.AddCommand(newDaemonStopCommand())
+	return cmd
+}
+
+func newDaemonQueryCommand() *cobra.Command {
+	var (
+		socket string
+		format string
+	)
+	request := &daemon.Request{Method: daemon.DiffMethod}
+
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "query the diff coverage from the daemon",
+		Long: `Query the diff coverage of the changes from the daemon, and exit with the low coverage exit code if the coverage
+is less than the coverage baseline. The cover profiles are relative to the working directory of the daemon.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			response, err := daemon.Query(ctx, socket, request)
+			if err != nil {
+				return err
+			}
+			switch format {
+			case "json":
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(response); err != nil {
+					return fmt.Errorf("write response: %w", err)
+				}
+			case "text":
+				writeDaemonResponse(cmd.OutOrStdout(), response)
+			default:
+				return fmt.Errorf("unknown format %q, one of: text, json", format)
+			}
+
+			if !response.Passed {
+				return gocover.WrapErrorWithCode(fmt.Errorf("coverage %.2f%% is less than the baseline %.2f%%",
+					response.Statistics.TotalCoveragePercent, request.CoverageBaseline), gocover.LowCoverageErrorExitCode, "")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&socket, "socket", defaultDaemonSocket, "path of the unix socket that the daemon listens on")
+	cmd.Flags().StringVar(&format, "format", "text", "format of the response, one of: text, json")
+	cmd.Flags().StringSliceVar(&request.CoverProfiles, "cover-profile", []string{}, `coverage profile produced by 'go test'`)
+	cmd.Flags().StringVar(&request.CompareBranch, "compare-branch", gocover.DefaultCompareBranch, `branch to compare`)
+	cmd.Flags().StringVar(&request.DiffTarget, "diff-target", gittool.HEADTarget, `what compares with the branch, one of: HEAD, worktree (uncommitted and untracked changes included), or a revision such as a stash like stash@{0}`)
+	cmd.Flags().StringSliceVar(&request.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().Float64Var(&request.CoverageBaseline, "coverage-baseline", 0, "returns an error code if coverage is less than coverage baseline")
+	cmd.Flags().StringSliceVar(&request.Files, "file", []string{}, "files whose coverage is printed, relative to the repository root or absolute, the totals are of all the changes")
+
+	cmd.MarkFlagRequired("cover-profile")
+	return cmd
+}
+
+func newDaemonStopCommand() *cobra.Command {
+	var socket string
+
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "stop the daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			_, err := daemon.Query(ctx, socket, &daemon.Request{Method: daemon.ShutdownMethod})
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&socket, "socket", defaultDaemonSocket, "path of the unix socket that the daemon listens on")
+	return cmd
+}
+
+// writeDaemonResponse writes the coverage of the response, and the uncovered lines of each file.
+func writeDaemonResponse(w io.Writer, response *daemon.Response) {
+	s := response.Statistics
+	fmt.Fprintf(w, "diff coverage %.2f%%, %d of %d lines covered (%.1fms, cache hits %d, misses %d)\n", s.TotalCoveragePercent,
+		s.TotalCoveredLines-s.TotalCoveredButIgnoredLines, s.TotalEffectiveLines, response.DurationMs, response.Cache.Hits, response.Cache.Misses)
+	for _, p := range s.CoverageProfile {
+		if len(p.TotalViolationLines) == 0 {
+			continue
+		}
+		lines := make([]string, 0, len(p.TotalViolationLines))
+		for _, l := range p.TotalViolationLines {
+			lines = append(lines, strconv.Itoa(l))
+		}
+		fmt.Fprintf(w, "%s: %s\n", p.FileName, strings.Join(lines, ","))
+	}
+}
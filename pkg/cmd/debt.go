@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/debt"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	debtLong = `Track the files in coverage debt as issues of the repository.
+
+A file is in debt if its changed lines fail the coverage gate in --min-failures pull requests within --window,
+or it ignores more than --max-ignored-lines lines in the latest full coverage. The runs are read from --history-dir,
+where the webhook server stores them, or from the git notes of the repository in --history-git-notes. Each file in debt gets an issue labeled with --labels, and the issue is closed
+once the file is no longer in debt. The issues are deduplicated by a hidden marker of the file in the issue body,
+so the command is supposed to run on a schedule.
+`
+
+	debtExample = `# Open and close the tracking issues of the repository every night.
+gocover debt --history-dir /var/lib/gocover/runs --owner Azure --repository gocover --github-token env:GITHUB_TOKEN
+
+# Print the files in debt without changing the issues.
+gocover debt --history-dir /var/lib/gocover/runs --owner Azure --repository gocover --max-ignored-lines 50 --dry-run
+`
+)
+
+type debtOption struct {
+	history      historyFlags
+	owner        string
+	repository   string
+	tokenSpec    string
+	githubAPIURL string
+	labels       []string
+	dryRun       bool
+	policy       debt.Policy
+}
+
+func newDebtCommand() *cobra.Command {
+	o := &debtOption{}
+
+	cmd := &cobra.Command{
+		Use:     "debt",
+		Short:   "track the files in coverage debt as issues",
+		Long:    debtLong,
+		Example: debtExample,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			logger := createLogger(cmd)
+
+			store, err := o.history.open(o.owner, o.repository)
+			if err != nil {
+				return err
+			}
+			debts, err := debt.Find(store, o.owner, o.repository, &o.policy, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+			printDebts(cmd.OutOrStdout(), debts)
+			if o.dryRun {
+				return nil
+			}
+
+			token, err := credential.NewProvider(o.tokenSpec, httpClient)
+			if err != nil {
+				return fmt.Errorf("github token: %w", err)
+			}
+			recorder := audit.NewRecorder(auditFile)
+			defer func() {
+				if flushErr := recorder.Flush(); flushErr != nil && err == nil {
+					err = fmt.Errorf("audit: %w", flushErr)
+				}
+			}()
+			tracker, err := debt.NewTracker(scm.NewGitHubIssueTracker(o.githubAPIURL, token, httpClient, recorder), o.labels, logger)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := commandContext()
+			defer cancel()
+			result, err := tracker.Sync(ctx, o.owner, o.repository, debts)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "opened %d, closed %d, kept %d tracking issues\n", len(result.Opened), len(result.Closed), len(result.Kept))
+			return nil
+		},
+	}
+
+	addHistoryFlags(cmd, &o.history)
+	cmd.Flags().StringVar(&o.owner, "owner", "", "owner of the repository")
+	cmd.Flags().StringVar(&o.repository, "repository", "", "name of the repository")
+	cmd.Flags().StringVar(&o.tokenSpec, "github-token", "env:GITHUB_TOKEN", "credential spec of the GitHub token that opens and closes the issues")
+	cmd.Flags().StringVar(&o.githubAPIURL, "github-api-url", scm.DefaultGitHubAPIURL, "GitHub api url, use https://{host}/api/v3 for GitHub Enterprise Server")
+	cmd.Flags().StringSliceVar(&o.labels, "labels", []string{debt.DefaultLabel}, "labels of the tracking issues, the first one finds the tracking issues")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "print the files in debt without changing the issues")
+	cmd.Flags().Float64Var(&o.policy.CoverageBaseline, "coverage-baseline", 80, "coverage that the changed lines of a file need in a pull request")
+	cmd.Flags().IntVar(&o.policy.MinFailures, "min-failures", debt.DefaultMinFailures, "number of the pull requests that a file fails the gate in before it's in debt")
+	cmd.Flags().DurationVar(&o.policy.Window, "window", debt.DefaultWindow, "how far back the stored runs are counted")
+	cmd.Flags().IntVar(&o.policy.MaxIgnoredLines, "max-ignored-lines", 0, "ignored lines that a file is allowed in the latest full coverage, the ignored lines are not checked if it's zero")
+
+	cmd.MarkFlagRequired("owner")
+	cmd.MarkFlagRequired("repository")
+
+	return cmd
+}
+
+func printDebts(w io.Writer, debts []*debt.Debt) {
+	fmt.Fprintf(w, "%d files in coverage debt\n", len(debts))
+	for _, d := range debts {
+		fmt.Fprintf(w, "  %s: %s\n", d.File, strings.Join(d.Reasons(), "; "))
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"io"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/debt"
+	"github.com/Azure/gocover/pkg/digest"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	digestLong = `Summarize a period of the stored runs of the repositories into a markdown or html digest.
+
+For each repository, the digest shows the coverage trend of the full runs, the pull requests that fail or bypass
+the coverage gate, the files that fail the gate in the most pull requests, and the files that go into or out of
+coverage debt during the period. The runs are read from --history-dir, where the webhook server stores them.
+A repository is {owner}/{name}, or {owner} for all the repositories of the owner with runs in the period.
+The html digest uses inline styles, so it can be sent as the body of an email.
+`
+
+	digestExample = `# Write the weekly markdown digest of a repository to stdout.
+gocover digest --history-dir /var/lib/gocover/runs --repositories Azure/gocover
+
+# Write the html digest of the repositories of a team, to be mailed every Monday.
+gocover digest --history-dir /var/lib/gocover/runs --repositories Azure/gocover,Azure/k8s --title "Platform Team" \
+  --format html --output digest.html
+`
+)
+
+type digestOption struct {
+	historyDir   string
+	repositories []string
+	title        string
+	format       string
+	output       string
+	period       time.Duration
+	maxOffenders int
+	policy       debt.Policy
+}
+
+func newDigestCommand() *cobra.Command {
+	o := &digestOption{}
+
+	cmd := &cobra.Command{
+		Use:     "digest",
+		Short:   "summarize a period of the stored runs into a markdown or html digest",
+		Long:    digestLong,
+		Example: digestExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := createLogger(cmd)
+
+			option := &digest.Option{
+				Title:        o.title,
+				Until:        time.Now().UTC(),
+				Period:       o.period,
+				MaxOffenders: o.maxOffenders,
+				Debt:         o.policy,
+			}
+			for _, r := range o.repositories {
+				repository, err := digest.ParseRepository(r)
+				if err != nil {
+					return err
+				}
+				option.Repositories = append(option.Repositories, repository)
+			}
+
+			store, err := history.NewFileStore(o.historyDir)
+			if err != nil {
+				return err
+			}
+			d, err := digest.Generate(store, option)
+			if err != nil {
+				return err
+			}
+
+			if o.output == report.StdoutOutput {
+				return digest.Write(cmd.OutOrStdout(), d, o.format)
+			}
+			err = atomicfile.WriteFile(o.output, func(w io.Writer) error {
+				return digest.Write(w, d, o.format)
+			})
+			if err != nil {
+				return err
+			}
+			logger.Infof("generate digest: %s", o.output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.historyDir, "history-dir", "", "directory of the runs stored by the webhook server")
+	cmd.Flags().StringSliceVar(&o.repositories, "repositories", nil, "repositories in the digest, {owner}/{name} or {owner} for all the repositories of the owner")
+	cmd.Flags().StringVar(&o.title, "title", "", "title of the digest, such as the name of the team")
+	cmd.Flags().StringVar(&o.format, "format", digest.MarkdownFormat, "format of the digest, one of: markdown, html")
+	cmd.Flags().StringVar(&o.output, "output", report.StdoutOutput, "file that the digest is written into, - writes it to stdout")
+	cmd.Flags().DurationVar(&o.period, "period", digest.DefaultPeriod, "period of the digest that ends now")
+	cmd.Flags().IntVar(&o.maxOffenders, "max-offenders", digest.DefaultMaxOffenders, "maximum files that fail the gate in the most pull requests shown for each repository")
+	cmd.Flags().Float64Var(&o.policy.CoverageBaseline, "coverage-baseline", 80, "coverage that the changed lines need in a pull request")
+	cmd.Flags().IntVar(&o.policy.MinFailures, "min-failures", debt.DefaultMinFailures, "number of the pull requests that a file fails the gate in before it's in debt")
+	cmd.Flags().DurationVar(&o.policy.Window, "debt-window", debt.DefaultWindow, "how far back the stored runs are counted for the coverage debt")
+	cmd.Flags().IntVar(&o.policy.MaxIgnoredLines, "max-ignored-lines", 0, "ignored lines that a file is allowed in the latest full coverage, the ignored lines are not checked if it's zero")
+
+	cmd.MarkFlagRequired("history-dir")
+	cmd.MarkFlagRequired("repositories")
+
+	return cmd
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envFileSuffix is the suffix of the environment variables that point to the files containing the values,
+// such as the secrets and config maps mounted into a container.
+const envFileSuffix = "_FILE"
+
+// envBinder sets the flags from the environment variables, the flags set on the command line take precedence.
+// The variable of a flag is the prefix followed by the flag name in upper case with dashes replaced by underscores,
+// for example --history-dir is GOCOVER_WEBHOOK_HISTORY_DIR, and GOCOVER_WEBHOOK_HISTORY_DIR_FILE reads the value from a file.
+type envBinder struct {
+	prefix   string
+	flags    *pflag.FlagSet
+	explicit map[string]bool
+}
+
+// newEnvBinder creates the binder, the flags that are already changed are regarded as set on the command line.
+func newEnvBinder(flags *pflag.FlagSet, prefix string) *envBinder {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *pflag.Flag) {
+		explicit[f.Name] = true
+	})
+	return &envBinder{prefix: prefix, flags: flags, explicit: explicit}
+}
+
+// envName returns the environment variable of the flag.
+func (b *envBinder) envName(flag string) string {
+	return b.prefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// apply sets the flags from the environment variables, it can be called again to pick up the changed files.
+// A variable that is removed doesn't reset the flag, the flag keeps the last value until the process restarts.
+func (b *envBinder) apply() error {
+	var err error
+	b.flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || b.explicit[f.Name] {
+			return
+		}
+		value, ok, lookupErr := b.lookup(f.Name)
+		if lookupErr != nil {
+			err = lookupErr
+			return
+		}
+		if !ok {
+			return
+		}
+		if setErr := setFlagValue(f, value); setErr != nil {
+			err = fmt.Errorf("set --%s from %s: %w", f.Name, b.envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// lookup returns the value of the flag from the environment variable, or the file that the _FILE variable points to.
+func (b *envBinder) lookup(flag string) (string, bool, error) {
+	name := b.envName(flag)
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+	file, ok := os.LookupEnv(name + envFileSuffix)
+	if !ok || file == "" {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", false, fmt.Errorf("read %s%s: %w", name, envFileSuffix, err)
+	}
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+// setFlagValue sets the value without marking the flag as changed, so it's set again when the environment is reapplied.
+// The lists are comma separated and replace the defaults.
+func setFlagValue(f *pflag.Flag, value string) error {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return slice.Replace(items)
+	}
+	return f.Value.Set(value)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestEnvBinder(t *testing.T) {
+	var (
+		listen   string
+		limit    int
+		excludes []string
+		secret   string
+	)
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringVar(&listen, "listen", ":8080", "")
+	flags.IntVar(&limit, "max-annotations", 10, "")
+	flags.StringSliceVar(&excludes, "excludes", []string{"default"}, "")
+	flags.StringVar(&secret, "webhook-secret", "", "")
+	if err := flags.Parse([]string{"--listen", ":9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("env:SECRET\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOCOVER_TEST_LISTEN", ":7070")
+	t.Setenv("GOCOVER_TEST_MAX_ANNOTATIONS", "3")
+	t.Setenv("GOCOVER_TEST_EXCLUDES", "a.go, b.go")
+	t.Setenv("GOCOVER_TEST_WEBHOOK_SECRET_FILE", file)
+
+	binder := newEnvBinder(flags, "GOCOVER_TEST_")
+	if err := binder.apply(); err != nil {
+		t.Fatal(err)
+	}
+	if listen != ":9090" {
+		t.Errorf("the command line should take precedence, but get %s", listen)
+	}
+	if limit != 3 || secret != "env:SECRET" || len(excludes) != 2 || excludes[1] != "b.go" {
+		t.Errorf("unexpected values %d %s %v", limit, secret, excludes)
+	}
+
+	// reapplying picks up the changed file and replaces the lists rather than appending.
+	if err := os.WriteFile(file, []byte("file:/secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := binder.apply(); err != nil {
+		t.Fatal(err)
+	}
+	if secret != "file:/secret" || len(excludes) != 2 {
+		t.Errorf("unexpected values after reload %s %v", secret, excludes)
+	}
+
+	t.Setenv("GOCOVER_TEST_MAX_ANNOTATIONS", "many")
+	if err := binder.apply(); err == nil {
+		t.Error("expect error for the bad int")
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// exitCodes are the exit codes of the process, so that the CI can tell the changes below the threshold
+// from the runs that gocover fails to finish.
+type exitCodes struct {
+	// lowCoverage is the exit code when the coverage gate fails.
+	lowCoverage int
+	// toolError is the exit code of the other errors, such as a missing cover profile or an unknown branch.
+	toolError int
+}
+
+var processExitCodes = &exitCodes{
+	lowCoverage: gocover.LowCoverageErrorExitCode,
+	toolError:   gocover.GeneralErrorExitCode,
+}
+
+func addExitCodeFlags(cmd *cobra.Command, c *exitCodes) {
+	cmd.PersistentFlags().IntVar(&c.lowCoverage, "low-coverage-exit-code", c.lowCoverage, "exit code when the coverage is lower than the baseline, such as --coverage-baseline, --fail-under or --fail-under-file")
+	cmd.PersistentFlags().IntVar(&c.toolError, "error-exit-code", c.toolError, "exit code when gocover fails to calculate the coverage, such as a missing cover profile or an unknown branch")
+}
+
+// validate checks the exit codes are distinct and in 1-125, the exit codes above are used by the shells for the signals.
+func (c *exitCodes) validate() error {
+	for _, code := range []int{c.lowCoverage, c.toolError} {
+		if code < 1 || code > 125 {
+			return fmt.Errorf("exit code %d is not in 1-125", code)
+		}
+	}
+	if c.lowCoverage == c.toolError {
+		return fmt.Errorf("low coverage exit code and error exit code are both %d", c.lowCoverage)
+	}
+	return nil
+}
+
+// ExitCode returns the exit code of the error returned by the command, the low coverage errors, the general errors
+// and the errors without an exit code are mapped to the exit codes of the flags, the other exit codes are kept,
+// such as the failed unit tests and the bad and skip answers of the bisect command, which git bisect reads.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var e *gocover.GoCoverError
+	switch {
+	case !errors.As(err, &e):
+		return processExitCodes.toolError
+	case errors.Is(e.Err, gocover.ErrBisectBad):
+		return gocover.BisectBadExitCode
+	case e.ExitCode == gocover.LowCoverageErrorExitCode:
+		return processExitCodes.lowCoverage
+	case e.ExitCode == gocover.GeneralErrorExitCode:
+		return processExitCodes.toolError
+	}
+	return e.ExitCode
+}
+
+// errorClass returns the class of the error for the telemetry, the error message itself is never sent.
+func errorClass(err error) string {
+	var e *gocover.GoCoverError
+	switch {
+	case err == nil:
+		return telemetry.ErrorClassNone
+	case errors.Is(err, context.DeadlineExceeded):
+		return telemetry.ErrorClassTimeout
+	case errors.As(err, &e) && e.ExitCode == gocover.LowCoverageErrorExitCode:
+		return telemetry.ErrorClassLowCoverage
+	case errors.As(err, &e):
+		return telemetry.ErrorClassExitCode
+	default:
+		return telemetry.ErrorClassToolError
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/telemetry"
+)
+
+func TestExitCode(t *testing.T) {
+	defer func(c exitCodes) { *processExitCodes = c }(*processExitCodes)
+	processExitCodes.lowCoverage, processExitCodes.toolError = 3, 4
+
+	testSuites := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "no error", err: nil, code: 0},
+		{name: "low coverage", err: gocover.WrapErrorWithCode(errors.New("low"), gocover.LowCoverageErrorExitCode, ""), code: 3},
+		{name: "tool error", err: errors.New("no cover profile"), code: 4},
+		{name: "general error", err: gocover.WrapError(errors.New("unknown branch"), ""), code: 4},
+		{name: "wrapped general error", err: fmt.Errorf("run: %w", gocover.WrapErrorWithCode(errors.New("unknown branch"), gocover.GeneralErrorExitCode, "")), code: 4},
+		{name: "wrapped low coverage", err: fmt.Errorf("run: %w", gocover.WrapErrorWithCode(errors.New("low"), gocover.LowCoverageErrorExitCode, "")), code: 3},
+		{name: "wrapped unit test failed", err: fmt.Errorf("run: %w", gocover.WrapErrorWithCode(errors.New("failed"), gocover.UnitTestFailedErrorExitCode, "")), code: gocover.UnitTestFailedErrorExitCode},
+		{name: "bisect bad", err: gocover.WrapErrorWithCode(fmt.Errorf("abc: %w", gocover.ErrBisectBad), gocover.BisectBadExitCode, ""), code: gocover.BisectBadExitCode},
+		{name: "unit test failed", err: gocover.WrapErrorWithCode(errors.New("failed"), gocover.UnitTestFailedErrorExitCode, ""), code: gocover.UnitTestFailedErrorExitCode},
+		{name: "bisect skip", err: gocover.WrapErrorWithCode(errors.New("skip"), gocover.BisectSkipExitCode, ""), code: gocover.BisectSkipExitCode},
+	}
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			if code := ExitCode(testCase.err); code != testCase.code {
+				t.Errorf("expect exit code %d, but get %d", testCase.code, code)
+			}
+		})
+	}
+}
+
+func TestExitCodesValidate(t *testing.T) {
+	valid := &exitCodes{lowCoverage: 2, toolError: 1}
+	if err := valid.validate(); err != nil {
+		t.Errorf("expect valid exit codes, but get %s", err)
+	}
+	for _, c := range []*exitCodes{{lowCoverage: 0, toolError: 1}, {lowCoverage: 12, toolError: 126}, {lowCoverage: 12, toolError: 12}} {
+		if err := c.validate(); err == nil {
+			t.Errorf("expect exit codes %+v are invalid", c)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	for _, c := range []struct {
+		err   error
+		class string
+	}{
+		{nil, telemetry.ErrorClassNone},
+		{gocover.WrapErrorWithCode(errors.New("low"), gocover.LowCoverageErrorExitCode, ""), telemetry.ErrorClassLowCoverage},
+		{gocover.WrapErrorWithCode(errors.New("failed"), gocover.UnitTestFailedErrorExitCode, ""), telemetry.ErrorClassExitCode},
+		{fmt.Errorf("go test: %w", context.DeadlineExceeded), telemetry.ErrorClassTimeout},
+		{errors.New("no cover profile"), telemetry.ErrorClassToolError},
+	} {
+		if class := errorClass(c.err); class != c.class {
+			t.Errorf("class of %v should be %s, but %s", c.err, c.class, class)
+		}
+	}
+}
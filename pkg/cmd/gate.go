@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/gate"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deployGateLong = `Answer whether a commit is allowed to deploy by the stored coverage results and a policy.
+
+The commit is allowed if it has a coverage result that doesn't expire, and all the checks of the policy pass:
+  diff coverage  the latest diff coverage of the commit meets --diff-coverage, or its gate is bypassed
+  ratchet        the latest full coverage of the commit doesn't drop from the previous commit
+
+With --server, the webhook server evaluates the policy of the tenant over its stored runs,
+otherwise the runs in --history-dir, or in the git notes of the repository in --history-git-notes,
+are evaluated with the policy flags.
+The command exits with code 12 if the commit is denied.
+`
+
+	deployGateExample = `# Ask the webhook server whether the commit is allowed to deploy.
+gocover deploy-gate --server https://gocover.example.com --api-token env:GOCOVER_API_TOKEN \
+  --owner Azure --repository gocover --commit $(git rev-parse HEAD)
+
+# Evaluate the runs in a directory, the results expire in a week.
+gocover deploy-gate --history-dir /var/lib/gocover/runs --owner Azure --repository gocover --commit abcdef1 \
+  --diff-coverage 80 --ratchet --max-age 168h
+
+# Evaluate the runs saved by 'gocover full --git-notes' in the git notes of the repository.
+git fetch origin refs/notes/gocover:refs/notes/gocover
+gocover deploy-gate --history-git-notes . --owner Azure --repository gocover --commit $(git rev-parse HEAD) --ratchet
+`
+)
+
+type deployGateOption struct {
+	owner        string
+	repository   string
+	commit       string
+	server       string
+	apiTokenSpec string
+	history      historyFlags
+	policy       gate.Policy
+}
+
+func newDeployGateCommand() *cobra.Command {
+	o := &deployGateOption{}
+
+	cmd := &cobra.Command{
+		Use:     "deploy-gate",
+		Short:   "answer whether a commit is allowed to deploy by the stored coverage results",
+		Long:    deployGateLong,
+		Example: deployGateExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			decision, err := o.decide(ctx)
+			if err != nil {
+				return err
+			}
+			printDecision(cmd.OutOrStdout(), decision)
+			if !decision.Allowed {
+				err := fmt.Errorf("commit %s is not allowed to deploy", decision.Commit)
+				return gocover.WrapErrorWithCode(err, gocover.LowCoverageErrorExitCode, err.Error())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.owner, "owner", "", "owner of the repository")
+	cmd.Flags().StringVar(&o.repository, "repository", "", "name of the repository")
+	cmd.Flags().StringVar(&o.commit, "commit", "", "sha of the commit to deploy, or a prefix of at least 7 characters")
+	cmd.Flags().StringVar(&o.server, "server", "", "url of the webhook server that evaluates the policy of the tenant, the storage in --history-dir is evaluated if it's empty")
+	cmd.Flags().StringVar(&o.apiTokenSpec, "api-token", "", "credential spec of the bearer token of the tenant on the webhook server, such as env:GOCOVER_API_TOKEN")
+	addHistoryFlags(cmd, &o.history)
+	addDeployPolicyFlags(cmd, &o.policy, "")
+	cmd.MarkFlagsMutuallyExclusive("server", "history-dir")
+	cmd.MarkFlagsMutuallyExclusive("server", "history-git-notes")
+
+	cmd.MarkFlagRequired("owner")
+	cmd.MarkFlagRequired("repository")
+	cmd.MarkFlagRequired("commit")
+
+	return cmd
+}
+
+// decide asks the webhook server, or evaluates the runs in the history directory.
+func (o *deployGateOption) decide(ctx context.Context) (*gate.Decision, error) {
+	if o.server != "" {
+		var token credential.Provider
+		if o.apiTokenSpec != "" {
+			p, err := credential.NewProvider(o.apiTokenSpec, httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("api token: %w", err)
+			}
+			token = p
+		}
+		return gate.NewClient(o.server, token, httpClient).Decide(ctx, o.owner, o.repository, o.commit)
+	}
+
+	if o.history.dir == "" && o.history.notesDir == "" {
+		return nil, errors.New("either --server, --history-dir or --history-git-notes is required")
+	}
+	store, err := o.history.open(o.owner, o.repository)
+	if err != nil {
+		return nil, err
+	}
+	return gate.Evaluate(store, o.owner, o.repository, o.commit, &o.policy, time.Now().UTC())
+}
+
+func printDecision(w io.Writer, decision *gate.Decision) {
+	verdict := "allowed"
+	if !decision.Allowed {
+		verdict = "denied"
+	}
+	fmt.Fprintf(w, "%s/%s@%s: %s\n", decision.Owner, decision.Repository, decision.Commit, verdict)
+	for _, c := range decision.Checks {
+		mark := "pass"
+		if !c.Passed {
+			mark = "fail"
+		}
+		fmt.Fprintf(w, "  [%s] %s: %s\n", mark, c.Name, c.Message)
+	}
+}
+
+// addDeployPolicyFlags adds the flags of the deploy policy, the prefix is prepended to the flag names.
+func addDeployPolicyFlags(cmd *cobra.Command, p *gate.Policy, prefix string) {
+	cmd.Flags().Float64Var(&p.DiffCoverage, prefix+"diff-coverage", 0, "minimum diff coverage of a commit to deploy, the diff coverage is not checked if it's zero")
+	cmd.Flags().BoolVar(&p.Ratchet, prefix+"ratchet", false, "deny a commit whose full coverage drops from the previous commit")
+	cmd.Flags().Float64Var(&p.RatchetTolerance, prefix+"ratchet-tolerance", 0, "coverage percent that the full coverage is allowed to drop by the ratchet")
+	cmd.Flags().DurationVar(&p.MaxAge, prefix+"max-age", 0, "how long a coverage result of a commit counts for deploying, the results never expire if it's zero")
+}
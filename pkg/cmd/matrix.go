@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	matrixLong = `Compare the diff coverage of the same commits applied to several release branches.
+
+The commits are cherry-picked onto each branch in a temporary worktree, the tests run there, and the diff coverage
+of the applied commits on the branch is calculated, so the backports can be judged by their test coverage on each
+branch. The report of each branch is written into a sub directory of --outputdir named by the branch, and the matrix
+is printed as a markdown table. A branch that the commits conflict with is marked as conflict.
+`
+
+	matrixExample = `# Compare the coverage of a fix on the release branches.
+gocover matrix --commits abcdef1 --branches origin/release/1.0,origin/release/1.1
+
+# Compare the commits of a pull request, and keep the json report of each branch.
+gocover matrix --commits origin/main..feature --branches origin/release/1.0,origin/release/1.1 --format json -o reports
+`
+)
+
+func newMatrixCommand() *cobra.Command {
+	o := gocover.NewMatrixOption()
+
+	cmd := &cobra.Command{
+		Use:     "matrix",
+		Short:   "compare the diff coverage of the same commits applied to several release branches",
+		Long:    matrixLong,
+		Example: matrixExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = createLogger(cmd)
+			o.StdOut = cmd.ErrOrStderr()
+			o.StdErr = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			results, err := gocover.RunMatrix(ctx, o)
+			if err != nil {
+				return err
+			}
+			return gocover.WriteMatrix(cmd.OutOrStdout(), results, o.CoverageBaseline)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.Commits, "commits", []string{}, "commits to cherry-pick onto each branch in order, a commit can be a range such as origin/main..feature")
+	cmd.Flags().StringSliceVar(&o.Branches, "branches", []string{}, "release branches to apply the commits to")
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringSliceVar(&o.GoFlags, "go-flags", []string{}, "go flags")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "coverage that the applied commits need on each branch")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("diff coverage report of each branch"))
+	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "diff coverage report name")
+	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", "", "directory that the report of each branch is written into a sub directory named by the branch, the reports are discarded if it's empty")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+	addLimitFlags(cmd, &o.Limits)
+
+	cmd.MarkFlagRequired("commits")
+	cmd.MarkFlagRequired("branches")
+
+	return cmd
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeLong = `Merge the json documents of the shards of a sharded run into the statistics of all the packages.
+
+Each shard runs gocover diff, full or test with --shard and --json-output, and a baseline of 0 so that
+only the merged coverage is gated. The documents of all the shards must be merged, then the reports
+are generated and the coverage baseline is checked as a run that analyzes all the packages.
+`
+
+	mergeExample = `# Merge the documents of 4 shards and gate the diff coverage.
+gocover merge shard-1.json shard-2.json shard-3.json shard-4.json --coverage-baseline 80 --format markdown
+`
+)
+
+func newMergeCommand() *cobra.Command {
+	o := gocover.NewMergeOption()
+
+	cmd := &cobra.Command{
+		Use:     "merge document...",
+		Short:   "merge the json documents of the shards and gate the merged coverage",
+		Long:    mergeLong,
+		Example: mergeExample,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = createLogger(cmd)
+			o.Verbose = isVerbose(cmd)
+			o.Documents = args
+
+			merge, err := gocover.NewShardMerge(o)
+			if err != nil {
+				return fmt.Errorf("NewShardMerge: %w", err)
+			}
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			if err := merge.Run(ctx); err != nil {
+				return fmt.Errorf("merge shards: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from the coverage tree of the documents by default, or from go.mod")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("merged coverage report"))
+	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", o.OutputDir, "merged coverage output directory")
+	cmd.Flags().StringVar(&o.JSONOutput, "json-output", "", "file that the json document of the merged statistics and the coverage tree is written into in a versioned schema, - writes it to the stdout")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "returns an error code if the merged coverage is less than coverage baseline")
+	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "merged coverage report name")
+	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+	cmd.Flags().StringVar((*string)(&o.FailedTestPolicy), "failed-test-policy", string(o.FailedTestPolicy), "how the failed tests of the shards are treated, one of: fail, warn, exclude")
+
+	return cmd
+}
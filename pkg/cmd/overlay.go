@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	overlayLong = `Print the unified diff of the changes compared with the branch, and color the added lines by coverage status.
+
+Green lines are covered, red lines are not covered, yellow lines are ignored by annotations,
+and the lines without statements are not colored. Colors are disabled if NO_COLOR environment is set.
+`
+
+	overlayExample = `# Review the coverage of the changes in a pager.
+gocover overlay --cover-profile coverage.out --compare-branch origin/main | less -R
+
+# Print the diff without colors.
+gocover overlay --cover-profile coverage.out --color=false
+`
+)
+
+func newOverlayCommand() *cobra.Command {
+	o := gocover.NewOverlayOption()
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		o.Color = false
+	}
+
+	cmd := &cobra.Command{
+		Use:     "overlay",
+		Short:   "print the diff with added lines colored by coverage status",
+		Long:    overlayLong,
+		Example: overlayExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = createLogger(cmd)
+			o.Output = cmd.OutOrStdout()
+
+			overlay, err := gocover.NewDiffOverlay(o)
+			if err != nil {
+				return fmt.Errorf("NewDiffOverlay: %w", err)
+			}
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			return overlay.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profile produced by 'go test'`)
+	cmd.Flags().StringVar(&o.CompareBranch, "compare-branch", o.CompareBranch, `branch to compare`)
+	cmd.Flags().StringVar(&o.DiffTarget, "diff-target", gittool.HEADTarget, `what compares with the branch, one of: HEAD, worktree (uncommitted and untracked changes included), or a revision such as a stash like stash@{0}`)
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().BoolVar(&o.Color, "color", o.Color, "color the added lines by coverage status with ANSI escape codes")
+
+	cmd.MarkFlagRequired("cover-profile")
+
+	return cmd
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	revertLong = `Preview the diff coverage of reverting a commit onto HEAD, for the rollback decisions of an incident.
+
+The commit is reverted in a temporary worktree and the tests run there, so the restored lines have the coverage
+of the reverted code: the uncovered lines that return with the revert. The lines that the revert removes are
+looked up in the cover profile of HEAD: the covered lines that disappear with the revert. HEAD and the working tree
+of the repository are not changed, and the cover profile should be of HEAD without uncommitted changes.
+`
+
+	revertExample = `# Preview the coverage of reverting a commit of the incident.
+go test ./... -coverprofile coverage.out
+gocover revert abcdef1 --cover-profile coverage.out
+`
+)
+
+func newRevertCommand() *cobra.Command {
+	o := gocover.NewRevertOption()
+
+	cmd := &cobra.Command{
+		Use:     "revert commit",
+		Short:   "preview the diff coverage of reverting a commit",
+		Long:    revertLong,
+		Example: revertExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Commit = args[0]
+			o.Logger = createLogger(cmd)
+			o.StdOut = cmd.ErrOrStderr()
+			o.StdErr = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext()
+			defer cancel()
+
+			preview, err := gocover.RunRevertPreview(ctx, o)
+			if err != nil {
+				return err
+			}
+			return gocover.WriteRevertPreview(cmd.OutOrStdout(), preview)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.CoverProfiles, "cover-profile", []string{}, `coverage profile of HEAD produced by 'go test'`)
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringSliceVar(&o.GoFlags, "go-flags", []string{}, "go flags of the tests of the reverted code")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	addLimitFlags(cmd, &o.Limits)
+
+	cmd.MarkFlagRequired("cover-profile")
+	return cmd
+}
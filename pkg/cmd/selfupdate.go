@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/selfupdate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfUpdateLong = `Replace the running gocover with the binary of a GitHub release for the current platform.
+
+The archive of the platform is downloaded from the latest release, or the release of --tag, and its sha256 is verified
+against the checksums file of the release before the binary is replaced. Nothing is downloaded if the release is the
+current version, unless --force is set. The binary is replaced by renaming, so it's either the old or the new one
+if the update is interrupted. Symlinks are resolved, so the target of a link is replaced. The directory of the binary
+must be writable.
+`
+
+	selfUpdateExample = `# Update to the latest release.
+gocover self-update
+
+# Install a specific release on a CI image, with a token so the rate limit of GitHub API is not hit.
+gocover self-update --tag v1.2.0 --github-token env:GITHUB_TOKEN
+
+# Check the latest release is downloadable and verified without replacing the binary.
+gocover self-update --dry-run
+`
+)
+
+type selfUpdateOption struct {
+	selfupdate.Option
+	tokenSpec string
+}
+
+func newSelfUpdateCommand(version string) *cobra.Command {
+	o := &selfUpdateOption{}
+
+	cmd := &cobra.Command{
+		Use:     "self-update",
+		Short:   "replace gocover with the verified binary of a release",
+		Long:    selfUpdateLong,
+		Example: selfUpdateExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.CurrentVersion = version
+			if o.tokenSpec != "" {
+				token, err := credential.NewProvider(o.tokenSpec, httpClient)
+				if err != nil {
+					return fmt.Errorf("github token: %w", err)
+				}
+				o.Token = token
+			}
+
+			ctx, cancel := commandContext()
+			defer cancel()
+			result, err := selfupdate.Update(ctx, &o.Option, httpClient, createLogger(cmd))
+			if err != nil {
+				return fmt.Errorf("self update: %w", err)
+			}
+			if result.Updated {
+				fmt.Fprintf(cmd.OutOrStdout(), "gocover %s is installed\n", result.Version)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Version, "tag", "", "tag of the release to install, such as v1.2.0, the latest release is installed if it's empty")
+	cmd.Flags().StringVar(&o.Repository, "repository", selfupdate.DefaultRepository, "GitHub repository of the releases in the format of owner/name, such as a mirror of the releases")
+	cmd.Flags().StringVar(&o.APIURL, "github-api-url", selfupdate.DefaultAPIURL, "GitHub api url, use https://{host}/api/v3 for GitHub Enterprise Server")
+	cmd.Flags().StringVar(&o.tokenSpec, "github-token", "", "credential spec of the GitHub token of the requests, such as env:GITHUB_TOKEN, the requests are anonymous if it's empty")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "install the release even if it's the current version")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "download and verify the release without replacing the binary")
+
+	return cmd
+}
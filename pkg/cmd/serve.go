@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveLong = `Serve a coverage report as web pages for the local review, rather than generating and shipping the static reports.
+
+The pages are the coverage tree of the packages and their files, the annotated source of each file, which is read from
+the module directory, and the list of the uncovered lines. The files are filtered by the import path of the package,
+and sorted by the name or the coverage. The report is a json report of --format json or the json document of --json-output,
+it's read again when it's modified, so the pages show the latest run after a refresh. Write the report with --verbose
+to highlight the covered lines too, otherwise only the uncovered lines are highlighted.
+`
+
+	serveExample = `# Review the full coverage of the module on http://localhost:8080.
+go test -coverprofile=coverage.out ./...
+gocover full --cover-profile coverage.out --format json --verbose --outputdir reports
+gocover serve --report reports/coverage.json
+
+# Review the diff coverage on another port.
+gocover diff --cover-profile coverage.out --compare-branch origin/main --json-output reports/diff.json --verbose
+gocover serve --report reports/diff.json --listen localhost:9090
+`
+)
+
+type serveOption struct {
+	address        string
+	report         string
+	repositoryPath string
+	moduleDir      string
+	modulePath     string
+	style          string
+	contextLines   int
+	theme          report.ThemeSettings
+}
+
+func newServeCommand() *cobra.Command {
+	o := &serveOption{}
+
+	cmd := &cobra.Command{
+		Use:     "serve",
+		Short:   "serve a coverage report as web pages for the local review",
+		Long:    serveLong,
+		Example: serveExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			moduleDir := filepath.Join(o.repositoryPath, o.moduleDir)
+			modulePath, err := gocover.ModulePath(moduleDir, o.modulePath)
+			if err != nil {
+				return fmt.Errorf("resolve module path: %w", err)
+			}
+			theme, err := o.theme.Load()
+			if err != nil {
+				return err
+			}
+
+			server := report.NewReportServer(&report.ReportServerOption{
+				Report:       o.report,
+				Style:        o.style,
+				ModulePath:   modulePath,
+				ModuleDir:    moduleDir,
+				ContextLines: o.contextLines,
+				Theme:        theme,
+			}, createLogger(cmd))
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return server.ListenAndServe(ctx, o.address)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.address, "listen", "localhost:8080", "address that the report server listens on")
+	cmd.Flags().StringVar(&o.report, "report", "", "json report or json document that the pages render")
+	cmd.Flags().StringVar(&o.repositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.moduleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.modulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringVar(&o.style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+	cmd.Flags().IntVar(&o.contextLines, "diff-context", report.DefaultContextLines, "number of the unchanged lines shown around the changed lines of the annotated source of a diff report")
+	addThemeFlags(cmd, &o.theme)
+
+	cmd.MarkFlagRequired("report")
+
+	return cmd
+}
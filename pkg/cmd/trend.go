@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trendLong = `Print the coverage of the stored runs of a branch over time, to track whether the coverage gate improves the codebase.
+
+The runs are read from --history-dir, where the webhook server stores them, or from the git notes of the repository
+in --history-git-notes, which 'gocover full --git-notes' saves with the branch of the build.
+The text format plots the coverage of each run as a bar, the bars are scaled from the lowest coverage to the highest,
+so the small changes stand out. The csv and the json formats are for the spreadsheets and the dashboards.
+`
+
+	trendExample = `# Plot the full coverage of the main branch saved in the git notes.
+git fetch origin refs/notes/gocover:refs/notes/gocover
+gocover trend --history-git-notes . --branch main
+
+# Export the diff coverage of the pull requests in the last 30 days stored by the webhook server.
+gocover trend --history-dir /var/lib/gocover/runs --owner Azure --repository gocover --type diff --period 720h --format csv
+`
+)
+
+type trendOption struct {
+	history    historyFlags
+	owner      string
+	repository string
+	branch     string
+	runType    string
+	period     time.Duration
+	limit      int
+	format     string
+}
+
+func newTrendCommand() *cobra.Command {
+	o := &trendOption{}
+
+	cmd := &cobra.Command{
+		Use:     "trend",
+		Short:   "print the coverage of the stored runs of a branch over time",
+		Long:    trendLong,
+		Example: trendExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			statisticsType := report.StatisticsType(o.runType)
+			switch statisticsType {
+			case "", report.FullStatisticsType, report.DiffStatisticsType:
+			default:
+				return errors.New("--type should be one of: full, diff")
+			}
+
+			store, err := o.history.open(o.owner, o.repository)
+			if err != nil {
+				return err
+			}
+			filter := &history.Filter{
+				Owner:      o.owner,
+				Repository: o.repository,
+				Branch:     o.branch,
+				Type:       statisticsType,
+				Limit:      o.limit,
+			}
+			if o.period > 0 {
+				filter.Since = time.Now().UTC().Add(-o.period)
+			}
+			points, err := history.Trend(store, filter)
+			if err != nil {
+				return err
+			}
+			return history.WriteTrend(cmd.OutOrStdout(), points, o.format)
+		},
+	}
+
+	addHistoryFlags(cmd, &o.history)
+	cmd.Flags().StringVar(&o.owner, "owner", "", "owner of the repository, the runs of all the repositories are read if it's empty")
+	cmd.Flags().StringVar(&o.repository, "repository", "", "name of the repository, the runs of all the repositories are read if it's empty")
+	cmd.Flags().StringVar(&o.branch, "branch", "", "branch of the runs, the runs of all the branches are read if it's empty")
+	cmd.Flags().StringVar(&o.runType, "type", string(report.FullStatisticsType), "type of the runs, one of: full, diff")
+	cmd.Flags().DurationVar(&o.period, "period", 0, "period of the trend that ends now, all the runs are read if it's zero")
+	cmd.Flags().IntVar(&o.limit, "limit", 0, "maximum number of the latest runs in the trend, all the runs are read if it's zero")
+	cmd.Flags().StringVar(&o.format, "format", history.TextFormat, "format of the trend, one of: text, csv, json")
+
+	return cmd
+}
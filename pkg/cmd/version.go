@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/Azure/gocover/pkg/report"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +16,7 @@ func newVersionCommand(version, commit, date string) *cobra.Command {
 			fmt.Fprintf(cmd.OutOrStdout(), "Gocover Version %s\n", version)
 			fmt.Fprintf(cmd.OutOrStdout(), "Runtime SHA: %s\n", commit)
 			fmt.Fprintf(cmd.OutOrStdout(), "Created At: %s\n", date)
+			fmt.Fprintf(cmd.OutOrStdout(), "Schema Version: %d (compatible with %d)\n", report.SchemaVersion, report.CompatibleSchemaVersion)
 			return nil
 		},
 	}
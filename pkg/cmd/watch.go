@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchLong = `Watch the working tree and print the diff coverage against the compared branch whenever a go file changes.
+
+The first run tests the packages of the go files changed against the compared branch. After that the module is
+checked for changes every --interval, the tests of the packages of the changed go files run again with coverage,
+and the cover profiles of the other packages are kept from their last runs. The diff coverage of the working tree,
+including the uncommitted and untracked changes, is printed with the changed hunks colored by their coverage, and
+the report is written into --outputdir again. A change of go.mod or go.sum runs the tests of all the packages again.
+The failed tests and the low coverage are printed without stopping the watch, press Ctrl+C to stop it.
+Colors are disabled if NO_COLOR environment is set.
+`
+
+	watchExample = `# Watch the module against origin/master, and keep the html report up to date in the reports directory.
+gocover watch -o reports
+
+# Watch a module in a sub directory of the repository against main every 2 seconds.
+gocover watch --module-dir service --compare-branch main --interval 2s
+`
+)
+
+func newWatchCommand() *cobra.Command {
+	o := gocover.NewWatchOption()
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		o.Color = false
+	}
+
+	cmd := &cobra.Command{
+		Use:     "watch",
+		Short:   "re-run the tests of the changed packages and print the diff coverage whenever the working tree changes",
+		Long:    watchLong,
+		Example: watchExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Logger = createLogger(cmd)
+			o.StdOut = cmd.OutOrStdout()
+			o.StdErr = cmd.ErrOrStderr()
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return gocover.Watch(ctx, o)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.CompareBranch, "compare-branch", o.CompareBranch, "branch to compare")
+	cmd.Flags().StringVar(&o.RepositoryPath, "repository-path", "./", `the root directory of git repository`)
+	cmd.Flags().StringVar(&o.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the project")
+	cmd.Flags().StringVar(&o.ModulePath, "module-path", "", "import path of the module directory, it's read from go.mod by default")
+	cmd.Flags().StringSliceVar(&o.GoFlags, "go-flags", []string{}, "go flags")
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "how often the module is checked for changes")
+	cmd.Flags().BoolVar(&o.Color, "color", o.Color, "color the changed hunks by their coverage")
+	cmd.Flags().Float64Var(&o.CoverageBaseline, "coverage-baseline", o.CoverageBaseline, "coverage that the changes need")
+	cmd.Flags().StringVar(&o.ReportFormat, "format", o.ReportFormat, reportFormatUsage("diff coverage report"))
+	cmd.Flags().StringVar(&o.ReportName, "report-name", "coverage", "diff coverage report name")
+	cmd.Flags().StringVarP(&o.OutputDir, "outputdir", "o", "", "directory that the report is written into after each run, a temporary directory is used if it's empty")
+	cmd.Flags().StringSliceVar(&o.Excludes, "excludes", []string{}, "exclude files for diff coverage calucation")
+	cmd.Flags().StringVar(&o.SkipListFile, "skip-list", "", "yaml file of the files whose coverage collection is flaky, the files are not counted for coverage until the entry expires and the entries are shown in the reports")
+	cmd.Flags().StringVar(&o.Style, "style", "colorful", "coverage report code format style, refer to https://pygments.org/docs/styles for more information")
+
+	return cmd
+}
@@ -0,0 +1,454 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/gate"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/kube"
+	"github.com/Azure/gocover/pkg/queue"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/Azure/gocover/pkg/webhook"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookLong = `Run gocover as a bot that serves GitHub webhooks.
+
+The bot responds the gocover commands in pull request comments:
+  /gocover rerun                  rerun the diff coverage analysis
+  /gocover report [diff|full]     report the diff coverage or the full coverage
+  /gocover explain {file}:{line}  explain the coverage state of a line
+
+The finished runs are stored and queried by the GraphQL API on /graphql,
+they're kept in memory unless --history-dir is set.
+
+The deploy gate on /deploy answers whether a commit is allowed to deploy by the stored runs
+and the --deploy-* policy, which is asked by the deploy-gate command.
+
+With --queue-dir, the submissions are kept in a durable queue and processed by the workers,
+the webhook replies the status url of the submission, which is polled on /queue/{id}.
+
+Every flag can be set by the environment variable GOCOVER_WEBHOOK_{FLAG}, such as
+GOCOVER_WEBHOOK_HISTORY_DIR for --history-dir, and GOCOVER_WEBHOOK_{FLAG}_FILE reads
+the value from a file, such as a mounted secret. The flags on the command line take precedence.
+SIGHUP reloads the variables, the files and the tenants config without dropping the requests.
+`
+
+	webhookExample = `# Serve the webhook on port 8080, verify the payload signature and reply with the token.
+export GOCOVER_WEBHOOK_SECRET=xxxx
+export GITHUB_TOKEN=xxxx
+gocover webhook --listen :8080 --webhook-secret env:GOCOVER_WEBHOOK_SECRET --github-token env:GITHUB_TOKEN
+
+# Keep the runs in a directory and authorize the GraphQL requests by the bearer token.
+gocover webhook --history-dir /var/lib/gocover/runs --api-token env:GOCOVER_API_TOKEN
+
+# Serve the organizations of tenants.yaml, each with its own credentials, storage and config.
+gocover webhook --tenants-config tenants.yaml --history-dir /var/lib/gocover/runs
+
+# Queue the submissions for 4 workers, and poll the status of a submission.
+gocover webhook --queue-dir /var/lib/gocover/queue --queue-workers 4
+curl -H "Authorization: Bearer $GOCOVER_API_TOKEN" http://localhost:8080/queue/{id}
+
+# Dispatch each analysis as a Kubernetes Job on the larger nodes.
+gocover webhook --runner kubernetes --job-image ghcr.io/azure/gocover:latest --job-token-secret gocover:github-token \
+  --job-cpu-request 2 --job-memory-limit 8Gi --job-node-selector agentpool=large
+
+# Configure by the environment in a container, then reload the changed config map.
+export GOCOVER_WEBHOOK_LISTEN=:8080
+export GOCOVER_WEBHOOK_TENANTS_CONFIG=/etc/gocover/tenants.yaml
+export GOCOVER_WEBHOOK_MAX_ANNOTATIONS_FILE=/etc/gocover/config/max-annotations
+gocover webhook &
+kill -HUP $!
+`
+)
+
+// runners of the webhook command.
+const (
+	localRunnerMode      = "local"
+	kubernetesRunnerMode = "kubernetes"
+)
+
+// webhookEnvPrefix is the prefix of the environment variables that set the flags of the webhook command.
+const webhookEnvPrefix = "GOCOVER_WEBHOOK_"
+
+type webhookOption struct {
+	address      string
+	secretSpec   string
+	tokenSpec    string
+	githubAPIURL string
+	verbosity    string
+	historyDir   string
+	apiTokenSpec string
+	tenantsFile  string
+	thresholds   report.Thresholds
+	commenters   []string
+	runner       webhook.RunnerOption
+
+	resultWebhooks resultWebhookFlags
+	deployPolicy   gate.Policy
+
+	queueDir       string
+	queueWorkers   int
+	queueRetention time.Duration
+
+	runnerMode       string
+	job              webhook.JobRunnerOption
+	jobCPURequest    string
+	jobCPULimit      string
+	jobMemoryRequest string
+	jobMemoryLimit   string
+	kubeClient       *kube.Client
+
+	env *envBinder
+	// stores keeps the run stores by the storage prefix, so the stored runs survive the reloads.
+	stores map[string]history.Store
+}
+
+func newWebhookCommand() *cobra.Command {
+	o := &webhookOption{stores: make(map[string]history.Store)}
+
+	cmd := &cobra.Command{
+		Use:     "webhook",
+		Short:   "run gocover as a bot that responds commands in pull request comments",
+		Long:    webhookLong,
+		Example: webhookExample,
+		// the environment variables are applied before the root command uses the persistent flags.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			o.env = newEnvBinder(cmd.Flags(), webhookEnvPrefix)
+			if err := o.env.apply(); err != nil {
+				return err
+			}
+			return cmd.Root().PersistentPreRunE(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := createLogger(cmd)
+
+			recorder := audit.NewRecorder(auditFile)
+			tenants, err := o.tenants(recorder, logger)
+			if err != nil {
+				return err
+			}
+			option := &webhook.ServerOption{
+				Recorder:       recorder,
+				Tenants:        tenants,
+				Workers:        o.queueWorkers,
+				QueueRetention: o.queueRetention,
+				Logger:         logger,
+			}
+			if o.queueDir != "" {
+				if option.Queue, err = queue.NewFileQueue(o.queueDir); err != nil {
+					return err
+				}
+			}
+			server := webhook.NewServer(option)
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			go o.reloadOnHangup(ctx, server, recorder, logger)
+			return server.ListenAndServe(ctx, o.address)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.address, "listen", ":8080", "address that the webhook server listens on")
+	cmd.Flags().StringVar(&o.secretSpec, "webhook-secret", "", "credential spec of the webhook secret, such as env:GOCOVER_WEBHOOK_SECRET or file:/path/to/secret, it's required unless each tenant of --tenants-config sets its own")
+	cmd.Flags().StringVar(&o.tokenSpec, "github-token", "env:GITHUB_TOKEN", "credential spec of the GitHub token, one of env:{name}, file:{path}, azure-msi:{resource}, github-oidc:{audience}")
+	cmd.Flags().StringVar(&o.githubAPIURL, "github-api-url", scm.DefaultGitHubAPIURL, "GitHub api url, use https://{host}/api/v3 for GitHub Enterprise Server")
+	cmd.Flags().StringVar(&o.verbosity, "comment-verbosity", string(report.AutoVerbosity), "verbosity of the coverage comments, one of minimal, summary, detailed, auto; can be overridden by the verbosity parameter of a command")
+	cmd.Flags().IntVar(&o.thresholds.MaxDetailedViolations, "max-detailed-violations", report.DefaultMaxDetailedViolations, "maximum violation lines that auto verbosity annotates the uncovered lines")
+	cmd.Flags().IntVar(&o.thresholds.MaxSummaryEffectiveLines, "max-summary-effective-lines", report.DefaultMaxSummaryEffectiveLines, "maximum effective lines that auto verbosity renders the coverage table, larger changes get a one-line status")
+	cmd.Flags().IntVar(&o.thresholds.MaxAnnotations, "max-annotations", report.DefaultMaxAnnotations, "maximum uncovered sections annotated in a detailed comment")
+	cmd.Flags().StringVar(&o.historyDir, "history-dir", "", "directory that the finished runs are stored in for the GraphQL API, the runs are kept in memory if it's empty")
+	cmd.Flags().StringVar(&o.apiTokenSpec, "api-token", "", "credential spec of the bearer token that authorizes the GraphQL requests, such as env:GOCOVER_API_TOKEN")
+	cmd.Flags().StringSliceVar(&o.commenters, "commenters", nil, "users allowed to run the analysis commands besides the ones with the write permission on the repository")
+	cmd.Flags().StringVar(&o.tenantsFile, "tenants-config", "", "YAML or JSON file of the tenants, each tenant serves its repositories with its own credentials, storage prefix and config")
+	cmd.Flags().StringVar(&o.queueDir, "queue-dir", "", "directory of the durable queue, the submissions are queued and processed by the workers asynchronously if it's set")
+	cmd.Flags().IntVar(&o.queueWorkers, "queue-workers", 2, "number of the workers that process the queued submissions")
+	cmd.Flags().DurationVar(&o.queueRetention, "queue-retention", 24*time.Hour, "how long the finished submissions are kept for the status API, they're kept forever if it's zero")
+	cmd.Flags().StringVar(&o.runnerMode, "runner", localRunnerMode, "where the analyses run, local runs them in the webhook process, kubernetes dispatches each of them as a Job in the cluster")
+	cmd.Flags().StringVar(&o.job.Namespace, "job-namespace", "", "namespace of the analysis Jobs, the namespace of the webhook pod is used if it's empty")
+	cmd.Flags().StringVar(&o.job.Image, "job-image", "", "gocover image of the analysis Jobs, it should contain the go toolchain that the repositories need")
+	cmd.Flags().StringVar(&o.job.ServiceAccount, "job-service-account", "", "service account of the analysis Jobs")
+	cmd.Flags().StringVar(&o.job.TokenSecret, "job-token-secret", "", "secret key that contains the GitHub token for the analysis Jobs to clone, in the format of {name}:{key}")
+	cmd.Flags().StringToStringVar(&o.job.NodeSelector, "job-node-selector", nil, "node selector of the analysis Jobs, such as agentpool=large")
+	cmd.Flags().StringVar(&o.jobCPURequest, "job-cpu-request", "", "cpu request of the analysis Jobs, such as 2")
+	cmd.Flags().StringVar(&o.jobCPULimit, "job-cpu-limit", "", "cpu limit of the analysis Jobs")
+	cmd.Flags().StringVar(&o.jobMemoryRequest, "job-memory-request", "", "memory request of the analysis Jobs, such as 4Gi")
+	cmd.Flags().StringVar(&o.jobMemoryLimit, "job-memory-limit", "", "memory limit of the analysis Jobs")
+	cmd.Flags().DurationVar(&o.job.Timeout, "job-timeout", time.Hour, "deadline of an analysis Job")
+	addResultWebhookFlags(cmd, &o.resultWebhooks)
+	addDeployPolicyFlags(cmd, &o.deployPolicy, "deploy-")
+	cmd.Flags().StringVar(&o.runner.WorkDir, "workdir", "", "directory that repositories are cloned into, system temp directory is used if it's empty")
+	cmd.Flags().StringVar(&o.runner.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the repository")
+	cmd.Flags().StringSliceVar(&o.runner.Excludes, "excludes", []string{}, "exclude files for coverage calucation")
+	cmd.Flags().Float64Var(&o.runner.CoverageBaseline, "coverage-baseline", 0, "coverage baseline of the analysis")
+
+	return cmd
+}
+
+// tenants creates the tenants of the tenants file, or a single tenant that serves all repositories by the flags.
+func (o *webhookOption) tenants(recorder audit.Recorder, logger logrus.FieldLogger) ([]*webhook.Tenant, error) {
+	verbosity, err := report.ParseVerbosity(o.verbosity)
+	if err != nil {
+		return nil, err
+	}
+	token, err := credential.NewProvider(o.tokenSpec, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("github token: %w", err)
+	}
+
+	if o.tenantsFile != "" {
+		config, err := webhook.LoadTenantsConfig(o.tenantsFile)
+		if err != nil {
+			return nil, err
+		}
+		return o.buildTenants(config, token, verbosity, recorder, logger)
+	}
+
+	deployPolicy := o.deployPolicy
+	t := &webhook.Tenant{
+		Name:             "default",
+		Client:           scm.NewGitHubClient(o.githubAPIURL, token, httpClient, recorder),
+		Verbosity:        verbosity,
+		Thresholds:       o.thresholds,
+		CoverageBaseline: o.runner.CoverageBaseline,
+		DeployPolicy:     &deployPolicy,
+		Commenters:       o.commenters,
+	}
+	// the unsigned payloads are rejected, so the server can't run without the secret.
+	if o.secretSpec == "" {
+		return nil, fmt.Errorf("%w, set --webhook-secret", webhook.ErrMissingSecret)
+	}
+	if t.Secret, err = credential.NewProvider(o.secretSpec, httpClient); err != nil {
+		return nil, fmt.Errorf("webhook secret: %w", err)
+	}
+	if o.apiTokenSpec != "" {
+		if t.APIToken, err = credential.NewProvider(o.apiTokenSpec, httpClient); err != nil {
+			return nil, fmt.Errorf("api token: %w", err)
+		}
+	} else {
+		logger.Warn("api token is not set, GraphQL requests won't be authorized")
+	}
+	if t.Store, err = o.store(""); err != nil {
+		return nil, err
+	}
+	if t.Notifier, err = newNotifier(o.resultWebhooks.configs(), recorder, logger); err != nil {
+		return nil, err
+	}
+
+	// the runner keeps a copy of the option, so a reload doesn't change the runs in processing.
+	runner := o.runner
+	runner.Token = token
+	if t.Runner, err = o.newRunner(&runner, o.job.TokenSecret, logger); err != nil {
+		return nil, err
+	}
+	return []*webhook.Tenant{t}, nil
+}
+
+// newRunner creates the runner that analyzes in the current process, or dispatches the analyses as Kubernetes Jobs.
+func (o *webhookOption) newRunner(runner *webhook.RunnerOption, tokenSecret string, logger logrus.FieldLogger) (webhook.Runner, error) {
+	switch o.runnerMode {
+	case localRunnerMode:
+		return webhook.NewLocalRunner(runner, logger), nil
+	case kubernetesRunnerMode:
+		if o.kubeClient == nil {
+			config, err := kube.InClusterConfig()
+			if err != nil {
+				return nil, err
+			}
+			if o.job.Namespace == "" {
+				o.job.Namespace = config.Namespace
+			}
+			if o.kubeClient, err = kube.NewClient(config); err != nil {
+				return nil, err
+			}
+		}
+		job := o.job
+		job.RunnerOption = *runner
+		job.TokenSecret = tokenSecret
+		job.Resources = kube.ResourceRequirements{
+			Requests: resourceList(o.jobCPURequest, o.jobMemoryRequest),
+			Limits:   resourceList(o.jobCPULimit, o.jobMemoryLimit),
+		}
+		return webhook.NewJobRunner(&job, o.kubeClient, logger)
+	default:
+		return nil, fmt.Errorf("unknown runner '%s', one of: %s, %s", o.runnerMode, localRunnerMode, kubernetesRunnerMode)
+	}
+}
+
+// resourceList returns the cpu and memory quantities that are set.
+func resourceList(cpu, memory string) map[string]string {
+	resources := make(map[string]string)
+	if cpu != "" {
+		resources["cpu"] = cpu
+	}
+	if memory != "" {
+		resources["memory"] = memory
+	}
+	return resources
+}
+
+// store returns the run store of the storage prefix, the store is created once and shared by the reloads.
+func (o *webhookOption) store(prefix string) (history.Store, error) {
+	key := filepath.Join(o.historyDir, prefix)
+	if store, ok := o.stores[key]; ok {
+		return store, nil
+	}
+
+	store := history.NewMemoryStore()
+	if o.historyDir != "" {
+		var err error
+		if store, err = history.NewFileStore(key); err != nil {
+			return nil, err
+		}
+	}
+	o.stores[key] = store
+	return store, nil
+}
+
+// reloadOnHangup reloads the environment variables, the secret files and the tenants file on SIGHUP,
+// the current tenants keep serving if the reload fails. The listen address and the global flags require a restart.
+func (o *webhookOption) reloadOnHangup(ctx context.Context, server *webhook.Server, recorder audit.Recorder, logger logrus.FieldLogger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := o.reload(server, recorder, logger); err != nil {
+				logger.WithError(err).Error("reload config, keep the current config")
+				continue
+			}
+			logger.Info("config reloaded")
+		}
+	}
+}
+
+func (o *webhookOption) reload(server *webhook.Server, recorder audit.Recorder, logger logrus.FieldLogger) error {
+	if err := o.env.apply(); err != nil {
+		return err
+	}
+	tenants, err := o.tenants(recorder, logger)
+	if err != nil {
+		return err
+	}
+	server.SetTenants(tenants)
+	return nil
+}
+
+// buildTenants creates the tenants of the config, the flags are the defaults of the fields that a tenant doesn't set.
+// The stored runs and the work directory of a tenant are isolated under its storage prefix.
+func (o *webhookOption) buildTenants(
+	config *webhook.TenantsConfig,
+	defaultToken credential.Provider,
+	defaultVerbosity report.Verbosity,
+	recorder audit.Recorder,
+	logger logrus.FieldLogger,
+) ([]*webhook.Tenant, error) {
+	var tenants []*webhook.Tenant
+	for _, c := range config.Tenants {
+		logger := logger.WithField("tenant", c.Name)
+		t := &webhook.Tenant{
+			Name:             c.Name,
+			Repositories:     c.Repositories,
+			Verbosity:        defaultVerbosity,
+			Thresholds:       o.thresholds,
+			CoverageBaseline: o.runner.CoverageBaseline,
+			Commenters:       o.commenters,
+		}
+
+		// the webhook secret is required when the config is loaded.
+		p, err := credential.NewProvider(c.WebhookSecret, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s webhook secret: %w", c.Name, err)
+		}
+		t.Secret = p
+
+		token := defaultToken
+		if c.GitHubToken != "" {
+			p, err := credential.NewProvider(c.GitHubToken, httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %s github token: %w", c.Name, err)
+			}
+			token = p
+		}
+		apiURL := o.githubAPIURL
+		if c.GitHubAPIURL != "" {
+			apiURL = c.GitHubAPIURL
+		}
+		t.Client = scm.NewGitHubClient(apiURL, token, httpClient, recorder)
+
+		if c.APIToken != "" {
+			p, err := credential.NewProvider(c.APIToken, httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %s api token: %w", c.Name, err)
+			}
+			t.APIToken = p
+		}
+
+		store, err := o.store(c.StoragePrefix)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", c.Name, err)
+		}
+		t.Store = store
+
+		if t.Notifier, err = newNotifier(c.ResultWebhooks, recorder, logger); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", c.Name, err)
+		}
+
+		if c.CommentVerbosity != "" {
+			// the verbosity is validated when the config is loaded.
+			t.Verbosity, _ = report.ParseVerbosity(c.CommentVerbosity)
+		}
+		if c.CoverageBaseline != nil {
+			t.CoverageBaseline = *c.CoverageBaseline
+		}
+		if c.Commenters != nil {
+			t.Commenters = c.Commenters
+		}
+		t.DeployPolicy = c.DeployPolicy
+		if t.DeployPolicy == nil {
+			deployPolicy := o.deployPolicy
+			t.DeployPolicy = &deployPolicy
+		}
+
+		runner := o.runner
+		runner.Token = token
+		runner.CoverageBaseline = t.CoverageBaseline
+		if c.ModuleDir != "" {
+			runner.ModuleDir = c.ModuleDir
+		}
+		if c.Excludes != nil {
+			runner.Excludes = c.Excludes
+		}
+		if runner.WorkDir != "" {
+			runner.WorkDir = filepath.Join(runner.WorkDir, c.StoragePrefix)
+			if err := os.MkdirAll(runner.WorkDir, os.ModePerm); err != nil {
+				return nil, fmt.Errorf("tenant %s work directory: %w", c.Name, err)
+			}
+		}
+		tokenSecret := o.job.TokenSecret
+		if c.JobTokenSecret != "" {
+			tokenSecret = c.JobTokenSecret
+		}
+		if t.Runner, err = o.newRunner(&runner, tokenSecret, logger); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", c.Name, err)
+		}
+
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/Azure/gocover/pkg/webhook"
+	"github.com/spf13/cobra"
+)
+
+type webhookJobOption struct {
+	pr        scm.PullRequest
+	mode      string
+	tokenSpec string
+	runner    webhook.RunnerOption
+}
+
+// newWebhookJobCommand creates the command that a Kubernetes Job of the webhook runs,
+// it analyzes a pull request and writes the result into the logs for the webhook to read.
+func newWebhookJobCommand() *cobra.Command {
+	o := &webhookJobOption{}
+
+	cmd := &cobra.Command{
+		Use:    webhook.JobCommand,
+		Short:  "analyze a pull request in a Kubernetes Job dispatched by the webhook",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := createLogger(cmd)
+
+			if o.tokenSpec != "" {
+				token, err := credential.NewProvider(o.tokenSpec, httpClient)
+				if err != nil {
+					return fmt.Errorf("github token: %w", err)
+				}
+				o.runner.Token = token
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			statistics, err := webhook.NewLocalRunner(&o.runner, logger).Run(ctx, &o.pr, gocover.CoverageMode(o.mode))
+			if err != nil {
+				return err
+			}
+			return webhook.WriteJobResult(cmd.OutOrStdout(), statistics)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.pr.Owner, "owner", "", "owner of the repository")
+	cmd.Flags().StringVar(&o.pr.Repository, "repository", "", "name of the repository")
+	cmd.Flags().IntVar(&o.pr.Number, "number", 0, "number of the pull request")
+	cmd.Flags().StringVar(&o.pr.CloneURL, "clone-url", "", "url to clone the repository")
+	cmd.Flags().StringVar(&o.pr.HeadSHA, "head-sha", "", "commit sha of the pull request head")
+	cmd.Flags().StringVar(&o.pr.BaseRef, "base-ref", "", "branch name that the pull request merges into")
+	cmd.Flags().StringVar(&o.mode, "mode", string(gocover.DiffCoverage), "coverage mode, diff or full")
+	cmd.Flags().StringVar(&o.tokenSpec, "github-token", "", "credential spec of the GitHub token to clone private repositories")
+	cmd.Flags().StringVar(&o.runner.WorkDir, "workdir", "", "directory that the repository is cloned into, system temp directory is used if it's empty")
+	cmd.Flags().StringVar(&o.runner.ModuleDir, "module-dir", "./", "module directory contains go.mod file that relative to the repository")
+	cmd.Flags().StringSliceVar(&o.runner.Excludes, "excludes", []string{}, "exclude files for coverage calucation")
+	cmd.Flags().Float64Var(&o.runner.CoverageBaseline, "coverage-baseline", 0, "coverage baseline of the analysis")
+	for _, flag := range []string{"owner", "repository", "clone-url", "head-sha"} {
+		cmd.MarkFlagRequired(flag)
+	}
+
+	return cmd
+}
@@ -0,0 +1,220 @@
+package covergate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The rules of a policy.
+const (
+	// CoverageRule checks the coverage against the coverage baseline.
+	CoverageRule = "coverage"
+	// FileRule checks the coverage of each file against the file baseline.
+	FileRule = "file"
+	// RegressionRule checks the coverage of each touched file against its coverage on the compared branch.
+	RegressionRule = "regression"
+	// LayerRule checks the coverage of a layer against the baseline of the layer.
+	LayerRule = "layer"
+	// PackageRule checks the coverage of a package against the baseline of the package.
+	PackageRule = "package"
+	// TodosRule checks the number of the added TODO and FIXME comments.
+	TodosRule = "todos"
+	// TodoDensityRule checks the added TODO and FIXME comments per 100 added lines.
+	TodoDensityRule = "todo-density"
+)
+
+// Stats is the coverage that the gate evaluates.
+type Stats struct {
+	// CoveragePercent is the coverage of the counted lines, such as the changed lines of a diff.
+	CoveragePercent float64
+	// Files are the coverage of the counted files.
+	Files []*File
+	// Regressions are the coverage of the touched files on the compared branch and now.
+	Regressions []*Regression
+	// Layers are the coverage of the layers of the code.
+	Layers []*Layer
+	// Packages are the coverage of the packages of the code.
+	Packages []*Package
+	// AddedTodos is the number of the TODO and FIXME comments added.
+	AddedTodos int
+	// AddedLines is the number of the lines added, it's the denominator of the todo density.
+	AddedLines int
+	// Bypassed indicates the failing gate is bypassed, such as by a pull request label.
+	Bypassed bool
+}
+
+// File is the coverage of a file.
+type File struct {
+	Name            string
+	CoveragePercent float64
+}
+
+// Regression is the coverage of a file on the compared branch and now.
+type Regression struct {
+	Name                string
+	BaseCoveragePercent float64
+	CoveragePercent     float64
+}
+
+// Layer is the coverage of a layer.
+type Layer struct {
+	Name            string
+	CoveragePercent float64
+}
+
+// Package is the coverage of a package.
+type Package struct {
+	Name            string
+	CoveragePercent float64
+}
+
+// Policy is the limits of the gate.
+type Policy struct {
+	// CoverageBaseline is the minimum coverage, it's not gated if it's zero.
+	CoverageBaseline float64
+	// FileBaseline is the minimum coverage of each file, the files are not gated if it's zero.
+	FileBaseline float64
+	// MaxRegression is the maximum coverage percent that a file drops from the compared branch, it's not gated if it's negative.
+	MaxRegression float64
+	// LayerBaselines are the minimum coverage of the layers by the name, a layer without a positive baseline is not gated.
+	LayerBaselines map[string]float64
+	// PackageBaselines are the minimum coverage of the packages by the import path, a package without a positive baseline is not gated.
+	PackageBaselines map[string]float64
+	// MaxAddedTodos is the maximum number of the added comments, it's not gated if it's negative.
+	MaxAddedTodos int
+	// MaxTodoDensity is the maximum added comments per 100 added lines, it's not gated if it's negative.
+	MaxTodoDensity float64
+}
+
+// NewPolicy creates the policy of the coverage baseline, the regressions and the comments are not gated.
+func NewPolicy(coverageBaseline float64) *Policy {
+	return &Policy{CoverageBaseline: coverageBaseline, MaxRegression: -1, MaxAddedTodos: -1, MaxTodoDensity: -1}
+}
+
+// Decision is the answer whether the stats pass the gate.
+type Decision struct {
+	// Passed indicates there is no violation, or the failing gate is bypassed.
+	Passed bool
+	// Bypassed indicates the gate fails but it's bypassed.
+	Bypassed bool
+	// Violations are the failed rules in the order of the coverage, the files, the regressions, the layers, the packages and the comments.
+	Violations []*Violation
+}
+
+// Violation is a failed rule of the policy.
+type Violation struct {
+	// Rule is one of the rules.
+	Rule string
+	// File is the name of the file of the file rule and the regression rule.
+	File string `json:",omitempty"`
+	// Layer is the name of the layer of the layer rule.
+	Layer string `json:",omitempty"`
+	// Package is the import path of the package of the package rule.
+	Package string `json:",omitempty"`
+	// Actual is the value that fails the rule.
+	Actual float64
+	// Limit is the limit of the rule.
+	Limit float64
+	// Message describes the violation.
+	Message string
+}
+
+// Evaluate evaluates the policy on the stats.
+func Evaluate(stats *Stats, policy *Policy) *Decision {
+	decision := &Decision{}
+	if stats.CoveragePercent < policy.CoverageBaseline {
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    CoverageRule,
+			Actual:  stats.CoveragePercent,
+			Limit:   policy.CoverageBaseline,
+			Message: fmt.Sprintf("the coverage baseline pass rate is %.2f, currently is %.2f", policy.CoverageBaseline, stats.CoveragePercent),
+		})
+	}
+	for _, f := range stats.Files {
+		if policy.FileBaseline <= 0 || f.CoveragePercent >= policy.FileBaseline {
+			continue
+		}
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    FileRule,
+			File:    f.Name,
+			Actual:  f.CoveragePercent,
+			Limit:   policy.FileBaseline,
+			Message: fmt.Sprintf("the coverage of file %s is %.2f, lower than the file baseline %.2f", f.Name, f.CoveragePercent, policy.FileBaseline),
+		})
+	}
+	for _, r := range stats.Regressions {
+		if policy.MaxRegression < 0 || r.CoveragePercent >= r.BaseCoveragePercent-policy.MaxRegression {
+			continue
+		}
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    RegressionRule,
+			File:    r.Name,
+			Actual:  r.CoveragePercent,
+			Limit:   r.BaseCoveragePercent - policy.MaxRegression,
+			Message: fmt.Sprintf("the coverage of file %s drops from %.2f to %.2f, more than the tolerance %.2f", r.Name, r.BaseCoveragePercent, r.CoveragePercent, policy.MaxRegression),
+		})
+	}
+	for _, l := range stats.Layers {
+		baseline := policy.LayerBaselines[l.Name]
+		if baseline <= 0 || l.CoveragePercent >= baseline {
+			continue
+		}
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    LayerRule,
+			Layer:   l.Name,
+			Actual:  l.CoveragePercent,
+			Limit:   baseline,
+			Message: fmt.Sprintf("the coverage of layer %s is %.2f, lower than its baseline %.2f", l.Name, l.CoveragePercent, baseline),
+		})
+	}
+	for _, p := range stats.Packages {
+		baseline := policy.PackageBaselines[p.Name]
+		if baseline <= 0 || p.CoveragePercent >= baseline {
+			continue
+		}
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    PackageRule,
+			Package: p.Name,
+			Actual:  p.CoveragePercent,
+			Limit:   baseline,
+			Message: fmt.Sprintf("the coverage of package %s is %.2f, lower than its baseline %.2f", p.Name, p.CoveragePercent, baseline),
+		})
+	}
+	if policy.MaxAddedTodos >= 0 && stats.AddedTodos > policy.MaxAddedTodos {
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    TodosRule,
+			Actual:  float64(stats.AddedTodos),
+			Limit:   float64(policy.MaxAddedTodos),
+			Message: fmt.Sprintf("%d TODO/FIXME comments are added, the limit is %d", stats.AddedTodos, policy.MaxAddedTodos),
+		})
+	}
+	if density := todoDensity(stats); policy.MaxTodoDensity >= 0 && density > policy.MaxTodoDensity {
+		decision.Violations = append(decision.Violations, &Violation{
+			Rule:    TodoDensityRule,
+			Actual:  density,
+			Limit:   policy.MaxTodoDensity,
+			Message: fmt.Sprintf("%.2f TODO/FIXME comments are added per 100 lines, the limit is %.2f", density, policy.MaxTodoDensity),
+		})
+	}
+
+	decision.Passed = len(decision.Violations) == 0 || stats.Bypassed
+	decision.Bypassed = len(decision.Violations) != 0 && stats.Bypassed
+	return decision
+}
+
+// Summary returns the messages of the violations in one line, it's empty if there is no violation.
+func (d *Decision) Summary() string {
+	messages := make([]string, 0, len(d.Violations))
+	for _, v := range d.Violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// todoDensity returns the added comments per 100 added lines.
+func todoDensity(stats *Stats) float64 {
+	if stats.AddedLines == 0 {
+		return 0
+	}
+	return float64(stats.AddedTodos) * 100 / float64(stats.AddedLines)
+}
@@ -0,0 +1,89 @@
+package covergate
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	policy := NewPolicy(80)
+	policy.FileBaseline = 60
+	policy.MaxRegression = 1
+	policy.LayerBaselines = map[string]float64{"domain": 90, "storage": 0}
+	policy.PackageBaselines = map[string]float64{"example.com/foo/auth": 95}
+	policy.MaxAddedTodos = 1
+	policy.MaxTodoDensity = 10
+
+	testSuites := []struct {
+		name       string
+		stats      *Stats
+		passed     bool
+		bypassed   bool
+		violations []string
+	}{
+		{
+			name:   "passed",
+			stats:  &Stats{CoveragePercent: 80, Files: []*File{{Name: "foo.go", CoveragePercent: 60}}, Regressions: []*Regression{{Name: "foo.go", BaseCoveragePercent: 61, CoveragePercent: 60}}, Layers: []*Layer{{Name: "domain", CoveragePercent: 90}, {Name: "storage", CoveragePercent: 10}}, Packages: []*Package{{Name: "example.com/foo/auth", CoveragePercent: 95}, {Name: "example.com/foo/cmd", CoveragePercent: 0}}, AddedTodos: 1, AddedLines: 10},
+			passed: true,
+		},
+		{
+			name:       "all rules fail",
+			stats:      &Stats{CoveragePercent: 50, Files: []*File{{Name: "foo.go", CoveragePercent: 100}, {Name: "bar.go", CoveragePercent: 40}}, Regressions: []*Regression{{Name: "bar.go", BaseCoveragePercent: 50, CoveragePercent: 40}}, Layers: []*Layer{{Name: "domain", CoveragePercent: 80}, {Name: "cmd", CoveragePercent: 0}}, Packages: []*Package{{Name: "example.com/foo/auth", CoveragePercent: 90}}, AddedTodos: 2, AddedLines: 10},
+			violations: []string{CoverageRule, FileRule, RegressionRule, LayerRule, PackageRule, TodosRule, TodoDensityRule},
+		},
+		{
+			name:       "file below baseline",
+			stats:      &Stats{CoveragePercent: 90, Files: []*File{{Name: "foo.go", CoveragePercent: 100}, {Name: "bar.go", CoveragePercent: 59.9}}},
+			violations: []string{FileRule},
+		},
+		{
+			name:       "file regression",
+			stats:      &Stats{CoveragePercent: 90, Regressions: []*Regression{{Name: "foo.go", BaseCoveragePercent: 80, CoveragePercent: 78.9}}},
+			violations: []string{RegressionRule},
+		},
+		{
+			name:       "bypassed",
+			stats:      &Stats{CoveragePercent: 50, Bypassed: true},
+			passed:     true,
+			bypassed:   true,
+			violations: []string{CoverageRule},
+		},
+		{
+			name:   "bypass without violation",
+			stats:  &Stats{CoveragePercent: 100, Bypassed: true},
+			passed: true,
+		},
+	}
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			decision := Evaluate(testCase.stats, policy)
+			if decision.Passed != testCase.passed || decision.Bypassed != testCase.bypassed {
+				t.Errorf("expect passed %t and bypassed %t, but get %+v", testCase.passed, testCase.bypassed, decision)
+			}
+			if len(decision.Violations) != len(testCase.violations) {
+				t.Fatalf("expect violations %v, but get %d", testCase.violations, len(decision.Violations))
+			}
+			for i, v := range decision.Violations {
+				if v.Rule != testCase.violations[i] {
+					t.Errorf("expect violations %v, but get %s at %d", testCase.violations, v.Rule, i)
+				}
+			}
+		})
+	}
+}
+
+func TestDecisionSummary(t *testing.T) {
+	decision := Evaluate(&Stats{CoveragePercent: 50, Layers: []*Layer{{Name: "domain", CoveragePercent: 80}}}, &Policy{
+		CoverageBaseline: 80,
+		LayerBaselines:   map[string]float64{"domain": 90},
+		MaxAddedTodos:    -1,
+		MaxTodoDensity:   -1,
+	})
+	expect := "the coverage baseline pass rate is 80.00, currently is 50.00; the coverage of layer domain is 80.00, lower than its baseline 90.00"
+	if summary := decision.Summary(); summary != expect {
+		t.Errorf("expect %q, but get %q", expect, summary)
+	}
+	if v := decision.Violations[1]; v.Layer != "domain" || v.Actual != 80 || v.Limit != 90 {
+		t.Errorf("unexpected violation %+v", v)
+	}
+	if summary := Evaluate(&Stats{}, NewPolicy(0)).Summary(); summary != "" {
+		t.Errorf("expect no summary, but get %q", summary)
+	}
+}
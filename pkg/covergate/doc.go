@@ -0,0 +1,4 @@
+// Package covergate evaluates a coverage gate policy on the coverage statistics, decoupled from how the coverage
+// is computed, so the tools that already compute the coverage reuse the same gate as gocover. It depends only on
+// the standard library.
+package covergate
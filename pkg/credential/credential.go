@@ -0,0 +1,306 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+// Kind indicates where the token comes from.
+type Kind string
+
+const (
+	EnvKind        Kind = "env"
+	FileKind       Kind = "file"
+	AzureMSIKind   Kind = "azure-msi"
+	GitHubOIDCKind Kind = "github-oidc"
+	// AzurePipelinesOIDCKind exchanges the OIDC token of an Azure Pipelines service connection
+	// for a Microsoft Entra token of Azure DevOps, the value is the id of the service connection.
+	AzurePipelinesOIDCKind Kind = "azure-pipelines-oidc"
+
+	// separator separates kind and value of the provider spec.
+	separator = ":"
+)
+
+const (
+	// azureIMDSEndpoint is the Azure instance metadata service endpoint for managed identity.
+	azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// azureClientIDEnv is the client id of the user assigned managed identity.
+	azureClientIDEnv = "AZURE_CLIENT_ID"
+
+	// environments injected by GitHub Actions when the workflow has `id-token: write` permission.
+	githubOIDCRequestURLEnv   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	githubOIDCRequestTokenEnv = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+
+	// environments of Azure Pipelines, $(System.AccessToken) has to be mapped to the environment of the step.
+	azurePipelinesOIDCRequestURIEnv = "SYSTEM_OIDCREQUESTURI"
+	azurePipelinesAccessTokenEnv    = "SYSTEM_ACCESSTOKEN"
+	// azureTenantIDEnv is the tenant of the app registration or the managed identity of the service connection.
+	azureTenantIDEnv = "AZURE_TENANT_ID"
+	// entraTokenEndpoint is the token endpoint of the Microsoft Entra tenant.
+	entraTokenEndpoint = "https://login.microsoftonline.com/%s/oauth2/v2.0/token"
+	// azureDevOpsScope is the scope of Azure DevOps, 499b84ac-1321-427f-aa17-267ca6975798 is the application id of Azure DevOps.
+	azureDevOpsScope = "499b84ac-1321-427f-aa17-267ca6975798/.default"
+)
+
+var (
+	ErrUnknownKind   = errors.New("unknown credential kind")
+	ErrWrongSpec     = errors.New("wrong credential spec, format is {kind}:{value}")
+	ErrEmptyToken    = errors.New("empty token")
+	ErrTokenResponse = errors.New("unexpected token response")
+)
+
+// Provider provides the token for authentication.
+type Provider interface {
+	// Token returns the token, the token is registered to the redactor before returning.
+	Token(ctx context.Context) (string, error)
+}
+
+// NewProvider creates the provider according to the spec, httpClient is used by the providers that request token remotely.
+func NewProvider(spec string, httpClient *http.Client) (Provider, error) {
+	kind, value, ok := strings.Cut(spec, separator)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%w: %s", ErrWrongSpec, spec)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	switch Kind(kind) {
+	case EnvKind:
+		return &envProvider{name: value}, nil
+	case FileKind:
+		return &fileProvider{filename: value}, nil
+	case AzureMSIKind:
+		return &azureMSIProvider{
+			endpoint: azureIMDSEndpoint,
+			resource: value,
+			clientID: os.Getenv(azureClientIDEnv),
+			client:   httpClient,
+		}, nil
+	case GitHubOIDCKind:
+		return &githubOIDCProvider{
+			audience:     value,
+			requestURL:   os.Getenv(githubOIDCRequestURLEnv),
+			requestToken: os.Getenv(githubOIDCRequestTokenEnv),
+			client:       httpClient,
+		}, nil
+	case AzurePipelinesOIDCKind:
+		return &azurePipelinesOIDCProvider{
+			serviceConnectionID: value,
+			requestURI:          os.Getenv(azurePipelinesOIDCRequestURIEnv),
+			accessToken:         os.Getenv(azurePipelinesAccessTokenEnv),
+			clientID:            os.Getenv(azureClientIDEnv),
+			tokenEndpoint:       fmt.Sprintf(entraTokenEndpoint, url.PathEscape(os.Getenv(azureTenantIDEnv))),
+			client:              httpClient,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+}
+
+// checkToken validates the token and registers it to the redactor.
+func checkToken(token string, source string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("%w from %s", ErrEmptyToken, source)
+	}
+	redact.AddSecret(token)
+	return token, nil
+}
+
+// envProvider reads token from environment variable.
+type envProvider struct {
+	name string
+}
+
+var _ Provider = (*envProvider)(nil)
+
+func (p *envProvider) Token(ctx context.Context) (string, error) {
+	return checkToken(os.Getenv(p.name), fmt.Sprintf("environment %s", p.name))
+}
+
+// fileProvider reads token from file, such as a mounted secret.
+type fileProvider struct {
+	filename string
+}
+
+var _ Provider = (*fileProvider)(nil)
+
+func (p *fileProvider) Token(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(p.filename)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	return checkToken(string(data), fmt.Sprintf("file %s", p.filename))
+}
+
+// azureMSIProvider requests token from Azure managed identity.
+type azureMSIProvider struct {
+	endpoint string
+	resource string
+	clientID string
+	client   *http.Client
+}
+
+var _ Provider = (*azureMSIProvider)(nil)
+
+func (p *azureMSIProvider) Token(ctx context.Context) (string, error) {
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", p.resource)
+	if p.clientID != "" {
+		query.Set("client_id", p.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	result := &struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := doTokenRequest(p.client, req, result); err != nil {
+		return "", fmt.Errorf("azure managed identity: %w", err)
+	}
+	return checkToken(result.AccessToken, "azure managed identity")
+}
+
+// githubOIDCProvider requests OIDC token from GitHub Actions.
+type githubOIDCProvider struct {
+	audience     string
+	requestURL   string
+	requestToken string
+	client       *http.Client
+}
+
+var _ Provider = (*githubOIDCProvider)(nil)
+
+func (p *githubOIDCProvider) Token(ctx context.Context) (string, error) {
+	if p.requestURL == "" || p.requestToken == "" {
+		return "", fmt.Errorf("%s and %s are required, make sure the workflow has 'id-token: write' permission",
+			githubOIDCRequestURLEnv, githubOIDCRequestTokenEnv)
+	}
+
+	u, err := url.Parse(p.requestURL)
+	if err != nil {
+		return "", fmt.Errorf("parse %s: %w", githubOIDCRequestURLEnv, err)
+	}
+	query := u.Query()
+	query.Set("audience", p.audience)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.requestToken)
+
+	result := &struct {
+		Value string `json:"value"`
+	}{}
+	if err := doTokenRequest(p.client, req, result); err != nil {
+		return "", fmt.Errorf("github oidc: %w", err)
+	}
+	return checkToken(result.Value, "github oidc")
+}
+
+// azurePipelinesOIDCProvider requests the OIDC token of a service connection from Azure Pipelines,
+// and exchanges it for a Microsoft Entra token of Azure DevOps by the workload identity federation.
+type azurePipelinesOIDCProvider struct {
+	serviceConnectionID string
+	requestURI          string
+	accessToken         string
+	clientID            string
+	tokenEndpoint       string
+	client              *http.Client
+}
+
+var _ Provider = (*azurePipelinesOIDCProvider)(nil)
+
+func (p *azurePipelinesOIDCProvider) Token(ctx context.Context) (string, error) {
+	if p.requestURI == "" || p.accessToken == "" {
+		return "", fmt.Errorf("%s and %s are required, make sure $(System.AccessToken) is mapped to the environment of the step",
+			azurePipelinesOIDCRequestURIEnv, azurePipelinesAccessTokenEnv)
+	}
+	if p.clientID == "" {
+		return "", fmt.Errorf("%s and %s of the service connection are required", azureClientIDEnv, azureTenantIDEnv)
+	}
+
+	u, err := url.Parse(p.requestURI)
+	if err != nil {
+		return "", fmt.Errorf("parse %s: %w", azurePipelinesOIDCRequestURIEnv, err)
+	}
+	query := u.Query()
+	query.Set("api-version", "7.1")
+	query.Set("serviceConnectionId", p.serviceConnectionID)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	oidc := &struct {
+		OIDCToken string `json:"oidcToken"`
+	}{}
+	if err := doTokenRequest(p.client, req, oidc); err != nil {
+		return "", fmt.Errorf("azure pipelines oidc: %w", err)
+	}
+	if oidc.OIDCToken == "" {
+		return "", fmt.Errorf("%w from azure pipelines oidc", ErrEmptyToken)
+	}
+	redact.AddSecret(oidc.OIDCToken)
+
+	form := url.Values{}
+	form.Set("client_id", p.clientID)
+	form.Set("scope", azureDevOpsScope)
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", oidc.OIDCToken)
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	result := &struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := doTokenRequest(p.client, req, result); err != nil {
+		return "", fmt.Errorf("microsoft entra token exchange: %w", err)
+	}
+	return checkToken(result.AccessToken, "azure pipelines oidc")
+}
+
+func doTokenRequest(client *http.Client, req *http.Request, result interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrTokenResponse, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("%w: %s", ErrTokenResponse, err)
+	}
+	return nil
+}
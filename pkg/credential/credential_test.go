@@ -0,0 +1,180 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+func TestNewProvider(t *testing.T) {
+	testSuites := []struct {
+		spec string
+		err  error
+	}{
+		{spec: "env:GITHUB_TOKEN"},
+		{spec: "file:/var/run/secrets/token"},
+		{spec: "azure-msi:https://storage.azure.com/"},
+		{spec: "github-oidc:api://AzureADTokenExchange"},
+		{spec: "azure-pipelines-oidc:00000000-0000-0000-0000-000000000000"},
+		{spec: "vault:secret", err: ErrUnknownKind},
+		{spec: "env", err: ErrWrongSpec},
+		{spec: "env:", err: ErrWrongSpec},
+	}
+
+	for _, testCase := range testSuites {
+		_, err := NewProvider(testCase.spec, nil)
+		if !errors.Is(err, testCase.err) {
+			t.Errorf("for spec %s, expect error %v, but get %v", testCase.spec, testCase.err, err)
+		}
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("GOCOVER_TEST_TOKEN", "envtokenvalue")
+
+	p, _ := NewProvider("env:GOCOVER_TEST_TOKEN", nil)
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if token != "envtokenvalue" {
+		t.Errorf("expect envtokenvalue, but get %s", token)
+	}
+	if redact.String(token) != redact.Mask {
+		t.Error("token should be registered to redactor")
+	}
+
+	p, _ = NewProvider("env:GOCOVER_TEST_TOKEN_NONEXIST", nil)
+	if _, err := p.Token(context.Background()); !errors.Is(err, ErrEmptyToken) {
+		t.Errorf("expect %s, but get %v", ErrEmptyToken, err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(filename, []byte("filetokenvalue\n"), 0600); err != nil {
+		t.Fatalf("write token: %s", err)
+	}
+
+	p, _ := NewProvider("file:"+filename, nil)
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if token != "filetokenvalue" {
+		t.Errorf("expect filetokenvalue, but get %s", token)
+	}
+
+	p, _ = NewProvider("file:"+filename+".nonexist", nil)
+	if _, err := p.Token(context.Background()); err == nil {
+		t.Error("should return error, but get nil")
+	}
+}
+
+func TestAzureMSIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("resource") != "https://storage.azure.com/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token": "msitokenvalue"}`))
+	}))
+	defer server.Close()
+
+	p := &azureMSIProvider{endpoint: server.URL, resource: "https://storage.azure.com/", client: server.Client()}
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if token != "msitokenvalue" {
+		t.Errorf("expect msitokenvalue, but get %s", token)
+	}
+
+	p.resource = "https://other"
+	if _, err := p.Token(context.Background()); !errors.Is(err, ErrTokenResponse) {
+		t.Errorf("expect %s, but get %v", ErrTokenResponse, err)
+	}
+}
+
+func TestGitHubOIDCProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer requesttoken" || r.URL.Query().Get("audience") != "gocover" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"value": "oidctokenvalue"}`))
+	}))
+	defer server.Close()
+
+	p := &githubOIDCProvider{audience: "gocover", requestURL: server.URL + "?api-version=2.0", requestToken: "requesttoken", client: server.Client()}
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if token != "oidctokenvalue" {
+		t.Errorf("expect oidctokenvalue, but get %s", token)
+	}
+
+	p.requestURL = ""
+	if _, err := p.Token(context.Background()); err == nil {
+		t.Error("should return error without request url, but get nil")
+	}
+}
+
+func TestAzurePipelinesOIDCProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oidc":
+			if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer systemtoken" || r.URL.Query().Get("serviceConnectionId") != "connection" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"oidcToken": "pipelinesoidctoken"}`))
+		case "/tenant/token":
+			if r.FormValue("client_assertion") != "pipelinesoidctoken" || r.FormValue("client_id") != "client" || r.FormValue("scope") != azureDevOpsScope {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token": "entratokenvalue"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &azurePipelinesOIDCProvider{
+		serviceConnectionID: "connection",
+		requestURI:          server.URL + "/oidc",
+		accessToken:         "systemtoken",
+		clientID:            "client",
+		tokenEndpoint:       server.URL + "/tenant/token",
+		client:              server.Client(),
+	}
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if token != "entratokenvalue" {
+		t.Errorf("expect entratokenvalue, but get %s", token)
+	}
+
+	p.clientID = "other"
+	if _, err := p.Token(context.Background()); !errors.Is(err, ErrTokenResponse) {
+		t.Errorf("expect %s, but get %v", ErrTokenResponse, err)
+	}
+
+	p.accessToken = ""
+	if _, err := p.Token(context.Background()); err == nil {
+		t.Error("should return error without the access token, but get nil")
+	}
+}
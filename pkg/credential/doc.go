@@ -0,0 +1,10 @@
+// Package credential provides the tokens used by the SCM and storage integrations.
+//
+// A provider is described by a spec in the format of {kind}:{value}:
+//   - env:GITHUB_TOKEN reads the token from the environment variable.
+//   - file:/var/run/secrets/token reads the token from the file.
+//   - azure-msi:https://storage.azure.com/ requests the token of the resource from Azure managed identity.
+//   - github-oidc:api://AzureADTokenExchange requests the OIDC token of the audience from GitHub Actions.
+//   - azure-pipelines-oidc:{service connection id} exchanges the OIDC token of the Azure Pipelines service connection
+//     for a Microsoft Entra token of Azure DevOps, the client and the tenant are $AZURE_CLIENT_ID and $AZURE_TENANT_ID.
+package credential
@@ -0,0 +1,4 @@
+// Package daemon keeps the parsed cover profiles, the git repository, and the annotations and the functions of the
+// source files warm in a long-running process, and answers the repeated diff coverage queries over a unix socket,
+// so the editor and the pre-commit integrations get the coverage of the changes in a fraction of a second.
+package daemon
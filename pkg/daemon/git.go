@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+)
+
+// resolveTimeout bounds the time of resolving a revision.
+const resolveTimeout = 10 * time.Second
+
+// cachedGitClient keeps the changes between the commits, which never change once they're calculated,
+// the changes of the working tree are calculated by each request.
+type cachedGitClient struct {
+	gittool.GitClient
+	repositoryPath string
+
+	mu      sync.Mutex
+	changes map[string][]*gittool.Change
+	files   map[string][]string
+}
+
+var _ gittool.GitClient = (*cachedGitClient)(nil)
+
+func newCachedGitClient(client gittool.GitClient, repositoryPath string) *cachedGitClient {
+	c := &cachedGitClient{GitClient: client, repositoryPath: repositoryPath}
+	c.reset()
+	return c
+}
+
+func (c *cachedGitClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = make(map[string][]*gittool.Change)
+	c.files = make(map[string][]string)
+}
+
+func (c *cachedGitClient) DiffChangesFromCommitted(compareBranch string) ([]*gittool.Change, error) {
+	return c.DiffChanges(compareBranch, gittool.HEADTarget)
+}
+
+func (c *cachedGitClient) DiffChanges(compareBranch, target string) ([]*gittool.Change, error) {
+	key, ok := c.key(compareBranch, target)
+	if ok {
+		c.mu.Lock()
+		changes, found := c.changes[key]
+		c.mu.Unlock()
+		if found {
+			return changes, nil
+		}
+	}
+
+	changes, err := c.GitClient.DiffChanges(compareBranch, target)
+	if err != nil || !ok {
+		return changes, err
+	}
+	c.mu.Lock()
+	c.changes[key] = changes
+	c.mu.Unlock()
+	return changes, nil
+}
+
+func (c *cachedGitClient) ChangedFiles(compareBranch, target string) ([]string, error) {
+	key, ok := c.key(compareBranch, target)
+	if ok {
+		c.mu.Lock()
+		files, found := c.files[key]
+		c.mu.Unlock()
+		if found {
+			return files, nil
+		}
+	}
+
+	files, err := c.GitClient.ChangedFiles(compareBranch, target)
+	if err != nil || !ok {
+		return files, err
+	}
+	c.mu.Lock()
+	c.files[key] = files
+	c.mu.Unlock()
+	return files, nil
+}
+
+// key returns the commits of the compared branch and the target, it returns false for the working tree
+// or the revisions that can't be resolved, whose changes are not cached.
+func (c *cachedGitClient) key(compareBranch, target string) (string, bool) {
+	if target == gittool.WorktreeTarget {
+		return "", false
+	}
+	if target == "" {
+		target = gittool.HEADTarget
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	base, err := gittool.ResolveCommit(ctx, c.repositoryPath, compareBranch)
+	if err != nil {
+		return "", false
+	}
+	head, err := gittool.ResolveCommit(ctx, c.repositoryPath, target)
+	if err != nil {
+		return "", false
+	}
+	return base + ".." + head, true
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// The methods of the requests.
+const (
+	// DiffMethod calculates the diff coverage of the changes.
+	DiffMethod = "diff"
+	// StatusMethod returns the stats of the caches, it's used to check the daemon is running.
+	StatusMethod = "status"
+	// ResetMethod drops the caches, such as after go.mod changes.
+	ResetMethod = "reset"
+	// ShutdownMethod stops the daemon after the response.
+	ShutdownMethod = "shutdown"
+)
+
+var ErrDaemon = errors.New("daemon error")
+
+// Request is a query to the daemon, each request and response is a json object on a line,
+// and a connection sends any number of requests.
+type Request struct {
+	Method string `json:"method"`
+	// CoverProfiles are the coverage profiles produced by 'go test', their paths are relative to the working directory of the daemon.
+	CoverProfiles []string `json:"coverProfiles,omitempty"`
+	// CompareBranch is the branch to compare, the default compare branch is used if it's empty.
+	CompareBranch string `json:"compareBranch,omitempty"`
+	// DiffTarget is what compares with the branch, one of HEAD, worktree, or a revision, it's HEAD if it's empty.
+	DiffTarget string `json:"diffTarget,omitempty"`
+	// Excludes are the patterns of the files excluded from the coverage.
+	Excludes []string `json:"excludes,omitempty"`
+	// CoverageBaseline decides whether the response passes the gate.
+	CoverageBaseline float64 `json:"coverageBaseline,omitempty"`
+	// Files are the files whose profiles are returned, such as the file open in the editor. They're relative to the
+	// repository root or absolute, and all the profiles are returned if it's empty. The totals are always of all the changes.
+	Files []string `json:"files,omitempty"`
+}
+
+// Response is the answer of a request.
+type Response struct {
+	// Error is the failure of the request, the other fields are empty if it's not empty.
+	Error string `json:"error,omitempty"`
+	// Statistics is the diff coverage of the changes.
+	Statistics *report.Statistics `json:"statistics,omitempty"`
+	// Passed indicates the coverage of the changes meets the baseline of the request.
+	Passed bool `json:"passed"`
+	// DurationMs is the time of answering the request in milliseconds.
+	DurationMs float64 `json:"durationMs"`
+	// Cache is the hits and the misses of the parsed files since the daemon starts or the caches are reset.
+	Cache parser.CacheStats `json:"cache"`
+}
+
+// Query sends the request to the daemon listening on the socket, and returns its response.
+// The error of the response is returned as ErrDaemon.
+func Query(ctx context.Context, socket string, request *Request) (*Response, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("connect daemon: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	response := &Response{}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		return nil, fmt.Errorf("read response: %w: connection closed", ErrDaemon)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%w: %s", ErrDaemon, response.Error)
+	}
+	return response, nil
+}
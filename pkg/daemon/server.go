@@ -0,0 +1,330 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMessageSize bounds the size of a request or a response line.
+const maxMessageSize = 64 * 1024 * 1024
+
+var ErrDaemonRunning = errors.New("daemon is already running")
+
+// Option contains the input for the daemon.
+type Option struct {
+	// Socket is the path of the unix socket that the daemon listens on.
+	Socket         string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath string
+	// IdleTimeout stops the daemon when no request is received for the duration, such as the daemon started by
+	// an editor that is closed later, the daemon never stops by itself if it's not positive.
+	IdleTimeout time.Duration
+}
+
+// Server answers the queries on the unix socket, the queries are answered one by one,
+// because resolving the packages of the cover profiles depends on the working directory of the process.
+type Server struct {
+	option    *Option
+	cache     *parser.Cache
+	gitClient *cachedGitClient
+	outputDir string
+
+	mu       sync.Mutex
+	listener net.Listener
+	activity chan struct{}
+	done     chan struct{}
+	once     sync.Once
+
+	logger logrus.FieldLogger
+}
+
+// NewServer opens the repository and creates the daemon, the socket is not listened until Serve.
+func NewServer(o *Option, logger logrus.FieldLogger) (*Server, error) {
+	repositoryPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of repo: %w", err)
+	}
+	client, err := gittool.NewGitClient(repositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("git repository: %w", err)
+	}
+	option := *o
+	option.RepositoryPath = repositoryPath
+
+	return &Server{
+		option:    &option,
+		cache:     parser.NewCache(),
+		gitClient: newCachedGitClient(client, repositoryPath),
+		activity:  make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		logger:    logger.WithField("source", "daemon"),
+	}, nil
+}
+
+// Serve listens on the socket and answers the queries until the context is done, a shutdown request is received,
+// or the daemon is idle for the idle timeout. A stale socket left by a daemon that crashed is replaced,
+// but ErrDaemonRunning is returned if another daemon answers on the socket.
+func (s *Server) Serve(ctx context.Context) error {
+	if err := s.removeStaleSocket(ctx); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", s.option.Socket)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", s.option.Socket, err)
+	}
+	defer os.Remove(s.option.Socket)
+
+	outputDir, err := os.MkdirTemp("", "gocover-daemon")
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+	s.outputDir = outputDir
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	s.logger.Infof("listening on %s", s.option.Socket)
+
+	go s.stopWhenDone(ctx)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return fmt.Errorf("accept: %w", err)
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// Shutdown stops accepting the connections, the requests being answered are finished.
+func (s *Server) Shutdown() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+	})
+}
+
+// stopWhenDone shuts the daemon down when the context is done or the daemon is idle.
+func (s *Server) stopWhenDone(ctx context.Context) {
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if s.option.IdleTimeout > 0 {
+		timer = time.NewTimer(s.option.IdleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			s.Shutdown()
+			return
+		case <-s.done:
+			return
+		case <-s.activity:
+			if timer != nil {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(s.option.IdleTimeout)
+			}
+		case <-idle:
+			s.logger.Infof("no request in %s, shutdown", s.option.IdleTimeout)
+			s.Shutdown()
+			return
+		}
+	}
+}
+
+// removeStaleSocket removes the socket file if no daemon answers on it.
+func (s *Server) removeStaleSocket(ctx context.Context) error {
+	if _, err := os.Stat(s.option.Socket); err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := Query(ctx, s.option.Socket, &Request{Method: StatusMethod}); err == nil {
+		return fmt.Errorf("%w on %s", ErrDaemonRunning, s.option.Socket)
+	}
+	s.logger.Warnf("remove the stale socket %s", s.option.Socket)
+	return os.Remove(s.option.Socket)
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		select {
+		case s.activity <- struct{}{}:
+		default:
+		}
+
+		request := &Request{}
+		var response *Response
+		if err := json.Unmarshal(scanner.Bytes(), request); err != nil {
+			response = &Response{Error: fmt.Sprintf("decode request: %s", err)}
+		} else {
+			response = s.Handle(ctx, request)
+		}
+		if err := encoder.Encode(response); err != nil {
+			s.logger.WithError(err).Warn("write response")
+			return
+		}
+		if request.Method == ShutdownMethod && response.Error == "" {
+			s.Shutdown()
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.logger.WithError(err).Warn("read request")
+	}
+}
+
+// Handle answers the request.
+func (s *Server) Handle(ctx context.Context, request *Request) *Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	var response *Response
+	switch request.Method {
+	case DiffMethod:
+		response = s.diff(ctx, request)
+	case StatusMethod, ShutdownMethod:
+		response = &Response{Passed: true}
+	case ResetMethod:
+		s.cache.Reset()
+		s.gitClient.reset()
+		response = &Response{Passed: true}
+	default:
+		response = &Response{Error: fmt.Sprintf("unknown method %q", request.Method)}
+	}
+	response.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	response.Cache = s.cache.Stats()
+	s.logger.Debugf("%s: %.1fms, cache hits %d, misses %d", request.Method, response.DurationMs, response.Cache.Hits, response.Cache.Misses)
+	return response
+}
+
+func (s *Server) diff(ctx context.Context, request *Request) *Response {
+	if len(request.CoverProfiles) == 0 {
+		return &Response{Error: "no cover profile"}
+	}
+
+	capture := &statisticsCapture{}
+	o := gocover.NewDiffOption()
+	o.CoverProfiles = request.CoverProfiles
+	if request.CompareBranch != "" {
+		o.CompareBranch = request.CompareBranch
+	}
+	o.DiffTarget = request.DiffTarget
+	o.RepositoryPath = s.option.RepositoryPath
+	o.ModuleDir = s.option.ModuleDir
+	o.ModulePath = s.option.ModulePath
+	o.Excludes = request.Excludes
+	o.CoverageBaseline = request.CoverageBaseline
+	o.OutputDir = s.outputDir
+	o.ReportName = "daemon"
+	o.ReportFormat = gocover.JSONReportFormat
+	o.Style = "colorful"
+	o.DbOption = &dbclient.DBOption{}
+	o.ParseCache = s.cache
+	o.GitClient = s.gitClient
+	o.ReportGenerators = []report.ReportGenerator{capture}
+	o.Logger = s.logger
+
+	diff, err := gocover.NewDiffCover(o)
+	if err != nil {
+		return &Response{Error: err.Error()}
+	}
+	response := &Response{Passed: true}
+	if err := diff.Run(ctx); err != nil {
+		// the coverage is still answered when it's lower than the baseline.
+		var e *gocover.GoCoverError
+		if !(errors.As(err, &e) && e.ExitCode == gocover.LowCoverageErrorExitCode) || capture.statistics == nil {
+			return &Response{Error: err.Error()}
+		}
+		response.Passed = false
+	}
+	response.Statistics = s.filterFiles(capture.statistics, request.Files)
+	return response
+}
+
+// filterFiles keeps the profiles of the files, the files are relative to the repository root or absolute.
+func (s *Server) filterFiles(statistics *report.Statistics, files []string) *report.Statistics {
+	if len(files) == 0 {
+		return statistics
+	}
+
+	moduleDir := filepath.Join(s.option.RepositoryPath, s.option.ModuleDir)
+	var suffixes []string
+	for _, f := range files {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(s.option.RepositoryPath, f)
+		}
+		rel, err := filepath.Rel(moduleDir, f)
+		if err != nil {
+			continue
+		}
+		suffixes = append(suffixes, "/"+filepath.ToSlash(rel))
+	}
+
+	filtered := *statistics
+	filtered.CoverageProfile = nil
+	for _, p := range statistics.CoverageProfile {
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(p.FileName, suffix) {
+				filtered.CoverageProfile = append(filtered.CoverageProfile, p)
+				break
+			}
+		}
+	}
+	return &filtered
+}
+
+// statisticsCapture is a report generator that keeps the statistics for the response.
+type statisticsCapture struct {
+	statistics *report.Statistics
+}
+
+var _ report.ReportGenerator = (*statisticsCapture)(nil)
+
+func (c *statisticsCapture) GenerateReport(statistics *report.Statistics) error {
+	c.statistics = statistics
+	return nil
+}
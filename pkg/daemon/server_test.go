@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testRepository creates a module whose feature branch adds a function, and a cover profile
+// that covers the first statement of the function, the working directory is changed to the module.
+func testRepository(t *testing.T) string {
+	for _, tool := range []string{"git", "go"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	write := func(name, contents string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("go.mod", "module example.com/foo\n\ngo 1.20\n")
+	write("foo.go", "package foo\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	run("checkout", "-q", "-b", "feature")
+	write("foo.go", "package foo\n\nfunc Foo(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn 0\n}\n")
+	run("commit", "-q", "-am", "add Foo")
+	write("coverage.out", "mode: set\nexample.com/foo/foo.go:3.21,4.11 1 1\nexample.com/foo/foo.go:4.11,6.3 1 0\nexample.com/foo/foo.go:7.2,7.10 1 1\n")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestServer(t *testing.T) {
+	dir := testRepository(t)
+	socket := filepath.Join(dir, "daemon.sock")
+	server, err := NewServer(&Option{Socket: socket, RepositoryPath: dir, ModuleDir: "."}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(context.Background()) }()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for {
+		if _, err := Query(ctx, socket, &Request{Method: StatusMethod}); err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("daemon is not started")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	request := &Request{Method: DiffMethod, CoverProfiles: []string{"coverage.out"}, CompareBranch: "main", CoverageBaseline: 50}
+	first, err := Query(ctx, socket, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Passed || first.Statistics.TotalEffectiveLines != 3 || first.Statistics.TotalCoveredLines != 2 {
+		t.Fatalf("unexpected response %+v", first.Statistics)
+	}
+
+	request.CoverageBaseline = 80
+	second, err := Query(ctx, socket, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Passed || second.Statistics.TotalCoveredLines != 2 {
+		t.Errorf("expect the coverage is lower than the baseline, but get %+v", second)
+	}
+	if second.Cache.Misses != first.Cache.Misses || second.Cache.Hits <= first.Cache.Hits {
+		t.Errorf("expect the files of the second query are read from the cache, but get %+v after %+v", second.Cache, first.Cache)
+	}
+
+	request.Files = []string{"bar.go"}
+	filtered, err := Query(ctx, socket, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Statistics.CoverageProfile) != 0 || filtered.Statistics.TotalEffectiveLines != 3 {
+		t.Errorf("expect no profile of the other files, but get %+v", filtered.Statistics)
+	}
+
+	if _, err := Query(ctx, socket, &Request{Method: "unknown"}); !errors.Is(err, ErrDaemon) {
+		t.Errorf("expect ErrDaemon of the unknown method, but get %v", err)
+	}
+	another, err := NewServer(&Option{Socket: socket, RepositoryPath: dir, ModuleDir: "."}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := another.Serve(ctx); !errors.Is(err, ErrDaemonRunning) {
+		t.Errorf("expect ErrDaemonRunning of the second daemon, but get %v", err)
+	}
+
+	if _, err := Query(ctx, socket, &Request{Method: ShutdownMethod}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expect the daemon stops, but get %v", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("expect the socket is removed, but get %v", err)
+	}
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	dir := testRepository(t)
+	server, err := NewServer(&Option{Socket: filepath.Join(dir, "daemon.sock"), RepositoryPath: dir, IdleTimeout: 10 * time.Millisecond}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(context.Background()); err != nil {
+		t.Errorf("expect the idle daemon stops, but get %v", err)
+	}
+}
@@ -22,6 +22,7 @@ type DbClient interface {
 	StoreIgnoreProfileDataFromFile(ctx context.Context, data []*IgnoreProfileData) error
 	StoreCoverageData(context context.Context, data *CoverageData) error
 	StoreIgnoreProfileData(context context.Context, data *IgnoreProfileData) error
+	StoreBypassData(context context.Context, data *BypassData) error
 }
 
 type CoverageData struct {
@@ -55,6 +56,19 @@ type IgnoreProfileData struct {
 	Extra map[string]interface{} // extra data that passing accordingly
 }
 
+// BypassData records a failing coverage gate that is downgraded to neutral.
+type BypassData struct {
+	PreciseTimestamp time.Time `json:"preciseTimestamp"` // time send to db
+	ModulePath       string    `json:"modulePath"`       // module name, which is declared in go.mod
+	Source           string    `json:"source"`           // where the bypass comes from, such as a label or a comment command
+	Reason           string    `json:"reason"`           // explanation of the bypass
+	Actor            string    `json:"actor"`            // login of the user who requested the bypass, empty if unknown
+	Coverage         float64   `json:"coverage"`         // coverage of the failing gate
+	Baseline         float64   `json:"baseline"`         // coverage baseline of the gate
+
+	Extra map[string]interface{} // extra data that passing accordingly
+}
+
 var ErrUnsupportedDBType = errors.New(`supportted type are "Kusto", unsupported DB client type`)
 
 type DBOption struct {
@@ -74,6 +88,30 @@ func (o *DBOption) Validate() error {
 	return fmt.Errorf("%w: %s", ErrUnsupportedDBType, o.DbType)
 }
 
+// CoverageTarget describes where the coverage data is sent to.
+func (o *DBOption) CoverageTarget() string {
+	return o.target(o.KustoOption.CoverageEvent)
+}
+
+// IgnoreTarget describes where the ignore profile data is sent to.
+func (o *DBOption) IgnoreTarget() string {
+	return o.target(o.KustoOption.IgnoreEvent)
+}
+
+// BypassTarget describes where the bypass data is sent to.
+func (o *DBOption) BypassTarget() string {
+	return o.target(o.KustoOption.BypassEvent)
+}
+
+func (o *DBOption) target(event string) string {
+	switch o.DbType {
+	case Kusto:
+		return fmt.Sprintf("%s:%s/%s/%s", o.DbType, o.KustoOption.Endpoint, o.KustoOption.Database, event)
+	default:
+		return string(o.DbType)
+	}
+}
+
 func (o *DBOption) GetDbClient(logger logrus.FieldLogger) (DbClient, error) {
 	switch o.DbType {
 	case Kusto:
@@ -60,3 +60,30 @@ func TestDBOption(t *testing.T) {
 		})
 	})
 }
+
+func TestDBOptionTarget(t *testing.T) {
+	t.Run("kusto target", func(t *testing.T) {
+		o := &DBOption{
+			DbType: Kusto,
+			KustoOption: KustoOption{
+				Endpoint:      "https://fake.kusto.windows.net",
+				Database:      "TestDB",
+				CoverageEvent: "TestCoverageTable",
+				IgnoreEvent:   "TestIgnoreTable",
+			},
+		}
+		if o.CoverageTarget() != "Kusto:https://fake.kusto.windows.net/TestDB/TestCoverageTable" {
+			t.Errorf("unexpected coverage target %s", o.CoverageTarget())
+		}
+		if o.IgnoreTarget() != "Kusto:https://fake.kusto.windows.net/TestDB/TestIgnoreTable" {
+			t.Errorf("unexpected ignore target %s", o.IgnoreTarget())
+		}
+	})
+
+	t.Run("other target", func(t *testing.T) {
+		o := &DBOption{DbType: None}
+		if o.CoverageTarget() != string(None) {
+			t.Errorf("unexpected coverage target %s", o.CoverageTarget())
+		}
+	})
+}
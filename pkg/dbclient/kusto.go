@@ -10,12 +10,14 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strings"
 
 	"github.com/Azure/azure-kusto-go/kusto"
 	"github.com/Azure/azure-kusto-go/kusto/ingest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/gocover/pkg/redact"
 	"github.com/sirupsen/logrus"
 )
 
@@ -40,7 +42,12 @@ func NewKustoClient(option *KustoOption) (DbClient, error) {
 		Config: auth.NewClientCredentialsConfig(option.clientID, option.clientSecret, option.tenantID),
 	}
 
-	kustoClient, err := kusto.New(option.Endpoint, authorizer)
+	var kustoOptions []kusto.Option
+	if option.HTTPClient != nil {
+		kustoOptions = append(kustoOptions, kusto.WithHttpClient(option.HTTPClient))
+	}
+
+	kustoClient, err := kusto.New(option.Endpoint, authorizer, kustoOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("kusto: %w", err)
 	}
@@ -57,9 +64,17 @@ func NewKustoClient(option *KustoOption) (DbClient, error) {
 		return nil, fmt.Errorf("ignore ingestor: %w", err)
 	}
 
+	var bypassIngestor ingest.Ingestor
+	if option.BypassEvent != "" { //+gocover:ignore:block cannot test kusto connection without enough credentials
+		if bypassIngestor, err = ingest.New(kustoClient, option.Database, option.BypassEvent); err != nil {
+			return nil, fmt.Errorf("bypass ingestor: %w", err)
+		}
+	}
+
 	return &KustoClient{ //+gocover:ignore:block cannot test kusto connection without enough credentials
 		coverageIngestor: coverageIngestor,
 		ignoreIngestor:   ignoreIngestor,
+		bypassIngestor:   bypassIngestor,
 		mappings:         option.extraMappings,
 		extraData:        option.extraData,
 		logger:           option.Logger.WithField("source", "KustoClient"),
@@ -71,6 +86,7 @@ func NewKustoClient(option *KustoOption) (DbClient, error) {
 type KustoClient struct {
 	coverageIngestor ingest.Ingestor
 	ignoreIngestor   ingest.Ingestor
+	bypassIngestor   ingest.Ingestor // nil if the bypass event isn't set
 	mappings         []mapping
 	extraData        map[string]interface{}
 	logger           logrus.FieldLogger
@@ -199,6 +215,30 @@ func (client *KustoClient) StoreIgnoreProfileData(ctx context.Context, data *Ign
 	return nil
 }
 
+// StoreBypassData sends the bypass to the bypass event, it's not sent if the bypass event isn't set.
+func (client *KustoClient) StoreBypassData(ctx context.Context, data *BypassData) error {
+	if client.bypassIngestor == nil {
+		client.logger.Debug("bypass event is not set, skip sending the bypass to kusto")
+		return nil
+	}
+
+	data.Extra = client.extraData
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("data json marshal: %w", err)
+	}
+	err = store(ctx,
+		client.bypassIngestor,
+		dataBytes,
+		append(basicBypassMappings, client.mappings...),
+		client.logger.WithField("ingestor", "bypass"),
+	)
+	if err != nil {
+		return fmt.Errorf("store bypass data: %w", err)
+	}
+	return nil
+}
+
 func store(ctx context.Context,
 	ingestor ingest.Ingestor,
 	dataBytes []byte,
@@ -231,8 +271,12 @@ type KustoOption struct {
 	Database      string
 	CoverageEvent string
 	IgnoreEvent   string
+	// BypassEvent is the event that the bypasses of the coverage gate are sent to, they're not sent if it's empty.
+	BypassEvent   string
 	CustomColumns []string
 	Logger        logrus.FieldLogger
+	// HTTPClient is the http client used to connect to kusto, the default client of kusto is used if it's nil.
+	HTTPClient *http.Client
 
 	tenantID     string
 	clientID     string
@@ -255,6 +299,7 @@ func (o *KustoOption) Validate() error {
 	if o.clientSecret = os.Getenv(clientSecretKey); o.clientSecret == "" {
 		return fmt.Errorf("%s %w", clientSecretKey, ErrEnvRequired)
 	}
+	redact.AddSecret(o.clientSecret)
 
 	if o.Endpoint == "" {
 		return fmt.Errorf("%s %w", "endpoint", ErrFlagRequired)
@@ -487,3 +532,55 @@ var basicIgnoreProfileMappings = []mapping{
 		},
 	},
 }
+
+var basicBypassMappings = []mapping{
+	{
+		Column:   "preciseTimestamp",
+		Datatype: "datetime",
+		Properties: properties{
+			Path: "$.preciseTimestamp",
+		},
+	},
+	{
+		Column:   "modulePath",
+		Datatype: "string",
+		Properties: properties{
+			Path: "$.modulePath",
+		},
+	},
+	{
+		Column:   "source",
+		Datatype: "string",
+		Properties: properties{
+			Path: "$.source",
+		},
+	},
+	{
+		Column:   "reason",
+		Datatype: "string",
+		Properties: properties{
+			Path: "$.reason",
+		},
+	},
+	{
+		Column:   "actor",
+		Datatype: "string",
+		Properties: properties{
+			Path: "$.actor",
+		},
+	},
+	{
+		Column:   "coverage",
+		Datatype: "real",
+		Properties: properties{
+			Path: "$.coverage",
+		},
+	},
+	{
+		Column:   "baseline",
+		Datatype: "real",
+		Properties: properties{
+			Path: "$.baseline",
+		},
+	},
+}
@@ -183,6 +183,39 @@ func TestKustoClient(t *testing.T) {
 		})
 	})
 
+	t.Run("StoreBypassData", func(t *testing.T) {
+		t.Run("bypass event not set", func(t *testing.T) {
+			client := KustoClient{logger: logger}
+			if err := client.StoreBypassData(ctx, &BypassData{}); err != nil {
+				t.Errorf("should return nil, but return %s", err)
+			}
+		})
+
+		t.Run("store succeeded", func(t *testing.T) {
+			client := KustoClient{
+				bypassIngestor: goodIngestor,
+				mappings:       []mapping{},
+				extraData:      map[string]interface{}{},
+				logger:         logger,
+			}
+			if err := client.StoreBypassData(ctx, &BypassData{}); err != nil {
+				t.Errorf("should return nil, but return %s", err)
+			}
+		})
+
+		t.Run("store failed", func(t *testing.T) {
+			client := KustoClient{
+				bypassIngestor: badIngestor,
+				mappings:       []mapping{},
+				extraData:      map[string]interface{}{},
+				logger:         logger,
+			}
+			if err := client.StoreBypassData(ctx, &BypassData{}); err == nil {
+				t.Error("should return error, but return nil")
+			}
+		})
+	})
+
 }
 
 type mockIngestor struct {
@@ -0,0 +1,136 @@
+package debt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// Default values of the policy.
+const (
+	DefaultMinFailures = 3
+	DefaultWindow      = 30 * 24 * time.Hour
+)
+
+// Policy decides which files are in debt.
+type Policy struct {
+	// CoverageBaseline is the coverage that the changed lines of a file need in a pull request,
+	// a file with violation lines below it fails the gate in the pull request.
+	CoverageBaseline float64
+	// MinFailures is the number of the pull requests that a file fails the gate in before it's in debt.
+	MinFailures int
+	// Window is how far back the runs are counted.
+	Window time.Duration
+	// MaxIgnoredLines is the ignored lines that a file is allowed to accumulate in the latest full coverage,
+	// the ignored lines are not checked if it's zero.
+	MaxIgnoredLines int
+}
+
+// Debt is a file in debt.
+type Debt struct {
+	// File is the file name that starts with the module path.
+	File string
+	// PullRequests are the pull requests that the file fails the gate in, sorted by the number.
+	PullRequests []int
+	// IgnoredLines is the ignored lines of the file in the latest full coverage, it's zero if they're in the limit.
+	IgnoredLines int
+}
+
+// Reasons describes why the file is in debt.
+func (d *Debt) Reasons() []string {
+	var reasons []string
+	if len(d.PullRequests) > 0 {
+		numbers := make([]string, 0, len(d.PullRequests))
+		for _, n := range d.PullRequests {
+			numbers = append(numbers, fmt.Sprintf("#%d", n))
+		}
+		reasons = append(reasons, fmt.Sprintf("the changed lines fail the coverage gate in %d pull requests: %s", len(d.PullRequests), strings.Join(numbers, ", ")))
+	}
+	if d.IgnoredLines > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d lines are ignored from coverage", d.IgnoredLines))
+	}
+	return reasons
+}
+
+// Find returns the files in debt of the repository by the runs in the store, sorted by the file name.
+// A pull request is counted once by its latest diff run, so the reruns don't count as the repeated failures.
+func Find(store history.Store, owner, repository string, policy *Policy, now time.Time) ([]*Debt, error) {
+	minFailures := policy.MinFailures
+	if minFailures <= 0 {
+		minFailures = DefaultMinFailures
+	}
+	window := policy.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	runs, err := store.List(&history.Filter{Owner: owner, Repository: repository, Since: now.Add(-window), Until: now})
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+
+	debts := make(map[string]*Debt)
+	debt := func(file string) *Debt {
+		if _, ok := debts[file]; !ok {
+			debts[file] = &Debt{File: file}
+		}
+		return debts[file]
+	}
+
+	failures := make(map[string][]int)
+	counted := make(map[int]bool)
+	var full *history.Run
+	// the runs are listed by the latest first.
+	for _, run := range runs {
+		switch run.Statistics.StatisticsType {
+		case report.FullStatisticsType:
+			if full == nil {
+				full = run
+			}
+		case report.DiffStatisticsType:
+			if run.Number == 0 || counted[run.Number] {
+				continue
+			}
+			counted[run.Number] = true
+			for _, p := range run.Statistics.CoverageProfile {
+				if FailsGate(p, policy.CoverageBaseline) {
+					failures[p.FileName] = append(failures[p.FileName], run.Number)
+				}
+			}
+		}
+	}
+	for file, numbers := range failures {
+		if len(numbers) >= minFailures {
+			sort.Ints(numbers)
+			debt(file).PullRequests = numbers
+		}
+	}
+	if full != nil && policy.MaxIgnoredLines > 0 {
+		for _, p := range full.Statistics.CoverageProfile {
+			if p.TotalIgnoredLines > policy.MaxIgnoredLines {
+				debt(p.FileName).IgnoredLines = p.TotalIgnoredLines
+			}
+		}
+	}
+
+	result := make([]*Debt, 0, len(debts))
+	for _, d := range debts {
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].File < result[j].File
+	})
+	return result, nil
+}
+
+// FailsGate reports whether the changed lines of the file have violations and their coverage is lower than the baseline.
+func FailsGate(p *report.CoverageProfile, baseline float64) bool {
+	if len(p.TotalViolationLines) == 0 || p.TotalEffectiveLines == 0 {
+		return false
+	}
+	return float64(p.CoveredLines)*100/float64(p.TotalEffectiveLines) < baseline
+}
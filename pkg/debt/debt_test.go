@@ -0,0 +1,70 @@
+package debt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var now = time.Date(2022, 10, 13, 8, 0, 0, 0, time.UTC)
+
+func profile(file string, effective, covered, ignored int) *report.CoverageProfile {
+	p := &report.CoverageProfile{FileName: file, TotalEffectiveLines: effective, CoveredLines: covered, TotalIgnoredLines: ignored}
+	for i := covered; i < effective; i++ {
+		p.TotalViolationLines = append(p.TotalViolationLines, i+1)
+	}
+	return p
+}
+
+func saveRun(t *testing.T, store history.Store, number int, statisticsType report.StatisticsType, age time.Duration, profiles ...*report.CoverageProfile) {
+	run := &history.Run{
+		Owner:      "Azure",
+		Repository: "gocover",
+		Number:     number,
+		HeadSHA:    "abcdef1",
+		CreatedAt:  now.Add(-age),
+		Statistics: &report.Statistics{StatisticsType: statisticsType, CoverageProfile: profiles},
+	}
+	if err := store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	store := history.NewMemoryStore()
+	foo, bar := "github.com/Azure/gocover/pkg/foo.go", "github.com/Azure/gocover/pkg/bar.go"
+	saveRun(t, store, 1, report.DiffStatisticsType, 60*24*time.Hour, profile(bar, 4, 0, 0))
+	saveRun(t, store, 2, report.DiffStatisticsType, 5*time.Hour, profile(foo, 4, 1, 0), profile(bar, 4, 4, 0))
+	// the rerun of a pull request is not counted again.
+	saveRun(t, store, 3, report.DiffStatisticsType, 4*time.Hour, profile(foo, 4, 1, 0), profile(bar, 4, 1, 0))
+	saveRun(t, store, 3, report.DiffStatisticsType, 3*time.Hour, profile(foo, 4, 2, 0), profile(bar, 4, 1, 0))
+	saveRun(t, store, 4, report.DiffStatisticsType, 2*time.Hour, profile(foo, 4, 3, 0), profile(bar, 4, 1, 0))
+	saveRun(t, store, 0, report.FullStatisticsType, 2*time.Hour, profile(foo, 40, 30, 90), profile(bar, 40, 30, 10))
+	saveRun(t, store, 0, report.FullStatisticsType, time.Hour, profile(foo, 40, 30, 60), profile(bar, 40, 30, 10))
+
+	debts, err := Find(store, "Azure", "gocover", &Policy{CoverageBaseline: 80, MinFailures: 3, MaxIgnoredLines: 50}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(debts) != 1 {
+		t.Fatalf("expect only foo.go is in debt, but get %+v", debts)
+	}
+	d := debts[0]
+	if d.File != foo || len(d.PullRequests) != 3 || d.PullRequests[0] != 2 || d.PullRequests[2] != 4 || d.IgnoredLines != 60 {
+		t.Errorf("unexpected debt %+v", d)
+	}
+	if reasons := d.Reasons(); len(reasons) != 2 {
+		t.Errorf("expect 2 reasons, but get %v", reasons)
+	}
+
+	// bar.go fails in #1 too if the window covers it.
+	debts, err = Find(store, "Azure", "gocover", &Policy{CoverageBaseline: 80, Window: 90 * 24 * time.Hour}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(debts) != 2 || debts[0].File != bar || debts[0].IgnoredLines != 0 {
+		t.Errorf("expect bar.go and foo.go are in debt, but get %+v", debts)
+	}
+}
@@ -0,0 +1,4 @@
+// Package debt finds the files that accumulate coverage debt in the stored runs, such as the files whose changed lines
+// fail the coverage gate in pull request after pull request, and tracks each of them as an issue on the SCM,
+// so that the noise of the reports turns into backlog items that are closed once the debt is paid.
+package debt
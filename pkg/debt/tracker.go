@@ -0,0 +1,120 @@
+package debt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLabel is the label of the tracking issues.
+const DefaultLabel = "coverage-debt"
+
+// markerFormat is the hidden marker of the file in the body of a tracking issue, which deduplicates the issues.
+const markerFormat = "<!-- gocover-debt: %s -->"
+
+var markerPattern = regexp.MustCompile(`<!-- gocover-debt: (\S+) -->`)
+
+var ErrNoLabel = errors.New("tracking issues need a label")
+
+// SyncResult is the changes of the tracking issues.
+type SyncResult struct {
+	// Opened are the issues opened for the new debts.
+	Opened []*scm.Issue
+	// Closed are the issues closed because the debts are paid, or they duplicate the other issues of the same file.
+	Closed []*scm.Issue
+	// Kept are the issues of the debts that are tracked already.
+	Kept []*scm.Issue
+}
+
+// Tracker opens an issue for each file in debt, and closes the issue once the file is no longer in debt.
+type Tracker struct {
+	issues scm.IssueTracker
+	labels []string
+	logger logrus.FieldLogger
+}
+
+// NewTracker creates the tracker, the issues are labeled with the labels and found by the first one of them.
+func NewTracker(issues scm.IssueTracker, labels []string, logger logrus.FieldLogger) (*Tracker, error) {
+	if len(labels) == 0 || labels[0] == "" {
+		return nil, ErrNoLabel
+	}
+	return &Tracker{issues: issues, labels: labels, logger: logger.WithField("source", "DebtTracker")}, nil
+}
+
+// Sync makes the open tracking issues of the repository match the debts. The issues without the marker,
+// such as the ones opened by hand with the label, are left alone.
+func (t *Tracker) Sync(ctx context.Context, owner, repository string, debts []*Debt) (*SyncResult, error) {
+	open, err := t.issues.ListIssues(ctx, owner, repository, t.labels[0])
+	if err != nil {
+		return nil, err
+	}
+	// the issues are sorted by the number, so the oldest issue of a file is kept.
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].Number < open[j].Number
+	})
+
+	result := &SyncResult{}
+	tracked := make(map[string]*scm.Issue)
+	inDebt := make(map[string]bool)
+	for _, d := range debts {
+		inDebt[d.File] = true
+	}
+
+	for _, issue := range open {
+		m := markerPattern.FindStringSubmatch(issue.Body)
+		if m == nil {
+			continue
+		}
+		file := m[1]
+		var comment string
+		switch {
+		case tracked[file] != nil:
+			comment = fmt.Sprintf("Duplicate of #%d.", tracked[file].Number)
+		case !inDebt[file]:
+			comment = fmt.Sprintf("The coverage debt of `%s` is paid, closed by gocover.", file)
+		default:
+			tracked[file] = issue
+			result.Kept = append(result.Kept, issue)
+			continue
+		}
+		if err := t.issues.CloseIssue(ctx, owner, repository, issue.Number, comment); err != nil {
+			return result, err
+		}
+		t.logger.Infof("close issue #%d of %s", issue.Number, file)
+		result.Closed = append(result.Closed, issue)
+	}
+
+	for _, d := range debts {
+		if tracked[d.File] != nil {
+			continue
+		}
+		issue, err := t.issues.CreateIssue(ctx, owner, repository, &scm.Issue{
+			Title:  fmt.Sprintf("Coverage debt: %s", d.File),
+			Body:   issueBody(d),
+			Labels: t.labels,
+		})
+		if err != nil {
+			return result, err
+		}
+		t.logger.Infof("open issue #%d of %s", issue.Number, d.File)
+		result.Opened = append(result.Opened, issue)
+	}
+	return result, nil
+}
+
+func issueBody(d *Debt) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "`%s` is in coverage debt:\n\n", d.File)
+	for _, reason := range d.Reasons() {
+		fmt.Fprintf(&b, "- %s\n", reason)
+	}
+	b.WriteString("\nThis issue is closed by gocover once the file is no longer in debt.\n\n")
+	fmt.Fprintf(&b, markerFormat, d.File)
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package debt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeIssueTracker struct {
+	issues map[int]*scm.Issue
+	closed map[int]string
+	next   int
+}
+
+func newFakeIssueTracker(issues ...*scm.Issue) *fakeIssueTracker {
+	f := &fakeIssueTracker{issues: make(map[int]*scm.Issue), closed: make(map[int]string), next: 100}
+	for _, i := range issues {
+		f.issues[i.Number] = i
+	}
+	return f
+}
+
+func (f *fakeIssueTracker) ListIssues(ctx context.Context, owner, repository, label string) ([]*scm.Issue, error) {
+	var result []*scm.Issue
+	for _, i := range f.issues {
+		for _, l := range i.Labels {
+			if l == label {
+				result = append(result, i)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeIssueTracker) CreateIssue(ctx context.Context, owner, repository string, issue *scm.Issue) (*scm.Issue, error) {
+	f.next++
+	issue.Number = f.next
+	f.issues[issue.Number] = issue
+	return issue, nil
+}
+
+func (f *fakeIssueTracker) CloseIssue(ctx context.Context, owner, repository string, number int, comment string) error {
+	f.closed[number] = comment
+	delete(f.issues, number)
+	return nil
+}
+
+func trackingIssue(number int, file string) *scm.Issue {
+	return &scm.Issue{Number: number, Labels: []string{DefaultLabel}, Body: fmt.Sprintf("debt\n\n"+markerFormat, file)}
+}
+
+func TestTrackerSync(t *testing.T) {
+	foo, bar, baz := "github.com/Azure/gocover/pkg/foo.go", "github.com/Azure/gocover/pkg/bar.go", "github.com/Azure/gocover/pkg/baz.go"
+	issues := newFakeIssueTracker(
+		trackingIssue(1, foo),
+		trackingIssue(2, foo),
+		trackingIssue(3, bar),
+		&scm.Issue{Number: 4, Labels: []string{DefaultLabel}, Body: "opened by hand"},
+	)
+	tracker, err := NewTracker(issues, []string{DefaultLabel, "tech-debt"}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	debts := []*Debt{{File: baz, PullRequests: []int{1, 2, 3}}, {File: foo, IgnoredLines: 60}}
+	result, err := tracker.Sync(context.Background(), "Azure", "gocover", debts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Opened) != 1 || len(result.Closed) != 2 || len(result.Kept) != 1 || result.Kept[0].Number != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if !strings.Contains(issues.closed[2], "Duplicate of #1") || !strings.Contains(issues.closed[3], "is paid") {
+		t.Errorf("unexpected close comments %v", issues.closed)
+	}
+	opened := result.Opened[0]
+	if opened.Title != "Coverage debt: "+baz || len(opened.Labels) != 2 || !strings.Contains(opened.Body, "#1, #2, #3") {
+		t.Errorf("unexpected opened issue %+v", opened)
+	}
+
+	// the next sync is idempotent.
+	result, err = tracker.Sync(context.Background(), "Azure", "gocover", debts)
+	if err != nil || len(result.Opened) != 0 || len(result.Closed) != 0 || len(result.Kept) != 2 {
+		t.Errorf("expect no change, but get %+v %v", result, err)
+	}
+
+	if _, err := NewTracker(issues, nil, logrus.New()); !errors.Is(err, ErrNoLabel) {
+		t.Errorf("expect ErrNoLabel, but get %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/debt"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// Default values of the option.
+const (
+	DefaultPeriod       = 7 * 24 * time.Hour
+	DefaultMaxOffenders = 5
+)
+
+var (
+	ErrNoRepository      = errors.New("no repository in the digest")
+	ErrInvalidRepository = errors.New("invalid repository")
+)
+
+// Repository is a repository in the digest, all the repositories of the owner are included if the name is empty.
+type Repository struct {
+	Owner string
+	Name  string
+}
+
+// ParseRepository parses a repository in the format of {owner}/{name} or {owner}.
+func ParseRepository(s string) (*Repository, error) {
+	owner, name, _ := strings.Cut(strings.TrimSpace(s), "/")
+	if owner == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("%w '%s', it should be {owner}/{name} or {owner}", ErrInvalidRepository, s)
+	}
+	return &Repository{Owner: owner, Name: name}, nil
+}
+
+// Option is the input of a digest.
+type Option struct {
+	// Title is the title of the digest, such as the name of the team.
+	Title string
+	// Repositories are the repositories summarized in the digest.
+	Repositories []*Repository
+	// Until is the end of the period, the period is [Until - Period, Until].
+	Until time.Time
+	// Period is the length of the period, DefaultPeriod is used if it's zero.
+	Period time.Duration
+	// MaxOffenders is the maximum offenders of each repository, DefaultMaxOffenders is used if it's zero.
+	MaxOffenders int
+	// Debt decides which files are in debt at the start and the end of the period,
+	// and its coverage baseline decides whether a file fails the gate in a pull request.
+	Debt debt.Policy
+}
+
+// Digest is the summary of the repositories in a period.
+type Digest struct {
+	Title        string
+	Since        time.Time
+	Until        time.Time
+	Repositories []*RepositoryDigest
+}
+
+// RepositoryDigest is the summary of a repository in the period.
+type RepositoryDigest struct {
+	Owner      string
+	Repository string
+	// Trend is the coverage of the full runs in the period, the earliest comes first.
+	// The first point is the latest full run before the period if there is one, so the change covers the whole period.
+	Trend []*Point
+	// PullRequests is the number of the pull requests with diff runs in the period.
+	PullRequests int
+	// FailedPullRequests is the number of the pull requests whose latest diff run fails the coverage gate.
+	FailedPullRequests int
+	// BypassedPullRequests is the number of the pull requests whose latest diff run bypasses the coverage gate.
+	BypassedPullRequests int
+	// Offenders are the files that fail the gate in the most pull requests.
+	Offenders []*Offender
+	// NewDebts are the files in debt at the end of the period but not at the start.
+	NewDebts []*debt.Debt
+	// PaidDebts are the files in debt at the start of the period but not at the end.
+	PaidDebts []string
+	// Debts is the number of the files in debt at the end of the period.
+	Debts int
+}
+
+// Point is the coverage of a full run.
+type Point struct {
+	Time     time.Time
+	HeadSHA  string
+	Coverage float64
+}
+
+// Offender is a file that fails the coverage gate in the pull requests of the period.
+type Offender struct {
+	File string
+	// PullRequests are the pull requests that the file fails the gate in, sorted by the number.
+	PullRequests []int
+	// UncoveredLines is the total uncovered changed lines of the file in the pull requests.
+	UncoveredLines int
+}
+
+// Change returns the coverage change in the trend, it's zero if there are less than two points.
+func (r *RepositoryDigest) Change() float64 {
+	if len(r.Trend) < 2 {
+		return 0
+	}
+	return r.Trend[len(r.Trend)-1].Coverage - r.Trend[0].Coverage
+}
+
+// Coverage returns the latest coverage in the trend, the second value is false if there is no full run.
+func (r *RepositoryDigest) Coverage() (float64, bool) {
+	if len(r.Trend) == 0 {
+		return 0, false
+	}
+	return r.Trend[len(r.Trend)-1].Coverage, true
+}
+
+// Generate summarizes the runs of the repositories in the store, the repositories are sorted by the name.
+func Generate(store history.Store, o *Option) (*Digest, error) {
+	if len(o.Repositories) == 0 {
+		return nil, ErrNoRepository
+	}
+	period := o.Period
+	if period <= 0 {
+		period = DefaultPeriod
+	}
+	until := o.Until
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	d := &Digest{Title: o.Title, Since: until.Add(-period), Until: until}
+
+	repositories, err := expand(store, o.Repositories, d.Since, d.Until)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repositories {
+		rd, err := summarize(store, r, d.Since, d.Until, o)
+		if err != nil {
+			return nil, fmt.Errorf("summarize %s/%s: %w", r.Owner, r.Name, err)
+		}
+		d.Repositories = append(d.Repositories, rd)
+	}
+	return d, nil
+}
+
+// expand resolves the repositories without the name into the repositories of the owner with runs in the period.
+func expand(store history.Store, repositories []*Repository, since, until time.Time) ([]*Repository, error) {
+	seen := make(map[string]bool)
+	var result []*Repository
+	add := func(owner, name string) {
+		key := strings.ToLower(owner + "/" + name)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, &Repository{Owner: owner, Name: name})
+		}
+	}
+	for _, r := range repositories {
+		if r.Name != "" {
+			add(r.Owner, r.Name)
+			continue
+		}
+		runs, err := store.List(&history.Filter{Owner: r.Owner, Since: since, Until: until})
+		if err != nil {
+			return nil, fmt.Errorf("list runs of %s: %w", r.Owner, err)
+		}
+		for _, run := range runs {
+			add(run.Owner, run.Repository)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Owner+"/"+result[i].Name) < strings.ToLower(result[j].Owner+"/"+result[j].Name)
+	})
+	return result, nil
+}
+
+// summarize summarizes the runs of a repository in the period.
+func summarize(store history.Store, r *Repository, since, until time.Time, o *Option) (*RepositoryDigest, error) {
+	rd := &RepositoryDigest{Owner: r.Owner, Repository: r.Name}
+
+	before, err := store.List(&history.Filter{Owner: r.Owner, Repository: r.Name, Type: report.FullStatisticsType, Until: since, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	runs, err := store.List(&history.Filter{Owner: r.Owner, Repository: r.Name, Since: since, Until: until})
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+
+	for _, run := range before {
+		rd.Trend = append(rd.Trend, point(run))
+	}
+	offenders := make(map[string]*Offender)
+	counted := make(map[int]bool)
+	// the runs are listed by the latest first, so the trend is built backwards and a pull request is counted by its latest run.
+	var trend []*Point
+	for _, run := range runs {
+		switch run.Statistics.StatisticsType {
+		case report.FullStatisticsType:
+			trend = append(trend, point(run))
+		case report.DiffStatisticsType:
+			if run.Number == 0 || counted[run.Number] {
+				continue
+			}
+			counted[run.Number] = true
+			rd.PullRequests++
+			if run.Statistics.Bypass != nil {
+				rd.BypassedPullRequests++
+			} else if run.Statistics.TotalCoveragePercent < o.Debt.CoverageBaseline {
+				rd.FailedPullRequests++
+			}
+			for _, p := range run.Statistics.CoverageProfile {
+				if !debt.FailsGate(p, o.Debt.CoverageBaseline) {
+					continue
+				}
+				if _, ok := offenders[p.FileName]; !ok {
+					offenders[p.FileName] = &Offender{File: p.FileName}
+				}
+				offender := offenders[p.FileName]
+				offender.PullRequests = append(offender.PullRequests, run.Number)
+				offender.UncoveredLines += len(p.TotalViolationLines)
+			}
+		}
+	}
+	for i := len(trend) - 1; i >= 0; i-- {
+		rd.Trend = append(rd.Trend, trend[i])
+	}
+	rd.Offenders = worst(offenders, o.MaxOffenders)
+
+	start, err := debt.Find(store, r.Owner, r.Name, &o.Debt, since)
+	if err != nil {
+		return nil, err
+	}
+	end, err := debt.Find(store, r.Owner, r.Name, &o.Debt, until)
+	if err != nil {
+		return nil, err
+	}
+	rd.Debts = len(end)
+	inDebt := make(map[string]bool)
+	for _, d := range start {
+		inDebt[d.File] = true
+	}
+	for _, d := range end {
+		if !inDebt[d.File] {
+			rd.NewDebts = append(rd.NewDebts, d)
+		}
+		delete(inDebt, d.File)
+	}
+	for _, d := range start {
+		if inDebt[d.File] {
+			rd.PaidDebts = append(rd.PaidDebts, d.File)
+		}
+	}
+	return rd, nil
+}
+
+func point(run *history.Run) *Point {
+	return &Point{Time: run.CreatedAt, HeadSHA: run.HeadSHA, Coverage: run.Statistics.TotalCoveragePercent}
+}
+
+// worst returns the offenders that fail the gate in the most pull requests, the ties are broken by the uncovered lines.
+func worst(offenders map[string]*Offender, max int) []*Offender {
+	if max <= 0 {
+		max = DefaultMaxOffenders
+	}
+	result := make([]*Offender, 0, len(offenders))
+	for _, o := range offenders {
+		sort.Ints(o.PullRequests)
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if len(a.PullRequests) != len(b.PullRequests) {
+			return len(a.PullRequests) > len(b.PullRequests)
+		}
+		if a.UncoveredLines != b.UncoveredLines {
+			return a.UncoveredLines > b.UncoveredLines
+		}
+		return a.File < b.File
+	})
+	if len(result) > max {
+		result = result[:max]
+	}
+	return result
+}
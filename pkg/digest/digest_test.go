@@ -0,0 +1,167 @@
+package digest
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/debt"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var now = time.Date(2022, 10, 17, 8, 0, 0, 0, time.UTC)
+
+const day = 24 * time.Hour
+
+func profile(file string, effective, covered int) *report.CoverageProfile {
+	p := &report.CoverageProfile{FileName: file, TotalEffectiveLines: effective, CoveredLines: covered}
+	for i := covered; i < effective; i++ {
+		p.TotalViolationLines = append(p.TotalViolationLines, i+1)
+	}
+	return p
+}
+
+func saveRun(t *testing.T, store history.Store, repository string, number int, age time.Duration, statistics *report.Statistics) {
+	run := &history.Run{
+		Owner:      "Azure",
+		Repository: repository,
+		Number:     number,
+		HeadSHA:    "abcdef1",
+		CreatedAt:  now.Add(-age),
+		Statistics: statistics,
+	}
+	if err := store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func full(coverage float64) *report.Statistics {
+	return &report.Statistics{StatisticsType: report.FullStatisticsType, TotalCoveragePercent: coverage}
+}
+
+func diff(coverage float64, profiles ...*report.CoverageProfile) *report.Statistics {
+	return &report.Statistics{StatisticsType: report.DiffStatisticsType, TotalCoveragePercent: coverage, CoverageProfile: profiles}
+}
+
+func TestGenerate(t *testing.T) {
+	store := history.NewMemoryStore()
+	foo, bar := "github.com/Azure/gocover/pkg/foo.go", "github.com/Azure/gocover/pkg/bar.go"
+	// bar.go is in debt before the period, and foo.go goes into debt during the period.
+	saveRun(t, store, "gocover", 1, 10*day, diff(20, profile(bar, 4, 0)))
+	saveRun(t, store, "gocover", 2, 9*day, diff(20, profile(bar, 4, 0)))
+	saveRun(t, store, "gocover", 3, 8*day, diff(20, profile(bar, 4, 0)))
+	saveRun(t, store, "gocover", 0, 8*day, full(70))
+	saveRun(t, store, "gocover", 0, 5*day, full(72))
+	saveRun(t, store, "gocover", 0, day, full(75))
+	saveRun(t, store, "gocover", 4, 6*day, diff(50, profile(foo, 4, 1)))
+	// the rerun of a pull request is not counted again.
+	saveRun(t, store, "gocover", 5, 5*day, diff(50, profile(foo, 4, 1)))
+	saveRun(t, store, "gocover", 5, 4*day, diff(50, profile(foo, 4, 2), profile(bar, 4, 1)))
+	saveRun(t, store, "gocover", 6, 3*day, diff(50, profile(foo, 4, 3)))
+	saveRun(t, store, "gocover", 7, 2*day, &report.Statistics{StatisticsType: report.DiffStatisticsType, Bypass: &report.Bypass{Reason: "hotfix"}})
+	saveRun(t, store, "other", 0, day, full(90))
+
+	d, err := Generate(store, &Option{
+		Title:        "Platform",
+		Repositories: []*Repository{{Owner: "azure"}},
+		Until:        now,
+		Debt:         debt.Policy{CoverageBaseline: 80, MinFailures: 3, Window: 30 * day},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Repositories) != 2 || d.Repositories[0].Repository != "gocover" || d.Repositories[1].Repository != "other" {
+		t.Fatalf("expect the repositories of the owner, but get %+v", d.Repositories)
+	}
+
+	r := d.Repositories[0]
+	if len(r.Trend) != 3 || r.Trend[0].Coverage != 70 || r.Change() != 5 {
+		t.Errorf("expect the trend starts from the run before the period, but get %+v", r.Trend)
+	}
+	if r.PullRequests != 4 || r.FailedPullRequests != 3 || r.BypassedPullRequests != 1 {
+		t.Errorf("unexpected pull requests %+v", r)
+	}
+	if len(r.Offenders) != 2 || r.Offenders[0].File != foo || len(r.Offenders[0].PullRequests) != 3 || r.Offenders[0].UncoveredLines != 6 {
+		t.Errorf("unexpected offenders %+v", r.Offenders)
+	}
+	if len(r.NewDebts) != 1 || r.NewDebts[0].File != foo || r.Debts != 2 || len(r.PaidDebts) != 0 {
+		t.Errorf("unexpected debts %+v", r)
+	}
+	if coverage, ok := d.Repositories[1].Coverage(); !ok || coverage != 90 {
+		t.Errorf("unexpected coverage of the other repository %+v", d.Repositories[1])
+	}
+
+	var markdown bytes.Buffer
+	if err := Write(&markdown, d, MarkdownFormat); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Coverage Digest of Platform", "2022-10-10 - 2022-10-17", "### Azure/gocover", "75.0% (+5.0%)", "70.0% → 72.0% → 75.0%", "| " + foo + " | #4, #5, #6 | 6 |", "Pull requests: 4, 3 failed the coverage gate, 1 bypassed it"} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown digest should contain %q:\n%s", want, markdown.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := Write(&html, d, HTMLFormat); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<title>Coverage Digest of Platform</title>", "<h3>Azure/gocover</h3>", "<td style=\"padding: 0.2em 1em; border-bottom: 1px solid #d0d7de;\">#4, #5, #6</td>"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("html digest should contain %q", want)
+		}
+	}
+
+	if err := Write(&html, d, "pdf"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expect ErrUnknownFormat, but get %v", err)
+	}
+}
+
+func TestPaidDebt(t *testing.T) {
+	store := history.NewMemoryStore()
+	foo := "github.com/Azure/gocover/pkg/foo.go"
+	saveRun(t, store, "gocover", 1, 36*day, diff(20, profile(foo, 4, 0)))
+	saveRun(t, store, "gocover", 2, 35*day, diff(20, profile(foo, 4, 0)))
+	saveRun(t, store, "gocover", 3, 34*day, diff(20, profile(foo, 4, 0)))
+
+	d, err := Generate(store, &Option{
+		Repositories: []*Repository{{Owner: "Azure", Name: "gocover"}},
+		Until:        now,
+		Debt:         debt.Policy{CoverageBaseline: 80},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.Repositories[0]
+	if len(r.PaidDebts) != 1 || r.PaidDebts[0] != foo || r.Debts != 0 || r.PullRequests != 0 {
+		t.Errorf("expect the debt out of the window is paid, but get %+v", r)
+	}
+	if _, ok := r.Coverage(); ok {
+		t.Error("expect no coverage without full runs")
+	}
+	if !strings.Contains(FormatMarkdown(d), "Coverage: no full coverage runs") {
+		t.Errorf("unexpected markdown digest %s", FormatMarkdown(d))
+	}
+}
+
+func TestParseRepository(t *testing.T) {
+	for s, want := range map[string]*Repository{
+		"Azure/gocover": {Owner: "Azure", Name: "gocover"},
+		"Azure":         {Owner: "Azure"},
+	} {
+		r, err := ParseRepository(s)
+		if err != nil || *r != *want {
+			t.Errorf("expect %+v from %s, but get %+v, %v", want, s, r, err)
+		}
+	}
+	for _, s := range []string{"", "/gocover", "Azure/gocover/pkg"} {
+		if _, err := ParseRepository(s); !errors.Is(err, ErrInvalidRepository) {
+			t.Errorf("expect ErrInvalidRepository of '%s', but get %v", s, err)
+		}
+	}
+	if _, err := Generate(history.NewMemoryStore(), &Option{}); !errors.Is(err, ErrNoRepository) {
+		t.Errorf("expect ErrNoRepository, but get %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package digest summarizes a period of the stored runs of the repositories into a digest, such as the coverage trend,
+// the files that fail the coverage gate most often in the pull requests and the changes of the coverage debt,
+// for the readers who follow the coverage of a team rather than the comment of each pull request.
+package digest
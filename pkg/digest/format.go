@@ -0,0 +1,129 @@
+package digest
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// The formats of a digest.
+const (
+	MarkdownFormat = "markdown"
+	HTMLFormat     = "html"
+)
+
+var ErrUnknownFormat = errors.New("unknown digest format")
+
+// Write writes the digest in the format.
+func Write(w io.Writer, d *Digest, format string) error {
+	switch strings.ToLower(format) {
+	case MarkdownFormat:
+		_, err := io.WriteString(w, FormatMarkdown(d))
+		return err
+	case HTMLFormat:
+		return htmlDigestTemplate.Execute(w, d)
+	default:
+		return fmt.Errorf("%w '%s', it should be one of: %s, %s", ErrUnknownFormat, format, MarkdownFormat, HTMLFormat)
+	}
+}
+
+// FormatMarkdown renders the digest into markdown, a section for each repository.
+func FormatMarkdown(d *Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", title(d))
+	fmt.Fprintf(&b, "%s - %s\n", d.Since.Format(dateLayout), d.Until.Format(dateLayout))
+	if len(d.Repositories) == 0 {
+		fmt.Fprintf(&b, "\nNo runs in this period.\n")
+		return b.String()
+	}
+
+	for _, r := range d.Repositories {
+		fmt.Fprintf(&b, "\n### %s/%s\n\n", r.Owner, r.Repository)
+		fmt.Fprintf(&b, "- Coverage: %s\n", coverage(r))
+		if len(r.Trend) > 1 {
+			fmt.Fprintf(&b, "- Trend: %s\n", trend(r))
+		}
+		fmt.Fprintf(&b, "- Pull requests: %d, %d failed the coverage gate, %d bypassed it\n", r.PullRequests, r.FailedPullRequests, r.BypassedPullRequests)
+		fmt.Fprintf(&b, "- Coverage debt: %d files, %d new, %d paid\n", r.Debts, len(r.NewDebts), len(r.PaidDebts))
+
+		if len(r.Offenders) > 0 {
+			fmt.Fprintf(&b, "\n| Worst Offender | Failed Pull Requests | Uncovered Lines |\n")
+			fmt.Fprintf(&b, "| --- | --- | --- |\n")
+			for _, o := range r.Offenders {
+				fmt.Fprintf(&b, "| %s | %s | %d |\n", o.File, pullRequests(o.PullRequests), o.UncoveredLines)
+			}
+		}
+		if len(r.NewDebts) > 0 {
+			fmt.Fprintf(&b, "\nNew coverage debt:\n\n")
+			for _, debt := range r.NewDebts {
+				fmt.Fprintf(&b, "- %s: %s\n", debt.File, strings.Join(debt.Reasons(), "; "))
+			}
+		}
+		if len(r.PaidDebts) > 0 {
+			fmt.Fprintf(&b, "\nPaid coverage debt:\n\n")
+			for _, file := range r.PaidDebts {
+				fmt.Fprintf(&b, "- %s\n", file)
+			}
+		}
+	}
+	return b.String()
+}
+
+const dateLayout = "2006-01-02"
+
+func title(d *Digest) string {
+	if d.Title == "" {
+		return "Coverage Digest"
+	}
+	return fmt.Sprintf("Coverage Digest of %s", d.Title)
+}
+
+// coverage describes the latest coverage and its change in the period.
+func coverage(r *RepositoryDigest) string {
+	latest, ok := r.Coverage()
+	if !ok {
+		return "no full coverage runs"
+	}
+	if len(r.Trend) < 2 {
+		return fmt.Sprintf("%.1f%%", latest)
+	}
+	return fmt.Sprintf("%.1f%% (%+.1f%%)", latest, r.Change())
+}
+
+func trend(r *RepositoryDigest) string {
+	points := make([]string, 0, len(r.Trend))
+	for _, p := range r.Trend {
+		points = append(points, fmt.Sprintf("%.1f%%", p.Coverage))
+	}
+	return strings.Join(points, " → ")
+}
+
+func pullRequests(numbers []int) string {
+	s := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		s = append(s, fmt.Sprintf("#%d", n))
+	}
+	return strings.Join(s, ", ")
+}
+
+// htmlDigestTemplate renders the digest into a html email, the styles are inline since the mail clients drop the style sheets.
+var htmlDigestTemplate = template.Must(
+	template.New("htmlDigestTemplate").
+		Funcs(template.FuncMap{
+			"Title":        title,
+			"Coverage":     coverage,
+			"Trend":        trend,
+			"PullRequests": pullRequests,
+			"Join":         strings.Join,
+			"Date":         func(d *Digest) string { return d.Since.Format(dateLayout) + " - " + d.Until.Format(dateLayout) },
+		}).
+		Parse(htmlDigest),
+)
+
+// htmlDigest is the template of the html email, it's embedded into the binary.
+//
+//go:embed templates/digest.html
+var htmlDigest string
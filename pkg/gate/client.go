@@ -0,0 +1,70 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+// Path is the path of the deploy gate endpoint of the webhook server.
+const Path = "/deploy"
+
+// DeniedStatusCode is the status code of a denied decision, an allowed decision is 200,
+// so that a pipeline can gate by the status code, such as curl --fail.
+const DeniedStatusCode = http.StatusPreconditionFailed
+
+var ErrUnexpectedResponse = errors.New("unexpected deploy gate response")
+
+// Client asks the deploy gate endpoint of the webhook server, which evaluates by the runs and the policy of the tenant.
+type Client struct {
+	server string
+	token  credential.Provider
+	client *http.Client
+}
+
+// NewClient creates the client of the webhook server, the token is the bearer token of the tenant.
+func NewClient(server string, token credential.Provider, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{server: strings.TrimSuffix(server, "/"), token: token, client: client}
+}
+
+// Decide asks whether the commit of the repository is allowed to deploy.
+func (c *Client) Decide(ctx context.Context, owner, repository, commit string) (*Decision, error) {
+	query := url.Values{"owner": {owner}, "repository": {repository}, "commit": {commit}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+Path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get api token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != DeniedStatusCode {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%w: %d %s", ErrUnexpectedResponse, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	decision := &Decision{}
+	if err := json.NewDecoder(resp.Body).Decode(decision); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, err)
+	}
+	return decision, nil
+}
@@ -0,0 +1,3 @@
+// Package gate decides whether a commit is allowed to deploy by the stored coverage results and a policy,
+// so that the continuous delivery pipelines can use gocover as a promotion gate.
+package gate
@@ -0,0 +1,194 @@
+package gate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// The checks of a decision.
+const (
+	// ResultCheck checks that the commit has a coverage result that doesn't expire.
+	ResultCheck = "result"
+	// DiffCoverageCheck checks the diff coverage of the commit against the policy.
+	DiffCoverageCheck = "diff-coverage"
+	// RatchetCheck checks that the full coverage of the commit doesn't drop below the previous commit.
+	RatchetCheck = "ratchet"
+)
+
+// minCommitLength is the minimum length of the commit prefix, the shorter prefixes are likely ambiguous.
+const minCommitLength = 7
+
+var ErrInvalidCommit = errors.New("invalid commit")
+
+// Policy is the coverage that a commit needs to deploy.
+type Policy struct {
+	// DiffCoverage is the minimum diff coverage of the commit, the diff coverage is not checked if it's zero.
+	// A bypassed coverage gate passes the check.
+	DiffCoverage float64 `yaml:"diffCoverage" json:"diffCoverage"`
+	// Ratchet requires the full coverage of the commit is not lower than the full coverage of the previous commit.
+	Ratchet bool `yaml:"ratchet" json:"ratchet"`
+	// RatchetTolerance is the coverage percent that the ratchet allows to drop.
+	RatchetTolerance float64 `yaml:"ratchetTolerance" json:"ratchetTolerance"`
+	// MaxAge is how long a result of the commit counts, the results never expire if it's zero.
+	MaxAge time.Duration `yaml:"maxAge" json:"maxAge"`
+}
+
+// Decision is the answer whether a commit is allowed to deploy.
+type Decision struct {
+	Owner      string
+	Repository string
+	// Commit is the full sha of the commit if it has results, otherwise it's the requested commit.
+	Commit string
+	// Allowed indicates all the checks pass.
+	Allowed     bool
+	Checks      []*Check
+	EvaluatedAt time.Time
+}
+
+// Check is a check of the policy.
+type Check struct {
+	Name    string
+	Passed  bool
+	Message string
+	// RunID is the stored run that the check is evaluated on.
+	RunID string `json:",omitempty"`
+}
+
+// Failures returns the failed checks.
+func (d *Decision) Failures() []*Check {
+	var failures []*Check
+	for _, c := range d.Checks {
+		if !c.Passed {
+			failures = append(failures, c)
+		}
+	}
+	return failures
+}
+
+// Evaluate decides whether the commit of the repository is allowed to deploy by the runs in the store,
+// the commit can be a prefix of the sha. The runs older than the max age of the policy don't count,
+// except the previous full coverage of the ratchet, which is the baseline that the commit competes with.
+func Evaluate(store history.Store, owner, repository, commit string, policy *Policy, now time.Time) (*Decision, error) {
+	if len(commit) < minCommitLength {
+		return nil, fmt.Errorf("%w '%s', it should be a sha of at least %d characters", ErrInvalidCommit, commit, minCommitLength)
+	}
+	if policy == nil {
+		policy = &Policy{}
+	}
+
+	runs, err := store.List(&history.Filter{Owner: owner, Repository: repository, HeadSHA: commit})
+	if err != nil {
+		return nil, fmt.Errorf("list runs of %s: %w", commit, err)
+	}
+	decision := &Decision{Owner: owner, Repository: repository, Commit: commit, EvaluatedAt: now}
+
+	var fresh []*history.Run
+	for _, run := range runs {
+		if policy.MaxAge == 0 || now.Sub(run.CreatedAt) <= policy.MaxAge {
+			fresh = append(fresh, run)
+		}
+	}
+	switch {
+	case len(fresh) > 0:
+		decision.Commit = fresh[0].HeadSHA
+		decision.add(&Check{Name: ResultCheck, Passed: true, Message: fmt.Sprintf("%d results", len(fresh)), RunID: fresh[0].ID})
+	case len(runs) > 0:
+		decision.Commit = runs[0].HeadSHA
+		decision.add(&Check{Name: ResultCheck, Message: fmt.Sprintf("the results expired, the latest one is at %s", runs[0].CreatedAt.Format(time.RFC3339))})
+	default:
+		decision.add(&Check{Name: ResultCheck, Message: "no coverage result of the commit"})
+	}
+
+	if policy.DiffCoverage > 0 {
+		decision.add(diffCoverageCheck(latest(fresh, report.DiffStatisticsType), policy.DiffCoverage))
+	}
+	if policy.Ratchet {
+		check, err := ratchetCheck(store, owner, repository, latest(fresh, report.FullStatisticsType), policy.RatchetTolerance)
+		if err != nil {
+			return nil, err
+		}
+		decision.add(check)
+	}
+
+	decision.Allowed = len(decision.Failures()) == 0
+	return decision, nil
+}
+
+func (d *Decision) add(check *Check) {
+	d.Checks = append(d.Checks, check)
+}
+
+func diffCoverageCheck(run *history.Run, baseline float64) *Check {
+	check := &Check{Name: DiffCoverageCheck}
+	if run == nil {
+		check.Message = "no diff coverage result of the commit"
+		return check
+	}
+	check.RunID = run.ID
+	coverage := run.Statistics.TotalCoveragePercent
+	switch {
+	case coverage >= baseline:
+		check.Passed = true
+		check.Message = fmt.Sprintf("diff coverage %.1f%% meets %.1f%%", coverage, baseline)
+	case run.Statistics.Bypass != nil:
+		check.Passed = true
+		check.Message = fmt.Sprintf("diff coverage %.1f%% is lower than %.1f%%, but the gate is bypassed", coverage, baseline)
+	default:
+		check.Message = fmt.Sprintf("diff coverage %.1f%% is lower than %.1f%%", coverage, baseline)
+	}
+	return check
+}
+
+// ratchetCheck compares the full coverage of the run with the latest full coverage of the other commits before it.
+func ratchetCheck(store history.Store, owner, repository string, run *history.Run, tolerance float64) (*Check, error) {
+	check := &Check{Name: RatchetCheck}
+	if run == nil {
+		check.Message = "no full coverage result of the commit"
+		return check, nil
+	}
+	check.RunID = run.ID
+
+	previous, err := store.List(&history.Filter{Owner: owner, Repository: repository, Type: report.FullStatisticsType, Until: run.CreatedAt})
+	if err != nil {
+		return nil, fmt.Errorf("list previous runs: %w", err)
+	}
+	coverage := run.Statistics.TotalCoveragePercent
+	for _, p := range previous {
+		if strings.EqualFold(p.HeadSHA, run.HeadSHA) {
+			continue
+		}
+		baseline := p.Statistics.TotalCoveragePercent
+		check.Passed = coverage >= baseline-tolerance
+		if check.Passed {
+			check.Message = fmt.Sprintf("full coverage %.1f%% doesn't drop from %.1f%% of %s", coverage, baseline, shortSHA(p.HeadSHA))
+		} else {
+			check.Message = fmt.Sprintf("full coverage %.1f%% drops from %.1f%% of %s", coverage, baseline, shortSHA(p.HeadSHA))
+		}
+		return check, nil
+	}
+	check.Passed = true
+	check.Message = fmt.Sprintf("full coverage %.1f%%, no previous full coverage to compare", coverage)
+	return check, nil
+}
+
+// latest returns the latest run of the statistics type, the runs are listed by the latest first.
+func latest(runs []*history.Run, statisticsType report.StatisticsType) *history.Run {
+	for _, run := range runs {
+		if run.Statistics.StatisticsType == statisticsType {
+			return run
+		}
+	}
+	return nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > minCommitLength {
+		return sha[:minCommitLength]
+	}
+	return sha
+}
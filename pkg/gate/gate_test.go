@@ -0,0 +1,123 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var now = time.Date(2022, 10, 13, 8, 0, 0, 0, time.UTC)
+
+func saveRun(t *testing.T, store history.Store, sha string, statisticsType report.StatisticsType, coverage float64, age time.Duration) {
+	run := &history.Run{
+		Owner:      "Azure",
+		Repository: "gocover",
+		HeadSHA:    sha,
+		CreatedAt:  now.Add(-age),
+		Statistics: &report.Statistics{StatisticsType: statisticsType, TotalCoveragePercent: coverage},
+	}
+	if err := store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestStore(t *testing.T) history.Store {
+	store := history.NewMemoryStore()
+	saveRun(t, store, "1111111aaaa", report.FullStatisticsType, 70, 48*time.Hour)
+	saveRun(t, store, "2222222bbbb", report.DiffStatisticsType, 90, 2*time.Hour)
+	saveRun(t, store, "2222222bbbb", report.FullStatisticsType, 71, 2*time.Hour)
+	saveRun(t, store, "3333333cccc", report.DiffStatisticsType, 60, time.Hour)
+	saveRun(t, store, "3333333cccc", report.FullStatisticsType, 68, time.Hour)
+	return store
+}
+
+func TestEvaluate(t *testing.T) {
+	store := newTestStore(t)
+
+	testCases := []struct {
+		name     string
+		commit   string
+		policy   *Policy
+		allowed  bool
+		failures []string
+	}{
+		{name: "result only", commit: "3333333", allowed: true},
+		{name: "no result", commit: "4444444", failures: []string{ResultCheck}},
+		{name: "expired", commit: "1111111", policy: &Policy{MaxAge: 24 * time.Hour}, failures: []string{ResultCheck}},
+		{name: "diff coverage", commit: "2222222", policy: &Policy{DiffCoverage: 80, Ratchet: true}, allowed: true},
+		{name: "low diff coverage", commit: "3333333", policy: &Policy{DiffCoverage: 80}, failures: []string{DiffCoverageCheck}},
+		{name: "no diff coverage", commit: "1111111", policy: &Policy{DiffCoverage: 80}, failures: []string{DiffCoverageCheck}},
+		{name: "ratchet", commit: "3333333", policy: &Policy{Ratchet: true}, failures: []string{RatchetCheck}},
+		{name: "ratchet tolerance", commit: "3333333", policy: &Policy{Ratchet: true, RatchetTolerance: 3}, allowed: true},
+		{name: "no previous full coverage", commit: "1111111", policy: &Policy{Ratchet: true}, allowed: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, err := Evaluate(store, "azure", "gocover", tc.commit, tc.policy, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Allowed != tc.allowed {
+				t.Errorf("expect allowed %t, but get %+v", tc.allowed, decision)
+			}
+			var failures []string
+			for _, c := range decision.Failures() {
+				failures = append(failures, c.Name)
+			}
+			if len(failures) != len(tc.failures) || (len(failures) > 0 && failures[0] != tc.failures[0]) {
+				t.Errorf("expect failures %v, but get %v", tc.failures, failures)
+			}
+		})
+	}
+
+	decision, _ := Evaluate(store, "Azure", "gocover", "2222222", nil, now)
+	if decision.Commit != "2222222bbbb" {
+		t.Errorf("expect the full sha of the commit, but get %s", decision.Commit)
+	}
+	if _, err := Evaluate(store, "Azure", "gocover", "abc", nil, now); !errors.Is(err, ErrInvalidCommit) {
+		t.Errorf("expect ErrInvalidCommit, but get %v", err)
+	}
+}
+
+type staticToken string
+
+func (s staticToken) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != Path || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		decision := &Decision{Commit: r.URL.Query().Get("commit"), Allowed: r.URL.Query().Get("commit") == "good"}
+		code := http.StatusOK
+		if !decision.Allowed {
+			code = DeniedStatusCode
+		}
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(decision)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", staticToken("secret"), nil)
+	for commit, allowed := range map[string]bool{"good": true, "bad": false} {
+		decision, err := client.Decide(context.Background(), "Azure", "gocover", commit)
+		if err != nil || decision.Allowed != allowed || decision.Commit != commit {
+			t.Errorf("expect %s allowed %t, but get %+v %v", commit, allowed, decision, err)
+		}
+	}
+
+	_, err := NewClient(server.URL, staticToken("wrong"), nil).Decide(context.Background(), "Azure", "gocover", "good")
+	if !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect ErrUnexpectedResponse, but get %v", err)
+	}
+}
@@ -0,0 +1,379 @@
+package gittool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	utildiff "github.com/go-git/go-git/v5/utils/diff"
+)
+
+// DiffAlgorithm is the algorithm that finds the changed lines of a modified file, as git diff --diff-algorithm.
+type DiffAlgorithm string
+
+const (
+	// MyersDiff is the default algorithm of git and go-git, it finds a minimal diff,
+	// which sometimes matches the unrelated lines such as the braces and the blank lines.
+	MyersDiff DiffAlgorithm = "myers"
+	// PatienceDiff anchors the diff at the lines that are unique in both files, such as the function signatures,
+	// so the moved and rewritten blocks don't borrow the common lines around them.
+	PatienceDiff DiffAlgorithm = "patience"
+	// HistogramDiff extends the patience diff to the lines that occur a few times, it's the algorithm of git diff --histogram.
+	HistogramDiff DiffAlgorithm = "histogram"
+)
+
+var ErrUnknownDiffAlgorithm = errors.New("unknown diff algorithm")
+
+// histogramMaxChain is the maximum occurrences of a line in a region that the histogram diff anchors at, as git.
+const histogramMaxChain = 64
+
+// Validate checks the algorithm is one of the algorithms, empty is the myers diff.
+func (a DiffAlgorithm) Validate() error {
+	switch a {
+	case "", MyersDiff, PatienceDiff, HistogramDiff:
+		return nil
+	}
+	return fmt.Errorf("%w %q, one of: %s, %s, %s", ErrUnknownDiffAlgorithm, a, MyersDiff, PatienceDiff, HistogramDiff)
+}
+
+// lineChunks returns the chunks of the line diff between the contents by the algorithm.
+func lineChunks(algorithm DiffAlgorithm, from, to string) []diff.Chunk {
+	if algorithm == "" || algorithm == MyersDiff {
+		return toChunks(utildiff.Do(from, to))
+	}
+
+	a, b := splitLines(from), splitLines(to)
+	// the lines are compared by the ids, so the algorithms compare ints rather than strings.
+	ids := make(map[string]int)
+	toIDs := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+	d := &lineDiff{a: toIDs(a), b: toIDs(b), algorithm: algorithm}
+	d.diff(0, len(a), 0, len(b))
+
+	var chunks []diff.Chunk
+	var content strings.Builder
+	operation := diff.Equal
+	flush := func() {
+		if content.Len() != 0 {
+			chunks = append(chunks, &textChunk{content: content.String(), operation: operation})
+			content.Reset()
+		}
+	}
+	ai, bi := 0, 0
+	emit := func(op diff.Operation, line string) {
+		if op != operation {
+			flush()
+			operation = op
+		}
+		content.WriteString(line)
+	}
+	for _, m := range append(d.matches, match{a: len(a), b: len(b)}) {
+		for ; ai < m.a; ai++ {
+			emit(diff.Delete, a[ai])
+		}
+		for ; bi < m.b; bi++ {
+			emit(diff.Add, b[bi])
+		}
+		if m.a < len(a) {
+			emit(diff.Equal, a[m.a])
+			ai, bi = m.a+1, m.b+1
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitLines splits the contents into the lines with their line breaks.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// match is a pair of the equal lines of the two files.
+type match struct {
+	a, b int
+}
+
+// lineDiff finds the matched lines of a and b in order.
+type lineDiff struct {
+	a, b      []int
+	algorithm DiffAlgorithm
+	matches   []match
+}
+
+// diff adds the matches of the regions a[a0:a1] and b[b0:b1] in order.
+func (d *lineDiff) diff(a0, a1, b0, b1 int) {
+	a0, a1, b0, b1, suffix := d.trim(a0, a1, b0, b1)
+	defer func() { d.matches = append(d.matches, suffix...) }()
+	if a0 == a1 || b0 == b1 {
+		return
+	}
+
+	var anchors []match
+	if d.algorithm == HistogramDiff {
+		anchors = d.histogramAnchors(a0, a1, b0, b1)
+	} else {
+		anchors = d.patienceAnchors(a0, a1, b0, b1)
+	}
+	if len(anchors) == 0 {
+		d.myers(a0, a1, b0, b1)
+		return
+	}
+	for _, m := range anchors {
+		d.diff(a0, m.a, b0, m.b)
+		d.matches = append(d.matches, m)
+		a0, b0 = m.a+1, m.b+1
+	}
+	d.diff(a0, a1, b0, b1)
+}
+
+// trim adds the matches of the common prefix of the regions, and returns the regions without the common prefix and suffix,
+// with the matches of the suffix in order, which are added after the regions are diffed.
+func (d *lineDiff) trim(a0, a1, b0, b1 int) (int, int, int, int, []match) {
+	// the common prefix and suffix are matched first, as git does.
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.matches = append(d.matches, match{a: a0, b: b0})
+		a0++
+		b0++
+	}
+	end := a1
+	for a0 < a1 && b0 < b1 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+	}
+	suffix := make([]match, 0, end-a1)
+	for i := a1; i < end; i++ {
+		suffix = append(suffix, match{a: i, b: b1 + i - a1})
+	}
+	return a0, a1, b0, b1, suffix
+}
+
+// patienceAnchors returns the longest increasing sequence of the lines that occur once in both regions.
+func (d *lineDiff) patienceAnchors(a0, a1, b0, b1 int) []match {
+	type occurrence struct {
+		countA, countB int
+		a, b           int
+	}
+	occurrences := make(map[int]*occurrence)
+	for i := a0; i < a1; i++ {
+		o, ok := occurrences[d.a[i]]
+		if !ok {
+			o = &occurrence{}
+			occurrences[d.a[i]] = o
+		}
+		o.countA++
+		o.a = i
+	}
+	for i := b0; i < b1; i++ {
+		if o, ok := occurrences[d.b[i]]; ok {
+			o.countB++
+			o.b = i
+		}
+	}
+	var unique []match
+	for i := a0; i < a1; i++ {
+		if o := occurrences[d.a[i]]; o.countA == 1 && o.countB == 1 {
+			unique = append(unique, match{a: o.a, b: o.b})
+		}
+	}
+	return longestIncreasing(unique)
+}
+
+// longestIncreasing returns the longest sequence of the matches, which are in the order of a, that increase in b.
+func longestIncreasing(matches []match) []match {
+	if len(matches) == 0 {
+		return nil
+	}
+	// tails[k] is the index of the match that ends the increasing sequence of length k+1 with the least b.
+	var tails []int
+	previous := make([]int, len(matches))
+	for i, m := range matches {
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if matches[tails[mid]].b < m.b {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		previous[i] = -1
+		if lo > 0 {
+			previous[i] = tails[lo-1]
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+	result := make([]match, len(tails))
+	for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; i, k = previous[i], k-1 {
+		result[k] = matches[i]
+	}
+	return result
+}
+
+// histogramAnchors returns the equal lines of the longest common region that contains the least frequent lines of a,
+// the region is extended from each occurrence of the lines of b in a, the lines that occur more than
+// histogramMaxChain times are not anchored. The regions before and after it are diffed again by the caller.
+func (d *lineDiff) histogramAnchors(a0, a1, b0, b1 int) []match {
+	occurrences := make(map[int][]int)
+	for i := a0; i < a1; i++ {
+		occurrences[d.a[i]] = append(occurrences[d.a[i]], i)
+	}
+
+	bestCount, bestA, bestB, bestLength := histogramMaxChain+1, 0, 0, 0
+	for j := b0; j < b1; {
+		next := j + 1
+		positions := occurrences[d.b[j]]
+		if len(positions) == 0 || len(positions) > bestCount {
+			j = next
+			continue
+		}
+		for _, i := range positions {
+			start, startB, end, endB := i, j, i+1, j+1
+			for start > a0 && startB > b0 && d.a[start-1] == d.b[startB-1] {
+				start--
+				startB--
+			}
+			for end < a1 && endB < b1 && d.a[end] == d.b[endB] {
+				end++
+				endB++
+			}
+			count := histogramMaxChain + 1
+			for k := start; k < end; k++ {
+				if c := len(occurrences[d.a[k]]); c < count {
+					count = c
+				}
+			}
+			if end-start > bestLength && count <= bestCount || count < bestCount {
+				bestCount, bestA, bestB, bestLength = count, start, startB, end-start
+			}
+			if endB > next {
+				next = endB
+			}
+		}
+		j = next
+	}
+	if bestLength == 0 || bestCount > histogramMaxChain {
+		return nil
+	}
+	anchors := make([]match, 0, bestLength)
+	for k := 0; k < bestLength; k++ {
+		anchors = append(anchors, match{a: bestA + k, b: bestB + k})
+	}
+	return anchors
+}
+
+// myers adds the matches of a minimal diff of the regions, it's the fallback when there is no anchor.
+// The regions are split at the middle of a minimal path and diffed recursively, so it takes linear memory
+// rather than keeping the frontier of every step.
+func (d *lineDiff) myers(a0, a1, b0, b1 int) {
+	a0, a1, b0, b1, suffix := d.trim(a0, a1, b0, b1)
+	defer func() { d.matches = append(d.matches, suffix...) }()
+	if a0 == a1 || b0 == b1 {
+		return
+	}
+	x, y, ok := d.middle(a0, a1, b0, b1)
+	if !ok {
+		return
+	}
+	d.myers(a0, x, b0, y)
+	d.myers(x, a1, y, b1)
+}
+
+// middle returns a point of a minimal path of the regions, where the forward search from the start and the backward search
+// from the end meet. The regions don't have common prefix or suffix. It returns false if the regions have no equal line.
+func (d *lineDiff) middle(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	maxSteps := (n + m + 1) / 2
+	offset := maxSteps + 1
+	// forward[offset+k] is the furthest x on the diagonal k from the start, backward[offset+k] is that from the end.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// the paths meet on a forward step if the delta is odd, or on a backward step otherwise.
+	odd := delta%2 != 0
+	// the diagonals that run out of the regions are skipped.
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+	for step := 0; step < maxSteps; step++ {
+		for k := -step + forwardStart; k <= step-forwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || k != step && forward[i-1] < forward[i+1] {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			forward[i] = x
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd:
+				if j := offset + delta - k; j >= 0 && j < len(backward) && backward[j] != -1 && x >= n-backward[j] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+
+		for k := -step + backwardStart; k <= step-backwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || k != step && backward[i-1] < backward[i+1] {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a1-x-1] == d.b[b1-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !odd:
+				if j := offset + delta - k; j >= 0 && j < len(forward) && forward[j] != -1 {
+					forwardX := forward[j]
+					if forwardX >= n-x {
+						return a0 + forwardX, b0 + forwardX - (j - offset), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
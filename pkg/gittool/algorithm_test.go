@@ -0,0 +1,195 @@
+package gittool
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// applyChunks returns the contents before and after the chunks.
+func applyChunks(chunks []diff.Chunk) (string, string) {
+	var from, to strings.Builder
+	for _, c := range chunks {
+		if c.Type() != diff.Add {
+			from.WriteString(c.Content())
+		}
+		if c.Type() != diff.Delete {
+			to.WriteString(c.Content())
+		}
+	}
+	return from.String(), to.String()
+}
+
+// addedLines returns the added line numbers of the chunks.
+func addedLines(chunks []diff.Chunk) []int {
+	var lines []int
+	line := 0
+	for _, c := range chunks {
+		if c.Type() == diff.Delete {
+			continue
+		}
+		for range splitLines(c.Content()) {
+			line++
+			if c.Type() == diff.Add {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+func TestLineChunks(t *testing.T) {
+	from := "func a() {\n\tx()\n}\n\nfunc b() {\n\tif y {\n\t\treturn\n\t}\n}\n"
+	to := "func a() {\n\tx()\n}\n\nfunc c() {\n\tif z {\n\t\treturn\n\t}\n}\n\nfunc b() {\n\tif y {\n\t\treturn\n\t}\n}\n"
+
+	for _, algorithm := range []DiffAlgorithm{MyersDiff, PatienceDiff, HistogramDiff} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			chunks := lineChunks(algorithm, from, to)
+			if f, tt := applyChunks(chunks); f != from || tt != to {
+				t.Fatalf("chunks don't restore the contents:\n%s\n%s", f, tt)
+			}
+			if algorithm == MyersDiff {
+				return
+			}
+			// the added function is func c() and the blank line after it, the lines of func b() are kept.
+			expected := []int{5, 6, 7, 8, 9, 10}
+			if lines := addedLines(chunks); !equalInts(lines, expected) {
+				t.Errorf("expect added lines %v, but get %v", expected, lines)
+			}
+		})
+	}
+}
+
+func TestDiffChangesAlgorithm(t *testing.T) {
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	base := commitFile(repo, path, "foo.go", "package foo\n\nfunc a() {\n\tx()\n}\n\nfunc b() {\n\tif y {\n\t\treturn\n\t}\n}\n")
+	head := commitFile(repo, path, "foo.go", "package foo\n\nfunc a() {\n\tx()\n}\n\nfunc c() {\n\tif z {\n\t\treturn\n\t}\n}\n\nfunc b() {\n\tif y {\n\t\treturn\n\t}\n}\n")
+
+	g := &gitClient{repositoryPath: path, repository: repo, algorithm: HistogramDiff}
+	for _, target := range []string{HEADTarget, head.String()} {
+		changes, err := g.DiffChanges(base.String(), target)
+		if err != nil {
+			t.Fatalf("should not return error, but get: %s", err)
+		}
+		if len(changes) != 1 || len(changes[0].Sections) != 1 || changes[0].Sections[0].StartLine != 7 || changes[0].Sections[0].EndLine != 12 {
+			t.Errorf("expect section of line 7 to 12 of %s, but get %+v", target, changes)
+		}
+	}
+}
+
+func TestLineChunksRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabet := []string{"{\n", "}\n", "\n", "a\n", "b\n", "c\n", "return\n"}
+	random := func() string {
+		var b strings.Builder
+		for i := r.Intn(30); i > 0; i-- {
+			b.WriteString(alphabet[r.Intn(len(alphabet))])
+		}
+		return b.String()
+	}
+	for i := 0; i < 500; i++ {
+		from, to := random(), random()
+		for _, algorithm := range []DiffAlgorithm{PatienceDiff, HistogramDiff} {
+			chunks := lineChunks(algorithm, from, to)
+			if f, tt := applyChunks(chunks); f != from || tt != to {
+				t.Fatalf("%s chunks of %q and %q restore %q and %q", algorithm, from, to, f, tt)
+			}
+		}
+	}
+}
+
+// longestCommon returns the length of the longest common subsequence of a and b.
+func longestCommon(a, b []int) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] > lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+// checkMatches checks the matches are equal lines in order, and reports the number of the matches.
+func checkMatches(t *testing.T, d *lineDiff) int {
+	t.Helper()
+	for i, m := range d.matches {
+		if d.a[m.a] != d.b[m.b] || i > 0 && (m.a <= d.matches[i-1].a || m.b <= d.matches[i-1].b) {
+			t.Fatalf("invalid match %d %+v of %v and %v", i, m, d.a, d.b)
+		}
+	}
+	return len(d.matches)
+}
+
+func TestMyersMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []int {
+		lines := make([]int, r.Intn(40))
+		for i := range lines {
+			lines[i] = r.Intn(4)
+		}
+		return lines
+	}
+	for i := 0; i < 1000; i++ {
+		d := &lineDiff{a: random(), b: random()}
+		d.myers(0, len(d.a), 0, len(d.b))
+		if matches, expected := checkMatches(t, d), longestCommon(d.a, d.b); matches != expected {
+			t.Fatalf("expect %d matches of %v and %v, but get %d", expected, d.a, d.b, matches)
+		}
+	}
+}
+
+func TestMyersLargeRegion(t *testing.T) {
+	// the regions would take gigabytes if the frontier of every step was kept.
+	a := make([]int, 50000)
+	b := make([]int, 0, len(a))
+	for i := range a {
+		a[i] = i % 3
+		if i%100 == 0 {
+			b = append(b, 3)
+			continue
+		}
+		b = append(b, a[i])
+	}
+	d := &lineDiff{a: a, b: b}
+	d.myers(0, len(a), 0, len(b))
+	if matches := checkMatches(t, d); matches != len(a)-len(a)/100 {
+		t.Errorf("expect %d matches, but get %d", len(a)-len(a)/100, matches)
+	}
+}
+
+func TestDiffAlgorithmValidate(t *testing.T) {
+	for _, a := range []DiffAlgorithm{"", MyersDiff, PatienceDiff, HistogramDiff} {
+		if err := a.Validate(); err != nil {
+			t.Errorf("expect %q is valid, but get %s", a, err)
+		}
+	}
+	if err := DiffAlgorithm("minimal").Validate(); !errors.Is(err, ErrUnknownDiffAlgorithm) {
+		t.Errorf("expect unknown diff algorithm, but get %v", err)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
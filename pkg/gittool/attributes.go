@@ -0,0 +1,74 @@
+package gittool
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+)
+
+// Attributes are the attributes that the .gitattributes files of a worktree set on its files, as git check-attr.
+type Attributes struct {
+	root string
+	// patterns are in the ascending order of precedence, the .gitattributes of the root first,
+	// then the .gitattributes of the sub directories, the later lines of a file take precedence.
+	patterns []gitattributes.MatchAttribute
+}
+
+// LoadAttributes reads the .gitattributes files of the worktree of the repository that contains the directory.
+func LoadAttributes(dir string) (*Attributes, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	repository, err := gogit.PlainOpenWithOptions(absDir, &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := gitattributes.ReadPatterns(worktree.Filesystem, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read .gitattributes: %w", err)
+	}
+	return &Attributes{root: worktree.Filesystem.Root(), patterns: patterns}, nil
+}
+
+// IsSet returns true if the attribute is set on the file, such as `linguist-generated` or `linguist-generated=true`,
+// it's false if the attribute is unset by `-name`, unspecified by `!name`, or set to another value.
+// The path is absolute or relative to the root of the worktree, the files out of the worktree have no attributes.
+// The macros defined by [attr] are not expanded.
+func (a *Attributes) IsSet(path, name string) bool {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(a.root, path)
+		if err != nil {
+			return false
+		}
+		path = rel
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == "." || path == ".." || strings.HasPrefix(path, "../") {
+		return false
+	}
+	parts := strings.Split(path, "/")
+
+	for i := len(a.patterns) - 1; i >= 0; i-- {
+		p := a.patterns[i]
+		if p.Pattern == nil || !p.Pattern.Match(parts) {
+			continue
+		}
+		// the last attribute of the name on a line takes precedence, as git.
+		for j := len(p.Attributes) - 1; j >= 0; j-- {
+			attr := p.Attributes[j]
+			if attr.Name() != name {
+				continue
+			}
+			return attr.IsSet() || attr.IsValueSet() && attr.Value() == "true"
+		}
+	}
+	return false
+}
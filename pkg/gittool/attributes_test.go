@@ -0,0 +1,47 @@
+package gittool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttributes(t *testing.T) {
+	path, _, clean := temporalRepository("")
+	defer clean()
+
+	writeFile(path, ".gitattributes", "*.pb.go linguist-generated=true\n"+
+		"mocks/** linguist-generated\n"+
+		"mocks/keep.go -linguist-generated\n"+
+		"/scripts/** export-ignore\n"+
+		"docs/*.go linguist-generated=false\n")
+	checkError(os.MkdirAll(filepath.Join(path, "api", "v2"), 0755))
+	writeFile(filepath.Join(path, "api"), ".gitattributes", "v2/*.go linguist-generated\nlegacy.pb.go !linguist-generated\n")
+
+	a, err := LoadAttributes(filepath.Join(path, "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		path, name string
+		expected   bool
+	}{
+		{path: "api/foo.pb.go", name: "linguist-generated", expected: true},
+		{path: "foo.pb.go", name: "linguist-generated", expected: true},
+		{path: "api/legacy.pb.go", name: "linguist-generated", expected: false},
+		{path: "api/v2/client.go", name: "linguist-generated", expected: true},
+		{path: "v2/client.go", name: "linguist-generated", expected: false},
+		{path: "mocks/foo/foo.go", name: "linguist-generated", expected: true},
+		{path: "mocks/keep.go", name: "linguist-generated", expected: false},
+		{path: "docs/doc.go", name: "linguist-generated", expected: false},
+		{path: "scripts/gen/main.go", name: "export-ignore", expected: true},
+		{path: "pkg/scripts/main.go", name: "export-ignore", expected: false},
+		{path: "main.go", name: "linguist-generated", expected: false},
+		{path: filepath.Join(path, "foo.pb.go"), name: "linguist-generated", expected: true},
+		{path: filepath.Join(path, "..", "foo.pb.go"), name: "linguist-generated", expected: false},
+	} {
+		if actual := a.IsSet(c.path, c.name); actual != c.expected {
+			t.Errorf("expect %s of %s to be %v, but get %v", c.name, c.path, c.expected, actual)
+		}
+	}
+}
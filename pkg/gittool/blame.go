@@ -0,0 +1,82 @@
+package gittool
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+var ErrOutsideRepository = errors.New("file is outside of the repository")
+
+// BlameLine is the last commit that changed a line of a file in HEAD.
+type BlameLine struct {
+	// Author is the email of the author of the commit.
+	Author string
+	// Commit is the sha of the commit.
+	Commit string
+}
+
+// Blamer returns the last commit that changed each line of the files in HEAD.
+type Blamer interface {
+	// Blame returns the lines of the file in HEAD, the first line is at index 0.
+	// The path is absolute or relative to the directory of the blamer.
+	Blame(path string) ([]*BlameLine, error)
+}
+
+// NewBlamer creates a blamer of the repository that contains the directory.
+func NewBlamer(dir string) (Blamer, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	repository, err := gogit.PlainOpenWithOptions(absDir, &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	return &blamer{repository: repository, root: worktree.Filesystem.Root(), dir: absDir}, nil
+}
+
+type blamer struct {
+	repository *gogit.Repository
+	// root is the root of the worktree.
+	root string
+	dir  string
+}
+
+var _ Blamer = (*blamer)(nil)
+
+func (b *blamer) Blame(path string) ([]*BlameLine, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(b.dir, path)
+	}
+	relative, err := filepath.Rel(b.root, path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%w: %s", ErrOutsideRepository, path)
+	}
+
+	head, err := b.repository.Head()
+	if err != nil {
+		return nil, fmt.Errorf("get HEAD: %w", err)
+	}
+	commit, err := b.repository.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("get commit %s: %w", head.Hash(), err)
+	}
+	result, err := gogit.Blame(commit, filepath.ToSlash(relative))
+	if err != nil {
+		return nil, fmt.Errorf("blame %s: %w", relative, err)
+	}
+
+	lines := make([]*BlameLine, 0, len(result.Lines))
+	for _, line := range result.Lines {
+		lines = append(lines, &BlameLine{Author: line.Author, Commit: line.Hash.String()})
+	}
+	return lines, nil
+}
@@ -0,0 +1,59 @@
+package gittool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestBlame(t *testing.T) {
+	path, repo, clean := temporalRepository("")
+	defer clean()
+
+	// the commits are an hour apart, since the history of blame is ordered by the commit time.
+	commit := func(author, contents string, when time.Time) plumbing.Hash {
+		writeFile(path, "foo.go", contents)
+		worktree, err := repo.Worktree()
+		checkError(err)
+		_, err = worktree.Add("foo.go")
+		checkError(err)
+		hash, err := worktree.Commit("change foo.go", &gogit.CommitOptions{
+			Author: &object.Signature{Name: author, Email: author + "@bar.org", When: when},
+		})
+		checkError(err)
+		return hash
+	}
+	now := time.Now()
+	first := commit("foo", "package foo\n\nfunc Foo() {}\n", now.Add(time.Hour))
+	second := commit("bar", "package foo\n\nfunc Foo() {}\n\nfunc Bar() {}\n", now.Add(2*time.Hour))
+	checkError(os.MkdirAll(filepath.Join(path, "pkg"), 0755))
+
+	// the blamer finds the repository from a sub directory.
+	b, err := NewBlamer(filepath.Join(path, "pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := b.Blame(filepath.Join("..", "foo.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("expect 5 lines, but get %d", len(lines))
+	}
+	if lines[0].Commit != first.String() || lines[0].Author != "foo@bar.org" || lines[4].Commit != second.String() || lines[4].Author != "bar@bar.org" {
+		t.Errorf("unexpected lines %+v %+v", lines[0], lines[4])
+	}
+
+	if _, err := b.Blame(filepath.Join(path, "..", "bar.go")); !errors.Is(err, ErrOutsideRepository) {
+		t.Errorf("expect ErrOutsideRepository, but get %v", err)
+	}
+	if _, err := b.Blame(filepath.Join(path, "untracked.go")); err == nil {
+		t.Error("expect an error of the file not in HEAD")
+	}
+}
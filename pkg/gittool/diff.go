@@ -10,34 +10,53 @@ import (
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/format/diff"
 	gogitobj "github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// NewGitClient creates a git client instance for git diff.
+// NewGitClient creates a git client instance for git diff, the changed lines are found by the myers diff.
 func NewGitClient(
 	repositoryPath string,
 ) (GitClient, error) {
-	repository, err := gogit.PlainOpen(repositoryPath)
+	return NewGitClientWithAlgorithm(repositoryPath, MyersDiff)
+}
+
+// NewGitClientWithAlgorithm creates a git client instance that finds the changed lines of the modified files by the algorithm.
+func NewGitClientWithAlgorithm(repositoryPath string, algorithm DiffAlgorithm) (GitClient, error) {
+	if err := algorithm.Validate(); err != nil {
+		return nil, err
+	}
+	// common dir is enabled so that the linked worktrees created by `git worktree add` can be opened.
+	repository, err := gogit.PlainOpenWithOptions(repositoryPath, &gogit.PlainOpenOptions{EnableDotGitCommonDir: true})
 	if err != nil {
 		return nil, err
 	}
 	return &gitClient{
 		repository:     repository,
 		repositoryPath: repositoryPath,
+		algorithm:      algorithm,
 	}, nil
 }
 
 type GitClient interface {
 	// DiffChangesFromCommitted returns the diff changes between HEAD and compared branch commit.
 	DiffChangesFromCommitted(compareBranch string) ([]*Change, error)
+	// DiffChanges returns the diff changes between target and compared branch commit,
+	// target is one of HEAD, worktree, or a revision such as a commit, a branch or a stash like stash@{0}.
+	DiffChanges(compareBranch, target string) ([]*Change, error)
+	// ChangedFiles returns the added or modified files between target and compared branch commit, the files of any type are included,
+	// such as the templates and the sql files, target is the same as DiffChanges.
+	ChangedFiles(compareBranch, target string) ([]string, error)
+	// LatestSemverTag returns the highest semver tag with the prefix that is reachable from HEAD.
+	LatestSemverTag(prefix string) (string, error)
 }
 
 type gitClient struct {
 	repository     *gogit.Repository
 	repositoryPath string
+	// algorithm finds the changed lines of the modified files.
+	algorithm DiffAlgorithm
 }
 
 var _ GitClient = (*gitClient)(nil)
@@ -59,7 +78,11 @@ func (g *gitClient) DiffChangesFromCommitted(compareBranch string) ([]*Change, e
 			return nil, errors.New("no patch found")
 		}
 
-		diffChange, err := g.buildChangeFromPatch(filePatches[0])
+		filePatch, err := g.rediff(change, filePatches[0])
+		if err != nil {
+			return nil, fmt.Errorf("diff %s: %w", change.To.Name, err)
+		}
+		diffChange, err := g.buildChangeFromPatch(filePatch)
 		if err != nil {
 			return nil, fmt.Errorf("build change from patch: %w", err)
 		}
@@ -94,7 +117,7 @@ func (g *gitClient) diffChanges(comparedBranch string) (gogitobj.Changes, error)
 	}
 
 	// get commit object of compared branch
-	comparedHash, err := g.repository.ResolveRevision(plumbing.Revision(comparedBranch))
+	comparedHash, err := g.resolveRevision(comparedBranch)
 	if err != nil {
 		return gogitobj.Changes{}, fmt.Errorf("get %s %w", comparedBranch, err)
 	}
@@ -111,6 +134,38 @@ func (g *gitClient) diffChanges(comparedBranch string) (gogitobj.Changes, error)
 	return gogitobj.DiffTree(comparedTree, headTree)
 }
 
+// rediff returns the file patch whose chunks are found by the algorithm of the client, the patches of go-git
+// are found by the myers diff, so they're returned as they are for the myers diff and the added and deleted files.
+func (g *gitClient) rediff(change *gogitobj.Change, filePatch diff.FilePatch) (diff.FilePatch, error) {
+	if g.algorithm == "" || g.algorithm == MyersDiff {
+		return filePatch, nil
+	}
+	from, to, err := change.Files()
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil || filePatch.IsBinary() {
+		return filePatch, nil
+	}
+	fromContents, err := from.Contents()
+	if err != nil {
+		return nil, err
+	}
+	toContents, err := to.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return &rediffedPatch{FilePatch: filePatch, chunks: lineChunks(g.algorithm, fromContents, toContents)}, nil
+}
+
+// rediffedPatch is a file patch whose chunks are found by another algorithm.
+type rediffedPatch struct {
+	diff.FilePatch
+	chunks []diff.Chunk
+}
+
+func (p *rediffedPatch) Chunks() []diff.Chunk { return p.chunks }
+
 // buildChangeFromPatch builds the diff change from file patch.
 // It's the entrance for building diff change and use different strategy according to different context.
 func (g *gitClient) buildChangeFromPatch(filePatch diff.FilePatch) (*Change, error) {
@@ -156,7 +211,7 @@ func (g *gitClient) buildChangeFromChunks(filename string, chunks []diff.Chunk)
 	// count the total lines of the file
 	// equals lines + added lines should be equal with total lines.
 	totalCount := 0
-	var sections []*Section
+	var sections, deletions []*Section
 
 	for _, chunk := range chunks {
 
@@ -190,14 +245,27 @@ func (g *gitClient) buildChangeFromChunks(filename string, chunks []diff.Chunk)
 			})
 
 		case diff.Delete:
-			// we omit delete chunks
+			// the deleted lines are not in the file, so they don't count for coverage.
+			var contents []string
+			scanner := bufio.NewScanner(bytes.NewBufferString(chunk.Content()))
+			for scanner.Scan() {
+				contents = append(contents, scanner.Text())
+			}
+			deletions = append(deletions, &Section{
+				StartLine: totalCount + 1,
+				EndLine:   totalCount + 1,
+				Count:     len(contents),
+				Contents:  contents,
+				Operation: Delete,
+			})
 		}
 	}
 
 	return &Change{
-		FileName: filename,
-		Sections: sections,
-		Mode:     ModifyMode,
+		FileName:  filename,
+		Sections:  sections,
+		Deletions: deletions,
+		Mode:      ModifyMode,
 	}, nil
 }
 
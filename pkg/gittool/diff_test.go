@@ -194,6 +194,15 @@ line6`
 		if section.Contents[1] != "line4" {
 			t.Errorf("first item should be 'line4', but get: %s", section.Contents[1])
 		}
+
+		// the deleted lines were after line4, at the end of the file.
+		if len(change.Deletions) != 1 {
+			t.Fatalf("change should contain 1 deletion, but get %d", len(change.Deletions))
+		}
+		deletion := change.Deletions[0]
+		if deletion.Operation != Delete || deletion.StartLine != 5 || deletion.EndLine != 5 || deletion.Count != 2 || deletion.Contents[1] != "line6" {
+			t.Errorf("unexpected deletion %+v", deletion)
+		}
 	})
 }
 
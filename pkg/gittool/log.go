@@ -0,0 +1,48 @@
+package gittool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Commit is a commit of the history listed by FirstParentLog.
+type Commit struct {
+	// SHA is the sha of the commit.
+	SHA string
+	// Parents are the shas of the parents, the first parent is the branch that the commit is on.
+	Parents []string
+	// Message is the full message of the commit.
+	Message string
+}
+
+// FirstParentLog returns the commits reachable from head but not from base by the first parents, the newest first,
+// they are the commits that landed on the branch one by one, such as the merge commits or the squashed commits of the pull requests.
+func FirstParentLog(ctx context.Context, repositoryPath, base, head string) ([]*Commit, error) {
+	out, err := runGit(ctx, repositoryPath, "log", "--first-parent", "--format=%H%x00%P%x00%B%x1e", base+".."+head)
+	if err != nil {
+		return nil, fmt.Errorf("log %s..%s: %w", base, head, err)
+	}
+	var commits []*Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, &Commit{
+			SHA:     fields[0],
+			Parents: strings.Fields(fields[1]),
+			Message: strings.TrimSpace(fields[2]),
+		})
+	}
+	return commits, nil
+}
+
+// RevList returns the shas of the commits reachable from head but not from base, the newest first.
+func RevList(ctx context.Context, repositoryPath, base, head string) ([]string, error) {
+	out, err := runGit(ctx, repositoryPath, "rev-list", base+".."+head)
+	if err != nil {
+		return nil, fmt.Errorf("list %s..%s: %w", base, head, err)
+	}
+	return strings.Fields(out), nil
+}
@@ -0,0 +1,60 @@
+package gittool
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestFirstParentLog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	ctx := context.Background()
+
+	base := commitFile(repo, path, "foo.go", "package foo\n")
+	worktree, err := repo.Worktree()
+	checkError(err)
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	feature := commitFile(repo, path, "bar.go", "package foo\n\nfunc Bar() {}\n")
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Hash: base, Force: true}))
+	squash := commitFile(repo, path, "baz.go", "package foo\n\nfunc Baz() {}\n")
+	if _, err := runGit(ctx, path, "merge", "--no-ff", "-m", "Merge pull request #1 from owner/feature", feature.String()); err != nil {
+		t.Fatal(err)
+	}
+	merge, err := ResolveCommit(ctx, path, "HEAD")
+	checkError(err)
+
+	commits, err := FirstParentLog(ctx, path, base.String(), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expect the merge and the squashed commits, but get %d commits", len(commits))
+	}
+	if commits[0].SHA != merge || !reflect.DeepEqual(commits[0].Parents, []string{squash.String(), feature.String()}) ||
+		commits[0].Message != "Merge pull request #1 from owner/feature" {
+		t.Errorf("unexpected merge commit %+v", commits[0])
+	}
+	if commits[1].SHA != squash.String() || commits[1].Message != "add baz.go" {
+		t.Errorf("unexpected squashed commit %+v", commits[1])
+	}
+
+	shas, err := RevList(ctx, path, squash.String(), merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(shas, []string{merge, feature.String()}) {
+		t.Errorf("expect the merge and the feature commits, but get %v", shas)
+	}
+
+	if _, err := FirstParentLog(ctx, path, "unknown", "HEAD"); err == nil {
+		t.Error("expect an error of the unknown base")
+	}
+}
@@ -0,0 +1,84 @@
+package gittool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultNotesRef is the notes ref of gocover, the notes are in refs/notes/gocover.
+const DefaultNotesRef = "gocover"
+
+var ErrNoteNotFound = errors.New("note not found")
+
+// Notes reads and writes the git notes of the commits under a notes ref.
+// The notes are changed by the git command, and they're shared by `git push origin refs/notes/{ref}`.
+type Notes struct {
+	repositoryPath string
+	ref            string
+}
+
+// NewNotes creates the notes of the repository under the ref, DefaultNotesRef is used if the ref is empty.
+func NewNotes(repositoryPath, ref string) *Notes {
+	if ref == "" {
+		ref = DefaultNotesRef
+	}
+	return &Notes{repositoryPath: repositoryPath, ref: ref}
+}
+
+// Set replaces the note of the commit.
+func (n *Notes) Set(ctx context.Context, commit string, note []byte) error {
+	if _, err := runGitWithInput(ctx, n.repositoryPath, bytes.NewReader(note), "notes", "--ref", n.ref, "add", "--force", "--file", "-", commit); err != nil {
+		return fmt.Errorf("set note of %s: %w", commit, err)
+	}
+	return nil
+}
+
+// Get returns the note of the commit, ErrNoteNotFound is returned if the commit has no note.
+func (n *Notes) Get(ctx context.Context, commit string) ([]byte, error) {
+	sha, err := n.Resolve(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+	if !n.exists(ctx) {
+		return nil, fmt.Errorf("%w: %s", ErrNoteNotFound, commit)
+	}
+	// the commit and the notes ref are valid, so the note is missing if it fails.
+	out, err := runGit(ctx, n.repositoryPath, "notes", "--ref", n.ref, "show", sha)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoteNotFound, commit)
+	}
+	return []byte(out), nil
+}
+
+// Commits returns the commits that have notes, it's empty if the notes ref doesn't exist.
+func (n *Notes) Commits(ctx context.Context) ([]string, error) {
+	if !n.exists(ctx) {
+		return nil, nil
+	}
+	out, err := runGit(ctx, n.repositoryPath, "notes", "--ref", n.ref, "list")
+	if err != nil {
+		return nil, fmt.Errorf("list notes: %w", err)
+	}
+	var commits []string
+	// each line is "{note object} {annotated commit}".
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			commits = append(commits, fields[1])
+		}
+	}
+	return commits, nil
+}
+
+// exists reports whether the notes ref exists, it's created by the first note.
+func (n *Notes) exists(ctx context.Context) bool {
+	_, err := runGit(ctx, n.repositoryPath, "rev-parse", "--verify", "--quiet", "refs/notes/"+n.ref)
+	return err == nil
+}
+
+// Resolve resolves the revision to the commit sha.
+func (n *Notes) Resolve(ctx context.Context, revision string) (string, error) {
+	return ResolveCommit(ctx, n.repositoryPath, revision)
+}
@@ -0,0 +1,49 @@
+package gittool
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	ctx := context.Background()
+
+	notes := NewNotes(path, "")
+	commits, err := notes.Commits(ctx)
+	if err != nil || len(commits) != 0 {
+		t.Fatalf("expect no notes before the notes ref exists, but get %v, %v", commits, err)
+	}
+
+	head := commitFile(repo, path, "foo.go", "package foo\n")
+	if _, err := notes.Get(ctx, "HEAD"); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("expect ErrNoteNotFound, but get %v", err)
+	}
+	if err := notes.Set(ctx, "HEAD", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.Set(ctx, head.String(), []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	note, err := notes.Get(ctx, head.String()[:7])
+	if err != nil || string(note) != "second\n" {
+		t.Errorf("expect the note is replaced, but get %q, %v", note, err)
+	}
+
+	commits, err = notes.Commits(ctx)
+	if err != nil || len(commits) != 1 || commits[0] != head.String() {
+		t.Errorf("expect the noted commit %s, but get %v, %v", head, commits, err)
+	}
+	if _, err := NewNotes(path, "other").Get(ctx, "HEAD"); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("expect the notes of another ref are separated, but get %v", err)
+	}
+	if _, err := notes.Resolve(ctx, "missing"); err == nil {
+		t.Error("expect an error of the unknown revision")
+	}
+}
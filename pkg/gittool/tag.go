@@ -0,0 +1,102 @@
+package gittool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+var ErrNoSemverTag = errors.New("no semver tag found")
+
+// semverTag is a release tag and the commit it points to.
+type semverTag struct {
+	name    string
+	version string
+	commit  plumbing.Hash
+}
+
+// LatestSemverTag returns the highest semver tag that is reachable from HEAD, pre-releases are ignored.
+// Only the tags with the prefix are considered, such as `modulea/` for the nested module modulea,
+// the version is the rest of the tag name, with or without the leading `v`.
+func (g *gitClient) LatestSemverTag(prefix string) (string, error) {
+	head, err := g.repository.Head()
+	if err != nil {
+		return "", fmt.Errorf("get HEAD %w", err)
+	}
+	headCommit, err := g.repository.CommitObject(head.Hash())
+	if err != nil {
+		return "", fmt.Errorf("get HEAD commit %w", err)
+	}
+
+	tags, err := g.semverTags(prefix)
+	if err != nil {
+		return "", err
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return semver.Compare(tags[i].version, tags[j].version) > 0
+	})
+
+	// the latest release is usually reachable, so the ancestor check runs on the highest versions first.
+	for _, tag := range tags {
+		commit, err := g.repository.CommitObject(tag.commit)
+		if err != nil {
+			return "", fmt.Errorf("get %s commit %w", tag.name, err)
+		}
+		reachable, err := commit.IsAncestor(headCommit)
+		if err != nil {
+			return "", fmt.Errorf("check %s is ancestor of HEAD %w", tag.name, err)
+		}
+		if reachable {
+			return tag.name, nil
+		}
+	}
+	return "", fmt.Errorf("%w with prefix %q reachable from HEAD", ErrNoSemverTag, prefix)
+}
+
+// semverTags returns the release tags with the prefix, annotated tags are resolved to their commits.
+func (g *gitClient) semverTags(prefix string) ([]*semverTag, error) {
+	iter, err := g.repository.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("list tags %w", err)
+	}
+
+	var tags []*semverTag
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		version := strings.TrimPrefix(name, prefix)
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		if !semver.IsValid(version) || semver.Prerelease(version) != "" {
+			return nil
+		}
+
+		hash := ref.Hash()
+		tagObj, err := g.repository.TagObject(hash)
+		switch {
+		case err == nil:
+			commit, err := tagObj.Commit()
+			if err != nil {
+				// the tag doesn't point to a commit, it can't be a release.
+				return nil
+			}
+			hash = commit.Hash
+		case !errors.Is(err, plumbing.ErrObjectNotFound):
+			return fmt.Errorf("get tag %s %w", name, err)
+		}
+
+		tags = append(tags, &semverTag{name: name, version: version, commit: hash})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
@@ -0,0 +1,70 @@
+package gittool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestLatestSemverTag(t *testing.T) {
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	g := &gitClient{repositoryPath: path, repository: repo}
+
+	if _, err := g.LatestSemverTag(""); !errors.Is(err, ErrNoSemverTag) {
+		t.Fatalf("expect ErrNoSemverTag, but get %v", err)
+	}
+
+	createTag := func(name string, hash plumbing.Hash, annotated bool) {
+		var opts *gogit.CreateTagOptions
+		if annotated {
+			opts = &gogit.CreateTagOptions{
+				Tagger:  &object.Signature{Name: "foo", Email: "foo@bar.org", When: time.Now()},
+				Message: "release " + name,
+			}
+		}
+		_, err := repo.CreateTag(name, hash, opts)
+		checkError(err)
+	}
+
+	first := commitFile(repo, path, "foo.go", "package foo\n")
+	createTag("v1.0.0", first, false)
+	createTag("modulea/v0.1.0", first, false)
+	second := commitFile(repo, path, "foo.go", "package foo\n\nfunc Foo() {}\n")
+	createTag("1.1.0", second, true)
+	createTag("v1.10.0-rc.1", second, false)
+	createTag("release", second, false)
+	head := commitFile(repo, path, "bar.go", "package foo\n")
+
+	// a release on another branch is not reachable from HEAD.
+	worktree, err := repo.Worktree()
+	checkError(err)
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("hotfix"), Create: true}))
+	hotfix := commitFile(repo, path, "hotfix.go", "package foo\n")
+	createTag("v2.0.0", hotfix, false)
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Hash: head}))
+
+	tag, err := g.LatestSemverTag("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "1.1.0" {
+		t.Errorf("expect 1.1.0, but get %s", tag)
+	}
+
+	tag, err = g.LatestSemverTag("modulea/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "modulea/v0.1.0" {
+		t.Errorf("expect modulea/v0.1.0, but get %s", tag)
+	}
+
+	if _, err := g.LatestSemverTag("moduleb/"); !errors.Is(err, ErrNoSemverTag) {
+		t.Errorf("expect ErrNoSemverTag, but get %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package gittool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	gogitobj "github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// HEADTarget compares the HEAD commit, it's the default target.
+	HEADTarget = "HEAD"
+	// WorktreeTarget compares the files in the working tree, uncommitted and untracked changes are included.
+	WorktreeTarget = "worktree"
+)
+
+var (
+	// stashPattern matches the stash revisions, such as stash@{0} or refs/stash@{1}.
+	stashPattern = regexp.MustCompile(`^(?:refs/)?stash@\{(\d+)\}$`)
+
+	ErrStashNotFound = errors.New("stash not found")
+)
+
+// DiffChanges returns the diff changes between the target and the compared branch commit.
+// Target is HEAD, the working tree or any revision includes the stashes like stash@{1}.
+func (g *gitClient) DiffChanges(compareBranch, target string) ([]*Change, error) {
+	switch target {
+	case "", HEADTarget:
+		return g.DiffChangesFromCommitted(compareBranch)
+	case WorktreeTarget:
+		return g.diffChangesFromWorktree(compareBranch)
+	default:
+		return g.diffChangesFromRevision(compareBranch, target)
+	}
+}
+
+// diffChangesFromRevision returns the diff changes between the target revision and the compared branch commit.
+// Different from HEAD, contents of the new files are read from the target commit rather than the working tree.
+func (g *gitClient) diffChangesFromRevision(compareBranch, target string) ([]*Change, error) {
+	comparedTree, err := g.revisionTree(compareBranch)
+	if err != nil {
+		return nil, err
+	}
+	targetTree, err := g.revisionTree(target)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := gogitobj.DiffTree(comparedTree, targetTree)
+	if err != nil {
+		return nil, fmt.Errorf("execute diff: %w", err)
+	}
+
+	var diffChanges []*Change
+	for _, change := range changes {
+		patch, err := change.Patch()
+		if err != nil {
+			return nil, fmt.Errorf("get patch: %w", err)
+		}
+		for _, filePatch := range patch.FilePatches() {
+			filePatch, err := g.rediff(change, filePatch)
+			if err != nil {
+				return nil, fmt.Errorf("diff %s: %w", change.To.Name, err)
+			}
+			from, to := filePatch.Files()
+			if to == nil || !isGoFile(to) {
+				continue
+			}
+
+			diffChange, err := g.buildChangeFromChunks(to.Path(), filePatch.Chunks())
+			if err != nil {
+				return nil, fmt.Errorf("build change from chunks: %w", err)
+			}
+			if from == nil {
+				diffChange.Mode = NewMode
+			}
+			diffChanges = append(diffChanges, diffChange)
+		}
+	}
+	return diffChanges, nil
+}
+
+// diffChangesFromWorktree returns the diff changes between the files in the working tree and the compared branch commit.
+// The files of the compared commit, the HEAD commit and the untracked files are compared, ignored files are skipped.
+func (g *gitClient) diffChangesFromWorktree(compareBranch string) ([]*Change, error) {
+	comparedTree, err := g.revisionTree(compareBranch)
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := g.revisionTree(HEADTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := g.repository.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("get worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("get worktree status: %w", err)
+	}
+
+	candidates := make(map[string]bool)
+	for _, tree := range []*gogitobj.Tree{comparedTree, headTree} {
+		err := tree.Files().ForEach(func(f *gogitobj.File) error {
+			candidates[f.Name] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list files: %w", err)
+		}
+	}
+	for path, s := range status {
+		if s.Worktree == gogit.Untracked {
+			candidates[path] = true
+		}
+	}
+
+	var diffChanges []*Change
+	for _, path := range sortedKeys(candidates) {
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		info, err := os.Lstat(filepath.Join(g.repositoryPath, path))
+		if err != nil || !info.Mode().IsRegular() {
+			// deleted files or not regular files are omitted.
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(g.repositoryPath, path))
+		if err != nil {
+			return nil, err
+		}
+
+		comparedFile, err := comparedTree.File(path)
+		if errors.Is(err, gogitobj.ErrFileNotFound) {
+			change, err := g.buildChangeFromFile(path)
+			if err != nil {
+				return nil, err
+			}
+			diffChanges = append(diffChanges, change)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", path, err)
+		}
+
+		comparedContents, err := comparedFile.Contents()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		if comparedContents == string(data) {
+			continue
+		}
+
+		change, err := g.buildChangeFromChunks(path, lineChunks(g.algorithm, comparedContents, string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("build change from chunks: %w", err)
+		}
+		diffChanges = append(diffChanges, change)
+	}
+	return diffChanges, nil
+}
+
+// ChangedFiles returns the added or modified regular files between the target and the compared branch commit in order,
+// the deleted files are omitted.
+func (g *gitClient) ChangedFiles(compareBranch, target string) ([]string, error) {
+	comparedTree, err := g.revisionTree(compareBranch)
+	if err != nil {
+		return nil, err
+	}
+	if target == WorktreeTarget {
+		return g.changedFilesFromWorktree(comparedTree)
+	}
+	if target == "" {
+		target = HEADTarget
+	}
+	targetTree, err := g.revisionTree(target)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := gogitobj.DiffTree(comparedTree, targetTree)
+	if err != nil {
+		return nil, fmt.Errorf("execute diff: %w", err)
+	}
+	var files []string
+	for _, change := range changes {
+		if change.To.Name == "" || change.To.TreeEntry.Mode != filemode.Regular && change.To.TreeEntry.Mode != filemode.Executable {
+			continue
+		}
+		files = append(files, change.To.Name)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// changedFilesFromWorktree returns the regular files in the working tree that differ from the compared tree,
+// the files are compared by the hashes of the blobs.
+func (g *gitClient) changedFilesFromWorktree(comparedTree *gogitobj.Tree) ([]string, error) {
+	headTree, err := g.revisionTree(HEADTarget)
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := g.repository.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("get worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("get worktree status: %w", err)
+	}
+
+	candidates := make(map[string]bool)
+	err = headTree.Files().ForEach(func(f *gogitobj.File) error {
+		candidates[f.Name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	for path, s := range status {
+		if s.Worktree == gogit.Untracked || s.Staging == gogit.Added {
+			candidates[path] = true
+		}
+	}
+
+	var files []string
+	for _, path := range sortedKeys(candidates) {
+		info, err := os.Lstat(filepath.Join(g.repositoryPath, path))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		comparedFile, err := comparedTree.File(path)
+		if errors.Is(err, gogitobj.ErrFileNotFound) {
+			files = append(files, path)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", path, err)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(g.repositoryPath, path))
+		if err != nil {
+			return nil, err
+		}
+		if plumbing.ComputeHash(plumbing.BlobObject, data) != comparedFile.Hash {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// revisionTree returns the tree object of the revision.
+func (g *gitClient) revisionTree(revision string) (*gogitobj.Tree, error) {
+	hash, err := g.resolveRevision(revision)
+	if err != nil {
+		return nil, fmt.Errorf("get %s %w", revision, err)
+	}
+	commit, err := g.repository.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("get %s commit %w", revision, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("get %s tree object %w", revision, err)
+	}
+	return tree, nil
+}
+
+// resolveRevision resolves the revision to a commit hash, stash@{n} is resolved by the stash reflog.
+func (g *gitClient) resolveRevision(revision string) (*plumbing.Hash, error) {
+	m := stashPattern.FindStringSubmatch(revision)
+	if m == nil {
+		return g.repository.ResolveRevision(plumbing.Revision(revision))
+	}
+
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStashNotFound, revision)
+	}
+
+	f, err := os.Open(filepath.Join(gitCommonDir(g.repositoryPath), "logs", "refs", "stash"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStashNotFound, revision)
+	}
+	defer f.Close()
+
+	// each line of the reflog is "{old hash} {new hash} {committer} {timestamp} {timezone}\t{message}",
+	// the latest stash is the last line.
+	var hashes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 {
+			hashes = append(hashes, fields[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stash reflog: %w", err)
+	}
+	if n >= len(hashes) {
+		return nil, fmt.Errorf("%w: %s", ErrStashNotFound, revision)
+	}
+
+	hash := plumbing.NewHash(hashes[len(hashes)-1-n])
+	return &hash, nil
+}
+
+// gitCommonDir returns the git directory that shared by all the worktrees of the repository.
+// For the main worktree it's the .git directory, for the linked worktree, .git is a file
+// contains "gitdir: {path}", and the commondir file of that path points to the common directory.
+func gitCommonDir(repositoryPath string) string {
+	dotGit := filepath.Join(repositoryPath, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil || info.IsDir() {
+		return dotGit
+	}
+
+	data, err := ioutil.ReadFile(dotGit)
+	if err != nil {
+		return dotGit
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repositoryPath, gitDir)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	commonDir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return commonDir
+}
+
+// textChunk implements diff.Chunk for the line diffs of the working tree files.
+type textChunk struct {
+	content   string
+	operation diff.Operation
+}
+
+func (c *textChunk) Content() string      { return c.content }
+func (c *textChunk) Type() diff.Operation { return c.operation }
+
+// toChunks converts the line diffs to chunks.
+func toChunks(diffs []diffmatchpatch.Diff) []diff.Chunk {
+	var chunks []diff.Chunk
+	for _, d := range diffs {
+		operation := diff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			operation = diff.Add
+		case diffmatchpatch.DiffDelete:
+			operation = diff.Delete
+		}
+		chunks = append(chunks, &textChunk{content: d.Text, operation: operation})
+	}
+	return chunks
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,247 @@
+package gittool
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+func TestDiffChangesTarget(t *testing.T) {
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	base := commitFile(repo, path, "foo.go", "package foo\n\nfunc Foo() {}\n")
+
+	g := &gitClient{repositoryPath: path, repository: repo}
+
+	t.Run("worktree target includes uncommitted and untracked changes", func(t *testing.T) {
+		writeFile(path, "foo.go", "package foo\n\nfunc Foo() {}\n\nfunc Bar() {}\n")
+		writeFile(path, "bar.go", "package foo\n")
+		writeFile(path, "bar_test.go", "package foo\n")
+		defer os.Remove(filepath.Join(path, "bar.go"))
+		defer os.Remove(filepath.Join(path, "bar_test.go"))
+
+		changes, err := g.DiffChanges(base.String(), WorktreeTarget)
+		if err != nil {
+			t.Fatalf("should not return error, but get: %s", err)
+		}
+		if len(changes) != 2 {
+			t.Fatalf("expect 2 changes, but get %d", len(changes))
+		}
+
+		if changes[0].FileName != "bar.go" || changes[0].Mode != NewMode {
+			t.Errorf("expect new file bar.go, but get %s %v", changes[0].FileName, changes[0].Mode)
+		}
+		if changes[1].FileName != "foo.go" || changes[1].Mode != ModifyMode {
+			t.Errorf("expect modified file foo.go, but get %s %v", changes[1].FileName, changes[1].Mode)
+		}
+		if len(changes[1].Sections) != 1 || changes[1].Sections[0].StartLine != 4 || changes[1].Sections[0].EndLine != 5 {
+			t.Errorf("expect section of line 4 to 5, but get %v", changes[1].Sections)
+		}
+	})
+
+	t.Run("worktree target without changes", func(t *testing.T) {
+		writeFile(path, "foo.go", "package foo\n\nfunc Foo() {}\n")
+
+		changes, err := g.DiffChanges(base.String(), WorktreeTarget)
+		if err != nil {
+			t.Fatalf("should not return error, but get: %s", err)
+		}
+		if len(changes) != 0 {
+			t.Errorf("expect no changes, but get %d", len(changes))
+		}
+	})
+
+	t.Run("revision target reads contents from the commit", func(t *testing.T) {
+		target := commitFile(repo, path, "baz.go", "package foo\n\nfunc Baz() {}\n")
+		// the working tree contents should not be used.
+		writeFile(path, "baz.go", "package foo\n")
+
+		changes, err := g.DiffChanges(base.String(), target.String())
+		if err != nil {
+			t.Fatalf("should not return error, but get: %s", err)
+		}
+		if len(changes) != 1 {
+			t.Fatalf("expect 1 change, but get %d", len(changes))
+		}
+		if changes[0].FileName != "baz.go" || changes[0].Mode != NewMode {
+			t.Errorf("expect new file baz.go, but get %s %v", changes[0].FileName, changes[0].Mode)
+		}
+		if len(changes[0].Sections) != 1 || changes[0].Sections[0].Count != 3 {
+			t.Errorf("expect one section of 3 lines, but get %v", changes[0].Sections)
+		}
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		_, err := g.DiffChanges("foo", "bar")
+		if err == nil {
+			t.Error("should return error")
+		}
+	})
+}
+
+func TestChangedFiles(t *testing.T) {
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	base := commitFile(repo, path, "query.sql", "SELECT 1;\n")
+	commitFile(repo, path, "page.tmpl", "{{ .Name }}\n")
+	head := commitFile(repo, path, "query.sql", "SELECT 2;\n")
+
+	g := &gitClient{repositoryPath: path, repository: repo}
+
+	t.Run("HEAD target includes the files of any type", func(t *testing.T) {
+		files, err := g.ChangedFiles(base.String(), HEADTarget)
+		if err != nil {
+			t.Fatalf("should not return error, but get: %s", err)
+		}
+		if fmt.Sprint(files) != "[page.tmpl query.sql]" {
+			t.Errorf("expect [page.tmpl query.sql], but get %v", files)
+		}
+	})
+
+	t.Run("worktree target includes untracked files", func(t *testing.T) {
+		writeFile(path, "schema.sql", "CREATE TABLE foo;\n")
+		defer os.Remove(filepath.Join(path, "schema.sql"))
+
+		files, err := g.ChangedFiles(head.String(), WorktreeTarget)
+		if err != nil {
+			t.Fatalf("should not return error, but get: %s", err)
+		}
+		if fmt.Sprint(files) != "[schema.sql]" {
+			t.Errorf("expect [schema.sql], but get %v", files)
+		}
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		if _, err := g.ChangedFiles("foo", HEADTarget); err == nil {
+			t.Error("should return error")
+		}
+	})
+}
+
+func TestResolveRevision(t *testing.T) {
+	path, repo, clean := temporalRepository("")
+	defer clean()
+
+	g := &gitClient{repositoryPath: path, repository: repo}
+
+	t.Run("stash not found", func(t *testing.T) {
+		_, err := g.resolveRevision("stash@{0}")
+		if !errors.Is(err, ErrStashNotFound) {
+			t.Errorf("expect ErrStashNotFound, but get %v", err)
+		}
+	})
+
+	older := commitFile(repo, path, "foo.go", "package foo\n")
+	newer := commitFile(repo, path, "bar.go", "package foo\n")
+	reflog := fmt.Sprintf("%s %s foo <foo@bar.org> 1660000000 +0000\tWIP on master: older\n", plumbing.ZeroHash, older) +
+		fmt.Sprintf("%s %s foo <foo@bar.org> 1660000001 +0000\tWIP on master: newer\n", older, newer)
+	checkError(os.MkdirAll(filepath.Join(path, ".git", "logs", "refs"), 0755))
+	writeFile(path, filepath.Join(".git", "logs", "refs", "stash"), reflog)
+
+	testSuites := []struct {
+		revision string
+		expected plumbing.Hash
+	}{
+		{revision: "stash@{0}", expected: newer},
+		{revision: "refs/stash@{1}", expected: older},
+		{revision: "HEAD", expected: newer},
+	}
+	for _, testSuite := range testSuites {
+		hash, err := g.resolveRevision(testSuite.revision)
+		if err != nil {
+			t.Errorf("resolve %s: %s", testSuite.revision, err)
+			continue
+		}
+		if *hash != testSuite.expected {
+			t.Errorf("expect %s resolved to %s, but get %s", testSuite.revision, testSuite.expected, hash)
+		}
+	}
+
+	_, err := g.resolveRevision("stash@{2}")
+	if !errors.Is(err, ErrStashNotFound) {
+		t.Errorf("expect ErrStashNotFound, but get %v", err)
+	}
+}
+
+func TestGitCommonDir(t *testing.T) {
+	t.Run("main worktree", func(t *testing.T) {
+		path, clean := temporalDir()
+		defer clean()
+		checkError(os.Mkdir(filepath.Join(path, ".git"), 0755))
+
+		if dir := gitCommonDir(path); dir != filepath.Join(path, ".git") {
+			t.Errorf("expect %s, but get %s", filepath.Join(path, ".git"), dir)
+		}
+	})
+
+	t.Run("linked worktree", func(t *testing.T) {
+		path, clean := temporalDir()
+		defer clean()
+
+		gitDir := filepath.Join(path, "main", ".git", "worktrees", "linked")
+		checkError(os.MkdirAll(gitDir, 0755))
+		writeFile(gitDir, "commondir", "../..\n")
+		checkError(os.MkdirAll(filepath.Join(path, "linked"), 0755))
+		writeFile(filepath.Join(path, "linked"), ".git", "gitdir: "+gitDir+"\n")
+
+		expected := filepath.Join(path, "main", ".git")
+		if dir := gitCommonDir(filepath.Join(path, "linked")); dir != expected {
+			t.Errorf("expect %s, but get %s", expected, dir)
+		}
+	})
+}
+
+func TestToChunks(t *testing.T) {
+	chunks := toChunks([]diffmatchpatch.Diff{
+		{Type: diffmatchpatch.DiffEqual, Text: "a\n"},
+		{Type: diffmatchpatch.DiffInsert, Text: "b\n"},
+		{Type: diffmatchpatch.DiffDelete, Text: "c\n"},
+	})
+
+	expected := []diff.Operation{diff.Equal, diff.Add, diff.Delete}
+	if len(chunks) != len(expected) {
+		t.Fatalf("expect %d chunks, but get %d", len(expected), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Type() != expected[i] {
+			t.Errorf("expect chunk %d is %v, but get %v", i, expected[i], chunk.Type())
+		}
+	}
+	if chunks[1].Content() != "b\n" {
+		t.Errorf("expect content b, but get %q", chunks[1].Content())
+	}
+}
+
+// commitFile writes the file and commits it, returns the hash of the commit.
+func commitFile(repo *gogit.Repository, path, filename, contents string) plumbing.Hash {
+	writeFile(path, filename, contents)
+
+	worktree, err := repo.Worktree()
+	checkError(err)
+	_, err = worktree.Add(filename)
+	checkError(err)
+
+	hash, err := worktree.Commit("add "+filename, &gogit.CommitOptions{
+		Author: &object.Signature{
+			Name:  "foo",
+			Email: "foo@bar.org",
+			When:  time.Now(),
+		},
+	})
+	checkError(err)
+	return hash
+}
+
+func writeFile(path, filename, contents string) {
+	checkError(ioutil.WriteFile(filepath.Join(path, filename), []byte(contents), 0644))
+}
@@ -57,4 +57,8 @@ type Change struct {
 	// For ModifyMode it contains the each change sections made to compared branch
 	// For DeleteMode it's empty
 	Sections []*Section
+	// Deletions are the sections deleted from the compared branch of a modified file, their StartLine is the line
+	// of the file that the deleted lines were before, which is the line after the end of the file if they're deleted at the end,
+	// and their EndLine equals the StartLine. They are kept apart from Sections, which are always in the file.
+	Deletions []*Section
 }
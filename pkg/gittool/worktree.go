@@ -0,0 +1,126 @@
+package gittool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrCherryPickConflict = errors.New("cherry-pick conflicts")
+	ErrRevertConflict     = errors.New("revert conflicts")
+)
+
+// committer is the committer of the cherry-picked commits and the author of the notes, the authors of the cherry-picked commits are kept.
+const committer = "gocover"
+
+// Worktree is a linked worktree of a repository that is detached from the branches,
+// so the commits can be cherry-picked onto another branch without changing it.
+// The worktrees are created and changed by the git command, since go-git doesn't support them.
+type Worktree struct {
+	// Path is the directory of the worktree.
+	Path           string
+	repositoryPath string
+}
+
+// AddWorktree checks out the revision of the repository into a new linked worktree at the path.
+func AddWorktree(ctx context.Context, repositoryPath, path, revision string) (*Worktree, error) {
+	if _, err := runGit(ctx, repositoryPath, "worktree", "add", "--detach", path, revision); err != nil {
+		return nil, fmt.Errorf("add worktree of %s: %w", revision, err)
+	}
+	return &Worktree{Path: path, repositoryPath: repositoryPath}, nil
+}
+
+// Head returns the commit sha of HEAD of the worktree.
+func (w *Worktree) Head(ctx context.Context) (string, error) {
+	out, err := runGit(ctx, w.Path, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CherryPick applies the commits onto HEAD of the worktree, a commit can be a range such as main..feature.
+// ErrCherryPickConflict is returned if a commit conflicts, and the worktree is restored to HEAD.
+func (w *Worktree) CherryPick(ctx context.Context, commits ...string) error {
+	args := append([]string{"cherry-pick", "--allow-empty", "--keep-redundant-commits"}, commits...)
+	if _, err := runGit(ctx, w.Path, args...); err != nil {
+		if _, abortErr := runGit(ctx, w.Path, "cherry-pick", "--abort"); abortErr != nil {
+			return fmt.Errorf("cherry-pick %s: %w", strings.Join(commits, " "), err)
+		}
+		return fmt.Errorf("%w: %s", ErrCherryPickConflict, err)
+	}
+	return nil
+}
+
+// Revert commits the revert of the commit onto HEAD of the worktree.
+// ErrRevertConflict is returned if the revert conflicts, and the worktree is restored to HEAD.
+func (w *Worktree) Revert(ctx context.Context, commit string) error {
+	if _, err := runGit(ctx, w.Path, "revert", "--no-edit", commit); err != nil {
+		if _, abortErr := runGit(ctx, w.Path, "revert", "--abort"); abortErr != nil {
+			return fmt.Errorf("revert %s: %w", commit, err)
+		}
+		return fmt.Errorf("%w: %s", ErrRevertConflict, err)
+	}
+	return nil
+}
+
+// Remove removes the worktree and its directory.
+func (w *Worktree) Remove(ctx context.Context) error {
+	if _, err := runGit(ctx, w.repositoryPath, "worktree", "remove", "--force", w.Path); err != nil {
+		return fmt.Errorf("remove worktree %s: %w", w.Path, err)
+	}
+	return nil
+}
+
+// ResolveCommit resolves the revision of the repository to the commit sha.
+func ResolveCommit(ctx context.Context, repositoryPath, revision string) (string, error) {
+	out, err := runGit(ctx, repositoryPath, "rev-parse", "--verify", revision+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", revision, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CurrentBranch returns the branch checked out in the repository, it's empty if HEAD is detached, such as in the CI builds of the tags.
+func CurrentBranch(ctx context.Context, repositoryPath string) (string, error) {
+	out, err := runGit(ctx, repositoryPath, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("resolve branch: %w", err)
+	}
+	branch := strings.TrimSpace(out)
+	if branch == "HEAD" {
+		return "", nil
+	}
+	return branch, nil
+}
+
+// runGit runs the git command in the directory and returns the stdout, the error contains the stderr.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGitWithInput(ctx, dir, nil, args...)
+}
+
+// runGitWithInput runs the git command with the stdin in the directory.
+func runGitWithInput(ctx context.Context, dir string, stdin io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	cmd.Env = append(os.Environ(),
+		"GIT_COMMITTER_NAME="+committer, "GIT_COMMITTER_EMAIL="+committer+"@localhost",
+		"GIT_AUTHOR_NAME="+committer, "GIT_AUTHOR_EMAIL="+committer+"@localhost")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,119 @@
+package gittool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestWorktreeCherryPick(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	ctx := context.Background()
+
+	base := commitFile(repo, path, "foo.go", "package foo\n")
+	worktree, err := repo.Worktree()
+	checkError(err)
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	feature := commitFile(repo, path, "bar.go", "package foo\n\nfunc Bar() {}\n")
+	conflict := commitFile(repo, path, "foo.go", "package foo\n\nfunc Foo() {}\n")
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Hash: base, Force: true}))
+	release := commitFile(repo, path, "foo.go", "package foo\n\nfunc Release() {}\n")
+
+	w, err := AddWorktree(ctx, path, filepath.Join(t.TempDir(), "release"), release.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CherryPick(ctx, feature.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(w.Path, "bar.go")); err != nil {
+		t.Errorf("expect the commit is cherry-picked, but get %v", err)
+	}
+	picked, err := w.Head(ctx)
+	if err != nil || picked == release.String() {
+		t.Errorf("expect a new HEAD, but get %s, %v", picked, err)
+	}
+
+	if err := w.CherryPick(ctx, conflict.String()); !errors.Is(err, ErrCherryPickConflict) {
+		t.Errorf("expect ErrCherryPickConflict, but get %v", err)
+	}
+	if head, err := w.Head(ctx); err != nil || head != picked {
+		t.Errorf("expect the conflicted cherry-pick is aborted, but get %s, %v", head, err)
+	}
+
+	if err := w.Remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(w.Path); !os.IsNotExist(err) {
+		t.Errorf("expect the worktree is removed, but get %v", err)
+	}
+}
+
+func TestWorktreeRevert(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	ctx := context.Background()
+
+	commitFile(repo, path, "foo.go", "package foo\n")
+	feature := commitFile(repo, path, "bar.go", "package foo\n\nfunc Bar() {}\n")
+	changed := commitFile(repo, path, "foo.go", "package foo\n\nfunc Foo() {}\n")
+	head := commitFile(repo, path, "foo.go", "package foo\n\nfunc Foo() { panic(1) }\n")
+
+	w, err := AddWorktree(ctx, path, filepath.Join(t.TempDir(), "revert"), head.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Remove(ctx)
+	if err := w.Revert(ctx, feature.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(w.Path, "bar.go")); !os.IsNotExist(err) {
+		t.Errorf("expect bar.go is removed by the revert, but get %v", err)
+	}
+	reverted, err := w.Head(ctx)
+	if err != nil || reverted == head.String() {
+		t.Errorf("expect a new HEAD, but get %s, %v", reverted, err)
+	}
+
+	if err := w.Revert(ctx, changed.String()); !errors.Is(err, ErrRevertConflict) {
+		t.Errorf("expect ErrRevertConflict, but get %v", err)
+	}
+	if h, err := w.Head(ctx); err != nil || h != reverted {
+		t.Errorf("expect the conflicted revert is aborted, but get %s, %v", h, err)
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	path, repo, clean := temporalRepository("")
+	defer clean()
+	ctx := context.Background()
+
+	base := commitFile(repo, path, "foo.go", "package foo\n")
+	worktree, err := repo.Worktree()
+	checkError(err)
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	if branch, err := CurrentBranch(ctx, path); err != nil || branch != "feature" {
+		t.Errorf("expect branch feature, but get %s, %v", branch, err)
+	}
+
+	checkError(worktree.Checkout(&gogit.CheckoutOptions{Hash: base}))
+	if branch, err := CurrentBranch(ctx, path); err != nil || branch != "" {
+		t.Errorf("expect no branch of the detached HEAD, but get %s, %v", branch, err)
+	}
+}
@@ -0,0 +1,139 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// The exit codes of a `git bisect run` script.
+const (
+	// BisectBadExitCode marks the commit as bad, its diff coverage is lower than the coverage baseline.
+	BisectBadExitCode = 1
+	// BisectSkipExitCode skips the commit, its diff coverage can't be calculated, such as the code doesn't build.
+	BisectSkipExitCode = 125
+)
+
+// ErrBisectBad marks the error of a bad commit, so that its exit code is kept for git bisect.
+var ErrBisectBad = errors.New("bad commit")
+
+// DefaultBisectCompareBranch compares each commit with its first parent.
+const DefaultBisectCompareBranch = "HEAD~1"
+
+// BisectOption contains the input to the gocover bisect command.
+type BisectOption struct {
+	// CompareBranch is the revision that the commit checked out by git bisect is compared with,
+	// such as HEAD~1 for the changes of the commit, or the good commit for all the changes since it.
+	CompareBranch  string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath       string
+	GoFlags          []string
+	CoverageBaseline float64
+	ReportFormat     string
+	ReportName       string
+	// OutputDir is the directory that the report of the commit is written into,
+	// the report is written into a temporary directory if it's empty.
+	OutputDir string
+	Excludes  []string
+	Style     string
+	Limits    Limits
+
+	StdOut io.Writer
+	StdErr io.Writer
+	Logger logrus.FieldLogger
+}
+
+// NewBisectOption returns a Bisect Option with default values.
+func NewBisectOption() *BisectOption {
+	return &BisectOption{
+		CompareBranch:    DefaultBisectCompareBranch,
+		CoverageBaseline: DefaultCoverageBaseline,
+		ReportFormat:     DefaultReportFormat,
+		Limits:           DefaultLimits(),
+	}
+}
+
+// BisectResult is the diff coverage of the commit checked out by git bisect.
+type BisectResult struct {
+	// Commit is the commit checked out, it's HEAD of the repository.
+	Commit string
+	// Statistics is the diff coverage of the commit against the compare branch.
+	Statistics *report.Statistics
+}
+
+// Passed reports whether the diff coverage of the commit passes the coverage gate, by the decision of the gate in the statistics.
+func (r *BisectResult) Passed() bool {
+	return r.Statistics != nil && r.Statistics.Gate != nil && r.Statistics.Gate.Passed
+}
+
+// RunBisect runs the tests with coverage on HEAD of the repository and returns its diff coverage against the compare branch,
+// it's supposed to run by `git bisect run`, which checks out the commit to test in the working tree.
+// The failed tests don't fail the run, since git bisect is looking for the commit that drops the coverage.
+func RunBisect(ctx context.Context, o *BisectOption) (*BisectResult, error) {
+	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of repo: %w", err)
+	}
+	commit, err := gittool.ResolveCommit(ctx, repositoryAbsPath, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir := o.OutputDir
+	if outputDir == "" {
+		workDir, err := createGoCoverTempDirectory()
+		if err != nil {
+			return nil, fmt.Errorf("create gocover temp directory: %w", err)
+		}
+		defer os.RemoveAll(workDir)
+		outputDir = workDir
+	}
+
+	logger := o.Logger.WithField("source", "bisect").WithField("commit", commit)
+	option := &GoCoverTestOption{
+		CompareBranch:    o.CompareBranch,
+		RepositoryPath:   repositoryAbsPath,
+		ModuleDir:        o.ModuleDir,
+		ModulePath:       o.ModulePath,
+		CoverageMode:     DiffCoverage,
+		ExecutorMode:     GoExecutor,
+		GoFlags:          o.GoFlags,
+		CoverageBaseline: o.CoverageBaseline,
+		ReportFormat:     o.ReportFormat,
+		ReportName:       o.ReportName,
+		OutputDir:        outputDir,
+		Excludes:         o.Excludes,
+		Style:            o.Style,
+		FailedTestPolicy: WarnOnFailedTests,
+		Limits:           o.Limits,
+		GitAttributes:    true,
+		DbOption:         &dbclient.DBOption{},
+		StdOut:           o.StdOut,
+		StdErr:           o.StdErr,
+		Logger:           logger,
+	}
+	// the packages of the cover profile are resolved from the working directory, so it's changed to the module.
+	var statistics *report.Statistics
+	err = inDirectory(filepath.Join(repositoryAbsPath, o.ModuleDir), logger, func() error {
+		statistics, err = runDiffCoverage(ctx, option)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if statistics == nil {
+		return nil, fmt.Errorf("no diff coverage of %s", commit)
+	}
+	decideGate(statistics, o.CoverageBaseline)
+	return &BisectResult{Commit: commit, Statistics: statistics}, nil
+}
@@ -0,0 +1,78 @@
+package gocover
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRunBisect(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	commitFiles(t, dir, "init", map[string]string{
+		"go.mod":      "module example.com/foo\n\ngo 1.20\n",
+		"foo.go":      "package foo\n\nfunc Foo() int {\n\treturn 1\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo()\n}\n",
+	})
+	good := git(t, dir, "rev-parse", "HEAD")
+	commitFiles(t, dir, "uncovered", map[string]string{
+		"bar.go": "package foo\n\nfunc Bar(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn -a\n}\n",
+	})
+	bad := git(t, dir, "rev-parse", "HEAD")
+
+	o := NewBisectOption()
+	o.RepositoryPath = dir
+	o.ModuleDir = "./"
+	o.ReportName = "coverage"
+	o.StdOut, o.StdErr = &bytes.Buffer{}, &bytes.Buffer{}
+	o.Logger = logrus.New()
+
+	result, err := RunBisect(context.Background(), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commit != bad || result.Statistics.TotalEffectiveLines != 3 || result.Passed() {
+		t.Errorf("expect the uncovered commit fails, but get %s %+v", result.Commit, result.Statistics)
+	}
+
+	git(t, dir, "checkout", "-q", good)
+	o.CompareBranch = good
+	result, err = RunBisect(context.Background(), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commit != good || !result.Passed() {
+		t.Errorf("expect the commit without changes passes, but get %s %+v", result.Commit, result.Statistics)
+	}
+
+	o.CompareBranch = "missing"
+	if _, err := RunBisect(context.Background(), o); err == nil {
+		t.Error("expect an error of the unknown compare branch")
+	}
+}
+
+func TestBisectResultPassed(t *testing.T) {
+	statistics := &report.Statistics{TotalCoveragePercent: 90}
+	result := &BisectResult{Commit: "abc", Statistics: statistics}
+	if result.Passed() {
+		t.Error("expect the result without a gate decision fails")
+	}
+
+	decideGate(statistics, 80)
+	if !result.Passed() {
+		t.Errorf("expect the coverage 90 passes the baseline 80, but get %+v", statistics.Gate)
+	}
+
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, Message: "the coverage of file bar.go is 50.00, lower than the file baseline 60.00"}}}
+	if result.Passed() {
+		t.Error("expect the failed file rule fails the commit, even if the total coverage passes the baseline")
+	}
+}
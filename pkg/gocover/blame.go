@@ -0,0 +1,44 @@
+package gocover
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// gitBlamer finds the authors of the lines by the git blame of HEAD.
+type gitBlamer struct {
+	blamer     gittool.Blamer
+	modulePath string
+	moduleDir  string
+}
+
+var _ report.Blamer = (*gitBlamer)(nil)
+
+// newGitBlamer creates the blamer of the repository that contains the module directory,
+// it returns nil if the module is not in a git repository, so the authors are left empty.
+func newGitBlamer(modulePath, moduleDir string, logger logrus.FieldLogger) report.Blamer {
+	blamer, err := gittool.NewBlamer(moduleDir)
+	if err != nil {
+		logger.WithError(err).Warnf("open git repository of %s, the authors of the lines are empty", moduleDir)
+		return nil
+	}
+	return &gitBlamer{blamer: blamer, modulePath: modulePath, moduleDir: moduleDir}
+}
+
+// Blame returns the author and the commit of each line of the file in HEAD.
+func (b *gitBlamer) Blame(fileName string) (map[int]*report.LineAuthor, error) {
+	relative := strings.TrimPrefix(strings.TrimPrefix(fileName, b.modulePath), "/")
+	lines, err := b.blamer.Blame(filepath.Join(b.moduleDir, filepath.FromSlash(relative)))
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[int]*report.LineAuthor, len(lines))
+	for i, line := range lines {
+		authors[i+1] = &report.LineAuthor{Author: line.Author, Commit: line.Commit}
+	}
+	return authors, nil
+}
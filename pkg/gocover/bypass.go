@@ -0,0 +1,212 @@
+package gocover
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/chatops"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SkipCommand is the comment command that bypasses the coverage gate, `/gocover skip reason=...`.
+	SkipCommand = "skip"
+	// skipReasonParam is the required parameter of the skip command.
+	skipReasonParam = "reason"
+)
+
+// bypassAssociations are the GitHub author associations of the commenters who may bypass the gate.
+var bypassAssociations = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+// BypassOption describes how a failing coverage gate can be bypassed.
+// The bypass downgrades the failing gate to neutral and it's recorded in the audit log and the report.
+type BypassOption struct {
+	// Label is the SCM label that bypasses the coverage gate, label bypass is disabled if it's empty.
+	Label string
+	// Labels are the labels of the pull request.
+	Labels []string
+	// Comments are the comments of the pull request that may contain the skip command,
+	// in the format of `login:comment` so the skip command is only accepted from the approvers.
+	Comments []string
+	// EventPath is the GitHub event payload file, labels and comment of the pull request are read from it.
+	EventPath string
+	// Approvers are the logins whose skip command is accepted, besides the owners, members and collaborators
+	// of the repository in the GitHub event.
+	Approvers []string
+	// Author is the login of the pull request author, whose skip command is never accepted.
+	// It's read from the GitHub event if it's empty.
+	Author string
+}
+
+// bypassComment is a comment of the pull request with its commenter.
+type bypassComment struct {
+	body  string
+	actor string
+	// association is the GitHub author association of the commenter, empty if unknown.
+	association string
+}
+
+// githubEvent contains the fields of GitHub pull_request and issue_comment event payloads that bypass cares about.
+type githubEvent struct {
+	PullRequest *struct {
+		Labels []githubLabel `json:"labels"`
+		User   githubUser    `json:"user"`
+	} `json:"pull_request"`
+	Issue *struct {
+		Labels []githubLabel `json:"labels"`
+		User   githubUser    `json:"user"`
+	} `json:"issue"`
+	Comment *struct {
+		Body              string     `json:"body"`
+		User              githubUser `json:"user"`
+		AuthorAssociation string     `json:"author_association"`
+	} `json:"comment"`
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubLabel struct {
+	Name string `json:"name"`
+}
+
+// findBypass finds the bypass from labels and comments, it returns nil if no bypass found.
+func findBypass(o *BypassOption, logger logrus.FieldLogger) (*report.Bypass, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	labels := o.Labels
+	author := o.Author
+	var comments []*bypassComment
+	for _, c := range o.Comments {
+		actor, body, _ := strings.Cut(c, ":")
+		if actor = strings.TrimSpace(actor); actor == "" || strings.ContainsAny(actor, " \t\n") {
+			logger.Warnf("ignore the comment without its commenter, the format is login:comment: %q", c)
+			continue
+		}
+		comments = append(comments, &bypassComment{body: body, actor: actor})
+	}
+	if o.EventPath != "" {
+		event, err := loadGitHubEvent(o.EventPath)
+		if err != nil {
+			return nil, fmt.Errorf("load github event: %w", err)
+		}
+		labels = append(labels, event.labels()...)
+		comments = append(comments, event.comments()...)
+		if author == "" {
+			author = event.author()
+		}
+	}
+
+	if o.Label != "" {
+		for _, label := range labels {
+			if strings.EqualFold(strings.TrimSpace(label), o.Label) {
+				return &report.Bypass{
+					Source: fmt.Sprintf("label %s", o.Label),
+					Reason: fmt.Sprintf("pull request is labeled with %s", o.Label),
+				}, nil
+			}
+		}
+	}
+
+	for _, comment := range comments {
+		for _, command := range chatops.Parse(comment.body) {
+			if command.Name != SkipCommand {
+				continue
+			}
+			if !o.authorized(comment, author) {
+				logger.Warnf("ignore '%s' of %s, who is not allowed to bypass the coverage gate", command.Raw, comment.actor)
+				continue
+			}
+			reason := command.Params[skipReasonParam]
+			if reason == "" {
+				logger.Warnf("ignore '%s', %s is required for skip command", command.Raw, skipReasonParam)
+				continue
+			}
+			return &report.Bypass{
+				Source: fmt.Sprintf("comment command '%s %s'", chatops.Prefix, SkipCommand),
+				Reason: reason,
+				Actor:  comment.actor,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// authorized returns whether the commenter may bypass the gate, the commenter is an approver,
+// or an owner, member or collaborator of the repository, and never the author of the pull request.
+func (o *BypassOption) authorized(comment *bypassComment, author string) bool {
+	if comment.actor == "" || (author != "" && strings.EqualFold(comment.actor, author)) {
+		return false
+	}
+	for _, approver := range o.Approvers {
+		if strings.EqualFold(comment.actor, approver) {
+			return true
+		}
+	}
+	for _, association := range bypassAssociations {
+		if strings.EqualFold(comment.association, association) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGitHubEvent reads the GitHub event payload.
+func loadGitHubEvent(eventPath string) (*githubEvent, error) {
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &githubEvent{}
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// labels returns the labels of the pull request of the event.
+func (event *githubEvent) labels() []string {
+	var labels []string
+	if event.PullRequest != nil {
+		for _, l := range event.PullRequest.Labels {
+			labels = append(labels, l.Name)
+		}
+	}
+	if event.Issue != nil {
+		for _, l := range event.Issue.Labels {
+			labels = append(labels, l.Name)
+		}
+	}
+	return labels
+}
+
+// comments returns the comment of the event with its commenter.
+func (event *githubEvent) comments() []*bypassComment {
+	if event.Comment == nil {
+		return nil
+	}
+	return []*bypassComment{{
+		body:        event.Comment.Body,
+		actor:       event.Comment.User.Login,
+		association: event.Comment.AuthorAssociation,
+	}}
+}
+
+// author returns the login of the pull request author of the event.
+func (event *githubEvent) author() string {
+	if event.PullRequest != nil && event.PullRequest.User.Login != "" {
+		return event.PullRequest.User.Login
+	}
+	if event.Issue != nil {
+		return event.Issue.User.Login
+	}
+	return ""
+}
@@ -0,0 +1,193 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFindBypass(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("no bypass option", func(t *testing.T) {
+		bypass, err := findBypass(nil, logger)
+		if err != nil || bypass != nil {
+			t.Errorf("expect no bypass, but get %+v, %v", bypass, err)
+		}
+	})
+
+	t.Run("bypass by label", func(t *testing.T) {
+		bypass, err := findBypass(&BypassOption{Label: "gocover-skip", Labels: []string{"bug", "GoCover-Skip"}}, logger)
+		if err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if bypass == nil || bypass.Source != "label gocover-skip" {
+			t.Errorf("expect bypass by label, but get %+v", bypass)
+		}
+	})
+
+	t.Run("label bypass disabled", func(t *testing.T) {
+		bypass, _ := findBypass(&BypassOption{Labels: []string{"gocover-skip"}}, logger)
+		if bypass != nil {
+			t.Errorf("expect no bypass, but get %+v", bypass)
+		}
+	})
+
+	t.Run("bypass by comment command", func(t *testing.T) {
+		bypass, err := findBypass(&BypassOption{Approvers: []string{"alice"}, Comments: []string{
+			"alice:/gocover skip",
+			"/gocover skip reason=no commenter",
+			"Alice:LGTM\n/gocover skip reason=generated client",
+		}}, logger)
+		if err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if bypass == nil || bypass.Reason != "generated client" || bypass.Actor != "Alice" {
+			t.Errorf("expect bypass by comment, but get %+v", bypass)
+		}
+	})
+
+	t.Run("comment command of unauthorized user", func(t *testing.T) {
+		bypass, err := findBypass(&BypassOption{Approvers: []string{"alice"}, Author: "bob", Comments: []string{
+			"mallory:/gocover skip reason=trust me",
+			"bob:/gocover skip reason=my own pull request",
+		}}, logger)
+		if err != nil || bypass != nil {
+			t.Errorf("expect the skip commands of the unauthorized users are ignored, but get %+v, %v", bypass, err)
+		}
+	})
+
+	t.Run("comment command of pull request author", func(t *testing.T) {
+		bypass, _ := findBypass(&BypassOption{Approvers: []string{"bob"}, Author: "bob", Comments: []string{"bob:/gocover skip reason=mine"}}, logger)
+		if bypass != nil {
+			t.Errorf("expect the skip command of the author is ignored, but get %+v", bypass)
+		}
+	})
+
+	t.Run("bypass from github event", func(t *testing.T) {
+		eventPath := filepath.Join(t.TempDir(), "event.json")
+		payload := `{"issue": {"labels": [{"name": "bug"}], "user": {"login": "bob"}}, "comment": {"body": "/gocover skip reason=hotfix", "user": {"login": "octocat"}, "author_association": "MEMBER"}}`
+		if err := os.WriteFile(eventPath, []byte(payload), 0644); err != nil {
+			t.Fatalf("write event: %s", err)
+		}
+
+		bypass, err := findBypass(&BypassOption{Label: "gocover-skip", EventPath: eventPath}, logger)
+		if err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if bypass == nil || bypass.Reason != "hotfix" || bypass.Actor != "octocat" {
+			t.Errorf("expect bypass from event, but get %+v", bypass)
+		}
+	})
+
+	t.Run("github event of unauthorized commenter", func(t *testing.T) {
+		payloads := map[string]string{
+			"contributor": `{"issue": {"user": {"login": "bob"}}, "comment": {"body": "/gocover skip reason=hotfix", "user": {"login": "mallory"}, "author_association": "CONTRIBUTOR"}}`,
+			"author":      `{"issue": {"user": {"login": "bob"}}, "comment": {"body": "/gocover skip reason=hotfix", "user": {"login": "bob"}, "author_association": "OWNER"}}`,
+		}
+		for name, payload := range payloads {
+			eventPath := filepath.Join(t.TempDir(), "event.json")
+			if err := os.WriteFile(eventPath, []byte(payload), 0644); err != nil {
+				t.Fatalf("write event: %s", err)
+			}
+			bypass, err := findBypass(&BypassOption{EventPath: eventPath}, logger)
+			if err != nil || bypass != nil {
+				t.Errorf("%s: expect the skip command is ignored, but get %+v, %v", name, bypass, err)
+			}
+		}
+	})
+
+	t.Run("wrong github event", func(t *testing.T) {
+		_, err := findBypass(&BypassOption{EventPath: filepath.Join(t.TempDir(), "nonexist.json")}, logger)
+		if err == nil {
+			t.Error("should return error, but get nil")
+		}
+	})
+}
+
+func TestCheckBypass(t *testing.T) {
+	newDiffCover := func(o *BypassOption) *diffCover {
+		return &diffCover{
+			coverageBaseline: 80,
+			bypassOption:     o,
+			auditRecorder:    audit.NewRecorder(""),
+			logger:           logrus.New(),
+		}
+	}
+
+	t.Run("coverage passes the baseline", func(t *testing.T) {
+		diff := newDiffCover(&BypassOption{Approvers: []string{"alice"}, Comments: []string{"alice:/gocover skip reason=legacy"}})
+		statistics := &report.Statistics{TotalCoveragePercent: 90}
+		if err := diff.checkBypass(context.Background(), statistics); err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if statistics.Bypass != nil {
+			t.Error("passed gate should not be bypassed")
+		}
+	})
+
+	t.Run("failed gate is bypassed", func(t *testing.T) {
+		diff := newDiffCover(&BypassOption{Approvers: []string{"alice"}, Comments: []string{"alice:/gocover skip reason=legacy"}})
+		statistics := &report.Statistics{TotalCoveragePercent: 50}
+		if err := diff.checkBypass(context.Background(), statistics); err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if statistics.Bypass == nil {
+			t.Fatal("failed gate should be bypassed")
+		}
+		if err := diff.pass(statistics); err != nil {
+			t.Errorf("bypassed gate should pass, but get %s", err)
+		}
+		actions := diff.auditRecorder.Actions()
+		if len(actions) != 1 || actions[0].Type != audit.BypassAction || actions[0].Details["reason"] != "legacy" || actions[0].Details["actor"] != "alice" {
+			t.Errorf("bypass should be recorded into audit log, but get %+v", actions)
+		}
+	})
+
+	t.Run("failed gate without bypass", func(t *testing.T) {
+		diff := newDiffCover(&BypassOption{})
+		statistics := &report.Statistics{TotalCoveragePercent: 50}
+		if err := diff.checkBypass(context.Background(), statistics); err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if err := diff.pass(statistics); err == nil {
+			t.Error("failed gate should return error")
+		}
+	})
+
+	t.Run("bypass is stored", func(t *testing.T) {
+		var stored *dbclient.BypassData
+		diff := newDiffCover(&BypassOption{Approvers: []string{"alice"}, Comments: []string{"alice:/gocover skip reason=legacy"}})
+		diff.dbOption = &dbclient.DBOption{DbType: dbclient.Kusto, KustoOption: dbclient.KustoOption{BypassEvent: "bypass"}}
+		diff.dbClient = &mockDbClient{storeBypassDataFn: func(ctx context.Context, data *dbclient.BypassData) error {
+			stored = data
+			return nil
+		}}
+		if err := diff.checkBypass(context.Background(), &report.Statistics{TotalCoveragePercent: 50}); err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if stored == nil || stored.Actor != "alice" || stored.Reason != "legacy" || stored.Coverage != 50 || stored.Baseline != 80 {
+			t.Errorf("expect the bypass is stored, but get %+v", stored)
+		}
+		actions := diff.auditRecorder.Actions()
+		if len(actions) != 2 || actions[1].Type != audit.UploadAction || !actions[1].Succeeded {
+			t.Errorf("expect the upload of the bypass in audit log, but get %+v", actions)
+		}
+
+		diff = newDiffCover(&BypassOption{Approvers: []string{"alice"}, Comments: []string{"alice:/gocover skip reason=legacy"}})
+		diff.dbOption = &dbclient.DBOption{}
+		diff.dbClient = &mockDbClient{storeBypassDataFn: func(ctx context.Context, data *dbclient.BypassData) error {
+			return errors.New("unexpected error")
+		}}
+		if err := diff.checkBypass(context.Background(), &report.Statistics{TotalCoveragePercent: 50}); err == nil {
+			t.Error("expect the error of storing the bypass, but get nil")
+		}
+	})
+}
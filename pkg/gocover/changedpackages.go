@@ -0,0 +1,101 @@
+package gocover
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/gittool"
+)
+
+// moduleChangedFiles returns the files changed against the compared branch that belong to the module,
+// as the slash separated paths relative to the module directory.
+func moduleChangedFiles(repositoryPath, moduleDir, compareBranch, target string, againstTag bool) ([]string, error) {
+	gitClient, err := gittool.NewGitClient(repositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("git repository: %w", err)
+	}
+	if againstTag {
+		if compareBranch, err = latestModuleTag(gitClient, moduleDir); err != nil {
+			return nil, fmt.Errorf("find latest release tag: %w", err)
+		}
+	}
+	files, err := gitClient.ChangedFiles(compareBranch, target)
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+
+	moduleAbsDir := filepath.Join(repositoryPath, moduleDir)
+	var changed []string
+	for _, f := range files {
+		rel, err := filepath.Rel(moduleAbsDir, filepath.Join(repositoryPath, filepath.FromSlash(f)))
+		if err != nil || rel == ".." || strings.HasPrefix(filepath.ToSlash(rel), "../") {
+			continue
+		}
+		changed = append(changed, filepath.ToSlash(rel))
+	}
+	return changed, nil
+}
+
+// changedPackages returns the sorted package patterns of `go test` for the changed go files relative to the module,
+// such as . or ./foo/bar. The packages that are removed, in testdata or vendor directories, or in nested modules are skipped,
+// since `go test` can't test them from the module.
+func changedPackages(moduleDir string, changed []string) []string {
+	dirs := make(map[string]bool)
+	for _, f := range changed {
+		if strings.HasSuffix(f, ".go") {
+			dirs[path.Dir(f)] = true
+		}
+	}
+
+	var packages []string
+	for dir := range dirs {
+		if !testablePackage(moduleDir, dir) {
+			continue
+		}
+		if dir != "." {
+			dir = "./" + dir
+		}
+		packages = append(packages, dir)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// testablePackage reports whether the package directory relative to the module can be tested from the module.
+func testablePackage(moduleDir, dir string) bool {
+	if !hasGoFiles(filepath.Join(moduleDir, filepath.FromSlash(dir))) {
+		return false
+	}
+	if dir == "." {
+		return true
+	}
+	parent := moduleDir
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "testdata" || elem == "vendor" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return false
+		}
+		parent = filepath.Join(parent, elem)
+		if _, err := os.Stat(filepath.Join(parent, "go.mod")); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// hasGoFiles reports whether the directory has go files, a package is removed if it has none.
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
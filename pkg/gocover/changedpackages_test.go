@@ -0,0 +1,151 @@
+package gocover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/sirupsen/logrus"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChangedPackages(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":               "module example.com/foo\n",
+		"foo.go":               "package foo\n",
+		"bar/bar.go":           "package bar\n",
+		"bar/baz/baz.go":       "package baz\n",
+		"testdata/data.go":     "package data\n",
+		"vendor/x/x.go":        "package x\n",
+		"nested/go.mod":        "module example.com/nested\n",
+		"nested/pkg/nested.go": "package pkg\n",
+	})
+	changed := []string{
+		"foo.go", "bar/bar.go", "bar/bar_test.go", "bar/baz/baz.go", "bar/README.md",
+		"testdata/data.go", "vendor/x/x.go", "nested/pkg/nested.go", "removed/removed.go",
+	}
+	expected := []string{".", "./bar", "./bar/baz"}
+	if packages := changedPackages(dir, changed); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("expect %v, but get %v", expected, packages)
+	}
+	if packages := changedPackages(dir, []string{"README.md"}); len(packages) != 0 {
+		t.Errorf("expect no packages, but get %v", packages)
+	}
+}
+
+func TestGoExecutorChangedPackages(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/foo\n\ngo 1.20\n",
+		"a/a.go":           "package a\n\nfunc A() int {\n\treturn 1\n}\n",
+		"a/a_test.go":      "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {\n\tA()\n}\n",
+		"b/b.go":           "package b\n\nfunc B() int {\n\treturn 1\n}\n",
+		"b/b_test.go":      "package b\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {\n\tt.Fatal(\"broken\")\n}\n",
+		"docs/README.md":   "docs\n",
+		"a/testdata/x.txt": "x\n",
+	})
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "init")
+	git(t, dir, "checkout", "-q", "-b", "feature")
+
+	// the packages of the cover profile are resolved from the working directory, as gocover test runs in the module.
+	newOption := func() *GoCoverTestOption {
+		return &GoCoverTestOption{
+			CompareBranch:    "main",
+			RepositoryPath:   dir,
+			ModuleDir:        "./",
+			CoverageMode:     DiffCoverage,
+			ExecutorMode:     GoExecutor,
+			GoFlags:          []string{"-count=1", " "},
+			ChangedPackages:  true,
+			ReportFormat:     "json",
+			ReportName:       "coverage",
+			OutputDir:        t.TempDir(),
+			FailedTestPolicy: FailOnFailedTests,
+			DbOption:         &dbclient.DBOption{},
+			StdOut:           &bytes.Buffer{},
+			StdErr:           &bytes.Buffer{},
+			Logger:           logrus.New(),
+		}
+	}
+
+	t.Run("no changed packages", func(t *testing.T) {
+		commitFiles(t, dir, "docs", map[string]string{"docs/README.md": "more docs\n"})
+		o := newOption()
+		executor, err := NewGoCoverTestExecutor(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := inDirectory(dir, o.Logger, func() error { return executor.Run(context.Background()) }); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("changed package", func(t *testing.T) {
+		// the broken tests of b don't run, since only a is changed.
+		commitFiles(t, dir, "change a", map[string]string{"a/a.go": "package a\n\nfunc A() int {\n\treturn 2\n}\n"})
+		o := newOption()
+		executor, err := NewGoCoverTestExecutor(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := inDirectory(dir, o.Logger, func() error { return executor.Run(context.Background()) }); err != nil {
+			t.Fatal(err)
+		}
+		results, err := os.ReadFile(filepath.Join(o.OutputDir, outTestResults))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(results, []byte("example.com/foo/a")) || bytes.Contains(results, []byte("example.com/foo/b")) {
+			t.Errorf("expect only the tests of a to run, but get:\n%s", results)
+		}
+	})
+
+	t.Run("all packages", func(t *testing.T) {
+		o := newOption()
+		o.ChangedPackages = false
+		executor, err := NewGoCoverTestExecutor(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := inDirectory(dir, o.Logger, func() error { return executor.Run(context.Background()) }); err == nil {
+			t.Error("expect the broken tests of b to fail")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		o := newOption()
+		executor, err := NewGoCoverTestExecutor(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = inDirectory(dir, o.Logger, func() error { return executor.Run(ctx) })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expect the tests don't run after the context is canceled, but get %v", err)
+		}
+	})
+}
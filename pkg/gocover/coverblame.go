@@ -0,0 +1,143 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// CoverageBlameOption contains the input to the gocover blame command.
+type CoverageBlameOption struct {
+	CoverProfiles []string
+	CompareBranch string
+	// DiffTarget is what compares with the branch, such as worktree or stash@{0}, it's HEAD if empty.
+	DiffTarget     string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath string
+	Excludes   []string
+	// Files are the files to blame, relative to the working directory or absolute.
+	Files []string
+	// Blame decides the lines and the summary of each file.
+	Blame report.BlameOption
+
+	StdOut io.Writer
+	Logger logrus.FieldLogger
+}
+
+// RunCoverageBlame writes the lines of the files in the format of git blame with the diff coverage status of each line,
+// so the owners of the uncovered lines of the changes can be reviewed. The authors are of the git blame of HEAD,
+// the lines of a file that isn't counted by the diff coverage are all not counted.
+func RunCoverageBlame(ctx context.Context, o *CoverageBlameOption) error {
+	logger := o.Logger.WithField("source", "blame")
+	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return fmt.Errorf("get absolute path of repo: %w", err)
+	}
+	moduleDir := filepath.Join(repositoryAbsPath, o.ModuleDir)
+
+	outputDir, err := createGoCoverTempDirectory()
+	if err != nil {
+		return fmt.Errorf("create gocover temp directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	recorder := &statisticsRecorder{}
+	option := NewDiffOption()
+	option.CoverProfiles = o.CoverProfiles
+	option.CompareBranch = o.CompareBranch
+	option.DiffTarget = o.DiffTarget
+	option.RepositoryPath = repositoryAbsPath
+	option.ModuleDir = o.ModuleDir
+	option.ModulePath = o.ModulePath
+	option.Excludes = o.Excludes
+	option.CoverageBaseline = 0
+	option.ReportFormat = JSONReportFormat
+	option.ReportName = "blame"
+	option.OutputDir = outputDir
+	option.Style = "colorful"
+	option.DbOption = &dbclient.DBOption{}
+	option.ReportGenerators = []report.ReportGenerator{recorder}
+	option.Logger = logger
+
+	diff, err := NewDiffCover(option)
+	if err != nil {
+		return err
+	}
+	err = diff.Run(ctx)
+	var gocoverErr *GoCoverError
+	if err != nil && !(recorder.statistics != nil && errors.As(err, &gocoverErr) && gocoverErr.ExitCode == LowCoverageErrorExitCode) {
+		return err
+	}
+	if recorder.statistics == nil {
+		return fmt.Errorf("no diff coverage against %s", o.CompareBranch)
+	}
+
+	blamer, err := gittool.NewBlamer(moduleDir)
+	if err != nil {
+		return fmt.Errorf("open git repository of %s: %w", moduleDir, err)
+	}
+	for i, file := range o.Files {
+		if i > 0 {
+			fmt.Fprintln(o.StdOut)
+		}
+		if err := writeCoverageBlame(o.StdOut, file, moduleDir, recorder.statistics, blamer, o.Blame, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCoverageBlame writes the blame of the file with the coverage status of its profile in the statistics.
+func writeCoverageBlame(w io.Writer, file, moduleDir string, statistics *report.Statistics, blamer gittool.Blamer, o report.BlameOption, logger logrus.FieldLogger) error {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("get absolute path of %s: %w", file, err)
+	}
+	relative, err := filepath.Rel(moduleDir, path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside of the module directory %s", file, moduleDir)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", file, err)
+	}
+	source := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	suffix := "/" + filepath.ToSlash(relative)
+	profile := &report.CoverageProfile{FileName: filepath.ToSlash(relative)}
+	found := false
+	for _, p := range statistics.CoverageProfile {
+		if strings.HasSuffix(p.FileName, suffix) {
+			profile, found = p, true
+			break
+		}
+	}
+	if !found {
+		logger.Infof("%s has no lines counted for the diff coverage", file)
+	}
+
+	var authors map[int]*report.LineAuthor
+	lines, err := blamer.Blame(path)
+	if err != nil {
+		logger.WithError(err).Warnf("blame %s, its lines are not committed", file)
+	}
+	if len(lines) != 0 {
+		authors = make(map[int]*report.LineAuthor, len(lines))
+		for i, line := range lines {
+			authors[i+1] = &report.LineAuthor{Author: line.Author, Commit: line.Commit}
+		}
+	}
+
+	return report.WriteBlame(w, profile, source, authors, o)
+}
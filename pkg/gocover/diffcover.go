@@ -2,15 +2,21 @@ package gocover
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"go/build"
+	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Azure/gocover/pkg/annotation"
+	"github.com/Azure/gocover/pkg/audit"
 	"github.com/Azure/gocover/pkg/dbclient"
 	"github.com/Azure/gocover/pkg/gittool"
 	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/phase"
 	"github.com/Azure/gocover/pkg/report"
 	"github.com/sirupsen/logrus"
 )
@@ -34,31 +40,145 @@ func NewDiffCover(o *DiffOption) (GoCover, error) {
 		}
 	}
 
+	policy := o.FailedTestPolicy
+	if policy == "" {
+		policy = WarnOnFailedTests
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	shard, err := ParseShard(o.Shard)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.Limits.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.SARIF.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.LineMapping.Validate(); err != nil {
+		return nil, err
+	}
+	theme, err := o.Theme.Load()
+	if err != nil {
+		return nil, err
+	}
+	pathMappings, err := report.ParsePathMappings(o.SonarQubePathMappings)
+	if err != nil {
+		return nil, err
+	}
+	skipList, err := newSkipList(o.SkipListFile, time.Now(), logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.DiffAlgorithm.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.Regression.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.Rollout.Validate(); err != nil {
+		return nil, err
+	}
+	contextLines := o.DiffContext
+	if contextLines <= 0 {
+		contextLines = report.DefaultContextLines
+	}
+
 	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
 	if err != nil {
 		return nil, fmt.Errorf("get absolute path of repo: %w", err)
 	}
 
-	modulePath, err := parseGoModulePath(filepath.Join(repositoryAbsPath, o.ModuleDir))
+	modulePath, err := resolveModulePath(filepath.Join(repositoryAbsPath, o.ModuleDir), o.ModulePath, logger)
 	if err != nil {
-		return nil, fmt.Errorf("parse go module path: %w", err)
+		return nil, fmt.Errorf("resolve module path: %w", err)
 	}
 
 	logger.Debugf("repository path: %s, module path: %s, output dir: %s, exclude patterns: %s",
 		repositoryAbsPath, modulePath, o.OutputDir, o.Excludes)
 
+	coverageTree := report.NewCoverageTree(modulePath)
+	extra := o.ReportGenerators
+	if o.JSONOutput != "" {
+		document := report.NewDocumentGenerator(o.JSONOutput, os.Stdout, coverageTree, o.Verbose, o.Logger)
+		extra = append([]report.ReportGenerator{document}, extra...)
+	}
+	generators := newReportGenerators(&reportGeneratorOption{
+		format:           o.ReportFormat,
+		style:            o.Style,
+		outputDir:        o.OutputDir,
+		reportName:       o.ReportName,
+		verbose:          o.Verbose,
+		modulePath:       modulePath,
+		moduleDir:        filepath.Join(repositoryAbsPath, o.ModuleDir),
+		contextLines:     contextLines,
+		coverageBaseline: o.CoverageBaseline,
+		theme:            theme,
+		sarif: &report.SARIFReportOption{
+			ModulePath:    modulePath,
+			ModuleDir:     o.ModuleDir,
+			SARIFSettings: o.SARIF,
+		},
+		sonarQube: &report.SonarQubeReportOption{
+			ModulePath:   modulePath,
+			ModuleDir:    o.ModuleDir,
+			PathMappings: pathMappings,
+		},
+		artifacts: &report.ArtifactsOption{
+			OutputDir:        o.ArtifactsDir,
+			Style:            o.Style,
+			Verbose:          o.Verbose,
+			ModulePath:       modulePath,
+			CoverageBaseline: o.CoverageBaseline,
+			Theme:            theme,
+		},
+		extra:  extra,
+		logger: o.Logger,
+	})
+
 	return &diffCover{
 		repositoryPath:   repositoryAbsPath,
 		comparedBranch:   o.CompareBranch,
+		diffTarget:       o.DiffTarget,
+		againstTag:       o.AgainstTag,
 		moduleDir:        o.ModuleDir,
 		modulePath:       modulePath,
 		excludeFiles:     make(excludeFileCache),
 		excludePatterns:  o.Excludes,
-		coverageTree:     report.NewCoverageTree(modulePath),
+		skipList:         skipList,
+		coverageTree:     coverageTree,
 		coverFilenames:   o.CoverProfiles,
+		testResults:      o.TestResults,
+		failedTestPolicy: policy,
 		coverageBaseline: o.CoverageBaseline,
+		bypassOption:     &o.Bypass,
+		limits:           o.Limits,
+		strictParse:      o.StrictParse,
+		layers:           o.Layers,
+		packageBaselines: o.PackageBaselines,
+		gracePeriods:     activeGracePeriods(o.GracePeriods, time.Now(), logger),
+		fileBaseline:     o.FileBaseline,
+		gitAttributes:    loadGitAttributes(o.GitAttributes, repositoryAbsPath, logger),
+		shard:            shard,
+		testHelpers:      newTestHelpers(o.TestHelpers, filepath.Join(repositoryAbsPath, o.ModuleDir), modulePath, logger),
+		ignoreProviders:  o.IgnoreProviders,
+		todos:            o.Todos,
+		lineMapping:      o.LineMapping.OrDefault(),
+		diffView:         o.DiffView,
+		hunks:            o.Hunks,
+		diffAlgorithm:    o.DiffAlgorithm,
+		regression:       o.Regression,
+		rollout:          o.Rollout,
+		parseCache:       o.ParseCache,
+		gitClient:        o.GitClient,
 		dbClient:         dbClient,
-		reportGenerator:  report.NewReportGenerator(o.Style, o.OutputDir, o.ReportName, o.Logger),
+		dbOption:         o.DbOption,
+		auditRecorder:    audit.NewRecorder(o.AuditFile),
+		recorder:         phase.NewRecorder(),
+		reportGenerators: generators,
+		annotators:       o.Annotators,
 		logger:           logger,
 	}, nil
 
@@ -69,37 +189,94 @@ var _ GoCover = (*diffCover)(nil)
 // diffCoverage implements the GoCover interface and generate the diff coverage statistics.
 type diffCover struct {
 	comparedBranch   string // git diff base branch
+	diffTarget       string // git diff target, HEAD if it's empty
+	againstTag       bool   // compare with the latest semver tag rather than the compared branch
 	repositoryPath   string
 	excludePatterns  []string
+	skipList         *skipList
 	ignoreProfiles   []*annotation.IgnoreProfile
 	excludeFiles     excludeFileCache
 	moduleDir        string
 	modulePath       string
 	coverFilenames   []string
+	testResults      []string
+	failedTestPolicy FailedTestPolicy
 	coverageBaseline float64
-
-	reportGenerator report.ReportGenerator
-	coverageTree    report.CoverageTree
-	dbClient        dbclient.DbClient
+	bypassOption     *BypassOption
+	limits           Limits
+	strictParse      bool
+	layers           []Layer
+	packageBaselines []PackageBaseline
+	gracePeriods     []GracePeriod
+	fileBaseline     float64
+	gitAttributes    *gitAttributes
+	testHelpers      *testHelpers
+	shard            *report.Shard
+	ignoreProviders  []annotation.IgnoreProvider
+	todos            TodoOption
+	lineMapping      parser.LineMapping
+	diffView         bool
+	hunks            HunkOption
+	hunkChanges      []*gittool.Change // counted changes, kept for the hunks printed after the reports
+	diffAlgorithm    gittool.DiffAlgorithm
+	regression       RegressionOption
+	rollout          RolloutOption
+	changedFiles     []string // changed files of any type, for the embedded files
+	parseCache       *parser.Cache
+	gitClient        gittool.GitClient
+
+	reportGenerators []report.ReportGenerator
+	annotators       []report.Annotator
+	coverageTree     report.CoverageTree
+	dbClient         dbclient.DbClient
+	dbOption         *dbclient.DBOption
+	auditRecorder    audit.Recorder
+	recorder         *phase.Recorder
 
 	logger logrus.FieldLogger
 }
 
-func (diff *diffCover) Run(ctx context.Context) error {
+func (diff *diffCover) Run(ctx context.Context) (err error) {
+	defer func() {
+		if flushErr := diff.auditRecorder.Flush(); flushErr != nil && err == nil {
+			err = fmt.Errorf("audit: %w", flushErr)
+		}
+	}()
 
 	statistics, err := diff.generateStatistics()
 	if err != nil {
 		return fmt.Errorf("diff: %w", err)
 	}
 
-	if err := diff.reportGenerator.GenerateReport(statistics); err != nil {
-		return fmt.Errorf("generate report: %w", err)
+	if err := diff.calculateRegression(ctx, statistics); err != nil {
+		return fmt.Errorf("regression: %w", err)
+	}
+
+	if statistics.Rollout, err = diff.rollout.decide(ctx, diff.repositoryPath); err != nil {
+		return fmt.Errorf("rollout: %w", err)
+	}
+
+	if err := diff.checkBypass(ctx, statistics); err != nil {
+		return fmt.Errorf("check bypass: %w", err)
+	}
+	decideGate(statistics, diff.coverageBaseline)
+
+	if err := generateReports(diff.annotators, diff.reportGenerators, statistics, diff.recorder, diff.logger); err != nil {
+		return err
+	}
+
+	if err := diff.writeHunks(diff.hunkChanges, statistics); err != nil {
+		return fmt.Errorf("write hunks: %w", err)
 	}
 
 	if err := diff.dump(ctx); err != nil {
 		return fmt.Errorf("%w", err)
 	}
 
+	if err := checkFailedTests(statistics, diff.failedTestPolicy, diff.logger); err != nil {
+		return err
+	}
+
 	if err := diff.pass(statistics); err != nil {
 		return fmt.Errorf("%w", err)
 	}
@@ -107,29 +284,65 @@ func (diff *diffCover) Run(ctx context.Context) error {
 	return nil
 }
 
-func (diff *diffCover) pass(statistics *report.Statistics) error {
-	if statistics.TotalCoveragePercent < diff.coverageBaseline {
-		return WrapErrorWithCode(
-			fmt.Errorf("the coverage baseline pass rate is %.2f, currently is %.2f",
-				diff.coverageBaseline,
-				statistics.TotalCoveragePercent,
-			),
-			LowCoverageErrorExitCode,
-			"",
-		)
+// checkBypass marks the statistics as bypassed when the coverage is lower than the baseline
+// and the bypass is requested by label or comment command, or the rollout doesn't enforce the gate on the pull request.
+func (diff *diffCover) checkBypass(ctx context.Context, statistics *report.Statistics) error {
+	if len(evaluateGate(statistics, diff.coverageBaseline).Violations) == 0 {
+		return nil
+	}
+
+	bypass, err := findBypass(diff.bypassOption, diff.logger)
+	if err != nil {
+		return err
+	}
+	if bypass == nil {
+		bypass = rolloutBypass(statistics.Rollout)
+	}
+	if bypass == nil {
+		return nil
+	}
+
+	statistics.Bypass = bypass
+	diff.logger.Warnf("coverage gate fails with coverage %.2f and baseline %.2f, but the gate is bypassed by %s: %s",
+		statistics.TotalCoveragePercent, diff.coverageBaseline, bypass.Source, bypass.Reason)
+	diff.auditRecorder.Record(&audit.Action{
+		Type:      audit.BypassAction,
+		Target:    "coverage-baseline",
+		Succeeded: true,
+		Details: map[string]string{
+			"source":   bypass.Source,
+			"reason":   bypass.Reason,
+			"actor":    bypass.Actor,
+			"coverage": fmt.Sprintf("%.2f", statistics.TotalCoveragePercent),
+			"baseline": fmt.Sprintf("%.2f", diff.coverageBaseline),
+		},
+	})
+
+	if diff.dbClient != nil {
+		err := storeBypassData(ctx, diff.dbClient, bypass, statistics.TotalCoveragePercent, diff.coverageBaseline, diff.modulePath)
+		diff.auditRecorder.Record(audit.NewAction(audit.UploadAction, diff.dbOption.BypassTarget(), "", err))
+		if err != nil {
+			return fmt.Errorf("store bypass data: %w", err)
+		}
 	}
 	return nil
 }
 
+func (diff *diffCover) pass(statistics *report.Statistics) error {
+	return checkGate(statistics, diff.coverageBaseline)
+}
+
 func (diff *diffCover) dump(ctx context.Context) error {
 	all := diff.coverageTree.All()
 
 	if diff.dbClient != nil {
 		err := storeCoverageData(ctx, diff.dbClient, all, DiffCoverage, diff.modulePath)
+		diff.auditRecorder.Record(audit.NewAction(audit.UploadAction, diff.dbOption.CoverageTarget(), "", err))
 		if err != nil {
 			return fmt.Errorf("store coverage data: %w", err)
 		}
 		err = storeIgnoreProfileData(ctx, diff.dbClient, diff.ignoreProfiles, DiffCoverage, diff.modulePath, diff.repositoryPath, diff.moduleDir)
+		diff.auditRecorder.Record(audit.NewAction(audit.UploadAction, diff.dbOption.IgnoreTarget(), "", err))
 		if err != nil {
 			return fmt.Errorf("store ignore profile data: %w", err)
 		}
@@ -140,139 +353,203 @@ func (diff *diffCover) dump(ctx context.Context) error {
 }
 
 func (diff *diffCover) getGitChanges() ([]*gittool.Change, error) {
-	gitClient, err := gittool.NewGitClient(diff.repositoryPath)
-	if err != nil {
-		return nil, fmt.Errorf("git repository: %w", err)
+	gitClient := diff.gitClient
+	if gitClient == nil {
+		var err error
+		if gitClient, err = gittool.NewGitClientWithAlgorithm(diff.repositoryPath, diff.diffAlgorithm); err != nil {
+			return nil, fmt.Errorf("git repository: %w", err)
+		}
+	}
+	if diff.againstTag {
+		tag, err := latestModuleTag(gitClient, diff.moduleDir)
+		if err != nil {
+			return nil, fmt.Errorf("find latest release tag: %w", err)
+		}
+		diff.logger.Infof("diff against the latest release tag %s", tag)
+		diff.comparedBranch = tag
 	}
-	changes, err := gitClient.DiffChangesFromCommitted(diff.comparedBranch)
+	changes, err := gitClient.DiffChanges(diff.comparedBranch, diff.diffTarget)
 	if err != nil {
 		return nil, fmt.Errorf("git diff: %w", err)
 	}
+	diff.changedFiles, err = gitClient.ChangedFiles(diff.comparedBranch, diff.diffTarget)
+	if err != nil {
+		// the embedded files are informational, the coverage is still calculated.
+		diff.logger.Warnf("list changed files for the embedded files: %s", err)
+	}
 	return changes, nil
 }
 
-func (diff *diffCover) generateStatistics() (*report.Statistics, error) {
-	changes, err := diff.getGitChanges()
-	if err != nil {
-		return nil, err
+// latestModuleTag returns the latest semver tag of the module. The tags of a nested module are prefixed
+// by the module directory, such as modulea/v1.2.0, the tags of the repository are used if the module has none.
+func latestModuleTag(gitClient gittool.GitClient, moduleDir string) (string, error) {
+	dir := filepath.ToSlash(filepath.Clean(moduleDir))
+	if dir != "." {
+		tag, err := gitClient.LatestSemverTag(dir + "/")
+		if !errors.Is(err, gittool.ErrNoSemverTag) {
+			return tag, err
+		}
 	}
+	return gitClient.LatestSemverTag("")
+}
 
-	packages, err := parser.NewParser(diff.coverFilenames, diff.logger).Parse(changes)
+func (diff *diffCover) generateStatistics() (*report.Statistics, error) {
+	stopDiff := diff.recorder.Start(phase.DiffPhase)
+	changes, err := diff.getGitChanges()
+	stopDiff()
 	if err != nil {
 		return nil, err
 	}
+	return diff.calculateStatistics(changes)
+}
 
+// calculateStatistics calculates the diff coverage statistics of the changes.
+func (diff *diffCover) calculateStatistics(changes []*gittool.Change) (*report.Statistics, error) {
 	statistics := &report.Statistics{
 		StatisticsType: report.DiffStatisticsType,
 		ComparedBranch: diff.comparedBranch,
+		DiffTarget:     diff.diffTarget,
+		LineMapping:    string(diff.lineMapping),
 	}
-	m := make(map[string]*report.CoverageProfile)
-	fileCache := make(fileContentsCache)
-	added := make(map[string]*report.CoverageProfile)
-	keep := make(map[string]string)
-	for _, pkg := range packages {
-		diff.logger.Debugf("package: %s", pkg.Name)
-		diff.ignoreProfiles = append(diff.ignoreProfiles, pkg.IgnoreProfiles...)
 
-		p, err := build.Import(pkg.Name, ".", build.FindOnly)
-		if err != nil {
-			return nil, fmt.Errorf("build import %w", err)
-		}
-
-		for _, fun := range pkg.Functions {
-
-			// extract into single function
-			coverProfile, ok := m[fun.File]
-			if !ok {
-				coverProfile = &report.CoverageProfile{
-					FileName: formatFilePath(p.Root, fun.File, diff.modulePath),
-				}
-				m[fun.File] = coverProfile
-			}
-
-			fileContents, err := findFileContents(fileCache, fun.File)
-			if err != nil {
-				return nil, fmt.Errorf("find file contents: %w", err)
-			}
+	changes, err := diff.limitChanges(changes, statistics)
+	if err != nil {
+		return nil, err
+	}
+	changes, err = diff.skipToolFiles(changes, statistics)
+	if err != nil {
+		return nil, err
+	}
+	changes = diff.skipGitAttributes(changes)
 
-			section := &report.ViolationSection{
-				StartLine: fun.StartLine,
-				EndLine:   fun.EndLine,
-			}
+	stopParse := diff.recorder.Start(phase.ParsePhase)
+	p := parser.NewParser(diff.coverFilenames, diff.logger).WithRecorder(diff.recorder).WithStrict(diff.strictParse).WithIgnoreProviders(diff.ignoreProviders...).WithLineMapping(diff.lineMapping).WithCache(diff.parseCache)
+	packages, err := p.Parse(changes)
+	stopParse()
+	if err != nil {
+		return nil, err
+	}
+	addParseErrors(statistics, p.ParseErrors())
+	engine := &coverageEngine{
+		modulePath:       diff.modulePath,
+		excludeFiles:     diff.excludeFiles,
+		excludePatterns:  diff.excludePatterns,
+		skipList:         diff.skipList,
+		coverageTree:     diff.coverageTree,
+		testResults:      diff.testResults,
+		failedTestPolicy: diff.failedTestPolicy,
+		limits:           diff.limits,
+		layers:           diff.layers,
+		packageBaselines: diff.packageBaselines,
+		gracePeriods:     diff.gracePeriods,
+		fileBaseline:     diff.fileBaseline,
+		gitAttributes:    diff.gitAttributes,
+		testHelpers:      diff.testHelpers,
+		shard:            diff.shard,
+		logger:           diff.logger,
+	}
+	stopCompute := diff.recorder.Start(phase.ComputePhase)
+	diff.ignoreProfiles, err = engine.calculate(packages, statistics, changedStatements)
+	stopCompute()
+	if err != nil {
+		return nil, err
+	}
+	diff.addUnmatchedChanges(statistics, p.UnmatchedChanges())
+	diff.addEmbeddedFiles(statistics, changes)
+	statistics.TestHelperPackages = diff.testHelpers.packages()
+	statistics.Todos = diff.countTodos(changes)
+	diff.addDiffSections(statistics, changes)
+	if diff.hunks.Enabled {
+		diff.hunkChanges = changes
+	}
+	return statistics, nil
+}
 
-			for i := fun.StartLine; i <= fun.EndLine; i++ {
-				section.Contents = append(section.Contents, fileContents[i-1])
-			}
+// addUnmatchedChanges adds the changed files of the module that match no cover profile to the non-fatal errors,
+// the file is reported as missing tests if the test results show its package has no tests.
+// The files of the test helpers are not reported, they have no cover profiles unless the tests of other packages cover them.
+func (diff *diffCover) addUnmatchedChanges(statistics *report.Statistics, changes []*gittool.Change) {
+	noTests := noTestPackages(statistics, diff.testHelpers)
+	for _, change := range changes {
+		fileName, ok := diff.reportFileName(change)
+		if !ok || inExclueds(make(excludeFileCache), diff.excludePatterns, fileName, diff.logger) || diff.skipList.skip(statistics, fileName) {
+			continue
+		}
+		if diff.testHelpers.exempt(path.Dir(fileName)) {
+			continue
+		}
 
-			var total, ignored, covered, coveredButIgnored int
-			violated := false
-			changed := false
-			for _, st := range fun.Statements {
-				if st.State == parser.Original {
-					continue
-				}
-
-				changed = true
-				total += 1
-
-				if st.Mode == parser.Ignore && st.Reached > 0 {
-					coveredButIgnored++
-				}
-
-				if st.Mode == parser.Ignore {
-					diff.logger.Debugf("%s ignore line %d", fun.File, st.StartLine)
-					ignored++
-				}
-				if st.Reached > 0 {
-					covered++
-				} else {
-					section.ViolationLines = append(section.ViolationLines, st.StartLine)
-					violated = true
-				}
+		if pkg := path.Dir(fileName); noTests[pkg] {
+			nonFatalErrors(statistics).Add(report.MissingTestsError, fileName, "no cover profile, package %s has no tests", pkg)
+			continue
+		}
+		nonFatalErrors(statistics).Add(report.UnmatchedFileError, fileName, "no cover profile, the package may have no tests or the file has no statements")
+	}
+}
 
-			}
+// addDiffSections adds the added and deleted sections of the changes to the profiles of the files for the diff view.
+func (diff *diffCover) addDiffSections(statistics *report.Statistics, changes []*gittool.Change) {
+	if !diff.diffView {
+		return
+	}
+	profiles := make(map[string]*report.CoverageProfile)
+	for _, p := range statistics.CoverageProfile {
+		profiles[p.FileName] = p
+	}
 
-			if changed {
-
-				if ok := inExclueds(
-					diff.excludeFiles,
-					diff.excludePatterns,
-					formatFilePath(p.Root, fun.File, diff.modulePath),
-					diff.logger,
-				); ok {
-					continue
-				}
-
-				coverProfile.TotalLines += total
-				coverProfile.CoveredLines += covered
-				coverProfile.TotalEffectiveLines += (total - ignored)
-				coverProfile.TotalIgnoredLines += ignored
-				coverProfile.CoveredButIgnoredLines += coveredButIgnored
-				if violated {
-					coverProfile.ViolationSections = append(coverProfile.ViolationSections, section)
-				}
-				if _, ok := added[fun.File]; !ok {
-					statistics.CoverageProfile = append(statistics.CoverageProfile, coverProfile)
-					added[fun.File] = coverProfile
-					keep[fun.File] = p.Root
-				}
+	for _, change := range changes {
+		fileName, _ := diff.reportFileName(change)
+		profile, ok := profiles[fileName]
+		if !ok {
+			continue
+		}
+		for _, s := range change.Sections {
+			if s.Operation == gittool.Add {
+				profile.DiffSections = append(profile.DiffSections, &report.DiffSection{StartLine: s.StartLine, EndLine: s.EndLine})
 			}
 		}
-
+		for _, s := range change.Deletions {
+			profile.DiffSections = append(profile.DiffSections, &report.DiffSection{
+				Deleted:   true,
+				StartLine: s.StartLine,
+				EndLine:   s.EndLine,
+				Contents:  diff.limits.truncateContents(s.Contents),
+			})
+		}
+		// the deleted lines are before the lines added at the same line, as a unified diff shows a replacement.
+		sort.SliceStable(profile.DiffSections, func(i, j int) bool {
+			si, sj := profile.DiffSections[i], profile.DiffSections[j]
+			return si.StartLine < sj.StartLine || si.StartLine == sj.StartLine && si.Deleted && !sj.Deleted
+		})
 	}
+}
 
-	for k, v := range added {
-		node := diff.coverageTree.FindOrCreate(strings.TrimPrefix(k, keep[k]))
-		node.TotalLines = int64(v.TotalLines)
-		node.TotalCoveredLines = int64(v.CoveredLines)
-		node.TotalEffectiveLines = int64(v.TotalEffectiveLines)
-		node.TotalIgnoredLines = int64(v.TotalIgnoredLines)
-		node.TotalCoveredButIgnoreLines = int64(v.CoveredButIgnoredLines)
+// reportFileName returns the file name of the change in the reports, which starts with the module path,
+// it returns false if the changed file is not in the module.
+func (diff *diffCover) reportFileName(change *gittool.Change) (string, bool) {
+	rel, err := filepath.Rel(filepath.Clean(diff.moduleDir), change.FileName)
+	if err != nil {
+		return filepath.ToSlash(filepath.Join(diff.modulePath, change.FileName)), false
 	}
+	return filepath.ToSlash(filepath.Join(diff.modulePath, rel)), !strings.HasPrefix(filepath.ToSlash(rel), "../")
+}
 
-	diff.coverageTree.CollectCoverageData()
-
-	reBuildStatistics(statistics, diff.excludeFiles)
-
-	return statistics, nil
+// limitChanges removes the changes of the oversized files before they are parsed, the removed files are added to the statistics.
+func (diff *diffCover) limitChanges(changes []*gittool.Change, statistics *report.Statistics) ([]*gittool.Change, error) {
+	// an empty slice rather than nil is returned, because nil changes mean all the profiles are kept by the parser.
+	result := make([]*gittool.Change, 0, len(changes))
+	for _, change := range changes {
+		fileName, _ := diff.reportFileName(change)
+		truncatedFile, err := diff.limits.checkFileSize(filepath.Join(diff.repositoryPath, change.FileName), fileName)
+		if err != nil {
+			return nil, err
+		}
+		if truncatedFile != nil {
+			diff.logger.Warnf("skip %s: %s", fileName, truncatedFile.Reason)
+			statistics.TruncatedFiles = append(statistics.TruncatedFiles, truncatedFile)
+			continue
+		}
+		result = append(result, change)
+	}
+	return result, nil
 }
@@ -0,0 +1,71 @@
+package gocover
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// tagGitClient is a git client with the release tags only.
+type tagGitClient struct {
+	gittool.GitClient
+	tags map[string]string
+}
+
+func (c *tagGitClient) LatestSemverTag(prefix string) (string, error) {
+	if tag, ok := c.tags[prefix]; ok {
+		return tag, nil
+	}
+	return "", fmt.Errorf("%w with prefix %q", gittool.ErrNoSemverTag, prefix)
+}
+
+func TestLatestModuleTag(t *testing.T) {
+	client := &tagGitClient{tags: map[string]string{"": "v1.2.0", "modulea/": "modulea/v0.3.0"}}
+
+	testSuites := []struct {
+		moduleDir string
+		expected  string
+	}{
+		{moduleDir: "./", expected: "v1.2.0"},
+		{moduleDir: "modulea/", expected: "modulea/v0.3.0"},
+		{moduleDir: "moduleb", expected: "v1.2.0"},
+	}
+	for _, testSuite := range testSuites {
+		tag, err := latestModuleTag(client, testSuite.moduleDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag != testSuite.expected {
+			t.Errorf("expect %s of module %s, but get %s", testSuite.expected, testSuite.moduleDir, tag)
+		}
+	}
+}
+
+func TestAddDiffSections(t *testing.T) {
+	diff := &diffCover{moduleDir: ".", modulePath: "github.com/Azure/gocover", diffView: true, limits: Limits{MaxSectionLines: 2}}
+	statistics := &report.Statistics{CoverageProfile: []*report.CoverageProfile{{FileName: "github.com/Azure/gocover/foo.go"}}}
+	changes := []*gittool.Change{
+		{
+			FileName: "foo.go",
+			Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 3, EndLine: 4}, {Operation: gittool.Add, StartLine: 9, EndLine: 9}},
+			Deletions: []*gittool.Section{
+				{Operation: gittool.Delete, StartLine: 3, EndLine: 3, Contents: []string{"a", "b", "c"}},
+			},
+		},
+		{FileName: "bar.go", Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 1, EndLine: 1}}},
+	}
+
+	diff.addDiffSections(statistics, changes)
+	sections := statistics.CoverageProfile[0].DiffSections
+	if len(sections) != 3 {
+		t.Fatalf("expect 3 diff sections, but get %d", len(sections))
+	}
+	if !sections[0].Deleted || len(sections[0].Contents) != 3 || sections[0].Contents[2] != fmt.Sprintf(report.TruncationMarker, 1) {
+		t.Errorf("expect the truncated deleted lines first, but get %+v", sections[0])
+	}
+	if sections[1].Deleted || sections[1].StartLine != 3 || sections[2].StartLine != 9 {
+		t.Errorf("unexpected added sections %+v %+v", sections[1], sections[2])
+	}
+}
@@ -0,0 +1,177 @@
+package gocover
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// embedDirective is the directive that embeds the files into a package, such as //go:embed templates/*.tmpl.
+const embedDirective = "//go:embed"
+
+// embed is a //go:embed directive of a go file.
+type embed struct {
+	// fileName is the name of the go file relative to the repository.
+	fileName string
+	// patterns are the patterns of the directive.
+	patterns []string
+}
+
+// embedPatterns returns the patterns of the //go:embed line, the quoted patterns are unquoted.
+// It returns false if the line is not a directive.
+func embedPatterns(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	rest, ok := strings.CutPrefix(line, embedDirective)
+	if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return nil, false
+	}
+
+	var patterns []string
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		var pattern string
+		switch rest[0] {
+		case '"', '`':
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return patterns, true
+			}
+			pattern, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+		default:
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			pattern, rest = rest[:end], rest[end:]
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, true
+}
+
+// matchEmbed returns true if the pattern of a directive embeds the file, the file name is relative to the directory of the directive.
+// A pattern that matches a directory embeds the files in it recursively, except the files begin with '.' or '_' without the all: prefix.
+func matchEmbed(pattern, fileName string) bool {
+	pattern, all := strings.CutPrefix(pattern, "all:")
+	if ok, _ := path.Match(pattern, fileName); ok {
+		return true
+	}
+	elems := strings.Split(fileName, "/")
+	for i := 1; i < len(elems); i++ {
+		if ok, _ := path.Match(pattern, path.Join(elems[:i]...)); !ok {
+			continue
+		}
+		if all {
+			return true
+		}
+		for _, elem := range elems[i:] {
+			if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// loadEmbeds returns the directives of the non-test go files in the directory of the repository.
+func (diff *diffCover) loadEmbeds(dir string) []*embed {
+	entries, err := os.ReadDir(filepath.Join(diff.repositoryPath, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil
+	}
+	var embeds []*embed
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		fileName := path.Join(dir, name)
+		f, err := os.Open(filepath.Join(diff.repositoryPath, filepath.FromSlash(fileName)))
+		if err != nil {
+			diff.logger.Debugf("skip the embed directives of %s: %s", fileName, err)
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if patterns, ok := embedPatterns(scanner.Text()); ok && len(patterns) > 0 {
+				embeds = append(embeds, &embed{fileName: fileName, patterns: patterns})
+			}
+		}
+		f.Close()
+	}
+	return embeds
+}
+
+// addEmbeddedFiles adds the changed files that are embedded by the //go:embed directives of the module, such as the templates
+// and the sql files, and the changed go files that add the directives. They are informational, gocover can't tell the coverage
+// of the embedded contents. The embedder of a file is in the directory of the file or its parents in the module.
+func (diff *diffCover) addEmbeddedFiles(statistics *report.Statistics, changes []*gittool.Change) {
+	moduleDir := filepath.ToSlash(filepath.Clean(diff.moduleDir))
+	embeds := make(map[string][]*embed)
+	for _, changedFile := range diff.changedFiles {
+		if strings.HasSuffix(changedFile, ".go") {
+			continue
+		}
+		fileName, ok := diff.reportFileName(&gittool.Change{FileName: changedFile})
+		if !ok {
+			continue
+		}
+
+	search:
+		for dir := path.Dir(changedFile); ; dir = path.Dir(dir) {
+			if _, ok := embeds[dir]; !ok {
+				embeds[dir] = diff.loadEmbeds(dir)
+			}
+			rel := strings.TrimPrefix(changedFile, dir+"/")
+			for _, e := range embeds[dir] {
+				for _, pattern := range e.patterns {
+					if matchEmbed(pattern, rel) {
+						embedder, _ := diff.reportFileName(&gittool.Change{FileName: e.fileName})
+						statistics.EmbeddedFiles = append(statistics.EmbeddedFiles, &report.EmbeddedFile{
+							FileName: fileName,
+							Embedder: embedder,
+							Pattern:  pattern,
+						})
+						break search
+					}
+				}
+			}
+			if dir == moduleDir || dir == "." {
+				break
+			}
+		}
+	}
+
+	for _, change := range changes {
+		fileName, ok := diff.reportFileName(change)
+		if !ok {
+			continue
+		}
+		for _, s := range change.Sections {
+			if s.Operation != gittool.Add {
+				continue
+			}
+			for _, line := range s.Contents {
+				if patterns, ok := embedPatterns(line); ok && len(patterns) > 0 {
+					statistics.EmbeddedFiles = append(statistics.EmbeddedFiles, &report.EmbeddedFile{
+						FileName: fileName,
+						Embedder: fileName,
+						Pattern:  strings.Join(patterns, " "),
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(statistics.EmbeddedFiles, func(i, j int) bool {
+		return statistics.EmbeddedFiles[i].FileName < statistics.EmbeddedFiles[j].FileName
+	})
+}
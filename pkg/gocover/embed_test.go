@@ -0,0 +1,93 @@
+package gocover
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEmbedPatterns(t *testing.T) {
+	testSuites := []struct {
+		line     string
+		patterns []string
+		ok       bool
+	}{
+		{line: "//go:embed schema.sql", patterns: []string{"schema.sql"}, ok: true},
+		{line: "//go:embed templates/*.tmpl static", patterns: []string{"templates/*.tmpl", "static"}, ok: true},
+		{line: "//go:embed \"my file.txt\" `raw*.txt`", patterns: []string{"my file.txt", "raw*.txt"}, ok: true},
+		{line: "  //go:embed all:assets", patterns: []string{"all:assets"}, ok: true},
+		{line: "//go:embedded foo", ok: false},
+		{line: "// go:embed foo", ok: false},
+	}
+	for _, testSuite := range testSuites {
+		patterns, ok := embedPatterns(testSuite.line)
+		if ok != testSuite.ok || !reflect.DeepEqual(patterns, testSuite.patterns) {
+			t.Errorf("%s: expect %v %t, but get %v %t", testSuite.line, testSuite.patterns, testSuite.ok, patterns, ok)
+		}
+	}
+}
+
+func TestMatchEmbed(t *testing.T) {
+	testSuites := []struct {
+		pattern  string
+		fileName string
+		expected bool
+	}{
+		{pattern: "schema.sql", fileName: "schema.sql", expected: true},
+		{pattern: "templates/*.tmpl", fileName: "templates/page.tmpl", expected: true},
+		{pattern: "templates/*.tmpl", fileName: "templates/page.html", expected: false},
+		{pattern: "static", fileName: "static/css/site.css", expected: true},
+		{pattern: "static", fileName: "static/.hidden", expected: false},
+		{pattern: "all:static", fileName: "static/_partial.html", expected: true},
+		{pattern: "static", fileName: "other/site.css", expected: false},
+	}
+	for _, testSuite := range testSuites {
+		if actual := matchEmbed(testSuite.pattern, testSuite.fileName); actual != testSuite.expected {
+			t.Errorf("match %s with %s: expect %t, but get %t", testSuite.pattern, testSuite.fileName, testSuite.expected, actual)
+		}
+	}
+}
+
+func TestAddEmbeddedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"foo/foo.go":              "package foo\n\nimport \"embed\"\n\n//go:embed templates\nvar templates embed.FS\n",
+		"foo/foo_test.go":         "package foo\n\n//go:embed testdata\nvar testdata embed.FS\n",
+		"foo/templates/page.tmpl": "{{ .Name }}\n",
+		"foo/testdata/case.json":  "{}\n",
+		"README.md":               "# foo\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff := &diffCover{
+		repositoryPath: dir,
+		modulePath:     "github.com/Azure/gocover",
+		changedFiles:   []string{"README.md", "foo/bar.go", "foo/templates/page.tmpl", "foo/testdata/case.json"},
+		logger:         logrus.New(),
+	}
+	statistics := &report.Statistics{}
+	changes := []*gittool.Change{{
+		FileName: "foo/bar.go",
+		Sections: []*gittool.Section{{Operation: gittool.Add, Contents: []string{"//go:embed schema.sql", "var schema string"}}},
+	}}
+	diff.addEmbeddedFiles(statistics, changes)
+
+	expected := []*report.EmbeddedFile{
+		{FileName: "github.com/Azure/gocover/foo/bar.go", Embedder: "github.com/Azure/gocover/foo/bar.go", Pattern: "schema.sql"},
+		{FileName: "github.com/Azure/gocover/foo/templates/page.tmpl", Embedder: "github.com/Azure/gocover/foo/foo.go", Pattern: "templates"},
+	}
+	if !reflect.DeepEqual(statistics.EmbeddedFiles, expected) {
+		t.Errorf("expect %+v, but get %+v", expected, statistics.EmbeddedFiles)
+	}
+}
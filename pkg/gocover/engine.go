@@ -0,0 +1,329 @@
+package gocover
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/annotation"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/testresult"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/tools/cover"
+)
+
+// statementFilter reports whether the statement counts for coverage.
+type statementFilter func(st *parser.Statement) bool
+
+// allStatements counts every statement, it's used by the full coverage.
+func allStatements(st *parser.Statement) bool {
+	return true
+}
+
+// changedStatements counts the statements that changed by the diff, it's used by the diff coverage.
+func changedStatements(st *parser.Statement) bool {
+	return st.State == parser.Changed
+}
+
+// coverageEngine calculates the coverage statistics from the parsed packages.
+// Both the diff coverage and the full coverage use it, so the statements are counted by the same rules,
+// the only difference is the statement filter.
+type coverageEngine struct {
+	modulePath      string
+	excludeFiles    excludeFileCache
+	excludePatterns []string
+	// skipList skips the files whose coverage collection is flaky, it's nil if there is no skip list.
+	skipList     *skipList
+	coverageTree report.CoverageTree
+	// testResults are the files of `go test -json` output, the profiles of failed packages are flagged as unreliable.
+	testResults []string
+	// failedTestPolicy decides whether the unreliable profiles are excluded.
+	failedTestPolicy FailedTestPolicy
+	// limits bounds the file size and the retained section contents.
+	limits Limits
+	// layers label the files with architecture layers, the coverage of each layer is calculated.
+	layers []Layer
+	// packageBaselines are the expected coverage of the packages.
+	packageBaselines []PackageBaseline
+	// gracePeriods are the active grace periods, the files in them don't count for the totals, the layers and the packages.
+	gracePeriods []GracePeriod
+	// fileBaseline is the expected coverage of each counted file.
+	fileBaseline float64
+	// gitAttributes excludes the files marked in .gitattributes, no file is excluded by them if it's nil.
+	gitAttributes *gitAttributes
+	// ratchet raises the package baselines to the baseline file, the packages are not ratcheted if it's nil.
+	ratchet *ratchet
+	// testHelpers recognize the test helper packages, whose missing tests are not reported.
+	testHelpers *testHelpers
+	// shard is the subset of the packages that are calculated, all the packages are calculated if it's nil.
+	shard  *report.Shard
+	logger logrus.FieldLogger
+}
+
+// calculate calculates the coverage profiles of the statements accepted by the filter and adds them to the statistics,
+// the coverage tree is updated and the ignore profiles of the packages are returned.
+func (e *coverageEngine) calculate(packages parser.Packages, statistics *report.Statistics, counted statementFilter) ([]*annotation.IgnoreProfile, error) {
+	var ignoreProfiles []*annotation.IgnoreProfile
+	profiles := make(map[string]*report.CoverageProfile)
+	roots := make(map[string]string)
+	fileCache := make(fileContentsCache)
+	// checked records the files whose size are checked, the value indicates the file is truncated.
+	checked := make(map[string]bool)
+	e.skipList.report(statistics)
+
+	statistics.Shard = e.shard
+	for _, pkg := range packages {
+		if !inShard(e.shard, pkg.Name) {
+			e.logger.Debugf("skip package %s of another shard", pkg.Name)
+			continue
+		}
+		e.logger.Debugf("package: %s", pkg.Name)
+		ignoreProfiles = append(ignoreProfiles, pkg.IgnoreProfiles...)
+
+		p, err := build.Import(pkg.Name, ".", build.FindOnly)
+		if err != nil {
+			return nil, fmt.Errorf("build import %w", err)
+		}
+
+		for _, fun := range pkg.Functions {
+			fileName := formatFilePath(p.Root, fun.File, e.modulePath)
+			if isTruncated, ok := checked[fun.File]; ok && isTruncated {
+				continue
+			}
+
+			section := &report.ViolationSection{
+				StartLine: fun.StartLine,
+				EndLine:   fun.EndLine,
+			}
+
+			var total, ignored, covered, coveredButIgnored int
+			var lines []*report.CountedLine
+			for _, st := range fun.Statements {
+				if !counted(st) {
+					continue
+				}
+
+				total++
+				lines = append(lines, &report.CountedLine{
+					Line:    st.StartLine,
+					Covered: st.Reached > 0,
+					Ignored: st.Mode == parser.Ignore,
+					Block:   toReportBlock(st.Block),
+				})
+				if st.Mode == parser.Ignore {
+					e.logger.Debugf("%s ignore line %d", fun.File, st.StartLine)
+					ignored++
+					if st.Reached > 0 {
+						coveredButIgnored++
+					}
+				}
+				if st.Reached > 0 {
+					covered++
+				} else {
+					section.ViolationLines = append(section.ViolationLines, st.StartLine)
+				}
+			}
+
+			if total == 0 {
+				continue
+			}
+			if ok := inExclueds(e.excludeFiles, e.excludePatterns, fileName, e.logger); ok {
+				continue
+			}
+			if e.gitAttributes.excluded(fun.File, fileName) {
+				e.excludeFiles[fileName] = true
+				continue
+			}
+			if e.skipList.skip(statistics, fileName) {
+				continue
+			}
+			if _, ok := checked[fun.File]; !ok {
+				truncatedFile, err := e.limits.checkFileSize(fun.File, fileName)
+				if err != nil {
+					return nil, err
+				}
+				checked[fun.File] = truncatedFile != nil
+				if truncatedFile != nil {
+					e.logger.Warnf("skip %s: %s", fileName, truncatedFile.Reason)
+					statistics.TruncatedFiles = append(statistics.TruncatedFiles, truncatedFile)
+					continue
+				}
+			}
+
+			coverProfile, ok := profiles[fun.File]
+			if !ok {
+				coverProfile = &report.CoverageProfile{FileName: fileName, GraceUntil: graceUntil(e.gracePeriods, fileName)}
+				profiles[fun.File] = coverProfile
+				roots[fun.File] = p.Root
+				statistics.CoverageProfile = append(statistics.CoverageProfile, coverProfile)
+			}
+
+			coverProfile.TotalLines += total
+			coverProfile.CoveredLines += covered
+			coverProfile.TotalEffectiveLines += (total - ignored)
+			coverProfile.TotalIgnoredLines += ignored
+			coverProfile.CoveredButIgnoredLines += coveredButIgnored
+			coverProfile.CountedLines = append(coverProfile.CountedLines, lines...)
+			coverProfile.Functions = append(coverProfile.Functions, &report.FunctionCoverage{
+				Name:                   fun.Name,
+				StartLine:              fun.StartLine,
+				EndLine:                fun.EndLine,
+				TotalLines:             total,
+				EffectiveLines:         total - ignored,
+				CoveredLines:           covered,
+				CoveredButIgnoredLines: coveredButIgnored,
+			})
+
+			if len(section.ViolationLines) != 0 {
+				fileContents, err := findFileContents(fileCache, fun.File)
+				if err != nil {
+					return nil, fmt.Errorf("find file contents: %w", err)
+				}
+				e.limits.fillSectionContents(section, fileContents)
+				coverProfile.TotalViolationLines = append(coverProfile.TotalViolationLines, section.ViolationLines...)
+				coverProfile.ViolationSections = append(coverProfile.ViolationSections, section)
+			}
+		}
+	}
+
+	if len(e.testResults) != 0 {
+		testPackages, err := testresult.ParseFiles(e.testResults)
+		if err != nil {
+			return nil, fmt.Errorf("parse test results: %w", err)
+		}
+		applyTestResults(statistics, testPackages)
+		if e.failedTestPolicy == ExcludeFailedTests {
+			e.excludeUnreliable(statistics, profiles)
+		}
+		addMissingTests(statistics, e.testHelpers)
+	}
+
+	for file, v := range profiles {
+		if !enforced(v) {
+			if len(v.TotalViolationLines) != 0 {
+				e.logger.Warnf("%s has %d uncovered lines, they don't fail the gate in the grace period until %s", v.FileName, len(v.TotalViolationLines), v.GraceUntil)
+			}
+			continue
+		}
+		node := e.coverageTree.FindOrCreate(strings.TrimPrefix(file, roots[file]))
+		node.TotalLines = int64(v.TotalLines)
+		node.TotalCoveredLines = int64(v.CoveredLines)
+		node.TotalEffectiveLines = int64(v.TotalEffectiveLines)
+		node.TotalIgnoredLines = int64(v.TotalIgnoredLines)
+		node.TotalCoveredButIgnoreLines = int64(v.CoveredButIgnoredLines)
+	}
+	e.coverageTree.CollectCoverageData()
+
+	reBuildStatistics(statistics, e.excludeFiles)
+	calculateLayers(statistics, e.layers)
+	calculatePackages(statistics, e.coverageTree, e.packageBaselines)
+	e.ratchet.apply(statistics.Packages)
+	statistics.FileBaseline = e.fileBaseline
+
+	return ignoreProfiles, nil
+}
+
+// excludeUnreliable removes the unreliable profiles from the statistics, and adds them to the exclude files.
+func (e *coverageEngine) excludeUnreliable(statistics *report.Statistics, profiles map[string]*report.CoverageProfile) {
+	kept := statistics.CoverageProfile[:0]
+	for _, p := range statistics.CoverageProfile {
+		if !p.Unreliable {
+			kept = append(kept, p)
+			continue
+		}
+		e.logger.Warnf("exclude %s as the tests of its package failed", p.FileName)
+		e.excludeFiles[p.FileName] = true
+	}
+	statistics.CoverageProfile = kept
+
+	for file, p := range profiles {
+		if p.Unreliable {
+			delete(profiles, file)
+		}
+	}
+}
+
+// applyTestResults adds the test results to the statistics, and flags the profiles in failed packages as unreliable.
+func applyTestResults(statistics *report.Statistics, testPackages []*testresult.Package) {
+	statistics.TestPackages = testPackages
+
+	failed := make(map[string]bool)
+	for _, p := range testPackages {
+		if p.IsFailed() {
+			failed[p.Name] = true
+		}
+	}
+	for _, p := range statistics.CoverageProfile {
+		if failed[filepath.ToSlash(filepath.Dir(p.FileName))] {
+			p.Unreliable = true
+		}
+	}
+}
+
+// noTestPackages returns the test packages that have no tests, the test helpers are exempted.
+func noTestPackages(statistics *report.Statistics, helpers *testHelpers) map[string]bool {
+	result := make(map[string]bool)
+	for _, p := range statistics.TestPackages {
+		if p.Status == testresult.SkipAction && p.Passed+p.Failed+p.Skipped == 0 && !helpers.exempt(p.Name) {
+			result[p.Name] = true
+		}
+	}
+	return result
+}
+
+// addMissingTests adds the packages of the counted files that have no tests to the non-fatal errors.
+func addMissingTests(statistics *report.Statistics, helpers *testHelpers) {
+	noTests := noTestPackages(statistics, helpers)
+	added := make(map[string]bool)
+	for _, p := range statistics.CoverageProfile {
+		pkg := filepath.ToSlash(filepath.Dir(p.FileName))
+		if !noTests[pkg] || added[pkg] {
+			continue
+		}
+		added[pkg] = true
+		nonFatalErrors(statistics).Add(report.MissingTestsError, pkg, "no tests in the package, its coverage comes from the tests of other packages")
+	}
+}
+
+// checkFailedTests returns the unit test failed error if any test package failed and the policy is fail,
+// otherwise it warns the unreliable coverage.
+func checkFailedTests(statistics *report.Statistics, policy FailedTestPolicy, logger logrus.FieldLogger) error {
+	var failed []string
+	for _, p := range statistics.TestPackages {
+		if p.IsFailed() {
+			failed = append(failed, p.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	if policy == FailOnFailedTests {
+		return WrapErrorWithCode(fmt.Errorf("tests of %d packages failed: %s", len(failed), strings.Join(failed, ", ")), UnitTestFailedErrorExitCode, "")
+	}
+	if policy == WarnOnFailedTests {
+		for _, p := range statistics.CoverageProfile {
+			if p.Unreliable {
+				logger.Warnf("coverage of %s is unreliable as the tests of its package failed", p.FileName)
+			}
+		}
+	}
+	return nil
+}
+
+// toReportBlock converts the profile block for the report, it returns nil if the block is nil.
+func toReportBlock(b *cover.ProfileBlock) *report.Block {
+	if b == nil {
+		return nil
+	}
+	return &report.Block{
+		StartLine: b.StartLine,
+		StartCol:  b.StartCol,
+		EndLine:   b.EndLine,
+		EndCol:    b.EndCol,
+		NumStmt:   b.NumStmt,
+		Count:     b.Count,
+	}
+}
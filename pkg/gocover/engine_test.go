@@ -0,0 +1,302 @@
+package gocover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/testresult"
+	"github.com/sirupsen/logrus"
+)
+
+func engineTestPackages(t *testing.T) parser.Packages {
+	file, err := filepath.Abs("engine.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parser.Packages{
+		{
+			Name: "github.com/Azure/gocover/pkg/gocover",
+			Functions: []*parser.Function{
+				{
+					Name:      "foo",
+					File:      file,
+					StartLine: 1,
+					EndLine:   10,
+					Statements: []*parser.Statement{
+						{StartLine: 2, State: parser.Changed, Mode: parser.Keep, Reached: 1},
+						{StartLine: 3, State: parser.Changed, Mode: parser.Keep},
+						{StartLine: 4, State: parser.Original, Mode: parser.Keep},
+						{StartLine: 5, State: parser.Changed, Mode: parser.Ignore, Reached: 1},
+					},
+				},
+				{
+					File:       file,
+					StartLine:  12,
+					EndLine:    14,
+					Statements: []*parser.Statement{{StartLine: 13, State: parser.Original, Mode: parser.Keep, Reached: 1}},
+				},
+			},
+		},
+	}
+}
+
+func newTestEngine(excludes []string) *coverageEngine {
+	return &coverageEngine{
+		modulePath:      "github.com/Azure/gocover",
+		excludeFiles:    make(excludeFileCache),
+		excludePatterns: excludes,
+		coverageTree:    report.NewCoverageTree("github.com/Azure/gocover"),
+		logger:          logrus.New(),
+	}
+}
+
+func TestCoverageEngine(t *testing.T) {
+	t.Run("changed statements", func(t *testing.T) {
+		statistics := &report.Statistics{}
+		if _, err := newTestEngine(nil).calculate(engineTestPackages(t), statistics, changedStatements); err != nil {
+			t.Fatal(err)
+		}
+		if len(statistics.CoverageProfile) != 1 {
+			t.Fatalf("expect 1 profile, but get %d", len(statistics.CoverageProfile))
+		}
+		p := statistics.CoverageProfile[0]
+		if !strings.HasSuffix(p.FileName, "engine.go") {
+			t.Errorf("unexpected file name %s", p.FileName)
+		}
+		if p.TotalLines != 3 || p.CoveredLines != 2 || p.TotalEffectiveLines != 2 || p.TotalIgnoredLines != 1 || p.CoveredButIgnoredLines != 1 {
+			t.Errorf("unexpected profile %+v", p)
+		}
+		if len(p.ViolationSections) != 1 || len(p.ViolationSections[0].Contents) != 10 || p.ViolationSections[0].ViolationLines[0] != 3 {
+			t.Errorf("unexpected violation sections %+v", p.ViolationSections)
+		}
+		if statistics.TotalViolationLines != 1 || statistics.TotalCoveragePercent != 50 {
+			t.Errorf("unexpected statistics %+v", statistics)
+		}
+		// the function without changed statements isn't listed.
+		if len(p.Functions) != 1 || *p.Functions[0] != (report.FunctionCoverage{
+			Name: "foo", StartLine: 1, EndLine: 10, TotalLines: 3, EffectiveLines: 2, CoveredLines: 2, CoveredButIgnoredLines: 1,
+		}) {
+			t.Errorf("unexpected functions %+v", p.Functions)
+		}
+	})
+
+	t.Run("all statements", func(t *testing.T) {
+		statistics := &report.Statistics{}
+		if _, err := newTestEngine(nil).calculate(engineTestPackages(t), statistics, allStatements); err != nil {
+			t.Fatal(err)
+		}
+		p := statistics.CoverageProfile[0]
+		if p.TotalLines != 5 || p.CoveredLines != 3 || p.TotalEffectiveLines != 4 {
+			t.Errorf("unexpected profile %+v", p)
+		}
+		if len(p.Functions) != 2 || p.Functions[1].StartLine != 12 || p.Functions[1].CoveredLines != 1 {
+			t.Errorf("unexpected functions %+v", p.Functions)
+		}
+		if len(p.ViolationSections) != 1 || len(p.TotalViolationLines) != 2 {
+			t.Errorf("unexpected violation sections %+v", p.ViolationSections)
+		}
+	})
+
+	t.Run("excluded", func(t *testing.T) {
+		statistics := &report.Statistics{}
+		if _, err := newTestEngine([]string{"**/engine.go"}).calculate(engineTestPackages(t), statistics, allStatements); err != nil {
+			t.Fatal(err)
+		}
+		if len(statistics.CoverageProfile) != 0 || len(statistics.ExcludeFiles) != 1 {
+			t.Errorf("engine.go should be excluded, but get %+v", statistics)
+		}
+	})
+}
+
+func TestCoverageEngineLimits(t *testing.T) {
+	t.Run("truncate oversized file", func(t *testing.T) {
+		engine := newTestEngine(nil)
+		engine.limits = Limits{MaxFileSize: 1, TruncationPolicy: SkipOversizedFiles}
+
+		statistics := &report.Statistics{}
+		if _, err := engine.calculate(engineTestPackages(t), statistics, allStatements); err != nil {
+			t.Fatal(err)
+		}
+		if len(statistics.CoverageProfile) != 0 {
+			t.Errorf("expect no profile, but get %d", len(statistics.CoverageProfile))
+		}
+		if len(statistics.TruncatedFiles) != 1 || !strings.HasSuffix(statistics.TruncatedFiles[0].FileName, "engine.go") {
+			t.Errorf("unexpected truncated files %+v", statistics.TruncatedFiles)
+		}
+	})
+
+	t.Run("fail on oversized file", func(t *testing.T) {
+		engine := newTestEngine(nil)
+		engine.limits = Limits{MaxFileSize: 1, TruncationPolicy: FailOnOversizedFiles}
+
+		_, err := engine.calculate(engineTestPackages(t), &report.Statistics{}, allStatements)
+		if !errors.Is(err, ErrFileTooLarge) {
+			t.Errorf("expect ErrFileTooLarge, but get %v", err)
+		}
+	})
+
+	t.Run("truncate section contents", func(t *testing.T) {
+		engine := newTestEngine(nil)
+		engine.limits = Limits{MaxSectionLines: 4}
+
+		statistics := &report.Statistics{}
+		if _, err := engine.calculate(engineTestPackages(t), statistics, changedStatements); err != nil {
+			t.Fatal(err)
+		}
+		section := statistics.CoverageProfile[0].ViolationSections[0]
+		if len(section.Contents) != 5 || section.TruncatedLines != 6 {
+			t.Errorf("unexpected section %+v", section)
+		}
+	})
+}
+
+func TestApplyTestResults(t *testing.T) {
+	statistics := &report.Statistics{
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/gocover/pkg/foo/foo.go"},
+			{FileName: "github.com/Azure/gocover/pkg/bar/bar.go"},
+		},
+	}
+	applyTestResults(statistics, []*testresult.Package{
+		{Name: "github.com/Azure/gocover/pkg/foo", Status: testresult.FailAction, Failed: 1, FailedTests: []string{"TestFoo"}},
+		{Name: "github.com/Azure/gocover/pkg/bar", Status: testresult.PassAction, Passed: 1},
+	})
+
+	if len(statistics.TestPackages) != 2 {
+		t.Errorf("expect 2 test packages, but get %d", len(statistics.TestPackages))
+	}
+	if !statistics.CoverageProfile[0].Unreliable {
+		t.Error("foo.go should be unreliable")
+	}
+	if statistics.CoverageProfile[1].Unreliable {
+		t.Error("bar.go should be reliable")
+	}
+}
+
+func TestFailedTestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	results := filepath.Join(dir, "test-results.json")
+	events := `{"Action":"fail","Package":"github.com/Azure/gocover/pkg/gocover","Test":"TestFoo"}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/gocover"}
+`
+	if err := os.WriteFile(results, []byte(events), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		for _, p := range []FailedTestPolicy{FailOnFailedTests, WarnOnFailedTests, ExcludeFailedTests} {
+			if err := p.Validate(); err != nil {
+				t.Errorf("%s should be valid", p)
+			}
+		}
+		if err := FailedTestPolicy("ignore").Validate(); !errors.Is(err, ErrUnknownFailedTestPolicy) {
+			t.Errorf("expect ErrUnknownFailedTestPolicy, but get %v", err)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		engine := newTestEngine(nil)
+		engine.testResults = []string{results}
+		engine.failedTestPolicy = WarnOnFailedTests
+		statistics := &report.Statistics{}
+		if _, err := engine.calculate(engineTestPackages(t), statistics, allStatements); err != nil {
+			t.Fatal(err)
+		}
+		if len(statistics.CoverageProfile) != 1 || !statistics.CoverageProfile[0].Unreliable {
+			t.Errorf("profile should be unreliable, but get %+v", statistics.CoverageProfile)
+		}
+		if err := checkFailedTests(statistics, WarnOnFailedTests, logrus.New()); err != nil {
+			t.Errorf("warn policy should not return error, but get %s", err)
+		}
+
+		err := checkFailedTests(statistics, FailOnFailedTests, logrus.New())
+		var e *GoCoverError
+		if !errors.As(err, &e) || e.ExitCode != UnitTestFailedErrorExitCode {
+			t.Errorf("fail policy should return unit test failed error, but get %v", err)
+		}
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		engine := newTestEngine(nil)
+		engine.testResults = []string{results}
+		engine.failedTestPolicy = ExcludeFailedTests
+		statistics := &report.Statistics{}
+		if _, err := engine.calculate(engineTestPackages(t), statistics, allStatements); err != nil {
+			t.Fatal(err)
+		}
+		if len(statistics.CoverageProfile) != 0 || len(statistics.ExcludeFiles) != 1 || statistics.TotalLines != 0 {
+			t.Errorf("profile should be excluded, but get %+v", statistics)
+		}
+		if len(statistics.TestPackages) != 1 {
+			t.Errorf("test results should be kept, but get %d", len(statistics.TestPackages))
+		}
+	})
+}
+
+func TestNonFatalErrors(t *testing.T) {
+	t.Run("missing tests", func(t *testing.T) {
+		statistics := &report.Statistics{
+			CoverageProfile: []*report.CoverageProfile{
+				{FileName: "github.com/Azure/gocover/pkg/foo/foo.go"},
+				{FileName: "github.com/Azure/gocover/pkg/foo/bar.go"},
+				{FileName: "github.com/Azure/gocover/pkg/bar/bar.go"},
+			},
+			TestPackages: []*testresult.Package{
+				{Name: "github.com/Azure/gocover/pkg/foo", Status: testresult.SkipAction},
+				{Name: "github.com/Azure/gocover/pkg/bar", Status: testresult.PassAction, Passed: 1},
+			},
+		}
+		addMissingTests(statistics, nil)
+
+		missing := statistics.Errors.ByKind(report.MissingTestsError)
+		if statistics.Errors.Len() != 1 || len(missing) != 1 || missing[0].Target != "github.com/Azure/gocover/pkg/foo" {
+			t.Errorf("expect missing tests of pkg/foo, but get %v", statistics.Errors)
+		}
+	})
+
+	t.Run("unmatched changes", func(t *testing.T) {
+		diff := &diffCover{
+			moduleDir:       "modulea",
+			modulePath:      "github.com/Azure/modulea",
+			excludePatterns: []string{"**/zz_generated.go"},
+			logger:          logrus.New(),
+		}
+		statistics := &report.Statistics{
+			TestPackages: []*testresult.Package{{Name: "github.com/Azure/modulea/pkg/bar", Status: testresult.SkipAction}},
+		}
+		diff.addUnmatchedChanges(statistics, []*gittool.Change{
+			{FileName: "modulea/pkg/foo/types.go"},
+			{FileName: "modulea/pkg/bar/bar.go"},
+			{FileName: "modulea/pkg/foo/zz_generated.go"},
+			{FileName: "moduleb/pkg/foo/foo.go"},
+		})
+
+		unmatched := statistics.Errors.ByKind(report.UnmatchedFileError)
+		if len(unmatched) != 1 || unmatched[0].Target != "github.com/Azure/modulea/pkg/foo/types.go" {
+			t.Errorf("unexpected unmatched files %v", statistics.Errors)
+		}
+		missing := statistics.Errors.ByKind(report.MissingTestsError)
+		if len(missing) != 1 || missing[0].Target != "github.com/Azure/modulea/pkg/bar/bar.go" {
+			t.Errorf("unexpected missing tests %v", statistics.Errors)
+		}
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		statistics := &report.Statistics{}
+		addParseErrors(statistics, []*parser.ParseError{
+			{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", Err: errors.New("comments required")},
+			{FileName: "github.com/Azure/gocover/pkg/foo/bar.go", Err: errors.New("expected ')'"), Skipped: true},
+		})
+
+		parseErrors := statistics.Errors.ByKind(report.ParseFailureError)
+		if len(parseErrors) != 2 || parseErrors[0].Message != "counted without annotations: comments required" || parseErrors[1].Message != "skipped: expected ')'" {
+			t.Errorf("unexpected parse errors %v", statistics.Errors)
+		}
+	})
+}
@@ -13,11 +13,15 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/testresult"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	outCoverageProfile = "coverage.out"
+	outTestResults     = "test-results.json"
 )
 
 type GoCoverTestExecutor interface {
@@ -30,6 +34,18 @@ func NewGoCoverTestExecutor(o *GoCoverTestOption) (GoCoverTestExecutor, error) {
 		return nil, fmt.Errorf("get absolute path of repo: %w", err)
 	}
 
+	// resolve the module path before running the tests, so the tests run in GOPATH mode if there is no go.mod.
+	if _, err := resolveModulePath(filepath.Join(repositoryAbsPath, o.ModuleDir), o.ModulePath, o.Logger); err != nil {
+		return nil, fmt.Errorf("resolve module path: %w", err)
+	}
+
+	if o.FailedTestPolicy == "" {
+		o.FailedTestPolicy = FailOnFailedTests
+	}
+	if err := o.FailedTestPolicy.Validate(); err != nil {
+		return nil, err
+	}
+
 	if o.OutputDir == "" {
 		dir, err := createGoCoverTempDirectory()
 		if err != nil {
@@ -38,6 +54,10 @@ func NewGoCoverTestExecutor(o *GoCoverTestOption) (GoCoverTestExecutor, error) {
 		o.OutputDir = dir
 	}
 
+	if o.ChangedPackages && (o.ExecutorMode != GoExecutor || o.CoverageMode != DiffCoverage) {
+		o.Logger.Warn("changed packages are only supported by go executor in diff coverage mode, the tests of all the packages run")
+	}
+
 	switch o.ExecutorMode {
 	case GoExecutor:
 		return &goBuiltInTestExecutor{
@@ -53,7 +73,9 @@ func NewGoCoverTestExecutor(o *GoCoverTestOption) (GoCoverTestExecutor, error) {
 			option:         o,
 		}, nil
 	case GinkgoExecutor:
-
+		if o.Progress.Enabled {
+			o.Logger.Warn("live progress is only supported by go executor, the test output is printed")
+		}
 		return &ginkgoTestExecutor{
 			repositoryPath: repositoryAbsPath,
 			flags:          o.GinkgoFlags,
@@ -96,26 +118,97 @@ func (t *goBuiltInTestExecutor) Run(ctx context.Context) error {
 	)
 
 	coverFile := filepath.Join(t.outputDir, outCoverageProfile)
-	testString := fmt.Sprintf("go test ./... -coverprofile %s -coverpkg=./... -v", coverFile)
+	resultFile := filepath.Join(t.outputDir, outTestResults)
 
-	cmd := exec.Command(t.executable, "test", "./...", "-coverprofile", coverFile, "-coverpkg=./...", "-v")
-	cmd.Dir = filepath.Join(t.repositoryPath, t.moduleDir)
-	cmd.Stdin = nil
-	cmd.Stdout = t.stdout
-	cmd.Stderr = t.stderr
+	packages := []string{"./..."}
+	if t.option.ChangedPackages && t.mode == DiffCoverage {
+		changed, err := moduleChangedFiles(t.repositoryPath, t.moduleDir, t.option.CompareBranch, t.option.DiffTarget, t.option.AgainstTag)
+		if err != nil {
+			return fmt.Errorf("select changed packages: %w", err)
+		}
+		packages = changedPackages(filepath.Join(t.repositoryPath, t.moduleDir), changed)
+		logger.Infof("changed packages: %s", strings.Join(packages, " "))
+	}
+	args := []string{"test"}
+	for _, flag := range t.flags {
+		if trimmed := strings.TrimSpace(flag); trimmed != "" {
+			args = append(args, trimmed)
+		}
+	}
+	args = append(args, packages...)
+	args = append(args, "-coverprofile", coverFile, "-coverpkg=./...", "-json")
+	testString := fmt.Sprintf("go %s", strings.Join(args, " "))
 
-	logger.Infof("run unit tests: '%s'", testString)
-	if err := cmd.Run(); err != nil {
-		t.logger.WithError(err).Errorf(`run unit test '%s'`, testString)
-		return WrapErrorWithCode(errors.New("unit test failed"), UnitTestFailedErrorExitCode, "")
+	f, err := atomicfile.Create(resultFile)
+	if err != nil {
+		return fmt.Errorf("create test results file: %w", err)
+	}
+	defer f.Abort()
+	// the events are recorded into the file, and the test output is printed as `go test -v` does,
+	// or the events are rendered as the live progress.
+	w := testresult.NewWriter(f, t.stdout)
+	var progress *testresult.Progress
+	if t.option.Progress.Enabled {
+		progress = testresult.NewProgress(t.stdout, t.option.Progress.Interactive)
+		w = testresult.NewWriter(io.MultiWriter(f, progress), nil)
 	}
 
-	gocover, err := buildGoCover(t.mode, t.option, []string{coverFile}, logger)
+	var runErr error
+	if len(packages) == 0 {
+		// no go files are changed, the diff coverage is calculated with an empty cover profile.
+		logger.Info("no changed packages, skip the unit tests")
+		if err := atomicfile.WriteFile(coverFile, func(w io.Writer) error {
+			_, err := io.WriteString(w, "mode: set\n")
+			return err
+		}); err != nil {
+			return fmt.Errorf("write cover profile: %w", err)
+		}
+	} else {
+		cmd := exec.CommandContext(ctx, t.executable, args...)
+		cmd.Dir = filepath.Join(t.repositoryPath, t.moduleDir)
+		cmd.Stdin = nil
+		cmd.Stdout = w
+		cmd.Stderr = t.stderr
+
+		logger.Infof("run unit tests: '%s'", testString)
+		runErr = cmd.Run()
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write test results: %w", err)
+	}
+	if err := f.Commit(); err != nil {
+		return fmt.Errorf("write test results: %w", err)
+	}
+	if progress != nil {
+		if err := progress.Close(); err != nil {
+			return fmt.Errorf("write test progress: %w", err)
+		}
+	}
+	if runErr != nil && ctx.Err() != nil {
+		// the tests are killed by the timeout or the cancellation, rather than failed.
+		return fmt.Errorf("run unit tests: %w", ctx.Err())
+	}
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if _, err := os.Stat(coverFile); !errors.As(runErr, &exitErr) || err != nil {
+			t.logger.WithError(runErr).Errorf(`run unit test '%s'`, testString)
+			return WrapErrorWithCode(errors.New("unit test failed"), UnitTestFailedErrorExitCode, "")
+		}
+		logger.WithError(runErr).Warnf("unit tests failed, failed test policy: %s", t.option.FailedTestPolicy)
+	}
+
+	option := *t.option
+	option.TestResults = append([]string{resultFile}, option.TestResults...)
+	if progress != nil {
+		option.ReportGenerators = append(append([]report.ReportGenerator(nil), option.ReportGenerators...), report.NewConsoleReportGenerator(t.stdout))
+	}
+
+	gocover, err := buildGoCover(t.mode, &option, []string{coverFile}, logger)
 	if err != nil {
 		return err
 	}
 
-	logger.Info("run unit test succeeded")
+	logger.Info("run unit test finished")
 	logger.Infof("cover profile: %s", coverFile)
 
 	if err := gocover.Run(ctx); err != nil {
@@ -182,26 +275,26 @@ func (e *ginkgoTestExecutor) Run(ctx context.Context) error {
 
 func mergeCoverProfiles(outputdir string, coverProfiles []string) (string, error) {
 	result := filepath.Join(outputdir, outCoverageProfile)
-	f, err := os.Create(result)
+	err := atomicfile.WriteFile(result, func(w io.Writer) error {
+		fmt.Fprint(w, "mode: atomic\n")
+		for _, c := range coverProfiles {
+			pf, err := os.Open(c)
+			if err != nil {
+				return err
+			}
+			s := bufio.NewScanner(pf)
+
+			s.Scan() // skip first line of cover profile because it's cover profile's metadata
+			for s.Scan() {
+				fmt.Fprintf(w, "%s\n", s.Text())
+			}
+			pf.Close()
+		}
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
-	defer f.Close()
-
-	fmt.Fprint(f, "mode: atomic\n")
-	for _, c := range coverProfiles {
-		pf, err := os.Open(c)
-		if err != nil {
-			return "", err
-		}
-		s := bufio.NewScanner(pf)
-
-		s.Scan() // skip first line of cover profile because it's cover profile's metadata
-		for s.Scan() {
-			fmt.Fprintf(f, "%s\n", s.Text())
-		}
-		pf.Close()
-	}
 
 	return result, nil
 }
@@ -218,7 +311,7 @@ func (executor *ginkgoTestExecutor) runTests(ctx context.Context) error {
 	buildString := fmt.Sprintf("%s %s", executor.executable, strings.Join(buildArgs, " "))
 
 	logger.Infof("executing cmd: %s", buildString)
-	buildCmd := exec.Command(executor.executable, buildArgs...)
+	buildCmd := exec.CommandContext(ctx, executor.executable, buildArgs...)
 	buildCmd.Dir = workingDir
 	buildCmd.Stdin = nil
 	buildCmd.Stdout = executor.stdout
@@ -239,7 +332,7 @@ func (executor *ginkgoTestExecutor) runTests(ctx context.Context) error {
 	runString := fmt.Sprintf("%s %s", executor.executable, strings.Join(ginkgoFlags, " "))
 
 	logger.Infof("executing cmd: %s", runString)
-	runCmd := exec.Command(executor.executable, ginkgoFlags...)
+	runCmd := exec.CommandContext(ctx, executor.executable, ginkgoFlags...)
 	runCmd.Dir = workingDir
 	runCmd.Stdin = nil
 	runCmd.Stdout = executor.stdout
@@ -308,35 +401,112 @@ func buildGoCover(
 	switch mode {
 	case FullCoverage:
 		return NewFullCover(&FullOption{
-			CoverProfiles:    coverProfiles,
-			RepositoryPath:   option.RepositoryPath,
-			ModuleDir:        option.ModuleDir,
-			CoverageBaseline: option.CoverageBaseline,
-			ReportFormat:     option.ReportFormat,
-			ReportName:       option.ReportName,
-			OutputDir:        option.OutputDir,
-			Excludes:         option.Excludes,
-			Style:            option.Style,
-			DbOption:         option.DbOption,
-			Logger:           logger,
+			CoverProfiles:         coverProfiles,
+			RepositoryPath:        option.RepositoryPath,
+			ModuleDir:             option.ModuleDir,
+			ModulePath:            option.ModulePath,
+			CoverageBaseline:      option.CoverageBaseline,
+			ReportFormat:          option.ReportFormat,
+			ReportName:            option.ReportName,
+			OutputDir:             option.OutputDir,
+			Excludes:              option.Excludes,
+			SkipListFile:          option.SkipListFile,
+			Style:                 option.Style,
+			Verbose:               option.Verbose,
+			ArtifactsDir:          option.ArtifactsDir,
+			JSONOutput:            option.JSONOutput,
+			SARIF:                 option.SARIF,
+			SonarQubePathMappings: option.SonarQubePathMappings,
+			Theme:                 option.Theme,
+			TestResults:           option.TestResults,
+			FailedTestPolicy:      option.FailedTestPolicy,
+			Limits:                option.Limits,
+			StrictParse:           option.StrictParse,
+			Layers:                option.Layers,
+			PackageBaselines:      option.PackageBaselines,
+			GracePeriods:          option.GracePeriods,
+			FileBaseline:          option.FileBaseline,
+			GitAttributes:         option.GitAttributes,
+			Ratchet:               option.Ratchet,
+			TestHelpers:           option.TestHelpers,
+			IgnoreProviders:       option.IgnoreProviders,
+			Shard:                 option.Shard,
+			DbOption:              option.DbOption,
+			AuditFile:             option.AuditFile,
+			ReportGenerators:      option.ReportGenerators,
+			Annotators:            option.Annotators,
+			Logger:                logger,
 		})
 	case DiffCoverage:
 		return NewDiffCover(&DiffOption{
-			CoverProfiles:    coverProfiles,
-			CompareBranch:    option.CompareBranch,
-			RepositoryPath:   option.RepositoryPath,
-			ModuleDir:        option.ModuleDir,
-			ModulePath:       option.ModuleDir,
-			CoverageBaseline: option.CoverageBaseline,
-			ReportFormat:     option.ReportFormat,
-			ReportName:       option.ReportName,
-			OutputDir:        option.OutputDir,
-			Excludes:         option.Excludes,
-			Style:            option.Style,
-			DbOption:         option.DbOption,
-			Logger:           logger,
+			CoverProfiles:         coverProfiles,
+			CompareBranch:         option.CompareBranch,
+			DiffTarget:            option.DiffTarget,
+			AgainstTag:            option.AgainstTag,
+			RepositoryPath:        option.RepositoryPath,
+			ModuleDir:             option.ModuleDir,
+			ModulePath:            option.ModulePath,
+			CoverageBaseline:      option.CoverageBaseline,
+			ReportFormat:          option.ReportFormat,
+			ReportName:            option.ReportName,
+			OutputDir:             option.OutputDir,
+			Excludes:              option.Excludes,
+			SkipListFile:          option.SkipListFile,
+			Style:                 option.Style,
+			Verbose:               option.Verbose,
+			ArtifactsDir:          option.ArtifactsDir,
+			JSONOutput:            option.JSONOutput,
+			SARIF:                 option.SARIF,
+			SonarQubePathMappings: option.SonarQubePathMappings,
+			Theme:                 option.Theme,
+			TestResults:           option.TestResults,
+			FailedTestPolicy:      option.FailedTestPolicy,
+			Limits:                option.Limits,
+			StrictParse:           option.StrictParse,
+			Layers:                option.Layers,
+			PackageBaselines:      option.PackageBaselines,
+			GracePeriods:          option.GracePeriods,
+			FileBaseline:          option.FileBaseline,
+			GitAttributes:         option.GitAttributes,
+			TestHelpers:           option.TestHelpers,
+			IgnoreProviders:       option.IgnoreProviders,
+			Shard:                 option.Shard,
+			Todos:                 option.Todos,
+			LineMapping:           option.LineMapping,
+			DiffView:              option.DiffView,
+			Hunks:                 hunkOption(option),
+			DiffAlgorithm:         option.DiffAlgorithm,
+			DiffContext:           option.DiffContext,
+			Regression:            regressionOption(option),
+			Rollout:               option.Rollout,
+			Bypass:                option.Bypass,
+			DbOption:              option.DbOption,
+			AuditFile:             option.AuditFile,
+			ReportGenerators:      option.ReportGenerators,
+			Annotators:            option.Annotators,
+			Logger:                logger,
 		})
 	default:
 		return nil, ErrUnknownCoverageMode
 	}
 }
+
+// regressionOption returns the regression option of the diff coverage, the tests of the compared branch run
+// with the go flags of the test command if no go flags are set for them.
+func regressionOption(option *GoCoverTestOption) RegressionOption {
+	regression := option.Regression
+	if len(regression.GoFlags) == 0 {
+		regression.GoFlags = option.GoFlags
+	}
+	return regression
+}
+
+// hunkOption returns the hunk option of the diff coverage, the hunks are written to the stdout of the test command
+// if no output is set for them.
+func hunkOption(option *GoCoverTestOption) HunkOption {
+	hunks := option.Hunks
+	if hunks.Output == nil {
+		hunks.Output = option.StdOut
+	}
+	return hunks
+}
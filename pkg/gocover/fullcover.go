@@ -3,13 +3,15 @@ package gocover
 import (
 	"context"
 	"fmt"
-	"go/build"
+	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/Azure/gocover/pkg/annotation"
+	"github.com/Azure/gocover/pkg/audit"
 	"github.com/Azure/gocover/pkg/dbclient"
 	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/phase"
 	"github.com/Azure/gocover/pkg/report"
 	"github.com/sirupsen/logrus"
 )
@@ -33,30 +35,121 @@ func NewFullCover(o *FullOption) (GoCover, error) {
 		}
 	}
 
+	policy := o.FailedTestPolicy
+	if policy == "" {
+		policy = WarnOnFailedTests
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	shard, err := ParseShard(o.Shard)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.Limits.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.SARIF.Validate(); err != nil {
+		return nil, err
+	}
+	theme, err := o.Theme.Load()
+	if err != nil {
+		return nil, err
+	}
+	pathMappings, err := report.ParsePathMappings(o.SonarQubePathMappings)
+	if err != nil {
+		return nil, err
+	}
+	skipList, err := newSkipList(o.SkipListFile, time.Now(), logger)
+	if err != nil {
+		return nil, err
+	}
+	ratchet, err := o.Ratchet.load()
+	if err != nil {
+		return nil, err
+	}
+
 	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
 	if err != nil {
 		return nil, fmt.Errorf("get absolute path of repo: %w", err)
 	}
 
-	modulePath, err := parseGoModulePath(filepath.Join(repositoryAbsPath, o.ModuleDir))
+	modulePath, err := resolveModulePath(filepath.Join(repositoryAbsPath, o.ModuleDir), o.ModulePath, logger)
 	if err != nil {
-		return nil, fmt.Errorf("parse go module path: %w", err)
+		return nil, fmt.Errorf("resolve module path: %w", err)
 	}
 
 	logger.Debugf("repository path: %s, module path: %s, output dir: %s, exclude patterns: %s",
 		repositoryAbsPath, modulePath, o.OutputDir, o.Excludes)
 
+	coverageTree := report.NewCoverageTree(modulePath)
+	extra := o.ReportGenerators
+	if o.JSONOutput != "" {
+		document := report.NewDocumentGenerator(o.JSONOutput, os.Stdout, coverageTree, o.Verbose, o.Logger)
+		extra = append([]report.ReportGenerator{document}, extra...)
+	}
+	generators := newReportGenerators(&reportGeneratorOption{
+		format:           o.ReportFormat,
+		style:            o.Style,
+		outputDir:        o.OutputDir,
+		reportName:       o.ReportName,
+		verbose:          o.Verbose,
+		modulePath:       modulePath,
+		moduleDir:        filepath.Join(repositoryAbsPath, o.ModuleDir),
+		contextLines:     report.DefaultContextLines,
+		coverageBaseline: o.CoverageBaseline,
+		theme:            theme,
+		sarif: &report.SARIFReportOption{
+			ModulePath:    modulePath,
+			ModuleDir:     o.ModuleDir,
+			SARIFSettings: o.SARIF,
+		},
+		sonarQube: &report.SonarQubeReportOption{
+			ModulePath:   modulePath,
+			ModuleDir:    o.ModuleDir,
+			PathMappings: pathMappings,
+		},
+		artifacts: &report.ArtifactsOption{
+			OutputDir:        o.ArtifactsDir,
+			Style:            o.Style,
+			Verbose:          o.Verbose,
+			ModulePath:       modulePath,
+			CoverageBaseline: o.CoverageBaseline,
+			Theme:            theme,
+		},
+		extra:  extra,
+		logger: o.Logger,
+	})
+
 	return &fullCover{
-		coverFilenames:  o.CoverProfiles,
-		modulePath:      modulePath,
-		repositoryPath:  repositoryAbsPath,
-		excludeFiles:    make(excludeFileCache),
-		excludePatterns: o.Excludes,
-		moduleDir:       o.ModuleDir,
-		coverageTree:    report.NewCoverageTree(modulePath),
-		logger:          logger,
-		dbClient:        dbClient,
-		reportGenerator: report.NewReportGenerator(o.Style, o.OutputDir, o.ReportName, o.Logger),
+		coverFilenames:   o.CoverProfiles,
+		testResults:      o.TestResults,
+		failedTestPolicy: policy,
+		limits:           o.Limits,
+		strictParse:      o.StrictParse,
+		layers:           o.Layers,
+		packageBaselines: o.PackageBaselines,
+		gracePeriods:     activeGracePeriods(o.GracePeriods, time.Now(), logger),
+		fileBaseline:     o.FileBaseline,
+		gitAttributes:    loadGitAttributes(o.GitAttributes, repositoryAbsPath, logger),
+		ratchet:          ratchet,
+		shard:            shard,
+		testHelpers:      newTestHelpers(o.TestHelpers, filepath.Join(repositoryAbsPath, o.ModuleDir), modulePath, logger),
+		ignoreProviders:  o.IgnoreProviders,
+		modulePath:       modulePath,
+		repositoryPath:   repositoryAbsPath,
+		excludeFiles:     make(excludeFileCache),
+		excludePatterns:  o.Excludes,
+		skipList:         skipList,
+		moduleDir:        o.ModuleDir,
+		coverageTree:     coverageTree,
+		logger:           logger,
+		dbClient:         dbClient,
+		dbOption:         o.DbOption,
+		auditRecorder:    audit.NewRecorder(o.AuditFile),
+		recorder:         phase.NewRecorder(),
+		reportGenerators: generators,
+		annotators:       o.Annotators,
 	}, nil
 
 }
@@ -65,36 +158,65 @@ var _ GoCover = (*fullCover)(nil)
 
 // diffCoverage implements the GoCover interface and generate the full coverage statistics.
 type fullCover struct {
-	coverFilenames  []string
-	moduleDir       string
-	modulePath      string
-	repositoryPath  string
-	excludePatterns []string
-	ignoreProfiles  []*annotation.IgnoreProfile
-	excludeFiles    excludeFileCache
-	coverageTree    report.CoverageTree
-	reportGenerator report.ReportGenerator
-	dbClient        dbclient.DbClient
+	coverFilenames   []string
+	testResults      []string
+	failedTestPolicy FailedTestPolicy
+	limits           Limits
+	strictParse      bool
+	layers           []Layer
+	packageBaselines []PackageBaseline
+	gracePeriods     []GracePeriod
+	fileBaseline     float64
+	gitAttributes    *gitAttributes
+	ratchet          *ratchet
+	testHelpers      *testHelpers
+	shard            *report.Shard
+	ignoreProviders  []annotation.IgnoreProvider
+	moduleDir        string
+	modulePath       string
+	repositoryPath   string
+	excludePatterns  []string
+	skipList         *skipList
+	ignoreProfiles   []*annotation.IgnoreProfile
+	excludeFiles     excludeFileCache
+	coverageTree     report.CoverageTree
+	reportGenerators []report.ReportGenerator
+	annotators       []report.Annotator
+	dbClient         dbclient.DbClient
+	dbOption         *dbclient.DBOption
+	auditRecorder    audit.Recorder
+	recorder         *phase.Recorder
 
 	logger logrus.FieldLogger
 }
 
-func (full *fullCover) Run(ctx context.Context) error {
+func (full *fullCover) Run(ctx context.Context) (err error) {
+	defer func() {
+		if flushErr := full.auditRecorder.Flush(); flushErr != nil && err == nil {
+			err = fmt.Errorf("audit: %w", flushErr)
+		}
+	}()
 
 	statistics, err := full.generateStatistics()
 	if err != nil {
 		return fmt.Errorf("full: %w", err)
 	}
+	// the full coverage has no baseline, only the layers are gated.
+	decideGate(statistics, 0)
 
-	if err := full.reportGenerator.GenerateReport(statistics); err != nil {
-		return fmt.Errorf("generate report: %w", err)
+	if err := generateReports(full.annotators, full.reportGenerators, statistics, full.recorder, full.logger); err != nil {
+		return err
 	}
 
 	if err := full.dump(ctx); err != nil {
 		return fmt.Errorf("%w", err)
 	}
 
-	return nil
+	if err := checkFailedTests(statistics, full.failedTestPolicy, full.logger); err != nil {
+		return err
+	}
+
+	return checkGate(statistics, 0)
 }
 
 func (full *fullCover) dump(ctx context.Context) error {
@@ -102,10 +224,12 @@ func (full *fullCover) dump(ctx context.Context) error {
 
 	if full.dbClient != nil {
 		err := storeCoverageData(ctx, full.dbClient, all, FullCoverage, full.modulePath)
+		full.auditRecorder.Record(audit.NewAction(audit.UploadAction, full.dbOption.CoverageTarget(), "", err))
 		if err != nil {
 			return fmt.Errorf("store coverage data: %w", err)
 		}
 		err = storeIgnoreProfileData(ctx, full.dbClient, full.ignoreProfiles, FullCoverage, full.modulePath, full.repositoryPath, full.moduleDir)
+		full.auditRecorder.Record(audit.NewAction(audit.UploadAction, full.dbOption.IgnoreTarget(), "", err))
 		if err != nil {
 			return fmt.Errorf("store ignore profile data: %w", err)
 		}
@@ -116,7 +240,10 @@ func (full *fullCover) dump(ctx context.Context) error {
 }
 
 func (full *fullCover) generateStatistics() (*report.Statistics, error) {
-	packages, err := parser.NewParser(full.coverFilenames, full.logger).Parse(nil)
+	stopParse := full.recorder.Start(phase.ParsePhase)
+	p := parser.NewParser(full.coverFilenames, full.logger).WithRecorder(full.recorder).WithStrict(full.strictParse).WithIgnoreProviders(full.ignoreProviders...)
+	packages, err := p.Parse(nil)
+	stopParse()
 	if err != nil {
 		return nil, err
 	}
@@ -124,97 +251,32 @@ func (full *fullCover) generateStatistics() (*report.Statistics, error) {
 	statistics := &report.Statistics{
 		StatisticsType: report.FullStatisticsType,
 	}
-	m := make(map[string]*report.CoverageProfile)
-	fileCache := make(fileContentsCache)
-	for _, pkg := range packages {
-		full.logger.Debugf("package: %s", pkg.Name)
-		full.ignoreProfiles = append(full.ignoreProfiles, pkg.IgnoreProfiles...)
-
-		p, err := build.Import(pkg.Name, ".", build.FindOnly)
-		if err != nil {
-			return nil, fmt.Errorf("build import %w", err)
-		}
-
-		for _, fun := range pkg.Functions {
-
-			if ok := inExclueds(
-				full.excludeFiles,
-				full.excludePatterns,
-				formatFilePath(p.Root, fun.File, full.modulePath),
-				full.logger,
-			); ok {
-				continue
-			}
-
-			// extract into single function
-			coverProfile, ok := m[fun.File]
-			if !ok {
-				coverProfile = &report.CoverageProfile{
-					FileName: formatFilePath(p.Root, fun.File, full.modulePath),
-				}
-				m[fun.File] = coverProfile
-				statistics.CoverageProfile = append(statistics.CoverageProfile, coverProfile)
-			}
-
-			fileContents, err := findFileContents(fileCache, fun.File)
-			if err != nil {
-				return nil, fmt.Errorf("find file contents: %w", err)
-			}
-
-			section := &report.ViolationSection{
-				StartLine: fun.StartLine,
-				EndLine:   fun.EndLine,
-			}
-
-			for i := fun.StartLine; i <= fun.EndLine; i++ {
-				section.Contents = append(section.Contents, fileContents[i-1])
-			}
-
-			node := full.coverageTree.FindOrCreate(strings.TrimPrefix(fun.File, p.Root))
-
-			var total, ignored, covered, coveredButIgnored int
-			violated := false
-			for _, st := range fun.Statements {
-				total += 1
-				node.TotalLines += 1
-
-				if st.Mode == parser.Ignore && st.Reached > 0 {
-					coveredButIgnored++
-					node.TotalCoveredButIgnoreLines += 1
-				}
-				if st.Mode == parser.Ignore {
-					full.logger.Debugf("%s ignore line %d", fun.File, st.StartLine)
-					ignored++
-					node.TotalIgnoredLines += 1
-				}
-				if st.Reached > 0 {
-					node.TotalCoveredLines += 1
-					covered++
-				} else {
-					section.ViolationLines = append(section.ViolationLines, st.StartLine)
-					violated = true
-				}
-
-			}
-
-			node.TotalEffectiveLines = node.TotalLines - node.TotalIgnoredLines
-
-			coverProfile.TotalLines += total
-			coverProfile.CoveredLines += covered
-			coverProfile.CoveredButIgnoredLines += coveredButIgnored
-			coverProfile.TotalEffectiveLines += (total - ignored)
-			coverProfile.TotalIgnoredLines += ignored
-			coverProfile.TotalViolationLines = append(coverProfile.TotalViolationLines, section.ViolationLines...)
-			if violated {
-				coverProfile.ViolationSections = append(coverProfile.ViolationSections, section)
-			}
-		}
-
+	addParseErrors(statistics, p.ParseErrors())
+	engine := &coverageEngine{
+		modulePath:       full.modulePath,
+		excludeFiles:     full.excludeFiles,
+		excludePatterns:  full.excludePatterns,
+		skipList:         full.skipList,
+		coverageTree:     full.coverageTree,
+		testResults:      full.testResults,
+		failedTestPolicy: full.failedTestPolicy,
+		limits:           full.limits,
+		layers:           full.layers,
+		packageBaselines: full.packageBaselines,
+		gracePeriods:     full.gracePeriods,
+		fileBaseline:     full.fileBaseline,
+		gitAttributes:    full.gitAttributes,
+		ratchet:          full.ratchet,
+		testHelpers:      full.testHelpers,
+		shard:            full.shard,
+		logger:           full.logger,
 	}
-
-	full.coverageTree.CollectCoverageData()
-
-	reBuildStatistics(statistics, full.excludeFiles)
-
+	stopCompute := full.recorder.Start(phase.ComputePhase)
+	full.ignoreProfiles, err = engine.calculate(packages, statistics, allStatements)
+	stopCompute()
+	if err != nil {
+		return nil, err
+	}
+	statistics.TestHelperPackages = full.testHelpers.packages()
 	return statistics, nil
 }
@@ -14,6 +14,8 @@ import (
 
 	"github.com/Azure/gocover/pkg/annotation"
 	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/phase"
 	"github.com/Azure/gocover/pkg/report"
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/sirupsen/logrus"
@@ -21,7 +23,42 @@ import (
 )
 
 const (
-	DefaultReportFormat     = "html"
+	HTMLReportFormat = "html"
+	JSONReportFormat = "json"
+	// AnnotatedReportFormat is the html report that renders the source of each file with the state of every line.
+	AnnotatedReportFormat = "annotated"
+	// MarkdownReportFormat is the markdown summary that can be pasted into a pull request comment.
+	MarkdownReportFormat = "markdown"
+	// CoberturaReportFormat is the cobertura xml report that the CI systems render on the diff view.
+	CoberturaReportFormat = "cobertura"
+	// LCOVReportFormat is the lcov tracefile that genhtml and the coverage gutters of the editors consume.
+	LCOVReportFormat = "lcov"
+	// SARIFReportFormat is the sarif log of the uncovered lines that GitHub code scanning shows.
+	SARIFReportFormat = "sarif"
+	// JUnitReportFormat is the junit xml report that each file below the baseline is a failed test case.
+	JUnitReportFormat = "junit"
+	// CSVReportFormat is the csv report of the counted lines with their authors for the data warehouses.
+	CSVReportFormat = "csv"
+	// FileCSVReportFormat is the csv report of the line counts and the coverage of each file for the spreadsheets.
+	FileCSVReportFormat = "csv-files"
+	// ParquetReportFormat is the parquet file of the rows of the csv report for the data warehouses.
+	ParquetReportFormat = "parquet"
+	// SonarQubeReportFormat is the generic coverage xml report that sonarqube imports.
+	SonarQubeReportFormat = "sonarqube"
+	// FuncReportFormat is the text report of the coverage of each function that has counted lines, as `go tool cover -func`.
+	FuncReportFormat = "func"
+	// SPDXReportFormat is the spdx document of the coverage and the gate status of each package for the compliance evidence.
+	SPDXReportFormat = "spdx"
+)
+
+// ReportFormats are the formats of the coverage reports that --format accepts.
+var ReportFormats = []string{
+	HTMLReportFormat, JSONReportFormat, AnnotatedReportFormat, MarkdownReportFormat, CoberturaReportFormat, LCOVReportFormat, SARIFReportFormat,
+	JUnitReportFormat, CSVReportFormat, FileCSVReportFormat, ParquetReportFormat, SonarQubeReportFormat, FuncReportFormat, SPDXReportFormat,
+}
+
+const (
+	DefaultReportFormat     = HTMLReportFormat
 	DefaultCompareBranch    = "origin/master"
 	DefaultCoverageBaseline = 80.0
 )
@@ -57,14 +94,18 @@ func calculateCoverage(covered int64, effectived int64) float64 {
 	return float64(covered) / float64(effectived) * 100
 }
 
-// reBuildStatistics rebuild fields of Statistics from its CoverageProfile
+// reBuildStatistics rebuild fields of Statistics from its CoverageProfile, the files in a grace period are not counted
 func reBuildStatistics(s *report.Statistics, cache excludeFileCache) {
 	for _, p := range s.CoverageProfile {
+		if !enforced(p) {
+			continue
+		}
 		s.TotalLines += p.TotalLines
 		s.TotalEffectiveLines += p.TotalEffectiveLines
 		s.TotalIgnoredLines += p.TotalIgnoredLines
 		s.TotalCoveredLines += p.CoveredLines
 		s.TotalCoveredButIgnoredLines += p.CoveredButIgnoredLines
+		s.TotalViolationLines += len(p.TotalViolationLines)
 	}
 
 	s.TotalCoveragePercent = calculateCoverage(
@@ -81,6 +122,166 @@ func reBuildStatistics(s *report.Statistics, cache excludeFileCache) {
 	}
 }
 
+// reportGeneratorOption is the input of the report generators of a run.
+type reportGeneratorOption struct {
+	format     string
+	style      string
+	outputDir  string
+	reportName string
+	verbose    bool
+	modulePath string
+	// moduleDir is the absolute module directory that the annotated report reads the source files in.
+	moduleDir string
+	// contextLines is the lines around the changed lines that the annotated report shows.
+	contextLines     int
+	coverageBaseline float64
+	// theme renders the html and the annotated reports.
+	theme     *report.Theme
+	sarif     *report.SARIFReportOption
+	sonarQube *report.SonarQubeReportOption
+	// artifacts generates the artifacts of the run if its output directory is set, they're not generated if it's nil.
+	artifacts *report.ArtifactsOption
+	// extra are the report generators appended after the others.
+	extra  []report.ReportGenerator
+	logger logrus.FieldLogger
+}
+
+// newReportGenerator creates the report generator of the report format, html report is generated if the format is unknown.
+// The annotated report reads the source files in the module directory, and the sarif and the sonarqube reports
+// are decided by their options.
+func newReportGenerator(o *reportGeneratorOption) report.ReportGenerator {
+	switch o.format {
+	case JSONReportFormat:
+		return report.NewJSONReportGenerator(o.outputDir, o.reportName, o.verbose, o.logger)
+	case AnnotatedReportFormat:
+		return report.NewAnnotatedReportGenerator(&report.AnnotatedReportOption{
+			OutputDir:    o.outputDir,
+			ReportName:   o.reportName,
+			Style:        o.style,
+			ModulePath:   o.modulePath,
+			ModuleDir:    o.moduleDir,
+			ContextLines: o.contextLines,
+			Theme:        o.theme,
+		}, o.logger)
+	case CoberturaReportFormat:
+		return report.NewCoberturaReportGenerator(&report.CoberturaReportOption{
+			OutputDir:  o.outputDir,
+			ReportName: o.reportName,
+			ModulePath: o.modulePath,
+			ModuleDir:  o.moduleDir,
+		}, o.logger)
+	case LCOVReportFormat:
+		return report.NewLCOVReportGenerator(&report.LCOVReportOption{
+			OutputDir:  o.outputDir,
+			ReportName: o.reportName,
+			ModulePath: o.modulePath,
+			ModuleDir:  o.moduleDir,
+		}, o.logger)
+	case CSVReportFormat:
+		return report.NewCSVReportGenerator(&report.CSVReportOption{
+			OutputDir:  o.outputDir,
+			ReportName: o.reportName,
+			Blamer:     newGitBlamer(o.modulePath, o.moduleDir, o.logger),
+		}, o.logger)
+	case ParquetReportFormat:
+		return report.NewParquetReportGenerator(&report.CSVReportOption{
+			OutputDir:  o.outputDir,
+			ReportName: o.reportName,
+			Blamer:     newGitBlamer(o.modulePath, o.moduleDir, o.logger),
+		}, o.logger)
+	case FileCSVReportFormat:
+		return report.NewFileCSVReportGenerator(o.outputDir, o.reportName, o.logger)
+	case FuncReportFormat:
+		return report.NewFuncReportGenerator(o.outputDir, o.reportName, o.logger)
+	case SARIFReportFormat:
+		sarif := *o.sarif
+		sarif.OutputDir = o.outputDir
+		sarif.ReportName = o.reportName
+		return report.NewSARIFReportGenerator(&sarif, o.logger)
+	case SonarQubeReportFormat:
+		sonarQube := *o.sonarQube
+		sonarQube.OutputDir = o.outputDir
+		sonarQube.ReportName = o.reportName
+		return report.NewSonarQubeReportGenerator(&sonarQube, o.logger)
+	case JUnitReportFormat:
+		return report.NewJUnitReportGenerator(&report.JUnitReportOption{
+			OutputDir:        o.outputDir,
+			ReportName:       o.reportName,
+			CoverageBaseline: o.coverageBaseline,
+		}, o.logger)
+	case SPDXReportFormat:
+		return report.NewSPDXReportGenerator(&report.SPDXReportOption{
+			OutputDir:        o.outputDir,
+			ReportName:       o.reportName,
+			ModulePath:       o.modulePath,
+			CoverageBaseline: o.coverageBaseline,
+		}, o.logger)
+	case MarkdownReportFormat:
+		return report.NewMarkdownReportGenerator(&report.MarkdownReportOption{
+			OutputDir:        o.outputDir,
+			ReportName:       o.reportName,
+			CoverageBaseline: o.coverageBaseline,
+		}, o.logger)
+	default:
+		return report.NewThemedReportGenerator(o.style, o.outputDir, o.reportName, o.theme, o.logger)
+	}
+}
+
+// newReportGenerators creates the report generator of the report format, the artifacts generator if the artifacts directory is set,
+// and appends the extra report generators.
+func newReportGenerators(o *reportGeneratorOption) []report.ReportGenerator {
+	generators := []report.ReportGenerator{newReportGenerator(o)}
+	if o.artifacts != nil && o.artifacts.OutputDir != "" {
+		generators = append(generators, report.NewArtifactsGenerator(o.artifacts, o.logger))
+	}
+	return append(generators, o.extra...)
+}
+
+// generateReports runs the annotators and then the report generators in the report phase, the statistics contains the phases
+// finished before it, and all the phases are logged after the reports are generated.
+func generateReports(annotators []report.Annotator, generators []report.ReportGenerator, statistics *report.Statistics, recorder *phase.Recorder, logger logrus.FieldLogger) error {
+	statistics.Phases = recorder.Phases()
+
+	stop := recorder.Start(phase.ReportPhase)
+	for _, a := range annotators {
+		if err := a.Annotate(statistics); err != nil {
+			stop()
+			return fmt.Errorf("annotate statistics: %w", err)
+		}
+	}
+	for _, g := range generators {
+		if err := g.GenerateReport(statistics); err != nil {
+			stop()
+			return fmt.Errorf("generate report: %w", err)
+		}
+	}
+	stop()
+
+	for _, p := range recorder.Phases() {
+		logger.Debugf("phase %s: wall time %.1fms, cpu time %.1fms, peak rss %d bytes", p.Name, p.WallTimeMs, p.CPUTimeMs, p.PeakRSSBytes)
+	}
+	return nil
+}
+
+// nonFatalErrors returns the non-fatal errors of the statistics, it's created if not exist.
+func nonFatalErrors(statistics *report.Statistics) *report.MultiError {
+	if statistics.Errors == nil {
+		statistics.Errors = &report.MultiError{}
+	}
+	return statistics.Errors
+}
+
+// addParseErrors adds the parse errors to the non-fatal errors of the statistics.
+func addParseErrors(statistics *report.Statistics, parseErrors []*parser.ParseError) {
+	for _, e := range parseErrors {
+		treatment := "counted without annotations"
+		if e.Skipped {
+			treatment = "skipped"
+		}
+		nonFatalErrors(statistics).Add(report.ParseFailureError, e.FileName, "%s: %s", treatment, e.Err)
+	}
+}
+
 // formatFilePath format filename that strip root path and adds module path
 // fileNamePath is the absolute path of the file, modulePath is the module path of go module
 // for example:
@@ -121,6 +322,18 @@ func storeCoverageData(ctx context.Context, dbClient dbclient.DbClient, all []*r
 	return dbClient.StoreCoverageDataFromFile(ctx, data)
 }
 
+func storeBypassData(ctx context.Context, dbClient dbclient.DbClient, bypass *report.Bypass, coverage, baseline float64, modulePath string) error {
+	return dbClient.StoreBypassData(ctx, &dbclient.BypassData{
+		PreciseTimestamp: time.Now().UTC(),
+		ModulePath:       modulePath,
+		Source:           bypass.Source,
+		Reason:           bypass.Reason,
+		Actor:            bypass.Actor,
+		Coverage:         coverage,
+		Baseline:         baseline,
+	})
+}
+
 func storeIgnoreProfileData(ctx context.Context, dbClient dbclient.DbClient, ignoreProfiles []*annotation.IgnoreProfile, coverageMode CoverageMode, modulePath string, repositoryPath string, moduleDir string) error {
 	now := time.Now().UTC()
 
@@ -188,6 +188,7 @@ type mockDbClient struct {
 	storeIgnoreProfileDataFn         func(ctx context.Context, data *dbclient.IgnoreProfileData) error
 	storeCoverageDataFromFileFn      func(ctx context.Context, data []*dbclient.CoverageData) error
 	storeIgnoreProfileDataFromFileFn func(ctx context.Context, data []*dbclient.IgnoreProfileData) error
+	storeBypassDataFn                func(ctx context.Context, data *dbclient.BypassData) error
 }
 
 func (client *mockDbClient) StoreCoverageData(context context.Context, data *dbclient.CoverageData) error {
@@ -206,6 +207,10 @@ func (client *mockDbClient) StoreIgnoreProfileDataFromFile(ctx context.Context,
 	return client.storeIgnoreProfileDataFromFileFn(ctx, data)
 }
 
+func (client *mockDbClient) StoreBypassData(ctx context.Context, data *dbclient.BypassData) error {
+	return client.storeBypassDataFn(ctx, data)
+}
+
 func TestStore(t *testing.T) {
 	t.Run("store successfully", func(t *testing.T) {
 		client := &mockDbClient{
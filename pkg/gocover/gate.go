@@ -0,0 +1,74 @@
+package gocover
+
+import (
+	"errors"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// evaluateGate evaluates the coverage gate on the statistics, the files, the regressions of the touched files, the layers, the packages and the added comments
+// are gated by their own baselines and limits in the statistics.
+func evaluateGate(statistics *report.Statistics, coverageBaseline float64) *covergate.Decision {
+	stats := &covergate.Stats{CoveragePercent: statistics.TotalCoveragePercent, Bypassed: statistics.Bypass != nil}
+	policy := covergate.NewPolicy(coverageBaseline)
+	if statistics.FileBaseline > 0 {
+		policy.FileBaseline = statistics.FileBaseline
+		for _, p := range statistics.CoverageProfile {
+			if !enforced(p) {
+				continue
+			}
+			stats.Files = append(stats.Files, &covergate.File{
+				Name:            p.FileName,
+				CoveragePercent: calculateCoverage(int64(p.CoveredLines-p.CoveredButIgnoredLines), int64(p.TotalEffectiveLines)),
+			})
+		}
+	}
+	if r := statistics.Regression; r != nil {
+		policy.MaxRegression = r.Tolerance
+		for _, f := range r.Files {
+			stats.Regressions = append(stats.Regressions, &covergate.Regression{Name: f.FileName, BaseCoveragePercent: f.BaseCoveragePercent, CoveragePercent: f.CoveragePercent})
+		}
+	}
+	if len(statistics.Layers) != 0 {
+		policy.LayerBaselines = make(map[string]float64, len(statistics.Layers))
+	}
+	for _, l := range statistics.Layers {
+		stats.Layers = append(stats.Layers, &covergate.Layer{Name: l.Name, CoveragePercent: l.CoveragePercent})
+		policy.LayerBaselines[l.Name] = l.Baseline
+	}
+	for _, p := range statistics.Packages {
+		if p.Baseline <= 0 {
+			continue
+		}
+		if policy.PackageBaselines == nil {
+			policy.PackageBaselines = make(map[string]float64)
+		}
+		stats.Packages = append(stats.Packages, &covergate.Package{Name: p.Name, CoveragePercent: p.CoveragePercent})
+		policy.PackageBaselines[p.Name] = p.Baseline
+	}
+	if todos := statistics.Todos; todos != nil {
+		stats.AddedTodos, stats.AddedLines = len(todos.Todos), todos.AddedLines
+		policy.MaxAddedTodos, policy.MaxTodoDensity = todos.MaxTodos, todos.MaxDensity
+	}
+	return covergate.Evaluate(stats, policy)
+}
+
+// decideGate evaluates the coverage gate and keeps the decision in the statistics,
+// so the reports show the same result as the exit code.
+func decideGate(statistics *report.Statistics, coverageBaseline float64) {
+	statistics.Gate = evaluateGate(statistics, coverageBaseline)
+}
+
+// checkGate returns the low coverage error if the coverage gate fails and it's not bypassed,
+// by the decision in the statistics if it's decided.
+func checkGate(statistics *report.Statistics, coverageBaseline float64) error {
+	decision := statistics.Gate
+	if decision == nil {
+		decision = evaluateGate(statistics, coverageBaseline)
+	}
+	if decision.Passed {
+		return nil
+	}
+	return WrapErrorWithCode(errors.New(decision.Summary()), LowCoverageErrorExitCode, "")
+}
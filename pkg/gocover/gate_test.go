@@ -0,0 +1,84 @@
+package gocover
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestEvaluateGateFileBaseline(t *testing.T) {
+	statistics := &report.Statistics{
+		TotalCoveragePercent: 75,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/foo/a.go", TotalEffectiveLines: 10, CoveredLines: 10},
+			{FileName: "github.com/Azure/foo/b.go", TotalEffectiveLines: 10, CoveredLines: 6, CoveredButIgnoredLines: 1},
+			{FileName: "github.com/Azure/foo/c.go", TotalIgnoredLines: 3},
+		},
+	}
+
+	if decision := evaluateGate(statistics, 70); !decision.Passed {
+		t.Errorf("expect the files are not gated without the file baseline, but get %+v", decision.Violations)
+	}
+
+	statistics.FileBaseline = 60
+	decision := evaluateGate(statistics, 70)
+	if decision.Passed || len(decision.Violations) != 1 {
+		t.Fatalf("expect b.go fails the file baseline, but get %+v", decision.Violations)
+	}
+	if v := decision.Violations[0]; v.Rule != covergate.FileRule || v.File != "github.com/Azure/foo/b.go" || v.Actual != 50 || v.Limit != 60 {
+		t.Errorf("unexpected violation %+v", v)
+	}
+}
+
+func TestEvaluateGateRegression(t *testing.T) {
+	statistics := &report.Statistics{TotalCoveragePercent: 90}
+	if decision := evaluateGate(statistics, 80); !decision.Passed {
+		t.Errorf("expect the files are not gated without the regression, but get %+v", decision.Violations)
+	}
+
+	statistics.Regression = &report.RegressionStatistics{
+		Tolerance: 0.5,
+		Files: []*report.FileRegression{
+			{FileName: "github.com/Azure/foo/a.go", BaseCoveragePercent: 80, CoveragePercent: 79.5},
+			{FileName: "github.com/Azure/foo/b.go", BaseCoveragePercent: 80, CoveragePercent: 70},
+		},
+	}
+	decision := evaluateGate(statistics, 80)
+	if decision.Passed || len(decision.Violations) != 1 {
+		t.Fatalf("expect b.go fails the regression, but get %+v", decision.Violations)
+	}
+	if v := decision.Violations[0]; v.Rule != covergate.RegressionRule || v.File != "github.com/Azure/foo/b.go" || v.Actual != 70 || v.Limit != 79.5 {
+		t.Errorf("unexpected violation %+v", v)
+	}
+}
+
+func TestDecideGate(t *testing.T) {
+	// the coverage meets the baseline, only the file gate fails.
+	statistics := &report.Statistics{
+		TotalCoveragePercent: 75,
+		FileBaseline:         60,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/foo/a.go", TotalEffectiveLines: 10, CoveredLines: 10},
+			{FileName: "github.com/Azure/foo/b.go", TotalEffectiveLines: 10, CoveredLines: 5},
+		},
+	}
+	decideGate(statistics, 70)
+	if statistics.Gate == nil || len(statistics.Gate.Violations) != 1 || statistics.Gate.Violations[0].Rule != covergate.FileRule {
+		t.Fatalf("expect the decision of the failed file gate, but get %+v", statistics.Gate)
+	}
+	if statistics.GatePassed(70) || len(statistics.GateViolations()) != 1 {
+		t.Errorf("expect the reports see the failed file gate, but get %v", statistics.GateViolations())
+	}
+	var exitErr *GoCoverError
+	if err := checkGate(statistics, 70); !errors.As(err, &exitErr) || exitErr.ExitCode != LowCoverageErrorExitCode {
+		t.Errorf("expect the low coverage error of the decision, but get %v", err)
+	}
+
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "legacy"}
+	decideGate(statistics, 70)
+	if !statistics.Gate.Passed || !statistics.GatePassed(70) || checkGate(statistics, 70) != nil {
+		t.Errorf("expect the bypassed file gate passes, but get %+v", statistics.Gate)
+	}
+}
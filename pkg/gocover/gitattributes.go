@@ -0,0 +1,65 @@
+package gocover
+
+import (
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/sirupsen/logrus"
+)
+
+// excludedAttributes are the attributes of .gitattributes that exclude a file from the coverage: linguist-generated
+// collapses the generated files in the diffs of GitHub, and export-ignore leaves the files out of the source archives.
+var excludedAttributes = []string{"linguist-generated", "export-ignore"}
+
+// gitAttributes excludes the files that the .gitattributes files mark, so the counted files agree with
+// the code that the SCM shows in the diffs.
+type gitAttributes struct {
+	attributes *gittool.Attributes
+	logger     logrus.FieldLogger
+}
+
+// loadGitAttributes reads the .gitattributes files of the repository if it's enabled, it's nil if they can't be read,
+// such as the repository is not a git repository, so no file is excluded by them.
+func loadGitAttributes(enabled bool, repositoryPath string, logger logrus.FieldLogger) *gitAttributes {
+	if !enabled {
+		return nil
+	}
+	attributes, err := gittool.LoadAttributes(repositoryPath)
+	if err != nil {
+		logger.Debugf("no .gitattributes of %s: %s", repositoryPath, err)
+		return nil
+	}
+	return &gitAttributes{attributes: attributes, logger: logger}
+}
+
+// excluded returns true if the file is marked by any of the excluded attributes, the path is absolute.
+func (g *gitAttributes) excluded(path, fileName string) bool {
+	if g == nil {
+		return false
+	}
+	for _, name := range excludedAttributes {
+		if g.attributes.IsSet(path, name) {
+			g.logger.Debugf("exclude file: [%s, %s in .gitattributes]", fileName, name)
+			return true
+		}
+	}
+	return false
+}
+
+// skipGitAttributes removes the changes of the files marked in .gitattributes before they are parsed,
+// the removed files are added to the exclude files as the files of --excludes.
+func (diff *diffCover) skipGitAttributes(changes []*gittool.Change) []*gittool.Change {
+	if diff.gitAttributes == nil {
+		return changes
+	}
+	result := make([]*gittool.Change, 0, len(changes))
+	for _, change := range changes {
+		fileName, _ := diff.reportFileName(change)
+		if diff.gitAttributes.excluded(filepath.Join(diff.repositoryPath, change.FileName), fileName) {
+			diff.excludeFiles[fileName] = true
+			continue
+		}
+		result = append(result, change)
+	}
+	return result
+}
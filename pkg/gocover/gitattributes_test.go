@@ -0,0 +1,48 @@
+package gocover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSkipGitAttributes(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := gogit.PlainInit(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.pb.go linguist-generated\n/tools/** export-ignore\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	if loadGitAttributes(false, dir, logger) != nil {
+		t.Error("expect no git attributes when disabled")
+	}
+	if loadGitAttributes(true, t.TempDir(), logger) != nil {
+		t.Error("expect no git attributes out of a git repository")
+	}
+
+	diff := &diffCover{
+		repositoryPath: dir,
+		moduleDir:      "",
+		modulePath:     "github.com/Azure/gocover",
+		excludeFiles:   make(excludeFileCache),
+		gitAttributes:  loadGitAttributes(true, dir, logger),
+		logger:         logger,
+	}
+	changes := diff.skipGitAttributes([]*gittool.Change{{FileName: "api/foo.pb.go"}, {FileName: "tools/gen/main.go"}, {FileName: "foo.go"}})
+	if len(changes) != 1 || changes[0].FileName != "foo.go" {
+		t.Errorf("expect only foo.go is kept, but get %+v", changes)
+	}
+	if len(diff.excludeFiles) != 2 || !diff.excludeFiles["github.com/Azure/gocover/api/foo.pb.go"] || !diff.excludeFiles["github.com/Azure/gocover/tools/gen/main.go"] {
+		t.Errorf("unexpected exclude files %v", diff.excludeFiles)
+	}
+	if !inExclueds(diff.excludeFiles, nil, "github.com/Azure/gocover/api/foo.pb.go", logger) {
+		t.Error("expect the generated file excluded by the exclude file cache")
+	}
+}
@@ -0,0 +1,87 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNotInGOPATH = errors.New("no go.mod found and the directory is not in GOPATH, set the module path explicitly")
+
+// resolveModulePath returns the import path of the module directory.
+// The explicit module path takes precedence, then the module path declared in go.mod.
+// If there is no go.mod, which is a legacy GOPATH layout, the import path is derived from GOPATH,
+// and the GOPATH mode is enabled by GO111MODULE=off, so that go/build and go test resolve the packages in GOPATH.
+func resolveModulePath(moduleDir, modulePath string, logger logrus.FieldLogger) (string, error) {
+	_, statErr := os.Stat(filepath.Join(moduleDir, "go.mod"))
+	hasGoMod := statErr == nil
+
+	if !hasGoMod {
+		if err := enableGOPATHMode(logger); err != nil {
+			return "", err
+		}
+	}
+
+	importPath, err := ModulePath(moduleDir, modulePath)
+	if err != nil {
+		return "", err
+	}
+	if !hasGoMod && modulePath == "" {
+		logger.Infof("no go.mod found in %s, use the import path %s derived from GOPATH", moduleDir, importPath)
+	}
+	return importPath, nil
+}
+
+// ModulePath returns the import path of the module directory as resolveModulePath does, but the GOPATH mode is not enabled,
+// so the reports that run after the coverage can map the file names of the statistics back to the repository.
+func ModulePath(moduleDir, modulePath string) (string, error) {
+	if modulePath != "" {
+		return modulePath, nil
+	}
+	if _, err := os.Stat(filepath.Join(moduleDir, "go.mod")); err == nil {
+		return parseGoModulePath(moduleDir)
+	}
+	return importPathInGOPATH(moduleDir, filepath.SplitList(build.Default.GOPATH))
+}
+
+// importPathInGOPATH returns the import path of the directory, which is relative to the src directory of a GOPATH entry.
+func importPathInGOPATH(dir string, gopaths []string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	candidates := []string{dir}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != dir {
+		candidates = append(candidates, resolved)
+	}
+
+	for _, gopath := range gopaths {
+		if gopath == "" {
+			continue
+		}
+		src := filepath.Join(gopath, "src")
+		for _, candidate := range candidates {
+			rel, err := filepath.Rel(src, candidate)
+			if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotInGOPATH, dir)
+}
+
+// enableGOPATHMode sets GO111MODULE=off for gocover and the commands it runs, unless it's set explicitly.
+func enableGOPATHMode(logger logrus.FieldLogger) error {
+	if value, ok := os.LookupEnv("GO111MODULE"); ok && value != "" {
+		logger.Debugf("GO111MODULE=%s is set, keep it", value)
+		return nil
+	}
+	logger.Infof("enable GOPATH mode by GO111MODULE=off")
+	return os.Setenv("GO111MODULE", "off")
+}
@@ -0,0 +1,72 @@
+package gocover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestImportPathInGOPATH(t *testing.T) {
+	gopath := t.TempDir()
+	dir := filepath.Join(gopath, "src", "github.com", "Azure", "legacy")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	importPath, err := importPathInGOPATH(dir, []string{"", t.TempDir(), gopath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if importPath != "github.com/Azure/legacy" {
+		t.Errorf("expect github.com/Azure/legacy, but get %s", importPath)
+	}
+
+	if _, err := importPathInGOPATH(t.TempDir(), []string{gopath}); !errors.Is(err, ErrNotInGOPATH) {
+		t.Errorf("expect ErrNotInGOPATH, but get %v", err)
+	}
+	if _, err := importPathInGOPATH(filepath.Join(gopath, "src"), []string{gopath}); !errors.Is(err, ErrNotInGOPATH) {
+		t.Errorf("src directory has no import path, expect ErrNotInGOPATH, but get %v", err)
+	}
+}
+
+func TestResolveModulePath(t *testing.T) {
+	t.Run("go.mod", func(t *testing.T) {
+		t.Setenv("GO111MODULE", "")
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module github.com/Azure/foo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		modulePath, err := resolveModulePath(dir, "", logrus.New())
+		if err != nil || modulePath != "github.com/Azure/foo" {
+			t.Errorf("expect github.com/Azure/foo, but get %s, %v", modulePath, err)
+		}
+		if v := os.Getenv("GO111MODULE"); v != "" {
+			t.Errorf("module mode should be kept, but get GO111MODULE=%s", v)
+		}
+	})
+
+	t.Run("explicit module path without go.mod", func(t *testing.T) {
+		t.Setenv("GO111MODULE", "")
+		modulePath, err := resolveModulePath(t.TempDir(), "github.com/Azure/legacy", logrus.New())
+		if err != nil || modulePath != "github.com/Azure/legacy" {
+			t.Errorf("expect github.com/Azure/legacy, but get %s, %v", modulePath, err)
+		}
+		if v := os.Getenv("GO111MODULE"); v != "off" {
+			t.Errorf("GOPATH mode should be enabled, but get GO111MODULE=%s", v)
+		}
+	})
+
+	t.Run("keep GO111MODULE set explicitly", func(t *testing.T) {
+		t.Setenv("GO111MODULE", "auto")
+		if _, err := resolveModulePath(t.TempDir(), "", logrus.New()); !errors.Is(err, ErrNotInGOPATH) {
+			t.Errorf("expect ErrNotInGOPATH, but get %v", err)
+		}
+		if v := os.Getenv("GO111MODULE"); v != "auto" {
+			t.Errorf("GO111MODULE should be kept, but get %s", v)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrInvalidGracePeriod = errors.New("invalid grace period")
+
+// graceDateLayout is the layout of the last day of a grace period.
+const graceDateLayout = "2006-01-02"
+
+// GracePeriod lets the files that match the pattern miss the coverage gate until the date, so a legacy directory
+// is migrated gradually. Their uncovered lines are still reported, but they don't count for the gate,
+// and they're enforced again after the date without a change of the configuration.
+type GracePeriod struct {
+	// Pattern is the doublestar pattern of the files, it matches the file names in the reports,
+	// which start with the module path, such as example.com/foo/legacy/**.
+	Pattern string
+	// Until is the last day of the grace period in UTC.
+	Until time.Time
+}
+
+// ParseGracePeriods parses the grace periods in the format of pattern=YYYY-MM-DD,
+// a file is in the grace period of the first pattern it matches.
+func ParseGracePeriods(periods []string) ([]GracePeriod, error) {
+	var result []GracePeriod
+	for _, s := range periods {
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%w %q, the format is pattern=YYYY-MM-DD", ErrInvalidGracePeriod, s)
+		}
+		pattern, value := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		if pattern == "" || !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("%w %q, bad pattern %s", ErrInvalidGracePeriod, s, pattern)
+		}
+		until, err := time.Parse(graceDateLayout, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q, the date should be YYYY-MM-DD", ErrInvalidGracePeriod, s)
+		}
+		result = append(result, GracePeriod{Pattern: pattern, Until: until})
+	}
+	return result, nil
+}
+
+// active reports whether the grace period is not over at the time, the last day is included.
+func (g GracePeriod) active(now time.Time) bool {
+	return now.Before(g.Until.AddDate(0, 0, 1))
+}
+
+// activeGracePeriods returns the grace periods that are not over at the time, the expired ones are logged,
+// since their files are enforced from now on.
+func activeGracePeriods(periods []GracePeriod, now time.Time, logger logrus.FieldLogger) []GracePeriod {
+	var result []GracePeriod
+	for _, g := range periods {
+		if !g.active(now) {
+			logger.Infof("grace period of %s ended on %s, its files are enforced", g.Pattern, g.Until.Format(graceDateLayout))
+			continue
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+// graceUntil returns the last day of the first grace period that the file matches, it's empty if the file is enforced.
+func graceUntil(periods []GracePeriod, fileName string) string {
+	for _, g := range periods {
+		if ok, _ := doublestar.Match(g.Pattern, fileName); ok {
+			return g.Until.Format(graceDateLayout)
+		}
+	}
+	return ""
+}
+
+// enforced reports whether the profile counts for the coverage gate, the files in a grace period don't.
+func enforced(p *report.CoverageProfile) bool {
+	return p.GraceUntil == ""
+}
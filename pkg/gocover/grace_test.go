@@ -0,0 +1,134 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseGracePeriods(t *testing.T) {
+	periods, err := ParseGracePeriods([]string{"example.com/foo/legacy/** = 2026-12-31", "**/a=b.go=2027-01-15"})
+	if err != nil {
+		t.Fatalf("parse grace periods: %v", err)
+	}
+	if len(periods) != 2 {
+		t.Fatalf("expect 2 grace periods, but get %+v", periods)
+	}
+	if p := periods[0]; p.Pattern != "example.com/foo/legacy/**" || !p.Until.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected grace period %+v", p)
+	}
+	if p := periods[1]; p.Pattern != "**/a=b.go" || p.Until.Format(graceDateLayout) != "2027-01-15" {
+		t.Errorf("expect the last = separates the date, but get %+v", p)
+	}
+
+	for _, s := range []string{"legacy/**", "=2026-12-31", "legacy/[=2026-12-31", "legacy/**=31/12/2026", "legacy/**=2026-13-01"} {
+		if _, err := ParseGracePeriods([]string{s}); !errors.Is(err, ErrInvalidGracePeriod) {
+			t.Errorf("expect ErrInvalidGracePeriod of %q, but get %v", s, err)
+		}
+	}
+}
+
+func TestActiveGracePeriods(t *testing.T) {
+	periods, err := ParseGracePeriods([]string{"legacy/**=2026-10-13", "old/**=2026-10-14", "older/**=2026-10-15"})
+	if err != nil {
+		t.Fatalf("parse grace periods: %v", err)
+	}
+	now := time.Date(2026, 10, 14, 23, 59, 0, 0, time.UTC)
+	active := activeGracePeriods(periods, now, logrus.New())
+	if len(active) != 2 || active[0].Pattern != "old/**" || active[1].Pattern != "older/**" {
+		t.Errorf("expect the grace periods ending today or later are active, but get %+v", active)
+	}
+	if until := graceUntil(active, "old/a.go"); until != "2026-10-14" {
+		t.Errorf("expect old/a.go in the grace period until 2026-10-14, but get %q", until)
+	}
+	if until := graceUntil(active, "legacy/a.go"); until != "" {
+		t.Errorf("expect legacy/a.go is enforced after its grace period, but get %q", until)
+	}
+}
+
+func TestReBuildStatisticsGracePeriod(t *testing.T) {
+	s := &report.Statistics{
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "a.go", TotalLines: 10, CoveredLines: 10, TotalEffectiveLines: 10},
+			{FileName: "legacy/b.go", TotalLines: 10, TotalEffectiveLines: 10, TotalViolationLines: []int{1, 2}, GraceUntil: "2026-12-31"},
+		},
+	}
+	reBuildStatistics(s, nil)
+	if s.TotalEffectiveLines != 10 || s.TotalCoveragePercent != 100 || s.TotalViolationLines != 0 {
+		t.Errorf("expect the file in the grace period is not counted, but get %+v", s)
+	}
+	if len(s.CoverageProfile) != 2 {
+		t.Errorf("expect the file in the grace period is still reported, but get %d profiles", len(s.CoverageProfile))
+	}
+}
+
+func TestFullCoverGracePeriod(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	commitFiles(t, dir, "init", map[string]string{
+		"go.mod":      "module example.com/foo\n\ngo 1.20\n",
+		"foo.go":      "package foo\n\nfunc Foo() int {\n\treturn 1\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo()\n}\n",
+		"legacy.go":   "package foo\n\nfunc Legacy() int {\n\treturn 1\n}\n",
+	})
+	profile := goTestProfile(t, dir)
+
+	for name, until := range map[string]time.Time{"active": time.Now().AddDate(0, 1, 0), "expired": time.Now().AddDate(0, 0, -2)} {
+		t.Run(name, func(t *testing.T) {
+			recorder := &statisticsRecorder{}
+			option := NewFullOption()
+			option.CoverProfiles = []string{profile}
+			option.RepositoryPath = dir
+			option.ModuleDir = "./"
+			option.FileBaseline = 100
+			option.ReportFormat = JSONReportFormat
+			option.OutputDir = t.TempDir()
+			option.Style = "colorful"
+			option.DbOption = &dbclient.DBOption{}
+			option.ReportGenerators = []report.ReportGenerator{recorder}
+			option.GracePeriods = []GracePeriod{{Pattern: "**/legacy.go", Until: until}}
+			option.Logger = logrus.New()
+
+			err := inDirectory(dir, option.Logger, func() error {
+				full, err := NewFullCover(option)
+				if err != nil {
+					return err
+				}
+				return full.Run(context.Background())
+			})
+			statistics := recorder.statistics
+			if statistics == nil {
+				t.Fatalf("expect the statistics, but get %v", err)
+			}
+			var legacy *report.CoverageProfile
+			for _, p := range statistics.CoverageProfile {
+				if p.FileName == "example.com/foo/legacy.go" {
+					legacy = p
+				}
+			}
+			if legacy == nil || len(legacy.TotalViolationLines) != 1 {
+				t.Fatalf("expect the uncovered line of legacy.go is reported, but get %+v", legacy)
+			}
+
+			if name == "active" {
+				if err != nil || statistics.TotalCoveragePercent != 100 || legacy.GraceUntil != until.Format(graceDateLayout) {
+					t.Errorf("expect legacy.go misses the gate in the grace period, but get %v, %.2f, %q", err, statistics.TotalCoveragePercent, legacy.GraceUntil)
+				}
+				return
+			}
+			var gocoverErr *GoCoverError
+			if !errors.As(err, &gocoverErr) || gocoverErr.ExitCode != LowCoverageErrorExitCode || legacy.GraceUntil != "" {
+				t.Errorf("expect legacy.go is enforced after the grace period, but get %v, %q", err, legacy.GraceUntil)
+			}
+		})
+	}
+}
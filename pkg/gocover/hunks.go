@@ -0,0 +1,108 @@
+package gocover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// ansiGray is the color of the ignored lines of the hunks.
+const ansiGray = "\x1b[90m"
+
+// HunkOption decides whether the changed hunks of the counted files are printed after the reports.
+type HunkOption struct {
+	// Enabled prints the added lines of each changed hunk, the uncovered lines are red, the covered lines are green,
+	// and the ignored lines are gray.
+	Enabled bool
+	// Color colors the lines with ANSI escape codes, the lines are marked by their status either way.
+	Color bool
+	// Output is where the hunks are written to, os.Stdout is used if it's nil.
+	Output io.Writer
+}
+
+// writeHunks writes the added sections of the changes whose files are in the statistics, as the console report of the diff.
+func (diff *diffCover) writeHunks(changes []*gittool.Change, statistics *report.Statistics) error {
+	if !diff.hunks.Enabled {
+		return nil
+	}
+	output := diff.hunks.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	profiles := make(map[string]*report.CoverageProfile)
+	for _, p := range statistics.CoverageProfile {
+		profiles[p.FileName] = p
+	}
+
+	w := bufio.NewWriter(output)
+	for _, change := range changes {
+		fileName, _ := diff.reportFileName(change)
+		if profile, ok := profiles[fileName]; ok {
+			writeHunk(w, profile, change.Sections, diff.hunks.Color)
+		}
+	}
+	return w.Flush()
+}
+
+// writeHunk writes the added sections of the file, each line is marked and colored by its coverage status.
+// The contents are the lines that git diff captured, so the file is not read again.
+func writeHunk(w io.Writer, profile *report.CoverageProfile, sections []*gittool.Section, color bool) {
+	paint := func(code, s string) string {
+		if !color || code == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	var added []*gittool.Section
+	for _, s := range sections {
+		if s.Operation == gittool.Add && len(s.Contents) > 0 {
+			added = append(added, s)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+
+	header := fmt.Sprintf("%s %.2f%%", profile.FileName,
+		calculateCoverage(int64(profile.CoveredLines-profile.CoveredButIgnoredLines), int64(profile.TotalEffectiveLines)))
+	if len(profile.TotalViolationLines) > 0 {
+		header += ", lines not covered: " + report.LineRanges(profile.TotalViolationLines)
+	}
+	fmt.Fprintln(w, paint(ansiBold, header))
+
+	lines := lineStatuses(profile)
+	for _, s := range added {
+		fmt.Fprintln(w, paint(ansiCyan, fmt.Sprintf("@@ +%d,%d @@", s.StartLine, len(s.Contents))))
+		for i, content := range s.Contents {
+			status := lines[s.StartLine+i]
+			fmt.Fprintln(w, paint(hunkColor(status), fmt.Sprintf("%s %5d %s", hunkMarker(status), s.StartLine+i, content)))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func hunkColor(status lineStatus) string {
+	if status == ignoredLine {
+		return ansiGray
+	}
+	return statusColor(status)
+}
+
+// hunkMarker marks the status of the line, so it's still told apart without colors.
+func hunkMarker(status lineStatus) string {
+	switch status {
+	case coveredLine:
+		return "+"
+	case uncoveredLine:
+		return "!"
+	case ignoredLine:
+		return "~"
+	default:
+		return " "
+	}
+}
@@ -0,0 +1,105 @@
+package gocover
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestWriteHunks(t *testing.T) {
+	changes := []*gittool.Change{
+		{
+			FileName: "pkg/foo/foo.go",
+			Mode:     gittool.ModifyMode,
+			Sections: []*gittool.Section{
+				{Operation: gittool.Add, StartLine: 10, EndLine: 13, Contents: []string{"\ta := 1", "\tb := 2", "\tc := 3", "}"}},
+			},
+		},
+		{
+			FileName: "pkg/foo/bar.go",
+			Mode:     gittool.NewMode,
+			Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 1, EndLine: 1, Contents: []string{"package foo"}}},
+		},
+		{
+			FileName: "README.md",
+			Mode:     gittool.NewMode,
+			Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 1, EndLine: 1, Contents: []string{"# gocover"}}},
+		},
+	}
+	statistics := &report.Statistics{
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName:            "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalEffectiveLines: 2,
+				CoveredLines:        1,
+				TotalViolationLines: []int{11},
+				CountedLines: []*report.CountedLine{
+					{Line: 10, Covered: true},
+					{Line: 11},
+					{Line: 12, Ignored: true},
+				},
+			},
+		},
+	}
+	newDiff := func(b *bytes.Buffer, color bool) *diffCover {
+		return &diffCover{
+			moduleDir:  ".",
+			modulePath: "github.com/Azure/gocover",
+			hunks:      HunkOption{Enabled: true, Color: color, Output: b},
+		}
+	}
+
+	t.Run("color", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := newDiff(&b, true).writeHunks(changes, statistics); err != nil {
+			t.Fatal(err)
+		}
+		output := b.String()
+		for _, expect := range []string{
+			ansiBold + "github.com/Azure/gocover/pkg/foo/foo.go 50.00%, lines not covered: 11" + ansiReset,
+			ansiCyan + "@@ +10,4 @@" + ansiReset,
+			ansiGreen + "+    10 \ta := 1" + ansiReset,
+			ansiRed + "!    11 \tb := 2" + ansiReset,
+			ansiGray + "~    12 \tc := 3" + ansiReset,
+			"\n     13 }\n",
+		} {
+			if !strings.Contains(output, expect) {
+				t.Errorf("hunks should contain %q, but get %q", expect, output)
+			}
+		}
+		if strings.Contains(output, "bar.go") || strings.Contains(output, "README.md") {
+			t.Errorf("files not in the statistics should not be printed, but get %q", output)
+		}
+	})
+
+	t.Run("no color", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := newDiff(&b, false).writeHunks(changes, statistics); err != nil {
+			t.Fatal(err)
+		}
+		expect := "github.com/Azure/gocover/pkg/foo/foo.go 50.00%, lines not covered: 11\n" +
+			"@@ +10,4 @@\n" +
+			"+    10 \ta := 1\n" +
+			"!    11 \tb := 2\n" +
+			"~    12 \tc := 3\n" +
+			"     13 }\n\n"
+		if b.String() != expect {
+			t.Errorf("hunks should be %q, but get %q", expect, b.String())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var b bytes.Buffer
+		diff := newDiff(&b, true)
+		diff.hunks.Enabled = false
+		if err := diff.writeHunks(changes, statistics); err != nil {
+			t.Fatal(err)
+		}
+		if b.Len() != 0 {
+			t.Errorf("hunks should not be printed, but get %q", b.String())
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+var ErrInvalidLayer = errors.New("invalid layer")
+
+// Layer labels the files of an architecture layer, such as handlers, domain or storage,
+// so the coverage of each layer is reported and gated by its own baseline.
+type Layer struct {
+	// Name is the name of the layer.
+	Name string
+	// Patterns are the doublestar patterns of the files in the layer, such as **/domain/**,
+	// they match the file names in the reports, which start with the module path.
+	Patterns []string
+	// Baseline is the expected coverage of the layer, the layer is not gated if it's zero.
+	Baseline float64
+}
+
+// ParseLayers parses the layers in the format of name=pattern[,pattern...],
+// and their baselines in the format of name=percent. A file belongs to the first layer it matches.
+func ParseLayers(layers, baselines []string) ([]Layer, error) {
+	var result []Layer
+	index := make(map[string]int)
+	for _, s := range layers {
+		name, patterns, ok := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.TrimSpace(patterns) == "" {
+			return nil, fmt.Errorf("%w %q, the format is name=pattern[,pattern...]", ErrInvalidLayer, s)
+		}
+
+		layer := Layer{Name: name}
+		for _, pattern := range strings.Split(patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if !doublestar.ValidatePattern(pattern) {
+				return nil, fmt.Errorf("%w %q, bad pattern %s", ErrInvalidLayer, s, pattern)
+			}
+			layer.Patterns = append(layer.Patterns, pattern)
+		}
+
+		if i, ok := index[name]; ok {
+			result[i].Patterns = append(result[i].Patterns, layer.Patterns...)
+			continue
+		}
+		index[name] = len(result)
+		result = append(result, layer)
+	}
+
+	for _, s := range baselines {
+		name, value, ok := strings.Cut(s, "=")
+		i, found := index[strings.TrimSpace(name)]
+		if !ok || !found {
+			return nil, fmt.Errorf("%w baseline %q, the format is name=percent of a defined layer", ErrInvalidLayer, s)
+		}
+		baseline, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || baseline < 0 || baseline > 100 {
+			return nil, fmt.Errorf("%w baseline %q, the percent should be in [0, 100]", ErrInvalidLayer, s)
+		}
+		result[i].Baseline = baseline
+	}
+	return result, nil
+}
+
+// match reports whether the file belongs to the layer.
+func (l *Layer) match(fileName string) bool {
+	for _, pattern := range l.Patterns {
+		if ok, _ := doublestar.Match(pattern, fileName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// calculateLayers adds the coverage of each layer to the statistics, a file belongs to the first layer it matches.
+func calculateLayers(statistics *report.Statistics, layers []Layer) {
+	if len(layers) == 0 {
+		return
+	}
+
+	result := make([]*report.LayerStatistics, 0, len(layers))
+	for _, l := range layers {
+		result = append(result, &report.LayerStatistics{Name: l.Name, Baseline: l.Baseline})
+	}
+
+	covered := make([]int, len(layers))
+	for _, p := range statistics.CoverageProfile {
+		if !enforced(p) {
+			continue
+		}
+		for i := range layers {
+			if !layers[i].match(p.FileName) {
+				continue
+			}
+			result[i].Files++
+			result[i].TotalEffectiveLines += p.TotalEffectiveLines
+			result[i].TotalCoveredLines += p.CoveredLines
+			covered[i] += p.CoveredLines - p.CoveredButIgnoredLines
+			break
+		}
+	}
+	for i, l := range result {
+		l.CoveragePercent = calculateCoverage(int64(covered[i]), int64(l.TotalEffectiveLines))
+	}
+	statistics.Layers = result
+}
@@ -0,0 +1,88 @@
+package gocover
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestParseLayers(t *testing.T) {
+	layers, err := ParseLayers(
+		[]string{"handlers=**/handlers/**", "domain=**/domain/**, **/model/**", "handlers=**/api/**"},
+		[]string{"domain=90", "handlers = 60.5"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 2 {
+		t.Fatalf("expect 2 layers, but get %+v", layers)
+	}
+	if layers[0].Name != "handlers" || len(layers[0].Patterns) != 2 || layers[0].Baseline != 60.5 {
+		t.Errorf("unexpected handlers layer %+v", layers[0])
+	}
+	if layers[1].Name != "domain" || len(layers[1].Patterns) != 2 || layers[1].Patterns[1] != "**/model/**" || layers[1].Baseline != 90 {
+		t.Errorf("unexpected domain layer %+v", layers[1])
+	}
+
+	for _, testSuite := range []struct {
+		layers    []string
+		baselines []string
+	}{
+		{layers: []string{"domain"}},
+		{layers: []string{"=**/domain/**"}},
+		{layers: []string{"domain=["}},
+		{layers: []string{"domain=**/domain/**"}, baselines: []string{"storage=80"}},
+		{layers: []string{"domain=**/domain/**"}, baselines: []string{"domain=120"}},
+	} {
+		if _, err := ParseLayers(testSuite.layers, testSuite.baselines); !errors.Is(err, ErrInvalidLayer) {
+			t.Errorf("expect ErrInvalidLayer of %v %v, but get %v", testSuite.layers, testSuite.baselines, err)
+		}
+	}
+}
+
+func TestCalculateLayers(t *testing.T) {
+	statistics := &report.Statistics{
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/foo/pkg/domain/order.go", TotalEffectiveLines: 10, CoveredLines: 9, CoveredButIgnoredLines: 1},
+			{FileName: "github.com/Azure/foo/pkg/domain/handlers/order.go", TotalEffectiveLines: 10, CoveredLines: 5},
+			{FileName: "github.com/Azure/foo/pkg/storage/db.go", TotalEffectiveLines: 4, CoveredLines: 1},
+		},
+	}
+	layers := []Layer{
+		{Name: "handlers", Patterns: []string{"**/handlers/**"}},
+		{Name: "domain", Patterns: []string{"**/domain/**"}, Baseline: 90},
+		{Name: "storage", Patterns: []string{"**/storage/**"}, Baseline: 20},
+		{Name: "cmd", Patterns: []string{"**/cmd/**"}},
+	}
+	calculateLayers(statistics, layers)
+
+	if len(statistics.Layers) != 4 {
+		t.Fatalf("expect 4 layers, but get %d", len(statistics.Layers))
+	}
+	handlers, domain, storage, cmd := statistics.Layers[0], statistics.Layers[1], statistics.Layers[2], statistics.Layers[3]
+	// handlers/order.go matches the handlers layer first, so it's not counted in the domain layer.
+	if handlers.Files != 1 || handlers.CoveragePercent != 50 {
+		t.Errorf("unexpected handlers layer %+v", handlers)
+	}
+	if domain.Files != 1 || domain.CoveragePercent != 80 || domain.Passed() {
+		t.Errorf("unexpected domain layer %+v", domain)
+	}
+	if storage.Files != 1 || storage.CoveragePercent != 25 || !storage.Passed() {
+		t.Errorf("unexpected storage layer %+v", storage)
+	}
+	if cmd.Files != 0 || cmd.CoveragePercent != 100 {
+		t.Errorf("unexpected cmd layer %+v", cmd)
+	}
+
+	err := checkGate(statistics, 0)
+	var e *GoCoverError
+	if !errors.As(err, &e) || e.ExitCode != LowCoverageErrorExitCode {
+		t.Errorf("expect low coverage error, but get %v", err)
+	}
+
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "legacy"}
+	if err := checkGate(statistics, 0); err != nil {
+		t.Errorf("bypassed gate should pass, but get %s", err)
+	}
+}
@@ -0,0 +1,111 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+const (
+	// DefaultMaxFileSize is the max size of a file that counts for coverage, 4 MiB.
+	DefaultMaxFileSize int64 = 4 << 20
+	// DefaultMaxSectionLines is the max lines of the source retained by a violation section.
+	DefaultMaxSectionLines = 500
+)
+
+// TruncationPolicy decides how the files exceed the limits are treated.
+type TruncationPolicy string
+
+const (
+	// SkipOversizedFiles skips the oversized files, they are listed as truncated in the reports.
+	SkipOversizedFiles TruncationPolicy = "skip"
+	// FailOnOversizedFiles returns an error if any file is oversized.
+	FailOnOversizedFiles TruncationPolicy = "fail"
+)
+
+var (
+	ErrUnknownTruncationPolicy = errors.New("unknown truncation policy, one of: skip, fail")
+	ErrFileTooLarge            = errors.New("file is too large")
+)
+
+// Limits bounds the memory used by pathological files, such as generated files with 100k lines.
+type Limits struct {
+	// MaxFileSize is the max size in bytes of a file that counts for coverage, no limit if it's zero.
+	MaxFileSize int64
+	// MaxSectionLines is the max lines of the source retained by a violation section, no limit if it's zero.
+	MaxSectionLines int
+	// TruncationPolicy decides how the oversized files are treated, skip is used if it's empty.
+	TruncationPolicy TruncationPolicy
+}
+
+// DefaultLimits returns the limits with default values.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFileSize:      DefaultMaxFileSize,
+		MaxSectionLines:  DefaultMaxSectionLines,
+		TruncationPolicy: SkipOversizedFiles,
+	}
+}
+
+// Validate validates the limits.
+func (l *Limits) Validate() error {
+	if l.MaxFileSize < 0 || l.MaxSectionLines < 0 {
+		return fmt.Errorf("limits should not be negative: max file size %d, max section lines %d", l.MaxFileSize, l.MaxSectionLines)
+	}
+	switch l.TruncationPolicy {
+	case "", SkipOversizedFiles, FailOnOversizedFiles:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownTruncationPolicy, l.TruncationPolicy)
+	}
+}
+
+// checkFileSize returns the truncated file if the file exceeds the max file size,
+// it returns an error instead if the policy is fail.
+func (l *Limits) checkFileSize(filename, reportName string) (*report.TruncatedFile, error) {
+	if l.MaxFileSize == 0 {
+		return nil, nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil || info.Size() <= l.MaxFileSize {
+		// the missing files are reported by the following steps.
+		return nil, nil
+	}
+
+	if l.TruncationPolicy == FailOnOversizedFiles {
+		return nil, fmt.Errorf("%w: %s is %d bytes, the max file size is %d bytes", ErrFileTooLarge, reportName, info.Size(), l.MaxFileSize)
+	}
+	return &report.TruncatedFile{
+		FileName: reportName,
+		Size:     info.Size(),
+		Reason:   fmt.Sprintf("file size %d bytes exceeds the max file size %d bytes", info.Size(), l.MaxFileSize),
+	}, nil
+}
+
+// fillSectionContents fills the source lines of the section from the file contents,
+// at most max section lines are retained and the truncation marker is appended.
+func (l *Limits) fillSectionContents(section *report.ViolationSection, fileContents []string) {
+	end := section.EndLine
+	if l.MaxSectionLines != 0 && end-section.StartLine+1 > l.MaxSectionLines {
+		end = section.StartLine + l.MaxSectionLines - 1
+		section.TruncatedLines = section.EndLine - end
+	}
+
+	for i := section.StartLine; i <= end; i++ {
+		section.Contents = append(section.Contents, fileContents[i-1])
+	}
+	if section.TruncatedLines != 0 {
+		section.Contents = append(section.Contents, fmt.Sprintf(report.TruncationMarker, section.TruncatedLines))
+	}
+}
+
+// truncateContents keeps the max section lines of the contents, the rest lines are replaced by the truncation marker.
+func (l *Limits) truncateContents(contents []string) []string {
+	if l.MaxSectionLines == 0 || len(contents) <= l.MaxSectionLines {
+		return contents
+	}
+	truncated := len(contents) - l.MaxSectionLines
+	return append(contents[:l.MaxSectionLines:l.MaxSectionLines], fmt.Sprintf(report.TruncationMarker, truncated))
+}
@@ -0,0 +1,98 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestLimitsValidate(t *testing.T) {
+	testSuites := []struct {
+		limits   Limits
+		hasError bool
+	}{
+		{limits: DefaultLimits()},
+		{limits: Limits{}},
+		{limits: Limits{TruncationPolicy: FailOnOversizedFiles}},
+		{limits: Limits{TruncationPolicy: "truncate"}, hasError: true},
+		{limits: Limits{MaxFileSize: -1}, hasError: true},
+		{limits: Limits{MaxSectionLines: -1}, hasError: true},
+	}
+	for _, testSuite := range testSuites {
+		if err := testSuite.limits.Validate(); (err != nil) != testSuite.hasError {
+			t.Errorf("validate %+v, expect error %t, but get %v", testSuite.limits, testSuite.hasError, err)
+		}
+	}
+}
+
+func TestCheckFileSize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "generated.go")
+	if err := os.WriteFile(filename, []byte(strings.Repeat("a", 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		for _, l := range []Limits{{MaxFileSize: 100}, {MaxFileSize: 0}} {
+			truncated, err := l.checkFileSize(filename, "generated.go")
+			if err != nil || truncated != nil {
+				t.Errorf("expect no truncation, but get %v, %v", truncated, err)
+			}
+		}
+	})
+
+	t.Run("skip oversized file", func(t *testing.T) {
+		l := &Limits{MaxFileSize: 99, TruncationPolicy: SkipOversizedFiles}
+		truncated, err := l.checkFileSize(filename, "generated.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if truncated == nil || truncated.FileName != "generated.go" || truncated.Size != 100 {
+			t.Errorf("unexpected truncated file %+v", truncated)
+		}
+	})
+
+	t.Run("fail on oversized file", func(t *testing.T) {
+		l := &Limits{MaxFileSize: 99, TruncationPolicy: FailOnOversizedFiles}
+		if _, err := l.checkFileSize(filename, "generated.go"); !errors.Is(err, ErrFileTooLarge) {
+			t.Errorf("expect ErrFileTooLarge, but get %v", err)
+		}
+	})
+
+	t.Run("file not exist", func(t *testing.T) {
+		l := &Limits{MaxFileSize: 1}
+		if truncated, err := l.checkFileSize("/not/exist.go", "exist.go"); err != nil || truncated != nil {
+			t.Errorf("expect no truncation, but get %v, %v", truncated, err)
+		}
+	})
+}
+
+func TestFillSectionContents(t *testing.T) {
+	var fileContents []string
+	for i := 1; i <= 10; i++ {
+		fileContents = append(fileContents, fmt.Sprintf("line %d", i))
+	}
+
+	t.Run("no truncation", func(t *testing.T) {
+		section := &report.ViolationSection{StartLine: 2, EndLine: 4}
+		(&Limits{MaxSectionLines: 3}).fillSectionContents(section, fileContents)
+		if len(section.Contents) != 3 || section.Contents[0] != "line 2" || section.TruncatedLines != 0 {
+			t.Errorf("unexpected section %+v", section)
+		}
+	})
+
+	t.Run("truncate section", func(t *testing.T) {
+		section := &report.ViolationSection{StartLine: 2, EndLine: 9}
+		(&Limits{MaxSectionLines: 3}).fillSectionContents(section, fileContents)
+		if len(section.Contents) != 4 || section.Contents[2] != "line 4" || section.TruncatedLines != 5 {
+			t.Errorf("unexpected section %+v", section)
+		}
+		if marker := fmt.Sprintf(report.TruncationMarker, 5); section.Contents[3] != marker {
+			t.Errorf("expect marker %q, but get %q", marker, section.Contents[3])
+		}
+	})
+}
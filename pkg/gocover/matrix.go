@@ -0,0 +1,251 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNoBranch = errors.New("no release branch to compare")
+
+// MatrixOption contains the input to the gocover matrix command.
+type MatrixOption struct {
+	// Commits are the commits of the pull request that are cherry-picked onto each branch in order,
+	// a commit can be a range such as main..feature.
+	Commits []string
+	// Branches are the release branches that the commits are applied to.
+	Branches       []string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath       string
+	GoFlags          []string
+	CoverageBaseline float64
+	ReportFormat     string
+	ReportName       string
+	// OutputDir is the directory that the report of each branch is written into a sub directory named by the branch,
+	// the reports are written into a temporary directory if it's empty.
+	OutputDir string
+	Excludes  []string
+	Style     string
+	Limits    Limits
+
+	StdOut io.Writer
+	StdErr io.Writer
+	Logger logrus.FieldLogger
+}
+
+// NewMatrixOption returns a Matrix Option with default values.
+func NewMatrixOption() *MatrixOption {
+	return &MatrixOption{
+		CoverageBaseline: DefaultCoverageBaseline,
+		ReportFormat:     DefaultReportFormat,
+		Limits:           DefaultLimits(),
+	}
+}
+
+// MatrixResult is the diff coverage of the commits applied to a branch.
+type MatrixResult struct {
+	Branch string
+	// Base is the commit of the branch that the commits are applied to.
+	Base string
+	// Conflict indicates the commits conflict with the branch, there are no statistics.
+	Conflict bool
+	// Err is the failure of the branch other than the conflict.
+	Err error
+	// Statistics is the diff coverage of the applied commits on the branch.
+	Statistics *report.Statistics
+}
+
+// Passed reports whether the diff coverage on the branch passes the coverage gate.
+func (r *MatrixResult) Passed(coverageBaseline float64) bool {
+	return r.Statistics != nil && r.Statistics.TotalCoveragePercent >= coverageBaseline
+}
+
+// RunMatrix cherry-picks the commits onto each branch in a linked worktree, runs the tests with coverage there,
+// and returns the diff coverage of the applied commits on each branch. The branches are independent,
+// so the failure of a branch is recorded in its result rather than stopping the others.
+// The branches run one by one, since the working directory is changed to the worktree of each branch.
+func RunMatrix(ctx context.Context, o *MatrixOption) ([]*MatrixResult, error) {
+	if len(o.Branches) == 0 {
+		return nil, ErrNoBranch
+	}
+	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of repo: %w", err)
+	}
+	workDir, err := createGoCoverTempDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("create gocover temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	outputDir := o.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Join(workDir, "reports")
+	}
+
+	logger := o.Logger.WithField("source", "matrix")
+	results := make([]*MatrixResult, 0, len(o.Branches))
+	for _, branch := range o.Branches {
+		name := branchDirName(branch)
+		result := runBranch(ctx, o, repositoryAbsPath, filepath.Join(workDir, name), filepath.Join(outputDir, name), branch, logger.WithField("branch", branch))
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runBranch applies the commits onto the branch and calculates their diff coverage.
+func runBranch(ctx context.Context, o *MatrixOption, repositoryPath, worktreePath, outputDir, branch string, logger logrus.FieldLogger) *MatrixResult {
+	result := &MatrixResult{Branch: branch}
+
+	worktree, err := gittool.AddWorktree(ctx, repositoryPath, worktreePath, branch)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer func() {
+		if err := worktree.Remove(context.Background()); err != nil {
+			logger.WithError(err).Warn("remove worktree")
+		}
+	}()
+	if result.Base, err = worktree.Head(ctx); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if err := worktree.CherryPick(ctx, o.Commits...); err != nil {
+		if errors.Is(err, gittool.ErrCherryPickConflict) {
+			logger.WithError(err).Warn("commits conflict with the branch")
+			result.Conflict = true
+			return result
+		}
+		result.Err = err
+		return result
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		result.Err = fmt.Errorf("create output directory: %w", err)
+		return result
+	}
+
+	option := &GoCoverTestOption{
+		CompareBranch:    result.Base,
+		RepositoryPath:   worktreePath,
+		ModuleDir:        o.ModuleDir,
+		ModulePath:       o.ModulePath,
+		CoverageMode:     DiffCoverage,
+		ExecutorMode:     GoExecutor,
+		GoFlags:          o.GoFlags,
+		CoverageBaseline: o.CoverageBaseline,
+		ReportFormat:     o.ReportFormat,
+		ReportName:       o.ReportName,
+		OutputDir:        outputDir,
+		Excludes:         o.Excludes,
+		Style:            o.Style,
+		FailedTestPolicy: WarnOnFailedTests,
+		Limits:           o.Limits,
+		GitAttributes:    true,
+		DbOption:         &dbclient.DBOption{},
+		StdOut:           o.StdOut,
+		StdErr:           o.StdErr,
+		Logger:           logger,
+	}
+	// the packages of the cover profile are resolved from the working directory, so it's changed to the module of the worktree.
+	err = inDirectory(filepath.Join(worktreePath, o.ModuleDir), logger, func() error {
+		result.Statistics, err = runDiffCoverage(ctx, option)
+		return err
+	})
+	result.Err = err
+	return result
+}
+
+// runDiffCoverage runs the tests with the option and returns the statistics of the run,
+// the low coverage is not an error since the caller judges the statistics.
+func runDiffCoverage(ctx context.Context, option *GoCoverTestOption) (*report.Statistics, error) {
+	recorder := &statisticsRecorder{}
+	option.ReportGenerators = append(option.ReportGenerators, recorder)
+	executor, err := NewGoCoverTestExecutor(option)
+	if err != nil {
+		return nil, err
+	}
+	err = executor.Run(ctx)
+	var gocoverErr *GoCoverError
+	if err != nil && !(recorder.statistics != nil && errors.As(err, &gocoverErr) && gocoverErr.ExitCode == LowCoverageErrorExitCode) {
+		return recorder.statistics, err
+	}
+	return recorder.statistics, nil
+}
+
+// inDirectory runs the function in the directory, and changes back to the working directory after it returns.
+func inDirectory(dir string, logger logrus.FieldLogger, f func() error) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get working directory: %w", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("change to the module directory: %w", err)
+	}
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			logger.WithError(err).Errorf("change back to %s", wd)
+		}
+	}()
+	return f()
+}
+
+// statisticsRecorder keeps the statistics of the run for the matrix and the bisect.
+type statisticsRecorder struct {
+	statistics *report.Statistics
+}
+
+var _ report.ReportGenerator = (*statisticsRecorder)(nil)
+
+func (r *statisticsRecorder) GenerateReport(statistics *report.Statistics) error {
+	r.statistics = statistics
+	return nil
+}
+
+var unsafeBranchChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// branchDirName returns a directory name of the branch, such as release-1.0 of origin/release/1.0.
+func branchDirName(branch string) string {
+	return strings.Trim(unsafeBranchChars.ReplaceAllString(branch, "-"), "-.")
+}
+
+// WriteMatrix writes the results as a markdown table, a row of each branch.
+func WriteMatrix(w io.Writer, results []*MatrixResult, coverageBaseline float64) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "| Branch | Base | Coverage | Covered Lines | Uncovered Lines | Gate |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- | --- | --- | --- |\n")
+	for _, r := range results {
+		base := r.Base
+		if len(base) > 7 {
+			base = base[:7]
+		}
+		switch {
+		case r.Conflict:
+			fmt.Fprintf(&b, "| %s | %s | - | - | - | conflict |\n", r.Branch, base)
+		case r.Err != nil:
+			fmt.Fprintf(&b, "| %s | %s | - | - | - | error: %s |\n", r.Branch, base, strings.ReplaceAll(r.Err.Error(), "|", `\|`))
+		default:
+			gate := "failed"
+			if r.Passed(coverageBaseline) {
+				gate = "passed"
+			}
+			s := r.Statistics
+			fmt.Fprintf(&b, "| %s | %s | %.1f%% | %d/%d | %d | %s |\n", r.Branch, base, s.TotalCoveragePercent,
+				s.TotalCoveredLines, s.TotalEffectiveLines, s.TotalViolationLines, gate)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,137 @@
+package gocover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=foo", "GIT_AUTHOR_EMAIL=foo@bar.org", "GIT_COMMITTER_NAME=foo", "GIT_COMMITTER_EMAIL=foo@bar.org")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func commitFiles(t *testing.T, dir, message string, files map[string]string) {
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", message)
+}
+
+func TestRunMatrix(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	commitFiles(t, dir, "init", map[string]string{
+		"go.mod":      "module example.com/foo\n\ngo 1.20\n",
+		"foo.go":      "package foo\n\nfunc Foo() int {\n\treturn 1\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo()\n}\n",
+	})
+	git(t, dir, "branch", "release-1.0")
+	git(t, dir, "checkout", "-q", "-b", "release-1.1")
+	commitFiles(t, dir, "release", map[string]string{"bar.go": "package foo\n\nfunc Release() {}\n"})
+	git(t, dir, "checkout", "-q", "main")
+	commitFiles(t, dir, "conflict", map[string]string{"bar.go": "package foo\n\nfunc Main() {}\n"})
+	git(t, dir, "checkout", "-q", "-b", "feature", "release-1.0")
+	commitFiles(t, dir, "fix", map[string]string{
+		"fix.go":      "package foo\n\nfunc Fix(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn -a\n}\n",
+		"fix_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFix(t *testing.T) {\n\tFix(1)\n}\n",
+	})
+	fix := git(t, dir, "rev-parse", "HEAD")
+	commitFiles(t, dir, "add bar.go", map[string]string{"bar.go": "package foo\n\nfunc Feature() {}\n"})
+	feature := git(t, dir, "rev-parse", "HEAD")
+
+	o := NewMatrixOption()
+	o.RepositoryPath = dir
+	o.ModuleDir = "./"
+	o.ReportFormat = "json"
+	o.ReportName = "coverage"
+	o.OutputDir = t.TempDir()
+	o.StdOut, o.StdErr = &bytes.Buffer{}, &bytes.Buffer{}
+	o.Logger = logrus.New()
+
+	t.Run("conflict", func(t *testing.T) {
+		o.Commits = []string{fix, feature}
+		o.Branches = []string{"release-1.0", "release-1.1", "main"}
+		results, err := RunMatrix(context.Background(), o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("expect 3 results, but get %d", len(results))
+		}
+		if r := results[0]; r.Err != nil || r.Conflict || r.Statistics == nil || r.Statistics.TotalEffectiveLines != 3 || r.Statistics.TotalCoveredLines != 2 {
+			t.Errorf("unexpected result of release-1.0 %+v", r)
+		}
+		if !results[1].Conflict || !results[2].Conflict {
+			t.Errorf("expect bar.go conflicts with release-1.1 and main, but get %+v %+v", results[1], results[2])
+		}
+		if _, err := os.Stat(filepath.Join(o.OutputDir, "release-1.0", "coverage.json")); err != nil {
+			t.Errorf("expect the report of release-1.0, but get %v", err)
+		}
+
+		var b bytes.Buffer
+		if err := WriteMatrix(&b, results, 80); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"| release-1.0 | " + results[0].Base[:7] + " | 66.7% | 2/3 | 1 | failed |", "| main | " + results[2].Base[:7] + " | - | - | - | conflict |"} {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("matrix should contain %q:\n%s", want, b.String())
+			}
+		}
+	})
+
+	t.Run("unknown branch", func(t *testing.T) {
+		o.Commits = []string{fix}
+		o.Branches = []string{"release-2.0"}
+		results, err := RunMatrix(context.Background(), o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0].Err == nil {
+			t.Errorf("expect an error of the unknown branch, but get %+v", results[0])
+		}
+	})
+
+	if _, err := RunMatrix(context.Background(), &MatrixOption{}); !errors.Is(err, ErrNoBranch) {
+		t.Errorf("expect ErrNoBranch, but get %v", err)
+	}
+}
+
+func TestWriteMatrix(t *testing.T) {
+	results := []*MatrixResult{
+		{Branch: "release/1.0", Base: "abcdef1234", Statistics: &report.Statistics{TotalCoveragePercent: 90, TotalCoveredLines: 9, TotalEffectiveLines: 10, TotalViolationLines: 1}},
+		{Branch: "release/1.1", Err: errors.New("a|b")},
+	}
+	var b bytes.Buffer
+	if err := WriteMatrix(&b, results, 80); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"| release/1.0 | abcdef1 | 90.0% | 9/10 | 1 | passed |", `| release/1.1 |  | - | - | - | error: a\|b |`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("matrix should contain %q:\n%s", want, b.String())
+		}
+	}
+	if name := branchDirName("origin/release/1.0"); name != "origin-release-1.0" {
+		t.Errorf("unexpected directory name %s", name)
+	}
+}
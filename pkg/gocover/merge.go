@@ -0,0 +1,363 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrIncompatibleShards = errors.New("incompatible shards")
+
+// MergeOption contains the input to the gocover merge command.
+type MergeOption struct {
+	// Documents are the json documents or the json reports of the shards.
+	Documents      []string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module, it's read from the coverage tree of the documents if it's empty,
+	// or from go.mod of the module directory if the documents have no tree.
+	ModulePath string
+
+	CoverageBaseline float64
+	ReportFormat     string
+	ReportName       string
+	OutputDir        string
+	Style            string
+	// Verbose includes the counted lines and the profile blocks they matched in the json report.
+	Verbose bool
+	// JSONOutput is the file that the versioned json document of the merged statistics is written into,
+	// report.StdoutOutput writes it to the stdout, no document is written if it's empty.
+	JSONOutput string
+	// FailedTestPolicy decides whether the failed tests of the shards fail the merge.
+	FailedTestPolicy FailedTestPolicy
+	// ReportGenerators are the extra report generators that run after the default one.
+	ReportGenerators []report.ReportGenerator
+
+	Logger logrus.FieldLogger
+}
+
+// NewMergeOption returns a Merge Option with default values.
+func NewMergeOption() *MergeOption {
+	return &MergeOption{
+		CoverageBaseline: DefaultCoverageBaseline,
+		ReportFormat:     DefaultReportFormat,
+		FailedTestPolicy: WarnOnFailedTests,
+	}
+}
+
+// NewShardMerge creates a GoCover that merges the statistics of the shards into one, and generates the reports
+// and gates the coverage of the merged statistics, as a run that analyzes all the packages.
+func NewShardMerge(o *MergeOption) (GoCover, error) {
+	logger := o.Logger
+	if logger == nil {
+		logger = logrus.New()
+	}
+	logger = logger.WithField("source", "merge")
+
+	if len(o.Documents) == 0 {
+		return nil, fmt.Errorf("%w: no document to merge", ErrIncompatibleShards)
+	}
+	policy := o.FailedTestPolicy
+	if policy == "" {
+		policy = WarnOnFailedTests
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of repo: %w", err)
+	}
+
+	return &shardMerge{
+		option:         o,
+		repositoryPath: repositoryAbsPath,
+		policy:         policy,
+		logger:         logger,
+	}, nil
+}
+
+var _ GoCover = (*shardMerge)(nil)
+
+// shardMerge implements the GoCover interface and merges the statistics of the shards.
+type shardMerge struct {
+	option         *MergeOption
+	repositoryPath string
+	policy         FailedTestPolicy
+	logger         logrus.FieldLogger
+}
+
+func (m *shardMerge) Run(ctx context.Context) error {
+	documents := make([]*report.Document, 0, len(m.option.Documents))
+	for _, name := range m.option.Documents {
+		document, err := readDocument(name)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		documents = append(documents, document)
+	}
+
+	modulePath := m.option.ModulePath
+	if modulePath == "" {
+		modulePath = documentModulePath(documents[0])
+	}
+	if modulePath == "" {
+		path, err := resolveModulePath(filepath.Join(m.repositoryPath, m.option.ModuleDir), "", m.logger)
+		if err != nil {
+			return fmt.Errorf("resolve module path: %w", err)
+		}
+		modulePath = path
+	}
+
+	shards := make([]*report.Statistics, 0, len(documents))
+	for _, d := range documents {
+		shards = append(shards, d.Statistics)
+	}
+	statistics, err := MergeStatistics(shards)
+	if err != nil {
+		return err
+	}
+	m.logger.Infof("merge %d shards: coverage %.2f%% of %d lines", len(shards), statistics.TotalCoveragePercent, statistics.TotalEffectiveLines)
+
+	decideGate(statistics, m.option.CoverageBaseline)
+
+	tree := report.NewCoverageTree(modulePath)
+	for _, p := range statistics.CoverageProfile {
+		node := tree.FindOrCreate(p.FileName)
+		node.TotalLines = int64(p.TotalLines)
+		node.TotalCoveredLines = int64(p.CoveredLines)
+		node.TotalEffectiveLines = int64(p.TotalEffectiveLines)
+		node.TotalIgnoredLines = int64(p.TotalIgnoredLines)
+		node.TotalCoveredButIgnoreLines = int64(p.CoveredButIgnoredLines)
+	}
+	tree.CollectCoverageData()
+
+	extra := m.option.ReportGenerators
+	if m.option.JSONOutput != "" {
+		extra = append([]report.ReportGenerator{report.NewDocumentGenerator(m.option.JSONOutput, os.Stdout, tree, m.option.Verbose, m.logger)}, extra...)
+	}
+	generators := newReportGenerators(&reportGeneratorOption{
+		format:           m.option.ReportFormat,
+		style:            m.option.Style,
+		outputDir:        m.option.OutputDir,
+		reportName:       m.option.ReportName,
+		verbose:          m.option.Verbose,
+		modulePath:       modulePath,
+		moduleDir:        filepath.Join(m.repositoryPath, m.option.ModuleDir),
+		contextLines:     report.DefaultContextLines,
+		coverageBaseline: m.option.CoverageBaseline,
+		sarif: &report.SARIFReportOption{
+			ModulePath: modulePath,
+			ModuleDir:  m.option.ModuleDir,
+		},
+		sonarQube: &report.SonarQubeReportOption{
+			ModulePath: modulePath,
+			ModuleDir:  m.option.ModuleDir,
+		},
+		extra:  extra,
+		logger: m.logger,
+	})
+	for _, g := range generators {
+		if err := g.GenerateReport(statistics); err != nil {
+			return fmt.Errorf("generate report: %w", err)
+		}
+	}
+	dump(tree.All(), m.logger)
+
+	if err := checkFailedTests(statistics, m.policy, m.logger); err != nil {
+		return err
+	}
+	return checkGate(statistics, m.option.CoverageBaseline)
+}
+
+func readDocument(name string) (*report.Document, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return report.ReadDocument(f)
+}
+
+// documentModulePath returns the path of the root of the coverage tree of the document, which is the module path,
+// it's empty if the document has no tree, such as a json report.
+func documentModulePath(document *report.Document) string {
+	root := ""
+	for _, node := range document.Tree {
+		if root == "" || len(node.Path) < len(root) {
+			root = node.Path
+		}
+	}
+	return root
+}
+
+// MergeStatistics merges the statistics of the shards of a run into the statistics of all the packages.
+// The shards must be of the same type and compare with the same branch, and if they're sharded, they must be
+// all the shards of the same count, such as 1/3, 2/3 and 3/3. The profiles of the shards are disjoint,
+// the totals, the layers and the files of each shard are combined, and what every shard sees from the same diff,
+// such as the added TODO comments and the embedded files, is kept once.
+func MergeStatistics(shards []*report.Statistics) (*report.Statistics, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("%w: no shard", ErrIncompatibleShards)
+	}
+	if err := checkShards(shards); err != nil {
+		return nil, err
+	}
+
+	first := shards[0]
+	merged := &report.Statistics{
+		ComparedBranch: first.ComparedBranch,
+		DiffTarget:     first.DiffTarget,
+		LineMapping:    first.LineMapping,
+		StatisticsType: first.StatisticsType,
+		Todos:          first.Todos,
+		FileBaseline:   first.FileBaseline,
+	}
+	files := make(map[string]bool)
+	excludes := make(map[string]bool)
+	nonCoverable := make(map[string]bool)
+	skipEntries := make(map[string]*report.SkipListEntry)
+	skipped := make(map[string]bool)
+	helpers := make(map[string]bool)
+	embedded := make(map[string]bool)
+	testPackages := make(map[string]bool)
+	layers := make(map[string]*report.LayerStatistics)
+	layerCovered := make(map[string]int)
+	packages := make(map[string]bool)
+	for _, s := range shards {
+		for _, p := range s.CoverageProfile {
+			if files[p.FileName] {
+				return nil, fmt.Errorf("%w: %s is in more than one shard", ErrIncompatibleShards, p.FileName)
+			}
+			files[p.FileName] = true
+			merged.CoverageProfile = append(merged.CoverageProfile, p)
+		}
+		for _, t := range s.TestPackages {
+			if !testPackages[t.Name] {
+				testPackages[t.Name] = true
+				merged.TestPackages = append(merged.TestPackages, t)
+			}
+		}
+		merged.TruncatedFiles = append(merged.TruncatedFiles, s.TruncatedFiles...)
+		merged.ExcludeFiles = appendUnique(merged.ExcludeFiles, excludes, s.ExcludeFiles)
+		merged.NonCoverableFiles = appendUnique(merged.NonCoverableFiles, nonCoverable, s.NonCoverableFiles)
+		// the shards share the skip list, each records the files it skipped.
+		for _, e := range s.SkipList {
+			entry, ok := skipEntries[e.Pattern]
+			if !ok {
+				entry = &report.SkipListEntry{Pattern: e.Pattern, Owner: e.Owner, Expires: e.Expires, Reason: e.Reason, Expired: e.Expired}
+				skipEntries[e.Pattern] = entry
+				merged.SkipList = append(merged.SkipList, entry)
+			}
+			entry.Files = appendUnique(entry.Files, skipped, e.Files)
+		}
+		merged.TestHelperPackages = appendUnique(merged.TestHelperPackages, helpers, s.TestHelperPackages)
+		for _, e := range s.EmbeddedFiles {
+			if !embedded[e.FileName] {
+				embedded[e.FileName] = true
+				merged.EmbeddedFiles = append(merged.EmbeddedFiles, e)
+			}
+		}
+		if merged.Bypass == nil {
+			merged.Bypass = s.Bypass
+		}
+		if s.Errors != nil {
+			for _, e := range s.Errors.Errors {
+				if !containsError(merged.Errors, e) {
+					nonFatalErrors(merged).Errors = append(nonFatalErrors(merged).Errors, e)
+				}
+			}
+		}
+		// the shards analyze disjoint packages, a package is only in more than one unsharded statistics.
+		for _, p := range s.Packages {
+			if !packages[p.Name] {
+				packages[p.Name] = true
+				merged.Packages = append(merged.Packages, p)
+			}
+		}
+		for _, l := range s.Layers {
+			layer, ok := layers[l.Name]
+			if !ok {
+				layer = &report.LayerStatistics{Name: l.Name, Baseline: l.Baseline}
+				layers[l.Name] = layer
+				merged.Layers = append(merged.Layers, layer)
+			}
+			layer.Files += l.Files
+			layer.TotalEffectiveLines += l.TotalEffectiveLines
+			layer.TotalCoveredLines += l.TotalCoveredLines
+			// the covered lines that are not ignored are restored from the coverage of the shard.
+			layerCovered[l.Name] += int(l.CoveragePercent*float64(l.TotalEffectiveLines)/100 + 0.5)
+		}
+	}
+	for _, l := range merged.Layers {
+		l.CoveragePercent = calculateCoverage(int64(layerCovered[l.Name]), int64(l.TotalEffectiveLines))
+	}
+	sort.Strings(merged.ExcludeFiles)
+	sort.Slice(merged.Packages, func(i, j int) bool { return merged.Packages[i].Name < merged.Packages[j].Name })
+
+	reBuildStatistics(merged, nil)
+	return merged, nil
+}
+
+// checkShards checks the shards are of the same run, and all the shards are present if they're sharded.
+func checkShards(shards []*report.Statistics) error {
+	first := shards[0]
+	seen := make(map[int]bool)
+	for _, s := range shards {
+		if s.StatisticsType != first.StatisticsType || s.ComparedBranch != first.ComparedBranch || s.DiffTarget != first.DiffTarget {
+			return fmt.Errorf("%w: %s coverage compared with %q and %s coverage compared with %q", ErrIncompatibleShards,
+				first.StatisticsType, first.ComparedBranch, s.StatisticsType, s.ComparedBranch)
+		}
+		if (s.Shard == nil) != (first.Shard == nil) {
+			return fmt.Errorf("%w: the statistics of a shard and of all the packages", ErrIncompatibleShards)
+		}
+		if s.Shard == nil {
+			continue
+		}
+		if s.Shard.Count != first.Shard.Count {
+			return fmt.Errorf("%w: shard %s and shard %s", ErrIncompatibleShards, first.Shard, s.Shard)
+		}
+		if seen[s.Shard.Index] {
+			return fmt.Errorf("%w: shard %s is merged twice", ErrIncompatibleShards, s.Shard)
+		}
+		seen[s.Shard.Index] = true
+	}
+	if first.Shard != nil {
+		for i := 1; i <= first.Shard.Count; i++ {
+			if !seen[i] {
+				return fmt.Errorf("%w: shard %d/%d is missing", ErrIncompatibleShards, i, first.Shard.Count)
+			}
+		}
+	}
+	return nil
+}
+
+// appendUnique appends the values that are not in seen, and adds them to seen.
+func appendUnique(result []string, seen map[string]bool, values []string) []string {
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func containsError(m *report.MultiError, e *report.NonFatalError) bool {
+	if m == nil {
+		return false
+	}
+	for _, existing := range m.Errors {
+		if reflect.DeepEqual(existing, e) {
+			return true
+		}
+	}
+	return false
+}
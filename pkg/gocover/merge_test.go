@@ -0,0 +1,147 @@
+package gocover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/testresult"
+	"github.com/sirupsen/logrus"
+)
+
+func testShards() []*report.Statistics {
+	todos := &report.TodoStatistics{AddedLines: 10, MaxTodos: -1, MaxDensity: -1}
+	return []*report.Statistics{
+		{
+			StatisticsType: report.DiffStatisticsType,
+			ComparedBranch: "origin/main",
+			Shard:          &report.Shard{Index: 1, Count: 2},
+			CoverageProfile: []*report.CoverageProfile{
+				{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", TotalLines: 4, TotalEffectiveLines: 3, TotalIgnoredLines: 1, CoveredLines: 3, CoveredButIgnoredLines: 1, TotalViolationLines: []int{5}},
+			},
+			ExcludeFiles:      []string{"github.com/Azure/gocover/pkg/foo/mock.go"},
+			NonCoverableFiles: []string{"tools/gen.go"},
+			SkipList:          []*report.SkipListEntry{{Pattern: "**/plugins/**", Owner: "foo", Expires: "2023-06-30", Files: []string{"github.com/Azure/gocover/pkg/foo/plugins/a.go"}}},
+			TestPackages:      []*testresult.Package{{Name: "github.com/Azure/gocover/pkg/foo", Status: testresult.PassAction}},
+			Layers:            []*report.LayerStatistics{{Name: "domain", Files: 1, TotalEffectiveLines: 3, TotalCoveredLines: 3, CoveragePercent: 200.0 / 3, Baseline: 80}},
+			Todos:             todos,
+		},
+		{
+			StatisticsType: report.DiffStatisticsType,
+			ComparedBranch: "origin/main",
+			Shard:          &report.Shard{Index: 2, Count: 2},
+			CoverageProfile: []*report.CoverageProfile{
+				{FileName: "github.com/Azure/gocover/pkg/bar/bar.go", TotalLines: 2, TotalEffectiveLines: 2, CoveredLines: 2},
+			},
+			NonCoverableFiles: []string{"tools/gen.go"},
+			SkipList:          []*report.SkipListEntry{{Pattern: "**/plugins/**", Owner: "foo", Expires: "2023-06-30", Files: []string{"github.com/Azure/gocover/pkg/bar/plugins/b.go"}}},
+			Layers:            []*report.LayerStatistics{{Name: "domain", Files: 1, TotalEffectiveLines: 2, TotalCoveredLines: 2, CoveragePercent: 100, Baseline: 80}},
+			Todos:             todos,
+		},
+	}
+}
+
+func TestMergeStatistics(t *testing.T) {
+	merged, err := MergeStatistics(testShards())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.CoverageProfile) != 2 || merged.TotalLines != 6 || merged.TotalEffectiveLines != 5 || merged.TotalCoveredLines != 5 ||
+		merged.TotalCoveredButIgnoredLines != 1 || merged.TotalViolationLines != 1 || merged.TotalCoveragePercent != 80 {
+		t.Errorf("unexpected merged statistics %+v", merged)
+	}
+	if merged.Shard != nil || merged.ComparedBranch != "origin/main" || merged.StatisticsType != report.DiffStatisticsType {
+		t.Errorf("unexpected merged run %+v", merged)
+	}
+	if len(merged.NonCoverableFiles) != 1 || len(merged.ExcludeFiles) != 1 || len(merged.TestPackages) != 1 || merged.Todos.AddedLines != 10 {
+		t.Errorf("unexpected merged files %+v", merged)
+	}
+	if len(merged.SkipList) != 1 || len(merged.SkipList[0].Files) != 2 {
+		t.Errorf("unexpected merged skip list %+v", merged.SkipList)
+	}
+	if len(merged.Layers) != 1 || merged.Layers[0].Files != 2 || merged.Layers[0].TotalEffectiveLines != 5 || merged.Layers[0].CoveragePercent != 80 {
+		t.Errorf("unexpected merged layers %+v", merged.Layers[0])
+	}
+	if _, err := MergeStatistics(testShards()[:1]); !errors.Is(err, ErrIncompatibleShards) {
+		t.Errorf("expect ErrIncompatibleShards of the missing shard, but get %v", err)
+	}
+
+	testCases := map[string]func(shards []*report.Statistics){
+		"duplicated shard": func(shards []*report.Statistics) { shards[1].Shard.Index = 1 },
+		"different count":  func(shards []*report.Statistics) { shards[1].Shard.Count = 3 },
+		"different branch": func(shards []*report.Statistics) { shards[1].ComparedBranch = "origin/release" },
+		"unsharded":        func(shards []*report.Statistics) { shards[1].Shard = nil },
+		"duplicated file": func(shards []*report.Statistics) {
+			shards[1].CoverageProfile[0].FileName = shards[0].CoverageProfile[0].FileName
+		},
+		"full and the diff": func(shards []*report.Statistics) { shards[1].StatisticsType = report.FullStatisticsType },
+	}
+	for name, change := range testCases {
+		shards := testShards()
+		change(shards)
+		if _, err := MergeStatistics(shards); !errors.Is(err, ErrIncompatibleShards) {
+			t.Errorf("%s: expect ErrIncompatibleShards, but get %v", name, err)
+		}
+	}
+}
+
+func TestShardMerge(t *testing.T) {
+	dir := t.TempDir()
+	var documents []string
+	for i, s := range testShards() {
+		data, err := json.Marshal(&report.Document{SchemaVersion: report.SchemaVersion, Statistics: s, Tree: []*report.AllInformation{
+			{Path: "github.com/Azure/gocover"}, {Path: "github.com/Azure/gocover/pkg"},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(dir, string(rune('a'+i))+".json")
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		documents = append(documents, name)
+	}
+
+	o := NewMergeOption()
+	o.Documents = documents
+	o.RepositoryPath = dir
+	o.ModuleDir = "."
+	o.ReportFormat = FuncReportFormat
+	o.ReportName = "coverage"
+	o.OutputDir = dir
+	o.JSONOutput = filepath.Join(dir, "merged.json")
+	o.CoverageBaseline = 90
+	o.Logger = logrus.New()
+	m, err := NewShardMerge(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Run(context.Background())
+	var exitErr *GoCoverError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != LowCoverageErrorExitCode {
+		t.Errorf("expect the low coverage error of the merged coverage, but get %v", err)
+	}
+
+	f, err := os.Open(o.JSONOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	document, err := report.ReadDocument(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.Statistics.TotalCoveragePercent != 80 || documentModulePath(document) != "github.com/Azure/gocover" {
+		t.Errorf("unexpected merged document %+v", document.Statistics)
+	}
+	if len(document.Statistics.SkipList) != 1 || len(document.Statistics.SkipList[0].Files) != 2 {
+		t.Errorf("expect the skip list of the shards in the merged document, but get %+v", document.Statistics.SkipList)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "coverage.func.txt")); err != nil {
+		t.Errorf("expect the report of the merged statistics, but get %v", err)
+	}
+}
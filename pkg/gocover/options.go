@@ -2,9 +2,14 @@ package gocover
 
 import (
 	"errors"
+	"fmt"
 	"io"
 
+	"github.com/Azure/gocover/pkg/annotation"
 	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/report"
 	"github.com/sirupsen/logrus"
 )
 
@@ -13,6 +18,9 @@ type FullOption struct {
 	CoverProfiles  []string
 	RepositoryPath string
 	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty,
+	// or derived from GOPATH if there is no go.mod.
+	ModulePath string
 
 	CoverageBaseline float64
 	ReportFormat     string
@@ -20,8 +28,58 @@ type FullOption struct {
 	OutputDir        string
 	Excludes         []string
 	Style            string
+	// SkipListFile is the skip list file of the files whose coverage collection is flaky, no files are skipped if it's empty.
+	SkipListFile string
+	// Verbose includes the counted lines and the profile blocks they matched in the json report.
+	Verbose bool
+	// ArtifactsDir is the directory that all the artifacts are written into, no artifacts are written if it's empty.
+	ArtifactsDir string
+	// JSONOutput is the file that the versioned json document is written into, report.StdoutOutput writes it to the stdout,
+	// no document is written if it's empty.
+	JSONOutput string
+	// SARIF decides the rule id, the level and the granularity of the results of the sarif report.
+	SARIF report.SARIFSettings
+	// SonarQubePathMappings map the repository relative paths of the sonarqube report, in the format of from=to.
+	SonarQubePathMappings []string
+	// Theme decides the theme, the custom css and the logo of the html and the annotated reports.
+	Theme report.ThemeSettings
+
+	// TestResults are the files of `go test -json` output, coverage of the failed packages is flagged as unreliable.
+	TestResults []string
+	// FailedTestPolicy decides how the coverage of the failed packages is treated.
+	FailedTestPolicy FailedTestPolicy
+	// Limits bounds the file size and the retained section contents.
+	Limits Limits
+	// StrictParse aborts the run on the first file that fails to parse,
+	// otherwise the failure is recorded in the reports and the run continues.
+	StrictParse bool
+	// Layers label the files with architecture layers, the coverage of each layer is reported and gated by its baseline.
+	Layers []Layer
+	// PackageBaselines are the expected coverage of the packages, a package is gated by the first pattern it matches.
+	PackageBaselines []PackageBaseline
+	// GracePeriods are the files that don't count for the coverage gate until their dates, their uncovered lines are still reported.
+	GracePeriods []GracePeriod
+	// FileBaseline is the expected coverage of each counted file, the files are not gated if it's zero.
+	FileBaseline float64
+	// GitAttributes excludes the files marked linguist-generated or export-ignore in the .gitattributes files.
+	GitAttributes bool
+	// Ratchet gates the coverage of each package by the baseline file written by gocover baseline write.
+	Ratchet RatchetOption
+	// TestHelpers decides which packages are test helpers, their missing tests are not reported.
+	TestHelpers TestHelperOption
+	// IgnoreProviders supply the exemptions from the external systems, they're applied besides the ignore annotations.
+	IgnoreProviders []annotation.IgnoreProvider
+	// Shard is the subset of the packages that the run analyzes in the format of index/count, such as 2/4,
+	// so the parallel jobs analyze a large module and their json documents are merged, all the packages are analyzed if it's empty.
+	Shard string
 
 	DbOption *dbclient.DBOption
+	// AuditFile is the file that records external actions, no audit log is written if it's empty.
+	AuditFile string
+	// ReportGenerators are the extra report generators that run after the default one.
+	ReportGenerators []report.ReportGenerator
+	// Annotators add the information to the statistics before the report generators run.
+	Annotators []report.Annotator
 
 	Logger logrus.FieldLogger
 }
@@ -31,6 +89,10 @@ func NewFullOption() *FullOption {
 	return &FullOption{
 		CoverageBaseline: DefaultCoverageBaseline,
 		ReportFormat:     DefaultReportFormat,
+		FailedTestPolicy: WarnOnFailedTests,
+		Limits:           DefaultLimits(),
+		TestHelpers:      DefaultTestHelperOption(),
+		GitAttributes:    true,
 	}
 }
 
@@ -40,11 +102,18 @@ func (o *FullOption) Validate() error {
 
 // DiffOption contains the input to the gocover diff command.
 type DiffOption struct {
-	CoverProfiles  []string
-	CompareBranch  string
+	CoverProfiles []string
+	CompareBranch string
+	// DiffTarget is what compares with the compare branch, one of HEAD, worktree or a revision like stash@{0}, HEAD is used if it's empty.
+	DiffTarget string
+	// AgainstTag compares with the latest semver tag reachable from HEAD rather than the compare branch,
+	// so release pipelines gate all the changes since the last release.
+	AgainstTag     bool
 	RepositoryPath string
 	ModuleDir      string
-	ModulePath     string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty,
+	// or derived from GOPATH if there is no go.mod.
+	ModulePath string
 
 	CoverageBaseline float64
 	ReportFormat     string
@@ -52,8 +121,79 @@ type DiffOption struct {
 	OutputDir        string
 	Excludes         []string
 	Style            string
+	Bypass           BypassOption
+	// SkipListFile is the skip list file of the files whose coverage collection is flaky, no files are skipped if it's empty.
+	SkipListFile string
+	// Verbose includes the counted lines and the profile blocks they matched in the json report.
+	Verbose bool
+	// ArtifactsDir is the directory that all the artifacts are written into, no artifacts are written if it's empty.
+	ArtifactsDir string
+	// JSONOutput is the file that the versioned json document is written into, report.StdoutOutput writes it to the stdout,
+	// no document is written if it's empty.
+	JSONOutput string
+	// SARIF decides the rule id, the level and the granularity of the results of the sarif report.
+	SARIF report.SARIFSettings
+	// SonarQubePathMappings map the repository relative paths of the sonarqube report, in the format of from=to.
+	SonarQubePathMappings []string
+	// Theme decides the theme, the custom css and the logo of the html and the annotated reports.
+	Theme report.ThemeSettings
+
+	// TestResults are the files of `go test -json` output, coverage of the failed packages is flagged as unreliable.
+	TestResults []string
+	// FailedTestPolicy decides how the coverage of the failed packages is treated.
+	FailedTestPolicy FailedTestPolicy
+	// Limits bounds the file size and the retained section contents.
+	Limits Limits
+	// StrictParse aborts the run on the first file that fails to parse,
+	// otherwise the failure is recorded in the reports and the run continues.
+	StrictParse bool
+	// Layers label the files with architecture layers, the coverage of each layer is reported and gated by its baseline.
+	Layers []Layer
+	// PackageBaselines are the expected coverage of the packages, a package is gated by the first pattern it matches.
+	PackageBaselines []PackageBaseline
+	// GracePeriods are the files that don't count for the coverage gate until their dates, their uncovered lines are still reported.
+	GracePeriods []GracePeriod
+	// FileBaseline is the expected coverage of each counted file, the files are not gated if it's zero.
+	FileBaseline float64
+	// GitAttributes excludes the files marked linguist-generated or export-ignore in the .gitattributes files.
+	GitAttributes bool
+	// TestHelpers decides which packages are test helpers, their missing tests are not reported.
+	TestHelpers TestHelperOption
+	// IgnoreProviders supply the exemptions from the external systems, they're applied besides the ignore annotations.
+	IgnoreProviders []annotation.IgnoreProvider
+	// Shard is the subset of the packages that the run analyzes in the format of index/count, such as 2/4,
+	// so the parallel jobs analyze a large module and their json documents are merged, all the packages are analyzed if it's empty.
+	Shard string
+	// Todos decides whether the TODO and FIXME comments added in the changed lines are counted and gated.
+	Todos TodoOption
+	// LineMapping decides which changed lines make a multi-line statement changed, span is used if it's empty.
+	LineMapping parser.LineMapping
+	// DiffView collects the added and deleted sections of the files, so the annotated report renders the diff.
+	DiffView bool
+	// Hunks prints the changed hunks of the counted files after the reports, the lines are colored by coverage status.
+	Hunks HunkOption
+	// DiffAlgorithm is the algorithm that finds the changed lines of the modified files, the myers diff is used if it's empty.
+	DiffAlgorithm gittool.DiffAlgorithm
+	// DiffContext is the number of the unchanged lines shown around the changed lines of the annotated report,
+	// report.DefaultContextLines is used if it's zero.
+	DiffContext int
+	// Regression compares the full coverage of the touched files with the compared branch, and gates the files that drop.
+	Regression RegressionOption
+	// Rollout enforces the coverage gate on a percent of the pull requests, the gate of the others only warns.
+	Rollout RolloutOption
+	// ParseCache keeps the parsed files across the runs of a long-running process, such as the daemon,
+	// the files are parsed by each run if it's nil.
+	ParseCache *parser.Cache
+	// GitClient is the client of the repository that is reused across the runs, the repository is opened by each run if it's nil.
+	GitClient gittool.GitClient
 
 	DbOption *dbclient.DBOption
+	// AuditFile is the file that records external actions, no audit log is written if it's empty.
+	AuditFile string
+	// ReportGenerators are the extra report generators that run after the default one.
+	ReportGenerators []report.ReportGenerator
+	// Annotators add the information to the statistics before the report generators run.
+	Annotators []report.Annotator
 
 	Logger logrus.FieldLogger
 }
@@ -64,6 +204,11 @@ func NewDiffOption() *DiffOption {
 		CompareBranch:    DefaultCompareBranch,
 		CoverageBaseline: DefaultCoverageBaseline,
 		ReportFormat:     DefaultReportFormat,
+		FailedTestPolicy: WarnOnFailedTests,
+		Limits:           DefaultLimits(),
+		TestHelpers:      DefaultTestHelperOption(),
+		GitAttributes:    true,
+		Todos:            DefaultTodoOption(),
 	}
 }
 
@@ -82,20 +227,52 @@ const (
 	GinkgoExecutor ExecutorMode = "ginkgo"
 )
 
+// FailedTestPolicy decides how the coverage of the packages whose tests failed is treated.
+type FailedTestPolicy string
+
+const (
+	// FailOnFailedTests generates the reports and exits with the unit test failed error code.
+	FailOnFailedTests FailedTestPolicy = "fail"
+	// WarnOnFailedTests flags the coverage of the failed packages as unreliable and warns.
+	WarnOnFailedTests FailedTestPolicy = "warn"
+	// ExcludeFailedTests excludes the files of the failed packages from the coverage calculation.
+	ExcludeFailedTests FailedTestPolicy = "exclude"
+)
+
+// Validate validates the policy.
+func (p FailedTestPolicy) Validate() error {
+	switch p {
+	case FailOnFailedTests, WarnOnFailedTests, ExcludeFailedTests:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFailedTestPolicy, p)
+	}
+}
+
 var ErrUnknownCoverageMode = errors.New("unknown coverage mode")
+var ErrUnknownFailedTestPolicy = errors.New("unknown failed test policy, one of: fail, warn, exclude")
 var ErrUnknownExecutorMode = errors.New("unknown executor mode")
 
 // GoCoverTestOption contains the input to the gocover govtest command.
 type GoCoverTestOption struct {
-	CoverProfiles  []string
-	CompareBranch  string
+	CoverProfiles []string
+	CompareBranch string
+	// DiffTarget is what compares with the compare branch in diff coverage mode.
+	DiffTarget string
+	// AgainstTag compares with the latest semver tag reachable from HEAD in diff coverage mode.
+	AgainstTag     bool
 	RepositoryPath string
 	ModuleDir      string
-	ModulePath     string
-	CoverageMode   CoverageMode
-	ExecutorMode   ExecutorMode
-	GinkgoFlags    []string
-	GoFlags        []string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty,
+	// or derived from GOPATH if there is no go.mod.
+	ModulePath   string
+	CoverageMode CoverageMode
+	ExecutorMode ExecutorMode
+	GinkgoFlags  []string
+	GoFlags      []string
+	// ChangedPackages only runs the tests of the packages whose go files are changed against the compared branch,
+	// it's only supported by the go executor in diff coverage mode, the tests of all the packages run otherwise.
+	ChangedPackages bool
 
 	CoverageBaseline float64
 	ReportFormat     string
@@ -103,19 +280,104 @@ type GoCoverTestOption struct {
 	OutputDir        string
 	Excludes         []string
 	Style            string
+	Bypass           BypassOption
+	// SkipListFile is the skip list file of the files whose coverage collection is flaky, no files are skipped if it's empty.
+	SkipListFile string
+	// Verbose includes the counted lines and the profile blocks they matched in the json report.
+	Verbose bool
+	// ArtifactsDir is the directory that all the artifacts are written into, no artifacts are written if it's empty.
+	ArtifactsDir string
+	// JSONOutput is the file that the versioned json document is written into, report.StdoutOutput writes it to the stdout,
+	// no document is written if it's empty.
+	JSONOutput string
+	// SARIF decides the rule id, the level and the granularity of the results of the sarif report.
+	SARIF report.SARIFSettings
+	// SonarQubePathMappings map the repository relative paths of the sonarqube report, in the format of from=to.
+	SonarQubePathMappings []string
+	// Theme decides the theme, the custom css and the logo of the html and the annotated reports.
+	Theme report.ThemeSettings
+	// FailedTestPolicy decides how the coverage of the packages whose tests failed is treated,
+	// it's only supported by the go executor, ginkgo executor always fails.
+	FailedTestPolicy FailedTestPolicy
+	// TestResults are the extra files of `go test -json` output, the go executor adds the output of its test run.
+	TestResults []string
+	// Progress replaces the test output with the live progress of the go executor, the failed tests are printed
+	// as soon as they fail, and the uncovered lines of each file are printed after the coverage calculation.
+	Progress ProgressOption
+	// Limits bounds the file size and the retained section contents.
+	Limits Limits
+	// StrictParse aborts the run on the first file that fails to parse,
+	// otherwise the failure is recorded in the reports and the run continues.
+	StrictParse bool
+	// Layers label the files with architecture layers, the coverage of each layer is reported and gated by its baseline.
+	Layers []Layer
+	// PackageBaselines are the expected coverage of the packages, a package is gated by the first pattern it matches.
+	PackageBaselines []PackageBaseline
+	// GracePeriods are the files that don't count for the coverage gate until their dates, their uncovered lines are still reported.
+	GracePeriods []GracePeriod
+	// FileBaseline is the expected coverage of each counted file, the files are not gated if it's zero.
+	FileBaseline float64
+	// GitAttributes excludes the files marked linguist-generated or export-ignore in the .gitattributes files.
+	GitAttributes bool
+	// Ratchet gates the coverage of each package by the baseline file in the full coverage mode written by gocover baseline write.
+	Ratchet RatchetOption
+	// TestHelpers decides which packages are test helpers, their missing tests are not reported.
+	TestHelpers TestHelperOption
+	// IgnoreProviders supply the exemptions from the external systems, they're applied besides the ignore annotations.
+	IgnoreProviders []annotation.IgnoreProvider
+	// Shard is the subset of the packages that the run analyzes in the format of index/count, such as 2/4,
+	// so the parallel jobs analyze a large module and their json documents are merged, all the packages are analyzed if it's empty.
+	Shard string
+	// Todos decides whether the TODO and FIXME comments added in the changed lines are counted and gated.
+	Todos TodoOption
+	// LineMapping decides which changed lines make a multi-line statement changed, span is used if it's empty.
+	LineMapping parser.LineMapping
+	// DiffView collects the added and deleted sections of the files, so the annotated report renders the diff.
+	DiffView bool
+	// Hunks prints the changed hunks of the counted files after the reports, the lines are colored by coverage status.
+	Hunks HunkOption
+	// DiffAlgorithm is the algorithm that finds the changed lines of the modified files, the myers diff is used if it's empty.
+	DiffAlgorithm gittool.DiffAlgorithm
+	// DiffContext is the number of the unchanged lines shown around the changed lines of the annotated report,
+	// report.DefaultContextLines is used if it's zero.
+	DiffContext int
+	// Regression compares the full coverage of the touched files with the compared branch, and gates the files that drop.
+	Regression RegressionOption
+	// Rollout enforces the coverage gate on a percent of the pull requests, the gate of the others only warns.
+	Rollout RolloutOption
 
 	DbOption *dbclient.DBOption
+	// AuditFile is the file that records external actions, no audit log is written if it's empty.
+	AuditFile string
+	// ReportGenerators are the extra report generators that run after the default one.
+	ReportGenerators []report.ReportGenerator
+	// Annotators add the information to the statistics before the report generators run.
+	Annotators []report.Annotator
 
 	StdOut io.Writer
 	StdErr io.Writer
 	Logger logrus.FieldLogger
 }
 
+// ProgressOption decides whether and how the live progress of the test run is rendered.
+type ProgressOption struct {
+	// Enabled renders the live progress rather than the test output.
+	Enabled bool
+	// Interactive redraws a status line of the running packages, such as the stdout is a terminal,
+	// otherwise a line is printed when each package finishes.
+	Interactive bool
+}
+
 // NewGoCoverTestOption returns a Options with default values.
 func NewGoCoverTestOption() *GoCoverTestOption {
 	return &GoCoverTestOption{
 		CompareBranch:    DefaultCompareBranch,
 		CoverageBaseline: DefaultCoverageBaseline,
 		ReportFormat:     DefaultReportFormat,
+		FailedTestPolicy: FailOnFailedTests,
+		Limits:           DefaultLimits(),
+		TestHelpers:      DefaultTestHelperOption(),
+		GitAttributes:    true,
+		Todos:            DefaultTodoOption(),
 	}
 }
@@ -0,0 +1,193 @@
+package gocover
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/parser"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// ANSI escape codes of the overlay.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// lineStatus is the coverage status of a source line.
+type lineStatus int
+
+const (
+	// notCounted means no statement starts at the line.
+	notCounted lineStatus = iota
+	ignoredLine
+	coveredLine
+	uncoveredLine
+)
+
+// OverlayOption contains the input for gocover overlay command.
+type OverlayOption struct {
+	CoverProfiles  []string
+	CompareBranch  string
+	DiffTarget     string
+	RepositoryPath string
+	ModuleDir      string
+	Excludes       []string
+
+	// Color colors the added lines by the coverage status with ANSI escape codes.
+	Color bool
+	// Output is where the overlay is written to, os.Stdout is used if it's nil.
+	Output io.Writer
+
+	Logger logrus.FieldLogger
+}
+
+// NewOverlayOption returns a Overlay Option with default values.
+func NewOverlayOption() *OverlayOption {
+	return &OverlayOption{
+		CompareBranch: DefaultCompareBranch,
+		Color:         true,
+	}
+}
+
+// NewDiffOverlay creates a GoCover that writes the unified diff of the changes,
+// and colors the added lines by coverage status.
+func NewDiffOverlay(o *OverlayOption) (GoCover, error) {
+	d, err := NewDiffCover(&DiffOption{
+		CoverProfiles:  o.CoverProfiles,
+		CompareBranch:  o.CompareBranch,
+		DiffTarget:     o.DiffTarget,
+		RepositoryPath: o.RepositoryPath,
+		ModuleDir:      o.ModuleDir,
+		Excludes:       o.Excludes,
+		GitAttributes:  true,
+		DbOption:       &dbclient.DBOption{},
+		Logger:         o.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := o.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	return &diffOverlay{
+		diff:   d.(*diffCover),
+		color:  o.Color,
+		output: output,
+	}, nil
+}
+
+var _ GoCover = (*diffOverlay)(nil)
+
+// diffOverlay implements the GoCover interface and writes the coverage overlay of the diff.
+type diffOverlay struct {
+	diff   *diffCover
+	color  bool
+	output io.Writer
+}
+
+func (o *diffOverlay) Run(ctx context.Context) error {
+	changes, err := o.diff.getGitChanges()
+	if err != nil {
+		return fmt.Errorf("overlay: %w", err)
+	}
+	statistics, err := o.diff.calculateStatistics(changes)
+	if err != nil {
+		return fmt.Errorf("overlay: %w", err)
+	}
+
+	w := bufio.NewWriter(o.output)
+	writeOverlay(w, changes, statistics, o.color)
+	return w.Flush()
+}
+
+// writeOverlay writes the changes in unified diff format, the added lines are colored by coverage status if color is true.
+func writeOverlay(w io.Writer, changes []*gittool.Change, statistics *report.Statistics, color bool) {
+	paint := func(code, s string) string {
+		if !color || code == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	for _, change := range changes {
+		if change.Mode == gittool.DeleteMode {
+			continue
+		}
+
+		fmt.Fprintln(w, paint(ansiBold, fmt.Sprintf("diff --git a/%s b/%s", change.FileName, change.FileName)))
+		if change.Mode == gittool.NewMode {
+			fmt.Fprintln(w, paint(ansiBold, "new file"))
+			fmt.Fprintln(w, paint(ansiBold, "--- /dev/null"))
+		} else {
+			fmt.Fprintln(w, paint(ansiBold, fmt.Sprintf("--- a/%s", change.FileName)))
+		}
+		fmt.Fprintln(w, paint(ansiBold, fmt.Sprintf("+++ b/%s", change.FileName)))
+
+		lines := lineStatuses(findCoverageProfile(statistics, change.FileName))
+		for _, section := range change.Sections {
+			fmt.Fprintln(w, paint(ansiCyan, fmt.Sprintf("@@ +%d,%d @@", section.StartLine, len(section.Contents))))
+			for i, content := range section.Contents {
+				fmt.Fprintln(w, paint(statusColor(lines[section.StartLine+i]), "+"+content))
+			}
+		}
+	}
+}
+
+// findCoverageProfile finds the coverage profile of the changed file, it returns nil if not found.
+func findCoverageProfile(statistics *report.Statistics, fileName string) *report.CoverageProfile {
+	for _, p := range statistics.CoverageProfile {
+		if parser.InFolder(p.FileName, fileName) {
+			return p
+		}
+	}
+	return nil
+}
+
+// lineStatuses returns the coverage status of each counted line of the profile.
+// If statements of a line differ, uncovered takes precedence over covered, and covered over ignored.
+func lineStatuses(p *report.CoverageProfile) map[int]lineStatus {
+	statuses := make(map[int]lineStatus)
+	if p == nil {
+		return statuses
+	}
+
+	for _, l := range p.CountedLines {
+		status := ignoredLine
+		if !l.Ignored {
+			status = uncoveredLine
+			if l.Covered {
+				status = coveredLine
+			}
+		}
+		if status > statuses[l.Line] {
+			statuses[l.Line] = status
+		}
+	}
+	return statuses
+}
+
+func statusColor(status lineStatus) string {
+	switch status {
+	case coveredLine:
+		return ansiGreen
+	case uncoveredLine:
+		return ansiRed
+	case ignoredLine:
+		return ansiYellow
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,75 @@
+package gocover
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestWriteOverlay(t *testing.T) {
+	changes := []*gittool.Change{
+		{
+			FileName: "pkg/foo/foo.go",
+			Mode:     gittool.ModifyMode,
+			Sections: []*gittool.Section{
+				{StartLine: 10, EndLine: 13, Contents: []string{"\ta := 1", "\tb := 2", "\tc := 3", "}"}},
+			},
+		},
+		{FileName: "pkg/foo/bar.go", Mode: gittool.DeleteMode},
+		{
+			FileName: "README.md",
+			Mode:     gittool.NewMode,
+			Sections: []*gittool.Section{{StartLine: 1, EndLine: 1, Contents: []string{"# gocover"}}},
+		},
+	}
+	statistics := &report.Statistics{
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				CountedLines: []*report.CountedLine{
+					{Line: 10, Covered: true},
+					{Line: 11},
+					{Line: 11, Covered: true},
+					{Line: 12, Ignored: true},
+				},
+			},
+		},
+	}
+
+	t.Run("color", func(t *testing.T) {
+		var b bytes.Buffer
+		writeOverlay(&b, changes, statistics, true)
+		output := b.String()
+		for _, expect := range []string{
+			ansiBold + "diff --git a/pkg/foo/foo.go b/pkg/foo/foo.go" + ansiReset,
+			ansiCyan + "@@ +10,4 @@" + ansiReset,
+			ansiGreen + "+\ta := 1" + ansiReset,
+			ansiRed + "+\tb := 2" + ansiReset,
+			ansiYellow + "+\tc := 3" + ansiReset,
+			"\n+}\n",
+			ansiBold + "--- /dev/null" + ansiReset,
+			"\n+# gocover\n",
+		} {
+			if !strings.Contains(output, expect) {
+				t.Errorf("overlay should contain %q, but get %q", expect, output)
+			}
+		}
+		if strings.Contains(output, "bar.go") {
+			t.Error("deleted file should not be in overlay")
+		}
+	})
+
+	t.Run("no color", func(t *testing.T) {
+		var b bytes.Buffer
+		writeOverlay(&b, changes, statistics, false)
+		if strings.Contains(b.String(), "\x1b[") {
+			t.Errorf("overlay should not contain ANSI escape codes, but get %q", b.String())
+		}
+		if !strings.HasPrefix(b.String(), "diff --git a/pkg/foo/foo.go b/pkg/foo/foo.go\n--- a/pkg/foo/foo.go\n+++ b/pkg/foo/foo.go\n@@ +10,4 @@\n+\ta := 1\n") {
+			t.Errorf("unexpected overlay %q", b.String())
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+var ErrInvalidPackageBaseline = errors.New("invalid package baseline")
+
+// PackageBaseline is the expected coverage of the packages that match the pattern.
+type PackageBaseline struct {
+	// Pattern is the doublestar pattern of the import paths of the packages, such as **/internal/auth or example.com/foo/**.
+	Pattern string
+	// Baseline is the expected coverage of each matched package.
+	Baseline float64
+}
+
+// ParsePackageBaselines parses the package baselines in the format of pattern=percent,
+// a package is gated by the baseline of the first pattern it matches.
+func ParsePackageBaselines(baselines []string) ([]PackageBaseline, error) {
+	var result []PackageBaseline
+	for _, s := range baselines {
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%w %q, the format is pattern=percent", ErrInvalidPackageBaseline, s)
+		}
+		pattern, value := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		if pattern == "" || !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("%w %q, bad pattern %s", ErrInvalidPackageBaseline, s, pattern)
+		}
+		baseline, err := strconv.ParseFloat(value, 64)
+		if err != nil || baseline < 0 || baseline > 100 {
+			return nil, fmt.Errorf("%w %q, the percent should be in [0, 100]", ErrInvalidPackageBaseline, s)
+		}
+		result = append(result, PackageBaseline{Pattern: pattern, Baseline: baseline})
+	}
+	return result, nil
+}
+
+// calculatePackages adds the coverage of each package of the coverage tree to the statistics,
+// with the baseline of the first pattern that the package matches.
+func calculatePackages(statistics *report.Statistics, tree report.CoverageTree, baselines []PackageBaseline) {
+	statistics.Packages = tree.Packages()
+	applyPackageBaselines(statistics.Packages, baselines)
+}
+
+// applyPackageBaselines sets the baseline of each package by the first pattern that it matches.
+func applyPackageBaselines(packages []*report.PackageStatistics, baselines []PackageBaseline) {
+	for _, p := range packages {
+		for _, b := range baselines {
+			if ok, _ := doublestar.Match(b.Pattern, p.Name); ok {
+				p.Baseline = b.Baseline
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gocover
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestParsePackageBaselines(t *testing.T) {
+	baselines, err := ParsePackageBaselines([]string{"**/internal/auth=90", " github.com/Azure/foo/** = 60.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(baselines) != 2 || baselines[0] != (PackageBaseline{Pattern: "**/internal/auth", Baseline: 90}) ||
+		baselines[1] != (PackageBaseline{Pattern: "github.com/Azure/foo/**", Baseline: 60.5}) {
+		t.Errorf("unexpected package baselines %+v", baselines)
+	}
+
+	for _, s := range []string{"**/auth", "=90", "[=90", "**/auth=abc", "**/auth=120"} {
+		if _, err := ParsePackageBaselines([]string{s}); !errors.Is(err, ErrInvalidPackageBaseline) {
+			t.Errorf("expect ErrInvalidPackageBaseline of %s, but get %v", s, err)
+		}
+	}
+}
+
+func TestCalculatePackages(t *testing.T) {
+	tree := report.NewCoverageTree("github.com/Azure/foo")
+	for _, f := range []struct {
+		name                       string
+		effective, covered, ignore int64
+	}{
+		{name: "github.com/Azure/foo/internal/auth/token.go", effective: 10, covered: 9, ignore: 1},
+		{name: "github.com/Azure/foo/internal/auth/jwt/jwt.go", effective: 4, covered: 1},
+		{name: "github.com/Azure/foo/cmd/main.go", effective: 2},
+	} {
+		node := tree.FindOrCreate(f.name)
+		node.TotalEffectiveLines = f.effective
+		node.TotalCoveredLines = f.covered
+		node.TotalCoveredButIgnoreLines = f.ignore
+	}
+	tree.CollectCoverageData()
+
+	statistics := &report.Statistics{TotalCoveragePercent: 100}
+	calculatePackages(statistics, tree, []PackageBaseline{{Pattern: "**/auth", Baseline: 90}, {Pattern: "**/auth/**", Baseline: 20}})
+	if len(statistics.Packages) != 3 {
+		t.Fatalf("expect 3 packages, but get %+v", statistics.Packages)
+	}
+	cmd, auth, jwt := statistics.Packages[0], statistics.Packages[1], statistics.Packages[2]
+	if cmd.Name != "github.com/Azure/foo/cmd" || cmd.CoveragePercent != 0 || cmd.Baseline != 0 {
+		t.Errorf("unexpected package %+v", cmd)
+	}
+	if auth.Name != "github.com/Azure/foo/internal/auth" || auth.Files != 1 || auth.CoveragePercent != 80 || auth.Baseline != 90 {
+		t.Errorf("unexpected package %+v", auth)
+	}
+	if jwt.Name != "github.com/Azure/foo/internal/auth/jwt" || jwt.CoveragePercent != 25 || jwt.Baseline != 20 {
+		t.Errorf("unexpected package %+v", jwt)
+	}
+
+	decision := evaluateGate(statistics, 0)
+	if decision.Passed || len(decision.Violations) != 1 || decision.Violations[0].Package != auth.Name {
+		t.Errorf("expect the auth package fails the gate, but get %+v", decision.Violations)
+	}
+}
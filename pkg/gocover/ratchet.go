@@ -0,0 +1,230 @@
+package gocover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrInvalidRatchet = errors.New("invalid coverage baseline file")
+
+// DefaultRatchetFile is the baseline file written by gocover baseline write, it's committed into the repository.
+const DefaultRatchetFile = ".gocover-baseline.json"
+
+// RatchetBaseline is the committed coverage of each package, the runs fail if a package drops below it,
+// so the coverage only goes up without a fixed global threshold.
+type RatchetBaseline struct {
+	// Packages are the coverage percent of each package by the import path.
+	Packages map[string]float64
+}
+
+// RatchetOption gates the coverage of each package by the baseline file.
+type RatchetOption struct {
+	// File is the baseline file, the packages are not gated by a baseline file if it's empty.
+	File string
+	// Slack is the coverage percent that a package may drop below its baseline, such as the noise of a refactoring.
+	Slack float64
+}
+
+// ratchet is the loaded baseline file with the slack.
+type ratchet struct {
+	baseline *RatchetBaseline
+	slack    float64
+}
+
+// load reads the baseline file, it's nil if the file is not set.
+func (o RatchetOption) load() (*ratchet, error) {
+	if o.File == "" {
+		return nil, nil
+	}
+	if o.Slack < 0 || o.Slack > 100 {
+		return nil, fmt.Errorf("%w: the slack %.2f should be in [0, 100]", ErrInvalidRatchet, o.Slack)
+	}
+	baseline, err := LoadRatchetBaseline(o.File)
+	if err != nil {
+		return nil, err
+	}
+	return &ratchet{baseline: baseline, slack: o.Slack}, nil
+}
+
+// apply raises the baseline of each package in the baseline file to its recorded coverage minus the slack,
+// the baselines of --package-baseline that are higher are kept. The packages not in the file, such as the new packages, are not changed.
+func (r *ratchet) apply(packages []*report.PackageStatistics) {
+	if r == nil {
+		return
+	}
+	for _, p := range packages {
+		recorded, ok := r.baseline.Packages[p.Name]
+		if !ok {
+			continue
+		}
+		if baseline := recorded - r.slack; baseline > p.Baseline {
+			p.Baseline = baseline
+		}
+	}
+}
+
+// LoadRatchetBaseline reads the baseline file.
+func LoadRatchetBaseline(file string) (*RatchetBaseline, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read coverage baseline file: %w", err)
+	}
+	baseline := &RatchetBaseline{}
+	if err := json.Unmarshal(data, baseline); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrInvalidRatchet, file, err)
+	}
+	for name, percent := range baseline.Packages {
+		if percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("%w %s: the coverage %.2f of package %s should be in [0, 100]", ErrInvalidRatchet, file, percent, name)
+		}
+	}
+	return baseline, nil
+}
+
+// WriteRatchetBaseline writes the baseline file, the packages are sorted by the import path so the changes are easy to review.
+func WriteRatchetBaseline(file string, baseline *RatchetBaseline) error {
+	return atomicfile.WriteFile(file, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(baseline)
+	})
+}
+
+// RatchetWriteOption contains the input to the gocover baseline write command.
+type RatchetWriteOption struct {
+	CoverProfiles  []string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath string
+	Excludes   []string
+	// File is the baseline file that is written.
+	File string
+	// Reset replaces the baseline of each package by its current coverage, otherwise a baseline never goes down.
+	Reset bool
+
+	StdOut io.Writer
+	Logger logrus.FieldLogger
+}
+
+// RunRatchetWrite calculates the full coverage of each package and writes it into the baseline file.
+// The baselines in the existing file are only raised unless reset, so a flaky drop doesn't lower the baseline,
+// and the packages that are removed from the module are removed from the file.
+func RunRatchetWrite(ctx context.Context, o *RatchetWriteOption) error {
+	logger := o.Logger.WithField("source", "baseline")
+
+	outputDir, err := createGoCoverTempDirectory()
+	if err != nil {
+		return fmt.Errorf("create gocover temp directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	recorder := &statisticsRecorder{}
+	option := NewFullOption()
+	option.CoverProfiles = o.CoverProfiles
+	option.RepositoryPath = o.RepositoryPath
+	option.ModuleDir = o.ModuleDir
+	option.ModulePath = o.ModulePath
+	option.Excludes = o.Excludes
+	option.CoverageBaseline = 0
+	option.ReportFormat = JSONReportFormat
+	option.ReportName = "baseline"
+	option.OutputDir = outputDir
+	option.Style = "colorful"
+	option.DbOption = &dbclient.DBOption{}
+	option.ReportGenerators = []report.ReportGenerator{recorder}
+	option.Logger = logger
+
+	full, err := NewFullCover(option)
+	if err != nil {
+		return err
+	}
+	if err := full.Run(ctx); err != nil {
+		return err
+	}
+	if recorder.statistics == nil {
+		return errors.New("no full coverage of the module")
+	}
+
+	var previous *RatchetBaseline
+	if !o.Reset {
+		if previous, err = LoadRatchetBaseline(o.File); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	baseline := ratchetBaseline(recorder.statistics.Packages, previous)
+	if err := WriteRatchetBaseline(o.File, baseline); err != nil {
+		return fmt.Errorf("write coverage baseline file: %w", err)
+	}
+	return writeRatchetChanges(o.StdOut, o.File, previous, baseline)
+}
+
+// ratchetBaseline returns the coverage of each package with the effective lines, the percents are rounded down to 2 decimals
+// so the rounding doesn't raise a baseline above the coverage. A baseline of the previous file that is higher is kept.
+func ratchetBaseline(packages []*report.PackageStatistics, previous *RatchetBaseline) *RatchetBaseline {
+	baseline := &RatchetBaseline{Packages: make(map[string]float64)}
+	for _, p := range packages {
+		if p.TotalEffectiveLines == 0 {
+			continue
+		}
+		percent := math.Floor(p.CoveragePercent*100) / 100
+		if previous != nil && previous.Packages[p.Name] > percent {
+			percent = previous.Packages[p.Name]
+		}
+		baseline.Packages[p.Name] = percent
+	}
+	return baseline
+}
+
+// writeRatchetChanges writes the packages whose baselines are added, changed or removed.
+func writeRatchetChanges(w io.Writer, file string, previous, current *RatchetBaseline) error {
+	if previous == nil {
+		previous = &RatchetBaseline{}
+	}
+	names := make(map[string]bool)
+	for name := range previous.Packages {
+		names[name] = true
+	}
+	for name := range current.Packages {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	changed := 0
+	for _, name := range sorted {
+		before, hadBefore := previous.Packages[name]
+		after, hasAfter := current.Packages[name]
+		var err error
+		switch {
+		case !hadBefore:
+			_, err = fmt.Fprintf(w, "+ %s %.2f\n", name, after)
+		case !hasAfter:
+			_, err = fmt.Fprintf(w, "- %s %.2f\n", name, before)
+		case before != after:
+			_, err = fmt.Fprintf(w, "~ %s %.2f -> %.2f\n", name, before, after)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		changed++
+	}
+	_, err := fmt.Fprintf(w, "%s: %d packages, %d changed\n", file, len(current.Packages), changed)
+	return err
+}
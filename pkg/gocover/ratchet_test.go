@@ -0,0 +1,116 @@
+package gocover
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestRatchetApply(t *testing.T) {
+	packages := []*report.PackageStatistics{
+		{Name: "github.com/Azure/foo/auth", CoveragePercent: 79.6, Baseline: 90},
+		{Name: "github.com/Azure/foo/cmd", CoveragePercent: 50},
+		{Name: "github.com/Azure/foo/new", CoveragePercent: 10},
+	}
+	r := &ratchet{
+		baseline: &RatchetBaseline{Packages: map[string]float64{
+			"github.com/Azure/foo/auth": 80,
+			"github.com/Azure/foo/cmd":  60,
+			"github.com/Azure/foo/gone": 70,
+		}},
+		slack: 0.5,
+	}
+	r.apply(packages)
+	if packages[0].Baseline != 90 {
+		t.Errorf("expect the higher package baseline 90 kept, but get %.2f", packages[0].Baseline)
+	}
+	if packages[1].Baseline != 59.5 || packages[1].Passed() {
+		t.Errorf("expect the baseline 59.5 of cmd, which fails, but get %+v", packages[1])
+	}
+	if packages[2].Baseline != 0 {
+		t.Errorf("expect the new package not gated, but get %+v", packages[2])
+	}
+
+	var nilRatchet *ratchet
+	nilRatchet.apply(packages)
+}
+
+func TestRatchetOptionLoad(t *testing.T) {
+	if r, err := (RatchetOption{}).load(); r != nil || err != nil {
+		t.Errorf("expect no ratchet without file, but get %v, %v", r, err)
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, DefaultRatchetFile)
+	if _, err := (RatchetOption{File: file}).load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expect a missing file error, but get %v", err)
+	}
+
+	if err := WriteRatchetBaseline(file, &RatchetBaseline{Packages: map[string]float64{"b": 20, "a": 10}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\"a\": 10,\n    \"b\": 20") {
+		t.Errorf("expect the sorted packages, but get %s", data)
+	}
+	r, err := (RatchetOption{File: file, Slack: 1}).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.baseline.Packages) != 2 || r.baseline.Packages["a"] != 10 || r.slack != 1 {
+		t.Errorf("unexpected ratchet %+v", r)
+	}
+
+	if _, err := (RatchetOption{File: file, Slack: -1}).load(); !errors.Is(err, ErrInvalidRatchet) {
+		t.Errorf("expect ErrInvalidRatchet of the negative slack, but get %v", err)
+	}
+	for _, content := range []string{"[]", `{"Packages": {"a": 120}}`} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadRatchetBaseline(file); !errors.Is(err, ErrInvalidRatchet) {
+			t.Errorf("expect ErrInvalidRatchet of %s, but get %v", content, err)
+		}
+	}
+}
+
+func TestRatchetBaseline(t *testing.T) {
+	packages := []*report.PackageStatistics{
+		{Name: "a", TotalEffectiveLines: 3, CoveragePercent: 66.666},
+		{Name: "b", TotalEffectiveLines: 10, CoveragePercent: 40},
+		{Name: "c", TotalEffectiveLines: 10, CoveragePercent: 90},
+		{Name: "empty"},
+	}
+	previous := &RatchetBaseline{Packages: map[string]float64{"b": 50, "c": 80, "gone": 70}}
+	baseline := ratchetBaseline(packages, previous)
+	expected := map[string]float64{"a": 66.66, "b": 50, "c": 90}
+	if len(baseline.Packages) != len(expected) {
+		t.Fatalf("expect %v, but get %v", expected, baseline.Packages)
+	}
+	for name, percent := range expected {
+		if baseline.Packages[name] != percent {
+			t.Errorf("expect %s %.2f, but get %.2f", name, percent, baseline.Packages[name])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeRatchetChanges(&buf, DefaultRatchetFile, previous, baseline); err != nil {
+		t.Fatal(err)
+	}
+	expectedOutput := "+ a 66.66\n~ c 80.00 -> 90.00\n- gone 70.00\n" + DefaultRatchetFile + ": 3 packages, 3 changed\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("expect %q, but get %q", expectedOutput, buf.String())
+	}
+
+	if baseline := ratchetBaseline(packages, nil); baseline.Packages["b"] != 40 {
+		t.Errorf("expect the current coverage of b without previous baseline, but get %v", baseline.Packages)
+	}
+}
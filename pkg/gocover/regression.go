@@ -0,0 +1,227 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/phase"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var ErrInvalidRegression = errors.New("invalid coverage regression option")
+
+// RegressionOption decides whether the full coverage of the compared branch is calculated, so the diff fails
+// when it lowers the coverage of a file it touches, even if the coverage of the changed lines passes.
+type RegressionOption struct {
+	Enabled bool
+	// BaseProfiles are the stored cover profiles of the compared branch, such as the profiles of the last build of main,
+	// the tests of the compared branch run in a linked worktree if it's empty.
+	BaseProfiles []string
+	// Tolerance is the coverage percent that a touched file may drop.
+	Tolerance float64
+	// GoFlags are the flags of go test that runs the tests of the compared branch.
+	GoFlags []string
+}
+
+// Validate validates the tolerance.
+func (o RegressionOption) Validate() error {
+	if o.Tolerance < 0 || o.Tolerance > 100 {
+		return fmt.Errorf("%w: the tolerance %.2f should be in [0, 100]", ErrInvalidRegression, o.Tolerance)
+	}
+	return nil
+}
+
+// calculateRegression calculates the full coverage of the compared branch and of HEAD, and compares the coverage
+// of the files in the diff coverage. The full coverage of HEAD is calculated from the cover profiles of the diff,
+// so they should cover the whole module, such as the profile of `go test ./...`.
+func (diff *diffCover) calculateRegression(ctx context.Context, statistics *report.Statistics) error {
+	if !diff.regression.Enabled {
+		return nil
+	}
+	stop := diff.recorder.Start(phase.RegressionPhase)
+	defer stop()
+
+	workDir, err := createGoCoverTempDirectory()
+	if err != nil {
+		return fmt.Errorf("create gocover temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	head, err := diff.fullCoverage(ctx, diff.coverFilenames, diff.repositoryPath, filepath.Join(workDir, "head"))
+	if err != nil {
+		return fmt.Errorf("full coverage of HEAD: %w", err)
+	}
+	base, err := diff.baseCoverage(ctx, workDir)
+	if err != nil {
+		return fmt.Errorf("full coverage of %s: %w", diff.comparedBranch, err)
+	}
+
+	statistics.Regression = compareCoverage(statistics.CoverageProfile, base, head, diff.regression.Tolerance)
+	for _, f := range statistics.Regression.Files {
+		if !f.Passed(statistics.Regression.Tolerance) {
+			diff.logger.Warnf("coverage of %s drops from %.2f to %.2f", f.FileName, f.BaseCoveragePercent, f.CoveragePercent)
+		}
+	}
+	return nil
+}
+
+// baseCoverage returns the full coverage of the compared branch, which is checked out into a linked worktree,
+// since the stored profiles are parsed by the source code of the compared branch too.
+func (diff *diffCover) baseCoverage(ctx context.Context, workDir string) (*report.Statistics, error) {
+	commit, err := gittool.ResolveCommit(ctx, diff.repositoryPath, diff.comparedBranch)
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := gittool.AddWorktree(ctx, diff.repositoryPath, filepath.Join(workDir, "base"), commit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := worktree.Remove(context.Background()); err != nil {
+			diff.logger.WithError(err).Warn("remove worktree")
+		}
+	}()
+
+	// the profile paths are relative to the working directory, which is changed to the module of the worktree.
+	var profiles []string
+	for _, p := range diff.regression.BaseProfiles {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("get absolute path of %s: %w", p, err)
+		}
+		profiles = append(profiles, abs)
+	}
+	outputDir := filepath.Join(workDir, "base-report")
+
+	var statistics *report.Statistics
+	err = inDirectory(filepath.Join(worktree.Path, diff.moduleDir), diff.logger, func() error {
+		if len(profiles) != 0 {
+			statistics, err = diff.fullCoverage(ctx, profiles, worktree.Path, outputDir)
+			return err
+		}
+		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+		statistics, err = runDiffCoverage(ctx, &GoCoverTestOption{
+			RepositoryPath:   worktree.Path,
+			ModuleDir:        diff.moduleDir,
+			ModulePath:       diff.modulePath,
+			CoverageMode:     FullCoverage,
+			ExecutorMode:     GoExecutor,
+			GoFlags:          diff.regression.GoFlags,
+			CoverageBaseline: 0,
+			ReportFormat:     JSONReportFormat,
+			ReportName:       "base",
+			OutputDir:        outputDir,
+			Excludes:         diff.excludePatterns,
+			Style:            "colorful",
+			FailedTestPolicy: WarnOnFailedTests,
+			Limits:           diff.limits,
+			GitAttributes:    diff.gitAttributes != nil,
+			IgnoreProviders:  diff.ignoreProviders,
+			DbOption:         &dbclient.DBOption{},
+			StdOut:           io.Discard,
+			StdErr:           os.Stderr,
+			Logger:           diff.logger,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if statistics == nil {
+		return nil, errors.New("no full coverage of the module")
+	}
+	return statistics, nil
+}
+
+// fullCoverage returns the full coverage of the repository by the cover profiles, with the excludes of the diff coverage.
+func (diff *diffCover) fullCoverage(ctx context.Context, profiles []string, repositoryPath, outputDir string) (*report.Statistics, error) {
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+	recorder := &statisticsRecorder{}
+	option := NewFullOption()
+	option.CoverProfiles = profiles
+	option.RepositoryPath = repositoryPath
+	option.ModuleDir = diff.moduleDir
+	option.ModulePath = diff.modulePath
+	option.Excludes = diff.excludePatterns
+	option.Limits = diff.limits
+	option.GitAttributes = diff.gitAttributes != nil
+	option.IgnoreProviders = diff.ignoreProviders
+	option.CoverageBaseline = 0
+	option.ReportFormat = JSONReportFormat
+	option.ReportName = "regression"
+	option.OutputDir = outputDir
+	option.Style = "colorful"
+	option.DbOption = &dbclient.DBOption{}
+	option.ReportGenerators = []report.ReportGenerator{recorder}
+	option.Logger = diff.logger
+
+	full, err := NewFullCover(option)
+	if err != nil {
+		return nil, err
+	}
+	if err := full.Run(ctx); err != nil {
+		return nil, err
+	}
+	if recorder.statistics == nil {
+		return nil, errors.New("no full coverage of the module")
+	}
+	return recorder.statistics, nil
+}
+
+// compareCoverage compares the full coverage of the touched files on the compared branch and on HEAD,
+// the new files and the files without effective lines on either side are skipped, since they have nothing to regress from,
+// and so are the files in a grace period.
+func compareCoverage(touched []*report.CoverageProfile, base, head *report.Statistics, tolerance float64) *report.RegressionStatistics {
+	regression := &report.RegressionStatistics{
+		BaseCoveragePercent: base.TotalCoveragePercent,
+		CoveragePercent:     head.TotalCoveragePercent,
+		Tolerance:           tolerance,
+	}
+	baseFiles := fileCoverage(base)
+	headFiles := fileCoverage(head)
+	for _, profile := range touched {
+		if !enforced(profile) {
+			continue
+		}
+		basePercent, ok := baseFiles[profile.FileName]
+		if !ok {
+			continue
+		}
+		headPercent, ok := headFiles[profile.FileName]
+		if !ok {
+			continue
+		}
+		regression.Files = append(regression.Files, &report.FileRegression{
+			FileName:            profile.FileName,
+			BaseCoveragePercent: basePercent,
+			CoveragePercent:     headPercent,
+		})
+	}
+	sort.Slice(regression.Files, func(i, j int) bool {
+		return regression.Files[i].FileName < regression.Files[j].FileName
+	})
+	return regression
+}
+
+// fileCoverage returns the coverage percent of each file with the effective lines, the covered but ignored lines are not covered.
+func fileCoverage(statistics *report.Statistics) map[string]float64 {
+	files := make(map[string]float64, len(statistics.CoverageProfile))
+	for _, profile := range statistics.CoverageProfile {
+		if profile.TotalEffectiveLines == 0 {
+			continue
+		}
+		files[profile.FileName] = calculateCoverage(int64(profile.CoveredLines-profile.CoveredButIgnoredLines), int64(profile.TotalEffectiveLines))
+	}
+	return files
+}
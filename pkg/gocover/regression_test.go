@@ -0,0 +1,139 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRegressionOptionValidate(t *testing.T) {
+	if err := (RegressionOption{Tolerance: 0.5}).Validate(); err != nil {
+		t.Errorf("expect valid tolerance, but get %v", err)
+	}
+	for _, tolerance := range []float64{-1, 101} {
+		if err := (RegressionOption{Tolerance: tolerance}).Validate(); !errors.Is(err, ErrInvalidRegression) {
+			t.Errorf("expect ErrInvalidRegression of %.2f, but get %v", tolerance, err)
+		}
+	}
+}
+
+func TestCompareCoverage(t *testing.T) {
+	touched := []*report.CoverageProfile{
+		{FileName: "example.com/foo/b.go"},
+		{FileName: "example.com/foo/a.go"},
+		{FileName: "example.com/foo/new.go"},
+		{FileName: "example.com/foo/empty.go"},
+	}
+	base := &report.Statistics{
+		TotalCoveragePercent: 80,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "example.com/foo/a.go", TotalEffectiveLines: 4, CoveredLines: 4},
+			{FileName: "example.com/foo/b.go", TotalEffectiveLines: 4, CoveredLines: 2},
+			{FileName: "example.com/foo/empty.go"},
+		},
+	}
+	head := &report.Statistics{
+		TotalCoveragePercent: 75,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "example.com/foo/a.go", TotalEffectiveLines: 4, CoveredLines: 3, CoveredButIgnoredLines: 1},
+			{FileName: "example.com/foo/b.go", TotalEffectiveLines: 4, CoveredLines: 4},
+			{FileName: "example.com/foo/new.go", TotalEffectiveLines: 1},
+			{FileName: "example.com/foo/empty.go"},
+		},
+	}
+	regression := compareCoverage(touched, base, head, 1)
+	if regression.BaseCoveragePercent != 80 || regression.CoveragePercent != 75 || regression.Tolerance != 1 {
+		t.Errorf("unexpected regression %+v", regression)
+	}
+	if len(regression.Files) != 2 {
+		t.Fatalf("expect a.go and b.go compared, but get %+v", regression.Files)
+	}
+	if f := regression.Files[0]; f.FileName != "example.com/foo/a.go" || f.Delta() != -50 || f.Passed(1) {
+		t.Errorf("expect a.go drops from 100 to 50, but get %+v", f)
+	}
+	if f := regression.Files[1]; f.FileName != "example.com/foo/b.go" || f.Delta() != 50 || !f.Passed(1) {
+		t.Errorf("expect b.go rises from 50 to 100, but get %+v", f)
+	}
+}
+
+func TestDiffCoverRegression(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	commitFiles(t, dir, "init", map[string]string{
+		"go.mod":      "module example.com/foo\n\ngo 1.20\n",
+		"foo.go":      "package foo\n\nfunc Foo(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn -a\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo(1)\n\tFoo(-1)\n}\n",
+	})
+	baseProfile := goTestProfile(t, dir)
+
+	// the added line is covered, but the test of the negative branch is removed.
+	git(t, dir, "checkout", "-q", "-b", "feature")
+	commitFiles(t, dir, "add Bar", map[string]string{
+		"foo.go":      "package foo\n\nfunc Foo(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn -a\n}\n\nfunc Bar() int {\n\treturn 1\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo(1)\n\tBar()\n}\n",
+	})
+	headProfile := goTestProfile(t, dir)
+
+	for name, baseProfiles := range map[string][]string{"stored profile": {baseProfile}, "tests of the base": nil} {
+		t.Run(name, func(t *testing.T) {
+			recorder := &statisticsRecorder{}
+			option := NewDiffOption()
+			option.CoverProfiles = []string{headProfile}
+			option.CompareBranch = "main"
+			option.RepositoryPath = dir
+			option.ModuleDir = "./"
+			option.CoverageBaseline = 100
+			option.ReportFormat = JSONReportFormat
+			option.OutputDir = t.TempDir()
+			option.Style = "colorful"
+			option.DbOption = &dbclient.DBOption{}
+			option.ReportGenerators = []report.ReportGenerator{recorder}
+			option.Regression = RegressionOption{Enabled: true, BaseProfiles: baseProfiles, Tolerance: 1}
+			option.Logger = logrus.New()
+
+			err := inDirectory(dir, option.Logger, func() error {
+				diff, err := NewDiffCover(option)
+				if err != nil {
+					return err
+				}
+				return diff.Run(context.Background())
+			})
+			var gocoverErr *GoCoverError
+			if !errors.As(err, &gocoverErr) || gocoverErr.ExitCode != LowCoverageErrorExitCode {
+				t.Fatalf("expect the low coverage error of the regression, but get %v", err)
+			}
+			statistics := recorder.statistics
+			if statistics.TotalCoveragePercent != 100 {
+				t.Errorf("expect the diff coverage passes, but get %.2f", statistics.TotalCoveragePercent)
+			}
+			r := statistics.Regression
+			if r == nil || r.BaseCoveragePercent != 100 || r.CoveragePercent != 75 || len(r.Files) != 1 {
+				t.Fatalf("unexpected regression %+v", r)
+			}
+			if f := r.Files[0]; f.FileName != "example.com/foo/foo.go" || f.BaseCoveragePercent != 100 || f.CoveragePercent != 75 {
+				t.Errorf("expect foo.go drops from 100 to 75, but get %+v", f)
+			}
+		})
+	}
+}
+
+// goTestProfile runs the tests of the module in the directory and returns the cover profile.
+func goTestProfile(t *testing.T, dir string) string {
+	t.Helper()
+	profile := filepath.Join(t.TempDir(), "coverage.out")
+	cmd := exec.Command("go", "test", "./...", "-coverprofile", profile)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test: %v: %s", err, out)
+	}
+	return profile
+}
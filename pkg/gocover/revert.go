@@ -0,0 +1,225 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// RevertOption contains the input to the gocover revert command.
+type RevertOption struct {
+	// Commit is the commit to revert.
+	Commit string
+	// CoverProfiles are the cover profiles of HEAD, they decide the coverage of the lines that the revert removes.
+	CoverProfiles  []string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath string
+	GoFlags    []string
+	Excludes   []string
+	Limits     Limits
+
+	StdOut io.Writer
+	StdErr io.Writer
+	Logger logrus.FieldLogger
+}
+
+// NewRevertOption returns a Revert Option with default values.
+func NewRevertOption() *RevertOption {
+	return &RevertOption{
+		Limits: DefaultLimits(),
+	}
+}
+
+// RevertPreview is the predicted diff coverage of reverting a commit onto HEAD.
+type RevertPreview struct {
+	// Commit is the reverted commit.
+	Commit string
+	// Base is HEAD of the repository that the revert applies to.
+	Base string
+	// Removed is the coverage of the lines that the revert removes by the cover profiles of HEAD,
+	// the covered lines are the coverage that disappears with the revert.
+	Removed *report.Statistics
+	// Restored is the diff coverage of the lines that the revert restores by the tests of the reverted code,
+	// the uncovered lines are the uncovered code that returns with the revert.
+	Restored *report.Statistics
+}
+
+// RunRevertPreview predicts the diff coverage of reverting the commit, for the rollback decisions of an incident.
+// The commit is reverted onto HEAD in a linked worktree, the tests run there for the coverage of the restored lines,
+// and the lines that the revert removes are looked up in the cover profiles of HEAD. HEAD of the repository isn't changed.
+func RunRevertPreview(ctx context.Context, o *RevertOption) (*RevertPreview, error) {
+	logger := o.Logger.WithField("source", "revert")
+	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of repo: %w", err)
+	}
+	preview := &RevertPreview{}
+	if preview.Commit, err = gittool.ResolveCommit(ctx, repositoryAbsPath, o.Commit); err != nil {
+		return nil, err
+	}
+	if preview.Base, err = gittool.ResolveCommit(ctx, repositoryAbsPath, "HEAD"); err != nil {
+		return nil, err
+	}
+
+	workDir, err := createGoCoverTempDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("create gocover temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	worktree, err := gittool.AddWorktree(ctx, repositoryAbsPath, filepath.Join(workDir, "revert"), preview.Base)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := worktree.Remove(context.Background()); err != nil {
+			logger.WithError(err).Warn("remove worktree")
+		}
+	}()
+	if err := worktree.Revert(ctx, preview.Commit); err != nil {
+		return nil, err
+	}
+	reverted, err := worktree.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lines that the revert removes are the lines that HEAD adds to the reverted code.
+	if preview.Removed, err = removedCoverage(ctx, o, repositoryAbsPath, reverted, filepath.Join(workDir, "removed"), logger); err != nil {
+		return nil, fmt.Errorf("coverage of the removed lines: %w", err)
+	}
+
+	outputDir := filepath.Join(workDir, "restored")
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+	option := &GoCoverTestOption{
+		CompareBranch:    preview.Base,
+		RepositoryPath:   worktree.Path,
+		ModuleDir:        o.ModuleDir,
+		ModulePath:       o.ModulePath,
+		CoverageMode:     DiffCoverage,
+		ExecutorMode:     GoExecutor,
+		GoFlags:          o.GoFlags,
+		CoverageBaseline: 0,
+		ReportFormat:     JSONReportFormat,
+		ReportName:       "restored",
+		OutputDir:        outputDir,
+		Excludes:         o.Excludes,
+		Style:            "colorful",
+		FailedTestPolicy: WarnOnFailedTests,
+		Limits:           o.Limits,
+		GitAttributes:    true,
+		DbOption:         &dbclient.DBOption{},
+		StdOut:           o.StdOut,
+		StdErr:           o.StdErr,
+		Logger:           logger,
+	}
+	// the packages of the cover profile are resolved from the working directory, so it's changed to the module of the worktree.
+	err = inDirectory(filepath.Join(worktree.Path, o.ModuleDir), logger, func() error {
+		preview.Restored, err = runDiffCoverage(ctx, option)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("coverage of the restored lines: %w", err)
+	}
+	return preview, nil
+}
+
+// removedCoverage returns the diff coverage of HEAD of the repository against the reverted commit by the cover profiles of HEAD.
+func removedCoverage(ctx context.Context, o *RevertOption, repositoryPath, reverted, outputDir string, logger logrus.FieldLogger) (*report.Statistics, error) {
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+	recorder := &statisticsRecorder{}
+	option := NewDiffOption()
+	option.CoverProfiles = o.CoverProfiles
+	option.CompareBranch = reverted
+	option.RepositoryPath = repositoryPath
+	option.ModuleDir = o.ModuleDir
+	option.ModulePath = o.ModulePath
+	option.Excludes = o.Excludes
+	option.Limits = o.Limits
+	option.CoverageBaseline = 0
+	option.ReportFormat = JSONReportFormat
+	option.ReportName = "removed"
+	option.OutputDir = outputDir
+	option.Style = "colorful"
+	option.DbOption = &dbclient.DBOption{}
+	option.ReportGenerators = []report.ReportGenerator{recorder}
+	option.Logger = logger
+
+	diff, err := NewDiffCover(option)
+	if err != nil {
+		return nil, err
+	}
+	err = diff.Run(ctx)
+	var gocoverErr *GoCoverError
+	if err != nil && !(recorder.statistics != nil && errors.As(err, &gocoverErr) && gocoverErr.ExitCode == LowCoverageErrorExitCode) {
+		return nil, err
+	}
+	return recorder.statistics, nil
+}
+
+// WriteRevertPreview writes the coverage of the removed and the restored lines as markdown tables,
+// with the covered lines that disappear and the uncovered lines that return of each file.
+func WriteRevertPreview(w io.Writer, p *RevertPreview) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Revert of %s onto %s\n\n", shortCommit(p.Commit), shortCommit(p.Base))
+	writeRevertSection(&b, "Removed", "Covered lines that disappear", p.Removed, coveredLines)
+	fmt.Fprintln(&b)
+	writeRevertSection(&b, "Restored", "Uncovered lines that return", p.Restored, func(profile *report.CoverageProfile) []int {
+		return profile.TotalViolationLines
+	})
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRevertSection writes the coverage of the lines and the table of the lines of each file.
+func writeRevertSection(b *strings.Builder, name, column string, statistics *report.Statistics, lines func(*report.CoverageProfile) []int) {
+	if statistics == nil || statistics.TotalEffectiveLines == 0 {
+		fmt.Fprintf(b, "%s lines: none\n", name)
+		return
+	}
+	fmt.Fprintf(b, "%s lines: %d/%d covered (%.1f%%)\n\n", name, statistics.TotalCoveredLines-statistics.TotalCoveredButIgnoredLines,
+		statistics.TotalEffectiveLines, statistics.TotalCoveragePercent)
+	fmt.Fprintf(b, "| File | Coverage | %s |\n", column)
+	fmt.Fprintf(b, "| --- | --- | --- |\n")
+	for _, profile := range statistics.CoverageProfile {
+		if profile.TotalEffectiveLines == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "| %s | %d/%d | %s |\n", profile.FileName, profile.CoveredLines-profile.CoveredButIgnoredLines,
+			profile.TotalEffectiveLines, report.LineRanges(lines(profile)))
+	}
+}
+
+// coveredLines returns the covered lines of the profile that are not ignored.
+func coveredLines(profile *report.CoverageProfile) []int {
+	var lines []int
+	for _, l := range profile.CountedLines {
+		if l.Covered && !l.Ignored {
+			lines = append(lines, l.Line)
+		}
+	}
+	return lines
+}
+
+// shortCommit returns the first 7 characters of the commit sha as git log --oneline.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,93 @@
+package gocover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRunRevertPreview(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	commitFiles(t, dir, "init", map[string]string{
+		"go.mod":      "module example.com/foo\n\ngo 1.20\n",
+		"foo.go":      "package foo\n\nfunc Foo(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn -a\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo(1)\n}\n",
+	})
+	commitFiles(t, dir, "simplify Foo", map[string]string{
+		"foo.go": "package foo\n\nfunc Foo(a int) int {\n\treturn a * 2\n}\n",
+	})
+	simplify := git(t, dir, "rev-parse", "HEAD")
+	commitFiles(t, dir, "add bar.go", map[string]string{"bar.go": "package foo\n\nfunc Bar() {}\n"})
+	head := git(t, dir, "rev-parse", "HEAD")
+
+	profile := filepath.Join(t.TempDir(), "coverage.out")
+	cmd := exec.Command("go", "test", "./...", "-coverprofile", profile)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test: %v: %s", err, out)
+	}
+
+	o := NewRevertOption()
+	o.Commit = simplify
+	o.CoverProfiles = []string{profile}
+	o.RepositoryPath = dir
+	o.ModuleDir = "./"
+	o.StdOut, o.StdErr = &bytes.Buffer{}, &bytes.Buffer{}
+	o.Logger = logrus.New()
+
+	var preview *RevertPreview
+	err := inDirectory(dir, o.Logger, func() (err error) {
+		preview, err = RunRevertPreview(context.Background(), o)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Commit != simplify || preview.Base != head {
+		t.Errorf("unexpected commits %s onto %s", preview.Commit, preview.Base)
+	}
+	if s := preview.Removed; s == nil || s.TotalEffectiveLines != 1 || s.TotalCoveredLines != 1 {
+		t.Errorf("expect the covered line removed, but get %+v", s)
+	}
+	if s := preview.Restored; s == nil || s.TotalEffectiveLines != 3 || s.TotalCoveredLines != 2 {
+		t.Errorf("expect 2 of 3 restored lines covered, but get %+v", s)
+	}
+	if head := git(t, dir, "rev-parse", "HEAD"); head != preview.Base {
+		t.Errorf("expect HEAD of the repository unchanged, but get %s", head)
+	}
+
+	var b bytes.Buffer
+	if err := WriteRevertPreview(&b, preview); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Revert of " + simplify[:7] + " onto " + head[:7],
+		"Removed lines: 1/1 covered (100.0%)",
+		"| example.com/foo/foo.go | 1/1 | 4 |",
+		"Restored lines: 2/3 covered (66.7%)",
+		"| example.com/foo/foo.go | 2/3 | 7 |",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("preview should contain %q:\n%s", want, b.String())
+		}
+	}
+
+	commitFiles(t, dir, "rewrite Foo", map[string]string{
+		"foo.go": "package foo\n\nfunc Foo(a int) int {\n\treturn a * 3\n}\n",
+	})
+	o.Commit = simplify
+	if _, err := RunRevertPreview(context.Background(), o); !errors.Is(err, gittool.ErrRevertConflict) {
+		t.Errorf("expect ErrRevertConflict, but get %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var ErrInvalidRollout = errors.New("invalid rollout")
+
+// RolloutOption enforces the coverage gate on a percent of the pull requests, the failing gate of the other pull requests
+// is bypassed, so it only warns. The pull requests are chosen by the hash of the key, so the same pull request is always
+// enforced or not, and raising the percent only adds the pull requests.
+type RolloutOption struct {
+	Enabled bool
+	// Percent is the percent of the pull requests that the gate is enforced on, in [0, 100].
+	Percent float64
+	// Key is the pull request number or the branch that the pull request is bucketed by, the current branch is used if it's empty.
+	// It doesn't change with the pushes to the pull request, so the pull request stays in its bucket.
+	Key string
+}
+
+// Validate validates the percent.
+func (o RolloutOption) Validate() error {
+	if o.Percent < 0 || o.Percent > 100 {
+		return fmt.Errorf("%w: the percent %.2f should be in [0, 100]", ErrInvalidRollout, o.Percent)
+	}
+	return nil
+}
+
+// decide returns whether the gate is enforced on the pull request, it's nil if the rollout is not enabled.
+func (o RolloutOption) decide(ctx context.Context, repositoryPath string) (*report.Rollout, error) {
+	if !o.Enabled {
+		return nil, nil
+	}
+	key := o.Key
+	if key == "" {
+		branch, err := gittool.CurrentBranch(ctx, repositoryPath)
+		if err != nil {
+			return nil, fmt.Errorf("resolve the key of the rollout: %w", err)
+		}
+		// the commit of a detached HEAD changes with each push, which moves the pull request between the buckets.
+		if branch == "" {
+			return nil, fmt.Errorf("%w: HEAD is detached and no pull request is detected, set --rollout-key to the pull request number or the branch", ErrInvalidRollout)
+		}
+		key = branch
+	}
+	bucket := rolloutBucket(key)
+	return &report.Rollout{Percent: o.Percent, Key: key, Bucket: bucket, Enforced: float64(bucket) < o.Percent}, nil
+}
+
+// rolloutBucket returns the bucket of the key in [0, 100) by the fnv-1a hash, which is stable across the versions of gocover.
+func rolloutBucket(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// rolloutBypass returns the bypass of the failing gate of a pull request that the rollout doesn't enforce the gate on.
+func rolloutBypass(rollout *report.Rollout) *report.Bypass {
+	if rollout == nil || rollout.Enforced {
+		return nil
+	}
+	return &report.Bypass{
+		Source: fmt.Sprintf("rollout %g%%", rollout.Percent),
+		Reason: fmt.Sprintf("the gate is enforced on %g%% of the pull requests, and %s in bucket %d is not one of them", rollout.Percent, rollout.Key, rollout.Bucket),
+	}
+}
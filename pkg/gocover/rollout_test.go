@@ -0,0 +1,104 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRolloutOptionValidate(t *testing.T) {
+	if err := (RolloutOption{Enabled: true, Percent: 25}).Validate(); err != nil {
+		t.Errorf("expect valid percent, but get %v", err)
+	}
+	for _, percent := range []float64{-1, 100.5} {
+		if err := (RolloutOption{Enabled: true, Percent: percent}).Validate(); !errors.Is(err, ErrInvalidRollout) {
+			t.Errorf("expect ErrInvalidRollout of %.2f, but get %v", percent, err)
+		}
+	}
+}
+
+func TestRolloutDecide(t *testing.T) {
+	ctx := context.Background()
+	if rollout, err := (RolloutOption{Percent: 50, Key: "1"}).decide(ctx, ""); rollout != nil || err != nil {
+		t.Errorf("expect no rollout if it's not enabled, but get %+v, %v", rollout, err)
+	}
+
+	testSuites := []struct {
+		key      string
+		percent  float64
+		bucket   int
+		enforced bool
+	}{
+		{key: "1", percent: 50, bucket: 44, enforced: true},
+		{key: "3", percent: 50, bucket: 82},
+		{key: "2", percent: 1, bucket: 1},
+		{key: "2", percent: 1.5, bucket: 1, enforced: true},
+		{key: "42", percent: 0, bucket: 11},
+		{key: "3", percent: 100, bucket: 82, enforced: true},
+	}
+	for _, testCase := range testSuites {
+		rollout, err := (RolloutOption{Enabled: true, Percent: testCase.percent, Key: testCase.key}).decide(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rollout.Key != testCase.key || rollout.Bucket != testCase.bucket || rollout.Enforced != testCase.enforced || rollout.Percent != testCase.percent {
+			t.Errorf("expect bucket %d and enforced %t of %s at %.1f%%, but get %+v", testCase.bucket, testCase.enforced, testCase.key, testCase.percent, rollout)
+		}
+	}
+}
+
+func TestRolloutDecideDefaultKey(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "feature/gate")
+	commitFiles(t, dir, "init", map[string]string{"go.mod": "module example.com/foo\n"})
+	commitFiles(t, dir, "push", map[string]string{"foo.go": "package foo\n"})
+
+	rollout, err := (RolloutOption{Enabled: true, Percent: 50}).decide(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rollout.Key != "feature/gate" || rollout.Bucket != rolloutBucket("feature/gate") {
+		t.Errorf("expect the key is the current branch, but get %+v", rollout)
+	}
+
+	git(t, dir, "checkout", "-q", "--detach", "HEAD~1")
+	if _, err := (RolloutOption{Enabled: true, Percent: 50}).decide(ctx, dir); !errors.Is(err, ErrInvalidRollout) {
+		t.Errorf("expect ErrInvalidRollout on a detached HEAD, but get %v", err)
+	}
+}
+
+func TestCheckBypassRollout(t *testing.T) {
+	diff := &diffCover{
+		coverageBaseline: 80,
+		bypassOption:     &BypassOption{},
+		auditRecorder:    audit.NewRecorder(""),
+		logger:           logrus.New(),
+	}
+
+	enforced := &report.Statistics{TotalCoveragePercent: 50, Rollout: &report.Rollout{Percent: 50, Key: "1", Bucket: 44, Enforced: true}}
+	if err := diff.checkBypass(context.Background(), enforced); err != nil {
+		t.Fatal(err)
+	}
+	if enforced.Bypass != nil || diff.pass(enforced) == nil {
+		t.Errorf("expect the gate of the enforced pull request fails, but get bypass %+v", enforced.Bypass)
+	}
+
+	warned := &report.Statistics{TotalCoveragePercent: 50, Rollout: &report.Rollout{Percent: 50, Key: "3", Bucket: 82}}
+	if err := diff.checkBypass(context.Background(), warned); err != nil {
+		t.Fatal(err)
+	}
+	if warned.Bypass == nil || warned.Bypass.Source != "rollout 50%" || diff.pass(warned) != nil {
+		t.Fatalf("expect the gate of the pull request out of the rollout is bypassed, but get %+v", warned.Bypass)
+	}
+	if expected := "the gate is enforced on 50% of the pull requests, and 3 in bucket 82 is not one of them"; warned.Bypass.Reason != expected {
+		t.Errorf("expect reason %q, but get %q", expected, warned.Bypass.Reason)
+	}
+	if actions := diff.auditRecorder.Actions(); len(actions) != 1 || actions[0].Details["source"] != "rollout 50%" {
+		t.Errorf("expect the rollout bypass recorded into audit log, but get %+v", actions)
+	}
+}
@@ -0,0 +1,48 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var ErrInvalidShard = errors.New("invalid shard")
+
+// ParseShard parses the shard in the format of index/count, such as 2/4, the index is from 1 to count.
+// It returns nil if the shard is empty, so all the packages are analyzed.
+func ParseShard(s string) (*report.Shard, error) {
+	if s == "" {
+		return nil, nil
+	}
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return nil, fmt.Errorf("%w %q, the format is index/count such as 2/4", ErrInvalidShard, s)
+	}
+	shard := &report.Shard{}
+	var err error
+	if shard.Index, err = strconv.Atoi(index); err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidShard, s, err)
+	}
+	if shard.Count, err = strconv.Atoi(count); err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidShard, s, err)
+	}
+	if shard.Count < 1 || shard.Index < 1 || shard.Index > shard.Count {
+		return nil, fmt.Errorf("%w %q, the index is from 1 to the count", ErrInvalidShard, s)
+	}
+	return shard, nil
+}
+
+// inShard reports whether the package is assigned to the shard. A package is assigned by the FNV-1a hash of its import path,
+// so every job assigns it to the same shard regardless of the other packages, and all the packages are in the nil shard.
+func inShard(shard *report.Shard, pkg string) bool {
+	if shard == nil {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(pkg))
+	return int(h.Sum32()%uint32(shard.Count)) == shard.Index-1
+}
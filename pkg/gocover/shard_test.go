@@ -0,0 +1,65 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("2/4")
+	if err != nil || *shard != (report.Shard{Index: 2, Count: 4}) {
+		t.Errorf("expect shard 2/4, but get %v, %v", shard, err)
+	}
+	if shard, err := ParseShard(""); shard != nil || err != nil {
+		t.Errorf("expect no shard, but get %v, %v", shard, err)
+	}
+	for _, s := range []string{"2", "a/4", "2/b", "0/4", "5/4", "1/0"} {
+		if _, err := ParseShard(s); !errors.Is(err, ErrInvalidShard) {
+			t.Errorf("%s: expect ErrInvalidShard, but get %v", s, err)
+		}
+	}
+}
+
+func TestInShard(t *testing.T) {
+	count := 3
+	assigned := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		pkg := fmt.Sprintf("github.com/Azure/gocover/pkg/p%d", i)
+		for index := 1; index <= count; index++ {
+			if inShard(&report.Shard{Index: index, Count: count}, pkg) {
+				if previous, ok := assigned[pkg]; ok {
+					t.Fatalf("%s is in shard %d and %d", pkg, previous, index)
+				}
+				assigned[pkg] = index
+			}
+		}
+		if _, ok := assigned[pkg]; !ok {
+			t.Fatalf("%s is in no shard", pkg)
+		}
+		if !inShard(nil, pkg) {
+			t.Fatalf("expect %s is in the nil shard", pkg)
+		}
+	}
+}
+
+func TestCoverageEngineShard(t *testing.T) {
+	packages := engineTestPackages(t)
+	for index := 1; index <= 2; index++ {
+		engine := newTestEngine(nil)
+		engine.shard = &report.Shard{Index: index, Count: 2}
+		statistics := &report.Statistics{}
+		if _, err := engine.calculate(packages, statistics, allStatements); err != nil {
+			t.Fatal(err)
+		}
+		expect := 0
+		if inShard(engine.shard, packages[0].Name) {
+			expect = 1
+		}
+		if len(statistics.CoverageProfile) != expect || statistics.Shard != engine.shard {
+			t.Errorf("shard %d: expect %d profiles, but get %+v", index, expect, statistics)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package gocover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidSkipList = errors.New("invalid skip list")
+
+// skipListDateLayout is the layout of the expiry of a skip list entry.
+const skipListDateLayout = "2006-01-02"
+
+// SkipList is the content of the skip list file, it lists the files whose coverage collection is known to be flaky,
+// such as the code loaded by plugins. Unlike the ignore annotations, the entries are managed in one file,
+// each has an owner and an expiry, and all of them are shown in the reports until they're removed.
+type SkipList struct {
+	Files []*SkipEntry `yaml:"files" json:"files"`
+}
+
+// SkipEntry is an entry of the skip list.
+type SkipEntry struct {
+	// Pattern is the doublestar pattern of the files, it matches the file names in the reports,
+	// which start with the module path, such as example.com/foo/plugins/**.
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Owner is who fixes the coverage collection of the files, such as a team or a person.
+	Owner string `yaml:"owner" json:"owner"`
+	// Expires is the last day in UTC that the files are skipped in the format of YYYY-MM-DD,
+	// the files count for coverage again after it.
+	Expires string `yaml:"expires" json:"expires"`
+	// Reason explains why the coverage of the files is flaky.
+	Reason string `yaml:"reason" json:"reason"`
+
+	expires time.Time
+}
+
+// LoadSkipList loads the skip list file in YAML or JSON.
+func LoadSkipList(file string) (*SkipList, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read skip list: %w", err)
+	}
+
+	list := &SkipList{}
+	if err := yaml.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrInvalidSkipList, file, err)
+	}
+	if err := list.Validate(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Validate validates the entries, each entry requires a pattern, an owner and an expiry.
+func (l *SkipList) Validate() error {
+	for i, e := range l.Files {
+		e.Pattern = strings.TrimSpace(e.Pattern)
+		if e.Pattern == "" || !doublestar.ValidatePattern(e.Pattern) {
+			return fmt.Errorf("%w: entry %d has bad pattern %q", ErrInvalidSkipList, i, e.Pattern)
+		}
+		if strings.TrimSpace(e.Owner) == "" {
+			return fmt.Errorf("%w: entry %s has no owner", ErrInvalidSkipList, e.Pattern)
+		}
+		expires, err := time.Parse(skipListDateLayout, strings.TrimSpace(e.Expires))
+		if err != nil {
+			return fmt.Errorf("%w: entry %s has bad expiry %q, the date should be YYYY-MM-DD", ErrInvalidSkipList, e.Pattern, e.Expires)
+		}
+		e.expires = expires
+	}
+	return nil
+}
+
+// expired reports whether the entry is over at the time, the last day is included.
+func (e *SkipEntry) expired(now time.Time) bool {
+	return !now.Before(e.expires.AddDate(0, 0, 1))
+}
+
+// skipList skips the files of the active entries of the skip list, it's nil if there is no skip list.
+type skipList struct {
+	entries []*SkipEntry
+	expired []bool
+}
+
+// newSkipList loads the skip list file, the expired entries are warned, since their files count for coverage again.
+// It returns nil if the file is empty.
+func newSkipList(file string, now time.Time, logger logrus.FieldLogger) (*skipList, error) {
+	if file == "" {
+		return nil, nil
+	}
+	list, err := LoadSkipList(file)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &skipList{entries: list.Files, expired: make([]bool, len(list.Files))}
+	for i, e := range list.Files {
+		if s.expired[i] = e.expired(now); s.expired[i] {
+			logger.Warnf("skip list entry %s of %s expired on %s, its files count for coverage, remove the entry once the coverage is fixed", e.Pattern, e.Owner, e.Expires)
+		}
+	}
+	return s, nil
+}
+
+// skip reports whether the file is skipped by an active entry, the file is recorded in the entry of the statistics.
+func (s *skipList) skip(statistics *report.Statistics, fileName string) bool {
+	if s == nil {
+		return false
+	}
+	s.report(statistics)
+	for i, e := range s.entries {
+		if s.expired[i] {
+			continue
+		}
+		if ok, _ := doublestar.Match(e.Pattern, fileName); !ok {
+			continue
+		}
+		entry := statistics.SkipList[i]
+		for _, f := range entry.Files {
+			if f == fileName {
+				return true
+			}
+		}
+		entry.Files = append(entry.Files, fileName)
+		return true
+	}
+	return false
+}
+
+// report adds all the entries to the statistics, so every report shows them until they're removed from the skip list.
+func (s *skipList) report(statistics *report.Statistics) {
+	if s == nil || statistics.SkipList != nil {
+		return
+	}
+	statistics.SkipList = make([]*report.SkipListEntry, 0, len(s.entries))
+	for i, e := range s.entries {
+		statistics.SkipList = append(statistics.SkipList, &report.SkipListEntry{
+			Pattern: e.Pattern,
+			Owner:   e.Owner,
+			Expires: e.Expires,
+			Reason:  e.Reason,
+			Expired: s.expired[i],
+		})
+	}
+}
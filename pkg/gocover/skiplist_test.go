@@ -0,0 +1,99 @@
+package gocover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func writeSkipList(t *testing.T, contents string) string {
+	file := filepath.Join(t.TempDir(), "skiplist.yml")
+	if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadSkipList(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		list, err := LoadSkipList(writeSkipList(t, `files:
+- pattern: github.com/Azure/gocover/pkg/plugins/**
+  owner: team-plugins
+  expires: 2023-06-30
+  reason: loaded by plugin.Open, the coverage is not collected
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Files) != 1 || list.Files[0].Owner != "team-plugins" || list.Files[0].expires != time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC) {
+			t.Errorf("unexpected skip list %+v", list.Files)
+		}
+	})
+
+	for name, contents := range map[string]string{
+		"no owner":    "files:\n- pattern: '**/plugin.go'\n  expires: 2023-06-30\n",
+		"no expiry":   "files:\n- pattern: '**/plugin.go'\n  owner: foo\n",
+		"bad expiry":  "files:\n- pattern: '**/plugin.go'\n  owner: foo\n  expires: next month\n",
+		"bad pattern": "files:\n- pattern: '[plugin.go'\n  owner: foo\n  expires: 2023-06-30\n",
+		"bad yaml":    "files: [",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadSkipList(writeSkipList(t, contents)); !errors.Is(err, ErrInvalidSkipList) {
+				t.Errorf("expect ErrInvalidSkipList, but get %v", err)
+			}
+		})
+	}
+}
+
+func TestSkipList(t *testing.T) {
+	file := writeSkipList(t, `files:
+- pattern: '**/engine.go'
+  owner: foo
+  expires: 2023-06-30
+  reason: flaky
+- pattern: '**/expired.go'
+  owner: bar
+  expires: 2023-05-31
+`)
+	now := time.Date(2023, 6, 30, 23, 0, 0, 0, time.UTC)
+	skipList, err := newSkipList(file, now, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newTestEngine(nil)
+	engine.skipList = skipList
+	statistics := &report.Statistics{}
+	if _, err := engine.calculate(engineTestPackages(t), statistics, allStatements); err != nil {
+		t.Fatal(err)
+	}
+	if len(statistics.CoverageProfile) != 0 {
+		t.Errorf("engine.go should be skipped, but get %d profiles", len(statistics.CoverageProfile))
+	}
+	if len(statistics.SkipList) != 2 {
+		t.Fatalf("expect all the entries to be reported, but get %+v", statistics.SkipList)
+	}
+	if e := statistics.SkipList[0]; e.Expired || len(e.Files) != 1 || e.Files[0] != "github.com/Azure/gocover/pkg/gocover/engine.go" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e := statistics.SkipList[1]; !e.Expired || skipList.skip(statistics, "github.com/Azure/gocover/pkg/gocover/expired.go") {
+		t.Errorf("the expired entry should not skip its files, but get %+v", e)
+	}
+
+	// the entries expire after their last days.
+	if skipList, err = newSkipList(file, now.Add(time.Hour), logrus.New()); err != nil {
+		t.Fatal(err)
+	}
+	if skipList.skip(&report.Statistics{}, "github.com/Azure/gocover/pkg/gocover/engine.go") {
+		t.Error("engine.go should be counted after the entry expires")
+	}
+
+	if skipList, err = newSkipList("", now, logrus.New()); err != nil || skipList != nil || skipList.skip(statistics, "foo.go") {
+		t.Errorf("expect no skip list, but get %v, %v", skipList, err)
+	}
+}
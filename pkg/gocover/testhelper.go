@@ -0,0 +1,185 @@
+package gocover
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// TestHelperOption decides which packages are test helpers, such as internal/testutil, they only contain the helpers
+// consumed by the tests of other packages, so their missing tests and cover profiles are not reported.
+type TestHelperOption struct {
+	// Patterns match the import paths of the test helper packages, such as **/testutil.
+	Patterns []string
+	// Detect regards the packages of the module that are only imported by the _test.go files as test helpers,
+	// the imports of the test helpers count as the imports of the tests.
+	Detect bool
+}
+
+// DefaultTestHelperOption returns the test helper option that detects the test helpers by the imports.
+func DefaultTestHelperOption() TestHelperOption {
+	return TestHelperOption{Detect: true}
+}
+
+// packageImporters are the files of the module that import a package.
+type packageImporters struct {
+	// tests indicates the package is imported by a _test.go file.
+	tests bool
+	// packages are the packages whose non-test files import the package.
+	packages map[string]bool
+}
+
+// testHelpers recognizes the test helper packages of a module, the imports of the module are loaded at the first detection.
+type testHelpers struct {
+	option     TestHelperOption
+	moduleDir  string
+	modulePath string
+	logger     logrus.FieldLogger
+
+	importers map[string]*packageImporters
+	// exempted are the test helpers whose missing tests are not reported.
+	exempted map[string]bool
+}
+
+// newTestHelpers creates the recognizer of the test helpers of the module in the directory.
+func newTestHelpers(option TestHelperOption, moduleDir, modulePath string, logger logrus.FieldLogger) *testHelpers {
+	return &testHelpers{
+		option:     option,
+		moduleDir:  moduleDir,
+		modulePath: modulePath,
+		logger:     logger,
+		exempted:   make(map[string]bool),
+	}
+}
+
+// exempt returns true if the package is a test helper, the package is recorded as exempted.
+// A nil recognizer regards no package as a test helper.
+func (h *testHelpers) exempt(pkg string) bool {
+	if h == nil {
+		return false
+	}
+	if h.exempted[pkg] {
+		return true
+	}
+	if !h.matchPatterns(pkg) && !(h.option.Detect && h.importedByTests(pkg, make(map[string]bool))) {
+		return false
+	}
+	h.logger.Debugf("package %s is a test helper, its missing tests are not reported", pkg)
+	h.exempted[pkg] = true
+	return true
+}
+
+// packages returns the exempted test helpers in order.
+func (h *testHelpers) packages() []string {
+	if h == nil || len(h.exempted) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(h.exempted))
+	for pkg := range h.exempted {
+		result = append(result, pkg)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (h *testHelpers) matchPatterns(pkg string) bool {
+	for _, pattern := range h.option.Patterns {
+		match, err := doublestar.Match(pattern, pkg)
+		if err != nil {
+			h.logger.Warnf("test helper pattern [%s, %s] %s", pattern, pkg, err)
+			continue
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// importedByTests returns true if the package is imported by the tests, and the packages that import it are test helpers too.
+func (h *testHelpers) importedByTests(pkg string, visited map[string]bool) bool {
+	if h.importers == nil {
+		h.importers = h.loadImporters()
+	}
+	importers, ok := h.importers[pkg]
+	if !ok || visited[pkg] {
+		return false
+	}
+	visited[pkg] = true
+	if len(importers.packages) == 0 {
+		return importers.tests
+	}
+	for importer := range importers.packages {
+		if !h.matchPatterns(importer) && !h.importedByTests(importer, visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadImporters reads the imports of the go files of the module, the vendor, testdata and hidden directories,
+// and the nested modules are skipped. The files fail to parse are skipped too.
+func (h *testHelpers) loadImporters() map[string]*packageImporters {
+	importers := make(map[string]*packageImporters)
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(h.moduleDir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if filename != h.moduleDir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(filename, "go.mod")); err == nil && filename != h.moduleDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(filename, ".go") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, filename, nil, parser.ImportsOnly)
+		if err != nil {
+			h.logger.Debugf("skip the imports of %s: %s", filename, err)
+			return nil
+		}
+		rel, err := filepath.Rel(h.moduleDir, filepath.Dir(filename))
+		if err != nil {
+			return nil
+		}
+		importer := path.Join(h.modulePath, filepath.ToSlash(rel))
+		isTest := strings.HasSuffix(filename, "_test.go")
+		for _, spec := range f.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || imported == importer || (imported != h.modulePath && !strings.HasPrefix(imported, h.modulePath+"/")) {
+				continue
+			}
+			i, ok := importers[imported]
+			if !ok {
+				i = &packageImporters{packages: make(map[string]bool)}
+				importers[imported] = i
+			}
+			if isTest {
+				i.tests = true
+			} else {
+				i.packages[importer] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Warnf("load the imports of the module to detect the test helpers: %s", err)
+	}
+	return importers
+}
@@ -0,0 +1,102 @@
+package gocover
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/testresult"
+	"github.com/sirupsen/logrus"
+)
+
+func writeModuleFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTestHelpers(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"go.mod":                          "module example.com/m\n",
+		"internal/testutil/testutil.go":   "package testutil\n",
+		"internal/testutil/db/db.go":      "package db\n\nimport _ \"example.com/m/internal/testutil\"\n",
+		"foo/foo.go":                      "package foo\n",
+		"foo/foo_test.go":                 "package foo\n\nimport _ \"example.com/m/internal/testutil/db\"\n",
+		"bar/bar.go":                      "package bar\n\nimport _ \"example.com/m/foo\"\n",
+		"bar/bar_test.go":                 "package bar\n\nimport _ \"example.com/m/fixtures\"\n",
+		"fixtures/fixtures.go":            "package fixtures\n",
+		"vendor/example.com/v/v.go":       "package v\n\nimport _ \"example.com/m/fixtures\"\n",
+		"nested/go.mod":                   "module example.com/m/nested\n",
+		"nested/nested.go":                "package nested\n\nimport _ \"example.com/m/fixtures\"\n",
+		"broken/broken.go":                "package broken\n\nimport (\n",
+		"mocks/mocks.go":                  "package mocks\n",
+		"mocks/generated/generated.go":    "package generated\n",
+		"internal/testutil/testdata/x.go": "package x\n\nimport _ \"example.com/m/foo\"\n",
+	})
+
+	testSuites := []struct {
+		name     string
+		option   TestHelperOption
+		pkg      string
+		expected bool
+	}{
+		{name: "imported by tests", option: DefaultTestHelperOption(), pkg: "example.com/m/internal/testutil/db", expected: true},
+		{name: "imported by a test helper", option: DefaultTestHelperOption(), pkg: "example.com/m/internal/testutil", expected: true},
+		{name: "imported only by tests of the module", option: DefaultTestHelperOption(), pkg: "example.com/m/fixtures", expected: true},
+		{name: "imported by sources", option: DefaultTestHelperOption(), pkg: "example.com/m/foo"},
+		{name: "not imported", option: DefaultTestHelperOption(), pkg: "example.com/m/mocks"},
+		{name: "detection disabled", option: TestHelperOption{}, pkg: "example.com/m/internal/testutil"},
+		{name: "pattern", option: TestHelperOption{Patterns: []string{"**/mocks/**"}}, pkg: "example.com/m/mocks/generated", expected: true},
+	}
+	for _, testSuite := range testSuites {
+		helpers := newTestHelpers(testSuite.option, dir, "example.com/m", logrus.New())
+		if exempted := helpers.exempt(testSuite.pkg); exempted != testSuite.expected {
+			t.Errorf("%s: expect %s exempted %t, but get %t", testSuite.name, testSuite.pkg, testSuite.expected, exempted)
+		}
+	}
+
+	var helpers *testHelpers
+	if helpers.exempt("example.com/m/internal/testutil") || helpers.packages() != nil {
+		t.Errorf("expect a nil recognizer exempts nothing")
+	}
+}
+
+func TestMissingTestsOfTestHelpers(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"go.mod":                        "module example.com/m\n",
+		"internal/testutil/testutil.go": "package testutil\n",
+		"foo/foo.go":                    "package foo\n",
+		"foo/foo_test.go":               "package foo\n\nimport _ \"example.com/m/internal/testutil\"\n",
+		"bar/bar.go":                    "package bar\n",
+	})
+	statistics := &report.Statistics{
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "example.com/m/internal/testutil/testutil.go"},
+			{FileName: "example.com/m/bar/bar.go"},
+		},
+		TestPackages: []*testresult.Package{
+			{Name: "example.com/m/internal/testutil", Status: testresult.SkipAction},
+			{Name: "example.com/m/bar", Status: testresult.SkipAction},
+		},
+	}
+	helpers := newTestHelpers(DefaultTestHelperOption(), dir, "example.com/m", logrus.New())
+	addMissingTests(statistics, helpers)
+
+	missing := statistics.Errors.ByKind(report.MissingTestsError)
+	if len(missing) != 1 || missing[0].Target != "example.com/m/bar" {
+		t.Errorf("expect only the missing tests of bar, but get %v", statistics.Errors)
+	}
+	if packages := helpers.packages(); !reflect.DeepEqual(packages, []string{"example.com/m/internal/testutil"}) {
+		t.Errorf("unexpected test helpers %v", packages)
+	}
+}
@@ -0,0 +1,74 @@
+package gocover
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// todoPattern matches a TODO or FIXME marker in a line or block comment, the text after the marker is captured.
+var todoPattern = regexp.MustCompile(`(?://|/\*).*?\b(TODO|FIXME)\b(.*)`)
+
+// TodoOption decides whether the TODO and FIXME comments added in the changed lines are counted and how they are gated.
+type TodoOption struct {
+	// Enabled counts the added comments, they are reported alongside the coverage.
+	Enabled bool
+	// MaxTodos is the maximum number of the added comments, it's not gated if it's negative.
+	MaxTodos int
+	// MaxDensity is the maximum added comments per 100 added lines, it's not gated if it's negative.
+	MaxDensity float64
+}
+
+// DefaultTodoOption returns the todo option that counts nothing.
+func DefaultTodoOption() TodoOption {
+	return TodoOption{MaxTodos: -1, MaxDensity: -1}
+}
+
+// countTodos counts the TODO and FIXME comments in the added lines of the go files, the section contents
+// collected by the diff are scanned so the files are not read again. The files out of the module and the
+// excluded files are not counted.
+func (diff *diffCover) countTodos(changes []*gittool.Change) *report.TodoStatistics {
+	if !diff.todos.Enabled {
+		return nil
+	}
+
+	result := &report.TodoStatistics{MaxTodos: diff.todos.MaxTodos, MaxDensity: diff.todos.MaxDensity}
+	for _, change := range changes {
+		fileName, ok := diff.reportFileName(change)
+		if !ok || !strings.HasSuffix(fileName, ".go") || inExclueds(diff.excludeFiles, diff.excludePatterns, fileName, diff.logger) {
+			continue
+		}
+		for _, section := range change.Sections {
+			if section.Operation != gittool.Add {
+				continue
+			}
+			result.AddedLines += len(section.Contents)
+			for i, line := range section.Contents {
+				match := todoPattern.FindStringSubmatch(line)
+				if match == nil {
+					continue
+				}
+				result.Todos = append(result.Todos, &report.Todo{
+					FileName: fileName,
+					Line:     section.StartLine + i,
+					Marker:   match[1],
+					Text:     todoText(match[2]),
+				})
+			}
+		}
+	}
+	return result
+}
+
+// todoText trims the separators after the marker and the end of a block comment, such as `(owner): text */`.
+func todoText(s string) string {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "*/"))
+	if strings.HasPrefix(s, "(") {
+		if i := strings.Index(s, ")"); i >= 0 {
+			s = s[i+1:]
+		}
+	}
+	return strings.TrimSpace(strings.TrimLeft(s, ":- "))
+}
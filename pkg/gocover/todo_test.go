@@ -0,0 +1,71 @@
+package gocover
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCountTodos(t *testing.T) {
+	diff := &diffCover{
+		moduleDir:       "modulea",
+		modulePath:      "github.com/Azure/modulea",
+		excludeFiles:    make(excludeFileCache),
+		excludePatterns: []string{"**/zz_generated.go"},
+		todos:           TodoOption{Enabled: true, MaxTodos: 1, MaxDensity: -1},
+		logger:          logrus.New(),
+	}
+	changes := []*gittool.Change{
+		{
+			FileName: "modulea/foo.go",
+			Sections: []*gittool.Section{
+				{Operation: gittool.Equal, StartLine: 1, Contents: []string{"// TODO: unchanged"}},
+				{Operation: gittool.Add, StartLine: 3, Contents: []string{
+					"func Foo() {",
+					"	// TODO(owner): handle the error",
+					`	s := "TODO is not a comment"`,
+					"	bar() /* FIXME: leaks */",
+				}},
+			},
+		},
+		{FileName: "modulea/zz_generated.go", Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 1, Contents: []string{"// TODO: generated"}}}},
+		{FileName: "modulea/README.md", Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 1, Contents: []string{"<!-- TODO: docs -->"}}}},
+		{FileName: "moduleb/bar.go", Sections: []*gittool.Section{{Operation: gittool.Add, StartLine: 1, Contents: []string{"// TODO: other module"}}}},
+	}
+
+	todos := diff.countTodos(changes)
+	if todos.AddedLines != 4 || len(todos.Todos) != 2 {
+		t.Fatalf("expect 2 comments in 4 added lines, but get %d in %d", len(todos.Todos), todos.AddedLines)
+	}
+	expect := []report.Todo{
+		{FileName: "github.com/Azure/modulea/foo.go", Line: 4, Marker: "TODO", Text: "handle the error"},
+		{FileName: "github.com/Azure/modulea/foo.go", Line: 6, Marker: "FIXME", Text: "leaks"},
+	}
+	for i, todo := range todos.Todos {
+		if *todo != expect[i] {
+			t.Errorf("expect %+v, but get %+v", expect[i], *todo)
+		}
+	}
+	if todos.Density() != 50 || todos.Passed() {
+		t.Errorf("expect density 50 over the limit, but get %.2f", todos.Density())
+	}
+
+	statistics := &report.Statistics{Todos: todos}
+	err := checkGate(statistics, 0)
+	var e *GoCoverError
+	if !errors.As(err, &e) || e.ExitCode != LowCoverageErrorExitCode {
+		t.Errorf("expect low coverage error, but get %v", err)
+	}
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "spike"}
+	if err := checkGate(statistics, 0); err != nil {
+		t.Errorf("bypassed gate should pass, but get %s", err)
+	}
+
+	diff.todos = DefaultTodoOption()
+	if todos := diff.countTodos(changes); todos != nil {
+		t.Errorf("expect no counting if it's disabled, but get %+v", todos)
+	}
+}
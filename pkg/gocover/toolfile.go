@@ -0,0 +1,87 @@
+package gocover
+
+import (
+	"bufio"
+	"errors"
+	"go/build/constraint"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// ignoreBuildTag is the build tag that conventionally excludes the tool and script files, such as the generators
+// run by `go run gen.go`, from the build of the package.
+const ignoreBuildTag = "ignore"
+
+// isToolFile returns true if the go file is excluded from the build by the ignore build tag, such as `//go:build ignore`
+// or `// +build ignore`. Such a file is never compiled into its package, so it has no cover profile.
+// It returns false if the file doesn't exist.
+func isToolFile(filename string) (bool, error) {
+	if filepath.Ext(filename) != ".go" {
+		return false, nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	// the build constraints must be in the leading comments of the file, before the package clause.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "/*") || strings.HasPrefix(line, "*") {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		if !constraint.IsGoBuild(line) && !constraint.IsPlusBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			continue
+		}
+		mentioned := false
+		// the other tags are satisfied, so the file is a tool file only if it's the ignore tag that excludes it.
+		built := expr.Eval(func(tag string) bool {
+			if tag == ignoreBuildTag {
+				mentioned = true
+				return false
+			}
+			return true
+		})
+		if mentioned && !built {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// skipToolFiles removes the changes of the tool files before they are parsed, the removed files are added to the statistics
+// as non-coverable files, rather than reported as the files without cover profiles.
+func (diff *diffCover) skipToolFiles(changes []*gittool.Change, statistics *report.Statistics) ([]*gittool.Change, error) {
+	result := make([]*gittool.Change, 0, len(changes))
+	for _, change := range changes {
+		ok, err := isToolFile(filepath.Join(diff.repositoryPath, change.FileName))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			fileName, _ := diff.reportFileName(change)
+			diff.logger.Debugf("skip %s: excluded from the build by the %s build tag", fileName, ignoreBuildTag)
+			statistics.NonCoverableFiles = append(statistics.NonCoverableFiles, fileName)
+			continue
+		}
+		result = append(result, change)
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package gocover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestIsToolFile(t *testing.T) {
+	dir := t.TempDir()
+	testSuites := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{name: "gen.go", content: "//go:build ignore\n\npackage main\n", expected: true},
+		{name: "legacy.go", content: "// Copyright 2022.\n\n// +build ignore\n\npackage main\n", expected: true},
+		{name: "platform.go", content: "//go:build ignore && linux\n\npackage main\n", expected: true},
+		{name: "linux.go", content: "//go:build linux\n\npackage foo\n"},
+		{name: "not_ignore.go", content: "//go:build !ignore\n\npackage foo\n"},
+		{name: "body.go", content: "package foo\n\n//go:build ignore\n"},
+		{name: "plain.go", content: "package foo\n"},
+		{name: "gen.sh", content: "//go:build ignore\n"},
+	}
+	for _, testSuite := range testSuites {
+		filename := filepath.Join(dir, testSuite.name)
+		if err := os.WriteFile(filename, []byte(testSuite.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := isToolFile(filename)
+		if err != nil {
+			t.Fatalf("%s: %s", testSuite.name, err)
+		}
+		if ok != testSuite.expected {
+			t.Errorf("%s: expect tool file %t, but get %t", testSuite.name, testSuite.expected, ok)
+		}
+	}
+
+	if ok, err := isToolFile(filepath.Join(dir, "deleted.go")); err != nil || ok {
+		t.Errorf("expect a deleted file is not a tool file, but get %t, %v", ok, err)
+	}
+}
+
+func TestSkipToolFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tools"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tools", "gen.go"), []byte("//go:build ignore\n\npackage main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "foo.go"), []byte("package foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := &diffCover{repositoryPath: dir, moduleDir: "", modulePath: "github.com/Azure/gocover", logger: logrus.New()}
+	statistics := &report.Statistics{}
+	changes, err := diff.skipToolFiles([]*gittool.Change{{FileName: "tools/gen.go"}, {FileName: "foo.go"}}, statistics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].FileName != "foo.go" {
+		t.Errorf("expect only foo.go is kept, but get %+v", changes)
+	}
+	if len(statistics.NonCoverableFiles) != 1 || statistics.NonCoverableFiles[0] != "github.com/Azure/gocover/tools/gen.go" {
+		t.Errorf("unexpected non-coverable files %v", statistics.NonCoverableFiles)
+	}
+}
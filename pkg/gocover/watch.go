@@ -0,0 +1,378 @@
+package gocover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultWatchInterval is how often the files of the module are checked for changes by default.
+const DefaultWatchInterval = time.Second
+
+// WatchOption contains the input for gocover watch command.
+type WatchOption struct {
+	CompareBranch  string
+	RepositoryPath string
+	ModuleDir      string
+	// ModulePath is the import path of the module directory, it's read from go.mod if it's empty.
+	ModulePath       string
+	GoFlags          []string
+	CoverageBaseline float64
+	ReportFormat     string
+	ReportName       string
+	// OutputDir is the directory that the reports are written into after each run,
+	// the reports are written into a temporary directory if it's empty.
+	OutputDir string
+	Excludes  []string
+	Style     string
+	// SkipListFile is the skip list file of the files whose coverage collection is flaky, no files are skipped if it's empty.
+	SkipListFile string
+	// Interval is how often the files of the module are checked for changes, DefaultWatchInterval is used if it's zero.
+	Interval time.Duration
+	// Color colors the changed hunks printed after each run.
+	Color bool
+
+	StdOut io.Writer
+	StdErr io.Writer
+	Logger logrus.FieldLogger
+}
+
+// NewWatchOption returns a Watch Option with default values.
+func NewWatchOption() *WatchOption {
+	return &WatchOption{
+		CompareBranch:    DefaultCompareBranch,
+		CoverageBaseline: DefaultCoverageBaseline,
+		ReportFormat:     DefaultReportFormat,
+		Interval:         DefaultWatchInterval,
+		Color:            true,
+	}
+}
+
+// Watch calculates the diff coverage of the working tree whenever the files of the module change, until the context is done.
+// The tests of the packages of the changed go files run again with coverage, and the cover profiles of the other packages
+// are kept from their last runs, so a save only waits for the tests of the affected packages. After the tests,
+// the diff coverage of the working tree against the compared branch is printed with the changed hunks,
+// and the reports are written again. The low coverage and the failed tests are printed rather than stopping the watch.
+func Watch(ctx context.Context, o *WatchOption) error {
+	repositoryAbsPath, err := filepath.Abs(o.RepositoryPath)
+	if err != nil {
+		return fmt.Errorf("get absolute path of repo: %w", err)
+	}
+	moduleDir := filepath.Join(repositoryAbsPath, o.ModuleDir)
+	if _, err := resolveModulePath(moduleDir, o.ModulePath, o.Logger); err != nil {
+		return fmt.Errorf("resolve module path: %w", err)
+	}
+	workDir, err := createGoCoverTempDirectory()
+	if err != nil {
+		return fmt.Errorf("create gocover temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	outputDir := o.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Join(workDir, "reports")
+	}
+	if outputDir, err = filepath.Abs(outputDir); err != nil {
+		return fmt.Errorf("get absolute path of output dir: %w", err)
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	profileDir := filepath.Join(workDir, "profiles")
+	if err := os.MkdirAll(profileDir, os.ModePerm); err != nil {
+		return fmt.Errorf("create profile directory: %w", err)
+	}
+
+	interval := o.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &watcher{
+		option:         o,
+		repositoryPath: repositoryAbsPath,
+		moduleDir:      moduleDir,
+		outputDir:      outputDir,
+		profileDir:     profileDir,
+		profiles:       make(map[string]string),
+		logger:         o.Logger.WithField("source", "watch"),
+	}
+	return w.watch(ctx, interval)
+}
+
+// watcher keeps the state of the files and the cover profiles between the runs.
+type watcher struct {
+	option         *WatchOption
+	repositoryPath string
+	moduleDir      string
+	outputDir      string
+	profileDir     string
+	// profiles are the cover profiles of the packages, by the directories of the packages relative to the module.
+	profiles map[string]string
+	logger   logrus.FieldLogger
+}
+
+// fileState is the state of a watched file, a file is changed if either changes.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (w *watcher) watch(ctx context.Context, interval time.Duration) error {
+	snapshot, err := w.scan()
+	if err != nil {
+		return err
+	}
+
+	// the first run covers the packages changed against the compared branch, the later runs cover the files saved since.
+	packages, err := w.initialPackages()
+	if err != nil {
+		return err
+	}
+	w.run(ctx, "start", packages)
+	fmt.Fprintf(w.option.StdOut, "watching %s for changes, the reports are in %s\n", w.moduleDir, w.outputDir)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := w.scan()
+		if err != nil {
+			w.logger.WithError(err).Warn("scan module")
+			continue
+		}
+		changed := changedWatchFiles(snapshot, next)
+		if len(changed) == 0 {
+			continue
+		}
+		snapshot = next
+		w.run(ctx, "changed "+summarizeFiles(changed), w.affectedPackages(changed))
+	}
+}
+
+// run runs the tests of the packages and prints the diff coverage, the failures are printed so the watch goes on.
+func (w *watcher) run(ctx context.Context, reason string, packages []string) {
+	fmt.Fprintf(w.option.StdOut, "\n── %s %s ──\n", time.Now().Format("15:04:05"), reason)
+	for _, pkg := range packages {
+		if err := w.runTests(ctx, pkg); err != nil {
+			fmt.Fprintf(w.option.StdOut, "%s, the coverage is not updated\n", err)
+			return
+		}
+	}
+
+	if len(w.profiles) == 0 {
+		fmt.Fprintln(w.option.StdOut, "no changed go packages, waiting for changes")
+		return
+	}
+	err := inDirectory(w.moduleDir, w.logger, func() error {
+		return w.runDiff(ctx)
+	})
+	var gocoverErr *GoCoverError
+	switch {
+	case errors.As(err, &gocoverErr) && gocoverErr.ExitCode == LowCoverageErrorExitCode:
+		fmt.Fprintf(w.option.StdOut, "coverage gate fails: %s\n", err)
+	case err != nil:
+		fmt.Fprintf(w.option.StdOut, "diff coverage: %s\n", err)
+	}
+}
+
+// runTests runs the tests of the package with coverage, and keeps its cover profile for the later runs.
+// The failed tests still leave a cover profile, but a package that fails to build doesn't.
+func (w *watcher) runTests(ctx context.Context, pkg string) error {
+	profile := filepath.Join(w.profileDir, packageProfileName(pkg))
+	if err := os.Remove(profile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if !hasGoFiles(filepath.Join(w.moduleDir, filepath.FromSlash(pkg))) {
+		delete(w.profiles, pkg)
+		return nil
+	}
+
+	args := append([]string{"test"}, w.option.GoFlags...)
+	args = append(args, "-coverprofile", profile, "./"+pkg)
+	cmd := exec.CommandContext(ctx, goCmd(), args...)
+	cmd.Dir = w.moduleDir
+	cmd.Stdout = w.option.StdOut
+	cmd.Stderr = w.option.StdErr
+	w.logger.Debugf("run unit tests: 'go %s'", strings.Join(args, " "))
+
+	runErr := cmd.Run()
+	if _, err := os.Stat(profile); err != nil {
+		delete(w.profiles, pkg)
+		if runErr == nil {
+			// a package without tests has no cover profile.
+			return nil
+		}
+		return fmt.Errorf("go test ./%s: %w", pkg, runErr)
+	}
+	if runErr != nil {
+		fmt.Fprintf(w.option.StdOut, "tests of ./%s failed, the coverage of the passed tests is shown\n", pkg)
+	}
+	w.profiles[pkg] = profile
+	return nil
+}
+
+// runDiff calculates the diff coverage of the working tree with the kept cover profiles.
+func (w *watcher) runDiff(ctx context.Context) error {
+	profiles := make([]string, 0, len(w.profiles))
+	for _, p := range w.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Strings(profiles)
+
+	option := NewDiffOption()
+	option.CoverProfiles = profiles
+	option.CompareBranch = w.option.CompareBranch
+	option.DiffTarget = gittool.WorktreeTarget
+	option.RepositoryPath = w.repositoryPath
+	option.ModuleDir = w.option.ModuleDir
+	option.ModulePath = w.option.ModulePath
+	option.CoverageBaseline = w.option.CoverageBaseline
+	option.ReportFormat = w.option.ReportFormat
+	option.ReportName = w.option.ReportName
+	option.OutputDir = w.outputDir
+	option.Excludes = w.option.Excludes
+	option.SkipListFile = w.option.SkipListFile
+	option.Style = w.option.Style
+	option.Hunks = HunkOption{Enabled: true, Color: w.option.Color, Output: w.option.StdOut}
+	option.ReportGenerators = []report.ReportGenerator{report.NewConsoleReportGenerator(w.option.StdOut)}
+	option.DbOption = &dbclient.DBOption{}
+	option.Logger = w.logger
+
+	diff, err := NewDiffCover(option)
+	if err != nil {
+		return err
+	}
+	return diff.Run(ctx)
+}
+
+// initialPackages returns the packages of the go files changed in the working tree against the compared branch.
+func (w *watcher) initialPackages() ([]string, error) {
+	changed, err := moduleChangedFiles(w.repositoryPath, w.option.ModuleDir, w.option.CompareBranch, gittool.WorktreeTarget, false)
+	if err != nil {
+		return nil, err
+	}
+	return w.affectedPackages(changed), nil
+}
+
+// affectedPackages returns the sorted packages whose tests run again for the changed files relative to the module,
+// all the kept packages run again if go.mod or go.sum changes. The kept packages are returned even if they're removed,
+// so their cover profiles are dropped.
+func (w *watcher) affectedPackages(changed []string) []string {
+	packages := make(map[string]bool)
+	for _, f := range changed {
+		switch {
+		case f == "go.mod" || f == "go.sum":
+			for pkg := range w.profiles {
+				packages[pkg] = true
+			}
+		case strings.HasSuffix(f, ".go"):
+			dir := path.Dir(f)
+			if _, ok := w.profiles[dir]; ok || testablePackage(w.moduleDir, dir) {
+				packages[dir] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(packages))
+	for pkg := range packages {
+		result = append(result, pkg)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// scan returns the states of the go files, go.mod and go.sum of the module, by their paths relative to the module.
+// The hidden directories, the vendor directories, the nested modules and the output directory are skipped.
+func (w *watcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(w.moduleDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == w.moduleDir {
+				return nil
+			}
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || name == "vendor" || p == w.outputDir {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// the file is removed while walking, it's found by the next scan.
+			return nil
+		}
+		rel, err := filepath.Rel(w.moduleDir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", w.moduleDir, err)
+	}
+	return files, nil
+}
+
+// changedWatchFiles returns the sorted files that are added, modified or removed between the snapshots.
+func changedWatchFiles(previous, current map[string]fileState) []string {
+	var changed []string
+	for f, state := range current {
+		if old, ok := previous[f]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			changed = append(changed, f)
+		}
+	}
+	for f := range previous {
+		if _, ok := current[f]; !ok {
+			changed = append(changed, f)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// summarizeFiles returns the changed files for the header of a run, the rest are counted if there are many.
+func summarizeFiles(files []string) string {
+	const max = 3
+	if len(files) <= max {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:max], ", "), len(files)-max)
+}
+
+// packageProfileName returns the name of the cover profile of the package directory.
+func packageProfileName(pkg string) string {
+	if pkg == "." {
+		return "root.out"
+	}
+	return strings.ReplaceAll(pkg, "/", "_") + ".out"
+}
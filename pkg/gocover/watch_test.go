@@ -0,0 +1,154 @@
+package gocover
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestChangedWatchFiles(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileState{
+		"foo.go":     {modTime: now, size: 10},
+		"bar/bar.go": {modTime: now, size: 10},
+		"removed.go": {modTime: now, size: 10},
+		"go.mod":     {modTime: now, size: 10},
+	}
+	current := map[string]fileState{
+		"foo.go":     {modTime: now, size: 12},
+		"bar/bar.go": {modTime: now.Add(time.Second), size: 10},
+		"added.go":   {modTime: now, size: 10},
+		"go.mod":     {modTime: now, size: 10},
+	}
+	changed := changedWatchFiles(previous, current)
+	expected := []string{"added.go", "bar/bar.go", "foo.go", "removed.go"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("expect %v, but get %v", expected, changed)
+	}
+	if changed := changedWatchFiles(current, current); len(changed) != 0 {
+		t.Errorf("expect no changes, but get %v", changed)
+	}
+}
+
+func TestAffectedPackages(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"foo.go", "bar/bar.go", "testdata/foo.go"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, f)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("package foo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := &watcher{moduleDir: dir, profiles: map[string]string{"baz": "baz.out", ".": "root.out"}}
+	packages := w.affectedPackages([]string{"foo.go", "bar/bar.go", "bar/bar_test.go", "testdata/foo.go", "removed/removed.go", "README.md"})
+	if expected := []string{".", "bar"}; !reflect.DeepEqual(packages, expected) {
+		t.Errorf("expect %v, but get %v", expected, packages)
+	}
+	// a change of go.sum runs the kept packages again.
+	packages = w.affectedPackages([]string{"go.sum", "bar/bar.go"})
+	if expected := []string{".", "bar", "baz"}; !reflect.DeepEqual(packages, expected) {
+		t.Errorf("expect %v, but get %v", expected, packages)
+	}
+}
+
+func TestPackageProfileName(t *testing.T) {
+	for pkg, expected := range map[string]string{".": "root.out", "foo": "foo.out", "foo/bar": "foo_bar.out"} {
+		if name := packageProfileName(pkg); name != expected {
+			t.Errorf("%s: expect %s, but get %s", pkg, expected, name)
+		}
+	}
+}
+
+// syncBuffer is a buffer written by the watch and read by the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitForOutput(t *testing.T, out *syncBuffer, s string) {
+	deadline := time.Now().Add(time.Minute)
+	for !strings.Contains(out.String(), s) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect %q in the output, but get:\n%s", s, out.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	commitFiles(t, dir, "init", map[string]string{
+		"go.mod":      "module example.com/foo\n\ngo 1.20\n",
+		"foo.go":      "package foo\n\nfunc Foo() int {\n\treturn 1\n}\n",
+		"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tFoo()\n}\n",
+	})
+	fix := filepath.Join(dir, "fix.go")
+	if err := os.WriteFile(fix, []byte("package foo\n\nfunc Fix(a int) int {\n\tif a > 0 {\n\t\treturn a\n\t}\n\treturn -a\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &syncBuffer{}
+	o := NewWatchOption()
+	o.CompareBranch = "main"
+	o.RepositoryPath = dir
+	o.ModuleDir = "./"
+	o.ReportFormat = "json"
+	o.ReportName = "coverage"
+	o.OutputDir = t.TempDir()
+	o.Interval = 50 * time.Millisecond
+	o.Color = false
+	o.StdOut, o.StdErr = out, out
+	o.Logger = logrus.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Watch(ctx, o) }()
+
+	waitForOutput(t, out, "watching")
+	if !strings.Contains(out.String(), "fix.go 0.00%") {
+		t.Errorf("expect the uncovered fix.go in the output, but get:\n%s", out.String())
+	}
+
+	// the modification time may not change within the resolution of the file system, so the size changes too.
+	if err := os.WriteFile(filepath.Join(dir, "fix_test.go"), []byte("package foo\n\nimport \"testing\"\n\nfunc TestFix(t *testing.T) {\n\tFix(1)\n\tFix(-1)\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, out, "fix.go 100.00%")
+	if !strings.Contains(out.String(), "changed fix_test.go") {
+		t.Errorf("expect the changed file in the output, but get:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(o.OutputDir, "coverage.json")); err != nil {
+		t.Errorf("expect the report to be written: %s", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,7 @@
+// Package graphql implements the subset of GraphQL that gocover serves:
+// queries with arguments, variables, aliases and nested selections.
+// Mutations, subscriptions, fragments and directives are not supported.
+//
+// A schema is a tree of Objects, each field of an Object is resolved by a Resolver,
+// and only the selected fields are resolved, so a query pays for exactly the slices it asks for.
+package graphql
@@ -0,0 +1,281 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxRequestSize limits the size of a GraphQL request body.
+const maxRequestSize = 1 << 20
+
+var ErrUnknownOperation = errors.New("unknown operation")
+
+// Object is a GraphQL object, it maps the field names to their resolvers.
+type Object map[string]Resolver
+
+// Resolver resolves a field with its arguments. The result is a scalar, a slice of scalars,
+// an Object, a slice of Objects or nil; an Object must be queried with a selection set.
+type Resolver func(args Args) (interface{}, error)
+
+// Args are the arguments of a field, the variables are already resolved.
+type Args map[string]interface{}
+
+// String returns the string argument, it's empty if the argument is absent.
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns the int argument, the floats from the JSON variables are truncated.
+func (a Args) Int(name string) int {
+	switch v := a[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// Float returns the float argument and whether it's present.
+func (a Args) Float(name string) (float64, bool) {
+	switch v := a[name].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// Bool returns the bool argument, it's false if the argument is absent.
+func (a Args) Bool(name string) bool {
+	b, _ := a[name].(bool)
+	return b
+}
+
+// Request is a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is an error in the response, the path locates the field that failed.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response is a GraphQL response.
+type Response struct {
+	Data   *ordered `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// ordered is an object in the response, which keeps the fields in the order they're selected.
+type ordered struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *ordered) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// Get returns the value of the field, it's used to inspect the response.
+func (o *ordered) Get(key string) interface{} {
+	return o.values[key]
+}
+
+func (o *ordered) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute executes the query against the root object. A syntax error fails the whole request,
+// while a field error nulls the field and is reported along with the other fields.
+func Execute(root Object, request *Request) *Response {
+	operations, err := parse(request.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(operations, request.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	variables := make(map[string]interface{}, len(op.defaults)+len(request.Variables))
+	for name, v := range op.defaults {
+		variables[name] = v
+	}
+	for name, v := range request.Variables {
+		variables[name] = v
+	}
+
+	e := &executor{variables: variables}
+	data := e.object(root, op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(operations []*operation, name string) (*operation, error) {
+	if name == "" {
+		if len(operations) > 1 {
+			return nil, fmt.Errorf("%w: operationName is required for a document with multiple operations", ErrUnknownOperation)
+		}
+		return operations[0], nil
+	}
+	for _, op := range operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownOperation, name)
+}
+
+type executor struct {
+	variables map[string]interface{}
+	errors    []*Error
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+func (e *executor) object(object Object, selections []*field, path []interface{}) *ordered {
+	result := &ordered{values: make(map[string]interface{}, len(selections))}
+	for _, f := range selections {
+		fieldPath := append(append([]interface{}{}, path...), f.key())
+		result.set(f.key(), e.field(object, f, fieldPath))
+	}
+	return result
+}
+
+func (e *executor) field(object Object, f *field, path []interface{}) interface{} {
+	resolver, ok := object[f.name]
+	if !ok {
+		e.fail(path, "unknown field %s", f.name)
+		return nil
+	}
+
+	args := make(Args, len(f.args))
+	for name, v := range f.args {
+		if resolved := v.resolve(e.variables); resolved != nil {
+			args[name] = resolved
+		}
+	}
+	v, err := resolver(args)
+	if err != nil {
+		e.fail(path, "%s", err)
+		return nil
+	}
+	return e.complete(v, f, path)
+}
+
+// complete projects the resolved value onto the selection set of the field.
+func (e *executor) complete(v interface{}, f *field, path []interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch v := v.(type) {
+	case Object:
+		if v == nil {
+			return nil
+		}
+		if len(f.selections) == 0 {
+			e.fail(path, "field %s of object type must have a selection set", f.name)
+			return nil
+		}
+		return e.object(v, f.selections, path)
+	case []Object:
+		if len(f.selections) == 0 {
+			e.fail(path, "field %s of object type must have a selection set", f.name)
+			return nil
+		}
+		result := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			itemPath := append(append([]interface{}{}, path...), i)
+			result = append(result, e.object(item, f.selections, itemPath))
+		}
+		return result
+	default:
+		if len(f.selections) > 0 {
+			e.fail(path, "field %s of scalar type must not have a selection set", f.name)
+			return nil
+		}
+		return v
+	}
+}
+
+// Handle serves a GraphQL request, either a POST of the JSON request or a GET with the query parameters.
+func Handle(w http.ResponseWriter, r *http.Request, root Object) {
+	request := &Request{}
+	switch r.Method {
+	case http.MethodGet:
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "decode variables: " + err.Error()}}})
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "read request"}}})
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			request.Query = string(body)
+		} else if err := json.Unmarshal(body, request); err != nil {
+			writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "decode request: " + err.Error()}}})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := Execute(root, request)
+	status := http.StatusOK
+	if response.Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeResponse(w, status, response)
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
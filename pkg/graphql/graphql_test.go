@@ -0,0 +1,178 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testRoot() Object {
+	item := func(name string, size int) Object {
+		return Object{
+			"name": func(Args) (interface{}, error) { return name, nil },
+			"size": func(Args) (interface{}, error) { return size, nil },
+		}
+	}
+	return Object{
+		"items": func(args Args) (interface{}, error) {
+			items := []Object{item("a", 1), item("b", 2), item("c", 3)}
+			if limit := args.Int("limit"); limit > 0 && limit < len(items) {
+				items = items[:limit]
+			}
+			return items, nil
+		},
+		"item": func(args Args) (interface{}, error) {
+			if args.String("name") == "" {
+				return nil, nil
+			}
+			return item(args.String("name"), 0), nil
+		},
+		"fail": func(Args) (interface{}, error) {
+			return nil, errors.New("boom")
+		},
+		"echo": func(args Args) (interface{}, error) {
+			f, ok := args.Float("f")
+			return []interface{}{args.String("s"), args.Int("i"), f, ok, args.Bool("b")}, nil
+		},
+	}
+}
+
+func marshal(t *testing.T, response *Response) string {
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	testSuites := []struct {
+		name    string
+		request *Request
+		expect  string
+	}{
+		{
+			name:    "shorthand with aliases keeps the selection order",
+			request: &Request{Query: `{ top: items(limit: 2) { size name } items { name } }`},
+			expect:  `{"data":{"top":[{"size":1,"name":"a"},{"size":2,"name":"b"}],"items":[{"name":"a"},{"name":"b"},{"name":"c"}]}}`,
+		},
+		{
+			name: "variables and defaults",
+			request: &Request{
+				Query:     `query Q($limit: Int = 1, $name: String!) { items(limit: $limit) { name } item(name: $name) { name } }`,
+				Variables: map[string]interface{}{"name": "x"},
+			},
+			expect: `{"data":{"items":[{"name":"a"}],"item":{"name":"x"}}}`,
+		},
+		{
+			name: "literals",
+			request: &Request{Query: `# comment
+{ echo(s: "a\"b", i: -3, f: 1.5e1, b: true) }`},
+			expect: `{"data":{"echo":["a\"b",-3,15,true,true]}}`,
+		},
+		{
+			name:    "null object",
+			request: &Request{Query: `{ item { name } }`},
+			expect:  `{"data":{"item":null}}`,
+		},
+		{
+			name:    "field errors",
+			request: &Request{Query: `{ fail unknown items { name size { x } } }`},
+			expect:  `{"data":{"fail":null,"unknown":null,"items":[{"name":"a","size":null},{"name":"b","size":null},{"name":"c","size":null}]},"errors":[{"message":"boom","path":["fail"]},{"message":"unknown field unknown","path":["unknown"]},{"message":"field size of scalar type must not have a selection set","path":["items",0,"size"]},{"message":"field size of scalar type must not have a selection set","path":["items",1,"size"]},{"message":"field size of scalar type must not have a selection set","path":["items",2,"size"]}]}`,
+		},
+		{
+			name:    "missing selection set",
+			request: &Request{Query: `{ items }`},
+			expect:  `{"data":{"items":null},"errors":[{"message":"field items of object type must have a selection set","path":["items"]}]}`,
+		},
+		{
+			name:    "operation name",
+			request: &Request{Query: `query A { item(name: "a") { name } } query B { item(name: "b") { name } }`, OperationName: "B"},
+			expect:  `{"data":{"item":{"name":"b"}}}`,
+		},
+	}
+
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := marshal(t, Execute(testRoot(), testCase.request))
+			if actual != testCase.expect {
+				t.Errorf("expect\n%s\nbut get\n%s", testCase.expect, actual)
+			}
+		})
+	}
+}
+
+func TestExecuteRequestErrors(t *testing.T) {
+	testSuites := []struct {
+		name    string
+		request *Request
+		expect  string
+	}{
+		{name: "syntax", request: &Request{Query: `{ items(limit: ) { name } }`}, expect: "syntax error"},
+		{name: "empty", request: &Request{Query: ` `}, expect: "no operation"},
+		{name: "mutation", request: &Request{Query: `mutation { items { name } }`}, expect: "mutation operation is not supported"},
+		{name: "fragment", request: &Request{Query: `{ items { ...f } }`}, expect: "fragments are not supported"},
+		{name: "unterminated string", request: &Request{Query: `{ item(name: "a) { name } }`}, expect: "unterminated string"},
+		{name: "ambiguous operation", request: &Request{Query: `query A { fail } query B { fail }`}, expect: "operationName is required"},
+		{name: "unknown operation", request: &Request{Query: `query A { fail }`, OperationName: "C"}, expect: "unknown operation"},
+	}
+
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			response := Execute(testRoot(), testCase.request)
+			if response.Data != nil || len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, testCase.expect) {
+				t.Errorf("expect error %q, but get %s", testCase.expect, marshal(t, response))
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Handle(w, r, testRoot())
+	})
+
+	testSuites := []struct {
+		name   string
+		req    *http.Request
+		code   int
+		expect string
+	}{
+		{
+			name:   "post json",
+			req:    httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query($n: String){ item(name: $n) { name } }","variables":{"n":"z"}}`)),
+			code:   http.StatusOK,
+			expect: `{"data":{"item":{"name":"z"}}}`,
+		},
+		{
+			name:   "get",
+			req:    httptest.NewRequest(http.MethodGet, `/graphql?query=%7Bitems(limit:1)%7Bname%7D%7D`, nil),
+			code:   http.StatusOK,
+			expect: `{"data":{"items":[{"name":"a"}]}}`,
+		},
+		{
+			name:   "bad request",
+			req:    httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{`)),
+			code:   http.StatusBadRequest,
+			expect: `"errors"`,
+		},
+		{
+			name: "method not allowed",
+			req:  httptest.NewRequest(http.MethodDelete, "/graphql", nil),
+			code: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, testCase.req)
+			if w.Code != testCase.code || !strings.Contains(w.Body.String(), testCase.expect) {
+				t.Errorf("expect %d %s, but get %d %s", testCase.code, testCase.expect, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,482 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrSyntax = errors.New("syntax error")
+
+// field is a selected field in the query.
+type field struct {
+	alias      string
+	name       string
+	args       map[string]*value
+	selections []*field
+}
+
+// key returns the name of the field in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type valueKind int
+
+const (
+	literalValue valueKind = iota
+	variableValue
+	listValue
+	objectValue
+)
+
+// value is an argument value in the query, variables are resolved when the query is executed.
+type value struct {
+	kind     valueKind
+	literal  interface{}
+	variable string
+	list     []*value
+	fields   map[string]*value
+}
+
+// resolve returns the value with the variables replaced.
+func (v *value) resolve(variables map[string]interface{}) interface{} {
+	switch v.kind {
+	case variableValue:
+		return variables[v.variable]
+	case listValue:
+		result := make([]interface{}, 0, len(v.list))
+		for _, item := range v.list {
+			result = append(result, item.resolve(variables))
+		}
+		return result
+	case objectValue:
+		result := make(map[string]interface{}, len(v.fields))
+		for name, item := range v.fields {
+			result[name] = item.resolve(variables)
+		}
+		return result
+	default:
+		return v.literal
+	}
+}
+
+// operation is a query operation in the document.
+type operation struct {
+	name       string
+	defaults   map[string]interface{}
+	selections []*field
+}
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	punctToken
+	nameToken
+	intToken
+	floatToken
+	stringToken
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+}
+
+// lexer splits the query into tokens, the commas and comments are insignificant and skipped.
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == '#' {
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		if c == ',' || unicode.IsSpace(rune(c)) {
+			l.pos++
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.input) {
+		return token{kind: eofToken, text: "<EOF>"}, nil
+	}
+
+	start := l.pos
+	c := l.input[l.pos]
+	switch {
+	case strings.IndexByte("{}()[]:!$=", c) >= 0:
+		l.pos++
+		return token{kind: punctToken, text: string(c)}, nil
+	case c == '.':
+		return token{}, fmt.Errorf("%w at %d: fragments are not supported", ErrSyntax, start)
+	case c == '@':
+		return token{}, fmt.Errorf("%w at %d: directives are not supported", ErrSyntax, start)
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.input) && (l.input[l.pos] == '_' || isLetter(l.input[l.pos]) || isDigit(l.input[l.pos])) {
+			l.pos++
+		}
+		return token{kind: nameToken, text: l.input[start:l.pos]}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		return l.string()
+	default:
+		return token{}, fmt.Errorf("%w at %d: unexpected character %q", ErrSyntax, start, c)
+	}
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := intToken
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case isDigit(c):
+		case c == '.' || c == 'e' || c == 'E':
+			kind = floatToken
+		case (c == '+' || c == '-') && kind == floatToken:
+		default:
+			return token{kind: kind, text: l.input[start:l.pos]}, nil
+		}
+		l.pos++
+	}
+	return token{kind: kind, text: l.input[start:l.pos]}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.input) {
+		switch l.input[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '\n':
+			return token{}, fmt.Errorf("%w at %d: unterminated string", ErrSyntax, start)
+		case '"':
+			l.pos++
+			text := l.input[start:l.pos]
+			s, err := strconv.Unquote(text)
+			if err != nil {
+				return token{}, fmt.Errorf("%w at %d: bad string %s", ErrSyntax, start, text)
+			}
+			return token{kind: stringToken, text: text, value: s}, nil
+		}
+		l.pos++
+	}
+	return token{}, fmt.Errorf("%w at %d: unterminated string", ErrSyntax, start)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser parses the query document with one token lookahead.
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+// parse parses the document and returns its operations.
+func parse(query string) ([]*operation, error) {
+	p := &parser{lexer: &lexer{input: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var operations []*operation
+	for p.tok.kind != eofToken {
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, op)
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("%w: no operation in the document", ErrSyntax)
+	}
+	return operations, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.kind == punctToken && p.tok.text == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.is(punct) {
+		return p.unexpected("'" + punct + "'")
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != nameToken {
+		return "", p.unexpected("name")
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+func (p *parser) unexpected(expected string) error {
+	return fmt.Errorf("%w at %d: expected %s, got %s", ErrSyntax, p.lexer.pos, expected, p.tok.text)
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{defaults: make(map[string]interface{})}
+	if p.is("{") {
+		selections, err := p.selectionSet()
+		op.selections = selections
+		return op, err
+	}
+
+	kind, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if kind != "query" {
+		return nil, fmt.Errorf("%w: %s operation is not supported", ErrSyntax, kind)
+	}
+	if p.tok.kind == nameToken {
+		op.name = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("(") {
+		if err := p.variableDefinitions(op); err != nil {
+			return nil, err
+		}
+	}
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+// variableDefinitions parses the variables, the types are not checked and only the default values are kept.
+func (p *parser) variableDefinitions(op *operation) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if err := p.typeReference(); err != nil {
+			return err
+		}
+		if p.is("=") {
+			if err := p.advance(); err != nil {
+				return err
+			}
+			v, err := p.value()
+			if err != nil {
+				return err
+			}
+			op.defaults[name] = v.resolve(nil)
+		}
+	}
+	return p.advance()
+}
+
+func (p *parser) typeReference() error {
+	if p.is("[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.typeReference(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.is("!") {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) selectionSet() ([]*field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*field
+	for !p.is("}") {
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, f)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("%w at %d: empty selection set", ErrSyntax, p.lexer.pos)
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) field() (*field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name}
+	if p.is(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f.args = make(map[string]*value)
+		for !p.is(")") {
+			argName, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.args[argName], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("{") {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) value() (*value, error) {
+	tok := p.tok
+	switch {
+	case p.is("$"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return &value{kind: variableValue, variable: name}, nil
+
+	case p.is("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v := &value{kind: listValue}
+		for !p.is("]") {
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+		return v, p.advance()
+
+	case p.is("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v := &value{kind: objectValue, fields: make(map[string]*value)}
+		for !p.is("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if v.fields[name], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return v, p.advance()
+
+	case tok.kind == intToken:
+		n, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("%w at %d: bad int %s", ErrSyntax, p.lexer.pos, tok.text)
+		}
+		return &value{literal: n}, p.advance()
+
+	case tok.kind == floatToken:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w at %d: bad float %s", ErrSyntax, p.lexer.pos, tok.text)
+		}
+		return &value{literal: f}, p.advance()
+
+	case tok.kind == stringToken:
+		return &value{literal: tok.value}, p.advance()
+
+	case tok.kind == nameToken:
+		// true, false, null and the enum values, the enum values are passed as strings.
+		var literal interface{}
+		switch tok.text {
+		case "true":
+			literal = true
+		case "false":
+			literal = false
+		case "null":
+			literal = nil
+		default:
+			literal = tok.text
+		}
+		return &value{literal: literal}, p.advance()
+
+	default:
+		return nil, p.unexpected("value")
+	}
+}
@@ -0,0 +1,4 @@
+// Package history stores the coverage runs, so that the results of the past runs
+// can be queried afterwards, such as by the GraphQL API of the webhook server and the trend of a branch.
+// The runs are kept in memory, in a directory, or in the git notes of the repository.
+package history
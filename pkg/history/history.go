@@ -0,0 +1,270 @@
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var (
+	ErrRunNotFound  = errors.New("run not found")
+	ErrNoStatistics = errors.New("run has no statistics")
+)
+
+// Run is a finished coverage analysis.
+type Run struct {
+	// ID identifies the run, it's generated when the run is saved.
+	ID string
+	// Owner is the owner of the repository.
+	Owner string
+	// Repository is the name of the repository.
+	Repository string
+	// Number is the pull request number, it's zero if the run is not for a pull request.
+	Number int `json:",omitempty"`
+	// HeadSHA is the commit that the run analyzed.
+	HeadSHA string
+	// Branch is the branch that the commit is on, such as main for the builds of the main branch,
+	// it's empty if it's unknown, such as the runs of the pull requests and the builds of a detached HEAD.
+	Branch string `json:",omitempty"`
+	// CreatedAt is the time when the run finished.
+	CreatedAt time.Time
+	// Statistics is the result of the run.
+	Statistics *report.Statistics
+	// SchemaVersion is the schema version of the stored run, the runs are negotiated by report.CheckSchemaVersion,
+	// so the stores shared by different versions of gocover skip the runs they can't read.
+	SchemaVersion int `json:",omitempty"`
+	// CompatibleSchemaVersion is the oldest schema version whose readers can read the run.
+	CompatibleSchemaVersion int `json:",omitempty"`
+	// ToolVersion is the version of gocover that saves the run.
+	ToolVersion string `json:",omitempty"`
+}
+
+// Filter selects the runs, the empty fields match all the runs.
+type Filter struct {
+	Owner      string
+	Repository string
+	Number     int
+	HeadSHA    string
+	Branch     string
+	Type       report.StatisticsType
+	// Since and Until limit the time range of the runs, both are inclusive.
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of the runs returned, all runs are returned if it's zero.
+	Limit int
+}
+
+// Match reports whether the run is selected by the filter.
+func (f *Filter) Match(run *Run) bool {
+	switch {
+	case f.Owner != "" && !strings.EqualFold(f.Owner, run.Owner):
+		return false
+	case f.Repository != "" && !strings.EqualFold(f.Repository, run.Repository):
+		return false
+	case f.Number != 0 && f.Number != run.Number:
+		return false
+	case f.HeadSHA != "" && !strings.HasPrefix(run.HeadSHA, f.HeadSHA):
+		return false
+	case f.Branch != "" && f.Branch != run.Branch:
+		return false
+	case f.Type != "" && (run.Statistics == nil || run.Statistics.StatisticsType != f.Type):
+		return false
+	case !f.Since.IsZero() && run.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && run.CreatedAt.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// Store saves and queries the runs.
+type Store interface {
+	// Save saves the run, the ID and the creation time are filled if they're empty.
+	Save(run *Run) error
+	// Get returns the run of the id, ErrRunNotFound is returned if there is no such run.
+	Get(id string) (*Run, error)
+	// List returns the runs selected by the filter, the latest run comes first.
+	List(filter *Filter) ([]*Run, error)
+}
+
+// NewMemoryStore creates a store that keeps the runs in memory, the runs are lost when the process exits.
+func NewMemoryStore() Store {
+	return &memoryStore{runs: make(map[string]*Run)}
+}
+
+type memoryStore struct {
+	mu   sync.RWMutex
+	runs map[string]*Run
+}
+
+var _ Store = (*memoryStore)(nil)
+
+func (s *memoryStore) Save(run *Run) error {
+	if err := prepare(run); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = run
+	return nil
+}
+
+func (s *memoryStore) Get(id string) (*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return run, nil
+}
+
+func (s *memoryStore) List(filter *Filter) ([]*Run, error) {
+	s.mu.RLock()
+	runs := make([]*Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	s.mu.RUnlock()
+	return selectRuns(runs, filter), nil
+}
+
+// NewFileStore creates a store that saves each run as a JSON file in the directory.
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create history directory: %w", err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+type fileStore struct {
+	dir string
+}
+
+var _ Store = (*fileStore)(nil)
+
+func (s *fileStore) Save(run *Run) error {
+	if err := prepare(run); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("marshal run: %w", err)
+	}
+	err = atomicfile.WriteFile(s.path(run.ID), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("save run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+func (s *fileStore) Get(id string) (*Run, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	run, err := readRun(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return run, err
+}
+
+func (s *fileStore) List(filter *Filter) ([]*Run, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*Run, 0, len(files))
+	for _, file := range files {
+		run, err := readRun(file)
+		if errors.Is(err, report.ErrUnsupportedSchemaVersion) {
+			// the run is saved by a newer gocover, it's left to the versions that can read it.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return selectRuns(runs, filter), nil
+}
+
+func (s *fileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func readRun(file string) (*Run, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	run := &Run{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, fmt.Errorf("decode run %s: %w", file, err)
+	}
+	if err := report.CheckSchemaVersion(run.SchemaVersion, run.CompatibleSchemaVersion); err != nil {
+		return nil, fmt.Errorf("read run %s: %w", file, err)
+	}
+	return run, nil
+}
+
+// prepare fills the ID, the creation time and the versions of a new run.
+func prepare(run *Run) error {
+	if run.Statistics == nil {
+		return ErrNoStatistics
+	}
+	run.SchemaVersion, run.CompatibleSchemaVersion, run.ToolVersion = report.SchemaVersion, report.CompatibleSchemaVersion, report.ToolVersion
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now().UTC()
+	}
+	if run.ID != "" {
+		return nil
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("generate run id: %w", err)
+	}
+	// the time prefix keeps the ids in the creation order.
+	run.ID = fmt.Sprintf("%d-%s", run.CreatedAt.Unix(), hex.EncodeToString(b))
+	return nil
+}
+
+// selectRuns returns the runs selected by the filter, the latest run comes first.
+func selectRuns(runs []*Run, filter *Filter) []*Run {
+	if filter == nil {
+		filter = &Filter{}
+	}
+
+	var result []*Run
+	for _, run := range runs {
+		if filter.Match(run) {
+			result = append(result, run)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	fileStore, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "file": fileStore}
+}
+
+func TestStore(t *testing.T) {
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			runs := []*Run{
+				{Owner: "Azure", Repository: "gocover", Number: 1, HeadSHA: "aaa111", CreatedAt: now,
+					Statistics: &report.Statistics{StatisticsType: report.DiffStatisticsType, TotalCoveragePercent: 50}},
+				{Owner: "Azure", Repository: "gocover", Number: 2, HeadSHA: "bbb222", Branch: "main", CreatedAt: now.Add(time.Hour),
+					Statistics: &report.Statistics{StatisticsType: report.FullStatisticsType, TotalCoveragePercent: 60}},
+				{Owner: "Azure", Repository: "other", Number: 1, HeadSHA: "ccc333", CreatedAt: now.Add(2 * time.Hour),
+					Statistics: &report.Statistics{StatisticsType: report.DiffStatisticsType, TotalCoveragePercent: 70}},
+			}
+			for _, run := range runs {
+				if err := store.Save(run); err != nil {
+					t.Fatal(err)
+				}
+				if run.ID == "" {
+					t.Fatal("expect id to be generated")
+				}
+			}
+			if err := store.Save(&Run{}); !errors.Is(err, ErrNoStatistics) {
+				t.Errorf("expect ErrNoStatistics, but get %v", err)
+			}
+
+			run, err := store.Get(runs[1].ID)
+			if err != nil || run.HeadSHA != "bbb222" || run.Statistics.TotalCoveragePercent != 60 {
+				t.Errorf("unexpected run %+v, %v", run, err)
+			}
+			if _, err := store.Get("../missing"); !errors.Is(err, ErrRunNotFound) {
+				t.Errorf("expect ErrRunNotFound, but get %v", err)
+			}
+
+			testSuites := []struct {
+				name   string
+				filter *Filter
+				expect []string
+			}{
+				{name: "all, latest first", filter: nil, expect: []string{"ccc333", "bbb222", "aaa111"}},
+				{name: "repository", filter: &Filter{Owner: "azure", Repository: "gocover"}, expect: []string{"bbb222", "aaa111"}},
+				{name: "type", filter: &Filter{Type: report.DiffStatisticsType}, expect: []string{"ccc333", "aaa111"}},
+				{name: "number and sha prefix", filter: &Filter{Number: 1, HeadSHA: "aaa"}, expect: []string{"aaa111"}},
+				{name: "time range", filter: &Filter{Since: now.Add(time.Hour), Until: now.Add(time.Hour)}, expect: []string{"bbb222"}},
+				{name: "limit", filter: &Filter{Limit: 1}, expect: []string{"ccc333"}},
+				{name: "branch", filter: &Filter{Branch: "main"}, expect: []string{"bbb222"}},
+			}
+			for _, testCase := range testSuites {
+				t.Run(testCase.name, func(t *testing.T) {
+					runs, err := store.List(testCase.filter)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var actual []string
+					for _, run := range runs {
+						actual = append(actual, run.HeadSHA)
+					}
+					if len(actual) != len(testCase.expect) {
+						t.Fatalf("expect %v, but get %v", testCase.expect, actual)
+					}
+					for i := range actual {
+						if actual[i] != testCase.expect[i] {
+							t.Fatalf("expect %v, but get %v", testCase.expect, actual)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestFileStoreSchemaVersion(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := &Run{Owner: "Azure", Repository: "gocover", Statistics: &report.Statistics{TotalCoveragePercent: 50}}
+	if err := store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+	if run.SchemaVersion != report.SchemaVersion || run.ToolVersion != report.ToolVersion {
+		t.Errorf("expect the versions of the saved run, but get %+v", run)
+	}
+
+	// the runs of the older gocover have no versions, the runs of a newer schema are read if they're compatible.
+	runs := map[string]string{
+		"1-legacy":       `{"ID": "1-legacy", "Statistics": {}}`,
+		"2-compatible":   fmt.Sprintf(`{"ID": "2-compatible", "Statistics": {}, "SchemaVersion": %d, "CompatibleSchemaVersion": %d}`, report.SchemaVersion+1, report.SchemaVersion),
+		"3-incompatible": fmt.Sprintf(`{"ID": "3-incompatible", "Statistics": {}, "SchemaVersion": %d, "CompatibleSchemaVersion": %d}`, report.SchemaVersion+1, report.SchemaVersion+1),
+	}
+	for id, data := range runs {
+		if err := os.WriteFile(filepath.Join(dir, id+".json"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listed, err := store.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 3 {
+		t.Errorf("expect the incompatible run is skipped, but get %+v", listed)
+	}
+	if _, err := store.Get("3-incompatible"); !errors.Is(err, report.ErrUnsupportedSchemaVersion) {
+		t.Errorf("expect %s, but get %v", report.ErrUnsupportedSchemaVersion, err)
+	}
+}
@@ -0,0 +1,155 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+var ErrNoHeadSHA = errors.New("run has no head sha")
+
+// NewGitNotesStore creates a store that saves the runs as the git notes of the commits they analyzed,
+// so the history travels with the repository rather than an external database.
+// The note of a commit is the JSON array of the runs of the commit, and the runs are kept as summaries
+// that drop the source lines and the counted lines of the profiles to keep the notes small.
+// The runs without the owner and the repository, such as the runs saved by the cli, belong to the repository of the store.
+func NewGitNotesStore(notes *gittool.Notes, owner, repository string) Store {
+	return &gitNotesStore{notes: notes, owner: owner, repository: repository}
+}
+
+type gitNotesStore struct {
+	mu         sync.Mutex
+	notes      *gittool.Notes
+	owner      string
+	repository string
+}
+
+var _ Store = (*gitNotesStore)(nil)
+
+// Save appends the run to the note of the head commit, the head sha can be a revision such as HEAD,
+// which is resolved to the commit sha.
+func (s *gitNotesStore) Save(run *Run) error {
+	if run.HeadSHA == "" {
+		return ErrNoHeadSHA
+	}
+	if err := prepare(run); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	sha, err := s.notes.Resolve(ctx, run.HeadSHA)
+	if err != nil {
+		return err
+	}
+	run.HeadSHA = sha
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the runs in the note are kept as they are, so the fields of the runs saved by a newer gocover are not dropped.
+	var runs []json.RawMessage
+	data, err := s.notes.Get(ctx, sha)
+	switch {
+	case errors.Is(err, gittool.ErrNoteNotFound):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &runs); err != nil {
+			return fmt.Errorf("decode note of %s: %w", sha, err)
+		}
+	}
+	summary, err := json.Marshal(summarize(run))
+	if err != nil {
+		return fmt.Errorf("marshal run: %w", err)
+	}
+	data, err = json.MarshalIndent(append(runs, summary), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal runs: %w", err)
+	}
+	if err := s.notes.Set(ctx, sha, data); err != nil {
+		return fmt.Errorf("save run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+func (s *gitNotesStore) Get(id string) (*Run, error) {
+	runs, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+}
+
+func (s *gitNotesStore) List(filter *Filter) ([]*Run, error) {
+	runs, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	return selectRuns(runs, filter), nil
+}
+
+// all returns the runs in the notes of all the commits.
+func (s *gitNotesStore) all() ([]*Run, error) {
+	ctx := context.Background()
+	commits, err := s.notes.Commits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Run
+	for _, commit := range commits {
+		r, err := s.read(ctx, commit)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r...)
+	}
+	return runs, nil
+}
+
+// read returns the runs in the note of the commit.
+func (s *gitNotesStore) read(ctx context.Context, commit string) ([]*Run, error) {
+	data, err := s.notes.Get(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+	var all []*Run
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("decode note of %s: %w", commit, err)
+	}
+	runs := make([]*Run, 0, len(all))
+	for _, run := range all {
+		// the runs saved by a newer gocover that can't be read are skipped.
+		if report.CheckSchemaVersion(run.SchemaVersion, run.CompatibleSchemaVersion) != nil {
+			continue
+		}
+		if run.Owner == "" && run.Repository == "" {
+			run.Owner, run.Repository = s.owner, s.repository
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// summarize returns a copy of the run without the source lines, the counted lines and the functions of the profiles.
+func summarize(run *Run) *Run {
+	summary := *run
+	statistics := *run.Statistics
+	statistics.CoverageProfile = make([]*report.CoverageProfile, 0, len(run.Statistics.CoverageProfile))
+	for _, p := range run.Statistics.CoverageProfile {
+		profile := *p
+		profile.ViolationSections = nil
+		profile.CountedLines = nil
+		profile.Functions = nil
+		statistics.CoverageProfile = append(statistics.CoverageProfile, &profile)
+	}
+	summary.Statistics = &statistics
+	return &summary
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// gitRepository creates a repository with the commits, it returns the path and the commit shas.
+func gitRepository(t *testing.T, commits int) (string, []string) {
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=foo", "GIT_AUTHOR_EMAIL=foo@bar.org", "GIT_COMMITTER_NAME=foo", "GIT_COMMITTER_EMAIL=foo@bar.org")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	var shas []string
+	for i := 0; i < commits; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "file"), []byte{byte('a' + i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "file")
+		git("commit", "-q", "-m", "commit")
+		shas = append(shas, git("rev-parse", "HEAD")[:40])
+	}
+	return dir, shas
+}
+
+func TestGitNotesStore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, shas := gitRepository(t, 2)
+	store := NewGitNotesStore(gittool.NewNotes(dir, ""), "Azure", "gocover")
+
+	if runs, err := store.List(nil); err != nil || len(runs) != 0 {
+		t.Fatalf("expect no runs before any note, but get %v, %v", runs, err)
+	}
+
+	full := &Run{HeadSHA: shas[0], Statistics: &report.Statistics{StatisticsType: report.FullStatisticsType, TotalCoveragePercent: 60,
+		CoverageProfile: []*report.CoverageProfile{{
+			FileName:            "github.com/Azure/gocover/foo.go",
+			TotalViolationLines: []int{3},
+			ViolationSections:   []*report.ViolationSection{{StartLine: 1, EndLine: 3, Contents: []string{"a", "b", "c"}}},
+			CountedLines:        []*report.CountedLine{{Line: 2, Covered: true}, {Line: 3}},
+		}}}}
+	if err := store.Save(full); err != nil {
+		t.Fatal(err)
+	}
+	diff := &Run{HeadSHA: "HEAD", Number: 3, Statistics: &report.Statistics{StatisticsType: report.DiffStatisticsType, TotalCoveragePercent: 70}}
+	if err := store.Save(diff); err != nil {
+		t.Fatal(err)
+	}
+	other := &Run{Owner: "Azure", Repository: "other", HeadSHA: "HEAD", Statistics: &report.Statistics{StatisticsType: report.FullStatisticsType}}
+	if err := store.Save(other); err != nil {
+		t.Fatal(err)
+	}
+	if diff.HeadSHA != shas[1] {
+		t.Errorf("expect HEAD is resolved to %s, but get %s", shas[1], diff.HeadSHA)
+	}
+	if err := store.Save(&Run{Statistics: &report.Statistics{}}); !errors.Is(err, ErrNoHeadSHA) {
+		t.Errorf("expect ErrNoHeadSHA, but get %v", err)
+	}
+
+	runs, err := store.List(&Filter{Owner: "azure", Repository: "gocover"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != diff.ID || runs[1].ID != full.ID {
+		t.Fatalf("expect the runs of the repository, but get %+v", runs)
+	}
+	profile := runs[1].Statistics.CoverageProfile[0]
+	if len(profile.ViolationSections) != 0 || len(profile.CountedLines) != 0 || len(profile.TotalViolationLines) != 1 {
+		t.Errorf("expect the run is saved as a summary, but get %+v", profile)
+	}
+	if len(full.Statistics.CoverageProfile[0].CountedLines) != 2 {
+		t.Error("expect the saved run is not changed")
+	}
+
+	run, err := store.Get(other.ID)
+	if err != nil || run.Repository != "other" {
+		t.Errorf("unexpected run %+v, %v", run, err)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expect ErrRunNotFound, but get %v", err)
+	}
+}
+
+func TestGitNotesStoreSchemaVersion(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, shas := gitRepository(t, 1)
+	notes := gittool.NewNotes(dir, "")
+	store := NewGitNotesStore(notes, "Azure", "gocover")
+
+	// the note has a run of a newer compatible schema and a run of an incompatible schema, which are saved by newer gocover.
+	newer := fmt.Sprintf(`[
+		{"ID": "1-compatible", "Statistics": {}, "SchemaVersion": %d, "CompatibleSchemaVersion": %d, "Added": "kept"},
+		{"ID": "2-incompatible", "Statistics": {}, "SchemaVersion": %d, "CompatibleSchemaVersion": %d}
+	]`, report.SchemaVersion+1, report.SchemaVersion, report.SchemaVersion+1, report.SchemaVersion+1)
+	if err := notes.Set(context.Background(), shas[0], []byte(newer)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&Run{HeadSHA: shas[0], Statistics: &report.Statistics{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := store.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[1].ID != "1-compatible" {
+		t.Errorf("expect the incompatible run is skipped, but get %+v", runs)
+	}
+	data, err := notes.Get(context.Background(), shas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Added": "kept"`) || !strings.Contains(string(data), "2-incompatible") {
+		t.Errorf("expect the runs of newer gocover are kept as they are, but get %s", data)
+	}
+}
@@ -0,0 +1,28 @@
+package history
+
+import "github.com/Azure/gocover/pkg/report"
+
+// NewReportGenerator creates a report generator that saves the statistics of the run into the store,
+// the run is a template that the owner, the repository, the head sha and the branch are copied from.
+func NewReportGenerator(store Store, run *Run) report.ReportGenerator {
+	return &reportGenerator{store: store, run: run}
+}
+
+type reportGenerator struct {
+	store Store
+	run   *Run
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport saves the statistics as a new run.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	return g.store.Save(&Run{
+		Owner:      g.run.Owner,
+		Repository: g.run.Repository,
+		Number:     g.run.Number,
+		HeadSHA:    g.run.HeadSHA,
+		Branch:     g.run.Branch,
+		Statistics: statistics,
+	})
+}
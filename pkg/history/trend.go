@@ -0,0 +1,150 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point is the coverage of a run in a trend.
+type Point struct {
+	RunID     string
+	HeadSHA   string
+	Branch    string
+	Number    int
+	CreatedAt time.Time
+	// CoveragePercent is the total coverage of the run.
+	CoveragePercent float64
+	// ViolationLines is the number of the uncovered lines of the run.
+	ViolationLines int
+	// Delta is the coverage change from the previous point, it's zero for the first point.
+	Delta float64
+}
+
+// Trend returns the coverage of the runs selected by the filter, the earliest comes first.
+// The limit of the filter keeps the latest runs, so the trend ends at the latest run.
+func Trend(store Store, filter *Filter) ([]*Point, error) {
+	runs, err := store.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// the runs are listed by the latest first, while a trend goes forward in time.
+	points := make([]*Point, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		p := &Point{
+			RunID:           run.ID,
+			HeadSHA:         run.HeadSHA,
+			Branch:          run.Branch,
+			Number:          run.Number,
+			CreatedAt:       run.CreatedAt,
+			CoveragePercent: run.Statistics.TotalCoveragePercent,
+			ViolationLines:  run.Statistics.TotalViolationLines,
+		}
+		if len(points) != 0 {
+			p.Delta = p.CoveragePercent - points[len(points)-1].CoveragePercent
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// Change returns the coverage change from the first point to the last, it's zero if there are less than two points.
+func Change(points []*Point) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	return points[len(points)-1].CoveragePercent - points[0].CoveragePercent
+}
+
+// The formats of the trend.
+const (
+	TextFormat = "text"
+	CSVFormat  = "csv"
+	JSONFormat = "json"
+)
+
+var ErrUnknownTrendFormat = errors.New("unknown trend format")
+
+// plotWidth is the width of the bar of the highest coverage in the text trend.
+const plotWidth = 40
+
+// WriteTrend writes the points in the format, the text format plots the coverage of each point as a bar.
+func WriteTrend(w io.Writer, points []*Point, format string) error {
+	switch strings.ToLower(format) {
+	case TextFormat:
+		_, err := io.WriteString(w, formatTrend(points))
+		return err
+	case CSVFormat:
+		return writeTrendCSV(w, points)
+	case JSONFormat:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(points)
+	default:
+		return fmt.Errorf("%w '%s', it should be one of: %s, %s, %s", ErrUnknownTrendFormat, format, TextFormat, CSVFormat, JSONFormat)
+	}
+}
+
+// formatTrend renders a line of each point with a bar of its coverage, the bars are scaled from the lowest coverage
+// to the highest, so the small changes stand out, and a summary of the change of the trend.
+func formatTrend(points []*Point) string {
+	if len(points) == 0 {
+		return "No runs in the trend.\n"
+	}
+	low, high := points[0].CoveragePercent, points[0].CoveragePercent
+	for _, p := range points {
+		low, high = math.Min(low, p.CoveragePercent), math.Max(high, p.CoveragePercent)
+	}
+
+	var b strings.Builder
+	for _, p := range points {
+		width := plotWidth
+		if high > low {
+			width = 1 + int(math.Round((p.CoveragePercent-low)/(high-low)*(plotWidth-1)))
+		}
+		fmt.Fprintf(&b, "%s  %-7s  %6.2f%%  %+6.2f  %s\n", p.CreatedAt.UTC().Format("2006-01-02 15:04"), shortSHA(p.HeadSHA),
+			p.CoveragePercent, p.Delta, strings.Repeat("#", width))
+	}
+	fmt.Fprintf(&b, "\n%d runs, coverage %.2f%% -> %.2f%% (%+.2f), lowest %.2f%%, highest %.2f%%\n", len(points),
+		points[0].CoveragePercent, points[len(points)-1].CoveragePercent, Change(points), low, high)
+	return b.String()
+}
+
+func writeTrendCSV(w io.Writer, points []*Point) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "commit", "branch", "number", "coverage", "delta", "violation_lines"}); err != nil {
+		return err
+	}
+	for _, p := range points {
+		record := []string{
+			p.CreatedAt.UTC().Format(time.RFC3339),
+			p.HeadSHA,
+			p.Branch,
+			strconv.Itoa(p.Number),
+			strconv.FormatFloat(p.CoveragePercent, 'f', 2, 64),
+			strconv.FormatFloat(p.Delta, 'f', 2, 64),
+			strconv.Itoa(p.ViolationLines),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// shortSHA returns the first 7 characters of the sha as git log --oneline.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestTrend(t *testing.T) {
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	for i, percent := range []float64{60, 65, 62.5, 70} {
+		branch := "main"
+		if i == 2 {
+			branch = "release"
+		}
+		run := &Run{HeadSHA: string(rune('a' + i)), Branch: branch, CreatedAt: now.Add(time.Duration(i) * time.Hour),
+			Statistics: &report.Statistics{StatisticsType: report.FullStatisticsType, TotalCoveragePercent: percent, TotalViolationLines: 10 - i}}
+		if err := store.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	points, err := Trend(store, &Filter{Branch: "main", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].HeadSHA != "b" || points[1].HeadSHA != "d" {
+		t.Fatalf("expect the latest 2 runs of main, the earliest first, but get %+v", points)
+	}
+	if points[0].Delta != 0 || points[1].Delta != 5 || points[1].ViolationLines != 7 || points[1].Branch != "main" {
+		t.Errorf("unexpected points %+v, %+v", points[0], points[1])
+	}
+	if change := Change(points); change != 5 {
+		t.Errorf("expect change 5, but get %.2f", change)
+	}
+	if change := Change(points[:1]); change != 0 {
+		t.Errorf("expect no change of a point, but get %.2f", change)
+	}
+}
+
+func TestWriteTrend(t *testing.T) {
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	points := []*Point{
+		{HeadSHA: "aaaaaaaaaa", Branch: "main", CreatedAt: now, CoveragePercent: 60, ViolationLines: 10},
+		{HeadSHA: "bbbbbbbbbb", Branch: "main", CreatedAt: now.Add(time.Hour), CoveragePercent: 70, ViolationLines: 5, Delta: 10},
+	}
+
+	var text strings.Builder
+	if err := WriteTrend(&text, points, TextFormat); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"2022-10-01 00:00  aaaaaaa   60.00%   +0.00  #\n",
+		"2022-10-01 01:00  bbbbbbb   70.00%  +10.00  " + strings.Repeat("#", plotWidth) + "\n",
+		"2 runs, coverage 60.00% -> 70.00% (+10.00), lowest 60.00%, highest 70.00%",
+	} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("expect %q in trend %s", expected, text.String())
+		}
+	}
+
+	var csv strings.Builder
+	if err := WriteTrend(&csv, points, CSVFormat); err != nil {
+		t.Fatal(err)
+	}
+	expected := "time,commit,branch,number,coverage,delta,violation_lines\n2022-10-01T00:00:00Z,aaaaaaaaaa,main,0,60.00,0.00,10\n2022-10-01T01:00:00Z,bbbbbbbbbb,main,0,70.00,10.00,5\n"
+	if csv.String() != expected {
+		t.Errorf("expect %q, but get %q", expected, csv.String())
+	}
+
+	var empty strings.Builder
+	if err := WriteTrend(&empty, nil, TextFormat); err != nil || empty.String() != "No runs in the trend.\n" {
+		t.Errorf("unexpected empty trend %q, %v", empty.String(), err)
+	}
+	if err := WriteTrend(&empty, points, "svg"); !errors.Is(err, ErrUnknownTrendFormat) {
+		t.Errorf("expect ErrUnknownTrendFormat, but get %v", err)
+	}
+}
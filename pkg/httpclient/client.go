@@ -0,0 +1,114 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default timeout for a single http request.
+	DefaultTimeout = 60 * time.Second
+)
+
+var (
+	ErrClientCertPair = errors.New("client certificate and client key should be provided together")
+	ErrInvalidCA      = errors.New("no valid certificate found in CA bundle")
+)
+
+// Option contains the input for building the http client.
+type Option struct {
+	// Proxy is the proxy url, when it's empty, HTTPS_PROXY, HTTP_PROXY and NO_PROXY environments are used.
+	Proxy string
+	// CABundle is the PEM file contains the extra CA certificates to trust, besides the system ones.
+	CABundle string
+	// ClientCert is the PEM file contains the client certificate for mTLS.
+	ClientCert string
+	// ClientKey is the PEM file contains the private key of the client certificate.
+	ClientKey string
+	// Timeout is the timeout of a single http request.
+	Timeout time.Duration
+}
+
+func (o *Option) Validate() error {
+	if (o.ClientCert == "") != (o.ClientKey == "") {
+		return ErrClientCertPair
+	}
+	if o.Proxy != "" {
+		if _, err := url.Parse(o.Proxy); err != nil {
+			return fmt.Errorf("parse proxy: %w", err)
+		}
+	}
+	return nil
+}
+
+// New creates the http client according to the option.
+func New(o *Option) (*http.Client, error) {
+	if o == nil {
+		o = &Option{}
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if o.Proxy != "" {
+		proxyURL, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
+
+// tlsConfig builds the tls configuration with custom CA bundle and client certificate.
+func (o *Option) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		data, err := os.ReadFile(o.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCA, o.CABundle)
+		}
+		config.RootCAs = pool
+	}
+
+	if o.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
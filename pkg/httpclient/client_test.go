@@ -0,0 +1,93 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptionValidate(t *testing.T) {
+	t.Run("client certificate without key", func(t *testing.T) {
+		o := &Option{ClientCert: "cert.pem"}
+		if err := o.Validate(); !errors.Is(err, ErrClientCertPair) {
+			t.Errorf("expect %s, but get %v", ErrClientCertPair, err)
+		}
+	})
+
+	t.Run("valid option", func(t *testing.T) {
+		o := &Option{ClientCert: "cert.pem", ClientKey: "key.pem", Proxy: "http://proxy:8080"}
+		if err := o.Validate(); err != nil {
+			t.Errorf("should not error, but get %s", err)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("default client", func(t *testing.T) {
+		client, err := New(nil)
+		if err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		if client.Timeout != DefaultTimeout {
+			t.Errorf("expect timeout %s, but get %s", DefaultTimeout, client.Timeout)
+		}
+	})
+
+	t.Run("explicit proxy", func(t *testing.T) {
+		client, err := New(&Option{Proxy: "http://proxy:8080"})
+		if err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		proxy, err := client.Transport.(*http.Transport).Proxy(req)
+		if err != nil {
+			t.Fatalf("proxy: %s", err)
+		}
+		if proxy.String() != "http://proxy:8080" {
+			t.Errorf("expect proxy http://proxy:8080, but get %s", proxy)
+		}
+	})
+
+	t.Run("custom CA bundle", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		if err := os.WriteFile(caFile, data, 0644); err != nil {
+			t.Fatalf("write CA bundle: %s", err)
+		}
+
+		client, err := New(&Option{CABundle: caFile})
+		if err != nil {
+			t.Fatalf("should not error, but get %s", err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request with custom CA: %s", err)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		if err := os.WriteFile(caFile, []byte("invalid"), 0644); err != nil {
+			t.Fatalf("write CA bundle: %s", err)
+		}
+		if _, err := New(&Option{CABundle: caFile}); !errors.Is(err, ErrInvalidCA) {
+			t.Errorf("expect %s, but get %v", ErrInvalidCA, err)
+		}
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		if _, err := New(&Option{ClientCert: "nonexist.pem", ClientKey: "nonexist.key"}); err == nil {
+			t.Error("should return error, but get nil")
+		}
+	})
+}
@@ -0,0 +1,3 @@
+// Package httpclient builds the http client shared by all the http integrations of gocover,
+// it supports proxies from HTTPS_PROXY/NO_PROXY environments, custom CA bundles and mTLS client certificates.
+package httpclient
@@ -0,0 +1,202 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/httpclient"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// serviceAccountDir is where the service account of the pod is mounted.
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// JobNameLabel is the label that Kubernetes adds to the pods of a Job.
+	JobNameLabel = "job-name"
+)
+
+var (
+	ErrNotInCluster       = errors.New("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	ErrUnexpectedResponse = errors.New("unexpected response")
+	ErrNoPod              = errors.New("no pod found")
+)
+
+// Config contains the input for the client.
+type Config struct {
+	// Host is the url of the API server, such as https://10.0.0.1:443.
+	Host string
+	// Token is the bearer token of the requests.
+	Token credential.Provider
+	// CAFile is the PEM file of the CA that signs the API server certificate.
+	CAFile string
+	// Namespace is the namespace of the pod, it's empty if it's unknown.
+	Namespace string
+}
+
+// InClusterConfig returns the config of the service account of the pod.
+// The token is read on every request, because the projected service account token is rotated.
+func InClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+	token, err := credential.NewProvider("file:"+filepath.Join(serviceAccountDir, "token"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		Host:   "https://" + net.JoinHostPort(host, port),
+		Token:  token,
+		CAFile: filepath.Join(serviceAccountDir, "ca.crt"),
+	}
+	if namespace, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		config.Namespace = strings.TrimSpace(string(namespace))
+	}
+	return config, nil
+}
+
+// Client is the client of the Kubernetes API.
+type Client struct {
+	host   string
+	token  credential.Provider
+	client *http.Client
+}
+
+// NewClient creates the client, the CA file is trusted besides the system CAs if it's set.
+func NewClient(c *Config) (*Client, error) {
+	httpClient, err := httpclient.New(&httpclient.Option{CABundle: c.CAFile})
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes http client: %w", err)
+	}
+	return &Client{host: strings.TrimSuffix(c.Host, "/"), token: c.Token, client: httpClient}, nil
+}
+
+// CreateJob creates the Job in the namespace.
+func (c *Client) CreateJob(ctx context.Context, namespace string, job *Job) (*Job, error) {
+	job.APIVersion, job.Kind = "batch/v1", "Job"
+	result := &Job{}
+	if err := c.do(ctx, http.MethodPost, jobsPath(namespace), job, result); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	return result, nil
+}
+
+// GetJob returns the Job of the name.
+func (c *Client) GetJob(ctx context.Context, namespace, name string) (*Job, error) {
+	result := &Job{}
+	if err := c.do(ctx, http.MethodGet, jobsPath(namespace)+"/"+url.PathEscape(name), nil, result); err != nil {
+		return nil, fmt.Errorf("get job %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// DeleteJob deletes the Job and its pods.
+func (c *Client) DeleteJob(ctx context.Context, namespace, name string) error {
+	body := map[string]string{"kind": "DeleteOptions", "apiVersion": "v1", "propagationPolicy": "Background"}
+	if err := c.do(ctx, http.MethodDelete, jobsPath(namespace)+"/"+url.PathEscape(name), body, nil); err != nil {
+		return fmt.Errorf("delete job %s: %w", name, err)
+	}
+	return nil
+}
+
+// JobLogs returns the logs of the latest pod of the Job.
+func (c *Client) JobLogs(ctx context.Context, namespace, name string) (string, error) {
+	pods := &PodList{}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods?labelSelector=%s", url.PathEscape(namespace), url.QueryEscape(JobNameLabel+"="+name))
+	if err := c.do(ctx, http.MethodGet, path, nil, pods); err != nil {
+		return "", fmt.Errorf("list pods of job %s: %w", name, err)
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("%w for job %s", ErrNoPod, name)
+	}
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		a, b := pods.Items[i].Metadata.CreationTimestamp, pods.Items[j].Metadata.CreationTimestamp
+		return a != nil && b != nil && a.After(*b)
+	})
+
+	pod := pods.Items[0].Metadata.Name
+	path = fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log", url.PathEscape(namespace), url.PathEscape(pod))
+	logs, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", fmt.Errorf("get logs of pod %s: %w", pod, err)
+	}
+	return string(logs), nil
+}
+
+func jobsPath(namespace string) string {
+	return fmt.Sprintf("/apis/batch/v1/namespaces/%s/jobs", url.PathEscape(namespace))
+}
+
+func (c *Client) do(ctx context.Context, method, path string, request interface{}, result interface{}) error {
+	data, err := c.request(ctx, method, path, request)
+	if err != nil {
+		return err
+	}
+	if result != nil && len(data) != 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) request(ctx context.Context, method, path string, request interface{}) ([]byte, error) {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// the API server describes the failure in the message of a Status.
+		status := &struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(data, status) != nil || status.Message == "" {
+			status.Message = string(data)
+		}
+		return nil, fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, method, path, resp.StatusCode, redact.String(status.Message))
+	}
+	return data, nil
+}
@@ -0,0 +1,137 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type staticToken string
+
+func (s staticToken) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient(&Config{Host: server.URL + "/", Token: staticToken("token")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCreateJob(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apis/batch/v1/namespaces/ci/jobs" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("unexpected authorization %q", auth)
+		}
+		job := &Job{}
+		if err := json.NewDecoder(r.Body).Decode(job); err != nil {
+			t.Fatal(err)
+		}
+		if job.APIVersion != "batch/v1" || job.Kind != "Job" {
+			t.Errorf("unexpected type %s %s", job.APIVersion, job.Kind)
+		}
+		job.Metadata.Name = job.Metadata.GenerateName + "abcde"
+		json.NewEncoder(w).Encode(job)
+	})
+
+	job, err := client.CreateJob(context.Background(), "ci", &Job{Metadata: ObjectMeta{GenerateName: "gocover-"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Metadata.Name != "gocover-abcde" {
+		t.Errorf("expect job gocover-abcde, but get %s", job.Metadata.Name)
+	}
+}
+
+func TestUnexpectedResponse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"kind":"Status","message":"jobs.batch is forbidden"}`))
+	})
+
+	_, err := client.GetJob(context.Background(), "ci", "gocover-abcde")
+	if !errors.Is(err, ErrUnexpectedResponse) {
+		t.Fatalf("expect ErrUnexpectedResponse, but get %v", err)
+	}
+	if want := "get job gocover-abcde: unexpected response: GET /apis/batch/v1/namespaces/ci/jobs/gocover-abcde returns 403: jobs.batch is forbidden"; err.Error() != want {
+		t.Errorf("expect %q, but get %q", want, err.Error())
+	}
+}
+
+func TestJobLogs(t *testing.T) {
+	older, newer := time.Unix(100, 0), time.Unix(200, 0)
+
+	t.Run("latest pod", func(t *testing.T) {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/namespaces/ci/pods":
+				if selector := r.URL.Query().Get("labelSelector"); selector != "job-name=gocover-abcde" {
+					t.Errorf("unexpected selector %q", selector)
+				}
+				json.NewEncoder(w).Encode(&PodList{Items: []Pod{
+					{Metadata: ObjectMeta{Name: "first", CreationTimestamp: &older}},
+					{Metadata: ObjectMeta{Name: "second", CreationTimestamp: &newer}},
+				}})
+			case "/api/v1/namespaces/ci/pods/second/log":
+				w.Write([]byte("logs of second"))
+			default:
+				t.Errorf("unexpected path %s", r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		logs, err := client.JobLogs(context.Background(), "ci", "gocover-abcde")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if logs != "logs of second" {
+			t.Errorf("unexpected logs %q", logs)
+		}
+	})
+
+	t.Run("no pod", func(t *testing.T) {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"items":[]}`))
+		})
+
+		if _, err := client.JobLogs(context.Background(), "ci", "gocover-abcde"); !errors.Is(err, ErrNoPod) {
+			t.Errorf("expect ErrNoPod, but get %v", err)
+		}
+	})
+}
+
+func TestDeleteJob(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/apis/batch/v1/namespaces/ci/jobs/gocover-abcde" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		options := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&options)
+		if options["propagationPolicy"] != "Background" {
+			t.Errorf("expect the pods are deleted, but get %v", options)
+		}
+		w.Write([]byte(`{"kind":"Status","status":"Success"}`))
+	})
+
+	if err := client.DeleteJob(context.Background(), "ci", "gocover-abcde"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInClusterConfig(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := InClusterConfig(); !errors.Is(err, ErrNotInCluster) {
+		t.Errorf("expect ErrNotInCluster, but get %v", err)
+	}
+}
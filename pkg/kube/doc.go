@@ -0,0 +1,3 @@
+// Package kube is a minimal client of the Kubernetes REST API that runs the gocover analyses as Jobs,
+// it authenticates by the service account of the pod, so the webhook service needs no kubeconfig.
+package kube
@@ -0,0 +1,113 @@
+package mergequeue
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// PullRequestCoverage is the coverage of the lines that a pull request of the batch changed.
+type PullRequestCoverage struct {
+	// Number is the number of the pull request.
+	Number int
+	// EffectiveLines are the counted lines that are not ignored.
+	EffectiveLines int
+	// CoveredLines are the effective lines reached by tests.
+	CoveredLines int
+	// CoveragePercent is the coverage of the effective lines, it's 100 if there is no effective line.
+	CoveragePercent float64
+	// Files are the coverage of the files that the pull request changed, in the order of the statistics.
+	Files []*FileCoverage
+}
+
+// FileCoverage is the coverage of the lines of a file that a pull request changed.
+type FileCoverage struct {
+	FileName       string
+	EffectiveLines int
+	CoveredLines   int
+	// UncoveredLines are the effective lines not reached by tests, in order.
+	UncoveredLines []int
+}
+
+// Attribution is the coverage of the batch split by the pull requests.
+type Attribution struct {
+	// PullRequests are the coverage of the pull requests in the merged order, the pull requests that changed
+	// no counted line are included with no lines.
+	PullRequests []*PullRequestCoverage
+	// UnattributedLines are the effective lines of the commits of the batch without a pull request, such as the rebased commits.
+	UnattributedLines int
+}
+
+// Attribute splits the counted lines of the statistics by the pull requests of the batch that last changed them,
+// which are found by the blamer. The lines last changed before the batch don't count for any pull request,
+// and a line of a commit that belongs to several pull requests counts for each of them.
+func Attribute(statistics *report.Statistics, batch *Batch, blamer report.Blamer) (*Attribution, error) {
+	coverages := make(map[int]*PullRequestCoverage, len(batch.PullRequests))
+	attribution := &Attribution{}
+	for _, number := range batch.PullRequests {
+		coverages[number] = &PullRequestCoverage{Number: number}
+		attribution.PullRequests = append(attribution.PullRequests, coverages[number])
+	}
+
+	for _, profile := range statistics.CoverageProfile {
+		if len(profile.CountedLines) == 0 {
+			continue
+		}
+		authors, err := blamer.Blame(profile.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("blame %s: %w", profile.FileName, err)
+		}
+
+		files := make(map[int]*FileCoverage)
+		for _, line := range profile.CountedLines {
+			if line.Ignored {
+				continue
+			}
+			author, ok := authors[line.Line]
+			if !ok {
+				continue
+			}
+			numbers, ok := batch.PullRequestsOf(author.Commit)
+			if !ok {
+				continue
+			}
+			if len(numbers) == 0 {
+				attribution.UnattributedLines++
+			}
+			for _, number := range numbers {
+				file, ok := files[number]
+				if !ok {
+					file = &FileCoverage{FileName: profile.FileName}
+					files[number] = file
+					coverages[number].Files = append(coverages[number].Files, file)
+				}
+				file.EffectiveLines++
+				if line.Covered {
+					file.CoveredLines++
+				} else {
+					file.UncoveredLines = append(file.UncoveredLines, line.Line)
+				}
+			}
+		}
+	}
+
+	for _, c := range attribution.PullRequests {
+		for _, file := range c.Files {
+			sort.Ints(file.UncoveredLines)
+			c.EffectiveLines += file.EffectiveLines
+			c.CoveredLines += file.CoveredLines
+		}
+		c.CoveragePercent = percent(c.CoveredLines, c.EffectiveLines)
+	}
+	return attribution, nil
+}
+
+// percent returns the percentage of covered in total rounded to 2 decimals, it's 100 if total is zero.
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(float64(covered)/float64(total)*10000) / 100
+}
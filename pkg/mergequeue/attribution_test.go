@@ -0,0 +1,122 @@
+package mergequeue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+type mockBlamer map[string]map[int]*report.LineAuthor
+
+func (b mockBlamer) Blame(fileName string) (map[int]*report.LineAuthor, error) {
+	return b[fileName], nil
+}
+
+func testBatch() *Batch {
+	return &Batch{
+		PullRequests: []int{1, 2, 3},
+		commits:      map[string][]int{"a": {1}, "b": {2}, "rebased": nil},
+	}
+}
+
+func testStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		ComparedBranch:       "origin/main",
+		TotalEffectiveLines:  5,
+		TotalCoveredLines:    2,
+		TotalCoveragePercent: 40,
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName: "github.com/Azure/modulea/foo.go",
+				CountedLines: []*report.CountedLine{
+					{Line: 3, Covered: true},
+					{Line: 4},
+					{Line: 5},
+					{Line: 6, Ignored: true},
+				},
+			},
+			{
+				FileName: "github.com/Azure/modulea/bar.go",
+				CountedLines: []*report.CountedLine{
+					{Line: 1, Covered: true},
+					{Line: 2},
+					{Line: 8},
+				},
+			},
+		},
+	}
+}
+
+func testBlamer() mockBlamer {
+	return mockBlamer{
+		"github.com/Azure/modulea/foo.go": {
+			3: {Commit: "a"}, 4: {Commit: "a"}, 5: {Commit: "b"}, 6: {Commit: "a"},
+		},
+		"github.com/Azure/modulea/bar.go": {
+			1: {Commit: "b"}, 2: {Commit: "rebased"}, 8: {Commit: "old"},
+		},
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	attribution, err := Attribute(testStatistics(), testBatch(), testBlamer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attribution.PullRequests) != 3 || attribution.UnattributedLines != 1 {
+		t.Fatalf("unexpected attribution %+v", attribution)
+	}
+
+	first := attribution.PullRequests[0]
+	if first.Number != 1 || first.EffectiveLines != 2 || first.CoveredLines != 1 || first.CoveragePercent != 50 || len(first.Files) != 1 {
+		t.Errorf("unexpected coverage of #1 %+v", first)
+	}
+	if !reflect.DeepEqual(first.Files[0].UncoveredLines, []int{4}) {
+		t.Errorf("expect the uncovered line 4 of #1, but get %v", first.Files[0].UncoveredLines)
+	}
+
+	second := attribution.PullRequests[1]
+	if second.EffectiveLines != 2 || second.CoveredLines != 1 || len(second.Files) != 2 ||
+		second.Files[0].FileName != "github.com/Azure/modulea/foo.go" || second.Files[1].FileName != "github.com/Azure/modulea/bar.go" {
+		t.Errorf("unexpected coverage of #2 %+v", second)
+	}
+
+	if third := attribution.PullRequests[2]; third.EffectiveLines != 0 || third.CoveragePercent != 100 || len(third.Files) != 0 {
+		t.Errorf("expect #3 changed no lines, but get %+v", third)
+	}
+}
+
+func TestFormatComment(t *testing.T) {
+	statistics := testStatistics()
+	attribution, err := Attribute(statistics, testBatch(), testBlamer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := &CommentOption{HeadSHA: "0123456789abcdef", CoverageBaseline: 80}
+	comment := FormatComment(statistics, attribution, attribution.PullRequests[0], o)
+	for _, expect := range []string{
+		"### Merge Queue Coverage",
+		"#1 is merged in a batch of #1, #2, #3 at `0123456`.",
+		":x: The lines it changed are 50.00% covered, 1 of 2 lines (baseline 80.00%).",
+		"| github.com/Azure/modulea/foo.go | 50.00% (1/2) | 4 |",
+		"The batch is 40.00% covered, 2 of 5 lines.",
+	} {
+		if !strings.Contains(comment, expect) {
+			t.Errorf("expect %q in the comment, but get:\n%s", expect, comment)
+		}
+	}
+
+	if comment := FormatComment(statistics, attribution, attribution.PullRequests[2], o); !strings.Contains(comment, "It changed no lines that count for coverage.") || strings.Contains(comment, "| File |") {
+		t.Errorf("unexpected comment of the pull request without lines:\n%s", comment)
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	if ranges := lineRanges([]int{3, 4, 5, 9, 11, 12}); ranges != "3-5, 9, 11-12" {
+		t.Errorf("expect 3-5, 9, 11-12, but get %s", ranges)
+	}
+}
@@ -0,0 +1,122 @@
+package mergequeue
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/gittool"
+)
+
+var (
+	// githubMergePattern matches the subject of a merge commit of GitHub, such as `Merge pull request #12 from owner/branch`.
+	githubMergePattern = regexp.MustCompile(`^Merge pull request #(\d+)\b`)
+	// borsMergePattern matches the subject of a merge commit of bors, such as `Merge #12 #34`.
+	borsMergePattern = regexp.MustCompile(`^Merge((?: #\d+)+)$`)
+	// squashPattern matches the subject of a squashed commit of GitHub, such as `Add the foo (#12)`.
+	squashPattern = regexp.MustCompile(`\(#(\d+)\)$`)
+)
+
+// PullRequestNumbers returns the numbers of the pull requests in the subject of the commit message,
+// the merge commits of GitHub and bors, and the squashed commits of GitHub are recognized.
+func PullRequestNumbers(message string) []int {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	subject = strings.TrimSpace(subject)
+
+	var numbers []string
+	if m := githubMergePattern.FindStringSubmatch(subject); m != nil {
+		numbers = []string{m[1]}
+	} else if m := borsMergePattern.FindStringSubmatch(subject); m != nil {
+		numbers = strings.Fields(strings.ReplaceAll(m[1], "#", ""))
+	} else if m := squashPattern.FindStringSubmatch(subject); m != nil {
+		numbers = []string{m[1]}
+	}
+
+	var result []int
+	for _, n := range numbers {
+		if number, err := strconv.Atoi(n); err == nil {
+			result = append(result, number)
+		}
+	}
+	return result
+}
+
+// Batch is the pull requests merged in a batch, and the commits that each pull request brings.
+type Batch struct {
+	// PullRequests are the numbers of the pull requests in the merged order.
+	PullRequests []int
+	// commits are the pull requests of the commits of the batch, the commits without a pull request,
+	// such as the rebased commits, have no numbers.
+	commits map[string][]int
+}
+
+// NewBatch creates the batch of the commits landed on the branch by the first parents, the newest first, as FirstParentLog returns.
+// The commits of a merge commit are listed by introduced, which returns the commits reachable from the commit but not from the base.
+func NewBatch(commits []*gittool.Commit, introduced func(base, commit string) ([]string, error)) (*Batch, error) {
+	b := &Batch{commits: make(map[string][]int)}
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		numbers := PullRequestNumbers(c.Message)
+		for _, number := range numbers {
+			if !b.has(number) {
+				b.PullRequests = append(b.PullRequests, number)
+			}
+		}
+		b.add(c.SHA, numbers)
+		if len(c.Parents) < 2 {
+			continue
+		}
+
+		// the parents of an octopus merge of bors are the pull requests in order, otherwise the commits
+		// of a merge commit belong to all its pull requests.
+		perParent := len(c.Parents)-1 == len(numbers) && len(numbers) > 1
+		for j, parent := range c.Parents[1:] {
+			shas, err := introduced(c.Parents[0], parent)
+			if err != nil {
+				return nil, err
+			}
+			owners := numbers
+			if perParent {
+				owners = numbers[j : j+1]
+			}
+			for _, sha := range shas {
+				b.add(sha, owners)
+			}
+		}
+	}
+	return b, nil
+}
+
+// LoadBatch loads the batch of the commits reachable from head but not from base in the repository.
+func LoadBatch(ctx context.Context, repositoryPath, base, head string) (*Batch, error) {
+	commits, err := gittool.FirstParentLog(ctx, repositoryPath, base, head)
+	if err != nil {
+		return nil, err
+	}
+	return NewBatch(commits, func(base, commit string) ([]string, error) {
+		return gittool.RevList(ctx, repositoryPath, base, commit)
+	})
+}
+
+// add records the pull requests of the commit, the commit brought by an earlier merge is kept.
+func (b *Batch) add(sha string, numbers []int) {
+	if _, ok := b.commits[sha]; !ok {
+		b.commits[sha] = numbers
+	}
+}
+
+func (b *Batch) has(number int) bool {
+	for _, n := range b.PullRequests {
+		if n == number {
+			return true
+		}
+	}
+	return false
+}
+
+// PullRequestsOf returns the pull requests of the commit, it's false if the commit isn't in the batch.
+func (b *Batch) PullRequestsOf(commit string) ([]int, bool) {
+	numbers, ok := b.commits[commit]
+	return numbers, ok
+}
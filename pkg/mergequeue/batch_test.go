@@ -0,0 +1,67 @@
+package mergequeue
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gittool"
+)
+
+func TestPullRequestNumbers(t *testing.T) {
+	testCases := []struct {
+		message string
+		expect  []int
+	}{
+		{message: "Merge pull request #12 from owner/feature\n\nAdd the foo", expect: []int{12}},
+		{message: "Merge #12 #34\n\n12: Add the foo r=alice a=bob", expect: []int{12, 34}},
+		{message: "Add the foo (#56)\n\n* add foo\n* fix tests", expect: []int{56}},
+		{message: "Fix the bar of #78", expect: nil},
+		{message: "Merge branch 'main' into feature", expect: nil},
+	}
+	for _, testCase := range testCases {
+		if numbers := PullRequestNumbers(testCase.message); !reflect.DeepEqual(numbers, testCase.expect) {
+			t.Errorf("%q: expect %v, but get %v", testCase.message, testCase.expect, numbers)
+		}
+	}
+}
+
+func TestNewBatch(t *testing.T) {
+	commits := []*gittool.Commit{
+		{SHA: "octopus", Parents: []string{"squash", "c3", "c4"}, Message: "Merge #3 #4"},
+		{SHA: "squash", Parents: []string{"merge"}, Message: "Add the bar (#2)"},
+		{SHA: "merge", Parents: []string{"base", "c1"}, Message: "Merge pull request #1 from owner/foo"},
+		{SHA: "rebased", Parents: []string{"base"}, Message: "Fix the typo"},
+	}
+	introduced := map[string][]string{
+		"base c1":   {"c1", "c0"},
+		"squash c3": {"c3"},
+		"squash c4": {"c4", "c3"},
+	}
+	batch, err := NewBatch(commits, func(base, commit string) ([]string, error) {
+		return introduced[base+" "+commit], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(batch.PullRequests, []int{1, 2, 3, 4}) {
+		t.Errorf("expect the pull requests in the merged order, but get %v", batch.PullRequests)
+	}
+
+	expects := map[string][]int{
+		"merge": {1}, "c0": {1}, "c1": {1}, "squash": {2}, "octopus": {3, 4}, "c3": {3}, "c4": {4}, "rebased": nil,
+	}
+	for sha, expect := range expects {
+		if numbers, ok := batch.PullRequestsOf(sha); !ok || !reflect.DeepEqual(numbers, expect) {
+			t.Errorf("%s: expect %v, but get %v, %v", sha, expect, numbers, ok)
+		}
+	}
+	if _, ok := batch.PullRequestsOf("base"); ok {
+		t.Error("expect the base isn't in the batch")
+	}
+
+	errIntroduced := errors.New("rev-list failed")
+	if _, err := NewBatch(commits, func(base, commit string) ([]string, error) { return nil, errIntroduced }); !errors.Is(err, errIntroduced) {
+		t.Errorf("expect the error of the introduced commits, but get %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package mergequeue attributes the coverage of a merge queue batch, such as a GitHub merge queue group or a bors batch,
+// back to the pull requests merged in the batch, by the pull request numbers in the messages of the commits and the
+// git blame of the changed lines, and posts a summary of each pull request, so the batched merges keep the accountability of each pull request.
+package mergequeue
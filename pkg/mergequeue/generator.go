@@ -0,0 +1,218 @@
+package mergequeue
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+// publishTimeout bounds the time of attributing the batch and posting the comments.
+const publishTimeout = 2 * time.Minute
+
+// Option contains the input for the merge queue report generator.
+type Option struct {
+	// Owner is the owner of the repository.
+	Owner string
+	// Repository is the name of the repository.
+	Repository string
+	// Base is the branch that the batch merges into, the compared branch of the statistics is used if it's empty.
+	Base string
+	// RepositoryPath is the root directory of the git repository, HEAD of it is the batch.
+	RepositoryPath string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root.
+	ModuleDir string
+	// CoverageBaseline decides the icon of each pull request.
+	CoverageBaseline float64
+	// DryRun logs the summaries of the pull requests rather than posting them.
+	DryRun bool
+}
+
+// NewReportGenerator creates a report generator that attributes the statistics of a merge queue batch to the pull requests
+// of the batch, and posts the summary of each pull request as a comment. The failures are logged rather than failing the command,
+// the gate of the batch is the coverage of the statistics.
+func NewReportGenerator(client scm.Client, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{client: client, option: o, logger: logger.WithField("source", "MergeQueue")}
+}
+
+type reportGenerator struct {
+	client scm.Client
+	option *Option
+	logger logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport posts the summary of each pull request of the batch.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	base := g.option.Base
+	if base == "" {
+		base = statistics.ComparedBranch
+	}
+	head, err := gittool.ResolveCommit(ctx, g.option.RepositoryPath, "HEAD")
+	if err != nil {
+		g.logger.WithError(err).Error("resolve the commit of the batch")
+		return nil
+	}
+	batch, err := LoadBatch(ctx, g.option.RepositoryPath, base, head)
+	if err != nil {
+		g.logger.WithError(err).Errorf("load the batch of %s..HEAD", base)
+		return nil
+	}
+	if len(batch.PullRequests) == 0 {
+		g.logger.Warnf("no pull request is found in the commits of %s..HEAD", base)
+		return nil
+	}
+
+	blamer, err := gittool.NewBlamer(g.option.RepositoryPath)
+	if err != nil {
+		g.logger.WithError(err).Error("open the repository of the batch")
+		return nil
+	}
+	attribution, err := Attribute(statistics, batch, &moduleBlamer{blamer: blamer, option: g.option})
+	if err != nil {
+		g.logger.WithError(err).Error("attribute the batch to the pull requests")
+		return nil
+	}
+	if attribution.UnattributedLines != 0 {
+		g.logger.Warnf("%d lines of the batch are changed by the commits without a pull request", attribution.UnattributedLines)
+	}
+
+	for _, c := range attribution.PullRequests {
+		target := fmt.Sprintf("%s/%s#%d", g.option.Owner, g.option.Repository, c.Number)
+		g.logger.Infof("%s: %.2f%% of %d lines", target, c.CoveragePercent, c.EffectiveLines)
+		if g.option.DryRun {
+			continue
+		}
+		body := FormatComment(statistics, attribution, c, &CommentOption{HeadSHA: head, CoverageBaseline: g.option.CoverageBaseline})
+		pr := &scm.PullRequest{Owner: g.option.Owner, Repository: g.option.Repository, Number: c.Number}
+		if id, err := g.client.PostComment(ctx, pr, body); err != nil {
+			g.logger.WithError(err).Errorf("post comment of %s", target)
+		} else {
+			g.logger.Infof("post comment of %s: %s", target, id)
+		}
+	}
+	return nil
+}
+
+// moduleBlamer blames the files of the statistics in the repository.
+type moduleBlamer struct {
+	blamer gittool.Blamer
+	option *Option
+}
+
+var _ report.Blamer = (*moduleBlamer)(nil)
+
+func (b *moduleBlamer) Blame(fileName string) (map[int]*report.LineAuthor, error) {
+	relative := strings.TrimPrefix(strings.TrimPrefix(fileName, b.option.ModulePath), "/")
+	lines, err := b.blamer.Blame(filepath.Join(b.option.ModuleDir, filepath.FromSlash(relative)))
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[int]*report.LineAuthor, len(lines))
+	for i, line := range lines {
+		authors[i+1] = &report.LineAuthor{Author: line.Author, Commit: line.Commit}
+	}
+	return authors, nil
+}
+
+// CommentOption contains the input for the comment of a pull request of the batch.
+type CommentOption struct {
+	// HeadSHA is the commit of the batch.
+	HeadSHA string
+	// CoverageBaseline decides the icon of the pull request, there is no baseline if it's not positive.
+	CoverageBaseline float64
+}
+
+// FormatComment returns the markdown comment of the pull request of the batch, with the coverage of the lines
+// that it changed, the uncovered lines of each file, and the coverage of the whole batch.
+func FormatComment(statistics *report.Statistics, attribution *Attribution, c *PullRequestCoverage, o *CommentOption) string {
+	var b strings.Builder
+	b.WriteString("### Merge Queue Coverage\n\n")
+
+	numbers := make([]string, 0, len(attribution.PullRequests))
+	for _, pr := range attribution.PullRequests {
+		numbers = append(numbers, fmt.Sprintf("#%d", pr.Number))
+	}
+	fmt.Fprintf(&b, "#%d is merged in a batch of %s", c.Number, strings.Join(numbers, ", "))
+	if o.HeadSHA != "" {
+		fmt.Fprintf(&b, " at `%s`", shortSHA(o.HeadSHA))
+	}
+	b.WriteString(".\n\n")
+
+	if c.EffectiveLines == 0 {
+		b.WriteString("It changed no lines that count for coverage.\n")
+	} else {
+		fmt.Fprintf(&b, "%s The lines it changed are %.2f%% covered, %d of %d lines", icon(c.CoveragePercent, o.CoverageBaseline),
+			c.CoveragePercent, c.CoveredLines, c.EffectiveLines)
+		if o.CoverageBaseline > 0 {
+			fmt.Fprintf(&b, " (baseline %.2f%%)", o.CoverageBaseline)
+		}
+		b.WriteString(".\n")
+	}
+
+	var uncovered []*FileCoverage
+	for _, file := range c.Files {
+		if len(file.UncoveredLines) != 0 {
+			uncovered = append(uncovered, file)
+		}
+	}
+	if len(uncovered) != 0 {
+		b.WriteString("\n| File | Coverage | Uncovered lines |\n| --- | --- | --- |\n")
+		for _, file := range uncovered {
+			fmt.Fprintf(&b, "| %s | %.2f%% (%d/%d) | %s |\n", file.FileName, percent(file.CoveredLines, file.EffectiveLines),
+				file.CoveredLines, file.EffectiveLines, lineRanges(file.UncoveredLines))
+		}
+	}
+
+	fmt.Fprintf(&b, "\nThe batch is %.2f%% covered, %d of %d lines.\n", statistics.TotalCoveragePercent,
+		statistics.TotalCoveredLines-statistics.TotalCoveredButIgnoredLines, statistics.TotalEffectiveLines)
+	return b.String()
+}
+
+func icon(percent, baseline float64) string {
+	switch {
+	case baseline <= 0:
+		return ":bar_chart:"
+	case percent >= baseline:
+		return ":white_check_mark:"
+	default:
+		return ":x:"
+	}
+}
+
+// lineRanges joins the sorted lines as ranges, such as 3-5, 9.
+func lineRanges(lines []int) string {
+	var ranges []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] <= lines[j]+1 {
+			j++
+		}
+		if lines[i] == lines[j] {
+			ranges = append(ranges, fmt.Sprintf("%d", lines[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,3 @@
+// Package metrics exports the coverage of a run as Prometheus metrics, pushed to a Pushgateway or written into
+// an OpenMetrics text file for the textfile collector of the node exporter, so the coverage is on the dashboards and alerted.
+package metrics
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// DefaultPushgatewayJob is the job label of the metrics pushed to the Pushgateway.
+	DefaultPushgatewayJob = "gocover"
+
+	// textContentType is the content type of the text exposition format.
+	textContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var ErrUnexpectedResponse = errors.New("unexpected pushgateway response")
+
+// Exporter exports the metrics of a run with the labels.
+type Exporter interface {
+	Export(ctx context.Context, metrics []*Metric, labels Labels) error
+}
+
+// NewFileExporter creates the exporter writes the metrics into the OpenMetrics text file, the file is replaced atomically,
+// so the textfile collector of the node exporter never reads a partial file.
+func NewFileExporter(filename string) Exporter {
+	return &fileExporter{filename: filename}
+}
+
+type fileExporter struct {
+	filename string
+}
+
+var _ Exporter = (*fileExporter)(nil)
+
+func (e *fileExporter) Export(ctx context.Context, metrics []*Metric, labels Labels) error {
+	err := atomicfile.WriteFile(e.filename, func(w io.Writer) error {
+		return Write(w, metrics, labels, true)
+	})
+	if err != nil {
+		return fmt.Errorf("write metrics file %s: %w", e.filename, err)
+	}
+	return nil
+}
+
+// NewPushgatewayExporter creates the exporter pushes the metrics to the Pushgateway, the labels are the grouping key
+// besides the job, so each module of each branch is a group, and a push replaces the metrics of its group.
+// The token is sent as the bearer token if it's not nil, such as the Pushgateway behind an authenticating proxy.
+func NewPushgatewayExporter(baseURL, job string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) Exporter {
+	if job == "" {
+		job = DefaultPushgatewayJob
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &pushgatewayExporter{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		job:      job,
+		token:    token,
+		client:   httpClient,
+		recorder: recorder,
+	}
+}
+
+type pushgatewayExporter struct {
+	baseURL  string
+	job      string
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ Exporter = (*pushgatewayExporter)(nil)
+
+func (e *pushgatewayExporter) Export(ctx context.Context, metrics []*Metric, labels Labels) error {
+	endpoint := e.groupURL(labels)
+	err := e.push(ctx, endpoint, metrics)
+
+	e.recorder.Record(audit.NewAction(audit.UploadAction, redact.String(endpoint), "", err))
+	if err != nil {
+		return fmt.Errorf("push metrics to pushgateway: %w", err)
+	}
+	return nil
+}
+
+// groupURL returns the url of the group of the labels, the label values are base64 encoded
+// if they contain slashes or they're empty, such as the branches like feature/foo.
+func (e *pushgatewayExporter) groupURL(labels Labels) string {
+	var b strings.Builder
+	b.WriteString(e.baseURL + "/metrics/job/" + url.PathEscape(e.job))
+	for _, name := range labels.names() {
+		value := labels[name]
+		if value == "" || strings.Contains(value, "/") {
+			b.WriteString("/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value)))
+			if value == "" {
+				b.WriteString("=")
+			}
+			continue
+		}
+		b.WriteString("/" + name + "/" + url.PathEscape(value))
+	}
+	return b.String()
+}
+
+// push replaces the metrics of the group by PUT, the labels are in the url rather than the samples.
+func (e *pushgatewayExporter) push(ctx context.Context, endpoint string, metrics []*Metric) error {
+	var body bytes.Buffer
+	if err := Write(&body, metrics, nil, false); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return redact.Error(err)
+	}
+	req.Header.Set("Content-Type", textContentType)
+	if e.token != nil {
+		token, err := e.token.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: %d %s", ErrUnexpectedResponse, resp.StatusCode, redact.String(strings.TrimSpace(string(data))))
+	}
+	return nil
+}
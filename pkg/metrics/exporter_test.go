@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFileExporter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "gocover.prom")
+	metrics := Collect(testStatistics(), 80, time.Unix(0, 0))
+	if err := NewFileExporter(filename).Export(context.Background(), metrics, Labels{ModuleLabel: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `gocover_violation_lines{module="foo"} 2`) || !strings.HasSuffix(string(data), "# EOF\n") {
+		t.Errorf("unexpected metrics file:\n%s", data)
+	}
+}
+
+func TestPushgatewayExporter(t *testing.T) {
+	var method, path, contentType, authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType, authorization = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if strings.Contains(path, "fail") {
+			http.Error(w, "push failed", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_PUSHGATEWAY_TOKEN", "secret")
+	token, _ := credential.NewProvider("env:GOCOVER_TEST_PUSHGATEWAY_TOKEN", nil)
+	e := NewPushgatewayExporter(server.URL+"/", "", token, server.Client(), nil)
+	labels := Labels{RepositoryLabel: "Azure/gocover", BranchLabel: "main", ModuleLabel: "github.com/Azure/gocover", TypeLabel: ""}
+	if err := e.Export(context.Background(), Collect(testStatistics(), 80, time.Unix(0, 0)), labels); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || contentType != textContentType || authorization != "Bearer secret" {
+		t.Errorf("unexpected request %s, content type %s, authorization %s", method, contentType, authorization)
+	}
+	expectPath := "/metrics/job/gocover/branch/main/module@base64/Z2l0aHViLmNvbS9BenVyZS9nb2NvdmVy/repository@base64/QXp1cmUvZ29jb3Zlcg/type@base64/="
+	if path != expectPath {
+		t.Errorf("expect path %s, but get %s", expectPath, path)
+	}
+	if !strings.Contains(body, "gocover_coverage_percent 75\n") || strings.Contains(body, "# EOF") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+
+	err := NewPushgatewayExporter(server.URL, "fail", nil, server.Client(), nil).Export(context.Background(), nil, nil)
+	if !errors.Is(err, ErrUnexpectedResponse) || !strings.Contains(err.Error(), "push failed") {
+		t.Errorf("expect ErrUnexpectedResponse, but get %v", err)
+	}
+}
+
+type fakeExporter struct {
+	metrics []*Metric
+	labels  Labels
+	err     error
+}
+
+func (e *fakeExporter) Export(ctx context.Context, metrics []*Metric, labels Labels) error {
+	e.metrics, e.labels = metrics, labels
+	return e.err
+}
+
+func TestReportGenerator(t *testing.T) {
+	e := &fakeExporter{err: errors.New("export failed")}
+	g := NewReportGenerator(e, &Option{Repository: "Azure/gocover", Branch: "main", Module: "github.com/Azure/gocover", CoverageBaseline: 80}, logrus.New())
+	// the failed export doesn't fail the command.
+	if err := g.GenerateReport(testStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	expect := Labels{RepositoryLabel: "Azure/gocover", BranchLabel: "main", ModuleLabel: "github.com/Azure/gocover", TypeLabel: "diff"}
+	for name, value := range expect {
+		if e.labels[name] != value {
+			t.Errorf("expect label %s=%s, but get %s", name, value, e.labels[name])
+		}
+	}
+	if len(e.metrics) == 0 || e.metrics[2].Value != 80 {
+		t.Errorf("unexpected metrics %+v", e.metrics)
+	}
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// exportTimeout bounds the time of exporting the metrics.
+const exportTimeout = time.Minute
+
+// Option contains the input for the metrics report generator.
+type Option struct {
+	// RepositoryPath is the root directory of the git repository, the branch is resolved from it if it's not set.
+	RepositoryPath string
+	// Repository is the repository label, such as owner/name.
+	Repository string
+	// Branch is the branch label, the branch checked out in the repository is used if it's empty.
+	Branch string
+	// Module is the module label, the path of the go module.
+	Module string
+	// CoverageBaseline is exported as the baseline gauge.
+	CoverageBaseline float64
+}
+
+// NewReportGenerator creates a report generator that exports the metrics of the statistics,
+// the failed export is logged rather than failing the command, as the other reports are written.
+func NewReportGenerator(exporter Exporter, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{exporter: exporter, option: o, now: time.Now, logger: logger.WithField("source", "Metrics")}
+}
+
+type reportGenerator struct {
+	exporter Exporter
+	option   *Option
+	now      func() time.Time
+	logger   logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	branch := g.option.Branch
+	if branch == "" {
+		var err error
+		if branch, err = gittool.CurrentBranch(ctx, g.option.RepositoryPath); err != nil {
+			g.logger.WithError(err).Warn("resolve the branch of the metrics")
+		}
+	}
+	labels := Labels{
+		RepositoryLabel: g.option.Repository,
+		BranchLabel:     branch,
+		ModuleLabel:     g.option.Module,
+		TypeLabel:       string(statistics.StatisticsType),
+	}
+
+	if err := g.exporter.Export(ctx, Collect(statistics, g.option.CoverageBaseline, g.now()), labels); err != nil {
+		g.logger.WithError(err).Error("export metrics")
+		return nil
+	}
+	g.logger.Infof("export metrics of %s %s", g.option.Module, branch)
+	return nil
+}
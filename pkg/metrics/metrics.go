@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// The labels of the metrics, they identify the coverage of a module of a branch.
+const (
+	RepositoryLabel = "repository"
+	BranchLabel     = "branch"
+	ModuleLabel     = "module"
+	// TypeLabel is the statistics type of the run, diff or full, so both are kept for the same module.
+	TypeLabel = "type"
+)
+
+// Labels are the labels of the exported metrics.
+type Labels map[string]string
+
+// names returns the sorted label names.
+func (l Labels) names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Metric is a gauge of the coverage of a run.
+type Metric struct {
+	Name  string
+	Help  string
+	Value float64
+}
+
+// Collect returns the metrics of the statistics, the percentages are in 0-100 as the reports.
+func Collect(statistics *report.Statistics, coverageBaseline float64, now time.Time) []*Metric {
+	return []*Metric{
+		{Name: "gocover_coverage_percent", Help: "Coverage percent of the lines that count for coverage.", Value: statistics.TotalCoveragePercent},
+		{Name: "gocover_coverage_without_ignore_percent", Help: "Coverage percent of the lines without ignoring the ignored lines.", Value: statistics.TotalCoverageWithoutIgnore},
+		{Name: "gocover_coverage_baseline_percent", Help: "Coverage baseline that the coverage is gated by.", Value: coverageBaseline},
+		{Name: "gocover_effective_lines", Help: "Lines that count for coverage.", Value: float64(statistics.TotalEffectiveLines)},
+		{Name: "gocover_covered_lines", Help: "Covered lines that count for coverage.", Value: float64(statistics.TotalCoveredLines)},
+		{Name: "gocover_violation_lines", Help: "Lines that miss test coverage.", Value: float64(statistics.TotalViolationLines)},
+		{Name: "gocover_ignored_lines", Help: "Lines ignored by the annotations and the exemptions.", Value: float64(statistics.TotalIgnoredLines)},
+		{Name: "gocover_files", Help: "Files that count for coverage.", Value: float64(len(statistics.CoverageProfile))},
+		{Name: "gocover_last_run_timestamp_seconds", Help: "Unix time of the run.", Value: float64(now.Unix())},
+	}
+}
+
+// Write writes the metrics in the text exposition format of Prometheus with the labels, the OpenMetrics text format
+// if openMetrics is true, which ends with the EOF marker.
+func Write(w io.Writer, metrics []*Metric, labels Labels, openMetrics bool) error {
+	var pairs []string
+	for _, name := range labels.names() {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(labels[name])))
+	}
+	set := ""
+	if len(pairs) > 0 {
+		set = "{" + strings.Join(pairs, ",") + "}"
+	}
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.Name, m.Help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", m.Name)
+		fmt.Fprintf(&b, "%s%s %s\n", m.Name, set, strconv.FormatFloat(m.Value, 'g', -1, 64))
+	}
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabelValue escapes the backslashes, the double quotes and the line feeds of the label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
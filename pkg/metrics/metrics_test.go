@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func testStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:             report.DiffStatisticsType,
+		TotalEffectiveLines:        8,
+		TotalCoveredLines:          6,
+		TotalViolationLines:        2,
+		TotalIgnoredLines:          3,
+		TotalCoveragePercent:       75,
+		TotalCoverageWithoutIgnore: 54.55,
+		CoverageProfile:            []*report.CoverageProfile{{}, {}},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	metrics := Collect(testStatistics(), 80, time.Unix(1700000000, 0))
+	b := &strings.Builder{}
+	labels := Labels{BranchLabel: `release/"1.0"`, ModuleLabel: "github.com/Azure/gocover"}
+	if err := Write(b, metrics[:2], labels, true); err != nil {
+		t.Fatal(err)
+	}
+	expect := `# HELP gocover_coverage_percent Coverage percent of the lines that count for coverage.
+# TYPE gocover_coverage_percent gauge
+gocover_coverage_percent{branch="release/\"1.0\"",module="github.com/Azure/gocover"} 75
+# HELP gocover_coverage_without_ignore_percent Coverage percent of the lines without ignoring the ignored lines.
+# TYPE gocover_coverage_without_ignore_percent gauge
+gocover_coverage_without_ignore_percent{branch="release/\"1.0\"",module="github.com/Azure/gocover"} 54.55
+# EOF
+`
+	if b.String() != expect {
+		t.Errorf("expect:\n%s\nbut get:\n%s", expect, b.String())
+	}
+
+	b.Reset()
+	if err := Write(b, metrics, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"gocover_coverage_baseline_percent 80\n",
+		"gocover_violation_lines 2\n",
+		"gocover_ignored_lines 3\n",
+		"gocover_files 2\n",
+		"gocover_last_run_timestamp_seconds 1.7e+09\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expect %q in the metrics:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "# EOF") {
+		t.Errorf("unexpected EOF marker of the text format:\n%s", b.String())
+	}
+}
@@ -0,0 +1,3 @@
+// Package notify posts the results of the finished runs to the outbound webhooks,
+// so that the downstream systems, such as deployment gates and dashboards, react to them without polling.
+package notify
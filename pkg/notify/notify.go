@@ -0,0 +1,267 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EventHeader is the header of the event of a delivery.
+	EventHeader = "X-Gocover-Event"
+	// DeliveryHeader is the header of the unique id of a delivery, the retries of a delivery share the id.
+	DeliveryHeader = "X-Gocover-Delivery"
+	// SignatureHeader is the header of the HMAC SHA256 signature of the payload, in the format of sha256={hex}.
+	SignatureHeader = "X-Gocover-Signature-256"
+	signaturePrefix = "sha256="
+
+	// RunCompletedEvent is the event that a run completes.
+	RunCompletedEvent = "run.completed"
+
+	// maxAttempts is the number of attempts of a delivery, the network failures and the server errors are retried.
+	maxAttempts = 3
+	// defaultRetryInterval is the interval before the first retry, it doubles for each retry.
+	defaultRetryInterval = time.Second
+)
+
+var (
+	ErrDeliveryFailed = errors.New("result webhook delivery failed")
+	ErrInvalidURL     = errors.New("invalid result webhook url")
+)
+
+// Endpoint is an outbound webhook that receives the results.
+type Endpoint struct {
+	// URL is the url that the payload is posted to.
+	URL string
+	// Secret signs the payload in SignatureHeader, the payload is not signed if it's nil.
+	Secret credential.Provider
+	// Name identifies the endpoint in the logs and the audit log rather than the url, for the urls that are secrets,
+	// such as a Slack incoming webhook. The url without the query and the user info is used if it's empty.
+	Name string
+}
+
+// Payload is the body of a delivery.
+type Payload struct {
+	// Event is the event of the delivery.
+	Event string `json:"event"`
+	// SchemaVersion is the schema version of the payload, the receivers negotiate it as report.CheckSchemaVersion does.
+	SchemaVersion int `json:"schemaVersion"`
+	// CompatibleSchemaVersion is the oldest schema version whose receivers can read the payload.
+	CompatibleSchemaVersion int `json:"compatibleSchemaVersion"`
+	// ToolVersion is the version of gocover that delivers the payload.
+	ToolVersion string `json:"toolVersion"`
+	// CompletedAt is the time when the run completes.
+	CompletedAt time.Time `json:"completedAt"`
+	// RunID identifies the stored run, it's empty if the run is not stored.
+	RunID string `json:"runId,omitempty"`
+	// Owner is the owner of the repository, it's empty if the run is not for a pull request.
+	Owner string `json:"owner,omitempty"`
+	// Repository is the name of the repository, it's empty if the run is not for a pull request.
+	Repository string `json:"repository,omitempty"`
+	// Number is the pull request number, it's zero if the run is not for a pull request.
+	Number int `json:"number,omitempty"`
+	// HeadSHA is the commit that the run analyzed, it's empty if it's unknown.
+	HeadSHA string `json:"headSHA,omitempty"`
+	// CoverageBaseline is the coverage baseline of the coverage gate.
+	CoverageBaseline float64 `json:"coverageBaseline"`
+	// Passed indicates the coverage gate passes, it's true if the gate is bypassed.
+	Passed bool `json:"passed"`
+	// Statistics is the result of the run.
+	Statistics *report.Statistics `json:"statistics"`
+}
+
+// NewPayload creates the payload of a completed run, the gate is passed by the decision of the gate in the statistics,
+// or by the coverage baseline if the gate isn't evaluated.
+func NewPayload(statistics *report.Statistics, coverageBaseline float64) *Payload {
+	return &Payload{
+		Event:                   RunCompletedEvent,
+		SchemaVersion:           report.SchemaVersion,
+		CompatibleSchemaVersion: report.CompatibleSchemaVersion,
+		ToolVersion:             report.ToolVersion,
+		CompletedAt:             time.Now().UTC(),
+		CoverageBaseline:        coverageBaseline,
+		Passed:                  statistics.GatePassed(coverageBaseline),
+		Statistics:              statistics,
+	}
+}
+
+// Notifier delivers the payloads to the endpoints.
+type Notifier struct {
+	endpoints     []*Endpoint
+	client        *http.Client
+	recorder      audit.Recorder
+	retryInterval time.Duration
+	logger        logrus.FieldLogger
+}
+
+// NewNotifier creates the notifier of the endpoints, the deliveries are recorded by the recorder if it's not nil.
+func NewNotifier(endpoints []*Endpoint, client *http.Client, recorder audit.Recorder, logger logrus.FieldLogger) (*Notifier, error) {
+	for _, e := range endpoints {
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w '%s', it should be a http or https url", ErrInvalidURL, redact.String(e.URL))
+		}
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Notifier{
+		endpoints:     endpoints,
+		client:        client,
+		recorder:      recorder,
+		retryInterval: defaultRetryInterval,
+		logger:        logger.WithField("source", "Notifier"),
+	}, nil
+}
+
+// Notify delivers the payload to all the endpoints, a failed endpoint doesn't stop the others.
+func (n *Notifier) Notify(ctx context.Context, payload *Payload) error {
+	if n == nil || len(n.endpoints) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	return n.send(ctx, payload.Event, data)
+}
+
+// send delivers the body of the event to all the endpoints.
+func (n *Notifier) send(ctx context.Context, event string, data []byte) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("generate delivery id: %w", err)
+	}
+	delivery := hex.EncodeToString(b)
+
+	var errs []error
+	for _, e := range n.endpoints {
+		err := n.deliver(ctx, e, event, delivery, data)
+		target := e.target()
+		if n.recorder != nil {
+			action := audit.NewAction(audit.WebhookAction, target, delivery, err)
+			action.Details = map[string]string{"event": event}
+			n.recorder.Record(action)
+		}
+		if err != nil {
+			n.logger.WithError(err).Errorf("deliver %s to %s", event, target)
+			errs = append(errs, err)
+			continue
+		}
+		n.logger.Infof("deliver %s to %s", event, target)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver posts the payload to the endpoint, the network failures, 429 and 5xx responses are retried.
+func (n *Notifier) deliver(ctx context.Context, e *Endpoint, event, delivery string, data []byte) error {
+	var signature string
+	if e.Secret != nil {
+		secret, err := e.Secret.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get secret of %s: %w", e.target(), err)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		signature = signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	interval := n.retryInterval
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		retry, err := n.post(ctx, e.URL, event, delivery, signature, data)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+	return fmt.Errorf("%w: %s: %s", ErrDeliveryFailed, e.target(), redact.String(lastErr.Error()))
+}
+
+// post posts the payload once and returns whether the failure is worth retrying.
+func (n *Notifier) post(ctx context.Context, endpoint, event, delivery, signature string, data []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, delivery)
+	if signature != "" {
+		req.Header.Set(SignatureHeader, signature)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// the url error contains the whole url, which may contain the secrets.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("returns %d", resp.StatusCode)
+}
+
+// target returns the name of the endpoint, or the url without the query and the user info, which may contain the secrets.
+func (e *Endpoint) target() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return "result webhook"
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// NewReportGenerator creates a report generator that delivers the statistics of the run to the endpoints of the notifier,
+// so it runs after the other reports of a coverage command.
+func NewReportGenerator(n *Notifier, coverageBaseline float64) report.ReportGenerator {
+	return &reportGenerator{notifier: n, coverageBaseline: coverageBaseline}
+}
+
+type reportGenerator struct {
+	notifier         *Notifier
+	coverageBaseline float64
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport delivers the statistics, the failed deliveries are logged rather than failing the command.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	_ = g.notifier.Notify(context.Background(), NewPayload(statistics, g.coverageBaseline))
+	return nil
+}
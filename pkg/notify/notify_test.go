@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+type staticToken string
+
+func (s staticToken) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+// receiver records the deliveries, and fails the first failures of them with the status.
+type receiver struct {
+	mu         sync.Mutex
+	failures   int
+	status     int
+	deliveries []*http.Request
+	bodies     [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, req)
+	r.bodies = append(r.bodies, body)
+	if len(r.deliveries) <= r.failures {
+		w.WriteHeader(r.status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestNotifier(t *testing.T, recorder audit.Recorder, endpoints ...*Endpoint) *Notifier {
+	n, err := NewNotifier(endpoints, nil, recorder, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.retryInterval = time.Millisecond
+	return n
+}
+
+func TestNotify(t *testing.T) {
+	statistics := &report.Statistics{StatisticsType: report.DiffStatisticsType, TotalCoveragePercent: 85}
+
+	t.Run("signed payload", func(t *testing.T) {
+		r := &receiver{}
+		server := httptest.NewServer(r)
+		defer server.Close()
+		recorder := audit.NewRecorder("")
+		n := newTestNotifier(t, recorder, &Endpoint{URL: server.URL + "/hook?token=xxx", Secret: staticToken("secret")})
+
+		payload := NewPayload(statistics, 80)
+		payload.Owner, payload.Repository, payload.Number, payload.HeadSHA = "Azure", "gocover", 12, "abc"
+		if err := n.Notify(context.Background(), payload); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(r.deliveries) != 1 {
+			t.Fatalf("expect 1 delivery, but get %d", len(r.deliveries))
+		}
+		req, body := r.deliveries[0], r.bodies[0]
+		if req.Header.Get(EventHeader) != RunCompletedEvent || req.Header.Get(DeliveryHeader) == "" {
+			t.Errorf("unexpected headers %v", req.Header)
+		}
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(SignatureHeader) != want {
+			t.Errorf("expect signature %s, but get %s", want, req.Header.Get(SignatureHeader))
+		}
+
+		got := &Payload{}
+		if err := json.Unmarshal(body, got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Passed || got.Number != 12 || got.Statistics.TotalCoveragePercent != 85 || got.SchemaVersion != report.SchemaVersion || got.ToolVersion != report.ToolVersion {
+			t.Errorf("unexpected payload %s", body)
+		}
+
+		actions := recorder.Actions()
+		if len(actions) != 1 || !actions[0].Succeeded || actions[0].Type != audit.WebhookAction || strings.Contains(actions[0].Target, "token") {
+			t.Errorf("unexpected actions %+v", actions[0])
+		}
+	})
+
+	t.Run("retry server errors", func(t *testing.T) {
+		r := &receiver{failures: 2, status: http.StatusBadGateway}
+		server := httptest.NewServer(r)
+		defer server.Close()
+		n := newTestNotifier(t, nil, &Endpoint{URL: server.URL})
+
+		if err := n.Notify(context.Background(), NewPayload(statistics, 90)); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.deliveries) != 3 || r.deliveries[0].Header.Get(DeliveryHeader) != r.deliveries[2].Header.Get(DeliveryHeader) {
+			t.Errorf("expect the retries share the delivery, but get %d deliveries", len(r.deliveries))
+		}
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		failed := &receiver{failures: 3, status: http.StatusBadRequest}
+		failedServer := httptest.NewServer(failed)
+		defer failedServer.Close()
+		ok := &receiver{}
+		okServer := httptest.NewServer(ok)
+		defer okServer.Close()
+		n := newTestNotifier(t, nil, &Endpoint{URL: failedServer.URL}, &Endpoint{URL: okServer.URL})
+
+		err := n.Notify(context.Background(), NewPayload(statistics, 90))
+		if !errors.Is(err, ErrDeliveryFailed) {
+			t.Errorf("expect ErrDeliveryFailed, but get %v", err)
+		}
+		if len(failed.deliveries) != 1 || len(ok.deliveries) != 1 {
+			t.Errorf("expect a delivery for each endpoint, but get %d and %d", len(failed.deliveries), len(ok.deliveries))
+		}
+	})
+}
+
+func TestNewNotifier(t *testing.T) {
+	for _, u := range []string{"", "ftp://example.com", "example.com/hook"} {
+		if _, err := NewNotifier([]*Endpoint{{URL: u}}, nil, nil, logrus.New()); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("expect ErrInvalidURL for '%s', but get %v", u, err)
+		}
+	}
+}
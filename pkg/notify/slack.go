@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+const (
+	// SlackMessageEvent is the event of the Slack messages of the completed runs.
+	SlackMessageEvent = "slack.message"
+
+	// maxSlackSectionText is the length of the text of a section block that Slack accepts.
+	maxSlackSectionText = 3000
+)
+
+// DefaultSlackTemplate is the text/template of the Slack message, it renders the Summary into the mrkdwn text.
+const DefaultSlackTemplate = `{{ if .Passed }}:white_check_mark:{{ else }}:x:{{ end }} *{{ .Title }}*
+{{- if .Bypass }}
+Bypassed by {{ .Bypass.Source }}: {{ .Bypass.Reason }}
+{{- end }}
+{{- if .Violations }}
+
+*Failed rules*
+{{- range .Violations }}
+• {{ . }}
+{{- end }}
+{{- end }}
+{{- if .WorstFiles }}
+
+*Lowest coverage*
+{{- range .WorstFiles }}
+• ` + "`{{ .FileName }}`" + ` {{ printf "%.2f" .CoveragePercent }}% ({{ .CoveredLines }}/{{ .EffectiveLines }} lines)
+{{- end }}
+{{- end }}`
+
+// NewSlackReportGenerator creates a report generator that posts a Block Kit message of the statistics to the Slack incoming
+// webhooks of the notifier, the failed deliveries are logged rather than failing the command.
+func NewSlackReportGenerator(n *Notifier, o *SummaryOption) (report.ReportGenerator, error) {
+	return newSummaryReportGenerator(n, o, SlackMessageEvent, DefaultSlackTemplate, slackMessage)
+}
+
+// FormatSlackMessage returns the Block Kit message of the statistics: the rendered template as a section,
+// and a button that links to the full report if there is a report url. The title is the fallback text of the notifications.
+func FormatSlackMessage(statistics *report.Statistics, tmpl *template.Template, o *SummaryOption) ([]byte, error) {
+	return formatSummary(statistics, tmpl, o, slackMessage)
+}
+
+func slackMessage(summary *Summary, text string) ([]byte, error) {
+	if len(text) > maxSlackSectionText {
+		text = strings.ToValidUTF8(text[:maxSlackSectionText-len("…")], "") + "…"
+	}
+	blocks := []map[string]interface{}{
+		{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+	}
+	if summary.ReportURL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{
+				{"type": "button", "text": map[string]string{"type": "plain_text", "text": "View full report"}, "url": summary.ReportURL},
+			},
+		})
+	}
+	return json.Marshal(map[string]interface{}{"text": summary.Title, "blocks": blocks})
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func TestFormatSlackMessage(t *testing.T) {
+	tmpl := template.Must(template.New("slack").Parse(DefaultSlackTemplate))
+	statistics := summaryStatistics()
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "legacy"}
+	data, err := FormatSlackMessage(statistics, tmpl, &SummaryOption{CoverageBaseline: 80, ReportURL: "https://build/artifacts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+			Elements []struct {
+				URL string `json:"url"`
+			} `json:"elements"`
+		} `json:"blocks"`
+	}{}
+	if err := json.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "Diff coverage 60.00% of 20 lines (baseline 80.00%)" || len(message.Blocks) != 2 {
+		t.Fatalf("unexpected message %s", data)
+	}
+	for _, want := range []string{":white_check_mark: *Diff coverage", "Bypassed by label gocover-skip: legacy", "• `github.com/Azure/gocover/b.go` 25.00% (1/4 lines)"} {
+		if !strings.Contains(message.Blocks[0].Text.Text, want) {
+			t.Errorf("message should contain %q, but get %s", want, message.Blocks[0].Text.Text)
+		}
+	}
+	if message.Blocks[1].Type != "actions" || message.Blocks[1].Elements[0].URL != "https://build/artifacts" {
+		t.Errorf("unexpected link %s", data)
+	}
+
+	// the section is truncated to the limit of slack.
+	long := template.Must(template.New("slack").Parse(`{{ range .WorstFiles }}{{ printf "%4000s" .FileName }}{{ end }}`))
+	if data, err := FormatSlackMessage(summaryStatistics(), long, &SummaryOption{}); err != nil || strings.Contains(string(data), "actions") || len(data) > 3200 {
+		t.Errorf("expect the truncated message without link, but get %d bytes, %v", len(data), err)
+	}
+}
+
+func TestSlackReportGenerator(t *testing.T) {
+	if _, err := NewSlackReportGenerator(nil, &SummaryOption{Template: "{{ .Title "}); err == nil {
+		t.Error("expect an error of the broken template")
+	}
+
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	recorder := audit.NewRecorder("")
+	n := newTestNotifier(t, recorder, &Endpoint{URL: server.URL + "/services/T000/B000/secret", Name: "slack"})
+	g, err := NewSlackReportGenerator(n, &SummaryOption{Template: "{{ .Title }} {{ len .Statistics.CoverageProfile }} files"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.GenerateReport(summaryStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.bodies) != 1 || !strings.Contains(string(r.bodies[0]), "baseline 0.00%) 5 files") {
+		t.Errorf("unexpected deliveries %q", r.bodies)
+	}
+	if actions := recorder.Actions(); len(actions) != 1 || actions[0].Target != "slack" || actions[0].Details["event"] != SlackMessageEvent {
+		t.Errorf("expect the endpoint name rather than the url in the audit log, but get %+v", actions)
+	}
+}
@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// DefaultWorstFiles is the number of the files with the lowest coverage in a summary.
+const DefaultWorstFiles = 5
+
+// SummaryOption contains the input for the summary message report generators, such as the Slack and the Teams messages.
+type SummaryOption struct {
+	// Template is the text/template of the text of the message, the default template of the chat is used if it's empty.
+	Template string
+	// WorstFiles is the number of the files with the lowest coverage in the message, DefaultWorstFiles is used if it's zero,
+	// and no file is listed if it's negative.
+	WorstFiles int
+	// ReportURL is the url of the full report that the message links to, such as the artifacts of the build, the url of the
+	// uploaded report of the statistics is used if it's empty.
+	ReportURL string
+	// CoverageBaseline decides whether the run passes.
+	CoverageBaseline float64
+}
+
+// Summary is the data of the template of a summary message.
+type Summary struct {
+	// Title is the coverage of the run, such as "Diff coverage 72.50% of 40 lines (baseline 80.00%)".
+	Title  string
+	Passed bool
+	// Violations are the messages of the failed rules of the coverage gate.
+	Violations       []string
+	Bypass           *report.Bypass
+	CoveragePercent  float64
+	EffectiveLines   int
+	CoverageBaseline float64
+	// WorstFiles are the files with the lowest coverage, the files without effective lines are skipped.
+	WorstFiles []*SummaryFile
+	ReportURL  string
+	// Statistics is the whole result of the run for the custom templates.
+	Statistics *report.Statistics
+}
+
+// SummaryFile is the coverage of a file in a summary.
+type SummaryFile struct {
+	FileName        string
+	CoveragePercent float64
+	CoveredLines    int
+	EffectiveLines  int
+}
+
+// NewSummary returns the template data of the statistics.
+func NewSummary(statistics *report.Statistics, o *SummaryOption) *Summary {
+	what := "Diff"
+	if statistics.StatisticsType == report.FullStatisticsType {
+		what = "Full"
+	}
+	summary := &Summary{
+		Title: fmt.Sprintf("%s coverage %.2f%% of %d lines (baseline %.2f%%)",
+			what, statistics.TotalCoveragePercent, statistics.TotalEffectiveLines, o.CoverageBaseline),
+		Passed:           statistics.GatePassed(o.CoverageBaseline),
+		Violations:       statistics.GateViolations(),
+		Bypass:           statistics.Bypass,
+		CoveragePercent:  statistics.TotalCoveragePercent,
+		EffectiveLines:   statistics.TotalEffectiveLines,
+		CoverageBaseline: o.CoverageBaseline,
+		ReportURL:        o.ReportURL,
+		Statistics:       statistics,
+	}
+
+	if summary.ReportURL == "" {
+		summary.ReportURL = statistics.ReportURL
+	}
+
+	for _, p := range statistics.CoverageProfile {
+		if p.TotalEffectiveLines == 0 {
+			continue
+		}
+		covered := p.CoveredLines - p.CoveredButIgnoredLines
+		summary.WorstFiles = append(summary.WorstFiles, &SummaryFile{
+			FileName:        p.FileName,
+			CoveragePercent: float64(covered) * 100 / float64(p.TotalEffectiveLines),
+			CoveredLines:    covered,
+			EffectiveLines:  p.TotalEffectiveLines,
+		})
+	}
+	// the files of the same coverage are ordered by the uncovered lines.
+	sort.SliceStable(summary.WorstFiles, func(i, j int) bool {
+		a, b := summary.WorstFiles[i], summary.WorstFiles[j]
+		if a.CoveragePercent != b.CoveragePercent {
+			return a.CoveragePercent < b.CoveragePercent
+		}
+		return a.EffectiveLines-a.CoveredLines > b.EffectiveLines-b.CoveredLines
+	})
+	worst := o.WorstFiles
+	if worst == 0 {
+		worst = DefaultWorstFiles
+	}
+	switch {
+	case worst < 0:
+		summary.WorstFiles = nil
+	case len(summary.WorstFiles) > worst:
+		summary.WorstFiles = summary.WorstFiles[:worst]
+	}
+	// the perfectly covered files are not worth a mention.
+	for i, f := range summary.WorstFiles {
+		if f.CoveragePercent >= 100 {
+			summary.WorstFiles = summary.WorstFiles[:i]
+			break
+		}
+	}
+	return summary
+}
+
+// summaryFormatter returns the body of the message of the summary, the text is the rendered template.
+type summaryFormatter func(summary *Summary, text string) ([]byte, error)
+
+// newSummaryReportGenerator creates a report generator that delivers the formatted summary as the event,
+// the default template is used if the template of the option is empty.
+func newSummaryReportGenerator(n *Notifier, o *SummaryOption, event, defaultTemplate string, format summaryFormatter) (report.ReportGenerator, error) {
+	text := o.Template
+	if text == "" {
+		text = defaultTemplate
+	}
+	tmpl, err := template.New(event).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s template: %w", event, err)
+	}
+	return &summaryReportGenerator{notifier: n, option: o, event: event, template: tmpl, format: format}, nil
+}
+
+type summaryReportGenerator struct {
+	notifier *Notifier
+	option   *SummaryOption
+	event    string
+	template *template.Template
+	format   summaryFormatter
+}
+
+var _ report.ReportGenerator = (*summaryReportGenerator)(nil)
+
+// GenerateReport delivers the message of the statistics, the failed deliveries are logged rather than failing the command.
+func (g *summaryReportGenerator) GenerateReport(statistics *report.Statistics) error {
+	data, err := formatSummary(statistics, g.template, g.option, g.format)
+	if err != nil {
+		return err
+	}
+	_ = g.notifier.send(context.Background(), g.event, data)
+	return nil
+}
+
+// formatSummary renders the template of the summary of the statistics and formats the message.
+func formatSummary(statistics *report.Statistics, tmpl *template.Template, o *SummaryOption, format summaryFormatter) ([]byte, error) {
+	summary := NewSummary(statistics, o)
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, summary); err != nil {
+		return nil, fmt.Errorf("render %s message: %w", tmpl.Name(), err)
+	}
+	return format(summary, strings.TrimSpace(text.String()))
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func summaryStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		TotalCoveragePercent: 60,
+		TotalEffectiveLines:  20,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/gocover/a.go", TotalEffectiveLines: 10, CoveredLines: 8},
+			{FileName: "github.com/Azure/gocover/b.go", TotalEffectiveLines: 4, CoveredLines: 1},
+			{FileName: "github.com/Azure/gocover/c.go", TotalEffectiveLines: 2, CoveredLines: 2},
+			{FileName: "github.com/Azure/gocover/d.go", TotalEffectiveLines: 4, CoveredLines: 1},
+			{FileName: "github.com/Azure/gocover/e.go"},
+		},
+	}
+}
+
+func TestNewSummary(t *testing.T) {
+	summary := NewSummary(summaryStatistics(), &SummaryOption{CoverageBaseline: 80})
+	if summary.Passed || summary.Title != "Diff coverage 60.00% of 20 lines (baseline 80.00%)" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	var files []string
+	for _, f := range summary.WorstFiles {
+		files = append(files, f.FileName[len("github.com/Azure/gocover/"):])
+	}
+	// the fully covered file and the file without effective lines are not listed.
+	if strings.Join(files, ",") != "b.go,d.go,a.go" {
+		t.Errorf("unexpected worst files %v", files)
+	}
+
+	if summary := NewSummary(summaryStatistics(), &SummaryOption{WorstFiles: 1}); len(summary.WorstFiles) != 1 || !summary.Passed {
+		t.Errorf("expect 1 worst file of the passed run, but get %+v", summary)
+	}
+	if summary := NewSummary(summaryStatistics(), &SummaryOption{WorstFiles: -1}); len(summary.WorstFiles) != 0 {
+		t.Errorf("expect no worst file, but get %+v", summary.WorstFiles)
+	}
+
+	// the coverage meets the baseline, but the file gate fails.
+	statistics := summaryStatistics()
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, File: "github.com/Azure/gocover/b.go", Actual: 40, Limit: 60, Message: "the coverage of file github.com/Azure/gocover/b.go is 40.00, lower than the file baseline 60.00"}}}
+	if summary := NewSummary(statistics, &SummaryOption{CoverageBaseline: 50}); summary.Passed || len(summary.Violations) != 1 {
+		t.Errorf("expect the summary of the failed file gate, but get %+v", summary)
+	}
+	if payload := NewPayload(statistics, 50); payload.Passed {
+		t.Error("expect the payload of the failed file gate isn't passed")
+	}
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"encoding/json"
+	"text/template"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+const (
+	// TeamsMessageEvent is the event of the Microsoft Teams messages of the completed runs.
+	TeamsMessageEvent = "teams.message"
+
+	// adaptiveCardVersion is the version of the Adaptive Cards schema that the Teams incoming webhooks render.
+	adaptiveCardVersion = "1.4"
+)
+
+// DefaultTeamsTemplate is the text/template of the Teams message, it renders the Summary into the markdown text
+// under the title of the card.
+const DefaultTeamsTemplate = `{{ if .Bypass }}Bypassed by {{ .Bypass.Source }}: {{ .Bypass.Reason }}
+{{ end }}
+{{- if .Violations }}
+**Failed rules**
+{{ range .Violations }}
+- {{ . }}
+{{- end }}
+{{ end }}
+{{- if .WorstFiles }}
+**Lowest coverage**
+{{ range .WorstFiles }}
+- {{ .FileName }} {{ printf "%.2f" .CoveragePercent }}% ({{ .CoveredLines }}/{{ .EffectiveLines }} lines)
+{{- end }}
+{{- end }}`
+
+// NewTeamsReportGenerator creates a report generator that posts an Adaptive Card of the statistics to the Microsoft Teams
+// incoming webhooks or workflows of the notifier, the failed deliveries are logged rather than failing the command.
+func NewTeamsReportGenerator(n *Notifier, o *SummaryOption) (report.ReportGenerator, error) {
+	return newSummaryReportGenerator(n, o, TeamsMessageEvent, DefaultTeamsTemplate, teamsMessage)
+}
+
+// FormatTeamsMessage returns the message of the Adaptive Card of the statistics: the title colored by the gate,
+// the rendered template, and an action that opens the full report if there is a report url.
+func FormatTeamsMessage(statistics *report.Statistics, tmpl *template.Template, o *SummaryOption) ([]byte, error) {
+	return formatSummary(statistics, tmpl, o, teamsMessage)
+}
+
+func teamsMessage(summary *Summary, text string) ([]byte, error) {
+	color := "Good"
+	if !summary.Passed {
+		color = "Attention"
+	}
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": summary.Title, "size": "Medium", "weight": "Bolder", "color": color, "wrap": true},
+	}
+	if text != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": text, "wrap": true})
+	}
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": adaptiveCardVersion,
+		"body":    body,
+	}
+	if summary.ReportURL != "" {
+		card["actions"] = []map[string]string{{"type": "Action.OpenUrl", "title": "View full report", "url": summary.ReportURL}}
+	}
+	return json.Marshal(map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+type teamsCard struct {
+	Type        string `json:"type"`
+	Attachments []struct {
+		ContentType string `json:"contentType"`
+		Content     struct {
+			Type    string `json:"type"`
+			Version string `json:"version"`
+			Body    []struct {
+				Text  string `json:"text"`
+				Color string `json:"color"`
+			} `json:"body"`
+			Actions []struct {
+				Type string `json:"type"`
+				URL  string `json:"url"`
+			} `json:"actions"`
+		} `json:"content"`
+	} `json:"attachments"`
+}
+
+func TestFormatTeamsMessage(t *testing.T) {
+	tmpl := template.Must(template.New("teams").Parse(DefaultTeamsTemplate))
+	data, err := FormatTeamsMessage(summaryStatistics(), tmpl, &SummaryOption{CoverageBaseline: 80, ReportURL: "https://build/artifacts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &teamsCard{}
+	if err := json.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Type != "message" || len(message.Attachments) != 1 || message.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("unexpected message %s", data)
+	}
+	card := message.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || len(card.Body) != 2 || card.Body[0].Text != "Diff coverage 60.00% of 20 lines (baseline 80.00%)" || card.Body[0].Color != "Attention" {
+		t.Fatalf("unexpected card %s", data)
+	}
+	if !strings.HasPrefix(card.Body[1].Text, "**Lowest coverage**\n\n- github.com/Azure/gocover/b.go 25.00% (1/4 lines)\n") {
+		t.Errorf("unexpected text %q", card.Body[1].Text)
+	}
+	if len(card.Actions) != 1 || card.Actions[0].Type != "Action.OpenUrl" || card.Actions[0].URL != "https://build/artifacts" {
+		t.Errorf("unexpected actions %s", data)
+	}
+
+	// the card of a passed run without the files only has the title.
+	statistics := summaryStatistics()
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "legacy"}
+	data, err = FormatTeamsMessage(statistics, tmpl, &SummaryOption{CoverageBaseline: 80, WorstFiles: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message = &teamsCard{}
+	if err := json.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+	card = message.Attachments[0].Content
+	if len(card.Body) != 2 || card.Body[0].Color != "Good" || card.Body[1].Text != "Bypassed by label gocover-skip: legacy" || len(card.Actions) != 0 {
+		t.Errorf("unexpected card %s", data)
+	}
+}
+
+func TestTeamsReportGenerator(t *testing.T) {
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	n := newTestNotifier(t, nil, &Endpoint{URL: server.URL + "/webhookb2/secret", Name: "teams"})
+	g, err := NewTeamsReportGenerator(n, &SummaryOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.GenerateReport(summaryStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.deliveries) != 1 || r.deliveries[0].Header.Get(EventHeader) != TeamsMessageEvent || !strings.Contains(string(r.bodies[0]), "AdaptiveCard") {
+		t.Errorf("unexpected deliveries %q", r.bodies)
+	}
+}
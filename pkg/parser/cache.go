@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"go/build"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/annotation"
+	"golang.org/x/tools/cover"
+)
+
+// Cache keeps the parsed cover profiles, the packages of the profiles, and the annotations and the functions
+// of the source files across the parsers, so that a long-running process, such as the daemon, only parses
+// the files that changed since the last run. The profiles and the source files are parsed again when their size
+// or modification time changes, the packages are kept until the cache is reset. It's safe for concurrent use.
+type Cache struct {
+	mu          sync.Mutex
+	profiles    map[string]*cachedProfiles
+	packages    map[string]*build.Package
+	annotations map[annotationKey]*cachedAnnotation
+	functions   map[string]*cachedFunctions
+	stats       CacheStats
+}
+
+// CacheStats are the hits and the misses of a cache.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// fileStamp identifies the version of a file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+type cachedProfiles struct {
+	stamp    fileStamp
+	profiles []*cover.Profile
+}
+
+// annotationKey identifies the annotations of a source file, which depend on the blocks of its cover profile.
+// A profile is the same pointer as long as its cover profile file is cached.
+type annotationKey struct {
+	file    string
+	profile *cover.Profile
+}
+
+type cachedAnnotation struct {
+	stamp   fileStamp
+	profile *annotation.IgnoreProfile
+	err     error
+}
+
+type cachedFunctions struct {
+	stamp   fileStamp
+	extents []*FuncExtent
+	err     error
+}
+
+// NewCache creates an empty cache.
+func NewCache() *Cache {
+	c := &Cache{}
+	c.Reset()
+	return c
+}
+
+// Reset drops everything of the cache, such as after go.mod changes.
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.profiles = make(map[string]*cachedProfiles)
+	c.packages = make(map[string]*build.Package)
+	c.annotations = make(map[annotationKey]*cachedAnnotation)
+	c.functions = make(map[string]*cachedFunctions)
+	c.stats = CacheStats{}
+}
+
+// Stats returns the hits and the misses since the cache is created or reset.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Profiles returns the parsed cover profiles of the file.
+func (c *Cache) Profiles(fileName string) ([]*cover.Profile, error) {
+	stamp, err := stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.profiles[fileName]
+	c.mu.Unlock()
+	if ok && cached.stamp == stamp {
+		c.hit()
+		return cached.profiles, nil
+	}
+
+	c.miss()
+	profiles, err := cover.ParseProfiles(fileName)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.profiles[fileName] = &cachedProfiles{stamp: stamp, profiles: profiles}
+	c.mu.Unlock()
+	return profiles, nil
+}
+
+// ignoreProfile returns a copy of the ignore profile of the source file, so that the exemptions applied to it
+// don't change the cached one.
+func (c *Cache) ignoreProfile(fileName string, p *cover.Profile) (*annotation.IgnoreProfile, error) {
+	stamp, err := stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	key := annotationKey{file: fileName, profile: p}
+	c.mu.Lock()
+	cached, ok := c.annotations[key]
+	c.mu.Unlock()
+	if ok && cached.stamp == stamp {
+		c.hit()
+		return copyIgnoreProfile(cached.profile), cached.err
+	}
+
+	c.miss()
+	profile, err := annotation.ParseIgnoreProfiles(fileName, p)
+	c.mu.Lock()
+	c.annotations[key] = &cachedAnnotation{stamp: stamp, profile: profile, err: err}
+	c.mu.Unlock()
+	return copyIgnoreProfile(profile), err
+}
+
+// funcs returns the function extents of the source file, the extents are not modified by the parser.
+func (c *Cache) funcs(fileName string) ([]*FuncExtent, error) {
+	stamp, err := stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.functions[fileName]
+	c.mu.Unlock()
+	if ok && cached.stamp == stamp {
+		c.hit()
+		return cached.extents, cached.err
+	}
+
+	c.miss()
+	extents, err := findFuncs(fileName)
+	c.mu.Lock()
+	c.functions[fileName] = &cachedFunctions{stamp: stamp, extents: extents, err: err}
+	c.mu.Unlock()
+	return extents, err
+}
+
+// importPackage returns the package of the directory, which is found once.
+func (c *Cache) importPackage(dir string) (*build.Package, error) {
+	c.mu.Lock()
+	pkg, ok := c.packages[dir]
+	c.mu.Unlock()
+	if ok {
+		c.hit()
+		return pkg, nil
+	}
+
+	c.miss()
+	pkg, err := build.Import(dir, ".", build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.packages[dir] = pkg
+	c.mu.Unlock()
+	return pkg, nil
+}
+
+func (c *Cache) hit() {
+	c.mu.Lock()
+	c.stats.Hits++
+	c.mu.Unlock()
+}
+
+func (c *Cache) miss() {
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+}
+
+func stat(fileName string) (fileStamp, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+func copyIgnoreProfile(profile *annotation.IgnoreProfile) *annotation.IgnoreProfile {
+	if profile == nil {
+		return nil
+	}
+	result := *profile
+	if profile.IgnoreBlocks != nil {
+		result.IgnoreBlocks = make(map[cover.ProfileBlock]*annotation.IgnoreBlock, len(profile.IgnoreBlocks))
+		for b, ignore := range profile.IgnoreBlocks {
+			result.IgnoreBlocks[b] = ignore
+		}
+	}
+	return &result
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/annotation"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/tools/cover"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	parse := func() Packages {
+		packages, err := NewParser([]string{"testdata/cover.out"}, logrus.New()).WithCache(cache).Parse(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return packages
+	}
+
+	first := parse()
+	misses := cache.Stats().Misses
+	if misses == 0 || cache.Stats().Hits != 0 {
+		t.Fatalf("expect only misses of the first parse, but get %+v", cache.Stats())
+	}
+	second := parse()
+	if stats := cache.Stats(); stats.Misses != misses || stats.Hits != misses {
+		t.Errorf("expect every file is read from the cache, but get %+v", stats)
+	}
+	if len(first) != len(second) {
+		t.Fatalf("expect %d packages, but get %d", len(first), len(second))
+	}
+	for i := range first {
+		if len(first[i].Functions) != len(second[i].Functions) {
+			t.Errorf("%s: expect %d functions, but get %d", first[i].Name, len(first[i].Functions), len(second[i].Functions))
+		}
+	}
+
+	cache.Reset()
+	if stats := cache.Stats(); stats != (CacheStats{}) {
+		t.Errorf("expect empty stats after reset, but get %+v", stats)
+	}
+}
+
+func TestCacheProfiles(t *testing.T) {
+	data, err := os.ReadFile("testdata/cover.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "cover.out")
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCache()
+	first, err := cache.Profiles(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cache.Profiles(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first[0] != &second[0] || cache.Stats().Hits != 1 {
+		t.Errorf("expect the cached profiles, but get %+v", cache.Stats())
+	}
+
+	// the profiles are parsed again once the file changes.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(fileName, later, later); err != nil {
+		t.Fatal(err)
+	}
+	third, err := cache.Profiles(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third[0] == first[0] || cache.Stats().Misses != 2 {
+		t.Errorf("expect the profiles are parsed again, but get %+v", cache.Stats())
+	}
+}
+
+func TestCacheIgnoreProfile(t *testing.T) {
+	file := filepath.Join("testdata", "..", "parser.go")
+	p := &cover.Profile{FileName: "github.com/Azure/gocover/pkg/parser/parser.go", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 2}}}
+	cache := NewCache()
+
+	profile, err := cache.ignoreProfile(file, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exempted, err := annotation.ApplyExemptions(profile, file, p, []*annotation.Exemption{{Reason: "generated"}})
+	if err != nil || exempted.Type != annotation.FILE_IGNORE {
+		t.Fatalf("expect the file is exempted, but get %+v, %v", exempted, err)
+	}
+
+	cached, err := cache.ignoreProfile(file, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Type != annotation.BLOCK_IGNORE || cache.Stats().Hits != 1 {
+		t.Errorf("expect the cached profile is not changed by the exemptions, but get %+v", cached)
+	}
+}
@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LineMapping decides which changed lines make a multi-line statement changed.
+type LineMapping string
+
+const (
+	// SpanLineMapping changes a statement if any of its code lines is changed, so a wrapped statement
+	// counts whichever of its wrapped lines is changed, except the brace-only lines.
+	SpanLineMapping LineMapping = "span"
+	// FirstLineMapping attributes a statement to its first line, it's changed only if the first line is changed,
+	// so the counts don't depend on which wrapped line is changed, and rewrapping the arguments of a call doesn't count it.
+	FirstLineMapping LineMapping = "first-line"
+)
+
+var ErrUnknownLineMapping = errors.New("unknown line mapping")
+
+// Validate validates the line mapping, empty is span.
+func (m LineMapping) Validate() error {
+	switch m {
+	case "", SpanLineMapping, FirstLineMapping:
+		return nil
+	default:
+		return fmt.Errorf("%w '%s', one of: %s, %s", ErrUnknownLineMapping, m, SpanLineMapping, FirstLineMapping)
+	}
+}
+
+// OrDefault returns span if the line mapping is empty.
+func (m LineMapping) OrDefault() LineMapping {
+	if m == "" {
+		return SpanLineMapping
+	}
+	return m
+}
+
+// maps reports whether the changed line makes the statement changed.
+func (m LineMapping) maps(lineNumber int, stmt *statement) bool {
+	if m == FirstLineMapping {
+		return stmt.startLine == lineNumber
+	}
+	return lineNumberInStatement(lineNumber, stmt)
+}
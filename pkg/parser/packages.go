@@ -8,6 +8,7 @@ import (
 	"sort"
 
 	"github.com/Azure/gocover/pkg/annotation"
+	"golang.org/x/tools/cover"
 )
 
 type Package struct {
@@ -66,6 +67,9 @@ type Statement struct {
 
 	// Mode indicates whether current statement counts for coverage.
 	Mode Mode
+
+	// Block is the profile block that the statement matched, it's nil if no block matched.
+	Block *cover.ProfileBlock
 }
 
 // State represents statement's state.
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Azure/gocover/pkg/annotation"
 	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/phase"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/tools/cover"
 )
@@ -37,10 +38,82 @@ type Parser struct {
 	packagesCache     packagesCache
 	coverProfileFiles []string
 	coverProfiles     []*cover.Profile
+	// recorder records the time of parsing the annotations, it's optional.
+	recorder *phase.Recorder
+	// strict returns the first parse error rather than recording it and continuing.
+	strict bool
+	// lineMapping decides which changed lines make a multi-line statement changed.
+	lineMapping LineMapping
+	// ignoreProviders supply the exemptions besides the ignore annotations in the files.
+	ignoreProviders []annotation.IgnoreProvider
+	// cache keeps the parsed files across the parsers, it's optional.
+	cache       *Cache
+	parseErrors []*ParseError
+	// unmatchedChanges are the changes that match no cover profile.
+	unmatchedChanges []*gittool.Change
 
 	logger logrus.FieldLogger
 }
 
+// ParseError is a file that failed to parse.
+type ParseError struct {
+	// FileName is the file name in the cover profile.
+	FileName string
+	// Err is the parse error.
+	Err error
+	// Skipped indicates the file is not counted for coverage, otherwise it's counted without the ignore annotations.
+	Skipped bool
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse %s: %s", e.FileName, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// WithRecorder sets the recorder that records the annotate phase.
+func (parser *Parser) WithRecorder(recorder *phase.Recorder) *Parser {
+	parser.recorder = recorder
+	return parser
+}
+
+// WithStrict sets whether a file that fails to parse aborts the parsing.
+func (parser *Parser) WithStrict(strict bool) *Parser {
+	parser.strict = strict
+	return parser
+}
+
+// WithLineMapping sets which changed lines make a multi-line statement changed, span is used if it's empty.
+func (parser *Parser) WithLineMapping(m LineMapping) *Parser {
+	parser.lineMapping = m
+	return parser
+}
+
+// WithIgnoreProviders sets the providers of the exemptions that are applied besides the ignore annotations.
+func (parser *Parser) WithIgnoreProviders(providers ...annotation.IgnoreProvider) *Parser {
+	parser.ignoreProviders = providers
+	return parser
+}
+
+// WithCache sets the cache that the cover profiles, the packages, and the annotations and the functions
+// of the source files are read from, the files are parsed by each parser if it's nil.
+func (parser *Parser) WithCache(cache *Cache) *Parser {
+	parser.cache = cache
+	return parser
+}
+
+// ParseErrors returns the files that failed to parse, it's always empty in strict mode.
+func (parser *Parser) ParseErrors() []*ParseError {
+	return parser.parseErrors
+}
+
+// UnmatchedChanges returns the changes that match no cover profile, it's empty if no changes are parsed.
+func (parser *Parser) UnmatchedChanges() []*gittool.Change {
+	return parser.unmatchedChanges
+}
+
 // Parse parses cover profiles into statements, and modify their state based on git changes.
 func (parser *Parser) Parse(changes []*gittool.Change) (Packages, error) {
 	if err := parser.filterCoverProfiles(changes); err != nil {
@@ -72,9 +145,10 @@ func (parser *Parser) Parse(changes []*gittool.Change) (Packages, error) {
 // If changes is nil, all cover profiles will be kept.
 // If changes is not nil, only cover profiles that are changed will be kept.
 func (parser *Parser) filterCoverProfiles(changes []*gittool.Change) error {
+	matched := make(map[*gittool.Change]bool)
 
 	for _, coverProfile := range parser.coverProfileFiles {
-		profiles, err := cover.ParseProfiles(coverProfile)
+		profiles, err := parser.parseProfiles(coverProfile)
 		if err != nil {
 			return err
 		}
@@ -85,12 +159,18 @@ func (parser *Parser) filterCoverProfiles(changes []*gittool.Change) error {
 		}
 
 		for _, p := range profiles {
-			if findChange(p, changes) != nil {
+			if change := findChange(p, changes); change != nil {
+				matched[change] = true
 				parser.coverProfiles = append(parser.coverProfiles, p)
 			}
 		}
 	}
 
+	for _, change := range changes {
+		if !matched[change] {
+			parser.unmatchedChanges = append(parser.unmatchedChanges, change)
+		}
+	}
 	return nil
 }
 
@@ -104,7 +184,7 @@ func (parser *Parser) buildPackageCache() error {
 		}
 		_, ok := parser.packagesCache[dir]
 		if !ok {
-			pkg, err := build.Import(dir, ".", build.FindOnly)
+			pkg, err := parser.importPackage(dir)
 			if err != nil {
 				return err
 			}
@@ -116,6 +196,29 @@ func (parser *Parser) buildPackageCache() error {
 	return nil
 }
 
+// applyExemptions applies the exemptions of the ignore providers to the ignore profile of the file.
+// A provider that fails is recorded as a parse error like an invalid annotation, the file is counted without its exemptions.
+func (parser *Parser) applyExemptions(ignoreProfile *annotation.IgnoreProfile, file string, p *cover.Profile) (*annotation.IgnoreProfile, error) {
+	for _, provider := range parser.ignoreProviders {
+		exemptions, err := provider.Exemptions(p.FileName)
+		if err == nil {
+			var exempted *annotation.IgnoreProfile
+			if exempted, err = annotation.ApplyExemptions(ignoreProfile, file, p, exemptions); err == nil {
+				ignoreProfile = exempted
+			}
+		}
+		if err != nil {
+			if parser.strict {
+				parser.logger.WithError(err).Error("apply exemptions")
+				return nil, err
+			}
+			parser.logger.WithError(err).Warnf("apply exemptions of %s, count it without exemptions", p.FileName)
+			parser.parseErrors = append(parser.parseErrors, &ParseError{FileName: p.FileName, Err: err})
+		}
+	}
+	return ignoreProfile, nil
+}
+
 // wrapper for Statement
 type statement struct {
 	*Statement
@@ -136,9 +239,21 @@ func (parser *Parser) convertProfile(p *cover.Profile, change *gittool.Change) e
 		parser.packages[pkgpath] = pkg
 	}
 
-	ignoreProfile, err := annotation.ParseIgnoreProfiles(file, p)
+	stopAnnotate := parser.recorder.Start(phase.AnnotatePhase)
+	ignoreProfile, err := parser.parseIgnoreProfile(file, p)
+	stopAnnotate()
+	if err != nil {
+		if parser.strict {
+			parser.logger.WithError(err).Error("parse ignore profile")
+			return err
+		}
+		// the file is still counted, but without the ignore annotations.
+		parser.logger.WithError(err).Warnf("parse ignore profile of %s, count it without annotations", p.FileName)
+		parser.parseErrors = append(parser.parseErrors, &ParseError{FileName: p.FileName, Err: err})
+		ignoreProfile = nil
+	}
+	ignoreProfile, err = parser.applyExemptions(ignoreProfile, file, p)
 	if err != nil {
-		parser.logger.WithError(err).Error("parse ignore profile")
 		return err
 	}
 	if ignoreProfile != nil {
@@ -155,10 +270,16 @@ func (parser *Parser) convertProfile(p *cover.Profile, change *gittool.Change) e
 	// Functions and Statements, and keep a separate
 	// slice of Statements so we can match them with profile
 	// blocks.
-	extents, err := findFuncs(file)
+	extents, err := parser.findFuncs(file)
 	if err != nil {
-		parser.logger.WithError(err).Error("find Functions")
-		return err
+		if parser.strict {
+			parser.logger.WithError(err).Error("find Functions")
+			return err
+		}
+		// no statement can be found in an unparseable file, skip it.
+		parser.logger.WithError(err).Warnf("find functions of %s, skip it", p.FileName)
+		parser.parseErrors = append(parser.parseErrors, &ParseError{FileName: p.FileName, Err: err, Skipped: true})
+		return nil
 	}
 	var stmts []*statement
 	for _, fe := range extents {
@@ -204,6 +325,8 @@ func (parser *Parser) convertProfile(p *cover.Profile, change *gittool.Change) e
 			}
 
 			s.Reached += int64(b.Count)
+			block := b
+			s.Block = &block
 
 			if ignoreProfile != nil {
 				if ignoreProfile.Type == annotation.FILE_IGNORE {
@@ -226,6 +349,34 @@ func (parser *Parser) convertProfile(p *cover.Profile, change *gittool.Change) e
 	return nil
 }
 
+func (parser *Parser) parseProfiles(fileName string) ([]*cover.Profile, error) {
+	if parser.cache != nil {
+		return parser.cache.Profiles(fileName)
+	}
+	return cover.ParseProfiles(fileName)
+}
+
+func (parser *Parser) importPackage(dir string) (*build.Package, error) {
+	if parser.cache != nil {
+		return parser.cache.importPackage(dir)
+	}
+	return build.Import(dir, ".", build.FindOnly)
+}
+
+func (parser *Parser) parseIgnoreProfile(fileName string, p *cover.Profile) (*annotation.IgnoreProfile, error) {
+	if parser.cache != nil {
+		return parser.cache.ignoreProfile(fileName, p)
+	}
+	return annotation.ParseIgnoreProfiles(fileName, p)
+}
+
+func (parser *Parser) findFuncs(fileName string) ([]*FuncExtent, error) {
+	if parser.cache != nil {
+		return parser.cache.funcs(fileName)
+	}
+	return findFuncs(fileName)
+}
+
 // findFile finds the location of the named file in GOROOT, GOPATH etc.
 func findFile(packages packagesCache, file string) (filename, pkgpath string, err error) {
 	dir, file := filepath.Split(file)
@@ -446,9 +597,44 @@ func (parser *Parser) setStatementsState(change *gittool.Change, statements []*s
 	}
 }
 
-func isCodeLine(line string) bool {
+// LineClass classifies a source line by its contents.
+type LineClass int
+
+const (
+	// CodeLine contains code that may start or belong to a statement.
+	CodeLine LineClass = iota
+	// BlankLine contains whitespaces only.
+	BlankLine
+	// CommentLine contains a line comment only.
+	CommentLine
+	// BraceLine contains brackets and separators only, such as `}`, `})` or `},`, with an optional trailing comment.
+	BraceLine
+)
+
+// ClassifyLine returns the class of the source line.
+func ClassifyLine(line string) LineClass {
 	trimmed := strings.TrimSpace(line)
-	return trimmed != "" && !strings.HasPrefix(trimmed, "//")
+	switch {
+	case trimmed == "":
+		return BlankLine
+	case strings.HasPrefix(trimmed, "//"):
+		return CommentLine
+	}
+
+	if i := strings.Index(trimmed, "//"); i > 0 {
+		trimmed = strings.TrimSpace(trimmed[:i])
+	}
+	if strings.Trim(trimmed, "{}()[],;") == "" {
+		return BraceLine
+	}
+	return CodeLine
+}
+
+// isCodeLine reports whether the changed line should change the state of its statement.
+// Blank, comment and brace-only lines are excluded, so that a new file, which has all lines changed,
+// counts the same statements as a modified file whose changes are the code lines only.
+func isCodeLine(line string) bool {
+	return ClassifyLine(line) == CodeLine
 }
 
 // setStatementsStateByLineNumber sets statements' State field based on code line number.
@@ -472,7 +658,7 @@ func (parser *Parser) setStatementsStateByLineNumber(changedlineNumber int, stat
 	idx--
 	stmt := statements[idx]
 
-	if stmt != nil && lineNumberInStatement(changedlineNumber, stmt) {
+	if stmt != nil && parser.lineMapping.maps(changedlineNumber, stmt) {
 		stmt.State = Changed
 		parser.logger.Debugf(
 			"for changed line number %d, set statement [%d:%d] to %s",
@@ -1,8 +1,13 @@
 package parser
 
 import (
+	"errors"
+	"go/build"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/Azure/gocover/pkg/annotation"
 	"github.com/Azure/gocover/pkg/gittool"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -89,6 +94,9 @@ func TestParser(t *testing.T) {
 				{
 					FileName: "pkg/gocover/executor.go",
 				},
+				{
+					FileName: "pkg/notexist/notexist.go",
+				},
 			}
 
 			err := parser.filterCoverProfiles(changes)
@@ -102,11 +110,38 @@ func TestParser(t *testing.T) {
 			for _, profile := range parser.coverProfiles {
 				assert.Contains(t, expected, profile.FileName)
 			}
+
+			unmatched := parser.UnmatchedChanges()
+			assert.Len(t, unmatched, 1)
+			assert.Equal(t, "pkg/notexist/notexist.go", unmatched[0].FileName)
 		})
 	})
 
 }
 
+func TestClassifyLine(t *testing.T) {
+	testSuites := []struct {
+		line   string
+		expect LineClass
+	}{
+		{line: "", expect: BlankLine},
+		{line: " \t ", expect: BlankLine},
+		{line: "  // comment", expect: CommentLine},
+		{line: "}", expect: BraceLine},
+		{line: "\t})", expect: BraceLine},
+		{line: "}, // end", expect: BraceLine},
+		{line: ")", expect: BraceLine},
+		{line: "} else {", expect: CodeLine},
+		{line: "return err }", expect: CodeLine},
+		{line: `fmt.Println("//")`, expect: CodeLine},
+	}
+	for _, testcase := range testSuites {
+		if class := ClassifyLine(testcase.line); class != testcase.expect {
+			t.Errorf("line %q should be class %d, but get %d", testcase.line, testcase.expect, class)
+		}
+	}
+}
+
 func TestSetStatementsState(t *testing.T) {
 	t.Run("change is nil", func(t *testing.T) {
 		parser := &Parser{}
@@ -149,6 +184,11 @@ func TestSetStatementsState(t *testing.T) {
 					EndLine:   14,
 					Contents:  []string{"// increase i", "i++"},
 				},
+				{
+					StartLine: 15,
+					EndLine:   15,
+					Contents:  []string{"} // end for"},
+				},
 				{
 					StartLine: 17,
 					EndLine:   17,
@@ -201,7 +241,7 @@ func TestSetStatementsState(t *testing.T) {
 		// 12     fmt.Println(i)
 		//*13     // increase i
 		//*14     i++
-		// 15 }
+		//*15 } // end for  // brace-only line doesn't change the statement
 		// 16
 		//*17 if err != nil { return err }
 		//*18 if err != nil { return err
@@ -307,4 +347,133 @@ func TestSetStatementsState(t *testing.T) {
 		}
 
 	})
+
+	t.Run("wrapped statements", func(t *testing.T) {
+		// 1  err := call(a,
+		//*2     b)
+		//*3  return wrap(
+		// 4     err,
+		// 5  )
+		change := &gittool.Change{
+			FileName: "foo.go",
+			Sections: []*gittool.Section{
+				{StartLine: 2, EndLine: 3, Contents: []string{"	b)", "return wrap("}},
+			},
+		}
+		testCases := map[LineMapping][]State{
+			"":               {Changed, Changed},
+			SpanLineMapping:  {Changed, Changed},
+			FirstLineMapping: {Original, Changed},
+		}
+		for mapping, states := range testCases {
+			parser := (&Parser{logger: logrus.New()}).WithLineMapping(mapping)
+			statements := []*statement{
+				{Statement: &Statement{State: Original}, StmtExtent: &StmtExtent{startLine: 1, endLine: 2}},
+				{Statement: &Statement{State: Original}, StmtExtent: &StmtExtent{startLine: 3, endLine: 5}},
+			}
+			parser.setStatementsState(change, statements)
+			for i, state := range states {
+				if statements[i].State != state {
+					t.Errorf("expect statement %d state %s with %q mapping, but get %s", i+1, state, mapping, statements[i].State)
+				}
+			}
+		}
+	})
+}
+
+func TestLineMappingValidate(t *testing.T) {
+	for _, m := range []LineMapping{"", SpanLineMapping, FirstLineMapping} {
+		if err := m.Validate(); err != nil {
+			t.Errorf("expect %q is valid, but get %s", m, err)
+		}
+	}
+	if err := LineMapping("last-line").Validate(); !errors.Is(err, ErrUnknownLineMapping) {
+		t.Errorf("expect ErrUnknownLineMapping, but get %v", err)
+	}
+}
+
+func TestConvertProfileParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"annotation.go": "package foo\n\nfunc Foo() int {\n\treturn 1 //+gocover:ignore:block\n}\n",
+		"syntax.go":     "package foo\n\nfunc Bar( {\n",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profiles := []*cover.Profile{
+		{
+			FileName: "example.com/foo/annotation.go",
+			Blocks:   []cover.ProfileBlock{{StartLine: 3, StartCol: 16, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0}},
+		},
+		{FileName: "example.com/foo/syntax.go"},
+	}
+
+	newParser := func(strict bool) *Parser {
+		parser := NewParser(nil, logrus.New()).WithStrict(strict)
+		parser.packagesCache["example.com/foo"] = &build.Package{Dir: dir, ImportPath: "example.com/foo"}
+		return parser
+	}
+
+	t.Run("record the errors and continue", func(t *testing.T) {
+		parser := newParser(false)
+		for _, p := range profiles {
+			assert.NoError(t, parser.convertProfile(p, nil))
+		}
+
+		parseErrors := parser.ParseErrors()
+		assert.Len(t, parseErrors, 2)
+		assert.Equal(t, "example.com/foo/annotation.go", parseErrors[0].FileName)
+		assert.ErrorIs(t, parseErrors[0], annotation.ErrCommentsRequired)
+		assert.False(t, parseErrors[0].Skipped)
+		assert.Equal(t, "example.com/foo/syntax.go", parseErrors[1].FileName)
+		assert.True(t, parseErrors[1].Skipped)
+
+		// the file with the wrong annotation is still counted.
+		functions := parser.packages["example.com/foo"].Functions
+		assert.Len(t, functions, 1)
+		assert.Equal(t, Keep, functions[0].Statements[0].Mode)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		for _, p := range profiles {
+			parser := newParser(true)
+			assert.Error(t, parser.convertProfile(p, nil))
+			assert.Empty(t, parser.ParseErrors())
+		}
+	})
+}
+
+func TestConvertProfileExemptions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foo.go"), []byte("package foo\n\nfunc Foo() int {\n\treturn 1\n}\n\nfunc Bar() int {\n\treturn 2\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile := &cover.Profile{
+		FileName: "example.com/foo/foo.go",
+		Blocks: []cover.ProfileBlock{
+			{StartLine: 3, StartCol: 16, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+			{StartLine: 7, StartCol: 16, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 0},
+		},
+	}
+	provider := annotation.NewStaticIgnoreProvider([]*annotation.Exemption{
+		{FileName: "example.com/foo/foo.go", StartLine: 7, EndLine: 9, Reason: "approved", Source: "exemptions"},
+	})
+
+	parser := NewParser(nil, logrus.New()).WithIgnoreProviders(provider)
+	parser.packagesCache["example.com/foo"] = &build.Package{Dir: dir, ImportPath: "example.com/foo"}
+	assert.NoError(t, parser.convertProfile(profile, nil))
+
+	pkg := parser.packages["example.com/foo"]
+	assert.Len(t, pkg.IgnoreProfiles, 1)
+	ignoreBlock := pkg.IgnoreProfiles[0].IgnoreBlocks[profile.Blocks[1]]
+	if assert.NotNil(t, ignoreBlock) {
+		assert.Equal(t, "exemption from exemptions", ignoreBlock.Annotation)
+		assert.Equal(t, "approved", ignoreBlock.Comments)
+		assert.Equal(t, []int{7, 8, 9}, ignoreBlock.Lines)
+	}
+	assert.Equal(t, Keep, pkg.Functions[0].Statements[0].Mode)
+	assert.Equal(t, Ignore, pkg.Functions[1].Statements[0].Mode)
 }
@@ -0,0 +1,3 @@
+// Package phase records the wall time, CPU time and peak RSS of the pipeline phases,
+// so performance regressions in large repositories can be tracked over time.
+package phase
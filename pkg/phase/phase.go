@@ -0,0 +1,145 @@
+package phase
+
+import (
+	"sync"
+	"time"
+)
+
+// The phases of the coverage pipeline.
+const (
+	DiffPhase     = "diff"
+	ParsePhase    = "parse"
+	AnnotatePhase = "annotate"
+	ComputePhase  = "compute"
+	ReportPhase   = "report"
+	// RegressionPhase calculates the full coverage of the compared branch and HEAD.
+	RegressionPhase = "regression"
+)
+
+// Stats is the resource usage of a phase.
+type Stats struct {
+	// Name is the name of the phase.
+	Name string
+	// WallTimeMs is the elapsed time of the phase in milliseconds.
+	WallTimeMs float64
+	// CPUTimeMs is the user and system CPU time of the process spent in the phase in milliseconds.
+	CPUTimeMs float64
+	// PeakRSSBytes is the peak resident set size of the process when the phase ends,
+	// it's zero if the platform doesn't support it.
+	PeakRSSBytes int64
+}
+
+// usage is the resource usage of the process.
+type usage struct {
+	cpu     time.Duration
+	peakRSS int64
+}
+
+// active is a started phase.
+type active struct {
+	stats     *Stats
+	start     time.Time
+	startCPU  time.Duration
+	childWall time.Duration
+	childCPU  time.Duration
+}
+
+// Recorder records the phases in the order they start. A phase that starts more than once accumulates,
+// and the time of a nested phase is excluded from the outer phase, for example, annotating happens
+// during parsing and it's not counted twice. All methods are no-op on a nil recorder.
+type Recorder struct {
+	mu     sync.Mutex
+	phases []*Stats
+	names  map[string]*Stats
+	stack  []*active
+	now    func() time.Time
+	usage  func() usage
+}
+
+// NewRecorder creates a recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		names: make(map[string]*Stats),
+		now:   time.Now,
+		usage: readUsage,
+	}
+}
+
+// Start starts the phase, and returns the function that stops it.
+func (r *Recorder) Start(name string) (stop func()) {
+	if r == nil {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.names[name]
+	if !ok {
+		stats = &Stats{Name: name}
+		r.names[name] = stats
+		r.phases = append(r.phases, stats)
+	}
+	a := &active{stats: stats, start: r.now(), startCPU: r.usage().cpu}
+	r.stack = append(r.stack, a)
+
+	var once sync.Once
+	return func() { once.Do(func() { r.stop(a) }) }
+}
+
+func (r *Recorder) stop(a *active) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.usage()
+	wall := r.now().Sub(a.start)
+	cpu := u.cpu - a.startCPU
+
+	a.stats.WallTimeMs += milliseconds(wall - a.childWall)
+	a.stats.CPUTimeMs += milliseconds(cpu - a.childCPU)
+	if u.peakRSS > a.stats.PeakRSSBytes {
+		a.stats.PeakRSSBytes = u.peakRSS
+	}
+
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		if r.stack[i] != a {
+			continue
+		}
+		r.stack = append(r.stack[:i], r.stack[i+1:]...)
+		if i > 0 {
+			parent := r.stack[i-1]
+			parent.childWall += wall
+			parent.childCPU += cpu
+		}
+		break
+	}
+}
+
+// Phases returns a copy of the recorded phases, the phases not stopped yet are not included.
+func (r *Recorder) Phases() []*Stats {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	running := make(map[*Stats]bool)
+	for _, a := range r.stack {
+		running[a.stats] = true
+	}
+
+	var result []*Stats
+	for _, s := range r.phases {
+		if running[s] && s.WallTimeMs == 0 {
+			continue
+		}
+		stats := *s
+		result = append(result, &stats)
+	}
+	return result
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,94 @@
+package phase
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeRecorder returns a recorder whose clock and CPU time advance by one millisecond,
+// and peak RSS by one byte, on each reading.
+func fakeRecorder() *Recorder {
+	r := NewRecorder()
+	var ticks int64
+	r.now = func() time.Time {
+		ticks++
+		return time.Unix(0, 0).Add(time.Duration(ticks) * time.Millisecond)
+	}
+	var cpu int64
+	r.usage = func() usage {
+		cpu++
+		return usage{cpu: time.Duration(cpu) * time.Millisecond, peakRSS: cpu}
+	}
+	return r
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("nested phase is excluded from the outer phase", func(t *testing.T) {
+		r := fakeRecorder()
+		stopParse := r.Start(ParsePhase)       // now 1, cpu 1
+		stopAnnotate := r.Start(AnnotatePhase) // now 2, cpu 2
+		stopAnnotate()                         // now 3, cpu 3
+		stopParse()                            // now 4, cpu 4
+
+		phases := r.Phases()
+		if len(phases) != 2 || phases[0].Name != ParsePhase || phases[1].Name != AnnotatePhase {
+			t.Fatalf("unexpected phases %+v", phases)
+		}
+		if phases[0].WallTimeMs != 2 || phases[0].CPUTimeMs != 2 || phases[0].PeakRSSBytes != 4 {
+			t.Errorf("unexpected parse phase %+v", phases[0])
+		}
+		if phases[1].WallTimeMs != 1 || phases[1].CPUTimeMs != 1 || phases[1].PeakRSSBytes != 3 {
+			t.Errorf("unexpected annotate phase %+v", phases[1])
+		}
+	})
+
+	t.Run("repeated phase accumulates", func(t *testing.T) {
+		r := fakeRecorder()
+		r.Start(ComputePhase)()
+		r.Start(ComputePhase)()
+
+		phases := r.Phases()
+		if len(phases) != 1 || phases[0].WallTimeMs != 2 || phases[0].CPUTimeMs != 2 {
+			t.Errorf("unexpected phases %+v", phases)
+		}
+	})
+
+	t.Run("running phase is not returned", func(t *testing.T) {
+		r := fakeRecorder()
+		r.Start(DiffPhase)()
+		stop := r.Start(ReportPhase)
+		if phases := r.Phases(); len(phases) != 1 || phases[0].Name != DiffPhase {
+			t.Errorf("unexpected phases %+v", phases)
+		}
+
+		stop()
+		stop()
+		if phases := r.Phases(); len(phases) != 2 || phases[1].WallTimeMs != 1 {
+			t.Errorf("stop should take effect once, but get %+v", phases)
+		}
+	})
+
+	t.Run("phases are copied", func(t *testing.T) {
+		r := fakeRecorder()
+		r.Start(DiffPhase)()
+		r.Phases()[0].WallTimeMs = 100
+		if phases := r.Phases(); phases[0].WallTimeMs != 1 {
+			t.Errorf("phases should be copied, but get %+v", phases[0])
+		}
+	})
+
+	t.Run("nil recorder", func(t *testing.T) {
+		var r *Recorder
+		r.Start(DiffPhase)()
+		if phases := r.Phases(); phases != nil {
+			t.Errorf("expect no phases, but get %+v", phases)
+		}
+	})
+}
+
+func TestReadUsage(t *testing.T) {
+	u := readUsage()
+	if u.cpu < 0 || u.peakRSS < 0 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package phase
+
+// readUsage returns zero usage, the resource usage is not supported on the platform.
+func readUsage() usage {
+	return usage{}
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package phase
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+func readUsage() usage {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return usage{}
+	}
+
+	peakRSS := int64(ru.Maxrss)
+	// ru_maxrss is in bytes on darwin, and in kilobytes on the other unix systems.
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		peakRSS *= 1024
+	}
+	return usage{
+		cpu:     time.Duration(ru.Utime.Nano() + ru.Stime.Nano()),
+		peakRSS: peakRSS,
+	}
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package phase
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	psapi                    = syscall.NewLazyDLL("psapi.dll")
+	procGetProcessMemoryInfo = psapi.NewProc("GetProcessMemoryInfo")
+)
+
+// processMemoryCounters is PROCESS_MEMORY_COUNTERS of psapi.
+type processMemoryCounters struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+}
+
+func readUsage() usage {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return usage{}
+	}
+
+	var result usage
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(process, &creation, &exit, &kernel, &user); err == nil {
+		// the process times are in 100-nanosecond units rather than the time since epoch.
+		result.cpu = time.Duration((filetimeTicks(kernel) + filetimeTicks(user)) * 100)
+	}
+
+	var counters processMemoryCounters
+	counters.cb = uint32(unsafe.Sizeof(counters))
+	if err := procGetProcessMemoryInfo.Find(); err == nil {
+		r, _, _ := procGetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb))
+		if r != 0 {
+			result.peakRSS = int64(counters.peakWorkingSetSize)
+		}
+	}
+	return result
+}
+
+func filetimeTicks(ft syscall.Filetime) int64 {
+	return int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime)
+}
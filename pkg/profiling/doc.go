@@ -0,0 +1,3 @@
+// Package profiling enables the runtime profiling of gocover itself, it serves the pprof endpoints
+// and writes the execution trace, so users can file actionable performance reports for huge repositories.
+package profiling
@@ -0,0 +1,112 @@
+package profiling
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime/trace"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Option contains the input for profiling gocover.
+type Option struct {
+	// PprofAddr is the address that the pprof endpoints are served on, such as localhost:6060,
+	// disabled if it's empty.
+	PprofAddr string
+	// TraceFile is the file that the execution trace is written into, disabled if it's empty.
+	TraceFile string
+}
+
+// Profiler is the started profiling, all methods are no-op on a nil profiler.
+type Profiler struct {
+	listener  net.Listener
+	server    *http.Server
+	traceFile *os.File
+	logger    logrus.FieldLogger
+}
+
+// Start starts the profiling according to the option, it returns nil if nothing is enabled.
+func Start(o *Option, logger logrus.FieldLogger) (*Profiler, error) {
+	if o == nil || (o.PprofAddr == "" && o.TraceFile == "") {
+		return nil, nil
+	}
+
+	p := &Profiler{logger: logger}
+	if o.TraceFile != "" {
+		f, err := os.Create(o.TraceFile)
+		if err != nil {
+			return nil, fmt.Errorf("create trace file: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("start trace: %w", err)
+		}
+		p.traceFile = f
+		logger.Infof("writing execution trace to %s", o.TraceFile)
+	}
+
+	if o.PprofAddr != "" {
+		// listen before serving, so an invalid or occupied address fails the command.
+		listener, err := net.Listen("tcp", o.PprofAddr)
+		if err != nil {
+			p.Stop()
+			return nil, fmt.Errorf("listen pprof address: %w", err)
+		}
+		p.listener = listener
+		p.server = &http.Server{Handler: newPprofHandler()}
+		go func() {
+			if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Warnf("serve pprof: %s", err)
+			}
+		}()
+		logger.Infof("serving pprof on http://%s/debug/pprof/", listener.Addr())
+	}
+	return p, nil
+}
+
+// Addr returns the address that the pprof endpoints are served on, it's nil if pprof is disabled.
+func (p *Profiler) Addr() net.Addr {
+	if p == nil || p.listener == nil {
+		return nil
+	}
+	return p.listener.Addr()
+}
+
+// Stop stops serving the pprof endpoints, and flushes the execution trace.
+func (p *Profiler) Stop() error {
+	if p == nil {
+		return nil
+	}
+
+	var errs []error
+	if p.server != nil {
+		if err := p.server.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close pprof server: %w", err))
+		}
+		p.server = nil
+	}
+	if p.traceFile != nil {
+		trace.Stop()
+		if err := p.traceFile.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close trace file: %w", err))
+		}
+		p.traceFile = nil
+	}
+	return errors.Join(errs...)
+}
+
+// newPprofHandler registers the pprof endpoints on a dedicated mux,
+// so nothing else registered on the default mux is exposed.
+func newPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
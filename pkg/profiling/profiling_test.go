@@ -0,0 +1,69 @@
+package profiling
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStart(t *testing.T) {
+	t.Run("nothing enabled", func(t *testing.T) {
+		p, err := Start(&Option{}, logrus.New())
+		if err != nil || p != nil {
+			t.Fatalf("expect nil profiler, but get %v, %v", p, err)
+		}
+		if p.Addr() != nil || p.Stop() != nil {
+			t.Error("nil profiler should be no-op")
+		}
+	})
+
+	t.Run("serve pprof and write trace", func(t *testing.T) {
+		traceFile := filepath.Join(t.TempDir(), "trace.out")
+		p, err := Start(&Option{PprofAddr: "127.0.0.1:0", TraceFile: traceFile}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Get("http://" + p.Addr().String() + "/debug/pprof/cmdline")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expect status 200, but get %d", resp.StatusCode)
+		}
+
+		if err := p.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		// stop twice is a no-op.
+		if err := p.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(traceFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == 0 {
+			t.Error("trace file should not be empty")
+		}
+	})
+
+	t.Run("invalid pprof address", func(t *testing.T) {
+		traceFile := filepath.Join(t.TempDir(), "trace.out")
+		if _, err := Start(&Option{PprofAddr: "127.0.0.1:-1", TraceFile: traceFile}, logrus.New()); err == nil {
+			t.Fatal("should return error")
+		}
+		// the trace started before is stopped, so it can be started again.
+		p, err := Start(&Option{TraceFile: traceFile}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Stop()
+	})
+}
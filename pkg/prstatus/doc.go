@@ -0,0 +1,3 @@
+// Package prstatus publishes the coverage of a run to a pull request, as a status that a branch policy can require
+// and a comment with the coverage of each file, for the SCMs whose pull requests have statuses, such as Azure DevOps.
+package prstatus
@@ -0,0 +1,114 @@
+package prstatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultName is the name of the pull request status.
+	DefaultName = "coverage"
+
+	// publishTimeout bounds the time of setting the status and posting the comment.
+	publishTimeout = time.Minute
+)
+
+// Option contains the input for the pull request status report generator.
+type Option struct {
+	// Name is the name of the status, DefaultName is used if it's empty.
+	Name string
+	// Owner is the owner of the repository, it's the project of an Azure DevOps repository.
+	Owner string
+	// Repository is the name of the repository.
+	Repository string
+	// Number is the number of the pull request.
+	Number int
+	// HeadSHA is the commit that the coverage is calculated on, it's shown in the comment.
+	HeadSHA string
+	// TargetURL is the url of the details of the status, such as the build, it's optional.
+	TargetURL string
+	// CoverageBaseline decides the state of the status.
+	CoverageBaseline float64
+	// NoComment only sets the status.
+	NoComment bool
+}
+
+// NewReportGenerator creates a report generator that sets the status of the pull request and posts the coverage comment,
+// the failed publishing is logged rather than failing the command, as the other reports are written.
+func NewReportGenerator(client scm.Client, statuses scm.StatusPublisher, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{client: client, statuses: statuses, option: o, logger: logger.WithField("source", "PullRequestStatus")}
+}
+
+type reportGenerator struct {
+	client   scm.Client
+	statuses scm.StatusPublisher
+	option   *Option
+	logger   logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport sets the status and posts the comment of the statistics.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	pr := &scm.PullRequest{Owner: g.option.Owner, Repository: g.option.Repository, Number: g.option.Number, HeadSHA: g.option.HeadSHA}
+	target := fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repository, pr.Number)
+
+	status := NewStatus(statistics, g.option)
+	if id, err := g.statuses.SetStatus(ctx, pr, status); err != nil {
+		g.logger.WithError(err).Errorf("set status %s of %s", status.Name, target)
+	} else {
+		g.logger.Infof("set status %s of %s to %s: %s", status.Name, target, status.State, id)
+	}
+
+	if g.option.NoComment {
+		return nil
+	}
+	body := report.FormatComment(statistics, &report.CommentOption{
+		Verbosity:        report.SummaryVerbosity,
+		HeadSHA:          g.option.HeadSHA,
+		CoverageBaseline: g.option.CoverageBaseline,
+	})
+	if id, err := g.client.PostComment(ctx, pr, body); err != nil {
+		g.logger.WithError(err).Errorf("post comment of %s", target)
+	} else {
+		g.logger.Infof("post comment of %s: %s", target, id)
+	}
+	return nil
+}
+
+// NewStatus returns the status of the statistics, it fails if the coverage is lower than the baseline
+// and the gate is not bypassed.
+func NewStatus(statistics *report.Statistics, o *Option) *scm.PullRequestStatus {
+	name := o.Name
+	if name == "" {
+		name = DefaultName
+	}
+
+	what := "Diff"
+	if statistics.StatisticsType == report.FullStatisticsType {
+		what = "Full"
+	}
+	status := &scm.PullRequestStatus{
+		Name:      name,
+		State:     scm.SucceededStatusState,
+		TargetURL: o.TargetURL,
+		Description: fmt.Sprintf("%s coverage %.2f%% of %d lines (baseline %.2f%%)",
+			what, statistics.TotalCoveragePercent, statistics.TotalEffectiveLines, o.CoverageBaseline),
+	}
+	switch {
+	case !statistics.GateFailed(o.CoverageBaseline):
+	case statistics.Bypass != nil:
+		status.Description += fmt.Sprintf(", bypassed by %s", statistics.Bypass.Source)
+	default:
+		status.State = scm.FailedStatusState
+	}
+	return status
+}
@@ -0,0 +1,98 @@
+package prstatus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+type mockClient struct {
+	scm.Client
+	comments []string
+	statuses []*scm.PullRequestStatus
+	err      error
+}
+
+func (c *mockClient) PostComment(ctx context.Context, pr *scm.PullRequest, body string) (string, error) {
+	c.comments = append(c.comments, body)
+	return "1", nil
+}
+
+func (c *mockClient) SetStatus(ctx context.Context, pr *scm.PullRequest, status *scm.PullRequestStatus) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	c.statuses = append(c.statuses, status)
+	return "2", nil
+}
+
+func testStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		ComparedBranch:       "origin/main",
+		TotalCoveragePercent: 50,
+		TotalEffectiveLines:  4,
+		CoverageProfile: []*report.CoverageProfile{
+			{FileName: "github.com/Azure/modulea/foo/foo.go", TotalEffectiveLines: 4, CoveredLines: 2, TotalViolationLines: []int{5, 9}},
+		},
+	}
+}
+
+func TestNewStatus(t *testing.T) {
+	o := &Option{CoverageBaseline: 80, TargetURL: "https://dev.azure.com/org/project/_build/results?buildId=1"}
+	status := NewStatus(testStatistics(), o)
+	if status.Name != DefaultName || status.State != scm.FailedStatusState || status.Description != "Diff coverage 50.00% of 4 lines (baseline 80.00%)" || status.TargetURL != o.TargetURL {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	statistics := testStatistics()
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip", Reason: "legacy"}
+	if status := NewStatus(statistics, o); status.State != scm.SucceededStatusState || !strings.HasSuffix(status.Description, "bypassed by label gocover-skip") {
+		t.Errorf("expect succeeded status of the bypassed gate, but get %+v", status)
+	}
+
+	o.CoverageBaseline = 50
+	if status := NewStatus(testStatistics(), o); status.State != scm.SucceededStatusState {
+		t.Errorf("expect succeeded status, but get %s", status.State)
+	}
+
+	// the coverage meets the baseline, but the file gate fails.
+	statistics = testStatistics()
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, File: "github.com/Azure/modulea/foo/foo.go", Actual: 40, Limit: 60, Message: "the coverage of file github.com/Azure/modulea/foo/foo.go is 40.00, lower than the file baseline 60.00"}}}
+	if status := NewStatus(statistics, o); status.State != scm.FailedStatusState {
+		t.Errorf("expect failed status of the failed file gate, but get %s", status.State)
+	}
+}
+
+func TestReportGenerator(t *testing.T) {
+	client := &mockClient{}
+	g := NewReportGenerator(client, client, &Option{Owner: "project", Repository: "modulea", Number: 12, CoverageBaseline: 80}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.statuses) != 1 || client.statuses[0].State != scm.FailedStatusState {
+		t.Errorf("unexpected statuses %+v", client.statuses)
+	}
+	if len(client.comments) != 1 || !strings.Contains(client.comments[0], "| github.com/Azure/modulea/foo/foo.go | 50.00 | 2 | 4 | 0 |") {
+		t.Errorf("the comment should have the coverage of the files, but get %v", client.comments)
+	}
+
+	// the failed status is logged rather than failing the command, and the comment is still posted.
+	client = &mockClient{err: errors.New("unauthorized")}
+	g = NewReportGenerator(client, client, &Option{Owner: "project", Repository: "modulea", Number: 12}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil || len(client.comments) != 1 {
+		t.Errorf("expect the comment and no error, but get %v", err)
+	}
+
+	client = &mockClient{}
+	g = NewReportGenerator(client, client, &Option{Owner: "project", Repository: "modulea", Number: 12, NoComment: true}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil || len(client.statuses) != 1 || len(client.comments) != 0 {
+		t.Errorf("expect only the status, but get %+v", client)
+	}
+}
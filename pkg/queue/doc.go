@@ -0,0 +1,4 @@
+// Package queue keeps the submissions of the webhook server in a durable queue,
+// so that the analyses are processed asynchronously by the workers, the bursts of pull request pushes
+// don't time out the webhooks, and the submissions survive restarts of the server.
+package queue
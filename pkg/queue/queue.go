@@ -0,0 +1,374 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+)
+
+var (
+	ErrItemNotFound = errors.New("queue item not found")
+	ErrNotRunning   = errors.New("queue item is not running")
+)
+
+// State is the state of a queue item.
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+)
+
+// Item is a submission in the queue.
+type Item struct {
+	// ID identifies the item, it's generated when the item is enqueued.
+	ID string
+	// Tenant is the name of the tenant that the submission belongs to.
+	Tenant string
+	// Owner is the owner of the repository.
+	Owner string
+	// Repository is the name of the repository.
+	Repository string
+	// Number is the pull request number.
+	Number int
+	// Sender is the user who submitted the commands.
+	Sender string `json:",omitempty"`
+	// SenderAssociation is the author association of the sender with the repository, such as MEMBER.
+	SenderAssociation string `json:",omitempty"`
+	// Body is the comment that contains the commands.
+	Body string
+	// State is the state of the item.
+	State State
+	// Error is the failure of the item, it's set if the state is failed.
+	Error string `json:",omitempty"`
+	// Attempts is the number of times that the item is dequeued, an item interrupted by a restart is attempted again.
+	Attempts int
+	// EnqueuedAt is the time when the item is enqueued.
+	EnqueuedAt time.Time
+	// StartedAt is the time when the latest attempt starts.
+	StartedAt *time.Time `json:",omitempty"`
+	// FinishedAt is the time when the item finishes.
+	FinishedAt *time.Time `json:",omitempty"`
+}
+
+// Finished reports whether the item is finished.
+func (i *Item) Finished() bool {
+	return i.State == Succeeded || i.State == Failed
+}
+
+// Filter selects the items, the empty fields match all the items.
+type Filter struct {
+	Tenant     string
+	Owner      string
+	Repository string
+	Number     int
+	State      State
+	// Limit is the maximum number of the items returned, all items are returned if it's zero.
+	Limit int
+}
+
+// Match reports whether the item is selected by the filter.
+func (f *Filter) Match(item *Item) bool {
+	switch {
+	case f.Tenant != "" && f.Tenant != item.Tenant:
+		return false
+	case f.Owner != "" && !strings.EqualFold(f.Owner, item.Owner):
+		return false
+	case f.Repository != "" && !strings.EqualFold(f.Repository, item.Repository):
+		return false
+	case f.Number != 0 && f.Number != item.Number:
+		return false
+	case f.State != "" && f.State != item.State:
+		return false
+	}
+	return true
+}
+
+// Queue is a first in first out queue of the submissions, which is safe for concurrent use by the workers.
+type Queue interface {
+	// Enqueue adds the item as queued, the ID and the enqueue time are filled.
+	Enqueue(item *Item) error
+	// Dequeue marks the earliest queued item as running and returns it, it blocks until an item is queued or the context is done.
+	Dequeue(ctx context.Context) (*Item, error)
+	// Finish marks the running item as succeeded, or failed with the error.
+	Finish(id string, err error) error
+	// Get returns the item of the id, ErrItemNotFound is returned if there is no such item.
+	Get(id string) (*Item, error)
+	// List returns the items selected by the filter, the latest item comes first.
+	List(filter *Filter) ([]*Item, error)
+	// Prune removes the items finished before the time and returns the number of the removed items.
+	Prune(before time.Time) (int, error)
+}
+
+// NewMemoryQueue creates a queue that keeps the items in memory, the items are lost when the process exits.
+func NewMemoryQueue() Queue {
+	return newQueue("")
+}
+
+// NewFileQueue creates a queue that saves each item as a JSON file in the directory.
+// The items in the directory are loaded, and the running items, which are interrupted by a restart, are queued again.
+func NewFileQueue(dir string) (Queue, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create queue directory: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	q := newQueue(dir)
+	for _, file := range files {
+		item, err := readItem(file)
+		if err != nil {
+			return nil, err
+		}
+		if item.State == Running {
+			item.State = Queued
+			if err := q.save(item); err != nil {
+				return nil, err
+			}
+		}
+		q.items[item.ID] = item
+		if item.EnqueuedAt.After(q.last) {
+			q.last = item.EnqueuedAt
+		}
+	}
+	q.signal()
+	return q, nil
+}
+
+func newQueue(dir string) *queue {
+	return &queue{dir: dir, items: make(map[string]*Item), ready: make(chan struct{}, 1)}
+}
+
+// queue keeps all the items in memory, and saves each change into the directory if it's set.
+type queue struct {
+	dir string
+
+	mu    sync.Mutex
+	items map[string]*Item
+	// last is the latest enqueue time.
+	last time.Time
+	// ready wakes up a waiting worker when there may be queued items.
+	ready chan struct{}
+}
+
+var _ Queue = (*queue)(nil)
+
+func (q *queue) Enqueue(item *Item) error {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("generate item id: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// the enqueue times are strictly increasing, so the items keep the order even if they're enqueued at the same time.
+	now := time.Now().UTC()
+	if !now.After(q.last) {
+		now = q.last.Add(time.Nanosecond)
+	}
+	q.last = now
+
+	item.EnqueuedAt = now
+	item.ID = fmt.Sprintf("%d-%s", now.Unix(), hex.EncodeToString(b))
+	item.State = Queued
+	stored := *item
+	if err := q.save(&stored); err != nil {
+		return err
+	}
+	q.items[stored.ID] = &stored
+	q.signal()
+	return nil
+}
+
+func (q *queue) Dequeue(ctx context.Context) (*Item, error) {
+	for {
+		item, err := q.next()
+		if err != nil || item != nil {
+			return item, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.ready:
+		}
+	}
+}
+
+// next marks the earliest queued item as running, it returns nil if there is no queued item.
+func (q *queue) next() (*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued := q.selectItems(&Filter{State: Queued})
+	if len(queued) == 0 {
+		return nil, nil
+	}
+	earliest := *queued[len(queued)-1]
+	now := time.Now().UTC()
+	earliest.State = Running
+	earliest.Attempts++
+	earliest.StartedAt = &now
+	if err := q.save(&earliest); err != nil {
+		return nil, err
+	}
+	q.items[earliest.ID] = &earliest
+	if len(queued) > 1 {
+		// wakes up another worker for the rest of the items.
+		q.signal()
+	}
+	result := earliest
+	return &result, nil
+}
+
+func (q *queue) Finish(id string, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	current, ok := q.items[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrItemNotFound, id)
+	}
+	if current.State != Running {
+		return fmt.Errorf("%w: %s is %s", ErrNotRunning, id, current.State)
+	}
+	item := *current
+	now := time.Now().UTC()
+	item.FinishedAt = &now
+	item.State = Succeeded
+	if err != nil {
+		item.State = Failed
+		item.Error = err.Error()
+	}
+	if err := q.save(&item); err != nil {
+		return err
+	}
+	q.items[id] = &item
+	return nil
+}
+
+func (q *queue) Get(id string) (*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item, ok := q.items[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrItemNotFound, id)
+	}
+	result := *item
+	return &result, nil
+}
+
+func (q *queue) List(filter *Filter) ([]*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := q.selectItems(filter)
+	result := make([]*Item, 0, len(items))
+	for _, item := range items {
+		copied := *item
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (q *queue) Prune(before time.Time) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pruned := 0
+	for id, item := range q.items {
+		if !item.Finished() || item.FinishedAt == nil || !item.FinishedAt.Before(before) {
+			continue
+		}
+		if q.dir != "" {
+			if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return pruned, fmt.Errorf("remove item %s: %w", id, err)
+			}
+		}
+		delete(q.items, id)
+		pruned++
+	}
+	return pruned, nil
+}
+
+// selectItems returns the items selected by the filter, the latest item comes first. The lock must be held.
+func (q *queue) selectItems(filter *Filter) []*Item {
+	if filter == nil {
+		filter = &Filter{}
+	}
+
+	var result []*Item
+	for _, item := range q.items {
+		if filter.Match(item) {
+			result = append(result, item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].EnqueuedAt.Equal(result[j].EnqueuedAt) {
+			return result[i].EnqueuedAt.After(result[j].EnqueuedAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
+
+// signal wakes up a waiting worker without blocking.
+func (q *queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// save saves the item into the directory, it does nothing for a memory queue.
+func (q *queue) save(item *Item) error {
+	if q.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("marshal item: %w", err)
+	}
+	err = atomicfile.WriteFile(q.path(item.ID), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("save item %s: %w", item.ID, err)
+	}
+	return nil
+}
+
+func (q *queue) path(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+func readItem(file string) (*Item, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, fmt.Errorf("decode item %s: %w", file, err)
+	}
+	return item, nil
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testQueues(t *testing.T) map[string]Queue {
+	fileQueue, err := NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Queue{"memory": NewMemoryQueue(), "file": fileQueue}
+}
+
+func TestQueue(t *testing.T) {
+	for name, q := range testQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			items := []*Item{
+				{Tenant: "a", Owner: "Azure", Repository: "gocover", Number: 1, Body: "/gocover rerun"},
+				{Tenant: "a", Owner: "Azure", Repository: "gocover", Number: 2, Body: "/gocover report full"},
+				{Tenant: "b", Owner: "Azure", Repository: "other", Number: 1, Body: "/gocover rerun"},
+			}
+			for _, item := range items {
+				if err := q.Enqueue(item); err != nil {
+					t.Fatal(err)
+				}
+				if item.ID == "" || item.State != Queued {
+					t.Fatalf("expect a queued item with id, but get %+v", item)
+				}
+			}
+
+			ctx := context.Background()
+			first, err := q.Dequeue(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.ID != items[0].ID || first.State != Running || first.Attempts != 1 || first.StartedAt == nil {
+				t.Errorf("expect the earliest item is running, but get %+v", first)
+			}
+			if err := q.Finish(first.ID, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := q.Finish(first.ID, nil); !errors.Is(err, ErrNotRunning) {
+				t.Errorf("expect ErrNotRunning, but get %v", err)
+			}
+
+			second, _ := q.Dequeue(ctx)
+			if err := q.Finish(second.ID, errors.New("clone failed")); err != nil {
+				t.Fatal(err)
+			}
+			got, err := q.Get(second.ID)
+			if err != nil || got.State != Failed || got.Error != "clone failed" || got.FinishedAt == nil {
+				t.Errorf("unexpected failed item %+v, %v", got, err)
+			}
+			if _, err := q.Get("missing"); !errors.Is(err, ErrItemNotFound) {
+				t.Errorf("expect ErrItemNotFound, but get %v", err)
+			}
+
+			testSuites := []struct {
+				name   string
+				filter *Filter
+				expect []string
+			}{
+				{name: "all, latest first", filter: nil, expect: []string{items[2].ID, items[1].ID, items[0].ID}},
+				{name: "tenant", filter: &Filter{Tenant: "a"}, expect: []string{items[1].ID, items[0].ID}},
+				{name: "state", filter: &Filter{State: Queued}, expect: []string{items[2].ID}},
+				{name: "pull request", filter: &Filter{Owner: "azure", Repository: "GOCOVER", Number: 2}, expect: []string{items[1].ID}},
+				{name: "limit", filter: &Filter{Limit: 1}, expect: []string{items[2].ID}},
+			}
+			for _, ts := range testSuites {
+				t.Run(ts.name, func(t *testing.T) {
+					list, err := q.List(ts.filter)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var ids []string
+					for _, item := range list {
+						ids = append(ids, item.ID)
+					}
+					if len(ids) != len(ts.expect) {
+						t.Fatalf("expect %v, but get %v", ts.expect, ids)
+					}
+					for i := range ids {
+						if ids[i] != ts.expect[i] {
+							t.Errorf("expect %v, but get %v", ts.expect, ids)
+						}
+					}
+				})
+			}
+
+			pruned, err := q.Prune(time.Now().Add(time.Minute))
+			if err != nil || pruned != 2 {
+				t.Errorf("expect the finished items are pruned, but get %d, %v", pruned, err)
+			}
+			if list, _ := q.List(nil); len(list) != 1 || list[0].ID != items[2].ID {
+				t.Errorf("expect the queued item is kept, but get %v", list)
+			}
+		})
+	}
+}
+
+func TestDequeueBlocks(t *testing.T) {
+	q := NewMemoryQueue()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect the deadline is exceeded, but get %v", err)
+	}
+
+	result := make(chan *Item)
+	for i := 0; i < 2; i++ {
+		go func() {
+			item, err := q.Dequeue(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			result <- item
+		}()
+	}
+	// both waiting workers are woken up, even if the items are enqueued before either of them wakes up.
+	for i := 0; i < 2; i++ {
+		if err := q.Enqueue(&Item{Owner: "Azure", Repository: "gocover", Number: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-result:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expect the items are dequeued")
+		}
+	}
+}
+
+func TestFileQueueRecovers(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupted := &Item{Owner: "Azure", Repository: "gocover", Number: 1}
+	waiting := &Item{Owner: "Azure", Repository: "gocover", Number: 2}
+	q.Enqueue(interrupted)
+	q.Enqueue(waiting)
+	if _, err := q.Dequeue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the queue is opened again as the server restarts.
+	q, err = NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != interrupted.ID || item.Attempts != 2 {
+		t.Errorf("expect the interrupted item is attempted again, but get %+v", item)
+	}
+	if item, _ := q.Get(waiting.ID); item.State != Queued {
+		t.Errorf("expect the waiting item is still queued, but get %s", item.State)
+	}
+}
@@ -0,0 +1,3 @@
+// Package redact scrubs credentials, such as tokens and PATs, from logs, error messages and artifacts,
+// so that they won't be leaked accidentally into CI logs.
+package redact
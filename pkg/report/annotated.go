@@ -0,0 +1,330 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultContextLines is the number of the unchanged lines shown around the changed lines of a diff report.
+const DefaultContextLines = 3
+
+// The states of a line in the annotated report.
+const (
+	coveredLineState   = "covered"
+	uncoveredLineState = "uncovered"
+	ignoredLineState   = "ignored"
+)
+
+// AnnotatedReportOption contains the input for the annotated html report generator.
+type AnnotatedReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// Style is the code style of the report.
+	Style string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module that the source files are read from.
+	ModuleDir string
+	// ContextLines is the number of the unchanged lines shown around the changed lines of a diff report,
+	// the whole file is shown in a full report.
+	ContextLines int
+	// Theme is the theme of the report page, the light theme is used if it's nil.
+	Theme *Theme
+}
+
+// annotatedReportGenerator renders each file with the state of every counted line.
+type annotatedReportGenerator struct {
+	option *AnnotatedReportOption
+	lexer  chroma.Lexer
+	style  *chroma.Style
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*annotatedReportGenerator)(nil)
+
+// NewAnnotatedReportGenerator creates a report generator that writes a single page html report,
+// which renders the source of each file and highlights the covered, uncovered and ignored lines.
+// A diff report only shows the changed lines and the context around them, and a full report shows the whole files.
+// If the source of a file can't be found, the uncovered sections in the statistics are shown instead.
+func NewAnnotatedReportGenerator(o *AnnotatedReportOption, logger logrus.FieldLogger) ReportGenerator {
+	style := styles.Get(o.Style)
+	if style == nil {
+		style = styles.Fallback
+	}
+	lexer := lexers.Get(CodeLanguage)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return &annotatedReportGenerator{option: o, lexer: lexer, style: style, logger: logger}
+}
+
+// annotatedReport is the data of the annotated report template.
+type annotatedReport struct {
+	*Statistics
+	// CSS is the style of the highlighted code.
+	CSS   template.CSS
+	Theme *Theme
+	Files []*annotatedFile
+	// Back is the link to the page that lists the files, such as the tree of the report server, it's empty in a static report.
+	Back string
+}
+
+// annotatedFile is a file in the annotated report.
+type annotatedFile struct {
+	Profile *CoverageProfile
+	// Anchor is the id of the file in the page.
+	Anchor string
+	// Missing indicates the source is not found, only the uncovered sections are shown.
+	Missing bool
+	// Diff indicates the hunks are a unified diff, which has the added and the deleted lines.
+	Diff bool
+	// Hunks are the shown parts of the file.
+	Hunks [][]*annotatedLine
+}
+
+// annotatedLine is a source line in the annotated report.
+type annotatedLine struct {
+	Number int
+	// State is the state of the statement starts at the line, it's empty if no counted statement starts at the line.
+	State string
+	Code  template.HTML
+	// Added indicates the line is added by the diff.
+	Added bool
+	// Deleted indicates the line is deleted by the diff, it's not in the file so it has no number.
+	Deleted bool
+}
+
+// GenerateReport renders the files of the statistics and writes the report.
+func (g *annotatedReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, finalName(g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return g.render(w, statistics, statistics.CoverageProfile, "")
+	})
+	if err != nil {
+		return fmt.Errorf("write annotated report: %w", err)
+	}
+
+	g.logger.Infof("generate annotated html coverage report: %s", reportFile)
+	return nil
+}
+
+// render renders the profiles of the statistics into a page, with the link back to the list of the files if it's not empty.
+func (g *annotatedReportGenerator) render(w io.Writer, statistics *Statistics, profiles []*CoverageProfile, back string) error {
+	formatter := html.New(html.WithClasses(true), html.PreventSurroundingPre(true))
+	var css bytes.Buffer
+	if err := formatter.WriteCSS(&css, g.style); err != nil {
+		return fmt.Errorf("write code style: %w", err)
+	}
+
+	data := &annotatedReport{Statistics: statistics, CSS: template.CSS(css.String()), Theme: g.option.Theme.orDefault(), Back: back}
+	for i, profile := range profiles {
+		file, err := g.annotateFile(formatter, profile, statistics.StatisticsType == FullStatisticsType)
+		if err != nil {
+			return fmt.Errorf("annotate %s: %w", profile.FileName, err)
+		}
+		file.Anchor = fmt.Sprintf("file-%d", i)
+		data.Files = append(data.Files, file)
+	}
+	return htmlAnnotatedReportTemplate.Execute(w, data)
+}
+
+// annotateFile renders the whole file or the hunks around the counted lines,
+// the lines of the uncovered sections are rendered if the source is not found.
+func (g *annotatedReportGenerator) annotateFile(formatter *html.Formatter, profile *CoverageProfile, whole bool) (*annotatedFile, error) {
+	states := lineStates(profile)
+	file := &annotatedFile{Profile: profile}
+
+	source, err := os.ReadFile(g.sourcePath(profile.FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		g.logger.Warnf("source of %s not found, only the uncovered sections are annotated", profile.FileName)
+		file.Missing = true
+		for _, section := range profile.ViolationSections {
+			lines, err := g.highlight(formatter, strings.Join(section.Contents, "\n"), section.StartLine, states)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, lines)
+		}
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := g.highlight(formatter, string(source), 1, states)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case whole:
+		file.Hunks = [][]*annotatedLine{lines}
+	case len(profile.DiffSections) != 0:
+		file.Diff = true
+		file.Hunks, err = g.diffHunks(formatter, lines, states, profile.DiffSections)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		file.Hunks = g.hunks(lines, states)
+	}
+	return file, nil
+}
+
+// hunks returns the counted lines with the context around them, the adjacent lines are merged into a hunk.
+func (g *annotatedReportGenerator) hunks(lines []*annotatedLine, states map[int]string) [][]*annotatedLine {
+	shown := make([]bool, len(lines))
+	for line := range states {
+		for i := line - 1 - g.option.ContextLines; i <= line-1+g.option.ContextLines; i++ {
+			if i >= 0 && i < len(lines) {
+				shown[i] = true
+			}
+		}
+	}
+
+	var hunks [][]*annotatedLine
+	var hunk []*annotatedLine
+	for i, line := range lines {
+		if shown[i] {
+			hunk = append(hunk, line)
+			continue
+		}
+		if hunk != nil {
+			hunks = append(hunks, hunk)
+			hunk = nil
+		}
+	}
+	if hunk != nil {
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// diffHunks returns the added and the deleted lines of the diff sections, and the counted lines, with the context around them.
+// The deleted lines are inserted before the line that they were before, so the hunks read like a unified diff.
+func (g *annotatedReportGenerator) diffHunks(formatter *html.Formatter, lines []*annotatedLine, states map[int]string, sections []*DiffSection) ([][]*annotatedLine, error) {
+	shown := make([]bool, len(lines))
+	show := func(from, to int) {
+		for i := from; i <= to; i++ {
+			if i >= 1 && i <= len(lines) {
+				shown[i-1] = true
+			}
+		}
+	}
+	for line := range states {
+		show(line-g.option.ContextLines, line+g.option.ContextLines)
+	}
+
+	deleted := make(map[int][]*annotatedLine)
+	for _, section := range sections {
+		if !section.Deleted {
+			show(section.StartLine-g.option.ContextLines, section.EndLine+g.option.ContextLines)
+			for i := section.StartLine; i <= section.EndLine && i <= len(lines); i++ {
+				lines[i-1].Added = true
+			}
+			continue
+		}
+		show(section.StartLine-g.option.ContextLines, section.StartLine+g.option.ContextLines-1)
+		highlighted, err := g.highlight(formatter, strings.Join(section.Contents, "\n"), 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range highlighted {
+			line.Number, line.Deleted = 0, true
+		}
+		deleted[section.StartLine] = append(deleted[section.StartLine], highlighted...)
+	}
+
+	var hunks [][]*annotatedLine
+	var hunk []*annotatedLine
+	for number := 1; number <= len(lines)+1; number++ {
+		hunk = append(hunk, deleted[number]...)
+		if number > len(lines) {
+			break
+		}
+		if shown[number-1] {
+			hunk = append(hunk, lines[number-1])
+			continue
+		}
+		if hunk != nil {
+			hunks = append(hunks, hunk)
+			hunk = nil
+		}
+	}
+	if hunk != nil {
+		hunks = append(hunks, hunk)
+	}
+	return hunks, nil
+}
+
+// highlight highlights the code and annotates each line with its state, the first line of the code is the start line.
+func (g *annotatedReportGenerator) highlight(formatter *html.Formatter, code string, startLine int, states map[int]string) ([]*annotatedLine, error) {
+	iter, err := g.lexer.Tokenise(nil, code)
+	if err != nil {
+		return nil, fmt.Errorf("tokenise failed: %w", err)
+	}
+
+	var lines []*annotatedLine
+	for i, tokens := range chroma.SplitTokensIntoLines(iter.Tokens()) {
+		var buf bytes.Buffer
+		if err := formatter.Format(&buf, g.style, chroma.Literator(tokens...)); err != nil {
+			return nil, fmt.Errorf("format line: %w", err)
+		}
+		number := startLine + i
+		lines = append(lines, &annotatedLine{
+			Number: number,
+			State:  states[number],
+			Code:   template.HTML(strings.TrimSuffix(buf.String(), "\n")),
+		})
+	}
+	return lines, nil
+}
+
+// sourcePath returns the path of the source file in the module directory.
+func (g *annotatedReportGenerator) sourcePath(fileName string) string {
+	relative := strings.TrimPrefix(strings.TrimPrefix(fileName, g.option.ModulePath), "/")
+	return filepath.Join(g.option.ModuleDir, filepath.FromSlash(relative))
+}
+
+// lineStates returns the state of each counted line, the violation lines are uncovered
+// in case the counted lines are not kept, such as the statistics of an old json report.
+func lineStates(profile *CoverageProfile) map[int]string {
+	states := make(map[int]string)
+	for _, line := range profile.TotalViolationLines {
+		states[line] = uncoveredLineState
+	}
+	for _, line := range profile.CountedLines {
+		switch {
+		case line.Ignored:
+			states[line.Line] = ignoredLineState
+		case line.Covered:
+			states[line.Line] = coveredLineState
+		default:
+			states[line.Line] = uncoveredLineState
+		}
+	}
+	return states
+}
+
+// htmlAnnotatedReportTemplate is the render engine for annotated html coverage report.
+var htmlAnnotatedReportTemplate = template.Must(
+	template.New("htmlAnnotatedReportTemplate").
+		Funcs(template.FuncMap{"PercentCovered": percentCovered}).
+		Funcs(template.FuncMap{"IsFullCoverageReport": isFullCoverageReport}).
+		Funcs(template.FuncMap{"IsDiffCoverageReport": isDiffCoverageReport}).
+		Parse(htmlAnnotatedReport),
+)
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const annotatedSource = `package foo
+
+func Foo(a int) int {
+	if a > 0 {
+		return a
+	}
+	// gocover:ignore:block
+	panic("negative")
+}
+
+func Bar() {}
+
+func Baz() {}
+`
+
+func writeAnnotatedSource(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pkg", "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "foo", "foo.go"), []byte(annotatedSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func annotatedStatistics(statisticsType StatisticsType) *Statistics {
+	return &Statistics{
+		ComparedBranch: "origin/master",
+		StatisticsType: statisticsType,
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName:            "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalLines:          3,
+				TotalEffectiveLines: 2,
+				TotalIgnoredLines:   1,
+				CoveredLines:        1,
+				TotalViolationLines: []int{5},
+				CountedLines: []*CountedLine{
+					{Line: 4, Covered: true},
+					{Line: 5},
+					{Line: 8, Ignored: true},
+				},
+				ViolationSections: []*ViolationSection{
+					{StartLine: 3, EndLine: 9, ViolationLines: []int{5}, Contents: strings.Split(annotatedSource, "\n")[2:9]},
+				},
+			},
+		},
+	}
+}
+
+func generateAnnotatedReport(t *testing.T, moduleDir string, statistics *Statistics) string {
+	output := t.TempDir()
+	g := NewAnnotatedReportGenerator(&AnnotatedReportOption{
+		OutputDir:    output,
+		ReportName:   "annotated",
+		Style:        "github",
+		ModulePath:   "github.com/Azure/gocover",
+		ModuleDir:    moduleDir,
+		ContextLines: 1,
+	}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "annotated.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAnnotatedReport(t *testing.T) {
+	moduleDir := writeAnnotatedSource(t)
+
+	t.Run("diff", func(t *testing.T) {
+		report := generateAnnotatedReport(t, moduleDir, annotatedStatistics(DiffStatisticsType))
+		for _, want := range []string{
+			`<tr id="file-0-L4" class="covered">`,
+			`<tr id="file-0-L5" class="uncovered">`,
+			`<tr id="file-0-L8" class="ignored">`,
+			// the context lines around the counted lines.
+			`<tr id="file-0-L3">`,
+			`<tr id="file-0-L9">`,
+			`origin/master`,
+			`<a href="#file-0">github.com/Azure/gocover/pkg/foo/foo.go</a>`,
+			// the states are labeled for the screen readers.
+			`aria-label="line 5, uncovered"`,
+			`aria-label="line 3"`,
+			`aria-label="Source of github.com/Azure/gocover/pkg/foo/foo.go"`,
+		} {
+			if !strings.Contains(report, want) {
+				t.Errorf("report should contain %q", want)
+			}
+		}
+		for _, unexpected := range []string{`id="file-0-L1"`, `id="file-0-L11"`} {
+			if strings.Contains(report, unexpected) {
+				t.Errorf("report should not contain the lines out of the context %q", unexpected)
+			}
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		report := generateAnnotatedReport(t, moduleDir, annotatedStatistics(FullStatisticsType))
+		for _, want := range []string{`<title>Full Coverage</title>`, `id="file-0-L1"`, `id="file-0-L13"`} {
+			if !strings.Contains(report, want) {
+				t.Errorf("report should contain %q", want)
+			}
+		}
+		if strings.Contains(report, `class="separator"`) {
+			t.Error("the whole file should be shown in one hunk")
+		}
+	})
+
+	t.Run("diff view", func(t *testing.T) {
+		statistics := annotatedStatistics(DiffStatisticsType)
+		statistics.CoverageProfile[0].DiffSections = []*DiffSection{
+			{Deleted: true, StartLine: 4, EndLine: 4, Contents: []string{"\tif a >= 0 {"}},
+			{StartLine: 4, EndLine: 5},
+			{Deleted: true, StartLine: 14, EndLine: 14, Contents: []string{"func Qux() {}"}},
+		}
+		report := generateAnnotatedReport(t, moduleDir, statistics)
+		for _, want := range []string{
+			`<tr id="file-0-L4" class="covered added">`,
+			`<tr id="file-0-L5" class="uncovered added">`,
+			`<tr id="file-0-L8" class="ignored">`,
+			`<td class="marker">-</td>`,
+			`aria-label="line 4, covered, added"`,
+			`<span class="sr-only">deleted line</span>`,
+			`>Qux</span>`,
+		} {
+			if !strings.Contains(report, want) {
+				t.Errorf("report should contain %q", want)
+			}
+		}
+		deleted, added := strings.Index(report, `<tr class="deleted">`), strings.Index(report, `id="file-0-L4"`)
+		if deleted < 0 || deleted > added {
+			t.Error("the deleted lines should be before the lines added at the same line")
+		}
+		// the context of the lines deleted at the end of the file.
+		if !strings.Contains(report, `id="file-0-L13"`) {
+			t.Error("report should contain the context of the deleted lines")
+		}
+	})
+
+	t.Run("source not found", func(t *testing.T) {
+		report := generateAnnotatedReport(t, t.TempDir(), annotatedStatistics(DiffStatisticsType))
+		for _, want := range []string{`Source is not found`, `<tr id="file-0-L5" class="uncovered">`, `id="file-0-L3"`} {
+			if !strings.Contains(report, want) {
+				t.Errorf("report should contain %q", want)
+			}
+		}
+	})
+
+	t.Run("no diff information", func(t *testing.T) {
+		report := generateAnnotatedReport(t, moduleDir, &Statistics{StatisticsType: DiffStatisticsType})
+		if !strings.Contains(report, "No lines with coverage information in this diff.") {
+			t.Error("report should contain empty diff information")
+		}
+	})
+}
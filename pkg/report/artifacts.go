@@ -0,0 +1,235 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/Azure/gocover/pkg/phase"
+	"github.com/sirupsen/logrus"
+)
+
+// The artifacts written into the output directory.
+const (
+	JSONReportArtifact = "report.json"
+	HTMLReportArtifact = "report.html"
+	CoberturaArtifact  = "cobertura.xml"
+	BadgeArtifact      = "badge.svg"
+	MetadataArtifact   = "metadata.json"
+)
+
+// checksummedArtifacts are the artifacts recorded in the manifest.
+var checksummedArtifacts = []string{JSONReportArtifact, HTMLReportArtifact, CoberturaArtifact, BadgeArtifact, MetadataArtifact}
+
+// artifactsReportName is the report name of the html and json report.
+const artifactsReportName = "report"
+
+var ErrOutputDirNotEmpty = errors.New("output directory is not empty and not generated by gocover")
+
+// Metadata describes the run that generates the artifacts.
+type Metadata struct {
+	// GeneratedAt is the time when the artifacts are generated.
+	GeneratedAt time.Time
+	// StatisticsType indicates the artifacts are diff coverage or full coverage.
+	StatisticsType StatisticsType
+	// ModulePath is the path of the go module.
+	ModulePath string
+	// ComparedBranch is the branch that diff compared with, it's empty for full coverage.
+	ComparedBranch string `json:",omitempty"`
+	// DiffTarget is what compared with the branch, it's empty for HEAD and full coverage.
+	DiffTarget string `json:",omitempty"`
+	// LineMapping is how the changed lines make the multi-line statements changed, it's empty for full coverage.
+	LineMapping string `json:",omitempty"`
+	// TotalCoveragePercent is the coverage percent.
+	TotalCoveragePercent float64
+	// CoverageBaseline is the coverage baseline of the coverage gate.
+	CoverageBaseline float64
+	// Passed indicates the coverage gate passes, it's true if the gate is bypassed.
+	Passed bool
+	// Bypassed indicates the failing coverage gate is bypassed.
+	Bypassed bool
+	// Violations are the messages of the failed rules of the coverage gate, even if the gate is bypassed.
+	Violations []string `json:",omitempty"`
+	// Artifacts are the file names of the artifacts in the output directory.
+	Artifacts []string
+	// Phases are the resource usage of the pipeline phases before the artifacts are generated.
+	Phases []*phase.Stats `json:",omitempty"`
+}
+
+// ArtifactsOption contains the input for the artifacts generator.
+type ArtifactsOption struct {
+	// OutputDir is the directory that all the artifacts are written into, it's replaced in each run.
+	OutputDir string
+	// Style is the code style of the html report.
+	Style string
+	// Verbose includes the counted lines in the json report.
+	Verbose bool
+	// ModulePath is the path of the go module, file names of the cobertura report are relative to it.
+	ModulePath string
+	// CoverageBaseline is the coverage baseline of the coverage gate.
+	CoverageBaseline float64
+	// Theme is the theme of the html report, the light theme is used if it's nil.
+	Theme *Theme
+}
+
+// artifactsGenerator writes all the artifacts into one directory.
+type artifactsGenerator struct {
+	option *ArtifactsOption
+	now    func() time.Time
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*artifactsGenerator)(nil)
+
+// NewArtifactsGenerator creates a report generator that writes report.json, report.html, cobertura.xml,
+// badge.svg, metadata.json and the checksum manifest.json into the output directory, so CI only needs to archive one directory.
+// The artifacts are written into a staging directory first, which replaces the output directory at last,
+// so the output directory contains all artifacts of a run or none of them.
+func NewArtifactsGenerator(o *ArtifactsOption, logger logrus.FieldLogger) ReportGenerator {
+	return &artifactsGenerator{
+		option: o,
+		now:    time.Now,
+		logger: logger,
+	}
+}
+
+// GenerateReport writes the artifacts of the statistics.
+func (g *artifactsGenerator) GenerateReport(statistics *Statistics) error {
+	outputDir := filepath.Clean(g.option.OutputDir)
+	if err := checkOutputDir(outputDir); err != nil {
+		return err
+	}
+
+	parent := filepath.Dir(outputDir)
+	if err := os.MkdirAll(parent, os.ModePerm); err != nil {
+		return fmt.Errorf("create parent of output directory: %w", err)
+	}
+	// the staging directory is a sibling of the output directory, so it can be renamed to the output directory.
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(outputDir)+"-")
+	if err != nil {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0755); err != nil {
+		return fmt.Errorf("change mode of staging directory: %w", err)
+	}
+
+	if err := g.writeArtifacts(staging, statistics); err != nil {
+		return err
+	}
+	if err := replaceDir(staging, outputDir); err != nil {
+		return fmt.Errorf("replace output directory: %w", err)
+	}
+
+	g.logger.Infof("generate coverage artifacts: %s", outputDir)
+	return nil
+}
+
+func (g *artifactsGenerator) writeArtifacts(dir string, statistics *Statistics) error {
+	logger := g.logger.WithField("source", "artifacts")
+	now := g.now().UTC()
+
+	if err := NewJSONReportGenerator(dir, artifactsReportName, g.option.Verbose, logger).GenerateReport(statistics); err != nil {
+		return err
+	}
+	if err := NewThemedReportGenerator(g.option.Style, dir, artifactsReportName, g.option.Theme, logger).GenerateReport(statistics); err != nil {
+		return err
+	}
+	err := writeArtifact(filepath.Join(dir, CoberturaArtifact), func(w io.Writer) error {
+		return WriteCobertura(w, statistics, g.option.ModulePath, now)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeArtifact(filepath.Join(dir, BadgeArtifact), func(w io.Writer) error {
+		return WriteBadge(w, statistics.TotalCoveragePercent)
+	})
+	if err != nil {
+		return err
+	}
+
+	metadata := &Metadata{
+		GeneratedAt:          now,
+		StatisticsType:       statistics.StatisticsType,
+		ModulePath:           g.option.ModulePath,
+		ComparedBranch:       statistics.ComparedBranch,
+		DiffTarget:           statistics.DiffTarget,
+		LineMapping:          statistics.LineMapping,
+		TotalCoveragePercent: statistics.TotalCoveragePercent,
+		CoverageBaseline:     g.option.CoverageBaseline,
+		Passed:               statistics.GatePassed(g.option.CoverageBaseline),
+		Bypassed:             statistics.Bypass != nil,
+		Violations:           statistics.GateViolations(),
+		Artifacts:            append(append([]string{}, checksummedArtifacts...), ManifestArtifact),
+		Phases:               statistics.Phases,
+	}
+	err = writeArtifact(filepath.Join(dir, MetadataArtifact), func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(metadata)
+	})
+	if err != nil {
+		return err
+	}
+
+	// the manifest checksums all the other artifacts, so it's written at last.
+	if err := WriteManifest(dir, checksummedArtifacts); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+func writeArtifact(filename string, write func(w io.Writer) error) error {
+	if err := atomicfile.WriteFile(filename, write); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(filename), err)
+	}
+	return nil
+}
+
+// checkOutputDir makes sure the output directory is safe to replace,
+// it should not exist, be empty or be generated by the previous run.
+func checkOutputDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read output directory: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, MetadataArtifact)); err != nil {
+		return fmt.Errorf("%w: %s", ErrOutputDirNotEmpty, dir)
+	}
+	return nil
+}
+
+// replaceDir replaces the dst directory with the src directory, the previous dst is restored if it fails.
+// The files in src are committed already, syncing the parent makes the rename durable.
+func replaceDir(src, dst string) error {
+	if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		return atomicfile.SyncDir(filepath.Dir(dst))
+	}
+
+	previous := src + ".previous"
+	if err := os.Rename(dst, previous); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		_ = os.Rename(previous, dst)
+		return err
+	}
+	if err := atomicfile.SyncDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	return os.RemoveAll(previous)
+}
@@ -0,0 +1,238 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/sirupsen/logrus"
+)
+
+func artifactsStatistics() *Statistics {
+	return &Statistics{
+		StatisticsType:       DiffStatisticsType,
+		ComparedBranch:       "origin/master",
+		TotalCoveragePercent: 50,
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName:     "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalLines:   3,
+				CoveredLines: 1,
+				CountedLines: []*CountedLine{
+					{Line: 3, Covered: true, Block: &Block{StartLine: 3, NumStmt: 1, Count: 4}},
+					{Line: 4},
+					{Line: 5, Ignored: true},
+				},
+			},
+			{
+				FileName:     "github.com/Azure/gocover/pkg/foo/bar.go",
+				TotalLines:   2,
+				CoveredLines: 1,
+				CountedLines: []*CountedLine{
+					{Line: 7, Covered: true},
+					{Line: 7},
+				},
+			},
+		},
+	}
+}
+
+func TestArtifactsGenerator(t *testing.T) {
+	newGenerator := func(dir string) *artifactsGenerator {
+		g := NewArtifactsGenerator(&ArtifactsOption{
+			OutputDir:        dir,
+			ModulePath:       "github.com/Azure/gocover",
+			CoverageBaseline: 80,
+		}, logrus.New()).(*artifactsGenerator)
+		g.now = func() time.Time { return time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC) }
+		return g
+	}
+
+	t.Run("write all artifacts", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "artifacts")
+		if err := newGenerator(dir).GenerateReport(artifactsStatistics()); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{JSONReportArtifact, HTMLReportArtifact, CoberturaArtifact, BadgeArtifact, MetadataArtifact, ManifestArtifact} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("artifact %s should exist: %s", name, err)
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, MetadataArtifact))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var metadata Metadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			t.Fatal(err)
+		}
+		if metadata.Passed || metadata.CoverageBaseline != 80 || metadata.ComparedBranch != "origin/master" || len(metadata.Artifacts) != 6 {
+			t.Errorf("unexpected metadata %+v", metadata)
+		}
+
+		if _, err := VerifyManifest(dir); err != nil {
+			t.Errorf("verify manifest: %s", err)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("staging directory should be removed, but get %d entries", len(entries))
+		}
+	})
+
+	t.Run("failed file gate", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "artifacts")
+		statistics := artifactsStatistics()
+		statistics.TotalCoveragePercent = 90
+		statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, Message: "the coverage of file bar.go is 50.00, lower than the file baseline 60.00"}}}
+		if err := newGenerator(dir).GenerateReport(statistics); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, MetadataArtifact))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var metadata Metadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			t.Fatal(err)
+		}
+		if metadata.Passed || len(metadata.Violations) != 1 {
+			t.Errorf("expect the metadata of the failed file gate, but get %+v", metadata)
+		}
+	})
+
+	t.Run("replace the artifacts of previous run", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "artifacts")
+		g := newGenerator(dir)
+		if err := g.GenerateReport(artifactsStatistics()); err != nil {
+			t.Fatal(err)
+		}
+		stale := filepath.Join(dir, "stale.txt")
+		if err := os.WriteFile(stale, []byte("stale"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.GenerateReport(artifactsStatistics()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("stale file should be removed, but get %v", err)
+		}
+	})
+
+	t.Run("output directory not generated by gocover", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := newGenerator(dir).GenerateReport(artifactsStatistics())
+		if !errors.Is(err, ErrOutputDirNotEmpty) {
+			t.Errorf("expect ErrOutputDirNotEmpty, but get %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+			t.Errorf("file should not be removed: %s", err)
+		}
+	})
+}
+
+func TestWriteCobertura(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCobertura(&buf, artifactsStatistics(), "github.com/Azure/gocover", time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), coberturaDocType) {
+		t.Error("cobertura report should contain the doctype")
+	}
+
+	var coverage coberturaCoverage
+	if err := xml.Unmarshal(buf.Bytes(), &coverage); err != nil {
+		t.Fatal(err)
+	}
+	// line 5 is ignored, and line 7 is not covered because one of its statements is not covered.
+	if coverage.LinesValid != 3 || coverage.LinesCovered != 1 || coverage.Timestamp != 1000 {
+		t.Errorf("unexpected coverage %+v", coverage)
+	}
+	if len(coverage.Packages) != 1 || coverage.Packages[0].Name != "github.com/Azure/gocover/pkg/foo" {
+		t.Fatalf("unexpected packages %+v", coverage.Packages)
+	}
+
+	classes := coverage.Packages[0].Classes
+	if len(classes) != 2 || classes[0].Filename != "pkg/foo/foo.go" || classes[0].Name != "foo" {
+		t.Fatalf("unexpected classes %+v", classes)
+	}
+	lines := classes[0].Lines
+	if len(lines) != 2 || lines[0].Number != 3 || lines[0].Hits != 4 || lines[1].Hits != 0 {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+}
+
+func TestWriteBadge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBadge(&buf, 85.25); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "85.2%") || !strings.Contains(buf.String(), BadgeColor(85.25)) {
+		t.Errorf("unexpected badge %s", buf.String())
+	}
+
+	testSuites := []struct {
+		percent float64
+		color   string
+	}{
+		{percent: 100, color: "#4c1"},
+		{percent: 80, color: "#97ca00"},
+		{percent: 49.9, color: "#e05d44"},
+	}
+	for _, testSuite := range testSuites {
+		if color := BadgeColor(testSuite.percent); color != testSuite.color {
+			t.Errorf("expect color %s of %.1f, but get %s", testSuite.color, testSuite.percent, color)
+		}
+	}
+}
+
+func TestWriteBadgeWithOption(t *testing.T) {
+	thresholds, err := ParseBadgeThresholds([]string{"60=yellow", "95%=green"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &BadgeOption{Label: "diff <coverage>", Thresholds: thresholds}
+	testSuites := []struct {
+		percent float64
+		color   string
+	}{
+		{percent: 95, color: "green"},
+		{percent: 94.9, color: "yellow"},
+		{percent: 10, color: badgeFallbackColor},
+	}
+	for _, testSuite := range testSuites {
+		var buf bytes.Buffer
+		if err := WriteBadgeWithOption(&buf, testSuite.percent, o); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `fill="`+testSuite.color+`"`) {
+			t.Errorf("expect color %s of %.1f, but get %s", testSuite.color, testSuite.percent, buf.String())
+		}
+		if !strings.Contains(buf.String(), "<title>diff &lt;coverage&gt;: ") {
+			t.Errorf("expect the escaped label, but get %s", buf.String())
+		}
+	}
+
+	for _, spec := range []string{"80", "abc=green", "80=", "120=green"} {
+		if _, err := ParseBadgeThresholds([]string{spec}); !errors.Is(err, ErrInvalidBadgeThreshold) {
+			t.Errorf("expect ErrInvalidBadgeThreshold of %q, but get %v", spec, err)
+		}
+	}
+}
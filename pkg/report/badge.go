@@ -0,0 +1,112 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// badgeTemplate is a flat badge in the style of shields.io.
+var badgeTemplate = template.Must(template.New("badge").Parse(mustReadAsset("templates/badge.svg")))
+
+const (
+	// DefaultBadgeLabel is the label on the left of the badge.
+	DefaultBadgeLabel = "coverage"
+	// badgeCharWidth and badgePadding estimate the width of the badge texts.
+	badgeCharWidth = 7
+	badgePadding   = 10
+	// badgeFallbackColor is the color of the coverage below all the thresholds.
+	badgeFallbackColor = "#e05d44"
+)
+
+var ErrInvalidBadgeThreshold = errors.New("invalid badge threshold, it should be in the format of percent=color")
+
+// BadgeThreshold is the color of the coverage at or above the percent.
+type BadgeThreshold struct {
+	Percent float64
+	Color   string
+}
+
+// DefaultBadgeThresholds are the colors of shields.io from brightgreen to orange, the coverage below 50% is red.
+var DefaultBadgeThresholds = []*BadgeThreshold{
+	{Percent: 90, Color: "#4c1"},
+	{Percent: 80, Color: "#97ca00"},
+	{Percent: 70, Color: "#a4a61d"},
+	{Percent: 60, Color: "#dfb317"},
+	{Percent: 50, Color: "#fe7d37"},
+}
+
+// ParseBadgeThresholds parses the thresholds in the format of percent=color, such as 80=#97ca00 or 80=green,
+// the coverage below all the thresholds is red.
+func ParseBadgeThresholds(specs []string) ([]*BadgeThreshold, error) {
+	thresholds := make([]*BadgeThreshold, 0, len(specs))
+	for _, spec := range specs {
+		p, color, ok := strings.Cut(spec, "=")
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(p, "%"), 64)
+		if !ok || err != nil || color == "" || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidBadgeThreshold, spec)
+		}
+		thresholds = append(thresholds, &BadgeThreshold{Percent: percent, Color: color})
+	}
+	return thresholds, nil
+}
+
+// BadgeOption decides the label and the colors of the badge.
+type BadgeOption struct {
+	// Label is the text on the left of the badge, DefaultBadgeLabel is used if it's empty.
+	Label string
+	// Thresholds decide the color of the coverage, DefaultBadgeThresholds are used if it's empty.
+	Thresholds []*BadgeThreshold
+}
+
+// color returns the color of the highest threshold that the coverage reaches.
+func (o *BadgeOption) color(percent float64) string {
+	thresholds := o.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultBadgeThresholds
+	}
+	sorted := append([]*BadgeThreshold{}, thresholds...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Percent > sorted[j].Percent })
+	for _, t := range sorted {
+		if percent >= t.Percent {
+			return t.Color
+		}
+	}
+	return badgeFallbackColor
+}
+
+// BadgeColor returns the color of the coverage badge by the default thresholds.
+func BadgeColor(percent float64) string {
+	return (&BadgeOption{}).color(percent)
+}
+
+// WriteBadge writes the coverage badge in svg format with the default label and colors.
+func WriteBadge(w io.Writer, percent float64) error {
+	return WriteBadgeWithOption(w, percent, &BadgeOption{})
+}
+
+// WriteBadgeWithOption writes the coverage badge in svg format, the label and the colors are decided by the option.
+func WriteBadgeWithOption(w io.Writer, percent float64, o *BadgeOption) error {
+	label := o.Label
+	if label == "" {
+		label = DefaultBadgeLabel
+	}
+	value := fmt.Sprintf("%.1f%%", percent)
+	labelWidth := len([]rune(label))*badgeCharWidth + badgePadding
+	valueWidth := len(value)*badgeCharWidth + badgePadding
+
+	return badgeTemplate.Execute(w, map[string]interface{}{
+		"Label":      label,
+		"Value":      value,
+		"Color":      o.color(percent),
+		"Width":      labelWidth + valueWidth,
+		"LabelWidth": labelWidth,
+		"ValueWidth": valueWidth,
+		"LabelX":     labelWidth / 2,
+		"ValueX":     labelWidth + valueWidth/2,
+	})
+}
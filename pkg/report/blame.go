@@ -0,0 +1,99 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// notCommittedAuthor is the author of the lines that are not in HEAD, such as the uncommitted changes, as git blame.
+const notCommittedAuthor = "Not Committed Yet"
+
+// notCountedLineState is the status of the lines that don't count for coverage, such as the unchanged lines of a diff.
+const notCountedLineState = "-"
+
+// BlameOption decides the lines and the summary of the coverage blame.
+type BlameOption struct {
+	// UncoveredOnly writes only the uncovered lines.
+	UncoveredOnly bool
+	// Summary writes the number of the uncovered lines of each author after the lines.
+	Summary bool
+}
+
+// WriteBlame writes the source of the file in the format of git blame, each line has the commit and the author
+// that changed it last, and its coverage status, which is covered, uncovered, ignored, or - if it doesn't count for coverage.
+// The lines are numbered from 1, the authors of the lines without a commit, such as the uncommitted changes, are Not Committed Yet.
+func WriteBlame(w io.Writer, profile *CoverageProfile, source []string, authors map[int]*LineAuthor, o BlameOption) error {
+	states := lineStates(profile)
+	authorWidth := len(notCommittedAuthor)
+	for _, a := range authors {
+		if len(a.Author) > authorWidth {
+			authorWidth = len(a.Author)
+		}
+	}
+	lineWidth := len(fmt.Sprint(len(source)))
+
+	uncovered := make(map[string]int)
+	for i, text := range source {
+		line := i + 1
+		state, ok := states[line]
+		if !ok {
+			state = notCountedLineState
+		}
+		author, commit := notCommittedAuthor, strings.Repeat("0", 8)
+		if a := authors[line]; a != nil {
+			author, commit = a.Author, shortCommit(a.Commit)
+		}
+		if state == uncoveredLineState {
+			uncovered[author]++
+		} else if o.UncoveredOnly {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s (%-*s %-9s %*d) %s\n", commit, authorWidth, author, state, lineWidth, line, text); err != nil {
+			return err
+		}
+	}
+
+	if o.Summary {
+		return writeBlameSummary(w, profile.FileName, uncovered)
+	}
+	return nil
+}
+
+// writeBlameSummary writes the uncovered lines of each author, the authors of the most uncovered lines first.
+func writeBlameSummary(w io.Writer, fileName string, uncovered map[string]int) error {
+	total, width := 0, 0
+	authors := make([]string, 0, len(uncovered))
+	for author, n := range uncovered {
+		total += n
+		authors = append(authors, author)
+		if len(author) > width {
+			width = len(author)
+		}
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if uncovered[authors[i]] != uncovered[authors[j]] {
+			return uncovered[authors[i]] > uncovered[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+
+	if _, err := fmt.Fprintf(w, "\n%s: %d uncovered lines\n", fileName, total); err != nil {
+		return err
+	}
+	for _, author := range authors {
+		if _, err := fmt.Fprintf(w, "  %-*s %d\n", width, author, uncovered[author]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortCommit returns the first 8 characters of the commit sha.
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteBlame(t *testing.T) {
+	profile := &CoverageProfile{
+		FileName: "github.com/Azure/foo/foo.go",
+		CountedLines: []*CountedLine{
+			{Line: 2, Covered: true},
+			{Line: 3},
+			{Line: 4, Ignored: true},
+		},
+	}
+	source := []string{"func Foo() {", "\ta()", "\tb()", "\tc() // +gocover:ignore", "}"}
+	authors := map[int]*LineAuthor{
+		1: {Author: "alice@example.com", Commit: "1111111111111111111111111111111111111111"},
+		2: {Author: "alice@example.com", Commit: "1111111111111111111111111111111111111111"},
+		4: {Author: "bob@example.com", Commit: "2222222222222222222222222222222222222222"},
+		5: {Author: "bob@example.com", Commit: "2222222222222222222222222222222222222222"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBlame(&buf, profile, source, authors, BlameOption{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "" +
+		"11111111 (alice@example.com -         1) func Foo() {\n" +
+		"11111111 (alice@example.com covered   2) \ta()\n" +
+		"00000000 (Not Committed Yet uncovered 3) \tb()\n" +
+		"22222222 (bob@example.com   ignored   4) \tc() // +gocover:ignore\n" +
+		"22222222 (bob@example.com   -         5) }\n"
+	if buf.String() != expected {
+		t.Errorf("expect blame\n%s\nbut get\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteBlame(&buf, profile, source, authors, BlameOption{UncoveredOnly: true, Summary: true}); err != nil {
+		t.Fatal(err)
+	}
+	expected = "" +
+		"00000000 (Not Committed Yet uncovered 3) \tb()\n" +
+		"\n" +
+		"github.com/Azure/foo/foo.go: 1 uncovered lines\n" +
+		"  Not Committed Yet 1\n"
+	if buf.String() != expected {
+		t.Errorf("expect blame\n%s\nbut get\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,224 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// coberturaDocType is the DTD of the cobertura report, which is recognized by most CI systems.
+const coberturaDocType = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int  `xml:"number,attr"`
+	Hits   int  `xml:"hits,attr"`
+	Branch bool `xml:"branch,attr"`
+}
+
+// WriteCobertura writes the statistics as a cobertura xml report.
+// Each class is a file whose name is relative to the module, ignored lines are not included,
+// and a line is covered only if all the statements start from it are covered.
+func WriteCobertura(w io.Writer, statistics *Statistics, modulePath string, timestamp time.Time) error {
+	return writeCobertura(w, statistics, modulePath, ".", timestamp)
+}
+
+// writeCobertura writes the cobertura xml report, the file names of the classes are relative to the source.
+func writeCobertura(w io.Writer, statistics *Statistics, modulePath, source string, timestamp time.Time) error {
+	coverage := coberturaCoverage{
+		Version:   "gocover",
+		Timestamp: timestamp.UnixMilli(),
+		Sources:   []string{source},
+	}
+
+	packages := make(map[string]*coberturaPackage)
+	var names []string
+	covered := make(map[string]int)
+	valid := make(map[string]int)
+
+	for _, p := range statistics.CoverageProfile {
+		filename := strings.TrimPrefix(strings.TrimPrefix(p.FileName, modulePath), "/")
+		class := coberturaClass{
+			Name:     strings.TrimSuffix(path.Base(filename), ".go"),
+			Filename: filename,
+			Lines:    coberturaLines(p.CountedLines),
+		}
+
+		classCovered := 0
+		for _, l := range class.Lines {
+			if l.Hits > 0 {
+				classCovered++
+			}
+		}
+		class.LineRate = lineRate(classCovered, len(class.Lines))
+
+		name := path.Dir(p.FileName)
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &coberturaPackage{Name: name}
+			packages[name] = pkg
+			names = append(names, name)
+		}
+		pkg.Classes = append(pkg.Classes, class)
+		covered[name] += classCovered
+		valid[name] += len(class.Lines)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := packages[name]
+		pkg.LineRate = lineRate(covered[name], valid[name])
+		coverage.Packages = append(coverage.Packages, *pkg)
+		coverage.LinesCovered += covered[name]
+		coverage.LinesValid += valid[name]
+	}
+	coverage.LineRate = lineRate(coverage.LinesCovered, coverage.LinesValid)
+
+	if _, err := fmt.Fprintf(w, "%s%s\n", xml.Header, coberturaDocType); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(coverage); err != nil {
+		return fmt.Errorf("encode cobertura report: %w", err)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// CoberturaReportOption contains the input for the cobertura report generator.
+type CoberturaReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// ModulePath is the path of the go module, the file names of the classes are relative to it.
+	ModulePath string
+	// ModuleDir is the source of the report, so the CI systems find the files of the classes, it's . if it's empty.
+	ModuleDir string
+}
+
+// coberturaReportGenerator writes the statistics as a cobertura xml report.
+type coberturaReportGenerator struct {
+	option *CoberturaReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*coberturaReportGenerator)(nil)
+
+// NewCoberturaReportGenerator creates a report generator that writes a cobertura xml report, which is rendered
+// on the diff view by Azure DevOps, GitLab and Jenkins. Each file is a class of its package, and the lines are
+// the statements of the cover profile blocks with their hit counts.
+func NewCoberturaReportGenerator(o *CoberturaReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &coberturaReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the cobertura report.
+func (g *coberturaReportGenerator) GenerateReport(statistics *Statistics) error {
+	source := g.option.ModuleDir
+	if source == "" {
+		source = "."
+	}
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.xml", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return writeCobertura(w, statistics, g.option.ModulePath, filepath.ToSlash(source), time.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("write cobertura report: %w", err)
+	}
+
+	g.logger.Infof("generate cobertura coverage report: %s", reportFile)
+	return nil
+}
+
+// LineHit is the hits of a source line, the hits are 0 if the line is not covered.
+type LineHit struct {
+	Number int
+	Hits   int
+}
+
+// LineHits returns the hits of the counted lines of the profile in the order of the line numbers,
+// the lines are merged as the cobertura report, and the ignored lines are skipped.
+func LineHits(profile *CoverageProfile) []LineHit {
+	lines := coberturaLines(profile.CountedLines)
+	hits := make([]LineHit, 0, len(lines))
+	for _, line := range lines {
+		hits = append(hits, LineHit{Number: line.Number, Hits: line.Hits})
+	}
+	return hits
+}
+
+// coberturaLines merges the counted lines that start from the same line, the ignored lines are skipped.
+func coberturaLines(countedLines []*CountedLine) []coberturaLine {
+	hits := make(map[int]int)
+	for _, l := range countedLines {
+		if l.Ignored {
+			continue
+		}
+
+		h := 0
+		if l.Covered {
+			h = 1
+			if l.Block != nil && l.Block.Count > 1 {
+				h = l.Block.Count
+			}
+		}
+		if v, ok := hits[l.Line]; !ok || h < v {
+			hits[l.Line] = h
+		}
+	}
+
+	lines := make([]coberturaLine, 0, len(hits))
+	for number, h := range hits {
+		lines = append(lines, coberturaLine{Number: number, Hits: h})
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Number < lines[j].Number })
+	return lines
+}
+
+func lineRate(covered, valid int) float64 {
+	if valid == 0 {
+		return 1
+	}
+	return float64(covered) / float64(valid)
+}
@@ -0,0 +1,56 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCoberturaReportGenerator(t *testing.T) {
+	output := t.TempDir()
+	g := NewCoberturaReportGenerator(&CoberturaReportOption{
+		OutputDir:  output,
+		ReportName: "coverage",
+		ModulePath: "github.com/Azure/gocover",
+		ModuleDir:  "/src/gocover",
+	}, logrus.New())
+	if err := g.GenerateReport(artifactsStatistics()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(output, "coverage.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var coverage coberturaCoverage
+	if err := xml.Unmarshal(data, &coverage); err != nil {
+		t.Fatal(err)
+	}
+	if len(coverage.Sources) != 1 || coverage.Sources[0] != "/src/gocover" {
+		t.Errorf("expect the module directory is the source, but get %v", coverage.Sources)
+	}
+	if coverage.LinesValid != 3 || coverage.LinesCovered != 1 {
+		t.Errorf("unexpected coverage %+v", coverage)
+	}
+
+	t.Run("no module directory", func(t *testing.T) {
+		g := NewCoberturaReportGenerator(&CoberturaReportOption{OutputDir: output, ReportName: "relative"}, logrus.New())
+		if err := g.GenerateReport(artifactsStatistics()); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(output, "relative.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var coverage coberturaCoverage
+		if err := xml.Unmarshal(data, &coverage); err != nil {
+			t.Fatal(err)
+		}
+		if len(coverage.Sources) != 1 || coverage.Sources[0] != "." {
+			t.Errorf("expect the current directory is the source, but get %v", coverage.Sources)
+		}
+	})
+}
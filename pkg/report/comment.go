@@ -0,0 +1,524 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Verbosity indicates how much detail a pull request comment contains.
+type Verbosity string
+
+const (
+	// MinimalVerbosity renders a one-line status.
+	MinimalVerbosity Verbosity = "minimal"
+	// SummaryVerbosity renders the coverage table of changed files.
+	SummaryVerbosity Verbosity = "summary"
+	// DetailedVerbosity renders the coverage table and per-line annotations of uncovered sections.
+	DetailedVerbosity Verbosity = "detailed"
+	// AutoVerbosity chooses the verbosity according to the size of the change.
+	AutoVerbosity Verbosity = "auto"
+)
+
+const (
+	// DefaultMaxDetailedViolations is the default maximum violation lines that auto verbosity renders the annotations.
+	DefaultMaxDetailedViolations = 200
+	// DefaultMaxSummaryEffectiveLines is the default maximum effective lines that auto verbosity renders the table.
+	DefaultMaxSummaryEffectiveLines = 5000
+	// DefaultMaxAnnotations is the default maximum violation sections annotated in a detailed comment.
+	DefaultMaxAnnotations = 50
+)
+
+// ParseVerbosity validates the verbosity string.
+func ParseVerbosity(s string) (Verbosity, error) {
+	switch v := Verbosity(strings.ToLower(s)); v {
+	case MinimalVerbosity, SummaryVerbosity, DetailedVerbosity, AutoVerbosity:
+		return v, nil
+	default:
+		return "", fmt.Errorf("unknown verbosity %q, one of: %s, %s, %s, %s", s, MinimalVerbosity, SummaryVerbosity, DetailedVerbosity, AutoVerbosity)
+	}
+}
+
+// CommentOption contains the input for rendering a pull request comment.
+type CommentOption struct {
+	// Verbosity indicates how much detail the comment contains.
+	Verbosity Verbosity
+	// HeadSHA is the commit that the coverage is calculated on.
+	HeadSHA string
+	// CoverageBaseline is the expected coverage, the gate state is omitted if it's zero.
+	CoverageBaseline float64
+	// Thresholds limits the detail of the comment, zero value uses the default threshold.
+	Thresholds Thresholds
+}
+
+// Thresholds controls the detail of the comment according to the size of the change.
+type Thresholds struct {
+	// MaxDetailedViolations is the maximum violation lines that auto verbosity renders the annotations.
+	MaxDetailedViolations int
+	// MaxSummaryEffectiveLines is the maximum effective lines that auto verbosity renders the table,
+	// the comment falls back to minimal verbosity on larger changes.
+	MaxSummaryEffectiveLines int
+	// MaxAnnotations is the maximum violation sections annotated in a detailed comment.
+	MaxAnnotations int
+}
+
+// withDefaults returns the thresholds that zero values are replaced by the defaults.
+func (t Thresholds) withDefaults() Thresholds {
+	if t.MaxDetailedViolations <= 0 {
+		t.MaxDetailedViolations = DefaultMaxDetailedViolations
+	}
+	if t.MaxSummaryEffectiveLines <= 0 {
+		t.MaxSummaryEffectiveLines = DefaultMaxSummaryEffectiveLines
+	}
+	if t.MaxAnnotations <= 0 {
+		t.MaxAnnotations = DefaultMaxAnnotations
+	}
+	return t
+}
+
+// ResolveVerbosity returns the verbosity used to render the statistics,
+// auto verbosity is resolved by the effective lines and violation lines of the change.
+func ResolveVerbosity(statistics *Statistics, verbosity Verbosity, thresholds Thresholds) Verbosity {
+	if verbosity != AutoVerbosity {
+		return verbosity
+	}
+
+	thresholds = thresholds.withDefaults()
+	switch {
+	case statistics.TotalViolationLines <= thresholds.MaxDetailedViolations:
+		return DetailedVerbosity
+	case statistics.TotalEffectiveLines <= thresholds.MaxSummaryEffectiveLines:
+		return SummaryVerbosity
+	default:
+		return MinimalVerbosity
+	}
+}
+
+// FormatComment renders the statistics into a markdown pull request comment.
+func FormatComment(statistics *Statistics, o *CommentOption) string {
+	var b strings.Builder
+
+	switch ResolveVerbosity(statistics, o.Verbosity, o.Thresholds) {
+	case MinimalVerbosity:
+		writeStatusLine(&b, statistics, o)
+	case DetailedVerbosity:
+		writeSummary(&b, statistics, o)
+		writeAnnotations(&b, statistics, o.Thresholds.withDefaults().MaxAnnotations)
+	default:
+		writeSummary(&b, statistics, o)
+	}
+	if statistics.ReportURL != "" {
+		fmt.Fprintf(&b, "\n[Full report](%s)\n", statistics.ReportURL)
+	}
+	return b.String()
+}
+
+// writeStatusLine writes a one-line coverage status.
+func writeStatusLine(b *strings.Builder, statistics *Statistics, o *CommentOption) {
+	title := "Diff coverage"
+	if statistics.StatisticsType == FullStatisticsType {
+		title = "Full coverage"
+	}
+	fmt.Fprintf(b, "%s %s: %.2f%% of %s", gateIcon(statistics, o), title,
+		statistics.TotalCoveragePercent, normalizeLines(statistics.TotalEffectiveLines))
+	if o.CoverageBaseline > 0 {
+		fmt.Fprintf(b, " (baseline %.2f%%)", o.CoverageBaseline)
+	}
+	if statistics.Bypass != nil {
+		fmt.Fprintf(b, ", gate bypassed by %s", statistics.Bypass.Source)
+	}
+	if failed := failedTestPackages(statistics); failed != 0 {
+		fmt.Fprintf(b, ", %d test packages failed", failed)
+	}
+	b.WriteString("\n")
+}
+
+// writeSummary writes the header, the status line and the table of changed files.
+func writeSummary(b *strings.Builder, statistics *Statistics, o *CommentOption) {
+	if statistics.StatisticsType == FullStatisticsType {
+		fmt.Fprintf(b, "### Full Coverage\n\n")
+	} else {
+		fmt.Fprintf(b, "### Diff Coverage\n\n")
+		target := shortSHA(o.HeadSHA)
+		if statistics.DiffTarget != "" {
+			target = statistics.DiffTarget
+		}
+		fmt.Fprintf(b, "Diff: `%s...%s`%s\n\n", statistics.ComparedBranch, target, lineMappingNote(statistics))
+	}
+
+	if len(statistics.CoverageProfile) == 0 {
+		fmt.Fprintf(b, "No lines with coverage information in this diff.\n")
+		writeFailedTests(b, statistics)
+		writeErrors(b, statistics)
+		writeTruncatedFiles(b, statistics)
+		writeSkipList(b, statistics)
+		writeNonCoverableFiles(b, statistics)
+		writeEmbeddedFiles(b, statistics)
+		return
+	}
+
+	writeStatusLine(b, statistics, o)
+	if statistics.Bypass != nil {
+		fmt.Fprintf(b, "\n**Coverage gate bypassed** by %s: %s\n", statistics.Bypass.By(), statistics.Bypass.Reason)
+	}
+	writeGateViolations(b, statistics)
+
+	fmt.Fprintf(b, "\n| Source File | Coverage (%%) | Covered Lines | Effective Lines | Ignored Lines | Violation Lines |\n")
+	fmt.Fprintf(b, "| --- | --- | --- | --- | --- | --- |\n")
+	for _, p := range statistics.CoverageProfile {
+		fileName := p.FileName
+		if p.Unreliable {
+			fileName += " :warning: unreliable"
+		}
+		if p.GraceUntil != "" {
+			fileName += " (grace until " + p.GraceUntil + ")"
+		}
+		fmt.Fprintf(b, "| %s | %.2f | %d | %d | %d | %s |\n",
+			fileName,
+			percentCovered(p.TotalEffectiveLines, p.CoveredLines, p.CoveredButIgnoredLines),
+			p.CoveredLines,
+			p.TotalEffectiveLines,
+			p.TotalIgnoredLines,
+			joinViolationLines(p),
+		)
+	}
+	writeRegression(b, statistics)
+	writeLayers(b, statistics)
+	writePackages(b, statistics)
+	writeTodos(b, statistics)
+	writeFailedTests(b, statistics)
+	writeErrors(b, statistics)
+	writeTruncatedFiles(b, statistics)
+	writeSkipList(b, statistics)
+	writeNonCoverableFiles(b, statistics)
+	writeEmbeddedFiles(b, statistics)
+	writeUncoveredSince(b, statistics)
+}
+
+// writeLayers writes the coverage of each architecture layer.
+func writeLayers(b *strings.Builder, statistics *Statistics) {
+	if len(statistics.Layers) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n| Layer | Coverage (%%) | Baseline (%%) | Files | Covered Lines | Effective Lines |\n")
+	fmt.Fprintf(b, "| --- | --- | --- | --- | --- | --- |\n")
+	for _, l := range statistics.Layers {
+		icon, baseline := ":bar_chart:", "-"
+		switch {
+		case l.Baseline <= 0:
+		case l.Passed():
+			icon, baseline = ":white_check_mark:", fmt.Sprintf("%.2f", l.Baseline)
+		default:
+			icon, baseline = ":x:", fmt.Sprintf("%.2f", l.Baseline)
+		}
+		fmt.Fprintf(b, "| %s %s | %.2f | %s | %d | %d | %d |\n", icon, l.Name, l.CoveragePercent, baseline, l.Files, l.TotalCoveredLines, l.TotalEffectiveLines)
+	}
+}
+
+// writeRegression writes the full coverage of the compared branch and HEAD, and the coverage of each touched file on both.
+func writeRegression(b *strings.Builder, statistics *Statistics) {
+	r := statistics.Regression
+	if r == nil {
+		return
+	}
+
+	fmt.Fprintf(b, "\n| Touched File | Base (%%) | Head (%%) | Delta (%%) |\n")
+	fmt.Fprintf(b, "| --- | --- | --- | --- |\n")
+	for _, f := range r.Files {
+		icon := ":white_check_mark:"
+		if !f.Passed(r.Tolerance) {
+			icon = ":x:"
+		}
+		fmt.Fprintf(b, "| %s %s | %.2f | %.2f | %+.2f |\n", icon, f.FileName, f.BaseCoveragePercent, f.CoveragePercent, f.Delta())
+	}
+	fmt.Fprintf(b, "| :bar_chart: Total | %.2f | %.2f | %+.2f |\n", r.BaseCoveragePercent, r.CoveragePercent, r.CoveragePercent-r.BaseCoveragePercent)
+}
+
+// writePackages writes the coverage of each package that has a baseline, the other packages are in the html and the json reports.
+func writePackages(b *strings.Builder, statistics *Statistics) {
+	var gated []*PackageStatistics
+	for _, p := range statistics.Packages {
+		if p.Baseline > 0 {
+			gated = append(gated, p)
+		}
+	}
+	if len(gated) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n| Package | Coverage (%%) | Baseline (%%) | Files | Covered Lines | Effective Lines |\n")
+	fmt.Fprintf(b, "| --- | --- | --- | --- | --- | --- |\n")
+	for _, p := range gated {
+		icon := ":white_check_mark:"
+		if !p.Passed() {
+			icon = ":x:"
+		}
+		fmt.Fprintf(b, "| %s %s | %.2f | %.2f | %d | %d | %d |\n", icon, p.Name, p.CoveragePercent, p.Baseline, p.Files, p.TotalCoveredLines, p.TotalEffectiveLines)
+	}
+}
+
+// lineMappingNote returns how the multi-line statements are counted, it's empty if the line mapping is unknown.
+func lineMappingNote(statistics *Statistics) string {
+	if statistics.LineMapping == "" {
+		return ""
+	}
+	return fmt.Sprintf(", statements mapped by `%s` lines", statistics.LineMapping)
+}
+
+// writeTodos writes the TODO and FIXME comments added in the changed lines.
+func writeTodos(b *strings.Builder, statistics *Statistics) {
+	s := statistics.Todos
+	if s == nil || len(s.Todos) == 0 {
+		return
+	}
+
+	icon := ":memo:"
+	if s.MaxTodos >= 0 || s.MaxDensity >= 0 {
+		icon = ":white_check_mark:"
+		if !s.Passed() {
+			icon = ":x:"
+		}
+	}
+	fmt.Fprintf(b, "\n%s **%d TODO/FIXME comments** are added in %d lines (%.2f per 100 lines)\n\n", icon, len(s.Todos), s.AddedLines, s.Density())
+	fmt.Fprintf(b, "| Source File | Line | Comment |\n")
+	fmt.Fprintf(b, "| --- | --- | --- |\n")
+	for _, t := range s.Todos {
+		fmt.Fprintf(b, "| %s | %d | %s: %s |\n", t.FileName, t.Line, t.Marker, strings.ReplaceAll(t.Text, "|", `\|`))
+	}
+}
+
+// writeErrors writes the non-fatal errors that make the coverage incomplete.
+func writeErrors(b *strings.Builder, statistics *Statistics) {
+	if statistics.Errors.Len() == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Warnings**, the coverage may be incomplete:\n\n")
+	for _, e := range statistics.Errors.Errors {
+		fmt.Fprintf(b, "- `%s` (%s): %s\n", e.Target, e.Kind, e.Message)
+	}
+}
+
+// writeTruncatedFiles writes the files that are skipped because they exceed the limits.
+func writeTruncatedFiles(b *strings.Builder, statistics *Statistics) {
+	if len(statistics.TruncatedFiles) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Truncated files**, these files are not counted for coverage:\n\n")
+	for _, f := range statistics.TruncatedFiles {
+		fmt.Fprintf(b, "- `%s`: %s\n", f.FileName, f.Reason)
+	}
+}
+
+// writeSkipList writes the entries of the skip list with their skipped files, the expired entries are marked,
+// so the skipped files stay visible until the entries are removed.
+func writeSkipList(b *strings.Builder, statistics *Statistics) {
+	if len(statistics.SkipList) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Skip list**, the files of these entries are not counted for coverage until the entries expire:\n\n")
+	for _, e := range statistics.SkipList {
+		status := "expires " + e.Expires
+		if e.Expired {
+			status = ":warning: expired on " + e.Expires + ", the files are counted"
+		}
+		fmt.Fprintf(b, "- `%s` (owner %s, %s)", e.Pattern, e.Owner, status)
+		if e.Reason != "" {
+			fmt.Fprintf(b, ": %s", e.Reason)
+		}
+		fmt.Fprintf(b, "\n")
+		for _, f := range e.Files {
+			fmt.Fprintf(b, "  - `%s`\n", f)
+		}
+	}
+}
+
+// writeNonCoverableFiles writes the tool files that are excluded from the build, so they have no coverage.
+func writeNonCoverableFiles(b *strings.Builder, statistics *Statistics) {
+	if len(statistics.NonCoverableFiles) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Non-coverable files**, these files are excluded from the build by the `ignore` build tag:\n\n")
+	for _, f := range statistics.NonCoverableFiles {
+		fmt.Fprintf(b, "- `%s`\n", f)
+	}
+}
+
+// writeEmbeddedFiles writes the changed files embedded by the //go:embed directives, their contents have no coverage.
+func writeEmbeddedFiles(b *strings.Builder, statistics *Statistics) {
+	if len(statistics.EmbeddedFiles) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Embedded files**, these changes are embedded by `//go:embed` and not analyzed for coverage:\n\n")
+	for _, f := range statistics.EmbeddedFiles {
+		if f.FileName == f.Embedder {
+			fmt.Fprintf(b, "- `%s`: adds the directive `//go:embed %s`\n", f.FileName, f.Pattern)
+			continue
+		}
+		fmt.Fprintf(b, "- `%s`: embedded by `%s` with `%s`\n", f.FileName, f.Embedder, f.Pattern)
+	}
+}
+
+// writeUncoveredSince writes the ranges of the uncovered lines of each file with the first run they're uncovered in,
+// so the fresh regressions stand out from the long-standing debt.
+func writeUncoveredSince(b *strings.Builder, statistics *Statistics) {
+	total, fresh := 0, 0
+	for _, p := range statistics.CoverageProfile {
+		for _, s := range p.UncoveredSince {
+			total++
+			if s.New {
+				fresh++
+			}
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Uncovered since**, %d of %d uncovered lines are new in this run:\n\n", fresh, total)
+	fmt.Fprintf(b, "| Source File | Uncovered Lines | Since |\n")
+	fmt.Fprintf(b, "| --- | --- | --- |\n")
+	for _, p := range statistics.CoverageProfile {
+		since := append([]*UncoveredSince(nil), p.UncoveredSince...)
+		sort.SliceStable(since, func(i, j int) bool { return since[i].Line < since[j].Line })
+		// the consecutive lines of the same run are a range.
+		for i := 0; i < len(since); {
+			j := i + 1
+			for j < len(since) && since[j].Commit == since[i].Commit && since[j].Line == since[j-1].Line+1 {
+				j++
+			}
+			lines := fmt.Sprintf("%d", since[i].Line)
+			if since[i].Line != since[j-1].Line {
+				lines = fmt.Sprintf("%d-%d", since[i].Line, since[j-1].Line)
+			}
+			fmt.Fprintf(b, "| `%s` | %s | %s |\n", p.FileName, lines, sinceRun(since[i]))
+			i = j
+		}
+	}
+}
+
+// sinceRun returns the run of the first uncovered line, such as `abc1234` 2024-01-02.
+func sinceRun(s *UncoveredSince) string {
+	if s.New {
+		return ":new: this run"
+	}
+	commit := s.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return fmt.Sprintf("`%s` %s", commit, s.Time.UTC().Format("2006-01-02"))
+}
+
+// writeFailedTests writes the failed tests of each failed package.
+func writeFailedTests(b *strings.Builder, statistics *Statistics) {
+	if failedTestPackages(statistics) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n**Failed tests**, coverage of the files in these packages is unreliable:\n\n")
+	for _, p := range statistics.TestPackages {
+		if !p.IsFailed() {
+			continue
+		}
+		if len(p.FailedTests) == 0 {
+			fmt.Fprintf(b, "- `%s`: package failed\n", p.Name)
+			continue
+		}
+		fmt.Fprintf(b, "- `%s`: %s\n", p.Name, strings.Join(p.FailedTests, ", "))
+	}
+}
+
+// failedTestPackages returns the number of failed test packages.
+func failedTestPackages(statistics *Statistics) int {
+	failed := 0
+	for _, p := range statistics.TestPackages {
+		if p.IsFailed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// writeAnnotations writes the contents of at most max violation sections, and marks the uncovered lines.
+func writeAnnotations(b *strings.Builder, statistics *Statistics, max int) {
+	count := 0
+	for _, p := range statistics.CoverageProfile {
+		for _, section := range p.ViolationSections {
+			count++
+			if count > max {
+				continue
+			}
+
+			violated := make(map[int]bool, len(section.ViolationLines))
+			for _, l := range section.ViolationLines {
+				violated[l] = true
+			}
+
+			fmt.Fprintf(b, "\n<details><summary>%s: %s not covered in [%d, %d]</summary>\n\n```go\n",
+				p.FileName, normalizeLines(len(section.ViolationLines)), section.StartLine, section.EndLine)
+			for i, content := range section.Contents {
+				line := section.StartLine + i
+				marker := " "
+				if violated[line] {
+					marker = "!"
+				}
+				fmt.Fprintf(b, "%s %5d  %s\n", marker, line, content)
+			}
+			fmt.Fprintf(b, "```\n\n</details>\n")
+		}
+	}
+	if count > max {
+		fmt.Fprintf(b, "\n%d more uncovered sections are not annotated.\n", count-max)
+	}
+}
+
+// writeGateViolations writes the failed rules of the coverage gate, even if the gate is bypassed.
+func writeGateViolations(b *strings.Builder, statistics *Statistics) {
+	violations := statistics.GateViolations()
+	if len(violations) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n**Failed rules of the coverage gate**\n\n")
+	for _, v := range violations {
+		fmt.Fprintf(b, "- %s\n", v)
+	}
+}
+
+// gateIcon returns the icon that shows whether the coverage gate passes.
+func gateIcon(statistics *Statistics, o *CommentOption) string {
+	failed := statistics.GateFailed(o.CoverageBaseline)
+	switch {
+	case o.CoverageBaseline <= 0 && !failed:
+		return ":bar_chart:"
+	case !failed:
+		return ":white_check_mark:"
+	case statistics.Bypass != nil:
+		return ":warning:"
+	default:
+		return ":x:"
+	}
+}
+
+// joinViolationLines returns all the violation lines of the profile joined by comma.
+func joinViolationLines(p *CoverageProfile) string {
+	var lines []int
+	for _, section := range p.ViolationSections {
+		lines = append(lines, section.ViolationLines...)
+	}
+	if len(lines) == 0 {
+		return "-"
+	}
+	return intsJoin(lines)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
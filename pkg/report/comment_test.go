@@ -0,0 +1,288 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/testresult"
+)
+
+func commentStatistics() *Statistics {
+	return &Statistics{
+		StatisticsType:       DiffStatisticsType,
+		ComparedBranch:       "origin/master",
+		TotalLines:           10,
+		TotalEffectiveLines:  8,
+		TotalCoveredLines:    6,
+		TotalCoveragePercent: 75,
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName:            "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalLines:          10,
+				TotalEffectiveLines: 8,
+				TotalIgnoredLines:   2,
+				CoveredLines:        6,
+				ViolationSections: []*ViolationSection{
+					{StartLine: 3, EndLine: 5, ViolationLines: []int{4}, Contents: []string{"func foo() {", "\tbar()", "}"}},
+				},
+			},
+		},
+	}
+}
+
+func TestParseVerbosity(t *testing.T) {
+	for _, input := range []string{"minimal", "Summary", "DETAILED"} {
+		if _, err := ParseVerbosity(input); err != nil {
+			t.Errorf("%s should be valid, but get %s", input, err)
+		}
+	}
+	if _, err := ParseVerbosity("verbose"); err == nil {
+		t.Error("verbose should be invalid")
+	}
+}
+
+func TestFormatComment(t *testing.T) {
+	t.Run("minimal", func(t *testing.T) {
+		comment := FormatComment(commentStatistics(), &CommentOption{Verbosity: MinimalVerbosity, CoverageBaseline: 80})
+		if strings.Count(comment, "\n") != 1 {
+			t.Errorf("minimal comment should be one line, but get %s", comment)
+		}
+		if !strings.Contains(comment, ":x: Diff coverage: 75.00% of 8 lines (baseline 80.00%)") {
+			t.Errorf("unexpected comment %s", comment)
+		}
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		comment := FormatComment(commentStatistics(), &CommentOption{Verbosity: SummaryVerbosity, HeadSHA: "abcdef123456"})
+		for _, v := range []string{"### Diff Coverage", "origin/master...abcdef1", "| github.com/Azure/gocover/pkg/foo/foo.go | 75.00 | 6 | 8 | 2 | 4 |"} {
+			if !strings.Contains(comment, v) {
+				t.Errorf("summary comment should contain %s, but get %s", v, comment)
+			}
+		}
+		if strings.Contains(comment, "<details>") {
+			t.Error("summary comment should not contain annotations")
+		}
+	})
+
+	t.Run("detailed", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.Bypass = &Bypass{Source: "label gocover-skip", Reason: "legacy"}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: DetailedVerbosity, CoverageBaseline: 80})
+		for _, v := range []string{":warning:", "legacy", "<details><summary>github.com/Azure/gocover/pkg/foo/foo.go: 1 line not covered in [3, 5]</summary>", "!     4  \tbar()", "      3  func foo() {"} {
+			if !strings.Contains(comment, v) {
+				t.Errorf("detailed comment should contain %q, but get %s", v, comment)
+			}
+		}
+	})
+
+	t.Run("failed tests", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.CoverageProfile[0].Unreliable = true
+		statistics.TestPackages = []*testresult.Package{
+			{Name: "github.com/Azure/gocover/pkg/foo", Status: testresult.FailAction, Failed: 2, FailedTests: []string{"TestFoo", "TestBar"}},
+			{Name: "github.com/Azure/gocover/pkg/broken", Status: testresult.FailAction},
+			{Name: "github.com/Azure/gocover/pkg/bar", Status: testresult.PassAction, Passed: 1},
+		}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, v := range []string{", 2 test packages failed", "foo.go :warning: unreliable |", "- `github.com/Azure/gocover/pkg/foo`: TestFoo, TestBar", "- `github.com/Azure/gocover/pkg/broken`: package failed"} {
+			if !strings.Contains(comment, v) {
+				t.Errorf("comment should contain %q, but get %s", v, comment)
+			}
+		}
+		if strings.Contains(comment, "pkg/bar`") {
+			t.Errorf("passed package should not be listed, but get %s", comment)
+		}
+	})
+
+	t.Run("truncated files", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.TruncatedFiles = []*TruncatedFile{{FileName: "github.com/Azure/gocover/pkg/foo/zz_generated.go", Size: 10 << 20, Reason: "too large"}}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		if !strings.Contains(comment, "**Truncated files**") || !strings.Contains(comment, "- `github.com/Azure/gocover/pkg/foo/zz_generated.go`: too large") {
+			t.Errorf("unexpected comment %s", comment)
+		}
+	})
+
+	t.Run("skip list", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.SkipList = []*SkipListEntry{
+			{Pattern: "**/plugins/**", Owner: "team-plugins", Expires: "2023-06-30", Reason: "loaded by plugin.Open", Files: []string{"github.com/Azure/gocover/pkg/plugins/a.go"}},
+			{Pattern: "**/old.go", Owner: "foo", Expires: "2023-01-31", Expired: true},
+		}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, expected := range []string{
+			"**Skip list**",
+			"- `**/plugins/**` (owner team-plugins, expires 2023-06-30): loaded by plugin.Open\n  - `github.com/Azure/gocover/pkg/plugins/a.go`\n",
+			"- `**/old.go` (owner foo, :warning: expired on 2023-01-31, the files are counted)\n",
+		} {
+			if !strings.Contains(comment, expected) {
+				t.Errorf("expect %q in the comment %s", expected, comment)
+			}
+		}
+	})
+
+	t.Run("report url", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.ReportURL = "https://reports.example.com/Azure/gocover/main/abc/report.html"
+		comment := FormatComment(statistics, &CommentOption{Verbosity: MinimalVerbosity})
+		if !strings.HasSuffix(comment, "\n[Full report](https://reports.example.com/Azure/gocover/main/abc/report.html)\n") {
+			t.Errorf("unexpected comment %s", comment)
+		}
+	})
+
+	t.Run("non-coverable files", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.NonCoverableFiles = []string{"github.com/Azure/gocover/tools/gen.go"}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		if !strings.Contains(comment, "**Non-coverable files**") || !strings.Contains(comment, "- `github.com/Azure/gocover/tools/gen.go`") {
+			t.Errorf("unexpected comment %s", comment)
+		}
+	})
+
+	t.Run("embedded files", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.EmbeddedFiles = []*EmbeddedFile{
+			{FileName: "github.com/Azure/gocover/pkg/report/templates/coverage.html", Embedder: "github.com/Azure/gocover/pkg/report/html.go", Pattern: "templates"},
+			{FileName: "github.com/Azure/gocover/pkg/db/db.go", Embedder: "github.com/Azure/gocover/pkg/db/db.go", Pattern: "schema.sql"},
+		}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, expected := range []string{
+			"**Embedded files**",
+			"- `github.com/Azure/gocover/pkg/report/templates/coverage.html`: embedded by `github.com/Azure/gocover/pkg/report/html.go` with `templates`",
+			"- `github.com/Azure/gocover/pkg/db/db.go`: adds the directive `//go:embed schema.sql`",
+		} {
+			if !strings.Contains(comment, expected) {
+				t.Errorf("expect %q in comment %s", expected, comment)
+			}
+		}
+	})
+
+	t.Run("uncovered since", func(t *testing.T) {
+		statistics := commentStatistics()
+		first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		statistics.CoverageProfile[0].UncoveredSince = []*UncoveredSince{
+			{Line: 9, Commit: "bbbbbbbbbb", New: true},
+			{Line: 3, Commit: "aaaaaaaaaa", Time: first},
+			{Line: 4, Commit: "aaaaaaaaaa", Time: first},
+			{Line: 5, Commit: "bbbbbbbbbb", New: true},
+		}
+		fileName := statistics.CoverageProfile[0].FileName
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, expected := range []string{
+			"**Uncovered since**, 2 of 4 uncovered lines are new in this run:",
+			"| `" + fileName + "` | 3-4 | `aaaaaaa` 2024-01-02 |\n| `" + fileName + "` | 5 | :new: this run |\n| `" + fileName + "` | 9 | :new: this run |",
+		} {
+			if !strings.Contains(comment, expected) {
+				t.Errorf("expect %q in comment %s", expected, comment)
+			}
+		}
+	})
+
+	t.Run("layers", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.Layers = []*LayerStatistics{
+			{Name: "domain", Files: 1, TotalEffectiveLines: 8, TotalCoveredLines: 6, CoveragePercent: 75, Baseline: 90},
+			{Name: "storage", CoveragePercent: 100},
+		}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, v := range []string{"| :x: domain | 75.00 | 90.00 | 1 | 6 | 8 |", "| :bar_chart: storage | 100.00 | - | 0 | 0 | 0 |"} {
+			if !strings.Contains(comment, v) {
+				t.Errorf("comment should contain %q, but get %s", v, comment)
+			}
+		}
+	})
+
+	t.Run("regression", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.Regression = &RegressionStatistics{
+			BaseCoveragePercent: 80,
+			CoveragePercent:     79.5,
+			Tolerance:           1,
+			Files: []*FileRegression{
+				{FileName: "github.com/Azure/gocover/pkg/foo/bar.go", BaseCoveragePercent: 90, CoveragePercent: 95},
+				{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", BaseCoveragePercent: 75, CoveragePercent: 50},
+			},
+		}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, v := range []string{
+			"| :white_check_mark: github.com/Azure/gocover/pkg/foo/bar.go | 90.00 | 95.00 | +5.00 |",
+			"| :x: github.com/Azure/gocover/pkg/foo/foo.go | 75.00 | 50.00 | -25.00 |",
+			"| :bar_chart: Total | 80.00 | 79.50 | -0.50 |",
+		} {
+			if !strings.Contains(comment, v) {
+				t.Errorf("comment should contain %q, but get %s", v, comment)
+			}
+		}
+	})
+
+	t.Run("packages", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.Packages = []*PackageStatistics{
+			{Name: "github.com/Azure/gocover/pkg/auth", Files: 1, TotalEffectiveLines: 8, TotalCoveredLines: 6, CoveragePercent: 75, Baseline: 90},
+			{Name: "github.com/Azure/gocover/pkg/foo", Files: 1, TotalEffectiveLines: 4, TotalCoveredLines: 4, CoveragePercent: 100},
+		}
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		if v := "| :x: github.com/Azure/gocover/pkg/auth | 75.00 | 90.00 | 1 | 6 | 8 |"; !strings.Contains(comment, v) {
+			t.Errorf("comment should contain %q, but get %s", v, comment)
+		}
+		if strings.Contains(comment, "| Package |") && strings.Contains(comment, "pkg/foo |") {
+			t.Errorf("comment should not contain the package without baseline, but get %s", comment)
+		}
+	})
+
+	t.Run("non-fatal errors", func(t *testing.T) {
+		statistics := commentStatistics()
+		statistics.Errors = &MultiError{}
+		statistics.Errors.Add(ParseFailureError, "github.com/Azure/gocover/pkg/foo/foo.go", "counted without annotations: %s", "comments required")
+		statistics.Errors.Add(UnmatchedFileError, "github.com/Azure/gocover/pkg/foo/bar.go", "no cover profile")
+		comment := FormatComment(statistics, &CommentOption{Verbosity: SummaryVerbosity})
+		for _, v := range []string{"**Warnings**", "- `github.com/Azure/gocover/pkg/foo/foo.go` (parse-failure): counted without annotations: comments required", "- `github.com/Azure/gocover/pkg/foo/bar.go` (unmatched-file): no cover profile"} {
+			if !strings.Contains(comment, v) {
+				t.Errorf("comment should contain %q, but get %s", v, comment)
+			}
+		}
+	})
+
+	t.Run("no diff", func(t *testing.T) {
+		comment := FormatComment(&Statistics{StatisticsType: FullStatisticsType}, &CommentOption{Verbosity: SummaryVerbosity})
+		if !strings.Contains(comment, "### Full Coverage") || !strings.Contains(comment, "No lines with coverage information") {
+			t.Errorf("unexpected comment %s", comment)
+		}
+	})
+}
+
+func TestResolveVerbosity(t *testing.T) {
+	statistics := &Statistics{TotalEffectiveLines: 1000, TotalViolationLines: 300}
+
+	testsuites := []struct {
+		verbosity  Verbosity
+		thresholds Thresholds
+		expect     Verbosity
+	}{
+		{verbosity: MinimalVerbosity, expect: MinimalVerbosity},
+		{verbosity: DetailedVerbosity, expect: DetailedVerbosity},
+		{verbosity: AutoVerbosity, expect: SummaryVerbosity},
+		{verbosity: AutoVerbosity, thresholds: Thresholds{MaxDetailedViolations: 300}, expect: DetailedVerbosity},
+		{verbosity: AutoVerbosity, thresholds: Thresholds{MaxSummaryEffectiveLines: 999}, expect: MinimalVerbosity},
+	}
+	for _, testcase := range testsuites {
+		if v := ResolveVerbosity(statistics, testcase.verbosity, testcase.thresholds); v != testcase.expect {
+			t.Errorf("%s with %+v should be %s, but get %s", testcase.verbosity, testcase.thresholds, testcase.expect, v)
+		}
+	}
+}
+
+func TestFormatCommentMaxAnnotations(t *testing.T) {
+	statistics := commentStatistics()
+	section := statistics.CoverageProfile[0].ViolationSections[0]
+	statistics.CoverageProfile[0].ViolationSections = append(statistics.CoverageProfile[0].ViolationSections, section, section)
+
+	comment := FormatComment(statistics, &CommentOption{Verbosity: AutoVerbosity, Thresholds: Thresholds{MaxAnnotations: 1}})
+	if strings.Count(comment, "<details>") != 1 {
+		t.Errorf("expect 1 annotation, but get %s", comment)
+	}
+	if !strings.Contains(comment, "2 more uncovered sections are not annotated.") {
+		t.Errorf("expect the omitted sections, but get %s", comment)
+	}
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// consoleReportGenerator prints the uncovered lines of each file, such as after the live progress of the test run.
+type consoleReportGenerator struct {
+	w io.Writer
+}
+
+var _ ReportGenerator = (*consoleReportGenerator)(nil)
+
+// NewConsoleReportGenerator creates a generator prints the files with the uncovered lines and the total coverage to w,
+// the files of the lowest coverage are printed first.
+func NewConsoleReportGenerator(w io.Writer) ReportGenerator {
+	return &consoleReportGenerator{w: w}
+}
+
+func (g *consoleReportGenerator) GenerateReport(statistics *Statistics) error {
+	var profiles []*CoverageProfile
+	for _, p := range statistics.CoverageProfile {
+		if len(p.TotalViolationLines) > 0 {
+			profiles = append(profiles, p)
+		}
+	}
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return filePercent(profiles[i]) < filePercent(profiles[j])
+	})
+
+	for _, p := range profiles {
+		note := ""
+		if p.Unreliable {
+			note = ", tests failed"
+		}
+		if p.GraceUntil != "" {
+			note += ", grace until " + p.GraceUntil
+		}
+		if _, err := fmt.Fprintf(g.w, "%s: %.2f%%%s, lines not covered: %s\n", p.FileName, filePercent(p), note, LineRanges(p.TotalViolationLines)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(g.w, "%s coverage: %.2f%% of %d lines, %d lines not covered\n",
+		statistics.StatisticsType, statistics.TotalCoveragePercent, statistics.TotalEffectiveLines, statistics.TotalViolationLines)
+	return err
+}
+
+func filePercent(p *CoverageProfile) float64 {
+	return percentCovered(p.TotalEffectiveLines, p.CoveredLines, p.CoveredButIgnoredLines)
+}
@@ -0,0 +1,29 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConsoleReportGenerator(t *testing.T) {
+	statistics := annotatedStatistics(DiffStatisticsType)
+	statistics.CoverageProfile = append(statistics.CoverageProfile,
+		&CoverageProfile{FileName: "github.com/Azure/gocover/pkg/bar/bar.go", TotalEffectiveLines: 4, TotalViolationLines: []int{3, 4, 5, 9}, Unreliable: true},
+		&CoverageProfile{FileName: "github.com/Azure/gocover/pkg/baz/baz.go", TotalEffectiveLines: 2, CoveredLines: 2},
+	)
+	statistics.TotalCoveragePercent = 37.5
+	statistics.TotalEffectiveLines = 8
+	statistics.TotalViolationLines = 5
+
+	b := &strings.Builder{}
+	if err := NewConsoleReportGenerator(b).GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	expect := `github.com/Azure/gocover/pkg/bar/bar.go: 0.00%, tests failed, lines not covered: 3-5, 9
+github.com/Azure/gocover/pkg/foo/foo.go: 50.00%, lines not covered: 5
+diff coverage: 37.50% of 8 lines, 5 lines not covered
+`
+	if b.String() != expect {
+		t.Errorf("expect:\n%s\nbut get:\n%s", expect, b.String())
+	}
+}
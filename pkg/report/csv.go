@@ -0,0 +1,213 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// csvHeader is the header of the per-line csv report.
+var csvHeader = []string{"file", "line", "status", "hits", "author", "commit"}
+
+// fileCSVHeader is the header of the per-file csv report.
+var fileCSVHeader = []string{"file", "total_lines", "effective_lines", "ignored_lines", "covered_lines", "violation_lines", "coverage"}
+
+// LineAuthor is the last commit that changed a line.
+type LineAuthor struct {
+	// Author is the email of the author of the commit.
+	Author string
+	// Commit is the sha of the commit.
+	Commit string
+}
+
+// Blamer finds the last commit that changed each line of a file.
+type Blamer interface {
+	// Blame returns the last commit of each line of the file, the file name starts with the module path.
+	Blame(fileName string) (map[int]*LineAuthor, error)
+}
+
+// CSVReportOption contains the input for the csv report generator.
+type CSVReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// Blamer fills the author and the commit of the lines, they're empty if it's nil.
+	Blamer Blamer
+}
+
+// csvReportGenerator writes a row for each counted line of the statistics.
+type csvReportGenerator struct {
+	option *CSVReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*csvReportGenerator)(nil)
+
+// NewCSVReportGenerator creates a report generator that writes a csv report of the counted lines,
+// a row of the file, line, status, hit count, author and commit for each line, to be ingested by the data warehouses.
+func NewCSVReportGenerator(o *CSVReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &csvReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the csv report.
+func (g *csvReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.csv", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteCSV(w, statistics, g.blame)
+	})
+	if err != nil {
+		return fmt.Errorf("write csv report: %w", err)
+	}
+
+	g.logger.Infof("generate csv coverage report: %s", reportFile)
+	return nil
+}
+
+// blame returns the authors of the lines of the file, the failure is logged since the authors are optional.
+func (g *csvReportGenerator) blame(fileName string) map[int]*LineAuthor {
+	if g.option.Blamer == nil {
+		return nil
+	}
+	authors, err := g.option.Blamer.Blame(fileName)
+	if err != nil {
+		g.logger.WithError(err).Warnf("blame %s, the authors of its lines are empty", fileName)
+		return nil
+	}
+	return authors
+}
+
+// WriteCSV writes a row for each counted line of the statistics, sorted by the file and the line.
+// The status is covered, uncovered or ignored, and the hits is the least count of the statements start from the line.
+// The author and the commit are empty if blame returns nil.
+func WriteCSV(w io.Writer, statistics *Statistics, blame func(fileName string) map[int]*LineAuthor) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range lineRecords(statistics, blame) {
+		record := []string{r.file, strconv.Itoa(r.line), r.status, strconv.Itoa(r.hits), r.author.Author, r.author.Commit}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// lineRecord is the row of a counted line in the per-line reports.
+type lineRecord struct {
+	file   string
+	line   int
+	status string
+	hits   int
+	author *LineAuthor
+}
+
+// lineRecords returns the rows of the counted lines of the statistics, sorted by the file and the line,
+// the author is empty if blame returns nil.
+func lineRecords(statistics *Statistics, blame func(fileName string) map[int]*LineAuthor) []*lineRecord {
+	var records []*lineRecord
+	for _, profile := range statistics.CoverageProfile {
+		states := lineStates(profile)
+		if len(states) == 0 {
+			continue
+		}
+		hits := lineHits(profile.CountedLines)
+		var authors map[int]*LineAuthor
+		if blame != nil {
+			authors = blame(profile.FileName)
+		}
+
+		lines := make([]int, 0, len(states))
+		for line := range states {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			author := authors[line]
+			if author == nil {
+				author = &LineAuthor{}
+			}
+			records = append(records, &lineRecord{file: profile.FileName, line: line, status: states[line], hits: hits[line], author: author})
+		}
+	}
+	return records
+}
+
+// lineHits returns the least count of the statements that start from each line.
+func lineHits(countedLines []*CountedLine) map[int]int {
+	hits := make(map[int]int)
+	for _, l := range countedLines {
+		h := 0
+		if l.Block != nil {
+			h = l.Block.Count
+		} else if l.Covered {
+			h = 1
+		}
+		if v, ok := hits[l.Line]; !ok || h < v {
+			hits[l.Line] = h
+		}
+	}
+	return hits
+}
+
+// fileCSVReportGenerator writes a row for each file of the statistics.
+type fileCSVReportGenerator struct {
+	outputDir  string
+	reportName string
+	logger     logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*fileCSVReportGenerator)(nil)
+
+// NewFileCSVReportGenerator creates a report generator that writes a csv report of the files,
+// a row of the line counts and the coverage of each file, to be loaded into the spreadsheets and the BI tools.
+func NewFileCSVReportGenerator(outputDir, reportName string, logger logrus.FieldLogger) ReportGenerator {
+	return &fileCSVReportGenerator{outputDir: outputDir, reportName: reportName, logger: logger}
+}
+
+// GenerateReport writes the per-file csv report.
+func (g *fileCSVReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.outputDir, fmt.Sprintf("%s.files.csv", g.reportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteFileCSV(w, statistics)
+	})
+	if err != nil {
+		return fmt.Errorf("write csv report: %w", err)
+	}
+
+	g.logger.Infof("generate per-file csv coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteFileCSV writes a row for each file of the statistics in the order of the statistics,
+// the coverage is the percent of the covered lines that are not ignored in the effective lines, as the html report.
+func WriteFileCSV(w io.Writer, statistics *Statistics) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fileCSVHeader); err != nil {
+		return err
+	}
+	for _, p := range statistics.CoverageProfile {
+		record := []string{
+			p.FileName,
+			strconv.Itoa(p.TotalLines),
+			strconv.Itoa(p.TotalEffectiveLines),
+			strconv.Itoa(p.TotalIgnoredLines),
+			strconv.Itoa(p.CoveredLines),
+			strconv.Itoa(len(p.TotalViolationLines)),
+			strconv.FormatFloat(percentCovered(p.TotalEffectiveLines, p.CoveredLines, p.CoveredButIgnoredLines), 'f', 2, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
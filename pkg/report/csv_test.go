@@ -0,0 +1,131 @@
+package report
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type mockBlamer map[string]map[int]*LineAuthor
+
+func (b mockBlamer) Blame(fileName string) (map[int]*LineAuthor, error) {
+	authors, ok := b[fileName]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return authors, nil
+}
+
+func TestCSVReportGenerator(t *testing.T) {
+	statistics := &Statistics{
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				CountedLines: []*CountedLine{
+					{Line: 7, Covered: true, Block: &Block{Count: 3}},
+					{Line: 3, Covered: true, Block: &Block{Count: 4}},
+					// the line is uncovered if one of the statements start from it is uncovered.
+					{Line: 7, Block: &Block{Count: 0}},
+					{Line: 5, Ignored: true},
+				},
+			},
+			{
+				// the statistics without the counted lines only have the violation lines.
+				FileName:            "github.com/Azure/gocover/pkg/bar/bar.go",
+				TotalViolationLines: []int{2},
+			},
+		},
+	}
+	blamer := mockBlamer{
+		"github.com/Azure/gocover/pkg/foo/foo.go": {
+			3: {Author: "foo@bar.org", Commit: "abcdef1"},
+			7: {Author: "bar@bar.org", Commit: "abcdef2"},
+		},
+	}
+
+	output := t.TempDir()
+	g := NewCSVReportGenerator(&CSVReportOption{OutputDir: output, ReportName: "lines", Blamer: blamer}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(output, "lines.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		csvHeader,
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "3", "covered", "4", "foo@bar.org", "abcdef1"},
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "5", "ignored", "0", "", ""},
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "7", "uncovered", "0", "bar@bar.org", "abcdef2"},
+		{"github.com/Azure/gocover/pkg/bar/bar.go", "2", "uncovered", "0", "", ""},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expect %d records, but get %v", len(expected), records)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if records[i][j] != expected[i][j] {
+				t.Errorf("expect record %d %v, but get %v", i, expected[i], records[i])
+				break
+			}
+		}
+	}
+}
+
+func TestFileCSVReportGenerator(t *testing.T) {
+	statistics := &Statistics{
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName:               "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalLines:             10,
+				TotalEffectiveLines:    8,
+				TotalIgnoredLines:      2,
+				CoveredLines:           7,
+				CoveredButIgnoredLines: 1,
+				TotalViolationLines:    []int{3, 4},
+			},
+			{FileName: "github.com/Azure/gocover/pkg/bar/bar.go"},
+		},
+	}
+
+	output := t.TempDir()
+	if err := NewFileCSVReportGenerator(output, "coverage", logrus.New()).GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(output, "coverage.files.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		fileCSVHeader,
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "10", "8", "2", "7", "2", "75.00"},
+		{"github.com/Azure/gocover/pkg/bar/bar.go", "0", "0", "0", "0", "0", "100.00"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expect %d records, but get %v", len(expected), records)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if records[i][j] != expected[i][j] {
+				t.Errorf("expect record %d %v, but get %v", i, expected[i], records[i])
+				break
+			}
+		}
+	}
+}
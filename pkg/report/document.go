@@ -0,0 +1,147 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// SchemaVersion is the version of the schema of the json document. It's increased only on the breaking changes,
+// such as a field is removed, renamed or changes its meaning, the new fields are added in the same version.
+const SchemaVersion = 1
+
+// CompatibleSchemaVersion is the oldest schema version whose readers can still read the artifacts of SchemaVersion,
+// it's kept as long as a new schema version only adds what the older readers can ignore.
+const CompatibleSchemaVersion = 1
+
+// ToolVersion is the version of gocover that writes the artifacts, the command sets it to the build version.
+var ToolVersion = "devel"
+
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// CheckSchemaVersion negotiates the schema of an artifact written by another version of gocover, such as a document
+// or a stored run. The artifacts of SchemaVersion or older, and the artifacts written before the schema is versioned
+// whose version is 0, are read. The artifacts of a newer schema are read only if their compatible schema version
+// is not newer than SchemaVersion, otherwise ErrUnsupportedSchemaVersion is returned.
+func CheckSchemaVersion(schemaVersion, compatibleSchemaVersion int) error {
+	if schemaVersion <= SchemaVersion || (compatibleSchemaVersion > 0 && compatibleSchemaVersion <= SchemaVersion) {
+		return nil
+	}
+	return fmt.Errorf("%w %d, gocover %s reads the schema version %d or the schemas compatible with it", ErrUnsupportedSchemaVersion, schemaVersion, ToolVersion, SchemaVersion)
+}
+
+// StdoutOutput is the output of the json document that writes to the stdout.
+const StdoutOutput = "-"
+
+// Document is the machine readable result of a run, its schema is versioned by SchemaVersion.
+type Document struct {
+	// SchemaVersion is the version of the schema that the document follows.
+	SchemaVersion int
+	// CompatibleSchemaVersion is the oldest schema version whose readers can read the document.
+	CompatibleSchemaVersion int `json:",omitempty"`
+	// ToolVersion is the version of gocover that writes the document.
+	ToolVersion string `json:",omitempty"`
+	// Statistics is the result of the run, the counted lines are only included in the verbose document.
+	Statistics *Statistics
+	// Tree is the coverage of the module, each directory and each file, sorted by the path.
+	Tree []*AllInformation
+}
+
+// documentGenerator writes the statistics and the coverage tree as the json document.
+type documentGenerator struct {
+	output  string
+	stdout  io.Writer
+	tree    CoverageTree
+	verbose bool
+	logger  logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*documentGenerator)(nil)
+
+// NewDocumentGenerator creates a report generator that writes the json document into the output file,
+// or into the stdout if the output is StdoutOutput, so it can be piped into the other tools.
+// The tree is read when the report is generated, after the coverage data is collected.
+func NewDocumentGenerator(output string, stdout io.Writer, tree CoverageTree, verbose bool, logger logrus.FieldLogger) ReportGenerator {
+	return &documentGenerator{output: output, stdout: stdout, tree: tree, verbose: verbose, logger: logger}
+}
+
+// GenerateReport writes the json document.
+func (g *documentGenerator) GenerateReport(statistics *Statistics) error {
+	document := &Document{
+		SchemaVersion:           SchemaVersion,
+		CompatibleSchemaVersion: CompatibleSchemaVersion,
+		ToolVersion:             ToolVersion,
+		Statistics:              statistics,
+		Tree:                    []*AllInformation{},
+	}
+	if !g.verbose {
+		document.Statistics = withoutCountedLines(statistics)
+	}
+	if g.tree != nil {
+		document.Tree = append(document.Tree, g.tree.All()...)
+		sort.Slice(document.Tree, func(i, j int) bool {
+			return document.Tree[i].Path < document.Tree[j].Path
+		})
+	}
+
+	if g.output == StdoutOutput {
+		if err := writeDocument(g.stdout, document); err != nil {
+			return fmt.Errorf("write json document: %w", err)
+		}
+		return nil
+	}
+	err := atomicfile.WriteFile(g.output, func(w io.Writer) error {
+		return writeDocument(w, document)
+	})
+	if err != nil {
+		return fmt.Errorf("write json document: %w", err)
+	}
+
+	g.logger.Infof("generate json document: %s", g.output)
+	return nil
+}
+
+func writeDocument(w io.Writer, document *Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// ReadStatistics reads the statistics from a json report or a json document, so the tools built on gocover take either.
+// The schema of the document is negotiated by CheckSchemaVersion.
+func ReadStatistics(r io.Reader) (*Statistics, error) {
+	document, err := ReadDocument(r)
+	if err != nil {
+		return nil, err
+	}
+	return document.Statistics, nil
+}
+
+// ReadDocument reads the json document, or a json report as a document of its statistics without the tree.
+// The schema of the document is negotiated by CheckSchemaVersion.
+func ReadDocument(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	document := &Document{}
+	if err := json.Unmarshal(data, document); err != nil {
+		return nil, fmt.Errorf("decode statistics: %w", err)
+	}
+	if err := CheckSchemaVersion(document.SchemaVersion, document.CompatibleSchemaVersion); err != nil {
+		return nil, err
+	}
+	if document.SchemaVersion > 0 && document.Statistics != nil {
+		return document, nil
+	}
+	statistics := &Statistics{}
+	if err := json.Unmarshal(data, statistics); err != nil {
+		return nil, fmt.Errorf("decode statistics: %w", err)
+	}
+	return &Document{Statistics: statistics}, nil
+}
@@ -0,0 +1,121 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDocumentGenerator(t *testing.T) {
+	tree := NewCoverageTree("github.com/Azure/gocover")
+	for _, name := range []string{"github.com/Azure/gocover/pkg/foo/foo.go", "github.com/Azure/gocover/pkg/bar/bar.go"} {
+		node := tree.FindOrCreate(name)
+		node.TotalEffectiveLines = 4
+		node.TotalCoveredLines = 2
+	}
+	tree.CollectCoverageData()
+
+	t.Run("stdout", func(t *testing.T) {
+		var stdout bytes.Buffer
+		if err := NewDocumentGenerator(StdoutOutput, &stdout, tree, false, logrus.New()).GenerateReport(jsonStatistics()); err != nil {
+			t.Fatal(err)
+		}
+		document := &Document{}
+		if err := json.Unmarshal(stdout.Bytes(), document); err != nil {
+			t.Fatal(err)
+		}
+		if document.SchemaVersion != SchemaVersion || document.CompatibleSchemaVersion != CompatibleSchemaVersion || document.ToolVersion != ToolVersion ||
+			document.Statistics.StatisticsType != DiffStatisticsType {
+			t.Errorf("unexpected document %s", stdout.String())
+		}
+		if document.Statistics.CoverageProfile[0].CountedLines != nil {
+			t.Error("the counted lines should not be included without verbose")
+		}
+
+		var paths []string
+		for _, info := range document.Tree {
+			paths = append(paths, info.Path)
+		}
+		expect := []string{
+			"github.com/Azure/gocover",
+			"github.com/Azure/gocover/pkg",
+			"github.com/Azure/gocover/pkg/bar",
+			"github.com/Azure/gocover/pkg/bar/bar.go",
+			"github.com/Azure/gocover/pkg/foo",
+			"github.com/Azure/gocover/pkg/foo/foo.go",
+		}
+		if len(paths) != len(expect) {
+			t.Fatalf("expect tree %v, but get %v", expect, paths)
+		}
+		for i := range expect {
+			if paths[i] != expect[i] {
+				t.Errorf("expect tree %v, but get %v", expect, paths)
+				break
+			}
+		}
+		if root := document.Tree[0]; root.TotalEffectiveLines != 8 || root.TotalCoveredLines != 4 {
+			t.Errorf("unexpected root %+v", root)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "gocover.json")
+		if err := NewDocumentGenerator(output, nil, nil, true, logrus.New()).GenerateReport(jsonStatistics()); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		document := &Document{}
+		if err := json.Unmarshal(data, document); err != nil {
+			t.Fatal(err)
+		}
+		if len(document.Statistics.CoverageProfile[0].CountedLines) != 2 || document.Tree == nil {
+			t.Errorf("unexpected document %s", data)
+		}
+	})
+}
+
+func TestReadStatistics(t *testing.T) {
+	expected := jsonStatistics()
+	expected.TotalCoveragePercent = 50
+	var document bytes.Buffer
+	if err := NewDocumentGenerator(StdoutOutput, &document, nil, false, logrus.New()).GenerateReport(expected); err != nil {
+		t.Fatal(err)
+	}
+	report, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"document": document.Bytes(), "report": report} {
+		statistics, err := ReadStatistics(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if statistics.StatisticsType != DiffStatisticsType || statistics.TotalCoveragePercent != 50 {
+			t.Errorf("unexpected statistics of the %s %+v", name, statistics)
+		}
+	}
+	if _, err := ReadStatistics(bytes.NewReader([]byte("{"))); err == nil {
+		t.Error("expect an error of the broken json")
+	}
+
+	// a newer schema is read if the older readers are compatible with it.
+	compatible := fmt.Sprintf(`{"SchemaVersion": %d, "CompatibleSchemaVersion": %d, "Statistics": {"TotalCoveragePercent": 50}, "Added": true}`, SchemaVersion+1, SchemaVersion)
+	if statistics, err := ReadStatistics(strings.NewReader(compatible)); err != nil || statistics.TotalCoveragePercent != 50 {
+		t.Errorf("expect the statistics of the compatible schema, but get %+v, %v", statistics, err)
+	}
+	incompatible := fmt.Sprintf(`{"SchemaVersion": %d, "CompatibleSchemaVersion": %d, "Statistics": {}}`, SchemaVersion+1, SchemaVersion+1)
+	if _, err := ReadStatistics(strings.NewReader(incompatible)); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("expect %s, but get %v", ErrUnsupportedSchemaVersion, err)
+	}
+}
@@ -0,0 +1,99 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies the non-fatal errors.
+type ErrorKind string
+
+const (
+	// UnmatchedFileError is a changed file that matches no cover profile.
+	UnmatchedFileError ErrorKind = "unmatched-file"
+	// ParseFailureError is a file that failed to parse, it's skipped or counted without the ignore annotations.
+	ParseFailureError ErrorKind = "parse-failure"
+	// MissingTestsError is a package that has no tests.
+	MissingTestsError ErrorKind = "missing-tests"
+)
+
+// NonFatalError is an error that doesn't stop the coverage calculation, but makes the result incomplete.
+type NonFatalError struct {
+	// Kind classifies the error.
+	Kind ErrorKind
+	// Target is the file or package that the error is about.
+	Target string
+	// Message describes the error.
+	Message string
+}
+
+func (e *NonFatalError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Kind, e.Target, e.Message)
+}
+
+// MultiError aggregates the non-fatal errors of a run in the order they are added,
+// all methods are safe on a nil MultiError.
+type MultiError struct {
+	Errors []*NonFatalError
+}
+
+// Add adds a non-fatal error.
+func (m *MultiError) Add(kind ErrorKind, target, format string, args ...interface{}) {
+	m.Errors = append(m.Errors, &NonFatalError{Kind: kind, Target: target, Message: fmt.Sprintf(format, args...)})
+}
+
+// Len returns the number of the errors.
+func (m *MultiError) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.Errors)
+}
+
+// ByKind returns the errors of the kind.
+func (m *MultiError) ByKind(kind ErrorKind) []*NonFatalError {
+	if m == nil {
+		return nil
+	}
+
+	var result []*NonFatalError
+	for _, e := range m.Errors {
+		if e.Kind == kind {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Unwrap returns the errors, so errors.As finds a NonFatalError.
+func (m *MultiError) Unwrap() []error {
+	if m == nil {
+		return nil
+	}
+
+	result := make([]error, 0, len(m.Errors))
+	for _, e := range m.Errors {
+		result = append(result, e)
+	}
+	return result
+}
+
+func (m *MultiError) Error() string {
+	if m.Len() == 0 {
+		return "no errors"
+	}
+
+	messages := make([]string, 0, len(m.Errors))
+	for _, e := range m.Errors {
+		messages = append(messages, e.Error())
+	}
+	return fmt.Sprintf("%d non-fatal errors: %s", len(m.Errors), strings.Join(messages, "; "))
+}
+
+// ErrorOrNil returns nil if there is no error, so the MultiError can be returned as an error.
+func (m *MultiError) ErrorOrNil() error {
+	if m.Len() == 0 {
+		return nil
+	}
+	return m
+}
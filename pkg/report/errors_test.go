@@ -0,0 +1,37 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMultiError(t *testing.T) {
+	var nilErrors *MultiError
+	if nilErrors.Len() != 0 || nilErrors.ByKind(ParseFailureError) != nil || nilErrors.ErrorOrNil() != nil {
+		t.Error("nil multi error should be empty")
+	}
+
+	m := &MultiError{}
+	if m.ErrorOrNil() != nil {
+		t.Error("empty multi error should be nil")
+	}
+	m.Add(ParseFailureError, "foo.go", "skipped: %s", "expected ')'")
+	m.Add(UnmatchedFileError, "bar.go", "no cover profile")
+
+	err := m.ErrorOrNil()
+	if err == nil || m.Len() != 2 {
+		t.Fatalf("expect 2 errors, but get %v", err)
+	}
+	if !strings.Contains(err.Error(), "2 non-fatal errors: parse-failure foo.go: skipped: expected ')'; unmatched-file bar.go") {
+		t.Errorf("unexpected error message %s", err)
+	}
+
+	var nonFatal *NonFatalError
+	if !errors.As(err, &nonFatal) || nonFatal.Target != "foo.go" {
+		t.Errorf("errors.As should find the first non-fatal error, but get %v", nonFatal)
+	}
+	if unmatched := m.ByKind(UnmatchedFileError); len(unmatched) != 1 || unmatched[0].Target != "bar.go" {
+		t.Errorf("unexpected unmatched files %v", unmatched)
+	}
+}
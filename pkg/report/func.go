@@ -0,0 +1,59 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+type funcReportGenerator struct {
+	outputDir  string
+	reportName string
+	logger     logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*funcReportGenerator)(nil)
+
+// NewFuncReportGenerator creates a report generator that writes the coverage of each function as `go tool cover -func`,
+// but only the functions that have counted lines, so the diff coverage lists the functions touched by the diff.
+func NewFuncReportGenerator(outputDir, reportName string, logger logrus.FieldLogger) ReportGenerator {
+	return &funcReportGenerator{outputDir: outputDir, reportName: reportName, logger: logger}
+}
+
+// GenerateReport writes the function coverage report.
+func (g *funcReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.outputDir, fmt.Sprintf("%s.func.txt", g.reportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteFunc(w, statistics)
+	})
+	if err != nil {
+		return fmt.Errorf("write function coverage report: %w", err)
+	}
+
+	g.logger.Infof("generate function coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteFunc writes a line of each function of the statistics, in the order of the files and the lines of the functions,
+// with the covered and the effective statements and the coverage, and a line of the total at the end.
+// The columns are aligned by tabs as `go tool cover -func`.
+func WriteFunc(w io.Writer, statistics *Statistics) error {
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	for _, p := range statistics.CoverageProfile {
+		functions := append([]*FunctionCoverage(nil), p.Functions...)
+		sort.SliceStable(functions, func(i, j int) bool { return functions[i].StartLine < functions[j].StartLine })
+		for _, f := range functions {
+			fmt.Fprintf(tw, "%s:%d:\t%s\t%d/%d\t%.2f%%\n", p.FileName, f.StartLine, f.Name,
+				f.CoveredLines-f.CoveredButIgnoredLines, f.EffectiveLines,
+				percentCovered(f.EffectiveLines, f.CoveredLines, f.CoveredButIgnoredLines))
+		}
+	}
+	fmt.Fprintf(tw, "total:\t(statements)\t%d/%d\t%.2f%%\n",
+		statistics.TotalCoveredLines-statistics.TotalCoveredButIgnoredLines, statistics.TotalEffectiveLines, statistics.TotalCoveragePercent)
+	return tw.Flush()
+}
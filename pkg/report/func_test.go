@@ -0,0 +1,48 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFuncReportGenerator(t *testing.T) {
+	statistics := &Statistics{
+		TotalEffectiveLines:         5,
+		TotalCoveredLines:           4,
+		TotalCoveredButIgnoredLines: 1,
+		TotalCoveragePercent:        60,
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				Functions: []*FunctionCoverage{
+					{Name: "Foo.Bar", StartLine: 20, EndLine: 30, TotalLines: 3, EffectiveLines: 2, CoveredLines: 2, CoveredButIgnoredLines: 1},
+					{Name: "NewFoo", StartLine: 5, EndLine: 10, TotalLines: 2, EffectiveLines: 2, CoveredLines: 2},
+				},
+			},
+			{
+				FileName:  "github.com/Azure/gocover/pkg/bar/bar.go",
+				Functions: []*FunctionCoverage{{Name: "bar", StartLine: 3, EndLine: 4, TotalLines: 1, EffectiveLines: 1}},
+			},
+		},
+	}
+
+	output := t.TempDir()
+	g := NewFuncReportGenerator(output, "coverage", logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "coverage.func.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "github.com/Azure/gocover/pkg/foo/foo.go:5:\tNewFoo\t\t2/2\t100.00%\n" +
+		"github.com/Azure/gocover/pkg/foo/foo.go:20:\tFoo.Bar\t\t1/2\t50.00%\n" +
+		"github.com/Azure/gocover/pkg/bar/bar.go:3:\tbar\t\t0/1\t0.00%\n" +
+		"total:\t\t\t\t\t\t(statements)\t3/5\t60.00%\n"
+	if string(data) != expect {
+		t.Errorf("expect:\n%q\nbut get:\n%q", expect, string(data))
+	}
+}
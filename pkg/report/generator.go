@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
-	"os"
+	"io"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/Azure/gocover/pkg/atomicfile"
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/alecthomas/chroma/v2/lexers"
@@ -21,6 +22,12 @@ type ReportGenerator interface {
 	GenerateReport(statistics *Statistics) error
 }
 
+// Annotator adds the information from the outside of the run to the statistics before the reports are generated,
+// such as the history of the uncovered lines, so all the reports have it.
+type Annotator interface {
+	Annotate(statistics *Statistics) error
+}
+
 // htmlReportGenerator implements a html style report generator.
 type htmlReportGenerator struct {
 	// lexer for parsing go code
@@ -31,6 +38,8 @@ type htmlReportGenerator struct {
 	outputPath string
 	// reportName report name
 	reportName string
+	// theme of the report page
+	theme *Theme
 	// logger
 	logger logrus.FieldLogger
 }
@@ -52,13 +61,25 @@ func NewReportGenerator(
 	outputPath string,
 	reportName string,
 	logger logrus.FieldLogger,
+) ReportGenerator {
+	return NewThemedReportGenerator(codeStyle, outputPath, reportName, nil, logger)
+}
+
+// NewThemedReportGenerator creates a html report generator that renders the page with the theme,
+// the light theme is used if the theme is nil.
+func NewThemedReportGenerator(
+	codeStyle string,
+	outputPath string,
+	reportName string,
+	theme *Theme,
+	logger logrus.FieldLogger,
 ) ReportGenerator {
 	style := styles.Get(codeStyle)
 	if style == nil {
 		style = styles.Fallback
 	}
 
-	builder := style.Builder().Add(chroma.LineHighlight, codeHighlightColor)
+	builder := style.Builder().Add(chroma.LineHighlight, theme.highlightColor())
 	if s, err := builder.Build(); err == nil {
 		style = s
 	}
@@ -73,10 +94,17 @@ func NewReportGenerator(
 		style:      style,
 		outputPath: outputPath,
 		reportName: reportName,
+		theme:      theme,
 		logger:     logger,
 	}
 }
 
+// htmlReport is the data of the html report template.
+type htmlReport struct {
+	*Statistics
+	Theme *Theme
+}
+
 // GenerateReport process the diff coverage profile statistics and generate the final html report.
 func (g *htmlReportGenerator) GenerateReport(statistics *Statistics) error {
 
@@ -86,12 +114,9 @@ func (g *htmlReportGenerator) GenerateReport(statistics *Statistics) error {
 	}
 
 	reportFile := filepath.Join(g.outputPath, finalName(g.reportName))
-	f, err := os.Create(reportFile)
-	if err != nil {
-		return fmt.Errorf("create report file: %w", err)
-	}
-
-	err = htmlCoverageReportTemplate.Execute(f, statistics)
+	err = atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return htmlCoverageReportTemplate.Execute(w, &htmlReport{Statistics: statistics, Theme: g.theme.orDefault()})
+	})
 	if err != nil {
 		return fmt.Errorf("write report: %w", err)
 	}
@@ -106,6 +131,8 @@ func (g *htmlReportGenerator) processCodeSnippets(statistics *Statistics) error
 
 	// each file has a coverage profile, and each coverage profile may have zero to many violation sections.
 	for _, profile := range statistics.CoverageProfile {
+		// the snippets are generated again if the statistics is shared by several html reports.
+		profile.CodeSnippet = nil
 		if profile.CoveredLines == profile.TotalLines {
 			continue
 		}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Azure/gocover/pkg/testresult"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/sirupsen/logrus"
@@ -51,6 +52,40 @@ func TestGenerateReport(t *testing.T) {
 		}
 	})
 
+	t.Run("test results", func(t *testing.T) {
+		path, clean := temporalDir()
+		defer clean()
+
+		g := &htmlReportGenerator{
+			lexer:      lexers.Get(CodeLanguage),
+			style:      styles.Get("colorful"),
+			outputPath: path,
+			reportName: "corverage.html",
+			logger:     logrus.New(),
+		}
+
+		err := g.GenerateReport(&Statistics{
+			StatisticsType:  DiffStatisticsType,
+			CoverageProfile: []*CoverageProfile{{FileName: "foo.go", Unreliable: true}},
+			TestPackages: []*testresult.Package{
+				{Name: "github.com/Azure/gocover/pkg/foo", Status: testresult.FailAction, Failed: 1, FailedTests: []string{"TestFoo"}},
+			},
+		})
+		if err != nil {
+			t.Errorf("should not error, but get: %s", err)
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(g.outputPath, finalName(g.reportName)))
+		checkError(err)
+
+		reportString := string(data)
+		for _, expect := range []string{"(unreliable: tests failed)", "<td><b>FAIL</b></td>", "TestFoo<br />"} {
+			if !strings.Contains(reportString, expect) {
+				t.Errorf("report should contain %s", expect)
+			}
+		}
+	})
+
 	t.Run("have diff coverage profiles", func(t *testing.T) {
 		path, clean := temporalDir()
 		defer clean()
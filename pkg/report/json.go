@@ -0,0 +1,80 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// jsonReportGenerator implements a json report generator.
+type jsonReportGenerator struct {
+	// outputPath report path
+	outputPath string
+	// reportName report name
+	reportName string
+	// verbose indicates whether the counted lines and their profile blocks are included.
+	verbose bool
+	// logger
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*jsonReportGenerator)(nil)
+
+// NewJSONReportGenerator creates a json report generator to generate json coverage report.
+// The verbose report includes the counted lines of each file with the profile blocks they matched,
+// which explains why a line is a violation.
+func NewJSONReportGenerator(
+	outputPath string,
+	reportName string,
+	verbose bool,
+	logger logrus.FieldLogger,
+) ReportGenerator {
+	return &jsonReportGenerator{
+		outputPath: outputPath,
+		reportName: reportName,
+		verbose:    verbose,
+		logger:     logger,
+	}
+}
+
+// GenerateReport writes the statistics into the json report.
+func (g *jsonReportGenerator) GenerateReport(statistics *Statistics) error {
+	output := statistics
+	if !g.verbose {
+		output = withoutCountedLines(statistics)
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+
+	reportFile := filepath.Join(g.outputPath, fmt.Sprintf("%s.json", g.reportName))
+	err = atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	g.logger.Infof("generate json coverage report: %s", reportFile)
+	return nil
+}
+
+// withoutCountedLines returns a copy of the statistics that the counted lines are removed,
+// the statistics is shared by the report generators so it's not modified.
+func withoutCountedLines(statistics *Statistics) *Statistics {
+	s := *statistics
+	s.CoverageProfile = make([]*CoverageProfile, 0, len(statistics.CoverageProfile))
+	for _, p := range statistics.CoverageProfile {
+		profile := *p
+		profile.CountedLines = nil
+		s.CoverageProfile = append(s.CoverageProfile, &profile)
+	}
+	return &s
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func jsonStatistics() *Statistics {
+	return &Statistics{
+		StatisticsType: DiffStatisticsType,
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				CountedLines: []*CountedLine{
+					{Line: 3, Covered: true, Block: &Block{StartLine: 3, StartCol: 2, EndLine: 3, EndCol: 10, NumStmt: 1, Count: 2}},
+					{Line: 4},
+				},
+			},
+		},
+	}
+}
+
+func TestJSONReportGenerator(t *testing.T) {
+	readReport := func(t *testing.T, dir string) *Statistics {
+		data, err := os.ReadFile(filepath.Join(dir, "coverage.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s Statistics
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatal(err)
+		}
+		return &s
+	}
+
+	t.Run("verbose", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := NewJSONReportGenerator(dir, "coverage", true, logrus.New()).GenerateReport(jsonStatistics()); err != nil {
+			t.Fatal(err)
+		}
+		lines := readReport(t, dir).CoverageProfile[0].CountedLines
+		if len(lines) != 2 {
+			t.Fatalf("expect 2 counted lines, but get %d", len(lines))
+		}
+		if b := lines[0].Block; b == nil || b.StartCol != 2 || b.NumStmt != 1 || b.Count != 2 {
+			t.Errorf("unexpected block %+v", b)
+		}
+		if lines[1].Block != nil || lines[1].Covered {
+			t.Errorf("line 4 should have no block, but get %+v", lines[1])
+		}
+	})
+
+	t.Run("not verbose", func(t *testing.T) {
+		dir := t.TempDir()
+		statistics := jsonStatistics()
+		if err := NewJSONReportGenerator(dir, "coverage", false, logrus.New()).GenerateReport(statistics); err != nil {
+			t.Fatal(err)
+		}
+		if lines := readReport(t, dir).CoverageProfile[0].CountedLines; len(lines) != 0 {
+			t.Errorf("expect no counted lines, but get %d", len(lines))
+		}
+		if len(statistics.CoverageProfile[0].CountedLines) != 2 {
+			t.Error("statistics should not be modified")
+		}
+	})
+
+	t.Run("output path not exist", func(t *testing.T) {
+		err := NewJSONReportGenerator("/not/exist", "coverage", false, logrus.New()).GenerateReport(jsonStatistics())
+		if err == nil {
+			t.Error("should return error")
+		}
+	})
+}
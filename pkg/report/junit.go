@@ -0,0 +1,138 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr,omitempty"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// JUnitReportOption contains the input for the junit report generator.
+type JUnitReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// CoverageBaseline is the coverage that each file needs to pass its test case.
+	CoverageBaseline float64
+}
+
+// junitReportGenerator writes each file of the statistics as a test case of a junit xml report.
+type junitReportGenerator struct {
+	option *JUnitReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*junitReportGenerator)(nil)
+
+// NewJUnitReportGenerator creates a report generator that writes a junit xml report, each file is a test case
+// that fails if its coverage is lower than the baseline, so the CI systems that only understand the test reports
+// surface the coverage failures.
+func NewJUnitReportGenerator(o *JUnitReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &junitReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the junit report.
+func (g *junitReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.junit.xml", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteJUnit(w, statistics, g.option.CoverageBaseline)
+	})
+	if err != nil {
+		return fmt.Errorf("write junit report: %w", err)
+	}
+
+	g.logger.Infof("generate junit coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteJUnit writes the statistics as a junit xml report in a test suite, each file is a test case named by the file
+// and classified by its package. The test case fails if the coverage of the file is lower than the baseline,
+// and the failure lists the uncovered lines. The files without effective lines are skipped,
+// and the files skipped by the skip list are the skipped test cases.
+func WriteJUnit(w io.Writer, statistics *Statistics, coverageBaseline float64) error {
+	name := "gocover diff coverage"
+	if statistics.StatisticsType == FullStatisticsType {
+		name = "gocover full coverage"
+	}
+	suite := junitTestSuite{Name: name}
+
+	for _, p := range statistics.CoverageProfile {
+		if p.TotalEffectiveLines == 0 {
+			continue
+		}
+		testCase := junitTestCase{ClassName: path.Dir(p.FileName), Name: path.Base(p.FileName)}
+		coverage := percentCovered(p.TotalEffectiveLines, p.CoveredLines, p.CoveredButIgnoredLines)
+		if coverage < coverageBaseline {
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("coverage %.1f%% is lower than the baseline %.1f%%", coverage, coverageBaseline),
+				Type:    "coverage",
+				Contents: fmt.Sprintf("%s: %d of %d lines are not covered: %s", p.FileName, len(p.TotalViolationLines), p.TotalEffectiveLines,
+					LineRanges(p.TotalViolationLines)),
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	for _, e := range statistics.SkipList {
+		for _, f := range e.Files {
+			message := fmt.Sprintf("skip list %s of %s expires %s", e.Pattern, e.Owner, e.Expires)
+			if e.Reason != "" {
+				message += ": " + e.Reason
+			}
+			testCase := junitTestCase{ClassName: path.Dir(f), Name: path.Base(f), Skipped: &junitSkipped{Message: message}}
+			suite.Tests++
+			suite.Skipped++
+			suite.Cases = append(suite.Cases, testCase)
+		}
+	}
+
+	suites := junitTestSuites{Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	statistics := &Statistics{
+		StatisticsType: DiffStatisticsType,
+		CoverageProfile: []*CoverageProfile{
+			{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", TotalEffectiveLines: 4, CoveredLines: 1, TotalViolationLines: []int{3, 4, 7}},
+			{FileName: "github.com/Azure/gocover/pkg/foo/bar.go", TotalEffectiveLines: 2, CoveredLines: 2},
+			// a file without effective lines is not a test case.
+			{FileName: "github.com/Azure/gocover/pkg/foo/doc.go"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, statistics, 80); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Error("junit report should start with the xml header")
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 2 || suites.Failures != 1 || len(suites.Suites) != 1 || suites.Suites[0].Name != "gocover diff coverage" {
+		t.Fatalf("unexpected test suites %+v", suites)
+	}
+	cases := suites.Suites[0].Cases
+	if cases[0].ClassName != "github.com/Azure/gocover/pkg/foo" || cases[0].Name != "foo.go" || cases[0].Failure == nil {
+		t.Fatalf("expect foo.go fails, but get %+v", cases[0])
+	}
+	failure := cases[0].Failure
+	if failure.Message != "coverage 25.0% is lower than the baseline 80.0%" || !strings.Contains(failure.Contents, "3 of 4 lines are not covered: 3-4, 7") {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+	if cases[1].Failure != nil {
+		t.Errorf("expect bar.go passes, but get %+v", cases[1].Failure)
+	}
+}
+
+func TestWriteJUnitSkipList(t *testing.T) {
+	statistics := &Statistics{
+		StatisticsType:  DiffStatisticsType,
+		CoverageProfile: []*CoverageProfile{{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", TotalEffectiveLines: 2, CoveredLines: 2}},
+		SkipList: []*SkipListEntry{
+			{Pattern: "**/plugins/**", Owner: "team-plugins", Expires: "2023-06-30", Reason: "loaded by plugin.Open", Files: []string{"github.com/Azure/gocover/pkg/plugins/a.go"}},
+			{Pattern: "**/old.go", Owner: "foo", Expires: "2023-01-31", Expired: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, statistics, 80); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatal(err)
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 2 || suite.Skipped != 1 || suite.Failures != 0 {
+		t.Fatalf("unexpected test suite %+v", suite)
+	}
+	skipped := suite.Cases[1]
+	if skipped.Name != "a.go" || skipped.Skipped == nil || skipped.Skipped.Message != "skip list **/plugins/** of team-plugins expires 2023-06-30: loaded by plugin.Open" {
+		t.Errorf("unexpected skipped test case %+v", skipped)
+	}
+}
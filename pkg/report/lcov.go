@@ -0,0 +1,82 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// LCOVReportOption contains the input for the lcov report generator.
+type LCOVReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module, the source files are relative to it so the editors can open them.
+	ModuleDir string
+}
+
+// lcovReportGenerator writes the statistics as a lcov tracefile.
+type lcovReportGenerator struct {
+	option *LCOVReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*lcovReportGenerator)(nil)
+
+// NewLCOVReportGenerator creates a report generator that writes a lcov tracefile, which is consumed by genhtml
+// and the coverage gutters of the editors. A diff report only contains the changed files and lines.
+func NewLCOVReportGenerator(o *LCOVReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &lcovReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the lcov tracefile.
+func (g *lcovReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.info", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteLCOV(w, statistics, g.sourcePath)
+	})
+	if err != nil {
+		return fmt.Errorf("write lcov report: %w", err)
+	}
+
+	g.logger.Infof("generate lcov coverage report: %s", reportFile)
+	return nil
+}
+
+// sourcePath returns the path of the source file in the module directory.
+func (g *lcovReportGenerator) sourcePath(fileName string) string {
+	relative := strings.TrimPrefix(strings.TrimPrefix(fileName, g.option.ModulePath), "/")
+	return filepath.Join(g.option.ModuleDir, filepath.FromSlash(relative))
+}
+
+// WriteLCOV writes a record for each file of the statistics, the source file of the record is returned by sourcePath.
+// The lines are merged as the cobertura report, and the files without counted lines are skipped.
+func WriteLCOV(w io.Writer, statistics *Statistics, sourcePath func(fileName string) string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "TN:\n")
+	for _, profile := range statistics.CoverageProfile {
+		lines := coberturaLines(profile.CountedLines)
+		if len(lines) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(bw, "SF:%s\n", sourcePath(profile.FileName))
+		hit := 0
+		for _, line := range lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line.Number, line.Hits)
+			if line.Hits > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLCOVReportGenerator(t *testing.T) {
+	statistics := &Statistics{
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				CountedLines: []*CountedLine{
+					{Line: 3, Covered: true, Block: &Block{Count: 4}},
+					{Line: 5, Ignored: true},
+					{Line: 7, Covered: true, Block: &Block{Count: 3}},
+					{Line: 7, Block: &Block{Count: 0}},
+				},
+			},
+			{FileName: "github.com/Azure/gocover/pkg/bar/bar.go"},
+		},
+	}
+
+	output := t.TempDir()
+	g := NewLCOVReportGenerator(&LCOVReportOption{
+		OutputDir:  output,
+		ReportName: "lcov",
+		ModulePath: "github.com/Azure/gocover",
+		ModuleDir:  "/src/gocover",
+	}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "lcov.info"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "TN:\n" +
+		"SF:" + filepath.Join("/src/gocover", "pkg", "foo", "foo.go") + "\n" +
+		"DA:3,4\n" +
+		"DA:7,0\n" +
+		"LF:2\n" +
+		"LH:1\n" +
+		"end_of_record\n"
+	if string(data) != expected {
+		t.Errorf("expect lcov report\n%s\nbut get\n%s", expected, string(data))
+	}
+}
@@ -0,0 +1,111 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+)
+
+// ManifestArtifact is the checksum manifest of the artifacts.
+const ManifestArtifact = "manifest.json"
+
+// ManifestAlgorithm is the hash algorithm of the manifest.
+const ManifestAlgorithm = "sha256"
+
+var (
+	ErrManifestNotFound = errors.New("manifest not found")
+	ErrArtifactMissing  = errors.New("artifact is missing")
+	ErrChecksumMismatch = errors.New("artifact checksum mismatch")
+)
+
+// Manifest records the checksum of each artifact, so the artifacts can be verified
+// before they are uploaded or merged.
+type Manifest struct {
+	// Algorithm is the hash algorithm of the checksums.
+	Algorithm string
+	// Files are the artifacts in the directory.
+	Files []*ManifestFile
+}
+
+// ManifestFile is an artifact of the manifest.
+type ManifestFile struct {
+	// Name is the file name that relative to the directory of the manifest.
+	Name string
+	// Size is the file size in bytes.
+	Size int64
+	// SHA256 is the hex encoded SHA-256 of the file contents.
+	SHA256 string
+}
+
+// WriteManifest writes the manifest of the files in the directory.
+func WriteManifest(dir string, names []string) error {
+	manifest := &Manifest{Algorithm: ManifestAlgorithm}
+	for _, name := range names {
+		sum, size, err := fileChecksum(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("checksum %s: %w", name, err)
+		}
+		manifest.Files = append(manifest.Files, &ManifestFile{Name: name, Size: size, SHA256: sum})
+	}
+
+	return atomicfile.WriteFile(filepath.Join(dir, ManifestArtifact), func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(manifest)
+	})
+}
+
+// VerifyManifest reads the manifest of the directory and verifies the checksums of the artifacts.
+func VerifyManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestArtifact))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrManifestNotFound, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if manifest.Algorithm != ManifestAlgorithm {
+		return nil, fmt.Errorf("unsupported manifest algorithm: %s", manifest.Algorithm)
+	}
+
+	for _, f := range manifest.Files {
+		sum, size, err := fileChecksum(filepath.Join(dir, f.Name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrArtifactMissing, f.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("checksum %s: %w", f.Name, err)
+		}
+		if sum != f.SHA256 || size != f.Size {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, f.Name)
+		}
+	}
+	return &manifest, nil
+}
+
+func fileChecksum(filename string) (string, int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	prepare := func(t *testing.T) string {
+		dir := t.TempDir()
+		for name, contents := range map[string]string{"report.json": "{}", "badge.svg": "<svg/>"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteManifest(dir, []string{"report.json", "badge.svg"}); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	t.Run("verify successfully", func(t *testing.T) {
+		manifest, err := VerifyManifest(prepare(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(manifest.Files) != 2 || manifest.Files[0].Size != 2 ||
+			manifest.Files[0].SHA256 != "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a" {
+			t.Errorf("unexpected manifest %+v", manifest.Files[0])
+		}
+	})
+
+	t.Run("artifact is tampered", func(t *testing.T) {
+		dir := prepare(t)
+		if err := os.WriteFile(filepath.Join(dir, "report.json"), []byte("[]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := VerifyManifest(dir); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("expect ErrChecksumMismatch, but get %v", err)
+		}
+	})
+
+	t.Run("artifact is missing", func(t *testing.T) {
+		dir := prepare(t)
+		if err := os.Remove(filepath.Join(dir, "badge.svg")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := VerifyManifest(dir); !errors.Is(err, ErrArtifactMissing) {
+			t.Errorf("expect ErrArtifactMissing, but get %v", err)
+		}
+	})
+
+	t.Run("manifest not found", func(t *testing.T) {
+		if _, err := VerifyManifest(t.TempDir()); !errors.Is(err, ErrManifestNotFound) {
+			t.Errorf("expect ErrManifestNotFound, but get %v", err)
+		}
+	})
+
+	t.Run("write manifest of missing file", func(t *testing.T) {
+		if err := WriteManifest(t.TempDir(), []string{"report.json"}); err == nil {
+			t.Error("should return error")
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// MarkdownReportOption contains the input for the markdown report generator.
+type MarkdownReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// CoverageBaseline is the expected coverage, the gate state is omitted if it's zero.
+	CoverageBaseline float64
+	// MaxAnnotations is the maximum uncovered sections rendered in the details, the default is used if it's zero.
+	MaxAnnotations int
+}
+
+// markdownReportGenerator writes the statistics as a markdown summary.
+type markdownReportGenerator struct {
+	option *MarkdownReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*markdownReportGenerator)(nil)
+
+// NewMarkdownReportGenerator creates a report generator that writes a markdown summary, which can be pasted into
+// a pull request comment or a CI job summary. The uncovered sections are collapsed, so large diffs stay readable.
+func NewMarkdownReportGenerator(o *MarkdownReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &markdownReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the markdown summary.
+func (g *markdownReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.md", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		_, err := io.WriteString(w, FormatMarkdown(statistics, g.option.CoverageBaseline, g.option.MaxAnnotations))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("write markdown report: %w", err)
+	}
+
+	g.logger.Infof("generate markdown coverage report: %s", reportFile)
+	return nil
+}
+
+// FormatMarkdown renders the statistics into a markdown summary: the overall coverage, the table of the files
+// with the ranges of their uncovered and ignored lines, and a collapsible section for each uncovered section.
+func FormatMarkdown(statistics *Statistics, coverageBaseline float64, maxAnnotations int) string {
+	var b strings.Builder
+	o := &CommentOption{CoverageBaseline: coverageBaseline}
+
+	if statistics.StatisticsType == FullStatisticsType {
+		fmt.Fprintf(&b, "### Full Coverage\n\n")
+	} else {
+		fmt.Fprintf(&b, "### Diff Coverage\n\n")
+		target := statistics.DiffTarget
+		if target == "" {
+			target = "HEAD"
+		}
+		fmt.Fprintf(&b, "Diff: `%s...%s`%s\n\n", statistics.ComparedBranch, target, lineMappingNote(statistics))
+	}
+	if len(statistics.CoverageProfile) == 0 {
+		fmt.Fprintf(&b, "No lines with coverage information in this diff.\n")
+		return b.String()
+	}
+
+	writeStatusLine(&b, statistics, o)
+	if statistics.Bypass != nil {
+		fmt.Fprintf(&b, "\n**Coverage gate bypassed** by %s: %s\n", statistics.Bypass.By(), statistics.Bypass.Reason)
+	}
+	writeGateViolations(&b, statistics)
+
+	fmt.Fprintf(&b, "\n| Source File | Coverage (%%) | Covered Lines | Effective Lines | Uncovered Lines | Ignored Lines |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- | --- | --- | --- |\n")
+	for _, p := range statistics.CoverageProfile {
+		fileName := "`" + p.FileName + "`"
+		if p.Unreliable {
+			fileName += " :warning: unreliable"
+		}
+		if p.GraceUntil != "" {
+			fileName += " (grace until " + p.GraceUntil + ")"
+		}
+		fmt.Fprintf(&b, "| %s | %.2f | %d | %d | %s | %s |\n",
+			fileName,
+			percentCovered(p.TotalEffectiveLines, p.CoveredLines, p.CoveredButIgnoredLines),
+			p.CoveredLines,
+			p.TotalEffectiveLines,
+			LineRanges(p.TotalViolationLines),
+			ignoredLines(p),
+		)
+	}
+	writeRegression(&b, statistics)
+	writeLayers(&b, statistics)
+	writeTodos(&b, statistics)
+	writeFailedTests(&b, statistics)
+	writeErrors(&b, statistics)
+	writeTruncatedFiles(&b, statistics)
+	writeSkipList(&b, statistics)
+	writeNonCoverableFiles(&b, statistics)
+	writeEmbeddedFiles(&b, statistics)
+	writeUncoveredSince(&b, statistics)
+
+	if maxAnnotations <= 0 {
+		maxAnnotations = DefaultMaxAnnotations
+	}
+	writeAnnotations(&b, statistics, maxAnnotations)
+	return b.String()
+}
+
+// ignoredLines returns the ranges of the ignored statements, or the number of the ignored lines
+// if the counted lines are not kept.
+func ignoredLines(p *CoverageProfile) string {
+	var lines []int
+	for _, l := range p.CountedLines {
+		if l.Ignored {
+			lines = append(lines, l.Line)
+		}
+	}
+	switch {
+	case len(lines) > 0:
+		return LineRanges(lines)
+	case p.TotalIgnoredLines > 0:
+		return normalizeLines(p.TotalIgnoredLines)
+	default:
+		return "-"
+	}
+}
+
+// LineRanges joins the lines into the ranges of the consecutive lines, such as 3-5, 9.
+func LineRanges(lines []int) string {
+	if len(lines) == 0 {
+		return "-"
+	}
+	sorted := append([]int(nil), lines...)
+	sort.Ints(sorted)
+
+	var ranges []string
+	start, end := sorted[0], sorted[0]
+	flush := func() {
+		if start == end {
+			ranges = append(ranges, fmt.Sprintf("%d", start))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start, end))
+		}
+	}
+	for _, l := range sorted[1:] {
+		switch {
+		case l == end:
+		case l == end+1:
+			end = l
+		default:
+			flush()
+			start, end = l, l
+		}
+	}
+	flush()
+	return strings.Join(ranges, ", ")
+}
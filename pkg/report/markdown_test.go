@@ -0,0 +1,103 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLineRanges(t *testing.T) {
+	testCases := map[string][]int{
+		"-":             nil,
+		"7":             {7},
+		"3-5, 9, 11-12": {11, 3, 4, 5, 9, 12},
+		"1-2":           {1, 2, 2},
+		"10, 20, 30-31": {10, 20, 30, 31},
+	}
+	for expect, lines := range testCases {
+		if got := LineRanges(lines); got != expect {
+			t.Errorf("expect %q of %v, but get %q", expect, lines, got)
+		}
+	}
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	statistics := annotatedStatistics(DiffStatisticsType)
+	statistics.TotalCoveragePercent = 50
+	statistics.TotalEffectiveLines = 2
+	statistics.LineMapping = "first-line"
+
+	markdown := FormatMarkdown(statistics, 80, 0)
+	for _, want := range []string{
+		"### Diff Coverage",
+		"Diff: `origin/master...HEAD`, statements mapped by `first-line` lines",
+		":x: Diff coverage: 50.00% of 2 lines (baseline 80.00%)",
+		"| Source File | Coverage (%) | Covered Lines | Effective Lines | Uncovered Lines | Ignored Lines |",
+		"| `github.com/Azure/gocover/pkg/foo/foo.go` | 50.00 | 1 | 2 | 5 | 8 |",
+		"<details><summary>github.com/Azure/gocover/pkg/foo/foo.go: 1 line not covered in [3, 9]</summary>",
+		"!     5  \t\treturn a",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown should contain %q, but get\n%s", want, markdown)
+		}
+	}
+
+	statistics.Todos = &TodoStatistics{
+		AddedLines: 10,
+		Todos:      []*Todo{{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", Line: 4, Marker: "TODO", Text: "a | b"}},
+		MaxTodos:   0,
+		MaxDensity: -1,
+	}
+	markdown = FormatMarkdown(statistics, 80, 0)
+	for _, want := range []string{
+		":x: **1 TODO/FIXME comments** are added in 10 lines (10.00 per 100 lines)",
+		"| github.com/Azure/gocover/pkg/foo/foo.go | 4 | TODO: a \\| b |",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown should contain %q, but get\n%s", want, markdown)
+		}
+	}
+	statistics.Todos = nil
+
+	// the coverage meets the baseline, but the file gate fails.
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, Message: "the coverage of file github.com/Azure/gocover/pkg/foo/foo.go is 50.00, lower than the file baseline 60.00"}}}
+	markdown = FormatMarkdown(statistics, 50, 0)
+	for _, want := range []string{
+		":x: Diff coverage: 50.00% of 2 lines (baseline 50.00%)",
+		"**Failed rules of the coverage gate**\n\n- the coverage of file github.com/Azure/gocover/pkg/foo/foo.go is 50.00, lower than the file baseline 60.00\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown should contain %q, but get\n%s", want, markdown)
+		}
+	}
+	statistics.Gate = nil
+
+	// the ignored lines fall back to the number without the counted lines.
+	statistics.CoverageProfile[0].CountedLines = nil
+	if markdown := FormatMarkdown(statistics, 0, 0); !strings.Contains(markdown, "| 5 | 1 line |") || !strings.Contains(markdown, ":bar_chart:") {
+		t.Errorf("unexpected markdown\n%s", markdown)
+	}
+
+	if markdown := FormatMarkdown(&Statistics{StatisticsType: FullStatisticsType}, 80, 0); !strings.Contains(markdown, "No lines with coverage information") {
+		t.Errorf("unexpected markdown of empty statistics\n%s", markdown)
+	}
+}
+
+func TestMarkdownReportGenerator(t *testing.T) {
+	dir := t.TempDir()
+	g := NewMarkdownReportGenerator(&MarkdownReportOption{OutputDir: dir, ReportName: "coverage", CoverageBaseline: 80}, logrus.New())
+	if err := g.GenerateReport(annotatedStatistics(FullStatisticsType)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "coverage.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "### Full Coverage") {
+		t.Errorf("unexpected report\n%s", data)
+	}
+}
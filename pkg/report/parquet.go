@@ -0,0 +1,271 @@
+package report
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// parquetMagic starts and ends a parquet file.
+const parquetMagic = "PAR1"
+
+// The physical types, the converted type, the encodings and the page type of the parquet format that the report uses.
+const (
+	parquetInt32     int32 = 1
+	parquetInt64     int32 = 2
+	parquetByteArray int32 = 6
+
+	parquetUTF8 int32 = 0
+
+	parquetPlain int32 = 0
+	parquetRLE   int32 = 3
+
+	parquetDataPage int32 = 0
+	parquetRequired int32 = 0
+)
+
+// parquetColumn is a required column of the per-line parquet report, its values are plain encoded.
+type parquetColumn struct {
+	name   string
+	typ    int32
+	values bytes.Buffer
+}
+
+func (c *parquetColumn) appendInt32(v int) {
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(int32(v))))
+}
+
+func (c *parquetColumn) appendInt64(v int) {
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(int64(v))))
+}
+
+func (c *parquetColumn) appendString(v string) {
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+	c.values.WriteString(v)
+}
+
+// parquetReportGenerator writes the rows of the csv report into a parquet file.
+type parquetReportGenerator struct {
+	csvReportGenerator
+}
+
+var _ ReportGenerator = (*parquetReportGenerator)(nil)
+
+// NewParquetReportGenerator creates a report generator that writes a parquet file of the counted lines,
+// the columns are the same as the csv report, so the data warehouses load it with the types of the columns.
+func NewParquetReportGenerator(o *CSVReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &parquetReportGenerator{csvReportGenerator{option: o, logger: logger}}
+}
+
+// GenerateReport writes the parquet report.
+func (g *parquetReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.parquet", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteParquet(w, statistics, g.blame)
+	})
+	if err != nil {
+		return fmt.Errorf("write parquet report: %w", err)
+	}
+
+	g.logger.Infof("generate parquet coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteParquet writes the rows of WriteCSV into a parquet file of a row group, the line is an int32 column,
+// the hits is an int64 column and the others are utf8 columns. The columns are uncompressed, since the warehouses
+// compress the tables that they load.
+func WriteParquet(w io.Writer, statistics *Statistics, blame func(fileName string) map[int]*LineAuthor) error {
+	columns := []*parquetColumn{
+		{name: csvHeader[0], typ: parquetByteArray},
+		{name: csvHeader[1], typ: parquetInt32},
+		{name: csvHeader[2], typ: parquetByteArray},
+		{name: csvHeader[3], typ: parquetInt64},
+		{name: csvHeader[4], typ: parquetByteArray},
+		{name: csvHeader[5], typ: parquetByteArray},
+	}
+	records := lineRecords(statistics, blame)
+	for _, r := range records {
+		columns[0].appendString(r.file)
+		columns[1].appendInt32(r.line)
+		columns[2].appendString(r.status)
+		columns[3].appendInt64(r.hits)
+		columns[4].appendString(r.author.Author)
+		columns[5].appendString(r.author.Commit)
+	}
+
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+	chunks := make([]*thriftWriter, 0, len(columns))
+	var totalSize int64
+	for _, c := range columns {
+		offset := int64(file.Len())
+		page := &thriftWriter{}
+		page.i32(1, parquetDataPage)
+		page.i32(2, int32(c.values.Len()))
+		page.i32(3, int32(c.values.Len()))
+		page.beginStruct(5)
+		page.i32(1, int32(len(records)))
+		page.i32(2, parquetPlain)
+		page.i32(3, parquetRLE)
+		page.i32(4, parquetRLE)
+		page.endStruct()
+		page.stop()
+		file.Write(page.Bytes())
+		file.Write(c.values.Bytes())
+		size := int64(file.Len()) - offset
+		totalSize += size
+
+		chunk := &thriftWriter{}
+		chunk.i64(2, offset)
+		chunk.beginStruct(3)
+		chunk.i32(1, c.typ)
+		chunk.listBegin(2, thriftI32, 2)
+		chunk.varint(int64(parquetPlain))
+		chunk.varint(int64(parquetRLE))
+		chunk.listBegin(3, thriftBinary, 1)
+		chunk.rawBinary(c.name)
+		chunk.i32(4, 0)
+		chunk.i64(5, int64(len(records)))
+		chunk.i64(6, size)
+		chunk.i64(7, size)
+		chunk.i64(9, offset)
+		chunk.endStruct()
+		chunk.stop()
+		chunks = append(chunks, chunk)
+	}
+
+	meta := &thriftWriter{}
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(columns)+1)
+	meta.beginElement()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.endElement()
+	for _, c := range columns {
+		meta.beginElement()
+		meta.i32(1, c.typ)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, c.name)
+		if c.typ == parquetByteArray {
+			meta.i32(6, parquetUTF8)
+		}
+		meta.endElement()
+	}
+	meta.i64(3, int64(len(records)))
+	if len(records) == 0 {
+		meta.listBegin(4, thriftStruct, 0)
+	} else {
+		meta.listBegin(4, thriftStruct, 1)
+		meta.beginElement()
+		meta.listBegin(1, thriftStruct, len(chunks))
+		for _, chunk := range chunks {
+			meta.Write(chunk.Bytes())
+		}
+		meta.i64(2, totalSize)
+		meta.i64(3, int64(len(records)))
+		meta.endElement()
+	}
+	meta.binary(6, "gocover")
+	meta.stop()
+
+	file.Write(meta.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(meta.Len())))
+	file.WriteString(parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// The types of the thrift compact protocol that the parquet metadata is encoded in.
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter encodes a thrift struct in the compact protocol, the ids of the last fields of the nested structs
+// are kept since the field headers are the deltas of the ids.
+type thriftWriter struct {
+	bytes.Buffer
+	lastIDs []int16
+	lastID  int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+// varint writes a zigzag varint, as the integers of the compact protocol.
+func (t *thriftWriter) varint(v int64) {
+	t.Write(binary.AppendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func (t *thriftWriter) rawBinary(v string) {
+	t.Write(binary.AppendUvarint(nil, uint64(len(v))))
+	t.WriteString(v)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.rawBinary(v)
+}
+
+// listBegin writes the header of a list field, the elements are written after it without the field headers.
+func (t *thriftWriter) listBegin(id int16, elementType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | elementType)
+		return
+	}
+	t.WriteByte(0xf0 | elementType)
+	t.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+// beginStruct starts a struct field, its fields are written until endStruct.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.endElement()
+}
+
+// beginElement starts a struct of a list, its fields are written until endElement.
+func (t *thriftWriter) beginElement() {
+	t.lastIDs = append(t.lastIDs, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) endElement() {
+	t.stop()
+	t.lastID = t.lastIDs[len(t.lastIDs)-1]
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+// stop ends the struct.
+func (t *thriftWriter) stop() {
+	t.WriteByte(0)
+}
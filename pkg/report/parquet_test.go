@@ -0,0 +1,220 @@
+package report
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// thriftReader decodes the thrift compact protocol into the maps of the field ids, the lists are slices.
+type thriftReader struct {
+	b []byte
+	i int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.b[r.i]
+	r.i++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.i:])
+	r.i += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.i += n
+		return string(r.b[r.i-n : r.i])
+	case thriftList:
+		h := r.byte()
+		size, elementType := int(h>>4), h&0x0f
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			list = append(list, r.value(elementType))
+		}
+		return list
+	case thriftStruct:
+		fields := make(map[int16]interface{})
+		var id int16
+		for {
+			h := r.byte()
+			if h == 0 {
+				return fields
+			}
+			if delta := int16(h >> 4); delta != 0 {
+				id += delta
+			} else {
+				id = int16(r.varint())
+			}
+			fields[id] = r.value(h & 0x0f)
+		}
+	}
+	panic("unknown thrift type " + strconv.Itoa(int(typ)))
+}
+
+// readParquet returns the file metadata and the values of the columns of each row group in the parquet file.
+func readParquet(t *testing.T, file []byte) (map[int16]interface{}, [][][]string) {
+	t.Helper()
+	if len(file) < 12 || string(file[:4]) != parquetMagic || string(file[len(file)-4:]) != parquetMagic {
+		t.Fatalf("expect the parquet magic at both ends of the file, but get %q", file)
+	}
+	metaLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	meta := (&thriftReader{b: file[len(file)-8-metaLen : len(file)-8]}).value(thriftStruct).(map[int16]interface{})
+
+	var rowGroups [][][]string
+	for _, g := range meta[4].([]interface{}) {
+		var columns [][]string
+		for _, c := range g.(map[int16]interface{})[1].([]interface{}) {
+			column := c.(map[int16]interface{})[3].(map[int16]interface{})
+			r := &thriftReader{b: file, i: int(column[9].(int64))}
+			page := r.value(thriftStruct).(map[int16]interface{})
+			numValues := page[5].(map[int16]interface{})[1].(int64)
+			data := file[r.i : r.i+int(page[3].(int64))]
+
+			var values []string
+			for i := int64(0); i < numValues; i++ {
+				switch int32(column[1].(int64)) {
+				case parquetInt32:
+					values = append(values, strconv.Itoa(int(int32(binary.LittleEndian.Uint32(data)))))
+					data = data[4:]
+				case parquetInt64:
+					values = append(values, strconv.Itoa(int(int64(binary.LittleEndian.Uint64(data)))))
+					data = data[8:]
+				case parquetByteArray:
+					n := int(binary.LittleEndian.Uint32(data))
+					values = append(values, string(data[4:4+n]))
+					data = data[4+n:]
+				}
+			}
+			if len(data) != 0 {
+				t.Errorf("expect %d values in the page of %v, but %d bytes are left", numValues, column[3], len(data))
+			}
+			columns = append(columns, values)
+		}
+		rowGroups = append(rowGroups, columns)
+	}
+	return meta, rowGroups
+}
+
+func TestParquetReportGenerator(t *testing.T) {
+	statistics := &Statistics{
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				CountedLines: []*CountedLine{
+					{Line: 7, Covered: true, Block: &Block{Count: 3}},
+					{Line: 3, Covered: true, Block: &Block{Count: 4}},
+					{Line: 7, Block: &Block{Count: 0}},
+					{Line: 5, Ignored: true},
+				},
+			},
+			{
+				FileName:            "github.com/Azure/gocover/pkg/bar/bar.go",
+				TotalViolationLines: []int{2},
+			},
+		},
+	}
+	blamer := mockBlamer{
+		"github.com/Azure/gocover/pkg/foo/foo.go": {
+			3: {Author: "foo@bar.org", Commit: "abcdef1"},
+			7: {Author: "bar@bar.org", Commit: "abcdef2"},
+		},
+	}
+
+	output := t.TempDir()
+	g := NewParquetReportGenerator(&CSVReportOption{OutputDir: output, ReportName: "lines", Blamer: blamer}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.ReadFile(filepath.Join(output, "lines.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, rowGroups := readParquet(t, file)
+
+	if meta[3].(int64) != 4 || len(rowGroups) != 1 {
+		t.Fatalf("expect 4 rows in a row group, but get %v rows in %d row groups", meta[3], len(rowGroups))
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(csvHeader)+1 || schema[0].(map[int16]interface{})[5].(int64) != int64(len(csvHeader)) {
+		t.Fatalf("expect the root and %d columns in the schema, but get %v", len(csvHeader), schema)
+	}
+	for i, name := range csvHeader {
+		if n := schema[i+1].(map[int16]interface{})[4]; n != name {
+			t.Errorf("expect column %d is %s, but get %v", i, name, n)
+		}
+	}
+
+	expected := [][]string{
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "3", "covered", "4", "foo@bar.org", "abcdef1"},
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "5", "ignored", "0", "", ""},
+		{"github.com/Azure/gocover/pkg/foo/foo.go", "7", "uncovered", "0", "bar@bar.org", "abcdef2"},
+		{"github.com/Azure/gocover/pkg/bar/bar.go", "2", "uncovered", "0", "", ""},
+	}
+	columns := rowGroups[0]
+	if len(columns) != len(csvHeader) {
+		t.Fatalf("expect %d columns, but get %v", len(csvHeader), columns)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if len(columns[j]) != len(expected) || columns[j][i] != expected[i][j] {
+				t.Errorf("expect row %d %v, but get column %s %v", i, expected[i], csvHeader[j], columns[j])
+				break
+			}
+		}
+	}
+}
+
+func TestWriteParquetWithoutLines(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteParquet(&b, &Statistics{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	meta, rowGroups := readParquet(t, b.Bytes())
+	if meta[3].(int64) != 0 || len(rowGroups) != 0 {
+		t.Errorf("expect no rows and no row groups, but get %v rows in %d row groups", meta[3], len(rowGroups))
+	}
+}
+
+func TestThriftWriter(t *testing.T) {
+	w := &thriftWriter{}
+	w.i32(1, -3)
+	// the field id is written in full if the delta is larger than 15.
+	w.binary(20, "foo")
+	w.listBegin(21, thriftI64, 20)
+	for i := 0; i < 20; i++ {
+		w.varint(int64(i) << 40)
+	}
+	w.beginStruct(22)
+	w.i64(16, 7)
+	w.endStruct()
+	w.i32(23, 1)
+	w.stop()
+
+	fields := (&thriftReader{b: w.Bytes()}).value(thriftStruct).(map[int16]interface{})
+	list := fields[21].([]interface{})
+	if fields[1] != int64(-3) || fields[20] != "foo" || len(list) != 20 || list[19] != int64(19)<<40 ||
+		fields[22].(map[int16]interface{})[16] != int64(7) || fields[23] != int64(1) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
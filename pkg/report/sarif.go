@@ -0,0 +1,227 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// The defaults and the choices of the sarif results.
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+
+	// DefaultSARIFRuleID is the rule id of the uncovered lines.
+	DefaultSARIFRuleID = "gocover/uncovered"
+	// DefaultSARIFLevel is the level of the results, one of: error, warning, note, none.
+	DefaultSARIFLevel = "warning"
+
+	// SARIFLineGranularity reports a result for each uncovered line.
+	SARIFLineGranularity = "line"
+	// SARIFSectionGranularity reports a result for each uncovered section.
+	SARIFSectionGranularity = "section"
+)
+
+var (
+	ErrUnknownSARIFLevel       = errors.New("unknown sarif level, one of: error, warning, note, none")
+	ErrUnknownSARIFGranularity = errors.New("unknown sarif granularity, one of: line, section")
+)
+
+// SARIFSettings decides the results of the sarif report.
+type SARIFSettings struct {
+	// RuleID is the rule id of the results, DefaultSARIFRuleID is used if it's empty.
+	RuleID string
+	// Level is the severity of the results, DefaultSARIFLevel is used if it's empty.
+	Level string
+	// Granularity is one of SARIFLineGranularity and SARIFSectionGranularity, a result for each line if it's empty.
+	Granularity string
+}
+
+// Validate validates the level and the granularity.
+func (s *SARIFSettings) Validate() error {
+	switch s.Level {
+	case "", "error", "warning", "note", "none":
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownSARIFLevel, s.Level)
+	}
+	switch s.Granularity {
+	case "", SARIFLineGranularity, SARIFSectionGranularity:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownSARIFGranularity, s.Granularity)
+	}
+	return nil
+}
+
+// SARIFReportOption contains the input for the sarif report generator.
+type SARIFReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root,
+	// since the code scanning locates the files relative to the repository root.
+	ModuleDir string
+	SARIFSettings
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// sarifReportGenerator writes the uncovered lines as the results of a sarif log.
+type sarifReportGenerator struct {
+	option *SARIFReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*sarifReportGenerator)(nil)
+
+// NewSARIFReportGenerator creates a report generator that writes the uncovered lines as a sarif log,
+// which is uploaded to GitHub code scanning, so the uncovered lines show in the security tab and the pull requests.
+func NewSARIFReportGenerator(o *SARIFReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &sarifReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the sarif log.
+func (g *sarifReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.sarif", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteSARIF(w, statistics, g.option)
+	})
+	if err != nil {
+		return fmt.Errorf("write sarif report: %w", err)
+	}
+
+	g.logger.Infof("generate sarif coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteSARIF writes the uncovered lines of the statistics as a sarif log, a result for each uncovered line,
+// or each uncovered section with the section granularity. The uris are relative to the repository root.
+func WriteSARIF(w io.Writer, statistics *Statistics, o *SARIFReportOption) error {
+	ruleID, level := o.RuleID, o.Level
+	if ruleID == "" {
+		ruleID = DefaultSARIFRuleID
+	}
+	if level == "" {
+		level = DefaultSARIFLevel
+	}
+	what := "changed"
+	if statistics.StatisticsType == FullStatisticsType {
+		what = "statement"
+	}
+
+	results := []sarifResult{}
+	for _, profile := range statistics.CoverageProfile {
+		relative := strings.TrimPrefix(strings.TrimPrefix(profile.FileName, o.ModulePath), "/")
+		uri := path.Join(filepath.ToSlash(o.ModuleDir), relative)
+		result := func(startLine, endLine int, message string) sarifResult {
+			return sarifResult{
+				RuleID:  ruleID,
+				Level:   level,
+				Message: sarifMessage{Text: message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri, URIBaseID: "%SRCROOT%"},
+					Region:           sarifRegion{StartLine: startLine, EndLine: endLine},
+				}}},
+			}
+		}
+
+		if o.Granularity == SARIFSectionGranularity {
+			for _, section := range profile.ViolationSections {
+				message := fmt.Sprintf("%d %s lines are not covered by tests: %s", len(section.ViolationLines), what, LineRanges(section.ViolationLines))
+				results = append(results, result(section.StartLine, section.EndLine, message))
+			}
+			continue
+		}
+		for _, line := range profile.TotalViolationLines {
+			results = append(results, result(line, line, fmt.Sprintf("This %s line is not covered by tests.", what)))
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "gocover",
+				InformationURI: "https://github.com/Azure/gocover",
+				Rules: []sarifRule{{
+					ID:                   ruleID,
+					ShortDescription:     sarifMessage{Text: "Lines not covered by tests"},
+					DefaultConfiguration: sarifConfiguration{Level: level},
+				}},
+			}},
+			Results: results,
+		}},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
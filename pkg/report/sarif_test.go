@@ -0,0 +1,92 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	statistics := &Statistics{
+		StatisticsType: DiffStatisticsType,
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName:            "github.com/Azure/gocover/modulea/pkg/foo.go",
+				TotalViolationLines: []int{5, 6, 9},
+				ViolationSections: []*ViolationSection{
+					{StartLine: 4, EndLine: 7, ViolationLines: []int{5, 6}},
+					{StartLine: 9, EndLine: 9, ViolationLines: []int{9}},
+				},
+			},
+		},
+	}
+
+	write := func(settings SARIFSettings) *sarifLog {
+		var buf bytes.Buffer
+		err := WriteSARIF(&buf, statistics, &SARIFReportOption{
+			ModulePath:    "github.com/Azure/gocover/modulea",
+			ModuleDir:     "./modulea",
+			SARIFSettings: settings,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		log := &sarifLog{}
+		if err := json.Unmarshal(buf.Bytes(), log); err != nil {
+			t.Fatal(err)
+		}
+		return log
+	}
+
+	t.Run("line", func(t *testing.T) {
+		log := write(SARIFSettings{})
+		if log.Version != sarifVersion || len(log.Runs) != 1 {
+			t.Fatalf("unexpected sarif log %+v", log)
+		}
+		run := log.Runs[0]
+		if run.Tool.Driver.Rules[0].ID != DefaultSARIFRuleID || len(run.Results) != 3 {
+			t.Fatalf("unexpected run %+v", run)
+		}
+		r := run.Results[1]
+		location := r.Locations[0].PhysicalLocation
+		if r.Level != DefaultSARIFLevel || location.ArtifactLocation.URI != "modulea/pkg/foo.go" || location.Region.StartLine != 6 || location.Region.EndLine != 6 {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("section", func(t *testing.T) {
+		log := write(SARIFSettings{RuleID: "coverage/changed", Level: "error", Granularity: SARIFSectionGranularity})
+		results := log.Runs[0].Results
+		if len(results) != 2 {
+			t.Fatalf("expect a result for each section, but get %+v", results)
+		}
+		r := results[0]
+		region := r.Locations[0].PhysicalLocation.Region
+		if r.RuleID != "coverage/changed" || r.Level != "error" || region.StartLine != 4 || region.EndLine != 7 || r.Message.Text != "2 changed lines are not covered by tests: 5-6" {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("no violations", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteSARIF(&buf, &Statistics{}, &SARIFReportOption{}); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte(`"results": []`)) {
+			t.Errorf("expect empty results, but get %s", buf.String())
+		}
+	})
+}
+
+func TestSARIFSettingsValidate(t *testing.T) {
+	if err := (&SARIFSettings{Level: "critical"}).Validate(); !errors.Is(err, ErrUnknownSARIFLevel) {
+		t.Errorf("expect ErrUnknownSARIFLevel, but get %v", err)
+	}
+	if err := (&SARIFSettings{Granularity: "file"}).Validate(); !errors.Is(err, ErrUnknownSARIFGranularity) {
+		t.Errorf("expect ErrUnknownSARIFGranularity, but get %v", err)
+	}
+	if err := (&SARIFSettings{Level: "note", Granularity: SARIFSectionGranularity}).Validate(); err != nil {
+		t.Errorf("expect valid settings, but get %v", err)
+	}
+}
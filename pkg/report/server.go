@@ -0,0 +1,330 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The paths of the report server.
+const (
+	ServerTreePath       = "/"
+	ServerFilePath       = "/file"
+	ServerViolationsPath = "/violations"
+)
+
+// The sort keys of the report server, the files and the packages are sorted by the name by default.
+const (
+	SortByName     = "name"
+	SortByCoverage = "coverage"
+)
+
+// ReportServerOption contains the input for the report server.
+type ReportServerOption struct {
+	// Report is the json report or the json document that the server renders, it's read again when it's modified,
+	// so a new run shows up on the next request.
+	Report string
+	// Style is the code style of the annotated sources.
+	Style string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module that the source files are read from.
+	ModuleDir string
+	// ContextLines is the number of the unchanged lines shown around the changed lines of a diff report.
+	ContextLines int
+	// Theme is the theme of the pages, the light theme is used if it's nil.
+	Theme *Theme
+}
+
+// ReportServer renders a coverage report as web pages, the coverage tree of the packages and the files,
+// the annotated source of each file and the list of the uncovered lines, so a report is reviewed locally
+// without generating and shipping the static reports.
+type ReportServer struct {
+	option    *ReportServerOption
+	annotator *annotatedReportGenerator
+	logger    logrus.FieldLogger
+
+	mu         sync.Mutex
+	modTime    time.Time
+	statistics *Statistics
+}
+
+// NewReportServer creates a report server of the report in the option.
+func NewReportServer(o *ReportServerOption, logger logrus.FieldLogger) *ReportServer {
+	annotator := NewAnnotatedReportGenerator(&AnnotatedReportOption{
+		Style:        o.Style,
+		ModulePath:   o.ModulePath,
+		ModuleDir:    o.ModuleDir,
+		ContextLines: o.ContextLines,
+		Theme:        o.Theme,
+	}, logger).(*annotatedReportGenerator)
+	return &ReportServer{option: o, annotator: annotator, logger: logger}
+}
+
+// Handler returns the http handler of the server.
+func (s *ReportServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ServerTreePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ServerTreePath {
+			http.NotFound(w, r)
+			return
+		}
+		s.handleList(w, r, false)
+	})
+	mux.HandleFunc(ServerViolationsPath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleList(w, r, true)
+	})
+	mux.HandleFunc(ServerFilePath, s.handleFile)
+	return mux
+}
+
+// ListenAndServe serves the report on the address until the context is done.
+func (s *ReportServer) ListenAndServe(ctx context.Context, address string) error {
+	// the report is read before listening, so a bad report fails at once instead of on the first request.
+	if _, err := s.load(); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Infof("serve %s on http://%s", s.option.Report, address)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// load returns the statistics of the report, the report is read again if it's modified since the last read.
+func (s *ReportServer) load() (*Statistics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.option.Report)
+	if err != nil {
+		return nil, err
+	}
+	if s.statistics != nil && info.ModTime().Equal(s.modTime) {
+		return s.statistics, nil
+	}
+
+	f, err := os.Open(s.option.Report)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	statistics, err := ReadStatistics(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", s.option.Report, err)
+	}
+	if statistics == nil {
+		return nil, fmt.Errorf("read %s: %w", s.option.Report, errors.New("no statistics in the report"))
+	}
+	s.statistics, s.modTime = statistics, info.ModTime()
+	return statistics, nil
+}
+
+// serverQuery is the filter and the order of the files in the pages.
+type serverQuery struct {
+	// Package keeps the files of the packages whose import paths contain it, all the files are kept if it's empty.
+	Package string
+	Sort    string
+	Order   string
+}
+
+// parseServerQuery reads the query of the request, the unknown sort keys and orders fall back to the defaults.
+func parseServerQuery(values url.Values) serverQuery {
+	q := serverQuery{Package: strings.TrimSpace(values.Get("package")), Sort: SortByName, Order: "asc"}
+	if values.Get("sort") == SortByCoverage {
+		q.Sort = SortByCoverage
+	}
+	if values.Get("order") == "desc" {
+		q.Order = "desc"
+	}
+	return q
+}
+
+// URL returns the url of the page with the query, so the filter is kept across the pages.
+func (q serverQuery) URL(page string) string {
+	values := url.Values{}
+	if q.Package != "" {
+		values.Set("package", q.Package)
+	}
+	if q.Sort != SortByName {
+		values.Set("sort", q.Sort)
+	}
+	if q.Order != "asc" {
+		values.Set("order", q.Order)
+	}
+	if len(values) == 0 {
+		return page
+	}
+	return page + "?" + values.Encode()
+}
+
+// less compares two entries by the sort key and the order, the names break the ties.
+func (q serverQuery) less(nameI, nameJ string, percentI, percentJ float64) bool {
+	if q.Sort == SortByCoverage && percentI != percentJ {
+		if q.Order == "desc" {
+			return percentI > percentJ
+		}
+		return percentI < percentJ
+	}
+	if q.Order == "desc" && q.Sort == SortByName {
+		return nameI > nameJ
+	}
+	return nameI < nameJ
+}
+
+// serverPackage is a package in the coverage tree, with the files directly in its directory.
+type serverPackage struct {
+	Name            string
+	Files           []*CoverageProfile
+	EffectiveLines  int
+	CoveredLines    int
+	ViolationLines  int
+	CoveragePercent float64
+}
+
+// serverPage is the data of the list pages of the report server.
+type serverPage struct {
+	*Statistics
+	Theme *Theme
+	Query serverQuery
+	// Violations indicates the page lists the uncovered lines rather than the coverage tree.
+	Violations bool
+	// Packages are the packages of the coverage tree that match the filter.
+	Packages []*serverPackage
+	// Files are the files with the uncovered lines that match the filter.
+	Files []*CoverageProfile
+}
+
+func (s *ReportServer) handleList(w http.ResponseWriter, r *http.Request, violations bool) {
+	statistics, err := s.load()
+	if err != nil {
+		s.logger.WithError(err).Error("load report")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := parseServerQuery(r.URL.Query())
+	page := &serverPage{Statistics: statistics, Theme: s.option.Theme.orDefault(), Query: query, Violations: violations}
+	if violations {
+		page.Files = violationFiles(statistics.CoverageProfile, query)
+	} else {
+		page.Packages = packageTree(statistics.CoverageProfile, query)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := htmlServerReportTemplate.Execute(w, page); err != nil {
+		s.logger.WithError(err).Error("render page")
+	}
+}
+
+func (s *ReportServer) handleFile(w http.ResponseWriter, r *http.Request) {
+	statistics, err := s.load()
+	if err != nil {
+		s.logger.WithError(err).Error("load report")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	for _, profile := range statistics.CoverageProfile {
+		if profile.FileName != name {
+			continue
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := s.annotator.render(w, statistics, []*CoverageProfile{profile}, ServerTreePath); err != nil {
+			s.logger.WithError(err).Errorf("render %s", name)
+		}
+		return
+	}
+	http.Error(w, fmt.Sprintf("file %s is not in the report", name), http.StatusNotFound)
+}
+
+// packageTree groups the profiles by the packages that match the filter, it sorts the packages and their files.
+func packageTree(profiles []*CoverageProfile, query serverQuery) []*serverPackage {
+	index := make(map[string]*serverPackage)
+	var packages []*serverPackage
+	for _, p := range profiles {
+		name := path.Dir(p.FileName)
+		if !strings.Contains(name, query.Package) {
+			continue
+		}
+		pkg, ok := index[name]
+		if !ok {
+			pkg = &serverPackage{Name: name}
+			index[name] = pkg
+			packages = append(packages, pkg)
+		}
+		pkg.Files = append(pkg.Files, p)
+		pkg.EffectiveLines += p.TotalEffectiveLines
+		pkg.CoveredLines += p.CoveredLines - p.CoveredButIgnoredLines
+		pkg.ViolationLines += len(p.TotalViolationLines)
+	}
+
+	for _, pkg := range packages {
+		pkg.CoveragePercent = percentCovered(pkg.EffectiveLines, pkg.CoveredLines, 0)
+		sortProfiles(pkg.Files, query)
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return query.less(packages[i].Name, packages[j].Name, packages[i].CoveragePercent, packages[j].CoveragePercent)
+	})
+	return packages
+}
+
+// violationFiles returns the sorted profiles with the uncovered lines in the packages that match the filter.
+func violationFiles(profiles []*CoverageProfile, query serverQuery) []*CoverageProfile {
+	var files []*CoverageProfile
+	for _, p := range profiles {
+		if len(p.TotalViolationLines) != 0 && strings.Contains(path.Dir(p.FileName), query.Package) {
+			files = append(files, p)
+		}
+	}
+	sortProfiles(files, query)
+	return files
+}
+
+func sortProfiles(profiles []*CoverageProfile, query serverQuery) {
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return query.less(profiles[i].FileName, profiles[j].FileName, filePercent(profiles[i]), filePercent(profiles[j]))
+	})
+}
+
+// fileURL returns the url of the annotated source of the file.
+func fileURL(fileName string) string {
+	return ServerFilePath + "?" + url.Values{"name": []string{fileName}}.Encode()
+}
+
+// htmlServerReportTemplate is the render engine for the list pages of the report server.
+var htmlServerReportTemplate = template.Must(
+	template.New("htmlServerReportTemplate").
+		Funcs(template.FuncMap{"PercentCovered": percentCovered}).
+		Funcs(template.FuncMap{"IsFullCoverageReport": isFullCoverageReport}).
+		Funcs(template.FuncMap{"FileURL": fileURL}).
+		Funcs(template.FuncMap{"FileBase": path.Base}).
+		Parse(htmlServerReport),
+)
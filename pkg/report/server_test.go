@@ -0,0 +1,123 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func writeServerReport(t *testing.T, file string, statistics *Statistics) {
+	t.Helper()
+	data, err := json.Marshal(statistics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serverGet(t *testing.T, handler http.Handler, target string) (int, string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	body, _ := io.ReadAll(recorder.Result().Body)
+	return recorder.Code, string(body)
+}
+
+func TestReportServer(t *testing.T) {
+	dir := writeAnnotatedSource(t)
+	statistics := annotatedStatistics(FullStatisticsType)
+	statistics.CoverageProfile = append(statistics.CoverageProfile,
+		&CoverageProfile{FileName: "github.com/Azure/gocover/pkg/bar/bar.go", TotalEffectiveLines: 4, CoveredLines: 4},
+		&CoverageProfile{FileName: "github.com/Azure/gocover/pkg/bar/baz.go", TotalEffectiveLines: 4, CoveredLines: 1, TotalViolationLines: []int{3, 4, 5}},
+	)
+	reportFile := filepath.Join(t.TempDir(), "coverage.json")
+	writeServerReport(t, reportFile, statistics)
+
+	server := NewReportServer(&ReportServerOption{
+		Report:     reportFile,
+		ModulePath: "github.com/Azure/gocover",
+		ModuleDir:  dir,
+	}, logrus.New())
+	handler := server.Handler()
+
+	t.Run("tree", func(t *testing.T) {
+		code, body := serverGet(t, handler, "/")
+		if code != http.StatusOK {
+			t.Fatalf("expect 200, but get %d: %s", code, body)
+		}
+		bar, foo := strings.Index(body, "github.com/Azure/gocover/pkg/bar</td>"), strings.Index(body, "github.com/Azure/gocover/pkg/foo</td>")
+		if bar < 0 || foo < 0 || bar > foo {
+			t.Errorf("expect the packages sorted by the name, but get %s", body)
+		}
+		if !strings.Contains(body, `href="/file?name=github.com%2FAzure%2Fgocover%2Fpkg%2Fbar%2Fbaz.go"`) {
+			t.Errorf("expect the link to the annotated source of baz.go, but get %s", body)
+		}
+	})
+
+	t.Run("filter and sort", func(t *testing.T) {
+		_, body := serverGet(t, handler, "/?package=pkg/bar&sort=coverage")
+		if strings.Contains(body, "pkg/foo") {
+			t.Errorf("expect pkg/foo filtered out, but get %s", body)
+		}
+		baz, bar := strings.Index(body, ">baz.go</a>"), strings.Index(body, ">bar.go</a>")
+		if baz < 0 || bar < 0 || baz > bar {
+			t.Errorf("expect the lowest coverage first, but get %s", body)
+		}
+		if !strings.Contains(body, `href="/violations?package=pkg%2Fbar&amp;sort=coverage"`) {
+			t.Errorf("expect the filter kept in the link to the violations, but get %s", body)
+		}
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		_, body := serverGet(t, handler, "/violations?sort=coverage&order=desc")
+		foo, baz := strings.Index(body, ">github.com/Azure/gocover/pkg/foo/foo.go</a>"), strings.Index(body, ">github.com/Azure/gocover/pkg/bar/baz.go</a>")
+		if foo < 0 || baz < 0 || foo > baz {
+			t.Errorf("expect the highest coverage first, but get %s", body)
+		}
+		if strings.Contains(body, "bar.go</a>") {
+			t.Errorf("expect the covered bar.go not listed, but get %s", body)
+		}
+		if !strings.Contains(body, `baz.go#file-0-L4">4</a>`) {
+			t.Errorf("expect the uncovered line links to the source, but get %s", body)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		code, body := serverGet(t, handler, "/file?name=github.com/Azure/gocover/pkg/foo/foo.go")
+		if code != http.StatusOK {
+			t.Fatalf("expect 200, but get %d: %s", code, body)
+		}
+		if !strings.Contains(body, `id="file-0-L5" class="uncovered"`) || !strings.Contains(body, "All files") {
+			t.Errorf("expect the annotated source with the link back, but get %s", body)
+		}
+		if code, _ := serverGet(t, handler, "/file?name=unknown.go"); code != http.StatusNotFound {
+			t.Errorf("expect 404 of an unknown file, but get %d", code)
+		}
+		if code, _ := serverGet(t, handler, "/unknown"); code != http.StatusNotFound {
+			t.Errorf("expect 404 of an unknown path, but get %d", code)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		statistics.CoverageProfile = statistics.CoverageProfile[1:2]
+		writeServerReport(t, reportFile, statistics)
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(reportFile, later, later); err != nil {
+			t.Fatal(err)
+		}
+		_, body := serverGet(t, handler, "/")
+		if strings.Contains(body, "pkg/foo") {
+			t.Errorf("expect the modified report read again, but get %s", body)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package report
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrInvalidPathMapping = errors.New("invalid path mapping, it should be in the format of from=to")
+
+// PathMapping replaces the prefix of a repository relative path.
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// ParsePathMappings parses the mappings in the format of from=to, such as services/api/=src/,
+// the to part can be empty to strip the prefix.
+func ParsePathMappings(mappings []string) ([]*PathMapping, error) {
+	result := make([]*PathMapping, 0, len(mappings))
+	for _, m := range mappings {
+		from, to, ok := strings.Cut(m, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPathMapping, m)
+		}
+		result = append(result, &PathMapping{From: from, To: to})
+	}
+	return result, nil
+}
+
+// mapPath replaces the prefix of the path by the first mapping that matches, the path is kept if none matches.
+func mapPath(p string, mappings []*PathMapping) string {
+	for _, m := range mappings {
+		if strings.HasPrefix(p, m.From) {
+			return m.To + strings.TrimPrefix(p, m.From)
+		}
+	}
+	return p
+}
+
+// SonarQubeReportOption contains the input for the sonarqube generic coverage report generator.
+type SonarQubeReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root,
+	// since sonarqube locates the files relative to the project base directory, which is the repository root by default.
+	ModuleDir string
+	// PathMappings map the repository relative paths to the paths that sonarqube expects,
+	// such as the project base directory is a sub directory of the repository.
+	PathMappings []*PathMapping
+}
+
+type sonarQubeCoverage struct {
+	XMLName xml.Name        `xml:"coverage"`
+	Version int             `xml:"version,attr"`
+	Files   []sonarQubeFile `xml:"file"`
+}
+
+type sonarQubeFile struct {
+	Path  string          `xml:"path,attr"`
+	Lines []sonarQubeLine `xml:"lineToCover"`
+}
+
+type sonarQubeLine struct {
+	LineNumber int  `xml:"lineNumber,attr"`
+	Covered    bool `xml:"covered,attr"`
+}
+
+// sonarQubeReportGenerator writes the statistics as a sonarqube generic coverage report.
+type sonarQubeReportGenerator struct {
+	option *SonarQubeReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*sonarQubeReportGenerator)(nil)
+
+// NewSonarQubeReportGenerator creates a report generator that writes a generic coverage xml report,
+// which is imported by sonarqube with sonar.coverageReportPaths. A diff report only contains the changed files and lines,
+// so sonarqube shows the diff coverage that gocover gates on.
+func NewSonarQubeReportGenerator(o *SonarQubeReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &sonarQubeReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the sonarqube generic coverage report.
+func (g *sonarQubeReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.sonarqube.xml", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteSonarQube(w, statistics, g.option)
+	})
+	if err != nil {
+		return fmt.Errorf("write sonarqube report: %w", err)
+	}
+
+	g.logger.Infof("generate sonarqube coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteSonarQube writes the counted lines of the statistics as a sonarqube generic coverage report,
+// the lines are merged as the cobertura report, and the files without counted lines are skipped.
+// The paths are relative to the repository root, and then mapped by the path mappings.
+func WriteSonarQube(w io.Writer, statistics *Statistics, o *SonarQubeReportOption) error {
+	coverage := &sonarQubeCoverage{Version: 1, Files: []sonarQubeFile{}}
+	for _, profile := range statistics.CoverageProfile {
+		lines := coberturaLines(profile.CountedLines)
+		if len(lines) == 0 {
+			continue
+		}
+
+		relative := strings.TrimPrefix(strings.TrimPrefix(profile.FileName, o.ModulePath), "/")
+		file := sonarQubeFile{Path: mapPath(path.Join(filepath.ToSlash(o.ModuleDir), relative), o.PathMappings)}
+		for _, line := range lines {
+			file.Lines = append(file.Lines, sonarQubeLine{LineNumber: line.Number, Covered: line.Hits > 0})
+		}
+		coverage.Files = append(coverage.Files, file)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(coverage); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSonarQubeReportGenerator(t *testing.T) {
+	statistics := &Statistics{
+		CoverageProfile: []*CoverageProfile{
+			{
+				FileName: "github.com/Azure/gocover/pkg/foo/foo.go",
+				CountedLines: []*CountedLine{
+					{Line: 3, Covered: true},
+					{Line: 5, Ignored: true},
+					{Line: 7, Covered: true},
+					{Line: 7},
+				},
+			},
+			{
+				FileName:     "github.com/Azure/gocover/cmd/main.go",
+				CountedLines: []*CountedLine{{Line: 2, Covered: true}},
+			},
+			{FileName: "github.com/Azure/gocover/pkg/bar/bar.go"},
+		},
+	}
+	mappings, err := ParsePathMappings([]string{"module/pkg/=src/", "module/cmd/="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	g := NewSonarQubeReportGenerator(&SonarQubeReportOption{
+		OutputDir:    output,
+		ReportName:   "coverage",
+		ModulePath:   "github.com/Azure/gocover",
+		ModuleDir:    "./module",
+		PathMappings: mappings,
+	}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "coverage.sonarqube.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<coverage version="1">
+  <file path="src/foo/foo.go">
+    <lineToCover lineNumber="3" covered="true"></lineToCover>
+    <lineToCover lineNumber="7" covered="false"></lineToCover>
+  </file>
+  <file path="main.go">
+    <lineToCover lineNumber="2" covered="true"></lineToCover>
+  </file>
+</coverage>
+`
+	if string(data) != expected {
+		t.Errorf("expect sonarqube report\n%s\nbut get\n%s", expected, string(data))
+	}
+}
+
+func TestParsePathMappings(t *testing.T) {
+	for _, m := range []string{"module", "=src/"} {
+		if _, err := ParsePathMappings([]string{m}); !errors.Is(err, ErrInvalidPathMapping) {
+			t.Errorf("expect ErrInvalidPathMapping of %q, but get %v", m, err)
+		}
+	}
+	if p := mapPath("pkg/foo.go", []*PathMapping{{From: "cmd/", To: ""}}); p != "pkg/foo.go" {
+		t.Errorf("expect the path is kept without a matched mapping, but get %s", p)
+	}
+}
@@ -0,0 +1,236 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+	"github.com/sirupsen/logrus"
+)
+
+// The gate status of the elements in the spdx report.
+const (
+	SPDXGatePassed   = "passed"
+	SPDXGateFailed   = "failed"
+	SPDXGateBypassed = "bypassed"
+	SPDXGateNotGated = "not-gated"
+)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Comment           string             `json:"comment,omitempty"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string           `json:"name"`
+	SPDXID           string           `json:"SPDXID"`
+	DownloadLocation string           `json:"downloadLocation"`
+	FilesAnalyzed    bool             `json:"filesAnalyzed"`
+	Annotations      []spdxAnnotation `json:"annotations"`
+}
+
+type spdxAnnotation struct {
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	Comment        string `json:"comment"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// SPDXReportOption contains the input for the spdx report generator.
+type SPDXReportOption struct {
+	// OutputDir is the directory that the report is written into.
+	OutputDir string
+	// ReportName is the name of the report without the extension.
+	ReportName string
+	// ModulePath is the path of the go module, it's the name of the package that contains the go packages.
+	ModulePath string
+	// CoverageBaseline is the expected total coverage, the total coverage is not gated if it's zero.
+	CoverageBaseline float64
+}
+
+// spdxReportGenerator writes the coverage and the gate status of each package as an spdx document.
+type spdxReportGenerator struct {
+	option *SPDXReportOption
+	logger logrus.FieldLogger
+}
+
+var _ ReportGenerator = (*spdxReportGenerator)(nil)
+
+// NewSPDXReportGenerator creates a report generator that writes an spdx 2.3 json document, the module and each package
+// whose files are counted are spdx packages annotated with their coverage and gate status. It's the evidence of the test
+// coverage of the changes for the compliance tools that read the sboms, and is attached to the releases along with them.
+func NewSPDXReportGenerator(o *SPDXReportOption, logger logrus.FieldLogger) ReportGenerator {
+	return &spdxReportGenerator{option: o, logger: logger}
+}
+
+// GenerateReport writes the spdx report.
+func (g *spdxReportGenerator) GenerateReport(statistics *Statistics) error {
+	reportFile := filepath.Join(g.option.OutputDir, fmt.Sprintf("%s.spdx.json", g.option.ReportName))
+	err := atomicfile.WriteFile(reportFile, func(w io.Writer) error {
+		return WriteSPDX(w, statistics, g.option, time.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("write spdx report: %w", err)
+	}
+
+	g.logger.Infof("generate spdx coverage report: %s", reportFile)
+	return nil
+}
+
+// WriteSPDX writes the statistics as an spdx document created at the time. The document describes the module,
+// which contains a package of each package of the statistics. Each package is annotated with its coverage, its baseline
+// and whether it passed, and the module with the total coverage and the status of the whole coverage gate.
+func WriteSPDX(w io.Writer, statistics *Statistics, o *SPDXReportOption, created time.Time) error {
+	date := created.UTC().Format(time.RFC3339)
+	annotator := "Tool: gocover-" + ToolVersion
+	module := o.ModulePath
+	if module == "" {
+		module = "module"
+	}
+
+	document := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              fmt.Sprintf("gocover %s coverage of %s", statistics.StatisticsType, module),
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/gocover-%s-%s-%s", spdxIDString(module), statistics.StatisticsType, created.UTC().Format("20060102T150405.000000000Z")),
+		CreationInfo:      spdxCreationInfo{Created: date, Creators: []string{annotator}},
+		Comment:           spdxComment(statistics),
+		Packages:          []spdxPackage{},
+		Relationships:     []spdxRelationship{},
+	}
+
+	annotation := func(comment string) []spdxAnnotation {
+		return []spdxAnnotation{{AnnotationDate: date, AnnotationType: "OTHER", Annotator: annotator, Comment: comment}}
+	}
+	coveredLines := statistics.TotalCoveredLines - statistics.TotalCoveredButIgnoredLines
+	moduleID := "SPDXRef-Package-" + spdxIDString(module)
+	document.Packages = append(document.Packages, spdxPackage{
+		Name:             module,
+		SPDXID:           moduleID,
+		DownloadLocation: "NOASSERTION",
+		Annotations: annotation(spdxCoverageComment(statistics.TotalCoveragePercent, statistics.TotalEffectiveLines,
+			coveredLines, o.CoverageBaseline, spdxGateStatus(statistics, o.CoverageBaseline))),
+	})
+	document.Relationships = append(document.Relationships, spdxRelationship{
+		SPDXElementID: document.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: moduleID,
+	})
+
+	for i, p := range statistics.Packages {
+		status := SPDXGateNotGated
+		if p.Baseline > 0 {
+			status = SPDXGateFailed
+			if p.Passed() {
+				status = SPDXGatePassed
+			}
+		}
+		id := fmt.Sprintf("SPDXRef-Package-%d-%s", i+1, spdxIDString(p.Name))
+		document.Packages = append(document.Packages, spdxPackage{
+			Name:             p.Name,
+			SPDXID:           id,
+			DownloadLocation: "NOASSERTION",
+			Annotations:      annotation(spdxCoverageComment(p.CoveragePercent, p.TotalEffectiveLines, p.TotalCoveredLines, p.Baseline, status)),
+		})
+		document.Relationships = append(document.Relationships, spdxRelationship{
+			SPDXElementID: moduleID, RelationshipType: "CONTAINS", RelatedSPDXElement: id,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// spdxGateStatus returns the status of the coverage gate by the decision of the gate, or by the total coverage
+// and the baselines in the statistics if the gate isn't evaluated. A failed gate is bypassed if the statistics has a bypass.
+func spdxGateStatus(statistics *Statistics, coverageBaseline float64) string {
+	failed := statistics.GateFailed(coverageBaseline)
+	if statistics.Gate == nil {
+		for _, p := range statistics.CoverageProfile {
+			failed = failed || (statistics.FileBaseline > 0 && p.GraceUntil == "" && filePercent(p) < statistics.FileBaseline)
+		}
+		for _, l := range statistics.Layers {
+			failed = failed || !l.Passed()
+		}
+		for _, p := range statistics.Packages {
+			failed = failed || !p.Passed()
+		}
+		if r := statistics.Regression; r != nil {
+			for _, f := range r.Files {
+				failed = failed || !f.Passed(r.Tolerance)
+			}
+		}
+		if statistics.Todos != nil {
+			failed = failed || !statistics.Todos.Passed()
+		}
+	}
+	switch {
+	case !failed:
+		return SPDXGatePassed
+	case statistics.Bypass != nil:
+		return SPDXGateBypassed
+	default:
+		return SPDXGateFailed
+	}
+}
+
+// spdxCoverageComment returns the comment of the coverage annotation in the format of key=value pairs,
+// so the compliance tools parse it.
+func spdxCoverageComment(percent float64, effectiveLines, coveredLines int, baseline float64, status string) string {
+	comment := fmt.Sprintf("gocover: coverage=%.2f%%; effectiveLines=%d; coveredLines=%d", percent, effectiveLines, coveredLines)
+	if baseline > 0 {
+		comment += fmt.Sprintf("; baseline=%.2f%%", baseline)
+	}
+	return comment + "; gate=" + status
+}
+
+// spdxComment describes what the coverage is calculated on.
+func spdxComment(statistics *Statistics) string {
+	comment := "Test coverage of the module calculated by gocover."
+	if statistics.StatisticsType == DiffStatisticsType {
+		target := statistics.DiffTarget
+		if target == "" {
+			target = "HEAD"
+		}
+		comment = fmt.Sprintf("Test coverage of the changes between %s and %s calculated by gocover.", statistics.ComparedBranch, target)
+	}
+	if statistics.Bypass != nil {
+		comment += fmt.Sprintf(" The coverage gate is bypassed by %s: %s.", statistics.Bypass.Source, statistics.Bypass.Reason)
+	}
+	return comment
+}
+
+// spdxIDString replaces the characters that an spdx identifier can't contain with dashes,
+// the identifiers only contain letters, numbers, dots and dashes.
+func spdxIDString(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+}
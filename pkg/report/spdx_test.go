@@ -0,0 +1,89 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSPDX(t *testing.T) {
+	statistics := &Statistics{
+		StatisticsType:       DiffStatisticsType,
+		ComparedBranch:       "origin/main",
+		TotalEffectiveLines:  10,
+		TotalCoveredLines:    9,
+		TotalCoveragePercent: 90,
+		Packages: []*PackageStatistics{
+			{Name: "github.com/Azure/foo/pkg/a", TotalEffectiveLines: 6, TotalCoveredLines: 6, CoveragePercent: 100, Baseline: 95},
+			{Name: "github.com/Azure/foo/pkg/b", TotalEffectiveLines: 4, TotalCoveredLines: 3, CoveragePercent: 75},
+		},
+	}
+	created := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+
+	var b bytes.Buffer
+	if err := WriteSPDX(&b, statistics, &SPDXReportOption{ModulePath: "github.com/Azure/foo", CoverageBaseline: 80}, created); err != nil {
+		t.Fatalf("write spdx: %v", err)
+	}
+	document := &spdxDocument{}
+	if err := json.Unmarshal(b.Bytes(), document); err != nil {
+		t.Fatalf("decode spdx: %v", err)
+	}
+	if document.SPDXVersion != "SPDX-2.3" || document.CreationInfo.Created != "2026-10-14T08:00:00Z" || !strings.Contains(document.Comment, "origin/main and HEAD") {
+		t.Errorf("unexpected document %+v", document)
+	}
+	if !strings.HasPrefix(document.DocumentNamespace, "https://spdx.org/spdxdocs/gocover-github.com-Azure-foo-diff-") {
+		t.Errorf("unexpected namespace %s", document.DocumentNamespace)
+	}
+	if len(document.Packages) != 3 || len(document.Relationships) != 3 {
+		t.Fatalf("expect the module and 2 packages, but get %+v", document)
+	}
+
+	expects := []struct {
+		id, comment string
+	}{
+		{"SPDXRef-Package-github.com-Azure-foo", "gocover: coverage=90.00%; effectiveLines=10; coveredLines=9; baseline=80.00%; gate=passed"},
+		{"SPDXRef-Package-1-github.com-Azure-foo-pkg-a", "gocover: coverage=100.00%; effectiveLines=6; coveredLines=6; baseline=95.00%; gate=passed"},
+		{"SPDXRef-Package-2-github.com-Azure-foo-pkg-b", "gocover: coverage=75.00%; effectiveLines=4; coveredLines=3; gate=not-gated"},
+	}
+	for i, expect := range expects {
+		p := document.Packages[i]
+		if p.SPDXID != expect.id || len(p.Annotations) != 1 || p.Annotations[0].Comment != expect.comment {
+			t.Errorf("expect package %s annotated with %q, but get %+v", expect.id, expect.comment, p)
+		}
+	}
+	if r := document.Relationships[0]; r.SPDXElementID != "SPDXRef-DOCUMENT" || r.RelationshipType != "DESCRIBES" {
+		t.Errorf("expect the document describes the module, but get %+v", r)
+	}
+	if r := document.Relationships[2]; r.SPDXElementID != expects[0].id || r.RelationshipType != "CONTAINS" || r.RelatedSPDXElement != expects[2].id {
+		t.Errorf("expect the module contains the package, but get %+v", r)
+	}
+}
+
+func TestSPDXGateStatus(t *testing.T) {
+	statistics := &Statistics{TotalCoveragePercent: 70}
+	if status := spdxGateStatus(statistics, 0); status != SPDXGatePassed {
+		t.Errorf("expect passed without the baseline, but get %s", status)
+	}
+	if status := spdxGateStatus(statistics, 80); status != SPDXGateFailed {
+		t.Errorf("expect failed below the baseline, but get %s", status)
+	}
+	statistics.Bypass = &Bypass{Source: "label", Reason: "hotfix"}
+	if status := spdxGateStatus(statistics, 80); status != SPDXGateBypassed {
+		t.Errorf("expect bypassed, but get %s", status)
+	}
+
+	statistics = &Statistics{
+		TotalCoveragePercent: 90,
+		FileBaseline:         80,
+		CoverageProfile:      []*CoverageProfile{{FileName: "a.go", TotalEffectiveLines: 2, CoveredLines: 1, GraceUntil: "2026-12-31"}},
+	}
+	if status := spdxGateStatus(statistics, 80); status != SPDXGatePassed {
+		t.Errorf("expect the file in the grace period not gated, but get %s", status)
+	}
+	statistics.Packages = []*PackageStatistics{{Name: "a", CoveragePercent: 50, Baseline: 60}}
+	if status := spdxGateStatus(statistics, 80); status != SPDXGateFailed {
+		t.Errorf("expect failed by the package baseline, but get %s", status)
+	}
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestEmbeddedAssets generates the reports out of the source tree, so they only use the assets embedded into the binary.
+func TestEmbeddedAssets(t *testing.T) {
+	moduleDir := writeAnnotatedSource(t)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	theme, err := (&ThemeSettings{Theme: DarkTheme, Palette: ColorBlindPalette}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	generators := []ReportGenerator{
+		NewThemedReportGenerator("colorful", dir, "coverage", theme, logger),
+		NewAnnotatedReportGenerator(&AnnotatedReportOption{OutputDir: dir, ReportName: "annotated", ModulePath: "github.com/Azure/gocover", ModuleDir: moduleDir, Theme: theme}, logger),
+		NewArtifactsGenerator(&ArtifactsOption{OutputDir: filepath.Join(dir, "artifacts"), Style: "colorful", Theme: theme}, logger),
+	}
+	for _, g := range generators {
+		if err := g.GenerateReport(annotatedStatistics(DiffStatisticsType)); err != nil {
+			t.Errorf("generate report: %s", err)
+		}
+	}
+	for _, file := range []string{"coverage.html", "annotated.html", filepath.Join("artifacts", BadgeArtifact)} {
+		if info, err := os.Stat(filepath.Join(dir, file)); err != nil || info.Size() == 0 {
+			t.Errorf("%s should be generated, but get %v", file, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package report
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The themes of the html reports.
+const (
+	// LightTheme is the default theme of the html reports.
+	LightTheme = "light"
+	// DarkTheme renders the html reports with a dark background, it's better paired with a dark code style, such as github-dark.
+	DarkTheme = "dark"
+)
+
+// The palettes of the coverage states of the html reports.
+const (
+	// DefaultPalette colors the covered lines green and the uncovered lines red.
+	DefaultPalette = "default"
+	// ColorBlindPalette colors the covered lines blue and the uncovered lines orange,
+	// which are distinguishable with the common color vision deficiencies.
+	ColorBlindPalette = "color-blind"
+)
+
+var (
+	ErrUnknownTheme   = errors.New("unknown theme, one of: light, dark")
+	ErrUnknownPalette = errors.New("unknown palette, one of: default, color-blind")
+)
+
+// ThemeSettings decides the look of the html reports, so they can be embedded in the portals with a consistent branding.
+type ThemeSettings struct {
+	// Theme is one of LightTheme and DarkTheme, LightTheme is used if it's empty.
+	Theme string
+	// Palette is one of DefaultPalette and ColorBlindPalette, DefaultPalette is used if it's empty.
+	Palette string
+	// CSSFile is the css file appended to the style of the html reports, so it overrides the theme.
+	CSSFile string
+	// Logo is the url or the image file of the logo shown in the header of the html reports,
+	// the image file is embedded as a data url so the reports stay in one file.
+	Logo string
+}
+
+// Load validates the theme and the palette, and reads the css file and the logo.
+func (s *ThemeSettings) Load() (*Theme, error) {
+	theme := &Theme{Name: s.Theme}
+	switch s.Theme {
+	case "":
+		theme.Name = LightTheme
+	case LightTheme:
+	case DarkTheme:
+		theme.CSS = darkThemeCSS
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTheme, s.Theme)
+	}
+	theme.Palette = s.Palette
+	switch s.Palette {
+	case "":
+		theme.Palette = DefaultPalette
+	case DefaultPalette:
+	case ColorBlindPalette:
+		theme.CSS += colorBlindPaletteCSS
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPalette, s.Palette)
+	}
+
+	if s.CSSFile != "" {
+		data, err := os.ReadFile(s.CSSFile)
+		if err != nil {
+			return nil, fmt.Errorf("read css file: %w", err)
+		}
+		theme.CSS += template.CSS(data)
+	}
+
+	logo, err := logoURL(s.Logo)
+	if err != nil {
+		return nil, fmt.Errorf("read logo: %w", err)
+	}
+	theme.Logo = logo
+	return theme, nil
+}
+
+// Theme is the loaded theme of the html reports.
+type Theme struct {
+	// Name is the name of the theme, it's the data-theme attribute of the page so the custom css can select it.
+	Name string
+	// Palette is the name of the palette, it's the data-palette attribute of the page.
+	Palette string
+	// CSS is the style of the theme, the palette and the custom css, it's appended to the style of the report.
+	CSS template.CSS
+	// Logo is the url of the logo, no logo is shown if it's empty.
+	Logo template.URL
+}
+
+// orDefault returns the light theme if the theme is nil, such as the reports that are not themed.
+func (t *Theme) orDefault() *Theme {
+	if t == nil {
+		return &Theme{Name: LightTheme, Palette: DefaultPalette}
+	}
+	return t
+}
+
+// highlightColor returns the background color of the uncovered lines of the code snippets, which are colored by chroma.
+func (t *Theme) highlightColor() string {
+	if t != nil && t.Palette == ColorBlindPalette {
+		return colorBlindHighlightColor
+	}
+	return codeHighlightColor
+}
+
+// logoURL returns the logo as is if it's a http, https or data url, otherwise reads the image file and returns its data url.
+func logoURL(logo string) (template.URL, error) {
+	if logo == "" {
+		return "", nil
+	}
+	for _, prefix := range []string{"http://", "https://", "data:"} {
+		if strings.HasPrefix(logo, prefix) {
+			return template.URL(logo), nil
+		}
+	}
+
+	data, err := os.ReadFile(logo)
+	if err != nil {
+		return "", err
+	}
+	mediaType := mime.TypeByExtension(filepath.Ext(logo))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	return template.URL(fmt.Sprintf("data:%s;base64,%s", mediaType, base64.StdEncoding.EncodeToString(data))), nil
+}
+
+// darkThemeCSS overrides the colors of the html and the annotated reports, the code snippets keep the colors of the code style.
+var darkThemeCSS = template.CSS(mustReadAsset("templates/dark.css"))
+
+// colorBlindHighlightColor is the background color of the uncovered lines of the code snippets of the color-blind palette.
+const colorBlindHighlightColor = "bg:#ffd9b3"
+
+// colorBlindPaletteCSS overrides the colors of the coverage states with the blue and the orange,
+// the dark selectors are more specific so they override the dark theme.
+var colorBlindPaletteCSS = template.CSS(mustReadAsset("templates/color-blind.css"))
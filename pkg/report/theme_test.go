@@ -0,0 +1,145 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestThemeSettingsLoad(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		theme, err := (&ThemeSettings{}).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if theme.Name != LightTheme || theme.CSS != "" || theme.Logo != "" {
+			t.Errorf("should be the light theme, but get %+v", theme)
+		}
+	})
+
+	t.Run("unknown theme", func(t *testing.T) {
+		_, err := (&ThemeSettings{Theme: "solarized"}).Load()
+		if !errors.Is(err, ErrUnknownTheme) {
+			t.Errorf("should return ErrUnknownTheme, but get %v", err)
+		}
+	})
+
+	t.Run("palette", func(t *testing.T) {
+		theme, err := (&ThemeSettings{Palette: ColorBlindPalette}).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if theme.Palette != ColorBlindPalette || theme.CSS != colorBlindPaletteCSS || theme.highlightColor() != colorBlindHighlightColor {
+			t.Errorf("should be the color-blind palette, but get %+v", theme)
+		}
+		if _, err := (&ThemeSettings{Palette: "rainbow"}).Load(); !errors.Is(err, ErrUnknownPalette) {
+			t.Errorf("should return ErrUnknownPalette, but get %v", err)
+		}
+	})
+
+	t.Run("dark theme with custom css", func(t *testing.T) {
+		dir := t.TempDir()
+		cssFile := filepath.Join(dir, "portal.css")
+		if err := os.WriteFile(cssFile, []byte("body { font-family: Inter; }"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		theme, err := (&ThemeSettings{Theme: DarkTheme, CSSFile: cssFile}).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		css := string(theme.CSS)
+		if !strings.Contains(css, `html[data-theme="dark"] body`) || !strings.HasSuffix(css, "body { font-family: Inter; }") {
+			t.Errorf("the custom css should be appended to the dark theme, but get %s", css)
+		}
+
+		if _, err := (&ThemeSettings{CSSFile: filepath.Join(dir, "missing.css")}).Load(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("should return ErrNotExist, but get %v", err)
+		}
+	})
+
+	t.Run("logo", func(t *testing.T) {
+		theme, err := (&ThemeSettings{Logo: "https://example.com/logo.svg"}).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if theme.Logo != "https://example.com/logo.svg" {
+			t.Errorf("the logo url should be kept, but get %s", theme.Logo)
+		}
+
+		logo := filepath.Join(t.TempDir(), "logo.svg")
+		if err := os.WriteFile(logo, []byte("<svg></svg>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		theme, err = (&ThemeSettings{Logo: logo}).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if theme.Logo != "data:image/svg+xml;base64,PHN2Zz48L3N2Zz4=" {
+			t.Errorf("the logo file should be embedded, but get %s", theme.Logo)
+		}
+	})
+}
+
+func TestThemedReports(t *testing.T) {
+	theme := &Theme{Name: DarkTheme, Palette: ColorBlindPalette, CSS: darkThemeCSS + colorBlindPaletteCSS + "h1 { color: #ff7f00; }", Logo: "data:image/png;base64,AAAA"}
+	wants := []string{
+		`<html lang="en" data-theme="dark" data-palette="color-blind">`,
+		`html[data-theme="dark"] .uncovered`,
+		`html[data-theme="dark"][data-palette="color-blind"] .uncovered`,
+		`<a class="skip-link" href="#files">`,
+		`h1 { color: #ff7f00; }`,
+		`<img class="logo" src="data:image/png;base64,AAAA" alt="logo">`,
+	}
+
+	t.Run("html", func(t *testing.T) {
+		output := t.TempDir()
+		g := NewThemedReportGenerator("github-dark", output, "coverage", theme, logrus.New())
+		if err := g.GenerateReport(&Statistics{StatisticsType: DiffStatisticsType}); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(output, "coverage.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("report should contain %q", want)
+			}
+		}
+	})
+
+	t.Run("annotated", func(t *testing.T) {
+		output := t.TempDir()
+		g := NewAnnotatedReportGenerator(&AnnotatedReportOption{OutputDir: output, ReportName: "annotated", Theme: theme}, logrus.New())
+		if err := g.GenerateReport(&Statistics{StatisticsType: FullStatisticsType}); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(output, "annotated.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("report should contain %q", want)
+			}
+		}
+	})
+
+	t.Run("light by default", func(t *testing.T) {
+		output := t.TempDir()
+		if err := NewReportGenerator("colorful", output, "coverage", logrus.New()).GenerateReport(&Statistics{StatisticsType: DiffStatisticsType}); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(output, "coverage.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `data-theme="light" data-palette="default"`) || strings.Contains(string(data), `class="logo"`) {
+			t.Error("report should be the light theme of the default palette without a logo")
+		}
+	})
+}
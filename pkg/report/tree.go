@@ -2,6 +2,7 @@ package report
 
 import (
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -17,6 +18,9 @@ type CoverageTree interface {
 	CollectCoverageData()
 	All() []*AllInformation
 	Statistics() *AllInformation
+	// Packages returns the coverage of each directory that contains source files in the order of the paths,
+	// it's called after CollectCoverageData.
+	Packages() []*PackageStatistics
 }
 
 type Tree *TreeNode
@@ -117,8 +121,47 @@ func (p *coverageTree) All() []*AllInformation {
 	return result
 }
 
+func (p *coverageTree) Packages() []*PackageStatistics {
+	var result []*PackageStatistics
+
+	var dfs func(root *TreeNode, path string)
+	dfs = func(root *TreeNode, path string) {
+		pkg := &PackageStatistics{Name: path}
+		var covered int64
+		for _, node := range root.Nodes {
+			if !node.isLeaf {
+				dfs(node, strings.TrimLeft(path+seperator+node.Name, seperator))
+				continue
+			}
+			pkg.Files++
+			pkg.TotalEffectiveLines += int(node.TotalEffectiveLines)
+			pkg.TotalCoveredLines += int(node.TotalCoveredLines)
+			covered += node.TotalCoveredLines - node.TotalCoveredButIgnoreLines
+		}
+		if pkg.Files == 0 {
+			return
+		}
+		pkg.CoveragePercent = 100
+		if pkg.TotalEffectiveLines != 0 {
+			pkg.CoveragePercent = float64(covered) / float64(pkg.TotalEffectiveLines) * 100
+		}
+		result = append(result, pkg)
+	}
+
+	if p.Root == nil {
+		return nil
+	}
+	dfs(p.Root, p.Root.Name)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Find returns the node of the package path, it's the root if the path is the module path.
 func (p *coverageTree) Find(pkgPath string) *TreeNode {
 	trimed := strings.TrimPrefix(pkgPath, p.ModuleHostPath)
+	if strings.Trim(trimed, seperator) == "" {
+		return p.Root
+	}
 	tokens := strings.Split(strings.Trim(trimed, seperator), seperator)
 
 	currentNode := p.Root
@@ -1,6 +1,9 @@
 package report
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 var root *TreeNode
 
@@ -194,6 +197,36 @@ func TestCoverageTree(t *testing.T) {
 		}
 	})
 
+	t.Run("Packages", func(t *testing.T) {
+		beforeRun()
+
+		coverageTree := &coverageTree{
+			ModuleHostPath: "github.com/Azure/gocover",
+			Root:           root,
+		}
+		coverageTree.CollectCoverageData()
+		packages := coverageTree.Packages()
+		expected := []PackageStatistics{
+			{Name: "github.com/Azure/gocover/child1", Files: 1, TotalEffectiveLines: 100, TotalCoveredLines: 80, CoveragePercent: 79},
+			{Name: "github.com/Azure/gocover/child1/child3", Files: 1, TotalEffectiveLines: 80, TotalCoveredLines: 50, CoveragePercent: 60},
+			{Name: "github.com/Azure/gocover/child2", Files: 2, TotalEffectiveLines: 110, TotalCoveredLines: 70, CoveragePercent: 57.27},
+		}
+		if len(packages) != len(expected) {
+			t.Fatalf("expect %d packages, but get %d", len(expected), len(packages))
+		}
+		for i, p := range packages {
+			actual := *p
+			actual.CoveragePercent = math.Round(actual.CoveragePercent*100) / 100
+			if actual != expected[i] {
+				t.Errorf("expect package %+v, but get %+v", expected[i], *p)
+			}
+		}
+
+		if packages := NewCoverageTree("github.com/Azure/gocover").Packages(); len(packages) != 0 {
+			t.Errorf("expect no package of the empty tree, but get %d", len(packages))
+		}
+	})
+
 	t.Run("Find", func(t *testing.T) {
 		beforeRun()
 
@@ -229,5 +262,10 @@ func TestCoverageTree(t *testing.T) {
 		if node != nil {
 			t.Errorf("should return nil when not found")
 		}
+
+		node = coverageTree.Find("github.com/Azure/gocover")
+		if node != root {
+			t.Errorf("should return the root for the module path")
+		}
 	})
 }
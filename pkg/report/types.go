@@ -1,7 +1,13 @@
 package report
 
 import (
+	"fmt"
 	"html/template"
+	"time"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/phase"
+	"github.com/Azure/gocover/pkg/testresult"
 )
 
 type StatisticsType string
@@ -16,6 +22,10 @@ const (
 type Statistics struct {
 	// ComparedBranch the branch that diff compared with.
 	ComparedBranch string
+	// DiffTarget is what compared with the branch, such as worktree or stash@{0}, it's HEAD if empty.
+	DiffTarget string `json:",omitempty"`
+	// LineMapping is how the changed lines make the multi-line statements changed, span or first-line, it's empty for full coverage.
+	LineMapping string `json:",omitempty"`
 	// TotalLines represents the total lines that count for coverage.
 	TotalLines int
 	// TotalEffectiveLines indicates effective lines for the coverage profile.
@@ -38,6 +48,267 @@ type Statistics struct {
 	StatisticsType StatisticsType
 	// exclude files that won't take participate to coverage calculation.
 	ExcludeFiles []string
+	// Bypass indicates the coverage gate is bypassed, it's nil if not bypassed.
+	Bypass *Bypass
+	// Gate is the decision of the coverage gate that decides the exit code, the reports show the same result.
+	// It's nil if the gate isn't evaluated, such as a document of an older version.
+	Gate *covergate.Decision `json:",omitempty"`
+	// Rollout is the decision of the gradual rollout of the coverage gate, it's nil if there is no rollout.
+	Rollout *Rollout `json:",omitempty"`
+	// TestPackages are the `go test -json` results of the packages, it's empty if no test result is provided.
+	TestPackages []*testresult.Package `json:",omitempty"`
+	// TruncatedFiles are the files that exceed the limits, they don't take participate to coverage calculation.
+	TruncatedFiles []*TruncatedFile `json:",omitempty"`
+	// SkipList are the entries of the skip list of the files whose coverage collection is flaky,
+	// the files of the active entries don't take participate to coverage calculation.
+	SkipList []*SkipListEntry `json:",omitempty"`
+	// NonCoverableFiles are the changed tool and script files excluded from the build by the ignore build tag,
+	// such as `//go:build ignore`, they have no cover profiles and don't take participate to coverage calculation.
+	NonCoverableFiles []string `json:",omitempty"`
+	// EmbeddedFiles are the changed files embedded by the //go:embed directives, such as the templates and the sql files,
+	// and the go files that add the directives, gocover sees them but can't analyze the coverage of their contents.
+	EmbeddedFiles []*EmbeddedFile `json:",omitempty"`
+	// TestHelperPackages are the packages without tests that are recognized as test helpers, such as internal/testutil,
+	// their missing tests are not reported.
+	TestHelperPackages []string `json:",omitempty"`
+	// ReportURL is the url of the uploaded html report, it's set by the storage generator before the generators after it.
+	ReportURL string `json:",omitempty"`
+	// Layers are the coverage of the files labeled with architecture layers.
+	Layers []*LayerStatistics `json:",omitempty"`
+	// Packages are the coverage of each package of the counted files, in the order of the import paths.
+	Packages []*PackageStatistics `json:",omitempty"`
+	// FileBaseline is the expected coverage of each counted file, the files are not gated if it's zero.
+	FileBaseline float64 `json:",omitempty"`
+	// Regression is the coverage of the files touched by the diff on the compared branch and on HEAD,
+	// it's nil if the coverage of the compared branch is not calculated.
+	Regression *RegressionStatistics `json:",omitempty"`
+	// Todos are the TODO and FIXME comments added in the changed lines, it's nil if they're not counted.
+	Todos *TodoStatistics `json:",omitempty"`
+	// Errors are the non-fatal errors that make the coverage incomplete, such as parse failures.
+	Errors *MultiError `json:",omitempty"`
+	// Phases are the resource usage of the pipeline phases finished before the reports are generated.
+	Phases []*phase.Stats `json:",omitempty"`
+	// Shard is the subset of the packages that the run analyzed, it's nil if the run analyzed all the packages.
+	Shard *Shard `json:",omitempty"`
+}
+
+// GateFailed returns whether any rule of the coverage gate fails, even if the gate is bypassed.
+// The rules are from the decision of the gate, or only the coverage baseline if the gate isn't evaluated.
+func (s *Statistics) GateFailed(coverageBaseline float64) bool {
+	if s.Gate != nil {
+		return len(s.Gate.Violations) != 0
+	}
+	return s.TotalCoveragePercent < coverageBaseline
+}
+
+// GatePassed returns whether the coverage gate passes, or it fails but it's bypassed.
+func (s *Statistics) GatePassed(coverageBaseline float64) bool {
+	return !s.GateFailed(coverageBaseline) || s.Bypass != nil
+}
+
+// GateViolations returns the messages of the failed rules of the coverage gate, it's empty if the gate isn't evaluated.
+func (s *Statistics) GateViolations() []string {
+	if s.Gate == nil {
+		return nil
+	}
+	messages := make([]string, 0, len(s.Gate.Violations))
+	for _, v := range s.Gate.Violations {
+		messages = append(messages, v.Message)
+	}
+	return messages
+}
+
+// TruncationMarker is the line appended to the truncated contents, the argument is the number of truncated lines.
+const TruncationMarker = "// ... %d more lines are truncated by gocover"
+
+// LayerStatistics represents the coverage of the files in an architecture layer.
+type LayerStatistics struct {
+	// Name is the name of the layer.
+	Name string
+	// Files is the number of the counted files in the layer.
+	Files int
+	// TotalEffectiveLines indicates effective lines of the files in the layer.
+	TotalEffectiveLines int
+	// TotalCoveredLines indicates covered lines of the files in the layer.
+	TotalCoveredLines int
+	// CoveragePercent represents the coverage percent of the layer with ignorance.
+	CoveragePercent float64
+	// Baseline is the expected coverage of the layer, the layer is not gated if it's zero.
+	Baseline float64 `json:",omitempty"`
+}
+
+// Passed reports whether the layer meets its baseline.
+func (l *LayerStatistics) Passed() bool {
+	return l.Baseline <= 0 || l.CoveragePercent >= l.Baseline
+}
+
+// PackageStatistics represents the coverage of the counted files in the directory of a package,
+// the files of the sub packages are not included.
+type PackageStatistics struct {
+	// Name is the import path of the package.
+	Name string
+	// Files is the number of the counted files in the package.
+	Files int
+	// TotalEffectiveLines indicates effective lines of the files in the package.
+	TotalEffectiveLines int
+	// TotalCoveredLines indicates covered lines of the files in the package.
+	TotalCoveredLines int
+	// CoveragePercent represents the coverage percent of the package with ignorance.
+	CoveragePercent float64
+	// Baseline is the expected coverage of the package, the package is not gated if it's zero.
+	Baseline float64 `json:",omitempty"`
+}
+
+// Passed reports whether the package meets its baseline.
+func (p *PackageStatistics) Passed() bool {
+	return p.Baseline <= 0 || p.CoveragePercent >= p.Baseline
+}
+
+// RegressionStatistics compares the full coverage of the compared branch and of HEAD, the touched files
+// are gated by their coverage on the compared branch, so a diff can't lower the coverage of a file it touches.
+type RegressionStatistics struct {
+	// BaseCoveragePercent is the full coverage of the module on the compared branch.
+	BaseCoveragePercent float64
+	// CoveragePercent is the full coverage of the module on HEAD.
+	CoveragePercent float64
+	// Tolerance is the coverage percent that a touched file may drop.
+	Tolerance float64
+	// Files are the touched files that have effective lines on both, in the order of the file names.
+	Files []*FileRegression
+}
+
+// FileRegression is the full coverage of a touched file on the compared branch and on HEAD.
+type FileRegression struct {
+	FileName            string
+	BaseCoveragePercent float64
+	CoveragePercent     float64
+}
+
+// Delta is the change of the coverage of the file, it's negative if the coverage drops.
+func (f *FileRegression) Delta() float64 {
+	return f.CoveragePercent - f.BaseCoveragePercent
+}
+
+// Passed reports whether the coverage of the file doesn't drop more than the tolerance.
+func (f *FileRegression) Passed(tolerance float64) bool {
+	return f.Delta() >= -tolerance
+}
+
+// TodoStatistics represents the TODO and FIXME comments added in the changed lines of a diff.
+type TodoStatistics struct {
+	// AddedLines is the number of the added lines of the counted files.
+	AddedLines int
+	// Todos are the added comments.
+	Todos []*Todo
+	// MaxTodos is the maximum number of the added comments, it's not gated if it's negative.
+	MaxTodos int
+	// MaxDensity is the maximum added comments per 100 added lines, it's not gated if it's negative.
+	MaxDensity float64
+}
+
+// Density returns the added comments per 100 added lines.
+func (s *TodoStatistics) Density() float64 {
+	if s.AddedLines == 0 {
+		return 0
+	}
+	return float64(len(s.Todos)) * 100 / float64(s.AddedLines)
+}
+
+// Passed reports whether the added comments meet both limits.
+func (s *TodoStatistics) Passed() bool {
+	return (s.MaxTodos < 0 || len(s.Todos) <= s.MaxTodos) && (s.MaxDensity < 0 || s.Density() <= s.MaxDensity)
+}
+
+// Todo is a TODO or FIXME comment added in a changed line.
+type Todo struct {
+	// FileName is the name of the file.
+	FileName string
+	// Line is the line of the comment.
+	Line int
+	// Marker is TODO or FIXME.
+	Marker string
+	// Text is the text of the comment after the marker.
+	Text string
+}
+
+// EmbeddedFile represents a changed file that is embedded into a package, or a go file that changes the embed directives.
+type EmbeddedFile struct {
+	// FileName is the name of the changed file.
+	FileName string
+	// Embedder is the go file of the directive, it's the same as the file name if the go file adds the directive.
+	Embedder string
+	// Pattern is the pattern of the directive that embeds the file.
+	Pattern string
+}
+
+// TruncatedFile represents a file that exceeds the limits.
+type TruncatedFile struct {
+	// FileName is the name of the file.
+	FileName string
+	// Size is the file size in bytes.
+	Size int64
+	// Reason explains which limit the file exceeds.
+	Reason string
+}
+
+// SkipListEntry represents an entry of the skip list, it's reported until it's removed from the skip list.
+type SkipListEntry struct {
+	// Pattern is the pattern of the skipped files.
+	Pattern string
+	// Owner is who fixes the coverage collection of the files.
+	Owner string
+	// Expires is the last day that the files are skipped.
+	Expires string
+	// Reason explains why the coverage of the files is flaky.
+	Reason string `json:",omitempty"`
+	// Expired indicates the entry is over, its files count for coverage again.
+	Expired bool `json:",omitempty"`
+	// Files are the files skipped by the entry.
+	Files []string `json:",omitempty"`
+}
+
+// Shard is one of the subsets of the packages that the parallel jobs analyze, the statistics of the shards are merged into one.
+type Shard struct {
+	// Index is the shard of the run, from 1 to Count.
+	Index int
+	// Count is the number of the shards.
+	Count int
+}
+
+// String returns the shard in the format of index/count.
+func (s *Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Bypass represents the reason why a failing coverage gate is downgraded to neutral.
+type Bypass struct {
+	// Source indicates where the bypass comes from, such as a label or a comment command.
+	Source string
+	// Reason is the explanation of the bypass.
+	Reason string
+	// Actor is the login of the user who requested the bypass, it's empty if unknown, such as a label or the rollout.
+	Actor string `json:",omitempty"`
+}
+
+// By returns the source of the bypass and the user who requested it, if known.
+func (b *Bypass) By() string {
+	if b.Actor == "" {
+		return b.Source
+	}
+	return fmt.Sprintf("%s of @%s", b.Source, b.Actor)
+}
+
+// Rollout represents whether the coverage gate is enforced on a pull request of a gradual rollout,
+// the failing gate of a pull request that is not enforced is bypassed, so it only warns.
+type Rollout struct {
+	// Percent is the percent of the pull requests that the gate is enforced on.
+	Percent float64
+	// Key is the pull request number or the commit sha that the pull request is bucketed by.
+	Key string
+	// Bucket is the bucket of the key in [0, 100), the gate is enforced if it's lower than the percent.
+	Bucket int
+	// Enforced indicates the gate is enforced on the pull request.
+	Enforced bool
 }
 
 // CoverageProfile represents the test coverage information for a file.
@@ -59,7 +330,85 @@ type CoverageProfile struct {
 	// ViolationSections indicates the violation sections that miss full coverage.
 	ViolationSections []*ViolationSection
 	// CodeSnippet represents the output of the ViolationSections, it's calculated from ViolationSections.
-	CodeSnippet []template.HTML
+	CodeSnippet []template.HTML `json:"-"`
+	// Unreliable indicates the package of the file has failed tests or failed to build, so the coverage is unreliable.
+	Unreliable bool
+	// GraceUntil is the last day of the grace period of the file in the format of YYYY-MM-DD, the file doesn't count
+	// for the totals and the coverage gate until then. It's empty if the file is enforced.
+	GraceUntil string `json:",omitempty"`
+	// CountedLines indicates the start line of each statement that counts for coverage and the profile block it matched.
+	CountedLines []*CountedLine `json:",omitempty"`
+	// DiffSections are the added and deleted sections of the file, the annotated report renders them as a unified diff,
+	// they are only collected for the diff view.
+	DiffSections []*DiffSection `json:",omitempty"`
+	// UncoveredSince is when each uncovered line first became uncovered, it's set by the history of the report storage.
+	UncoveredSince []*UncoveredSince `json:",omitempty"`
+	// Functions are the coverage of the functions that have counted lines, such as the functions touched by the diff.
+	Functions []*FunctionCoverage `json:",omitempty"`
+}
+
+// UncoveredSince is the first run that an uncovered line is uncovered in.
+type UncoveredSince struct {
+	// Line is the uncovered line.
+	Line int
+	// Commit is the commit of the first run that the line is uncovered in.
+	Commit string
+	// Time is the time of the first run.
+	Time time.Time
+	// New indicates the line is uncovered since this run, it's a fresh regression rather than a long-standing debt.
+	New bool `json:",omitempty"`
+}
+
+// DiffSection is the added or deleted lines of a file in the diff.
+type DiffSection struct {
+	// Deleted indicates the lines are deleted, otherwise they are added.
+	Deleted bool `json:",omitempty"`
+	// StartLine is the first added line, or the line of the file that the deleted lines were before.
+	StartLine int
+	// EndLine is the last added line, it equals the StartLine of the deleted lines.
+	EndLine int
+	// Contents are the deleted lines, the added lines are read from the file.
+	// If the deleted lines exceed the max section lines, the rest lines are replaced by the truncation marker.
+	Contents []string `json:",omitempty"`
+}
+
+// FunctionCoverage is the coverage of the counted statements of a function, the statements are counted by their start lines.
+type FunctionCoverage struct {
+	// Name is the name of the function, it's T.N for a method N of the type T.
+	Name string
+	// StartLine and EndLine are the lines of the function.
+	StartLine int
+	EndLine   int
+	// TotalLines are the counted statements of the function.
+	TotalLines int
+	// EffectiveLines are the counted statements that are not ignored.
+	EffectiveLines int
+	// CoveredLines are the counted statements reached by tests.
+	CoveredLines int
+	// CoveredButIgnoredLines are the covered statements that are ignored.
+	CoveredButIgnoredLines int
+}
+
+// CountedLine represents a statement that counts for coverage, it explains why the line is covered or violated.
+type CountedLine struct {
+	// Line is the start line of the statement.
+	Line int
+	// Covered indicates the statement is reached by tests.
+	Covered bool
+	// Ignored indicates the statement is ignored by annotations.
+	Ignored bool
+	// Block is the profile block that the statement matched, it's nil if no block matched.
+	Block *Block `json:",omitempty"`
+}
+
+// Block represents a block of the cover profile.
+type Block struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
 }
 
 // ViolationSection represents a portion of the change that miss unit test coverage.
@@ -71,5 +420,8 @@ type ViolationSection struct {
 	// EndLine indicates the end line of the section.
 	EndLine int
 	// Contents contains [StartLine..EndLine] lines from the source file.
+	// If the section exceeds the max section lines, the rest lines are replaced by the truncation marker.
 	Contents []string
+	// TruncatedLines is the number of lines that are not retained in Contents.
+	TruncatedLines int `json:",omitempty"`
 }
@@ -0,0 +1,3 @@
+// Package review publishes the coverage of a run as a review of a Gerrit change, with a label vote derived from
+// the baseline and a robot comment at each uncovered line, for the teams that review the changes on Gerrit.
+package review
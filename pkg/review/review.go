@@ -0,0 +1,120 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/gocover/pkg/checks"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRobotID is the id of the robot that posts the comments.
+	DefaultRobotID = "gocover"
+	// DefaultLabel is the label voted by the review.
+	DefaultLabel = "Code-Coverage"
+	// DefaultRevision is the current patch set of the change.
+	DefaultRevision = "current"
+
+	// publishTimeout bounds the time of posting the review.
+	publishTimeout = time.Minute
+)
+
+// Option contains the input for the review report generator.
+type Option struct {
+	// RobotID is the id of the robot, DefaultRobotID is used if it's empty.
+	RobotID string
+	// RunID is the id of the run of the robot, the time of the run is used if it's empty.
+	RunID string
+	// Change is the change number, or {project}~{change number}.
+	Change string
+	// Revision is the patch set number or the commit of the change, DefaultRevision is used if it's empty.
+	Revision string
+	// Label is the label voted by the review, there is no vote if it's empty.
+	Label string
+	// PassedVote is voted if the coverage reaches the baseline.
+	PassedVote int
+	// FailedVote is voted if the coverage is lower than the baseline, 0 is voted if the failing gate is bypassed.
+	FailedVote int
+	// URL is the url of the details of the run, such as the build, it's optional.
+	URL string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root.
+	ModuleDir string
+	// CoverageBaseline decides the vote of the review.
+	CoverageBaseline float64
+}
+
+// NewReportGenerator creates a report generator that posts the statistics as a review of the change,
+// the failed publishing is logged rather than failing the command, as the other reports are written.
+func NewReportGenerator(publisher scm.ReviewPublisher, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{publisher: publisher, option: o, logger: logger.WithField("source", "Review")}
+}
+
+type reportGenerator struct {
+	publisher scm.ReviewPublisher
+	option    *Option
+	logger    logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport posts the review of the statistics.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	revision := g.option.Revision
+	if revision == "" {
+		revision = DefaultRevision
+	}
+	review := NewReview(statistics, g.option)
+	if review.RunID == "" {
+		review.RunID = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := g.publisher.PostReview(ctx, g.option.Change, revision, review); err != nil {
+		g.logger.WithError(err).Errorf("post review of change %s revision %s", g.option.Change, revision)
+		return nil
+	}
+	g.logger.Infof("post review of change %s revision %s with %d robot comments", g.option.Change, revision, len(review.Comments))
+	return nil
+}
+
+// NewReview returns the review of the statistics, whose comments are the annotations of the check run.
+// The failed vote is given if the coverage is lower than the baseline, or 0 if the failing gate is bypassed.
+func NewReview(statistics *report.Statistics, o *Option) *scm.Review {
+	robotID := o.RobotID
+	if robotID == "" {
+		robotID = DefaultRobotID
+	}
+	run := checks.NewCheckRun(statistics, &checks.Option{
+		ModulePath:       o.ModulePath,
+		ModuleDir:        o.ModuleDir,
+		CoverageBaseline: o.CoverageBaseline,
+	})
+
+	review := &scm.Review{
+		RobotID:  robotID,
+		RunID:    o.RunID,
+		Message:  run.Title,
+		Label:    o.Label,
+		URL:      o.URL,
+		Comments: run.Annotations,
+	}
+	switch run.Conclusion {
+	case scm.SuccessConclusion:
+		review.Vote = o.PassedVote
+	case scm.FailureConclusion:
+		review.Vote = o.FailedVote
+	default:
+		review.Message += fmt.Sprintf(", bypassed by %s", statistics.Bypass.Source)
+	}
+	if o.URL != "" {
+		review.Message += "\n\n" + o.URL
+	}
+	return review
+}
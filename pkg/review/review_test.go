@@ -0,0 +1,85 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/covergate"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+type mockPublisher struct {
+	revisions []string
+	reviews   []*scm.Review
+	err       error
+}
+
+func (p *mockPublisher) PostReview(ctx context.Context, change, revision string, review *scm.Review) error {
+	p.revisions = append(p.revisions, change+"/"+revision)
+	p.reviews = append(p.reviews, review)
+	return p.err
+}
+
+func testStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		TotalCoveragePercent: 50,
+		TotalEffectiveLines:  4,
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName: "github.com/Azure/modulea/foo/foo.go",
+				ViolationSections: []*report.ViolationSection{
+					{StartLine: 3, EndLine: 6, ViolationLines: []int{5}},
+				},
+			},
+		},
+	}
+}
+
+func TestNewReview(t *testing.T) {
+	o := &Option{Label: DefaultLabel, PassedVote: 1, FailedVote: -1, ModulePath: "github.com/Azure/modulea", ModuleDir: "modulea", CoverageBaseline: 80, URL: "https://build"}
+	review := NewReview(testStatistics(), o)
+	if review.RobotID != DefaultRobotID || review.Vote != -1 || review.Message != "Diff coverage 50.00% of 4 lines (baseline 80.00%)\n\nhttps://build" {
+		t.Errorf("unexpected review %+v", review)
+	}
+	if len(review.Comments) != 1 || review.Comments[0].Path != "modulea/foo/foo.go" || review.Comments[0].StartLine != 5 {
+		t.Errorf("unexpected comments %+v", review.Comments)
+	}
+
+	statistics := testStatistics()
+	statistics.Bypass = &report.Bypass{Source: "label gocover-skip"}
+	if review := NewReview(statistics, o); review.Vote != 0 {
+		t.Errorf("expect no vote of the bypassed gate, but get %d", review.Vote)
+	}
+	o.CoverageBaseline = 50
+	if review := NewReview(testStatistics(), o); review.Vote != 1 {
+		t.Errorf("expect the passed vote, but get %d", review.Vote)
+	}
+
+	// the coverage meets the baseline, but the file gate fails.
+	statistics = testStatistics()
+	statistics.Gate = &covergate.Decision{Violations: []*covergate.Violation{{Rule: covergate.FileRule, File: "github.com/Azure/modulea/foo/foo.go", Actual: 40, Limit: 60, Message: "the coverage of file github.com/Azure/modulea/foo/foo.go is 40.00, lower than the file baseline 60.00"}}}
+	if review := NewReview(statistics, o); review.Vote != -1 {
+		t.Errorf("expect the failed vote of the failed file gate, but get %d", review.Vote)
+	}
+}
+
+func TestReportGenerator(t *testing.T) {
+	publisher := &mockPublisher{}
+	g := NewReportGenerator(publisher, &Option{Change: "12", Label: DefaultLabel, FailedVote: -1}, logrus.New())
+	if err := g.GenerateReport(testStatistics()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.reviews) != 1 || publisher.revisions[0] != "12/current" || publisher.reviews[0].RunID == "" {
+		t.Errorf("unexpected reviews %v %+v", publisher.revisions, publisher.reviews)
+	}
+
+	// a failed review is logged rather than failing the command.
+	publisher.err = errors.New("label not permitted")
+	if err := g.GenerateReport(testStatistics()); err != nil {
+		t.Errorf("should not error, but get %s", err)
+	}
+}
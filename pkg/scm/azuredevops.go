@@ -0,0 +1,224 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// azureDevOpsAPIVersion is the version of Azure DevOps REST API.
+	azureDevOpsAPIVersion = "7.1"
+	// azureDevOpsStatusGenre is the genre of the pull request statuses, it groups the statuses of gocover.
+	azureDevOpsStatusGenre = "gocover"
+)
+
+// The authentication schemes of Azure DevOps.
+const (
+	// BearerAzureDevOpsAuth sends the token as a bearer token, such as $(System.AccessToken) of a pipeline
+	// or a Microsoft Entra token.
+	BearerAzureDevOpsAuth = "bearer"
+	// PATAzureDevOpsAuth sends the token as a personal access token by the basic authentication.
+	PATAzureDevOpsAuth = "pat"
+)
+
+var ErrUnknownAzureDevOpsAuth = errors.New("unknown azure devops authentication, one of: bearer, pat")
+
+// NewAzureDevOpsClient creates the client of Azure DevOps REST API, the organization url is such as https://dev.azure.com/{organization},
+// which is $(System.CollectionUri) of a pipeline. The owner of the pull requests is the project of the repository.
+func NewAzureDevOpsClient(organizationURL, auth string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) (Client, error) {
+	switch auth {
+	case "", BearerAzureDevOpsAuth, PATAzureDevOpsAuth:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAzureDevOpsAuth, auth)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &azureDevOpsClient{
+		organizationURL: strings.TrimSuffix(organizationURL, "/"),
+		pat:             auth == PATAzureDevOpsAuth,
+		token:           token,
+		client:          httpClient,
+		recorder:        recorder,
+	}, nil
+}
+
+// NewAzureDevOpsStatusPublisher creates the status publisher of Azure DevOps REST API.
+func NewAzureDevOpsStatusPublisher(organizationURL, auth string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) (StatusPublisher, error) {
+	c, err := NewAzureDevOpsClient(organizationURL, auth, token, httpClient, recorder)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*azureDevOpsClient), nil
+}
+
+type azureDevOpsClient struct {
+	organizationURL string
+	// pat sends the token by the basic authentication.
+	pat      bool
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ Client = (*azureDevOpsClient)(nil)
+var _ StatusPublisher = (*azureDevOpsClient)(nil)
+
+type azureDevOpsPullRequest struct {
+	PullRequestID         int    `json:"pullRequestId"`
+	SourceRefName         string `json:"sourceRefName"`
+	TargetRefName         string `json:"targetRefName"`
+	LastMergeSourceCommit struct {
+		CommitID string `json:"commitId"`
+	} `json:"lastMergeSourceCommit"`
+	Repository struct {
+		RemoteURL string `json:"remoteUrl"`
+	} `json:"repository"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+func (c *azureDevOpsClient) GetPullRequest(ctx context.Context, project, repository string, number int) (*PullRequest, error) {
+	result := &azureDevOpsPullRequest{}
+	if err := c.do(ctx, http.MethodGet, pullRequestPath(project, repository, number), nil, result); err != nil {
+		return nil, fmt.Errorf("get pull request: %w", err)
+	}
+
+	pr := &PullRequest{
+		Owner:      project,
+		Repository: repository,
+		Number:     result.PullRequestID,
+		HeadSHA:    result.LastMergeSourceCommit.CommitID,
+		HeadRef:    strings.TrimPrefix(result.SourceRefName, "refs/heads/"),
+		BaseRef:    strings.TrimPrefix(result.TargetRefName, "refs/heads/"),
+		CloneURL:   result.Repository.RemoteURL,
+	}
+	for _, l := range result.Labels {
+		pr.Labels = append(pr.Labels, l.Name)
+	}
+	return pr, nil
+}
+
+// PostComment posts the comment as a new active thread of the pull request, and returns the id of the thread.
+func (c *azureDevOpsClient) PostComment(ctx context.Context, pr *PullRequest, body string) (string, error) {
+	request := map[string]interface{}{
+		"comments": []map[string]interface{}{
+			{"parentCommentId": 0, "content": body, "commentType": "text"},
+		},
+		"status": "active",
+	}
+	result := &struct {
+		ID int64 `json:"id"`
+	}{}
+	err := c.do(ctx, http.MethodPost, pullRequestPath(pr.Owner, pr.Repository, pr.Number)+"/threads", request, result)
+
+	id := ""
+	if err == nil {
+		id = strconv.FormatInt(result.ID, 10)
+	}
+	c.recorder.Record(audit.NewAction(audit.CommentAction, pullRequestTarget(pr), id, err))
+	if err != nil {
+		return "", fmt.Errorf("post comment thread: %w", err)
+	}
+	return id, nil
+}
+
+func (c *azureDevOpsClient) SetStatus(ctx context.Context, pr *PullRequest, status *PullRequestStatus) (string, error) {
+	request := map[string]interface{}{
+		"state":       status.State,
+		"description": status.Description,
+		"context":     map[string]string{"name": status.Name, "genre": azureDevOpsStatusGenre},
+	}
+	if status.TargetURL != "" {
+		request["targetUrl"] = status.TargetURL
+	}
+	result := &struct {
+		ID int64 `json:"id"`
+	}{}
+	err := c.do(ctx, http.MethodPost, pullRequestPath(pr.Owner, pr.Repository, pr.Number)+"/statuses", request, result)
+
+	id := ""
+	if err == nil {
+		id = strconv.FormatInt(result.ID, 10)
+	}
+	action := audit.NewAction(audit.StatusAction, pullRequestTarget(pr), id, err)
+	action.Details = map[string]string{"name": status.Name, "state": status.State}
+	c.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("set pull request status: %w", err)
+	}
+	return id, nil
+}
+
+// pullRequestPath returns the api path of the pull request, the project and the repository are names or ids.
+func pullRequestPath(project, repository string, number int) string {
+	return fmt.Sprintf("/%s/_apis/git/repositories/%s/pullRequests/%d", url.PathEscape(project), url.PathEscape(repository), number)
+}
+
+// do sends the request to Azure DevOps api and decodes the response into result.
+func (c *azureDevOpsClient) do(ctx context.Context, method, path string, request interface{}, result interface{}) error {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.organizationURL+path+"?api-version="+azureDevOpsAPIVersion, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		if c.pat {
+			req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+token)))
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// azure devops redirects the unauthenticated requests to the sign-in page rather than returning 401.
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, method, path, resp.StatusCode, redact.String(string(data)))
+	}
+	if result != nil && len(data) != 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
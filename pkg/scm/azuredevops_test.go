@@ -0,0 +1,156 @@
+package scm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func newTestAzureDevOpsClient(t *testing.T, auth string, handler http.HandlerFunc) (*azureDevOpsClient, audit.Recorder, func()) {
+	server := httptest.NewServer(handler)
+	t.Setenv("GOCOVER_TEST_AZURE_DEVOPS_TOKEN", "azuredevopstokenvalue")
+	token, err := credential.NewProvider("env:GOCOVER_TEST_AZURE_DEVOPS_TOKEN", nil)
+	if err != nil {
+		t.Fatalf("new provider: %s", err)
+	}
+	recorder := audit.NewRecorder("")
+	client, err := NewAzureDevOpsClient(server.URL+"/org/", auth, token, server.Client(), recorder)
+	if err != nil {
+		t.Fatalf("new client: %s", err)
+	}
+	return client.(*azureDevOpsClient), recorder, server.Close
+}
+
+func TestNewAzureDevOpsClient(t *testing.T) {
+	if _, err := NewAzureDevOpsClient("https://dev.azure.com/org", "oauth", nil, nil, nil); !errors.Is(err, ErrUnknownAzureDevOpsAuth) {
+		t.Errorf("expect %s, but get %v", ErrUnknownAzureDevOpsAuth, err)
+	}
+}
+
+func TestAzureDevOpsGetPullRequest(t *testing.T) {
+	client, _, clean := newTestAzureDevOpsClient(t, BearerAzureDevOpsAuth, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/org/my%20project/_apis/git/repositories/gocover/pullRequests/12" ||
+			r.URL.Query().Get("api-version") != azureDevOpsAPIVersion || r.Header.Get("Authorization") != "Bearer azuredevopstokenvalue" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"pullRequestId": 12,
+			"sourceRefName": "refs/heads/feature",
+			"targetRefName": "refs/heads/main",
+			"lastMergeSourceCommit": {"commitId": "abc"},
+			"repository": {"remoteUrl": "https://dev.azure.com/org/my%20project/_git/gocover"},
+			"labels": [{"name": "bug"}]
+		}`))
+	})
+	defer clean()
+
+	pr, err := client.GetPullRequest(context.Background(), "my project", "gocover", 12)
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if pr.Owner != "my project" || pr.HeadSHA != "abc" || pr.HeadRef != "feature" || pr.BaseRef != "main" || len(pr.Labels) != 1 {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+
+	if _, err := client.GetPullRequest(context.Background(), "my project", "gocover", 13); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
+
+func TestAzureDevOpsPostComment(t *testing.T) {
+	pat := "Basic " + base64.StdEncoding.EncodeToString([]byte(":azuredevopstokenvalue"))
+	client, recorder, clean := newTestAzureDevOpsClient(t, PATAzureDevOpsAuth, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/org/project/_apis/git/repositories/gocover/pullRequests/12/threads" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != pat {
+			// the unauthenticated requests are redirected to the sign-in page.
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>sign in</html>"))
+			return
+		}
+		body := &struct {
+			Comments []struct {
+				Content string `json:"content"`
+			} `json:"comments"`
+			Status string `json:"status"`
+		}{}
+		json.NewDecoder(r.Body).Decode(body)
+		if len(body.Comments) != 1 || body.Comments[0].Content != "hello" || body.Status != "active" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1001}`))
+	})
+	defer clean()
+
+	pr := &PullRequest{Owner: "project", Repository: "gocover", Number: 12}
+	id, err := client.PostComment(context.Background(), pr, "hello")
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if id != "1001" {
+		t.Errorf("expect thread id 1001, but get %s", id)
+	}
+	if actions := recorder.Actions(); len(actions) != 1 || actions[0].Type != audit.CommentAction || actions[0].Target != "project/gocover#12" {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	client.pat = false
+	if _, err := client.PostComment(context.Background(), pr, "hello"); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s of the sign-in page, but get %v", ErrUnexpectedResponse, err)
+	}
+}
+
+func TestAzureDevOpsSetStatus(t *testing.T) {
+	client, recorder, clean := newTestAzureDevOpsClient(t, BearerAzureDevOpsAuth, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/org/project/_apis/git/repositories/gocover/pullRequests/12/statuses" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body := &struct {
+			State   string `json:"state"`
+			Context struct {
+				Name  string `json:"name"`
+				Genre string `json:"genre"`
+			} `json:"context"`
+			TargetURL string `json:"targetUrl"`
+		}{}
+		json.NewDecoder(r.Body).Decode(body)
+		if body.State != FailedStatusState || body.Context.Name != "coverage" || body.Context.Genre != azureDevOpsStatusGenre || body.TargetURL != "https://build" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 3}`))
+	})
+	defer clean()
+
+	pr := &PullRequest{Owner: "project", Repository: "gocover", Number: 12}
+	id, err := client.SetStatus(context.Background(), pr, &PullRequestStatus{Name: "coverage", State: FailedStatusState, Description: "low", TargetURL: "https://build"})
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if id != "3" {
+		t.Errorf("expect status id 3, but get %s", id)
+	}
+	actions := recorder.Actions()
+	if len(actions) != 1 || actions[0].Type != audit.StatusAction || actions[0].Details["state"] != FailedStatusState {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	if _, err := client.SetStatus(context.Background(), pr, &PullRequestStatus{Name: "coverage", State: SucceededStatusState}); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
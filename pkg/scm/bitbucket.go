@@ -0,0 +1,246 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// DefaultBitbucketCloudAPIURL is the api url of Bitbucket Cloud.
+	DefaultBitbucketCloudAPIURL = "https://api.bitbucket.org/2.0"
+
+	// bitbucketReporter is the reporter of the Code Insights reports.
+	bitbucketReporter = "gocover"
+	// maxBitbucketAnnotations is the number of the annotations of a report that Bitbucket accepts, the rest are dropped.
+	maxBitbucketAnnotations = 1000
+	// maxBitbucketCloudAnnotationsPerRequest is the number of the annotations per request that Bitbucket Cloud accepts.
+	maxBitbucketCloudAnnotationsPerRequest = 100
+)
+
+// The authentication schemes of Bitbucket.
+const (
+	// BearerBitbucketAuth sends the token as a bearer token, such as a repository access token of Bitbucket Cloud
+	// or a HTTP access token of Bitbucket Server.
+	BearerBitbucketAuth = "bearer"
+	// BasicBitbucketAuth sends the token in the format of {username}:{app password} by the basic authentication.
+	BasicBitbucketAuth = "basic"
+)
+
+var ErrUnknownBitbucketAuth = errors.New("unknown bitbucket authentication, one of: bearer, basic")
+
+// NewBitbucketCheckPublisher creates the check publisher of Bitbucket Code Insights, which publishes a check run as a coverage report
+// of the commit with the annotations, the pull requests of the commit show them. The api url is DefaultBitbucketCloudAPIURL for
+// Bitbucket Cloud, or the base url of Bitbucket Server or Data Center if server is true, whose owner of the repositories is the project key.
+func NewBitbucketCheckPublisher(apiURL string, server bool, auth string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) (CheckPublisher, error) {
+	switch auth {
+	case "", BearerBitbucketAuth, BasicBitbucketAuth:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBitbucketAuth, auth)
+	}
+	if apiURL == "" && !server {
+		apiURL = DefaultBitbucketCloudAPIURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &bitbucketClient{
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		server:   server,
+		basic:    auth == BasicBitbucketAuth,
+		token:    token,
+		client:   httpClient,
+		recorder: recorder,
+	}, nil
+}
+
+type bitbucketClient struct {
+	apiURL string
+	// server uses the api of Bitbucket Server and Data Center.
+	server bool
+	// basic sends the token by the basic authentication.
+	basic    bool
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ CheckPublisher = (*bitbucketClient)(nil)
+
+type bitbucketReportData struct {
+	Title string  `json:"title"`
+	Type  string  `json:"type"`
+	Value float64 `json:"value"`
+}
+
+// bitbucketSeverity returns the severity of the annotation level.
+func bitbucketSeverity(level string) string {
+	switch level {
+	case FailureAnnotationLevel:
+		return "HIGH"
+	case NoticeAnnotationLevel:
+		return "LOW"
+	default:
+		return "MEDIUM"
+	}
+}
+
+// CreateCheckRun replaces the report of the check run name with the check run, the annotations of the replaced report are deleted.
+// The id of the report is the check run name.
+func (c *bitbucketClient) CreateCheckRun(ctx context.Context, owner, repository string, run *CheckRun) (string, error) {
+	annotations := run.Annotations
+	if len(annotations) > maxBitbucketAnnotations {
+		annotations = annotations[:maxBitbucketAnnotations]
+	}
+
+	var err error
+	if c.server {
+		err = c.createServerReport(ctx, owner, repository, run, annotations)
+	} else {
+		err = c.createCloudReport(ctx, owner, repository, run, annotations)
+	}
+
+	id := ""
+	if err == nil {
+		id = run.Name
+	}
+	action := audit.NewAction(audit.CheckAction, fmt.Sprintf("%s/%s@%s", owner, repository, run.HeadSHA), id, err)
+	action.Details = map[string]string{"conclusion": run.Conclusion, "annotations": strconv.Itoa(len(annotations))}
+	c.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("create code insights report: %w", err)
+	}
+	return id, nil
+}
+
+func (c *bitbucketClient) createCloudReport(ctx context.Context, workspace, repository string, run *CheckRun, annotations []*CheckAnnotation) error {
+	result := "PASSED"
+	if run.Conclusion == FailureConclusion {
+		result = "FAILED"
+	}
+	path := fmt.Sprintf("/repositories/%s/%s/commit/%s/reports/%s",
+		url.PathEscape(workspace), url.PathEscape(repository), url.PathEscape(run.HeadSHA), url.PathEscape(run.Name))
+	report := map[string]interface{}{
+		"title":       run.Name,
+		"details":     run.Title,
+		"report_type": "COVERAGE",
+		"reporter":    bitbucketReporter,
+		"result":      result,
+		"data":        []*bitbucketReportData{{Title: "Coverage", Type: "PERCENTAGE", Value: run.CoveragePercent}},
+	}
+	if err := c.do(ctx, http.MethodPut, path, report); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(annotations); start += maxBitbucketCloudAnnotationsPerRequest {
+		end := start + maxBitbucketCloudAnnotationsPerRequest
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		batch := make([]map[string]interface{}, 0, end-start)
+		for i, a := range annotations[start:end] {
+			batch = append(batch, map[string]interface{}{
+				"external_id":     fmt.Sprintf("%s-%d", run.Name, start+i),
+				"annotation_type": "CODE_SMELL",
+				"path":            a.Path,
+				"line":            a.StartLine,
+				"summary":         a.Title,
+				"details":         a.Message,
+				"severity":        bitbucketSeverity(a.Level),
+			})
+		}
+		if err := c.do(ctx, http.MethodPost, path+"/annotations", batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *bitbucketClient) createServerReport(ctx context.Context, project, repository string, run *CheckRun, annotations []*CheckAnnotation) error {
+	result := "PASS"
+	if run.Conclusion == FailureConclusion {
+		result = "FAIL"
+	}
+	path := fmt.Sprintf("/rest/insights/1.0/projects/%s/repos/%s/commits/%s/reports/%s",
+		url.PathEscape(project), url.PathEscape(repository), url.PathEscape(run.HeadSHA), url.PathEscape(run.Name))
+	report := map[string]interface{}{
+		"title":    run.Name,
+		"details":  run.Title,
+		"reporter": bitbucketReporter,
+		"result":   result,
+		"data":     []*bitbucketReportData{{Title: "Coverage", Type: "PERCENTAGE", Value: run.CoveragePercent}},
+	}
+	if err := c.do(ctx, http.MethodPut, path, report); err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	batch := make([]map[string]interface{}, 0, len(annotations))
+	for _, a := range annotations {
+		batch = append(batch, map[string]interface{}{
+			"path":     a.Path,
+			"line":     a.StartLine,
+			"message":  a.Message,
+			"severity": bitbucketSeverity(a.Level),
+			"type":     "CODE_SMELL",
+		})
+	}
+	return c.do(ctx, http.MethodPost, path+"/annotations", map[string]interface{}{"annotations": batch})
+}
+
+// do sends the request to Bitbucket api, the responses are not needed.
+func (c *bitbucketClient) do(ctx context.Context, method, path string, request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		if c.basic {
+			req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(token)))
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, method, path, resp.StatusCode, redact.String(string(body)))
+	}
+	return nil
+}
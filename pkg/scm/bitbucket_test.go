@@ -0,0 +1,148 @@
+package scm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func newTestBitbucketPublisher(t *testing.T, server bool, auth string, handler http.HandlerFunc) (CheckPublisher, audit.Recorder, func()) {
+	s := httptest.NewServer(handler)
+	t.Setenv("GOCOVER_TEST_BITBUCKET_TOKEN", "user:bitbuckettokenvalue")
+	token, err := credential.NewProvider("env:GOCOVER_TEST_BITBUCKET_TOKEN", nil)
+	if err != nil {
+		t.Fatalf("new provider: %s", err)
+	}
+	recorder := audit.NewRecorder("")
+	publisher, err := NewBitbucketCheckPublisher(s.URL, server, auth, token, s.Client(), recorder)
+	if err != nil {
+		t.Fatalf("new publisher: %s", err)
+	}
+	return publisher, recorder, s.Close
+}
+
+func testBitbucketCheckRun(annotations int) *CheckRun {
+	run := &CheckRun{Name: "gocover", HeadSHA: "abc", Conclusion: FailureConclusion, Title: "Diff coverage 50.00% of 4 lines (baseline 80.00%)", CoveragePercent: 50}
+	for i := 0; i < annotations; i++ {
+		run.Annotations = append(run.Annotations, &CheckAnnotation{Path: "foo/foo.go", StartLine: i + 1, EndLine: i + 1, Level: WarningAnnotationLevel, Title: "Uncovered line", Message: "This line is not covered by tests."})
+	}
+	return run
+}
+
+func TestNewBitbucketCheckPublisher(t *testing.T) {
+	if _, err := NewBitbucketCheckPublisher("", false, "oauth", nil, nil, nil); !errors.Is(err, ErrUnknownBitbucketAuth) {
+		t.Errorf("expect %s, but get %v", ErrUnknownBitbucketAuth, err)
+	}
+	publisher, err := NewBitbucketCheckPublisher("", false, "", nil, nil, nil)
+	if err != nil || publisher.(*bitbucketClient).apiURL != DefaultBitbucketCloudAPIURL {
+		t.Errorf("expect the api url of Bitbucket Cloud, but get %v", err)
+	}
+}
+
+func TestBitbucketCloudCreateCheckRun(t *testing.T) {
+	reportPath := "/repositories/workspace/gocover/commit/abc/reports/gocover"
+	var annotations []int
+	publisher, recorder, clean := newTestBitbucketPublisher(t, false, BearerBitbucketAuth, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user:bitbuckettokenvalue" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == reportPath:
+			report := &struct {
+				ReportType string `json:"report_type"`
+				Result     string `json:"result"`
+				Data       []struct {
+					Type  string  `json:"type"`
+					Value float64 `json:"value"`
+				} `json:"data"`
+			}{}
+			json.NewDecoder(r.Body).Decode(report)
+			if report.ReportType != "COVERAGE" || report.Result != "FAILED" || len(report.Data) != 1 || report.Data[0].Type != "PERCENTAGE" || report.Data[0].Value != 50 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"uuid": "{report}"}`))
+		case r.Method == http.MethodPost && r.URL.Path == reportPath+"/annotations":
+			var batch []map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&batch)
+			if len(batch) == 0 || batch[0]["external_id"] == "" || batch[0]["severity"] != "MEDIUM" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			annotations = append(annotations, len(batch))
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer clean()
+
+	id, err := publisher.CreateCheckRun(context.Background(), "workspace", "gocover", testBitbucketCheckRun(1005))
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if id != "gocover" {
+		t.Errorf("expect report id gocover, but get %s", id)
+	}
+	// the annotations are sent 100 per request, and the annotations more than 1000 are dropped.
+	if len(annotations) != 10 || annotations[9] != 100 {
+		t.Errorf("unexpected annotation batches %v", annotations)
+	}
+	actions := recorder.Actions()
+	if len(actions) != 1 || actions[0].Type != audit.CheckAction || actions[0].Target != "workspace/gocover@abc" || actions[0].Details["annotations"] != "1000" {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	if _, err := publisher.CreateCheckRun(context.Background(), "workspace", "other", testBitbucketCheckRun(0)); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
+
+func TestBitbucketServerCreateCheckRun(t *testing.T) {
+	reportPath := "/rest/insights/1.0/projects/PROJ/repos/gocover/commits/abc/reports/gocover"
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:bitbuckettokenvalue"))
+	annotated := 0
+	publisher, _, clean := newTestBitbucketPublisher(t, true, BasicBitbucketAuth, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != basic {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == reportPath:
+			report := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&report)
+			if report["result"] != "PASS" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"key": "gocover"}`))
+		case r.Method == http.MethodPost && r.URL.Path == reportPath+"/annotations":
+			body := &struct {
+				Annotations []map[string]interface{} `json:"annotations"`
+			}{}
+			json.NewDecoder(r.Body).Decode(body)
+			annotated += len(body.Annotations)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer clean()
+
+	run := testBitbucketCheckRun(3)
+	run.Conclusion = NeutralConclusion
+	if _, err := publisher.CreateCheckRun(context.Background(), "PROJ", "gocover", run); err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if annotated != 3 {
+		t.Errorf("expect 3 annotations, but get %d", annotated)
+	}
+}
@@ -0,0 +1,3 @@
+// Package scm talks to the source code management systems, such as GitHub, Azure DevOps, Bitbucket and Gerrit,
+// to read pull requests and publish coverage results to them.
+package scm
@@ -0,0 +1,151 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// gerritReviewTag marks the reviews of gocover as autogenerated, so they can be filtered out of the change messages.
+	gerritReviewTag = "autogenerated:gocover"
+	// maxGerritRobotComments is the number of the robot comments of a review that are posted, the rest are dropped.
+	maxGerritRobotComments = 1000
+)
+
+// NewGerritReviewPublisher creates the review publisher of Gerrit REST API, the url is the base url of Gerrit,
+// such as https://review.example.com. The token is in the format of {username}:{http password}, it's sent by
+// the basic authentication to the authenticated endpoints of /a/.
+func NewGerritReviewPublisher(baseURL string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) ReviewPublisher {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &gerritClient{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		token:    token,
+		client:   httpClient,
+		recorder: recorder,
+	}
+}
+
+type gerritClient struct {
+	baseURL  string
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ ReviewPublisher = (*gerritClient)(nil)
+
+type gerritRobotComment struct {
+	RobotID    string `json:"robot_id"`
+	RobotRunID string `json:"robot_run_id"`
+	Line       int    `json:"line"`
+	Message    string `json:"message"`
+	URL        string `json:"url,omitempty"`
+}
+
+// PostReview posts the review with the robot comments grouped by the files, the change is a change number
+// or a {project}~{change number}.
+func (c *gerritClient) PostReview(ctx context.Context, change, revision string, review *Review) error {
+	comments := review.Comments
+	if len(comments) > maxGerritRobotComments {
+		comments = comments[:maxGerritRobotComments]
+	}
+
+	request := map[string]interface{}{
+		"message": review.Message,
+		"tag":     gerritReviewTag,
+	}
+	if review.Label != "" {
+		request["labels"] = map[string]int{review.Label: review.Vote}
+	}
+	if len(comments) != 0 {
+		files := make(map[string][]*gerritRobotComment)
+		for _, comment := range comments {
+			message := comment.Message
+			if comment.Title != "" {
+				message = comment.Title + ": " + message
+			}
+			files[comment.Path] = append(files[comment.Path], &gerritRobotComment{
+				RobotID:    review.RobotID,
+				RobotRunID: review.RunID,
+				Line:       comment.StartLine,
+				Message:    message,
+				URL:        review.URL,
+			})
+		}
+		request["robot_comments"] = files
+	}
+	path := fmt.Sprintf("/changes/%s/revisions/%s/review", url.PathEscape(change), url.PathEscape(revision))
+	err := c.do(ctx, http.MethodPost, path, request)
+
+	action := audit.NewAction(audit.ReviewAction, change+"/"+revision, "", err)
+	action.Details = map[string]string{"comments": strconv.Itoa(len(comments))}
+	if review.Label != "" {
+		action.Details["label"] = fmt.Sprintf("%s%+d", review.Label, review.Vote)
+	}
+	c.recorder.Record(action)
+	if err != nil {
+		return fmt.Errorf("post review: %w", err)
+	}
+	return nil
+}
+
+// do sends the request to Gerrit api, the responses are not needed.
+func (c *gerritClient) do(ctx context.Context, method, path string, request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.baseURL + path
+	var authorization string
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		endpoint = c.baseURL + "/a" + path
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(token))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// gerrit returns the errors in plain text, such as the label not permitted.
+		return fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, method, path, resp.StatusCode, redact.String(strings.TrimSpace(string(body))))
+	}
+	return nil
+}
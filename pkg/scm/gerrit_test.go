@@ -0,0 +1,73 @@
+package scm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func TestGerritPostReview(t *testing.T) {
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("bot:gerrittokenvalue"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.EscapedPath() != "/a/changes/my%2Fproject~12/revisions/3/review" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != basic {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body := &struct {
+			Tag           string                           `json:"tag"`
+			Labels        map[string]int                   `json:"labels"`
+			RobotComments map[string][]*gerritRobotComment `json:"robot_comments"`
+		}{}
+		json.NewDecoder(r.Body).Decode(body)
+		if body.Tag != gerritReviewTag || body.Labels["Code-Coverage"] != -1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("label not permitted\n"))
+			return
+		}
+		comments := body.RobotComments["foo/foo.go"]
+		if len(comments) != 2 || comments[1].Line != 9 || comments[1].RobotID != "gocover" || comments[1].RobotRunID != "run" ||
+			comments[1].Message != "Uncovered line: This line is not covered by tests." {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(")]}'\n{\"labels\": {\"Code-Coverage\": -1}}"))
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_GERRIT_TOKEN", "bot:gerrittokenvalue")
+	token, err := credential.NewProvider("env:GOCOVER_TEST_GERRIT_TOKEN", nil)
+	if err != nil {
+		t.Fatalf("new provider: %s", err)
+	}
+	recorder := audit.NewRecorder("")
+	publisher := NewGerritReviewPublisher(server.URL+"/", token, server.Client(), recorder)
+
+	review := &Review{RobotID: "gocover", RunID: "run", Message: "Diff coverage 50.00%", Label: "Code-Coverage", Vote: -1}
+	for _, line := range []int{5, 9} {
+		review.Comments = append(review.Comments, &CheckAnnotation{Path: "foo/foo.go", StartLine: line, EndLine: line, Title: "Uncovered line", Message: "This line is not covered by tests."})
+	}
+	if err := publisher.PostReview(context.Background(), "my/project~12", "3", review); err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	actions := recorder.Actions()
+	if len(actions) != 1 || actions[0].Type != audit.ReviewAction || actions[0].Target != "my/project~12/3" ||
+		actions[0].Details["label"] != "Code-Coverage-1" || actions[0].Details["comments"] != "2" {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	review.Vote = 2
+	if err := publisher.PostReview(context.Background(), "my/project~12", "3", review); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
@@ -0,0 +1,333 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// DefaultGitHubAPIURL is the api url of github.com, GitHub Enterprise Server uses https://{host}/api/v3.
+	DefaultGitHubAPIURL = "https://api.github.com"
+)
+
+// NewGitHubClient creates the client of GitHub REST API.
+func NewGitHubClient(apiURL string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) Client {
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &githubClient{
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		token:    token,
+		client:   httpClient,
+		recorder: recorder,
+	}
+}
+
+// NewGitHubIssueTracker creates the issue tracker of GitHub REST API.
+func NewGitHubIssueTracker(apiURL string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) IssueTracker {
+	return NewGitHubClient(apiURL, token, httpClient, recorder).(*githubClient)
+}
+
+// NewGitHubCheckPublisher creates the check publisher of GitHub REST API.
+func NewGitHubCheckPublisher(apiURL string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) CheckPublisher {
+	return NewGitHubClient(apiURL, token, httpClient, recorder).(*githubClient)
+}
+
+type githubClient struct {
+	apiURL   string
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ Client = (*githubClient)(nil)
+var _ IssueTracker = (*githubClient)(nil)
+var _ CheckPublisher = (*githubClient)(nil)
+var _ PermissionChecker = (*githubClient)(nil)
+
+type githubPullRequest struct {
+	Number int `json:"number"`
+	Head   struct {
+		SHA string `json:"sha"`
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref  string `json:"ref"`
+		Repo struct {
+			CloneURL string `json:"clone_url"`
+		} `json:"repo"`
+	} `json:"base"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+func (c *githubClient) GetPullRequest(ctx context.Context, owner, repository string, number int) (*PullRequest, error) {
+	result := &githubPullRequest{}
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repository, number)
+	if err := c.do(ctx, http.MethodGet, path, nil, result); err != nil {
+		return nil, fmt.Errorf("get pull request: %w", err)
+	}
+
+	pr := &PullRequest{
+		Owner:      owner,
+		Repository: repository,
+		Number:     result.Number,
+		HeadSHA:    result.Head.SHA,
+		HeadRef:    result.Head.Ref,
+		BaseRef:    result.Base.Ref,
+		CloneURL:   result.Base.Repo.CloneURL,
+	}
+	for _, l := range result.Labels {
+		pr.Labels = append(pr.Labels, l.Name)
+	}
+	return pr, nil
+}
+
+func (c *githubClient) PostComment(ctx context.Context, pr *PullRequest, body string) (string, error) {
+	result := &struct {
+		ID int64 `json:"id"`
+	}{}
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", pr.Owner, pr.Repository, pr.Number)
+	err := c.do(ctx, http.MethodPost, path, map[string]string{"body": body}, result)
+
+	id := ""
+	if err == nil {
+		id = strconv.FormatInt(result.ID, 10)
+	}
+	c.recorder.Record(audit.NewAction(audit.CommentAction, pullRequestTarget(pr), id, err))
+	if err != nil {
+		return "", fmt.Errorf("post comment: %w", err)
+	}
+	return id, nil
+}
+
+func (c *githubClient) GetPermission(ctx context.Context, owner, repository, login string) (string, error) {
+	result := &struct {
+		Permission string `json:"permission"`
+	}{}
+	path := fmt.Sprintf("/repos/%s/%s/collaborators/%s/permission", owner, repository, url.PathEscape(login))
+	if err := c.do(ctx, http.MethodGet, path, nil, result); err != nil {
+		return "", fmt.Errorf("get permission: %w", err)
+	}
+	return result.Permission, nil
+}
+
+// maxListedIssues is the number of the issues listed per page, GitHub allows 100 at most.
+const maxListedIssues = 100
+
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	// PullRequest is set if the issue is a pull request, which are listed along with the issues.
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+func (i *githubIssue) issue() *Issue {
+	issue := &Issue{Number: i.Number, Title: i.Title, Body: i.Body, URL: i.HTMLURL}
+	for _, l := range i.Labels {
+		issue.Labels = append(issue.Labels, l.Name)
+	}
+	return issue
+}
+
+func (c *githubClient) ListIssues(ctx context.Context, owner, repository, label string) ([]*Issue, error) {
+	var issues []*Issue
+	for page := 1; ; page++ {
+		var result []*githubIssue
+		path := fmt.Sprintf("/repos/%s/%s/issues?state=open&labels=%s&per_page=%d&page=%d",
+			owner, repository, url.QueryEscape(label), maxListedIssues, page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return nil, fmt.Errorf("list issues: %w", err)
+		}
+		for _, i := range result {
+			if i.PullRequest == nil {
+				issues = append(issues, i.issue())
+			}
+		}
+		if len(result) < maxListedIssues {
+			return issues, nil
+		}
+	}
+}
+
+func (c *githubClient) CreateIssue(ctx context.Context, owner, repository string, issue *Issue) (*Issue, error) {
+	request := map[string]interface{}{"title": issue.Title, "body": issue.Body, "labels": issue.Labels}
+	result := &githubIssue{}
+	path := fmt.Sprintf("/repos/%s/%s/issues", owner, repository)
+	err := c.do(ctx, http.MethodPost, path, request, result)
+
+	id := ""
+	if err == nil {
+		id = strconv.Itoa(result.Number)
+	}
+	c.recorder.Record(audit.NewAction(audit.IssueAction, fmt.Sprintf("%s/%s", owner, repository), id, err))
+	if err != nil {
+		return nil, fmt.Errorf("create issue: %w", err)
+	}
+	return result.issue(), nil
+}
+
+func (c *githubClient) CloseIssue(ctx context.Context, owner, repository string, number int, comment string) error {
+	target := fmt.Sprintf("%s/%s#%d", owner, repository, number)
+	if comment != "" {
+		path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repository, number)
+		err := c.do(ctx, http.MethodPost, path, map[string]string{"body": comment}, nil)
+		c.recorder.Record(audit.NewAction(audit.CommentAction, target, "", err))
+		if err != nil {
+			return fmt.Errorf("comment issue: %w", err)
+		}
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repository, number)
+	err := c.do(ctx, http.MethodPatch, path, map[string]string{"state": "closed"}, nil)
+	c.recorder.Record(audit.NewAction(audit.IssueAction, target, strconv.Itoa(number), err))
+	if err != nil {
+		return fmt.Errorf("close issue: %w", err)
+	}
+	return nil
+}
+
+// maxCheckAnnotations is the number of the annotations per request of a check run, GitHub allows 50 at most,
+// the rest annotations are appended by updating the check run.
+const maxCheckAnnotations = 50
+
+type githubCheckAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+}
+
+type githubCheckOutput struct {
+	Title       string                   `json:"title"`
+	Summary     string                   `json:"summary"`
+	Annotations []*githubCheckAnnotation `json:"annotations,omitempty"`
+}
+
+func (c *githubClient) CreateCheckRun(ctx context.Context, owner, repository string, run *CheckRun) (string, error) {
+	annotations := make([]*githubCheckAnnotation, 0, len(run.Annotations))
+	for _, a := range run.Annotations {
+		annotations = append(annotations, &githubCheckAnnotation{
+			Path:            a.Path,
+			StartLine:       a.StartLine,
+			EndLine:         a.EndLine,
+			AnnotationLevel: a.Level,
+			Title:           a.Title,
+			Message:         a.Message,
+		})
+	}
+	batch := func() *githubCheckOutput {
+		n := len(annotations)
+		if n > maxCheckAnnotations {
+			n = maxCheckAnnotations
+		}
+		output := &githubCheckOutput{Title: run.Title, Summary: run.Summary, Annotations: annotations[:n]}
+		annotations = annotations[n:]
+		return output
+	}
+
+	result := &struct {
+		ID int64 `json:"id"`
+	}{}
+	target := fmt.Sprintf("%s/%s@%s", owner, repository, run.HeadSHA)
+	request := map[string]interface{}{
+		"name":       run.Name,
+		"head_sha":   run.HeadSHA,
+		"status":     "completed",
+		"conclusion": run.Conclusion,
+		"output":     batch(),
+	}
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/check-runs", owner, repository), request, result)
+	for err == nil && len(annotations) != 0 {
+		path := fmt.Sprintf("/repos/%s/%s/check-runs/%d", owner, repository, result.ID)
+		err = c.do(ctx, http.MethodPatch, path, map[string]interface{}{"output": batch()}, nil)
+	}
+
+	id := ""
+	if result.ID != 0 {
+		id = strconv.FormatInt(result.ID, 10)
+	}
+	action := audit.NewAction(audit.CheckAction, target, id, err)
+	action.Details = map[string]string{"conclusion": run.Conclusion, "annotations": strconv.Itoa(len(run.Annotations))}
+	c.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("create check run: %w", err)
+	}
+	return id, nil
+}
+
+// do sends the request to GitHub api and decodes the response into result.
+func (c *githubClient) do(ctx context.Context, method, path string, request interface{}, result interface{}) error {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, method, path, resp.StatusCode, redact.String(string(data)))
+	}
+	if result != nil && len(data) != 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// pullRequestTarget describes the pull request in audit log.
+func pullRequestTarget(pr *PullRequest) string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repository, pr.Number)
+}
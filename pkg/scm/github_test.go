@@ -0,0 +1,215 @@
+package scm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func newTestGitHubClient(t *testing.T, handler http.HandlerFunc) (Client, audit.Recorder, func()) {
+	server := httptest.NewServer(handler)
+	t.Setenv("GOCOVER_TEST_GITHUB_TOKEN", "githubtokenvalue")
+	token, err := credential.NewProvider("env:GOCOVER_TEST_GITHUB_TOKEN", nil)
+	if err != nil {
+		t.Fatalf("new provider: %s", err)
+	}
+	recorder := audit.NewRecorder("")
+	return NewGitHubClient(server.URL, token, server.Client(), recorder), recorder, server.Close
+}
+
+func TestGitHubGetPullRequest(t *testing.T) {
+	client, _, clean := newTestGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/Azure/gocover/pulls/12" || r.Header.Get("Authorization") != "Bearer githubtokenvalue" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+			"number": 12,
+			"head": {"sha": "abc", "ref": "feature"},
+			"base": {"ref": "main", "repo": {"clone_url": "https://github.com/Azure/gocover.git"}},
+			"labels": [{"name": "bug"}]
+		}`))
+	})
+	defer clean()
+
+	pr, err := client.GetPullRequest(context.Background(), "Azure", "gocover", 12)
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if pr.HeadSHA != "abc" || pr.BaseRef != "main" || pr.CloneURL != "https://github.com/Azure/gocover.git" || len(pr.Labels) != 1 {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+
+	if _, err := client.GetPullRequest(context.Background(), "Azure", "gocover", 13); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
+
+func TestGitHubGetPermission(t *testing.T) {
+	client, _, clean := newTestGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/Azure/gocover/collaborators/octocat/permission" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"permission": "write", "role_name": "maintain"}`))
+	})
+	defer clean()
+
+	checker := client.(PermissionChecker)
+	permission, err := checker.GetPermission(context.Background(), "Azure", "gocover", "octocat")
+	if err != nil || permission != WritePermission {
+		t.Errorf("expect write permission, but get %q, %v", permission, err)
+	}
+	if _, err := checker.GetPermission(context.Background(), "Azure", "gocover", "ghost"); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
+
+func TestGitHubPostComment(t *testing.T) {
+	client, recorder, clean := newTestGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/Azure/gocover/issues/12/comments" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["body"] != "hello" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1001}`))
+	})
+	defer clean()
+
+	pr := &PullRequest{Owner: "Azure", Repository: "gocover", Number: 12}
+	id, err := client.PostComment(context.Background(), pr, "hello")
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if id != "1001" {
+		t.Errorf("expect comment id 1001, but get %s", id)
+	}
+
+	if _, err := client.PostComment(context.Background(), pr, "bad"); err == nil {
+		t.Error("should return error, but get nil")
+	}
+
+	actions := recorder.Actions()
+	if len(actions) != 2 {
+		t.Fatalf("expect 2 audit actions, but get %d", len(actions))
+	}
+	if !actions[0].Succeeded || actions[0].ResponseID != "1001" || actions[0].Target != "Azure/gocover#12" {
+		t.Errorf("unexpected audit action %+v", actions[0])
+	}
+	if actions[1].Succeeded {
+		t.Errorf("failed comment should be recorded as failed, but get %+v", actions[1])
+	}
+}
+
+func TestGitHubIssues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/Azure/gocover/issues":
+			if r.URL.Query().Get("labels") != "coverage-debt" || r.URL.Query().Get("state") != "open" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`[{"number": 1, "title": "debt", "labels": [{"name": "coverage-debt"}]}, {"number": 2, "pull_request": {}}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/Azure/gocover/issues":
+			body := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 3, "title": "` + body["title"].(string) + `", "html_url": "https://github.com/Azure/gocover/issues/3"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/Azure/gocover/issues/1/comments":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 1001}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/Azure/gocover/issues/1":
+			w.Write([]byte(`{"number": 1, "state": "closed"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	recorder := audit.NewRecorder("")
+	tracker := NewGitHubIssueTracker(server.URL, nil, server.Client(), recorder)
+	ctx := context.Background()
+
+	issues, err := tracker.ListIssues(ctx, "Azure", "gocover", "coverage-debt")
+	if err != nil || len(issues) != 1 || issues[0].Labels[0] != "coverage-debt" {
+		t.Errorf("expect the issue without the pull request, but get %+v %v", issues, err)
+	}
+	issue, err := tracker.CreateIssue(ctx, "Azure", "gocover", &Issue{Title: "new debt", Labels: []string{"coverage-debt"}})
+	if err != nil || issue.Number != 3 || issue.Title != "new debt" || issue.URL == "" {
+		t.Errorf("unexpected created issue %+v %v", issue, err)
+	}
+	if err := tracker.CloseIssue(ctx, "Azure", "gocover", 1, "paid"); err != nil {
+		t.Errorf("should not error, but get %s", err)
+	}
+	if err := tracker.CloseIssue(ctx, "Azure", "gocover", 5, ""); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+
+	actions := recorder.Actions()
+	if len(actions) != 4 || actions[0].Type != audit.IssueAction || actions[0].ResponseID != "3" || actions[3].Succeeded {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+}
+
+func TestGitHubCreateCheckRun(t *testing.T) {
+	var created, updated []int
+	client, recorder, clean := newTestGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			HeadSHA    string             `json:"head_sha"`
+			Conclusion string             `json:"conclusion"`
+			Output     *githubCheckOutput `json:"output"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/Azure/gocover/check-runs":
+			if body.HeadSHA != "abc" || body.Conclusion != FailureConclusion || body.Output.Title == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			created = append(created, len(body.Output.Annotations))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 42}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/Azure/gocover/check-runs/42":
+			if body.Output.Summary == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			updated = append(updated, len(body.Output.Annotations))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer clean()
+
+	run := &CheckRun{Name: "gocover", HeadSHA: "abc", Conclusion: FailureConclusion, Title: "50.00%", Summary: "summary"}
+	for i := 1; i <= 120; i++ {
+		run.Annotations = append(run.Annotations, &CheckAnnotation{Path: "foo.go", StartLine: i, EndLine: i, Level: WarningAnnotationLevel, Message: "not covered"})
+	}
+	publisher := client.(CheckPublisher)
+	id, err := publisher.CreateCheckRun(context.Background(), "Azure", "gocover", run)
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if id != "42" || len(created) != 1 || created[0] != 50 || len(updated) != 2 || updated[0] != 50 || updated[1] != 20 {
+		t.Errorf("expect the annotations are sent in batches of 50, but get id %s, created %v, updated %v", id, created, updated)
+	}
+
+	if _, err := publisher.CreateCheckRun(context.Background(), "azure", "other", run); err == nil {
+		t.Error("should return error, but get nil")
+	}
+	actions := recorder.Actions()
+	if len(actions) != 2 || !actions[0].Succeeded || actions[0].Type != audit.CheckAction || actions[0].Target != "Azure/gocover@abc" || actions[1].Succeeded {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+}
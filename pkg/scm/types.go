@@ -0,0 +1,183 @@
+package scm
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrUnexpectedResponse = errors.New("unexpected response")
+)
+
+// PullRequest represents a pull request on the SCM.
+type PullRequest struct {
+	// Owner is the owner (user or organization) of the repository.
+	Owner string
+	// Repository is the name of the repository.
+	Repository string
+	// Number is the number of the pull request.
+	Number int
+	// HeadSHA is the commit sha of the pull request head.
+	HeadSHA string
+	// HeadRef is the branch name of the pull request head.
+	HeadRef string
+	// BaseRef is the branch name that the pull request merges into.
+	BaseRef string
+	// CloneURL is the url to clone the repository.
+	CloneURL string
+	// Labels are the labels of the pull request.
+	Labels []string
+}
+
+// Comment represents a comment on a pull request.
+type Comment struct {
+	ID     string
+	Author string
+	Body   string
+}
+
+// Client interface for interacting with the pull requests on SCM.
+type Client interface {
+	// GetPullRequest returns the pull request.
+	GetPullRequest(ctx context.Context, owner, repository string, number int) (*PullRequest, error)
+	// PostComment posts a comment on the pull request and returns the id of the comment.
+	PostComment(ctx context.Context, pr *PullRequest, body string) (string, error)
+}
+
+// The permissions of a user on a repository.
+const (
+	AdminPermission = "admin"
+	WritePermission = "write"
+	ReadPermission  = "read"
+	NonePermission  = "none"
+)
+
+// PermissionChecker interface for checking the permissions of the users on the repositories on SCM.
+type PermissionChecker interface {
+	// GetPermission returns the permission of the user on the repository, one of admin, write, read and none.
+	GetPermission(ctx context.Context, owner, repository, login string) (string, error)
+}
+
+// Issue represents an issue of a repository.
+type Issue struct {
+	Number int
+	Title  string
+	Body   string
+	Labels []string
+	// URL is the web url of the issue.
+	URL string
+}
+
+// IssueTracker interface for tracking the backlog items as issues on SCM.
+type IssueTracker interface {
+	// ListIssues returns the open issues of the repository that have the label.
+	ListIssues(ctx context.Context, owner, repository, label string) ([]*Issue, error)
+	// CreateIssue opens the issue in the repository, the number and the url of the returned issue are filled.
+	CreateIssue(ctx context.Context, owner, repository string, issue *Issue) (*Issue, error)
+	// CloseIssue comments on the issue if the comment is not empty, and closes it.
+	CloseIssue(ctx context.Context, owner, repository string, number int, comment string) error
+}
+
+// The conclusions of a completed check run.
+const (
+	SuccessConclusion = "success"
+	FailureConclusion = "failure"
+	NeutralConclusion = "neutral"
+)
+
+// The levels of a check run annotation.
+const (
+	NoticeAnnotationLevel  = "notice"
+	WarningAnnotationLevel = "warning"
+	FailureAnnotationLevel = "failure"
+)
+
+// CheckRun is a completed check run of a commit.
+type CheckRun struct {
+	// Name is the name of the check run, such as gocover.
+	Name string
+	// HeadSHA is the commit that the check run is attached to.
+	HeadSHA string
+	// Conclusion is one of success, failure and neutral.
+	Conclusion string
+	// Title is the title of the output of the check run.
+	Title string
+	// Summary is the markdown summary of the output of the check run.
+	Summary string
+	// CoveragePercent is the coverage of the run, it's shown by the SCMs that have the metrics of a report,
+	// such as Bitbucket Code Insights.
+	CoveragePercent float64
+	// Annotations are the inline annotations of the files changed.
+	Annotations []*CheckAnnotation
+}
+
+// CheckAnnotation is an inline annotation of a check run.
+type CheckAnnotation struct {
+	// Path is the file path relative to the repository root.
+	Path string
+	// StartLine is the first line of the annotation.
+	StartLine int
+	// EndLine is the last line of the annotation.
+	EndLine int
+	// Level is one of notice, warning and failure.
+	Level string
+	// Title is the title of the annotation.
+	Title string
+	// Message is the message of the annotation.
+	Message string
+}
+
+// CheckPublisher interface for publishing the check runs of the commits on SCM.
+type CheckPublisher interface {
+	// CreateCheckRun creates the completed check run in the repository and returns the id of the check run.
+	CreateCheckRun(ctx context.Context, owner, repository string, run *CheckRun) (string, error)
+}
+
+// The states of a pull request status.
+const (
+	SucceededStatusState = "succeeded"
+	FailedStatusState    = "failed"
+	PendingStatusState   = "pending"
+)
+
+// PullRequestStatus is a status of a pull request, which a branch policy can require.
+type PullRequestStatus struct {
+	// Name is the name of the status, a status replaces the previous status of the same name.
+	Name string
+	// State is one of succeeded, failed and pending.
+	State string
+	// Description is the description of the status.
+	Description string
+	// TargetURL is the url of the details of the status, such as the build, it's optional.
+	TargetURL string
+}
+
+// StatusPublisher interface for setting the statuses of the pull requests on SCM.
+type StatusPublisher interface {
+	// SetStatus sets the status of the pull request and returns the id of the status.
+	SetStatus(ctx context.Context, pr *PullRequest, status *PullRequestStatus) (string, error)
+}
+
+// Review is a review of a revision of a change, such as a Gerrit change, with a label vote and the robot comments.
+type Review struct {
+	// RobotID is the id of the robot that posts the comments, such as gocover.
+	RobotID string
+	// RunID is the id of the run of the robot, the comments of a run are grouped by it.
+	RunID string
+	// Message is the message of the review.
+	Message string
+	// Label is the label voted by the review, there is no vote if it's empty.
+	Label string
+	// Vote is the value voted on the label.
+	Vote int
+	// URL is the url of the details of the run, such as the build, it's optional.
+	URL string
+	// Comments are the robot comments at the lines of the files.
+	Comments []*CheckAnnotation
+}
+
+// ReviewPublisher interface for posting the reviews of the changes on SCM.
+type ReviewPublisher interface {
+	// PostReview posts the review on the revision of the change, the revision is a patch set number or a commit sha.
+	PostReview(ctx context.Context, change, revision string, review *Review) error
+}
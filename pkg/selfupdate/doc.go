@@ -0,0 +1,3 @@
+// Package selfupdate replaces the running gocover binary with the binary of a GitHub release for the current platform,
+// the archive is verified against the checksums of the release before the binary is replaced.
+package selfupdate
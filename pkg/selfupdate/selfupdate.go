@@ -0,0 +1,383 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRepository is the GitHub repository that publishes the releases of gocover.
+	DefaultRepository = "Azure/gocover"
+	// DefaultAPIURL is the api url of github.com.
+	DefaultAPIURL = "https://api.github.com"
+
+	// projectName is the name of the binary and the prefix of the release assets.
+	projectName = "gocover"
+	// maxAssetSize bounds the size of a downloaded asset, so a wrong asset doesn't exhaust the memory.
+	maxAssetSize = 256 << 20
+)
+
+var (
+	ErrNoAsset            = errors.New("no release asset")
+	ErrChecksumMismatch   = errors.New("checksum mismatch")
+	ErrUnexpectedResponse = errors.New("unexpected response")
+)
+
+// Option contains the input for the self update.
+type Option struct {
+	// Repository is the GitHub repository of the releases in the format of owner/name, DefaultRepository is used if it's empty.
+	Repository string
+	// APIURL is the url of GitHub REST API, DefaultAPIURL is used if it's empty, GitHub Enterprise Server uses https://{host}/api/v3.
+	APIURL string
+	// Token authenticates the requests of the release, so the rate limit of the anonymous requests is not hit, it's optional.
+	Token credential.Provider
+	// Version is the tag of the release to install, such as v1.2.0, the latest release is installed if it's empty.
+	Version string
+	// CurrentVersion is the version of the running binary, nothing is installed if it's the version of the release.
+	CurrentVersion string
+	// Force installs the release even if it's the current version.
+	Force bool
+	// DryRun finds and verifies the release, but doesn't replace the binary.
+	DryRun bool
+	// Executable is the binary that is replaced, the running binary is used if it's empty.
+	Executable string
+	// GOOS and GOARCH are the platform of the binary, the current platform is used if they are empty.
+	GOOS   string
+	GOARCH string
+}
+
+// Result is the outcome of the self update.
+type Result struct {
+	// Version is the tag of the release.
+	Version string
+	// Asset is the name of the archive of the binary.
+	Asset string
+	// Updated indicates the binary is replaced.
+	Updated bool
+}
+
+type release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []asset `json:"assets"`
+}
+
+type asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Update installs the release of the option. The releases are built by goreleaser, so the archive of the platform
+// is named gocover_{version}_{os}_{arch}.tar.gz or .zip, and its sha256 is in gocover_{version}_checksums.txt of the release.
+// The binary is only replaced after the checksum of the archive matches.
+func Update(ctx context.Context, o *Option, client *http.Client, logger logrus.FieldLogger) (*Result, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := &updater{option: o, client: client}
+
+	r, err := u.release(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("find release: %w", err)
+	}
+	result := &Result{Version: r.TagName}
+	if !o.Force && normalizeVersion(r.TagName) == normalizeVersion(o.CurrentVersion) {
+		logger.Infof("gocover %s is up to date", o.CurrentVersion)
+		return result, nil
+	}
+
+	goos, goarch := platform(o)
+	archive, checksums, err := findAssets(r, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+	result.Asset = archive.Name
+
+	sums, err := u.download(ctx, checksums.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", checksums.Name, err)
+	}
+	expected, err := findChecksum(sums, archive.Name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := u.download(ctx, archive.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", archive.Name, err)
+	}
+	if err := verifyChecksum(data, expected); err != nil {
+		return nil, fmt.Errorf("%s: %w", archive.Name, err)
+	}
+	logger.Infof("verified sha256 of %s", archive.Name)
+
+	binary, err := extractBinary(archive.Name, data, binaryName(goos))
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", archive.Name, err)
+	}
+	if o.DryRun {
+		logger.Infof("dry run, gocover %s is not installed", r.TagName)
+		return result, nil
+	}
+
+	executable, err := executablePath(o.Executable)
+	if err != nil {
+		return nil, err
+	}
+	if err := replaceExecutable(executable, binary); err != nil {
+		return nil, fmt.Errorf("replace %s: %w", executable, err)
+	}
+	logger.Infof("installed gocover %s into %s", r.TagName, executable)
+	result.Updated = true
+	return result, nil
+}
+
+type updater struct {
+	option *Option
+	client *http.Client
+}
+
+// release returns the release of the version, or the latest release.
+func (u *updater) release(ctx context.Context) (*release, error) {
+	repository := u.option.Repository
+	if repository == "" {
+		repository = DefaultRepository
+	}
+	apiURL := strings.TrimSuffix(u.option.APIURL, "/")
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/releases/latest", apiURL, repository)
+	if u.option.Version != "" {
+		endpoint = fmt.Sprintf("%s/repos/%s/releases/tags/%s", apiURL, repository, u.option.Version)
+	}
+
+	data, err := u.get(ctx, endpoint, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	r := &release{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	return r, nil
+}
+
+func (u *updater) download(ctx context.Context, url string) ([]byte, error) {
+	return u.get(ctx, url, "application/octet-stream")
+}
+
+func (u *updater) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if u.option.Token != nil {
+		token, err := u.option.Token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: GET %s returns %d: %s", ErrUnexpectedResponse, url, resp.StatusCode, redact.String(string(data)))
+	}
+	if len(data) > maxAssetSize {
+		return nil, fmt.Errorf("%w: GET %s is larger than %d bytes", ErrUnexpectedResponse, url, maxAssetSize)
+	}
+	return data, nil
+}
+
+// platform returns the platform of the option, the current platform is used for the empty fields.
+func platform(o *Option) (string, string) {
+	goos, goarch := o.GOOS, o.GOARCH
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return goos, goarch
+}
+
+// findAssets returns the archive of the platform and the checksums of the release.
+func findAssets(r *release, goos, goarch string) (*asset, *asset, error) {
+	prefix := fmt.Sprintf("%s_%s_%s_%s", projectName, normalizeVersion(r.TagName), goos, goarch)
+	var archive, checksums *asset
+	for i := range r.Assets {
+		a := &r.Assets[i]
+		switch {
+		case strings.HasSuffix(a.Name, "checksums.txt"):
+			checksums = a
+		case archive == nil && (a.Name == prefix+".tar.gz" || a.Name == prefix+".zip"):
+			archive = a
+		}
+	}
+	if archive == nil {
+		return nil, nil, fmt.Errorf("%w of %s/%s in release %s, expect %s.tar.gz or %s.zip", ErrNoAsset, goos, goarch, r.TagName, prefix, prefix)
+	}
+	if checksums == nil {
+		return nil, nil, fmt.Errorf("%w of the checksums in release %s, the binary is not installed unverified", ErrNoAsset, r.TagName)
+	}
+	return archive, checksums, nil
+}
+
+// findChecksum returns the sha256 of the file in the checksums, each line of which is in the format of sha256sum.
+func findChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no checksum of %s", ErrChecksumMismatch, name)
+}
+
+func verifyChecksum(data []byte, expected string) error {
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("%w: sha256 is %s, but the release has %s", ErrChecksumMismatch, actual, expected)
+	}
+	return nil
+}
+
+func binaryName(goos string) string {
+	if goos == "windows" {
+		return projectName + ".exe"
+	}
+	return projectName
+}
+
+// extractBinary returns the contents of the binary in the root of the archive.
+func extractBinary(archive string, data []byte, binary string) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range reader.File {
+			if path.Clean(f.Name) != binary {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(io.LimitReader(rc, maxAssetSize))
+		}
+		return nil, fmt.Errorf("%w: no %s in the archive", ErrNoAsset, binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: no %s in the archive", ErrNoAsset, binary)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && path.Clean(header.Name) == binary {
+			return io.ReadAll(io.LimitReader(reader, maxAssetSize))
+		}
+	}
+}
+
+// executablePath returns the binary to replace, the symlinks are resolved so the target of a link is replaced.
+func executablePath(executable string) (string, error) {
+	if executable == "" {
+		var err error
+		if executable, err = os.Executable(); err != nil {
+			return "", fmt.Errorf("find executable: %w", err)
+		}
+	}
+	resolved, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", fmt.Errorf("find executable: %w", err)
+	}
+	return resolved, nil
+}
+
+// replaceExecutable writes the binary next to the executable and renames it over the executable, so the executable
+// is either the old or the new binary if it's interrupted. The running binary of windows can't be replaced but renamed,
+// so it's moved to {executable}.old first.
+func replaceExecutable(executable string, binary []byte) error {
+	info, err := os.Stat(executable)
+	if err != nil {
+		return err
+	}
+	dir, base := filepath.Split(executable)
+	tmp, err := os.CreateTemp(dir, "."+base+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), executable); err != nil {
+			// the old binary is put back, so the command still works.
+			_ = os.Rename(old, executable)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), executable)
+}
+
+// normalizeVersion removes the v prefix of the tags, goreleaser names the archives by the version without it.
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
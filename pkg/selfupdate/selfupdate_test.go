@@ -0,0 +1,237 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func tarGz(t *testing.T, name string, contents []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	w := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name     string
+		contents []byte
+	}{{"README.md", []byte("# gocover")}, {name, contents}} {
+		if err := w.WriteHeader(&tar.Header{Name: f.name, Mode: 0755, Size: int64(len(f.contents)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(f.contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zipArchive(t *testing.T, name string, contents []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	f, err := w.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(contents); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func sha(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newReleaseServer serves the release v1.2.0 with the archives and their checksums, the checksums can be overridden.
+func newReleaseServer(t *testing.T, archives map[string][]byte, checksums map[string]string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	var sums bytes.Buffer
+	assets := []asset{{Name: "gocover_1.2.0_checksums.txt", URL: server.URL + "/download/gocover_1.2.0_checksums.txt"}}
+	for name, data := range archives {
+		sum := sha(data)
+		if s, ok := checksums[name]; ok {
+			sum = s
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", sum, name)
+		assets = append(assets, asset{Name: name, URL: server.URL + "/download/" + name})
+	}
+	r := &release{TagName: "v1.2.0", Assets: assets}
+
+	handleRelease := func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.Path+" "+req.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(r)
+	}
+	mux.HandleFunc("/repos/Azure/gocover/releases/latest", handleRelease)
+	mux.HandleFunc("/repos/Azure/gocover/releases/tags/v1.2.0", handleRelease)
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, req *http.Request) {
+		name := filepath.Base(req.URL.Path)
+		requests = append(requests, req.URL.Path)
+		if name == "gocover_1.2.0_checksums.txt" {
+			w.Write(sums.Bytes())
+			return
+		}
+		w.Write(archives[name])
+	})
+	return server, &requests
+}
+
+func writeExecutable(t *testing.T) string {
+	t.Helper()
+	executable := filepath.Join(t.TempDir(), "gocover")
+	if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return executable
+}
+
+type staticToken string
+
+func (s staticToken) Token(ctx context.Context) (string, error) { return string(s), nil }
+
+func TestUpdate(t *testing.T) {
+	archives := map[string][]byte{
+		"gocover_1.2.0_linux_amd64.tar.gz":  tarGz(t, "gocover", []byte("linux binary")),
+		"gocover_1.2.0_darwin_arm64.tar.gz": tarGz(t, "gocover", []byte("darwin binary")),
+		"gocover_1.2.0_windows_amd64.zip":   zipArchive(t, "gocover.exe", []byte("windows binary")),
+	}
+	logger := logrus.New()
+
+	t.Run("latest", func(t *testing.T) {
+		server, requests := newReleaseServer(t, archives, nil)
+		executable := writeExecutable(t)
+		result, err := Update(context.Background(), &Option{
+			APIURL: server.URL, Token: staticToken("secret"), CurrentVersion: "v1.1.0",
+			Executable: executable, GOOS: "linux", GOARCH: "amd64",
+		}, server.Client(), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Updated || result.Version != "v1.2.0" || result.Asset != "gocover_1.2.0_linux_amd64.tar.gz" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		data, _ := os.ReadFile(executable)
+		if string(data) != "linux binary" {
+			t.Errorf("executable should be replaced, but %q", data)
+		}
+		if info, _ := os.Stat(executable); info.Mode().Perm()&0111 == 0 {
+			t.Errorf("executable should be executable, but %s", info.Mode())
+		}
+		if (*requests)[0] != "/repos/Azure/gocover/releases/latest Bearer secret" {
+			t.Errorf("release should be requested with the token, but %v", *requests)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(executable))
+		if len(entries) != 1 {
+			t.Errorf("temporary files should be removed, but %v", entries)
+		}
+	})
+
+	t.Run("zip of version", func(t *testing.T) {
+		server, requests := newReleaseServer(t, archives, nil)
+		executable := writeExecutable(t)
+		_, err := Update(context.Background(), &Option{
+			APIURL: server.URL, Version: "v1.2.0", Executable: executable, GOOS: "windows", GOARCH: "amd64",
+		}, server.Client(), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(executable); string(data) != "windows binary" {
+			t.Errorf("executable should be replaced, but %q", data)
+		}
+		if (*requests)[0] != "/repos/Azure/gocover/releases/tags/v1.2.0 " {
+			t.Errorf("release of the tag should be requested, but %v", *requests)
+		}
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		server, requests := newReleaseServer(t, archives, nil)
+		executable := writeExecutable(t)
+		result, err := Update(context.Background(), &Option{
+			APIURL: server.URL, CurrentVersion: "1.2.0", Executable: executable, GOOS: "linux", GOARCH: "amd64",
+		}, server.Client(), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Updated || len(*requests) != 1 {
+			t.Errorf("nothing should be downloaded, but %+v %v", result, *requests)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		server, _ := newReleaseServer(t, archives, nil)
+		executable := writeExecutable(t)
+		result, err := Update(context.Background(), &Option{
+			APIURL: server.URL, CurrentVersion: "v1.2.0", Force: true, DryRun: true,
+			Executable: executable, GOOS: "darwin", GOARCH: "arm64",
+		}, server.Client(), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(executable); result.Updated || string(data) != "old" {
+			t.Errorf("executable should not be replaced, but %q", data)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		server, _ := newReleaseServer(t, archives, map[string]string{"gocover_1.2.0_linux_amd64.tar.gz": sha([]byte("tampered"))})
+		executable := writeExecutable(t)
+		_, err := Update(context.Background(), &Option{
+			APIURL: server.URL, Executable: executable, GOOS: "linux", GOARCH: "amd64",
+		}, server.Client(), logger)
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("should fail with checksum mismatch, but %v", err)
+		}
+		if data, _ := os.ReadFile(executable); string(data) != "old" {
+			t.Errorf("executable should not be replaced, but %q", data)
+		}
+	})
+
+	t.Run("no asset", func(t *testing.T) {
+		server, _ := newReleaseServer(t, archives, nil)
+		_, err := Update(context.Background(), &Option{
+			APIURL: server.URL, Executable: writeExecutable(t), GOOS: "plan9", GOARCH: "amd64",
+		}, server.Client(), logger)
+		if !errors.Is(err, ErrNoAsset) {
+			t.Errorf("should fail without the asset, but %v", err)
+		}
+	})
+}
+
+func TestFindChecksum(t *testing.T) {
+	checksums := []byte("abc  gocover_1.2.0_linux_amd64.tar.gz\nDEF *gocover_1.2.0_windows_amd64.zip\n")
+	if sum, err := findChecksum(checksums, "gocover_1.2.0_windows_amd64.zip"); err != nil || sum != "def" {
+		t.Errorf("checksum should be def, but %q %v", sum, err)
+	}
+	if _, err := findChecksum(checksums, "gocover_1.2.0_darwin_amd64.tar.gz"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("should fail without the checksum, but %v", err)
+	}
+}
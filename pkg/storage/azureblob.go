@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+// azureBlobAPIVersion is the version of Azure Blob Storage REST API, it accepts the bearer tokens of Microsoft Entra.
+const azureBlobAPIVersion = "2021-08-06"
+
+// The authentication schemes of Azure Blob Storage.
+const (
+	// SASAzureBlobAuth appends the token to the urls as a shared access signature, such as sv=...&sig=...
+	SASAzureBlobAuth = "sas"
+	// BearerAzureBlobAuth sends the token as a bearer token of Microsoft Entra, such as the token of a managed identity
+	// of the resource https://storage.azure.com/, the identity needs the Storage Blob Data Contributor role.
+	BearerAzureBlobAuth = "bearer"
+)
+
+var ErrUnknownAzureBlobAuth = errors.New("unknown azure blob authentication, one of: sas, bearer")
+
+// NewAzureBlobStorage creates the storage of an Azure Blob Storage container, the container url is such as
+// https://{account}.blob.core.windows.net/{container}. The urls of the objects are relative to the public url,
+// or the container url if it's empty, the shared access signature is never in the urls.
+// Get and List need the read and list permissions of the signature, or the Storage Blob Data Reader role.
+func NewAzureBlobStorage(containerURL, publicURL, auth string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) (Storage, error) {
+	switch auth {
+	case "", SASAzureBlobAuth, BearerAzureBlobAuth:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAzureBlobAuth, auth)
+	}
+	if publicURL == "" {
+		publicURL = containerURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &azureBlobStorage{
+		containerURL: strings.TrimSuffix(containerURL, "/"),
+		publicURL:    strings.TrimSuffix(publicURL, "/"),
+		bearer:       auth == BearerAzureBlobAuth,
+		token:        token,
+		client:       httpClient,
+		recorder:     recorder,
+	}, nil
+}
+
+type azureBlobStorage struct {
+	containerURL string
+	publicURL    string
+	// bearer sends the token as a bearer token rather than a shared access signature.
+	bearer   bool
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ Storage = (*azureBlobStorage)(nil)
+
+// Put uploads the data as a block blob, the blob of the key is replaced.
+func (s *azureBlobStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	blob := escapeKey(key)
+	err := s.put(ctx, blob, contentType, data)
+
+	action := audit.NewAction(audit.UploadAction, s.publicURL+"/"+blob, "", err)
+	action.Details = map[string]string{"bytes": strconv.Itoa(len(data))}
+	s.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("put blob %s: %w", key, err)
+	}
+	return s.publicURL + "/" + blob, nil
+}
+
+func (s *azureBlobStorage) put(ctx context.Context, blob, contentType string, data []byte) error {
+	req, err := s.newRequest(ctx, http.MethodPut, "/"+blob, nil, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-blob-content-type", contentType)
+	_, err = s.do(req, http.StatusCreated)
+	return err
+}
+
+// Get downloads the blob of the key.
+func (s *azureBlobStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, "/"+escapeKey(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.do(req, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("get blob %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// azureBlobList is the xml of the list blobs operation.
+type azureBlobList struct {
+	Blobs struct {
+		Blob []struct {
+			Name string `xml:"Name"`
+		} `xml:"Blob"`
+	} `xml:"Blobs"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// List lists the blobs with the prefix, the pages of the results are followed by the markers.
+func (s *azureBlobStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := s.do(req, http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("list blobs %s: %w", prefix, err)
+		}
+
+		list := &azureBlobList{}
+		if err := xml.Unmarshal(data, list); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, err)
+		}
+		for _, blob := range list.Blobs.Blob {
+			keys = append(keys, blob.Name)
+		}
+		if marker = list.NextMarker; marker == "" {
+			return keys, nil
+		}
+	}
+}
+
+// newRequest creates the request of the path of the container with the token, the shared access signature is merged into the query.
+func (s *azureBlobStorage) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	endpoint := s.containerURL + path
+	var authorization string
+	if s.token != nil {
+		token, err := s.token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token: %w", err)
+		}
+		if s.bearer {
+			authorization = "Bearer " + token
+		} else {
+			sas, err := url.ParseQuery(strings.TrimPrefix(token, "?"))
+			if err != nil {
+				return nil, fmt.Errorf("parse shared access signature: %w", redact.Error(err))
+			}
+			if query == nil {
+				query = url.Values{}
+			}
+			for k, v := range sas {
+				query[k] = v
+			}
+		}
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	req.Header.Set("x-ms-version", azureBlobAPIVersion)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req, nil
+}
+
+// do sends the request and returns the body of the response of the expected status.
+func (s *azureBlobStorage) do(req *http.Request, expected int) ([]byte, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && req.Method == http.MethodGet && req.URL.Query().Get("comp") == "" {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != expected {
+		// the errors of blob storage are in xml, the code is also in the header.
+		return nil, fmt.Errorf("%w: %s %s returns %d %s: %s", ErrUnexpectedResponse, req.Method, req.URL.Path, resp.StatusCode,
+			resp.Header.Get("x-ms-error-code"), redact.String(strings.TrimSpace(string(body))))
+	}
+	return body, nil
+}
+
+// escapeKey escapes each segment of the key, the slashes are kept as the virtual directories of the blobs.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
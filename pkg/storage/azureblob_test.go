@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func TestNewAzureBlobStorage(t *testing.T) {
+	if _, err := NewAzureBlobStorage("https://account.blob.core.windows.net/reports", "", "key", nil, nil, nil); !errors.Is(err, ErrUnknownAzureBlobAuth) {
+		t.Errorf("expect %s, but get %v", ErrUnknownAzureBlobAuth, err)
+	}
+}
+
+func TestAzureBlobPut(t *testing.T) {
+	blobs := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("x-ms-blob-type") != "BlockBlob" || r.Header.Get("x-ms-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		authorized := r.URL.Query().Get("sig") == "blobsignaturevalue" || r.Header.Get("Authorization") == "Bearer blobaccesstoken"
+		if !authorized {
+			w.Header().Set("x-ms-error-code", "AuthenticationFailed")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<Error><Code>AuthenticationFailed</Code></Error>"))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		blobs[r.URL.EscapedPath()] = r.Header.Get("Content-Type") + " " + string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_BLOB_SAS", "?sv=2021-08-06&sr=c&sp=cw&sig=blobsignaturevalue")
+	t.Setenv("GOCOVER_TEST_BLOB_TOKEN", "blobaccesstoken")
+	sas, _ := credential.NewProvider("env:GOCOVER_TEST_BLOB_SAS", nil)
+	bearer, _ := credential.NewProvider("env:GOCOVER_TEST_BLOB_TOKEN", nil)
+
+	recorder := audit.NewRecorder("")
+	sink, err := NewAzureBlobStorage(server.URL+"/reports/", "https://reports.example.com", SASAzureBlobAuth, sas, server.Client(), recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := sink.Put(context.Background(), "Azure/gocover/feature/x/abc/report.html", "text/html", []byte("<html>"))
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if url != "https://reports.example.com/Azure/gocover/feature/x/abc/report.html" || strings.Contains(url, "sig") {
+		t.Errorf("unexpected url %s", url)
+	}
+	if blobs["/reports/Azure/gocover/feature/x/abc/report.html"] != "text/html <html>" {
+		t.Errorf("unexpected blobs %v", blobs)
+	}
+	actions := recorder.Actions()
+	if len(actions) != 1 || actions[0].Type != audit.UploadAction || actions[0].Details["bytes"] != "6" || strings.Contains(actions[0].Target, "sig") {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	sink, _ = NewAzureBlobStorage(server.URL+"/reports", "", BearerAzureBlobAuth, bearer, server.Client(), nil)
+	url, err = sink.Put(context.Background(), "a b/report.json", "application/json", []byte("{}"))
+	if err != nil || url != server.URL+"/reports/a%20b/report.json" {
+		t.Errorf("unexpected url %s, %v", url, err)
+	}
+
+	sink, _ = NewAzureBlobStorage(server.URL+"/reports", "", SASAzureBlobAuth, nil, server.Client(), nil)
+	_, err = sink.Put(context.Background(), "report.json", "application/json", []byte("{}"))
+	if !errors.Is(err, ErrUnexpectedResponse) || !strings.Contains(err.Error(), "AuthenticationFailed") {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
+
+func TestAzureBlobGetList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sig") != "blobsignaturevalue" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == "/reports" && r.URL.Query().Get("comp") == "list":
+			if r.URL.Query().Get("prefix") != "Azure/gocover/main/abc/" {
+				w.Write([]byte("<EnumerationResults><Blobs></Blobs><NextMarker /></EnumerationResults>"))
+				return
+			}
+			if r.URL.Query().Get("marker") == "" {
+				w.Write([]byte("<EnumerationResults><Blobs><Blob><Name>Azure/gocover/main/abc/badge.svg</Name></Blob></Blobs><NextMarker>page2</NextMarker></EnumerationResults>"))
+				return
+			}
+			w.Write([]byte("<EnumerationResults><Blobs><Blob><Name>Azure/gocover/main/abc/report.json</Name></Blob></Blobs><NextMarker /></EnumerationResults>"))
+		case r.URL.Path == "/reports/Azure/gocover/main/abc/report.json":
+			w.Write([]byte(`{"TotalCoveragePercent":50}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_BLOB_SAS", "sv=2021-08-06&sr=c&sp=rl&sig=blobsignaturevalue")
+	sas, _ := credential.NewProvider("env:GOCOVER_TEST_BLOB_SAS", nil)
+	s, _ := NewAzureBlobStorage(server.URL+"/reports", "", SASAzureBlobAuth, sas, server.Client(), nil)
+
+	names, err := ListReports(context.Background(), s, "Azure/gocover", "main", "abc")
+	if err != nil || strings.Join(names, ",") != "badge.svg,report.json" {
+		t.Errorf("unexpected reports %v, %v", names, err)
+	}
+	data, err := GetReport(context.Background(), s, "Azure/gocover", "main", "abc", "report.json")
+	if err != nil || string(data) != `{"TotalCoveragePercent":50}` {
+		t.Errorf("unexpected report %s, %v", data, err)
+	}
+	if _, err := s.Get(context.Background(), "Azure/gocover/main/def/report.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect %s, but get %v", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package storage archives the reports of the runs in the storage backends, such as the local filesystem, S3, GCS
+// and Azure Blob Storage, keyed by the repository, the branch and the commit, so the reports outlive the builds
+// and the pull request comments can link to them.
+package storage
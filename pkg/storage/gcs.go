@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+// DefaultGCSURL is the url of the json api of Google Cloud Storage.
+const DefaultGCSURL = "https://storage.googleapis.com"
+
+// NewGCSStorage creates the storage of a Google Cloud Storage bucket by the json api. The token is an OAuth 2.0 access token,
+// such as the output of `gcloud auth print-access-token` or the workload identity federation of the CI, the principal
+// needs the Storage Object User role. The urls of the objects are relative to the public url, such as a load balancer
+// of the bucket, or https://storage.googleapis.com/{bucket} if it's empty.
+func NewGCSStorage(baseURL, bucket, publicURL string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) Storage {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if publicURL == "" {
+		publicURL = baseURL + "/" + url.PathEscape(bucket)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &gcsStorage{
+		baseURL:   baseURL,
+		bucket:    bucket,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		token:     token,
+		client:    httpClient,
+		recorder:  recorder,
+	}
+}
+
+type gcsStorage struct {
+	baseURL   string
+	bucket    string
+	publicURL string
+	token     credential.Provider
+	client    *http.Client
+	recorder  audit.Recorder
+}
+
+var _ Storage = (*gcsStorage)(nil)
+
+// Put uploads the data as the object of the key by a simple upload, the object of the key is replaced.
+func (s *gcsStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	err := s.put(ctx, key, contentType, data)
+
+	action := audit.NewAction(audit.UploadAction, s.publicURL+"/"+escapeKey(key), "", err)
+	action.Details = map[string]string{"bytes": strconv.Itoa(len(data))}
+	s.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("put object %s: %w", key, err)
+	}
+	return s.publicURL + "/" + escapeKey(key), nil
+}
+
+func (s *gcsStorage) put(ctx context.Context, key, contentType string, data []byte) error {
+	query := url.Values{"uploadType": {"media"}, "name": {key}}
+	req, err := s.newRequest(ctx, http.MethodPost, s.baseURL+"/upload/storage/v1/b/"+url.PathEscape(s.bucket)+"/o?"+query.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	_, err = s.do(req)
+	return err
+}
+
+// Get downloads the media of the object of the key.
+func (s *gcsStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, s.objectsURL()+"/"+url.PathEscape(key)+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// gcsObjectList is the json of the objects list api.
+type gcsObjectList struct {
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// List lists the objects with the prefix, the pages of the results are followed by the page tokens.
+func (s *gcsStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, s.objectsURL()+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := s.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("list objects %s: %w", prefix, err)
+		}
+
+		list := &gcsObjectList{}
+		if err := json.Unmarshal(data, list); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, err)
+		}
+		for _, item := range list.Items {
+			keys = append(keys, item.Name)
+		}
+		if pageToken = list.NextPageToken; pageToken == "" {
+			return keys, nil
+		}
+	}
+}
+
+func (s *gcsStorage) objectsURL() string {
+	return s.baseURL + "/storage/v1/b/" + url.PathEscape(s.bucket) + "/o"
+}
+
+func (s *gcsStorage) newRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	if s.token != nil {
+		token, err := s.token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// do sends the request and returns the body of the successful response.
+func (s *gcsStorage) do(req *http.Request) ([]byte, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && req.URL.Query().Get("alt") == "media" {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, req.Method, req.URL.Path, resp.StatusCode,
+			redact.String(strings.TrimSpace(string(body))))
+	}
+	return body, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func TestGCSStorage(t *testing.T) {
+	objects := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gcsaccesstoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/reports/o" && r.URL.Query().Get("uploadType") == "media":
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Query().Get("name")] = string(body)
+			w.Write([]byte(`{"name": "` + r.URL.Query().Get("name") + `"}`))
+		case r.URL.Path == "/storage/v1/b/reports/o":
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"items": [{"name": "Azure/gocover/main/abc/badge.svg"}], "nextPageToken": "page2"}`))
+				return
+			}
+			w.Write([]byte(`{"items": [{"name": "Azure/gocover/main/abc/report.json"}]}`))
+		case strings.HasPrefix(r.URL.Path, "/storage/v1/b/reports/o/") && r.URL.Query().Get("alt") == "media":
+			object, ok := objects[strings.TrimPrefix(r.URL.Path, "/storage/v1/b/reports/o/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(object))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_GCS_TOKEN", "gcsaccesstoken")
+	token, _ := credential.NewProvider("env:GOCOVER_TEST_GCS_TOKEN", nil)
+	s := NewGCSStorage(server.URL, "reports", "", token, server.Client(), nil)
+
+	url, err := s.Put(context.Background(), "Azure/gocover/main/abc/report.json", "application/json", []byte("{}"))
+	if err != nil || url != server.URL+"/reports/Azure/gocover/main/abc/report.json" {
+		t.Errorf("unexpected url %s, %v", url, err)
+	}
+	names, err := ListReports(context.Background(), s, "Azure/gocover", "main", "abc")
+	if err != nil || strings.Join(names, ",") != "badge.svg,report.json" {
+		t.Errorf("unexpected reports %v, %v", names, err)
+	}
+	data, err := GetReport(context.Background(), s, "Azure/gocover", "main", "abc", "report.json")
+	if err != nil || string(data) != "{}" {
+		t.Errorf("unexpected report %s, %v", data, err)
+	}
+	if _, err := s.Get(context.Background(), "Azure/gocover/main/def/report.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect %s, but get %v", ErrNotFound, err)
+	}
+
+	s = NewGCSStorage(server.URL, "reports", "https://reports.example.com", nil, server.Client(), nil)
+	if _, err := s.Put(context.Background(), "report.json", "application/json", []byte("{}")); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/atomicfile"
+)
+
+// NewLocalStorage creates the storage of a directory of the local filesystem, such as a mounted network share.
+// The urls of the objects are relative to the public url, such as the url of a static web server of the directory,
+// or the file urls of the objects if it's empty.
+func NewLocalStorage(dir, publicURL string) Storage {
+	return &localStorage{dir: dir, publicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+type localStorage struct {
+	dir       string
+	publicURL string
+}
+
+var _ Storage = (*localStorage)(nil)
+
+// Put writes the data into the file of the key atomically, the parent directories are created.
+func (s *localStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	filename, err := s.filename(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", fmt.Errorf("put %s: %w", key, err)
+	}
+	err = atomicfile.WriteFile(filename, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("put %s: %w", key, err)
+	}
+
+	if s.publicURL != "" {
+		return s.publicURL + "/" + escapeKey(key), nil
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	return "file://" + filepath.ToSlash(abs), nil
+}
+
+// Get reads the file of the key.
+func (s *localStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	filename, err := s.filename(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// List walks the directory for the files with the prefix.
+func (s *localStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(s.dir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && filename == s.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, filename)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			// the directories that can't contain the prefix are skipped.
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// the temporary files of the puts in progress are skipped.
+		if strings.HasPrefix(key, prefix) && !(strings.HasPrefix(d.Name(), ".") && strings.Contains(d.Name(), ".tmp-")) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", prefix, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// filename returns the file of the key, the keys out of the directory are rejected.
+func (s *localStorage) filename(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "https://reports.example.com/")
+
+	for _, key := range []string{"Azure/gocover/main/abc/report.json", "Azure/gocover/main/abc/badge.svg", "Azure/gocover/main/abcd/report.json"} {
+		url, err := s.Put(context.Background(), key, "application/json", []byte(key))
+		if err != nil || url != "https://reports.example.com/"+key {
+			t.Errorf("unexpected url %s, %v", url, err)
+		}
+	}
+	names, err := ListReports(context.Background(), s, "Azure/gocover", "main", "abc")
+	if err != nil || strings.Join(names, ",") != "badge.svg,report.json" {
+		t.Errorf("unexpected reports %v, %v", names, err)
+	}
+	data, err := GetReport(context.Background(), s, "Azure/gocover", "main", "abc", "report.json")
+	if err != nil || string(data) != "Azure/gocover/main/abc/report.json" {
+		t.Errorf("unexpected report %s, %v", data, err)
+	}
+	if _, err := s.Get(context.Background(), "Azure/gocover/main/def/report.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect %s, but get %v", ErrNotFound, err)
+	}
+	if _, err := s.Put(context.Background(), "../outside.json", "application/json", nil); err == nil {
+		t.Error("expect the key out of the directory is rejected")
+	}
+
+	s = NewLocalStorage(filepath.Join(dir, "empty"), "")
+	if keys, err := s.List(context.Background(), ""); err != nil || len(keys) != 0 {
+		t.Errorf("expect no keys of a missing directory, but get %v, %v", keys, err)
+	}
+	url, err := s.Put(context.Background(), "report.html", "text/html", []byte("<html>"))
+	if err != nil || !strings.HasPrefix(url, "file://") {
+		t.Errorf("unexpected url %s, %v", url, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "empty", "report.html")); err != nil {
+		t.Error(err)
+	}
+}
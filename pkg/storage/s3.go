@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// DefaultS3Region is the region of the bucket if it's not set.
+	DefaultS3Region = "us-east-1"
+
+	// s3Service is the service name in the credential scope of the signature.
+	s3Service = "s3"
+	// s3TimeFormat is the format of the x-amz-date header.
+	s3TimeFormat = "20060102T150405Z"
+)
+
+// S3Option contains the location and the credentials of an S3 bucket.
+type S3Option struct {
+	// Bucket is the name of the bucket.
+	Bucket string
+	// Region is the region of the bucket, DefaultS3Region is used if it's empty.
+	Region string
+	// Endpoint is the url of an S3 compatible service, such as MinIO, the bucket is addressed in the path of the endpoint.
+	// The virtual-hosted url of AWS, https://{bucket}.s3.{region}.amazonaws.com, is used if it's empty.
+	Endpoint string
+	// PublicURL is the url that the objects are read from, such as a CloudFront distribution, it's the bucket url if it's empty.
+	PublicURL string
+	// AccessKeyID and SecretAccessKey are the access key of the IAM user or the role, it needs s3:PutObject,
+	// s3:GetObject and s3:ListBucket of the bucket.
+	AccessKeyID     credential.Provider
+	SecretAccessKey credential.Provider
+	// SessionToken is the session token of the temporary credentials of a role, it's optional.
+	SessionToken credential.Provider
+}
+
+// NewS3Storage creates the storage of an S3 bucket, the requests are signed by AWS Signature Version 4.
+func NewS3Storage(o *S3Option, httpClient *http.Client, recorder audit.Recorder) Storage {
+	region := o.Region
+	if region == "" {
+		region = DefaultS3Region
+	}
+	bucketURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", o.Bucket, region)
+	if o.Endpoint != "" {
+		bucketURL = strings.TrimSuffix(o.Endpoint, "/") + "/" + url.PathEscape(o.Bucket)
+	}
+	publicURL := o.PublicURL
+	if publicURL == "" {
+		publicURL = bucketURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &s3Storage{
+		bucketURL:       bucketURL,
+		publicURL:       strings.TrimSuffix(publicURL, "/"),
+		region:          region,
+		accessKeyID:     o.AccessKeyID,
+		secretAccessKey: o.SecretAccessKey,
+		sessionToken:    o.SessionToken,
+		client:          httpClient,
+		recorder:        recorder,
+		now:             time.Now,
+	}
+}
+
+type s3Storage struct {
+	bucketURL       string
+	publicURL       string
+	region          string
+	accessKeyID     credential.Provider
+	secretAccessKey credential.Provider
+	sessionToken    credential.Provider
+	client          *http.Client
+	recorder        audit.Recorder
+	// now returns the time of the signatures.
+	now func() time.Time
+}
+
+var _ Storage = (*s3Storage)(nil)
+
+// Put uploads the data as the object of the key, the object of the key is replaced.
+func (s *s3Storage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	object := escapeKey(key)
+	err := s.put(ctx, object, contentType, data)
+
+	action := audit.NewAction(audit.UploadAction, s.publicURL+"/"+object, "", err)
+	action.Details = map[string]string{"bytes": strconv.Itoa(len(data))}
+	s.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("put object %s: %w", key, err)
+	}
+	return s.publicURL + "/" + object, nil
+}
+
+func (s *s3Storage) put(ctx context.Context, object, contentType string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.bucketURL+"/"+object, bytes.NewReader(data))
+	if err != nil {
+		return redact.Error(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	_, err = s.do(req, data)
+	return err
+}
+
+// Get downloads the object of the key.
+func (s *s3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.bucketURL+"/"+escapeKey(key), nil)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	data, err := s.do(req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// s3ObjectList is the xml of the ListObjectsV2 operation.
+type s3ObjectList struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List lists the objects with the prefix by ListObjectsV2, the pages of the results are followed by the continuation tokens.
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	continuation := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuation != "" {
+			query.Set("continuation-token", continuation)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.bucketURL+"/?"+strings.ReplaceAll(query.Encode(), "+", "%20"), nil)
+		if err != nil {
+			return nil, redact.Error(err)
+		}
+		data, err := s.do(req, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list objects %s: %w", prefix, err)
+		}
+
+		list := &s3ObjectList{}
+		if err := xml.Unmarshal(data, list); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, err)
+		}
+		for _, object := range list.Contents {
+			keys = append(keys, object.Key)
+		}
+		if continuation = list.NextContinuationToken; !list.IsTruncated || continuation == "" {
+			return keys, nil
+		}
+	}
+}
+
+// do signs and sends the request, it returns the body of the successful response.
+func (s *s3Storage) do(req *http.Request, payload []byte) ([]byte, error) {
+	if err := s.sign(req, payload); err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && req.Method == http.MethodGet && req.URL.RawQuery == "" {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, req.Method, req.URL.Path, resp.StatusCode,
+			redact.String(strings.TrimSpace(string(body))))
+	}
+	return body, nil
+}
+
+// sign adds the authorization header of AWS Signature Version 4 to the request, the host, the content type,
+// and the x-amz-* headers are signed.
+func (s *s3Storage) sign(req *http.Request, payload []byte) error {
+	ctx := req.Context()
+	accessKeyID, err := token(ctx, s.accessKeyID, "access key id")
+	if err != nil {
+		return err
+	}
+	secretAccessKey, err := token(ctx, s.secretAccessKey, "secret access key")
+	if err != nil {
+		return err
+	}
+	if s.sessionToken != nil {
+		sessionToken, err := s.sessionToken.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get session token: %w", err)
+		}
+		req.Header.Set("x-amz-security-token", sessionToken)
+	}
+
+	hash := sha256.Sum256(payload)
+	req.Header.Set("x-amz-content-sha256", hex.EncodeToString(hash[:]))
+	req.Header.Set("x-amz-date", s.now().UTC().Format(s3TimeFormat))
+	signV4(req, accessKeyID, secretAccessKey, s.region, s3Service)
+	return nil
+}
+
+// token returns the token of the provider, the provider is required.
+func token(ctx context.Context, p credential.Provider, name string) (string, error) {
+	if p == nil {
+		return "", fmt.Errorf("no %s", name)
+	}
+	t, err := p.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// signV4 signs the request with the x-amz-date and x-amz-content-sha256 headers by AWS Signature Version 4.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func signV4(req *http.Request, accessKeyID, secretAccessKey, region, service string) {
+	amzDate := req.Header.Get("x-amz-date")
+	date := amzDate[:8]
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query sorted by the names and the values, which are escaped by awsEscape.
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape escapes the characters except the unreserved characters of RFC 3986, the slashes are kept unless escapeSlash is true.
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !escapeSlash {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// UncoveredSinceName is the name of the history of the uncovered lines of a branch, it's at {repository}/{branch}/
+// besides the reports of the commits, and it's replaced by each run.
+const UncoveredSinceName = "uncovered-since.json"
+
+// uncoveredHistory is the first run of each uncovered line of a branch. The lines are keyed by the file, the content
+// and the occurrence of the content among the uncovered lines of the file, so the history follows the lines moved by the edits.
+type uncoveredHistory struct {
+	Lines map[string]*uncoveredRun `json:"lines"`
+}
+
+// uncoveredRun is the run that a line first became uncovered in.
+type uncoveredRun struct {
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+}
+
+// SinceOption contains the input for the annotator of the first uncovered runs.
+type SinceOption struct {
+	// Repository is the repository of the history in the format of owner/name.
+	Repository string
+	// Branch is the branch of the history, the branch checked out in the repository is used if it's empty.
+	Branch string
+	// BaseBranch is the branch that the history is read from if the branch has no history yet,
+	// such as the target branch of a pull request, so the first run of the pull request sees the debt of the target.
+	BaseBranch string
+	// Commit is the commit of the run, HEAD of the repository is resolved if it's empty.
+	Commit string
+	// RepositoryPath is the root directory of the git repository.
+	RepositoryPath string
+}
+
+// NewUncoveredSinceAnnotator creates an annotator that sets when each uncovered line of the statistics first became uncovered,
+// by the history of the branch in the storage, and stores the updated history. A full run replaces the history, so the lines
+// covered again are forgotten, and a diff run only adds its uncovered lines. The failures are logged rather than failing the command.
+func NewUncoveredSinceAnnotator(s Storage, o *SinceOption, logger logrus.FieldLogger) report.Annotator {
+	return &uncoveredSinceAnnotator{storage: s, option: o, now: time.Now, logger: logger.WithField("source", "UncoveredSince")}
+}
+
+type uncoveredSinceAnnotator struct {
+	storage Storage
+	option  *SinceOption
+	now     func() time.Time
+	logger  logrus.FieldLogger
+}
+
+var _ report.Annotator = (*uncoveredSinceAnnotator)(nil)
+
+func (a *uncoveredSinceAnnotator) Annotate(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	commit := a.option.Commit
+	if commit == "" {
+		sha, err := gittool.ResolveCommit(ctx, a.option.RepositoryPath, "HEAD")
+		if err != nil {
+			a.logger.WithError(err).Error("resolve the commit of the uncovered lines")
+			return nil
+		}
+		commit = sha
+	}
+	branch := a.option.Branch
+	if branch == "" {
+		b, err := gittool.CurrentBranch(ctx, a.option.RepositoryPath)
+		if err != nil {
+			a.logger.WithError(err).Warn("resolve the branch of the uncovered lines")
+		}
+		branch = b
+	}
+
+	previous, err := a.load(ctx, branch)
+	if err != nil {
+		a.logger.WithError(err).Error("load the history of the uncovered lines")
+		return nil
+	}
+	current := annotateUncoveredSince(statistics, previous, &uncoveredRun{Commit: commit, Time: a.now().UTC()})
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	key := path.Join(Key(a.option.Repository, branch, ""), UncoveredSinceName)
+	if _, err := a.storage.Put(ctx, key, "application/json", data); err != nil {
+		a.logger.WithError(err).Error("store the history of the uncovered lines")
+		return nil
+	}
+	a.logger.Infof("store the history of %d uncovered lines: %s", len(current.Lines), key)
+	return nil
+}
+
+// load returns the history of the branch, or of the base branch if the branch has no history, it's empty if neither has.
+func (a *uncoveredSinceAnnotator) load(ctx context.Context, branch string) (*uncoveredHistory, error) {
+	branches := []string{branch}
+	if a.option.BaseBranch != "" && a.option.BaseBranch != branch {
+		branches = append(branches, a.option.BaseBranch)
+	}
+	for _, b := range branches {
+		data, err := a.storage.Get(ctx, path.Join(Key(a.option.Repository, b, ""), UncoveredSinceName))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		h := &uncoveredHistory{}
+		if err := json.Unmarshal(data, h); err != nil {
+			return nil, fmt.Errorf("decode the history of %s: %w", b, err)
+		}
+		if h.Lines == nil {
+			h.Lines = make(map[string]*uncoveredRun)
+		}
+		return h, nil
+	}
+	return &uncoveredHistory{Lines: make(map[string]*uncoveredRun)}, nil
+}
+
+// annotateUncoveredSince sets the first uncovered runs of the uncovered lines of the statistics, the lines not in the history
+// are uncovered since the run. It returns the history of the run.
+func annotateUncoveredSince(statistics *report.Statistics, previous *uncoveredHistory, run *uncoveredRun) *uncoveredHistory {
+	current := &uncoveredHistory{Lines: make(map[string]*uncoveredRun)}
+	if statistics.StatisticsType != report.FullStatisticsType {
+		// a diff run only sees the changed lines, the other lines are kept.
+		for key, r := range previous.Lines {
+			current.Lines[key] = r
+		}
+	}
+
+	for _, profile := range statistics.CoverageProfile {
+		profile.UncoveredSince = nil
+		for _, line := range uncoveredLineKeys(profile) {
+			r, ok := previous.Lines[line.key]
+			if !ok {
+				r = run
+			}
+			current.Lines[line.key] = r
+			profile.UncoveredSince = append(profile.UncoveredSince, &report.UncoveredSince{
+				Line:   line.line,
+				Commit: r.Commit,
+				Time:   r.Time,
+				New:    !ok,
+			})
+		}
+	}
+	return current
+}
+
+type uncoveredLineKey struct {
+	line int
+	key  string
+}
+
+// uncoveredLineKeys returns the key of each uncovered line of the file in order, which is {file}:{content}#{occurrence},
+// the content is trimmed, it's the line number if the content isn't retained, such as the truncated section.
+func uncoveredLineKeys(profile *report.CoverageProfile) []*uncoveredLineKey {
+	contents := make(map[int]string)
+	for _, section := range profile.ViolationSections {
+		for _, line := range section.ViolationLines {
+			if i := line - section.StartLine; i >= 0 && i < len(section.Contents) {
+				contents[line] = strings.TrimSpace(section.Contents[i])
+			}
+		}
+	}
+
+	lines := append([]int(nil), profile.TotalViolationLines...)
+	sort.Ints(lines)
+	occurrences := make(map[string]int)
+	var keys []*uncoveredLineKey
+	for i, line := range lines {
+		if i > 0 && line == lines[i-1] {
+			continue
+		}
+		content, ok := contents[line]
+		if !ok || content == "" {
+			content = fmt.Sprintf("line %d", line)
+		}
+		keys = append(keys, &uncoveredLineKey{
+			line: line,
+			key:  fmt.Sprintf("%s:%s#%d", profile.FileName, content, occurrences[content]),
+		})
+		occurrences[content]++
+	}
+	return keys
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// sinceStatistics returns the statistics of foo.go whose uncovered lines are the return statements of the contents.
+func sinceStatistics(statisticsType report.StatisticsType, startLine int, contents ...string) *report.Statistics {
+	section := &report.ViolationSection{StartLine: startLine, EndLine: startLine + len(contents) - 1, Contents: contents}
+	for i, c := range contents {
+		if c == "\treturn err" || c == "\treturn nil" {
+			section.ViolationLines = append(section.ViolationLines, startLine+i)
+		}
+	}
+	return &report.Statistics{
+		StatisticsType: statisticsType,
+		CoverageProfile: []*report.CoverageProfile{{
+			FileName:            "github.com/Azure/gocover/pkg/foo/foo.go",
+			TotalViolationLines: section.ViolationLines,
+			ViolationSections:   []*report.ViolationSection{section},
+		}},
+	}
+}
+
+func annotate(t *testing.T, s Storage, commit string, now time.Time, statistics *report.Statistics, o *SinceOption) []*report.UncoveredSince {
+	t.Helper()
+	option := *o
+	option.Commit = commit
+	a := NewUncoveredSinceAnnotator(s, &option, logrus.New()).(*uncoveredSinceAnnotator)
+	a.now = func() time.Time { return now }
+	if err := a.Annotate(statistics); err != nil {
+		t.Fatal(err)
+	}
+	return statistics.CoverageProfile[0].UncoveredSince
+}
+
+func TestUncoveredSinceAnnotator(t *testing.T) {
+	s := NewLocalStorage(t.TempDir(), "")
+	o := &SinceOption{Repository: "Azure/gocover", Branch: "main"}
+	first, second := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+
+	since := annotate(t, s, "aaa", first, sinceStatistics(report.FullStatisticsType, 10, "\treturn err", "}"), o)
+	if len(since) != 1 || !since[0].New || since[0].Line != 10 || since[0].Commit != "aaa" {
+		t.Fatalf("expect line 10 uncovered since this run, but get %+v", since[0])
+	}
+
+	// the line moves down by an edit, it's uncovered since the first run, and the added line is new.
+	since = annotate(t, s, "bbb", second, sinceStatistics(report.FullStatisticsType, 12, "\treturn err", "}", "\treturn nil"), o)
+	if len(since) != 2 {
+		t.Fatalf("expect 2 uncovered lines, but get %d", len(since))
+	}
+	if since[0].Line != 12 || since[0].New || since[0].Commit != "aaa" || !since[0].Time.Equal(first) {
+		t.Errorf("expect line 12 uncovered since aaa, but get %+v", since[0])
+	}
+	if since[1].Line != 14 || !since[1].New || since[1].Commit != "bbb" {
+		t.Errorf("expect line 14 uncovered since this run, but get %+v", since[1])
+	}
+
+	// a pull request branch reads the history of the base branch, and stores its own history.
+	pr := &SinceOption{Repository: "Azure/gocover", Branch: "feature", BaseBranch: "main"}
+	since = annotate(t, s, "ccc", second, sinceStatistics(report.DiffStatisticsType, 12, "\treturn err"), pr)
+	if since[0].New || since[0].Commit != "aaa" {
+		t.Errorf("expect line 12 uncovered since aaa of the base branch, but get %+v", since[0])
+	}
+	data, err := s.Get(context.Background(), "Azure/gocover/feature/"+UncoveredSinceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the diff run keeps the lines of the history that it doesn't see.
+	h := &uncoveredHistory{}
+	if err := json.Unmarshal(data, h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Lines) != 2 {
+		t.Errorf("expect 2 lines in the history of the diff run, but get %v", h.Lines)
+	}
+
+	// the full run forgets the lines covered again.
+	annotate(t, s, "ddd", second, sinceStatistics(report.FullStatisticsType, 1, "}"), o)
+	data, err = s.Get(context.Background(), "Azure/gocover/main/"+UncoveredSinceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = &uncoveredHistory{}
+	if err := json.Unmarshal(data, h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Lines) != 0 {
+		t.Errorf("expect empty history, but get %v", h.Lines)
+	}
+}
+
+func TestUncoveredLineKeys(t *testing.T) {
+	profile := sinceStatistics(report.FullStatisticsType, 3, "\treturn err", "\treturn err").CoverageProfile[0]
+	// the line of the truncated contents is keyed by its number.
+	profile.TotalViolationLines = append(profile.TotalViolationLines, 20)
+	keys := uncoveredLineKeys(profile)
+	expect := []string{
+		"github.com/Azure/gocover/pkg/foo/foo.go:return err#0",
+		"github.com/Azure/gocover/pkg/foo/foo.go:return err#1",
+		"github.com/Azure/gocover/pkg/foo/foo.go:line 20#0",
+	}
+	if len(keys) != len(expect) {
+		t.Fatalf("expect %d keys, but get %d", len(expect), len(keys))
+	}
+	for i, k := range keys {
+		if k.key != expect[i] {
+			t.Errorf("expect %s, but get %s", expect[i], k.key)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// uploadTimeout bounds the time of uploading the reports of a run.
+const uploadTimeout = 5 * time.Minute
+
+var (
+	ErrUnexpectedResponse = errors.New("unexpected response")
+	ErrNotFound           = errors.New("object not found")
+)
+
+// Sink stores the objects of the reports.
+type Sink interface {
+	// Put stores the data as the object of the key, it returns the url of the object.
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+}
+
+// Storage archives the reports in a storage backend, such as the local filesystem, S3, GCS or Azure Blob Storage.
+// The keys of the reports of a commit are prefixed by the Key of the repository, the branch and the commit.
+type Storage interface {
+	Sink
+	// Get returns the data of the object of the key, it returns ErrNotFound if the object doesn't exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the keys of the objects with the prefix in order.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// GetReport returns the report of the commit, such as report.json, it returns ErrNotFound if the report isn't archived.
+func GetReport(ctx context.Context, s Storage, repository, branch, commit, name string) ([]byte, error) {
+	return s.Get(ctx, path.Join(Key(repository, branch, commit), name))
+}
+
+// ListReports returns the names of the archived reports of the commit in order.
+func ListReports(ctx context.Context, s Storage, repository, branch, commit string) ([]string, error) {
+	prefix := Key(repository, branch, commit)
+	if prefix != "" {
+		prefix += "/"
+	}
+	keys, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, strings.TrimPrefix(key, prefix))
+	}
+	return names, nil
+}
+
+// Key returns the key prefix of the reports of a commit, which is {repository}/{branch}/{commit},
+// the empty parts are left out, such as the branch of a detached HEAD.
+func Key(repository, branch, commit string) string {
+	var parts []string
+	for _, part := range []string{repository, strings.TrimPrefix(branch, "refs/heads/"), commit} {
+		if part = strings.Trim(part, "/"); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return path.Join(parts...)
+}
+
+// Option contains the input for the storage report generator.
+type Option struct {
+	// Repository is the repository of the reports in the format of owner/name.
+	Repository string
+	// Branch is the branch of the reports, the branch checked out in the repository is used if it's empty.
+	Branch string
+	// Commit is the commit of the reports, HEAD of the repository is resolved if it's empty.
+	Commit string
+	// RepositoryPath is the root directory of the git repository.
+	RepositoryPath string
+	// Artifacts decides the contents of the uploaded artifacts, the output directory is ignored.
+	Artifacts report.ArtifactsOption
+}
+
+// NewReportGenerator creates a report generator that uploads the artifacts of the statistics, such as report.html and report.json,
+// to the sink. The url of the html report is logged and set to the ReportURL of the statistics, so the report generators
+// after it link to the report. The failed upload is logged rather than failing the command, as the other reports are written.
+func NewReportGenerator(sink Sink, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{sink: sink, option: o, logger: logger.WithField("source", "Storage")}
+}
+
+type reportGenerator struct {
+	sink   Sink
+	option *Option
+	logger logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport writes the artifacts into a temporary directory and uploads them with the key of the commit.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	commit := g.option.Commit
+	if commit == "" {
+		sha, err := gittool.ResolveCommit(ctx, g.option.RepositoryPath, "HEAD")
+		if err != nil {
+			g.logger.WithError(err).Error("resolve the commit of the reports")
+			return nil
+		}
+		commit = sha
+	}
+	branch := g.option.Branch
+	if branch == "" {
+		b, err := gittool.CurrentBranch(ctx, g.option.RepositoryPath)
+		if err != nil {
+			g.logger.WithError(err).Warn("resolve the branch of the reports")
+		}
+		branch = b
+	}
+
+	reportURL, err := g.upload(ctx, statistics, Key(g.option.Repository, branch, commit))
+	if err != nil {
+		g.logger.WithError(err).Errorf("upload reports of %s", commit)
+		return nil
+	}
+	statistics.ReportURL = reportURL
+	g.logger.Infof("upload coverage report: %s", reportURL)
+	return nil
+}
+
+// upload uploads the artifacts, it returns the url of the html report.
+func (g *reportGenerator) upload(ctx context.Context, statistics *report.Statistics, key string) (string, error) {
+	dir, err := os.MkdirTemp("", "gocover-storage-")
+	if err != nil {
+		return "", fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifacts := g.option.Artifacts
+	artifacts.OutputDir = filepath.Join(dir, "artifacts")
+	if err := report.NewArtifactsGenerator(&artifacts, g.logger).GenerateReport(statistics); err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(artifacts.OutputDir)
+	if err != nil {
+		return "", fmt.Errorf("read artifacts: %w", err)
+	}
+
+	var reportURL string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(artifacts.OutputDir, entry.Name()))
+		if err != nil {
+			return "", fmt.Errorf("read artifact: %w", err)
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(entry.Name()))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		url, err := g.sink.Put(ctx, path.Join(key, entry.Name()), contentType, data)
+		if err != nil {
+			return "", err
+		}
+		if entry.Name() == report.HTMLReportArtifact {
+			reportURL = url
+		}
+	}
+	return reportURL, nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKey(t *testing.T) {
+	testSuites := []struct {
+		repository, branch, commit string
+		expected                   string
+	}{
+		{repository: "Azure/gocover", branch: "main", commit: "abc", expected: "Azure/gocover/main/abc"},
+		{repository: "Azure/gocover", branch: "refs/heads/feature/x", commit: "abc", expected: "Azure/gocover/feature/x/abc"},
+		{repository: "Azure/gocover", commit: "abc", expected: "Azure/gocover/abc"},
+		{commit: "abc", expected: "abc"},
+	}
+	for _, testSuite := range testSuites {
+		if key := Key(testSuite.repository, testSuite.branch, testSuite.commit); key != testSuite.expected {
+			t.Errorf("expect %s, but get %s", testSuite.expected, key)
+		}
+	}
+}
+
+type fakeSink struct {
+	keys []string
+	err  error
+}
+
+func (s *fakeSink) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.keys = append(s.keys, key)
+	return "https://reports.example.com/" + key, nil
+}
+
+func TestReportGenerator(t *testing.T) {
+	statistics := &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		TotalCoveragePercent: 50,
+		CoverageProfile:      []*report.CoverageProfile{{FileName: "github.com/Azure/gocover/pkg/foo/foo.go", TotalEffectiveLines: 2, CoveredLines: 1}},
+	}
+	sink := &fakeSink{}
+	g := NewReportGenerator(sink, &Option{Repository: "Azure/gocover", Branch: "main", Commit: "abc", Artifacts: report.ArtifactsOption{ModulePath: "github.com/Azure/gocover"}}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(sink.keys)
+	if len(sink.keys) == 0 || sink.keys[0] != "Azure/gocover/main/abc/badge.svg" {
+		t.Errorf("unexpected keys %v", sink.keys)
+	}
+	if statistics.ReportURL != "https://reports.example.com/Azure/gocover/main/abc/report.html" {
+		t.Errorf("unexpected report url %s", statistics.ReportURL)
+	}
+
+	// the failed upload doesn't fail the command, and no report url is set.
+	statistics.ReportURL = ""
+	g = NewReportGenerator(&fakeSink{err: errors.New("boom")}, &Option{Commit: "abc"}, logrus.New())
+	if err := g.GenerateReport(statistics); err != nil || statistics.ReportURL != "" {
+		t.Errorf("expect no report url, but get %s, %v", statistics.ReportURL, err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package telemetry sends the anonymous usage metrics of a run to the endpoint configured by the user,
+// it's off unless it's turned on explicitly, so the maintainers prioritize the work by the real-world data.
+package telemetry
@@ -0,0 +1,216 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// The modes of the telemetry.
+const (
+	ModeOff = "off"
+	ModeOn  = "on"
+)
+
+// The error classes of the runs, the error messages are never sent since they contain the paths and the branches.
+const (
+	ErrorClassNone        = "none"
+	ErrorClassLowCoverage = "low-coverage"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassExitCode    = "exit-code"
+	ErrorClassToolError   = "tool-error"
+)
+
+// sendTimeout bounds the time of sending the event, so the telemetry never slows down a run noticeably.
+const sendTimeout = 3 * time.Second
+
+var (
+	ErrInvalidMode = errors.New("invalid telemetry mode")
+	ErrNoEndpoint  = errors.New("no telemetry endpoint")
+)
+
+// Option contains the input for the telemetry.
+type Option struct {
+	// Mode is off or on, nothing is collected or sent if it's off.
+	Mode string
+	// Endpoint is the http or https url that the event is posted to as json, it's required if the mode is on.
+	Endpoint string
+}
+
+// Validate checks the mode and the endpoint.
+func (o *Option) Validate() error {
+	switch o.Mode {
+	case ModeOff, "":
+		return nil
+	case ModeOn:
+	default:
+		return fmt.Errorf("%w %q, one of: off, on", ErrInvalidMode, o.Mode)
+	}
+	if o.Endpoint == "" {
+		return fmt.Errorf("%w: --telemetry=on needs --telemetry-endpoint", ErrNoEndpoint)
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s is not an http or https url", ErrNoEndpoint, o.Endpoint)
+	}
+	return nil
+}
+
+// Event is the usage metrics of a run, it's all that is sent. It has no identifier of the user, the machine or the repository.
+type Event struct {
+	// Command is the command used, such as diff or baseline show, without the arguments and the flags.
+	Command string `json:"command"`
+	// Version is the version of gocover.
+	Version string `json:"version"`
+	// OS and Arch are the platform of gocover.
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// DurationSeconds is the duration of the run, rounded to 0.1 second.
+	DurationSeconds float64 `json:"durationSeconds"`
+	// Files and Lines are the buckets of the counted files and the effective lines of the coverage, such as 100-999,
+	// they're empty if the command calculates no coverage.
+	Files string `json:"files,omitempty"`
+	Lines string `json:"lines,omitempty"`
+	// ErrorClass is the class of the error of the run, one of: none, low-coverage, timeout, exit-code, tool-error.
+	ErrorClass string `json:"errorClass"`
+}
+
+// Recorder records the metrics of a run and sends them when the run finishes, all methods are no-op on a nil recorder.
+// It's a report generator, so the size of each coverage of the run is recorded without changing the commands.
+type Recorder struct {
+	endpoint string
+	client   *http.Client
+	logger   logrus.FieldLogger
+	start    time.Time
+
+	mu    sync.Mutex
+	event Event
+}
+
+var _ report.ReportGenerator = (*Recorder)(nil)
+
+// Start starts recording the run of the command, it returns nil if the telemetry is off.
+func Start(o *Option, command, version string, client *http.Client, logger logrus.FieldLogger) *Recorder {
+	if o == nil || o.Mode != ModeOn {
+		return nil
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Recorder{
+		endpoint: o.Endpoint,
+		client:   client,
+		logger:   logger,
+		start:    time.Now(),
+		event: Event{
+			Command: command,
+			Version: version,
+			OS:      runtime.GOOS,
+			Arch:    runtime.GOARCH,
+		},
+	}
+}
+
+// GenerateReport records the size of the coverage, the largest coverage is kept if a run calculates several.
+func (r *Recorder) GenerateReport(statistics *report.Statistics) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if files := Bucket(len(statistics.CoverageProfile)); bucketRank(files) >= bucketRank(r.event.Files) {
+		r.event.Files = files
+	}
+	if lines := Bucket(statistics.TotalEffectiveLines); bucketRank(lines) >= bucketRank(r.event.Lines) {
+		r.event.Lines = lines
+	}
+	return nil
+}
+
+// Finish sends the event of the run with the error class, a failure to send is only logged, it never fails the run.
+func (r *Recorder) Finish(errorClass string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	event := r.event
+	r.mu.Unlock()
+	event.DurationSeconds = math.Round(time.Since(r.start).Seconds()*10) / 10
+	event.ErrorClass = errorClass
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := r.send(ctx, &event); err != nil {
+		r.logger.Debugf("send telemetry: %s", err)
+	}
+}
+
+func (r *Recorder) send(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returns %d", r.endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// buckets are the upper bounds and the names of the size buckets, the sizes are sent as buckets rather than the counts,
+// so a repository can't be told by its size.
+var buckets = []struct {
+	below int
+	name  string
+}{
+	{1, "0"},
+	{10, "1-9"},
+	{100, "10-99"},
+	{1000, "100-999"},
+	{10000, "1k-10k"},
+	{100000, "10k-100k"},
+	{1000000, "100k-1m"},
+}
+
+// Bucket returns the bucket of the size.
+func Bucket(n int) string {
+	for _, b := range buckets {
+		if n < b.below {
+			return b.name
+		}
+	}
+	return "1m+"
+}
+
+// bucketRank returns the order of the bucket, the empty bucket is the lowest.
+func bucketRank(bucket string) int {
+	for i, b := range buckets {
+		if b.name == bucket {
+			return i + 1
+		}
+	}
+	if bucket == "" {
+		return 0
+	}
+	return len(buckets) + 1
+}
@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+func TestOptionValidate(t *testing.T) {
+	for _, c := range []struct {
+		option Option
+		err    error
+	}{
+		{Option{}, nil},
+		{Option{Mode: ModeOff, Endpoint: "not a url"}, nil},
+		{Option{Mode: ModeOn, Endpoint: "https://telemetry.example.com/gocover"}, nil},
+		{Option{Mode: "yes"}, ErrInvalidMode},
+		{Option{Mode: ModeOn}, ErrNoEndpoint},
+		{Option{Mode: ModeOn, Endpoint: "ftp://telemetry.example.com"}, ErrNoEndpoint},
+	} {
+		if err := c.option.Validate(); !errors.Is(err, c.err) {
+			t.Errorf("validate %+v should return %v, but %v", c.option, c.err, err)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	for n, expect := range map[int]string{0: "0", 1: "1-9", 99: "10-99", 100: "100-999", 5000: "1k-10k", 99999: "10k-100k", 100000: "100k-1m", 2000000: "1m+"} {
+		if b := Bucket(n); b != expect {
+			t.Errorf("bucket of %d should be %s, but %s", n, expect, b)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("off", func(t *testing.T) {
+		r := Start(&Option{Mode: ModeOff, Endpoint: "http://127.0.0.1:1"}, "diff", "v1.0.0", nil, logger)
+		if r != nil {
+			t.Fatalf("recorder should be nil if telemetry is off")
+		}
+		// a nil recorder is no-op.
+		if err := r.GenerateReport(&report.Statistics{}); err != nil {
+			t.Error(err)
+		}
+		r.Finish(ErrorClassNone)
+	})
+
+	t.Run("on", func(t *testing.T) {
+		var events []map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+			}
+			var event map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+				t.Error(err)
+			}
+			events = append(events, event)
+		}))
+		defer server.Close()
+
+		r := Start(&Option{Mode: ModeOn, Endpoint: server.URL}, "baseline show", "v1.2.0", server.Client(), logger)
+		large := &report.Statistics{TotalEffectiveLines: 1500, CoverageProfile: make([]*report.CoverageProfile, 12)}
+		small := &report.Statistics{TotalEffectiveLines: 5, CoverageProfile: make([]*report.CoverageProfile, 1)}
+		for _, s := range []*report.Statistics{large, small} {
+			if err := r.GenerateReport(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.Finish(ErrorClassLowCoverage)
+
+		if len(events) != 1 {
+			t.Fatalf("one event should be sent, but %d", len(events))
+		}
+		event := events[0]
+		for key, expect := range map[string]interface{}{
+			"command": "baseline show", "version": "v1.2.0", "os": runtime.GOOS, "arch": runtime.GOARCH,
+			"files": "10-99", "lines": "1k-10k", "errorClass": ErrorClassLowCoverage,
+		} {
+			if event[key] != expect {
+				t.Errorf("%s should be %v, but %v", key, expect, event[key])
+			}
+		}
+		if _, ok := event["durationSeconds"].(float64); !ok || len(event) != 8 {
+			t.Errorf("event should only have the documented fields, but %v", event)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		// the failure is only logged.
+		Start(&Option{Mode: ModeOn, Endpoint: server.URL}, "full", "v1.2.0", server.Client(), logger).Finish(ErrorClassNone)
+	})
+}
@@ -0,0 +1,2 @@
+// Package testresult parses the event streams produced by `go test -json`.
+package testresult
@@ -0,0 +1,216 @@
+package testresult
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions of the events that start the packages and the tests.
+const (
+	StartAction = "start"
+	RunAction   = "run"
+)
+
+const (
+	// maxFailureOutput limits the output lines printed for each failure, the last lines are kept.
+	maxFailureOutput = 20
+	// clearLine moves the cursor to the start of the line and erases the line.
+	clearLine = "\r\033[K"
+)
+
+// Progress renders the live progress of the `go test -json` stream, the failed tests and the packages that failed to build
+// are printed as soon as their events arrive, with their output, so they can be fixed before the run finishes.
+// If it's interactive, such as the stdout is a terminal, a status line of the running packages and the test counts is kept
+// redrawn below the failures, otherwise a line is printed when each package finishes.
+type Progress struct {
+	w           io.Writer
+	interactive bool
+	now         func() time.Time
+	start       time.Time
+
+	buf []byte
+	err error
+	// running are the start time of the running packages.
+	running map[string]time.Time
+	// outputs are the output of the running tests and packages, keyed by the package and the test.
+	outputs map[string][]string
+	// failedTests are the number of the failed tests of the running packages.
+	failedTests map[string]int
+
+	packages, failedPackages int
+	passed, failed, skipped  int
+}
+
+// NewProgress creates a Progress writes to w, it's written the `go test -json` stream and closed after the run.
+func NewProgress(w io.Writer, interactive bool) *Progress {
+	return &Progress{
+		w:           w,
+		interactive: interactive,
+		now:         time.Now,
+		start:       time.Now(),
+		running:     make(map[string]time.Time),
+		outputs:     make(map[string][]string),
+		failedTests: make(map[string]int),
+	}
+}
+
+// Write handles the complete event lines of p, the incomplete line is kept until the next write or close.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for p.err == nil {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.handle(p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+	if p.err != nil {
+		return 0, p.err
+	}
+	return len(b), nil
+}
+
+// Close handles the remaining incomplete line and prints the summary of the run.
+func (p *Progress) Close() error {
+	if len(p.buf) != 0 && p.err == nil {
+		p.handle(p.buf)
+		p.buf = nil
+	}
+	if p.err != nil {
+		return p.err
+	}
+	p.clearStatus()
+	p.printf("%s, %s\n", p.counts(), p.now().Sub(p.start).Round(time.Millisecond))
+	return p.err
+}
+
+func (p *Progress) handle(line []byte) {
+	var e Event
+	if err := json.Unmarshal(bytes.TrimSpace(line), &e); err != nil || e.Package == "" {
+		return
+	}
+
+	key := e.Package + " " + e.Test
+	switch e.Action {
+	case StartAction, RunAction:
+		if _, ok := p.running[e.Package]; !ok {
+			p.running[e.Package] = e.Time
+		}
+	case OutputAction:
+		if _, ok := p.running[e.Package]; !ok {
+			p.running[e.Package] = e.Time
+		}
+		p.outputs[key] = append(p.outputs[key], e.Output)
+	case PassAction, SkipAction, FailAction:
+		if e.Test != "" {
+			p.finishTest(&e, key)
+		} else {
+			p.finishPackage(&e, key)
+		}
+	}
+	p.drawStatus()
+}
+
+func (p *Progress) finishTest(e *Event, key string) {
+	output := p.outputs[key]
+	delete(p.outputs, key)
+	switch e.Action {
+	case PassAction:
+		p.passed++
+	case SkipAction:
+		p.skipped++
+	case FailAction:
+		p.failed++
+		p.failedTests[e.Package]++
+		p.clearStatus()
+		p.printf("--- FAIL: %s %s (%.2fs)\n", e.Package, e.Test, e.Elapsed)
+		p.printOutput(output)
+	}
+}
+
+func (p *Progress) finishPackage(e *Event, key string) {
+	output := p.outputs[key]
+	delete(p.outputs, key)
+	delete(p.running, e.Package)
+	p.packages++
+
+	failedTests := p.failedTests[e.Package]
+	delete(p.failedTests, e.Package)
+	for k := range p.outputs {
+		if strings.HasPrefix(k, e.Package+" ") {
+			delete(p.outputs, k)
+		}
+	}
+	if e.Action == FailAction {
+		p.failedPackages++
+	}
+
+	p.clearStatus()
+	switch {
+	case e.Action == FailAction && failedTests == 0:
+		// the package fails without failed tests if it fails to build, or the test binary exits early, such as os.Exit in a test.
+		p.printf("FAIL %s\n", e.Package)
+		p.printOutput(output)
+	case !p.interactive:
+		status := "ok  "
+		if e.Action == FailAction {
+			status = "FAIL"
+		}
+		p.printf("%s %s (%.2fs)\n", status, e.Package, e.Elapsed)
+	}
+}
+
+func (p *Progress) printOutput(output []string) {
+	if len(output) > maxFailureOutput {
+		p.printf("    ... %d lines omitted\n", len(output)-maxFailureOutput)
+		output = output[len(output)-maxFailureOutput:]
+	}
+	for _, o := range output {
+		p.printf("    %s\n", strings.TrimRight(o, "\n"))
+	}
+}
+
+func (p *Progress) counts() string {
+	return fmt.Sprintf("%d packages (%d failed), %d passed, %d failed, %d skipped tests",
+		p.packages, p.failedPackages, p.passed, p.failed, p.skipped)
+}
+
+// drawStatus redraws the status line of the running packages if it's interactive.
+func (p *Progress) drawStatus() {
+	if !p.interactive {
+		return
+	}
+	running := make([]string, 0, len(p.running))
+	for name := range p.running {
+		running = append(running, name)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return p.running[running[i]].Before(p.running[running[j]])
+	})
+	status := p.counts()
+	if len(running) > 0 {
+		status = fmt.Sprintf("%s, running %s", status, running[0])
+		if len(running) > 1 {
+			status = fmt.Sprintf("%s and %d more", status, len(running)-1)
+		}
+	}
+	p.printf("%s%s", clearLine, status)
+}
+
+func (p *Progress) clearStatus() {
+	if p.interactive {
+		p.printf("%s", clearLine)
+	}
+}
+
+func (p *Progress) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
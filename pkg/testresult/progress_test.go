@@ -0,0 +1,103 @@
+package testresult
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const progressEvents = `{"Action":"start","Package":"github.com/Azure/gocover/pkg/foo"}
+{"Action":"run","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo"}
+{"Action":"output","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo","Output":"=== RUN   TestFoo\n"}
+{"Action":"output","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo","Output":"    foo_test.go:10: expect 1, but get 2\n"}
+{"Action":"output","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo","Output":"--- FAIL: TestFoo (0.01s)\n"}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo","Elapsed":0.01}
+{"Action":"run","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestSkip"}
+{"Action":"skip","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestSkip","Elapsed":0}
+{"Action":"output","Package":"github.com/Azure/gocover/pkg/foo","Output":"FAIL\n"}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/foo","Elapsed":0.02}
+{"Action":"start","Package":"github.com/Azure/gocover/pkg/broken"}
+{"Action":"output","Package":"github.com/Azure/gocover/pkg/broken","Output":"FAIL\tgithub.com/Azure/gocover/pkg/broken [build failed]\n"}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/broken","Elapsed":0}
+{"Action":"start","Package":"github.com/Azure/gocover/pkg/bar"}
+{"Action":"pass","Package":"github.com/Azure/gocover/pkg/bar","Test":"TestBar","Elapsed":0.01}
+{"Action":"pass","Package":"github.com/Azure/gocover/pkg/bar","Elapsed":0.01}`
+
+func newTestProgress(b *strings.Builder, interactive bool) *Progress {
+	p := NewProgress(b, interactive)
+	p.start = time.Unix(0, 0)
+	p.now = func() time.Time { return time.Unix(1, 0) }
+	return p
+}
+
+func TestProgress(t *testing.T) {
+	b := &strings.Builder{}
+	p := newTestProgress(b, false)
+	// the events are split at arbitrary positions as the pipe of the test process.
+	for _, chunk := range []string{progressEvents[:100], progressEvents[100:701], progressEvents[701:]} {
+		if _, err := p.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `--- FAIL: github.com/Azure/gocover/pkg/foo TestFoo (0.01s)
+    === RUN   TestFoo
+        foo_test.go:10: expect 1, but get 2
+    --- FAIL: TestFoo (0.01s)
+FAIL github.com/Azure/gocover/pkg/foo (0.02s)
+FAIL github.com/Azure/gocover/pkg/broken
+    FAIL	github.com/Azure/gocover/pkg/broken [build failed]
+ok   github.com/Azure/gocover/pkg/bar (0.01s)
+3 packages (2 failed), 1 passed, 1 failed, 1 skipped tests, 1s
+`
+	if b.String() != expect {
+		t.Errorf("expect:\n%s\nbut get:\n%s", expect, b.String())
+	}
+}
+
+func TestProgressInteractive(t *testing.T) {
+	b := &strings.Builder{}
+	p := newTestProgress(b, true)
+	if _, err := p.Write([]byte(progressEvents + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := b.String()
+	for _, want := range []string{
+		clearLine + "0 packages (0 failed), 0 passed, 0 failed, 0 skipped tests, running github.com/Azure/gocover/pkg/foo",
+		clearLine + "--- FAIL: github.com/Azure/gocover/pkg/foo TestFoo (0.01s)\n",
+		clearLine + "FAIL github.com/Azure/gocover/pkg/broken\n",
+		clearLine + "3 packages (2 failed), 1 passed, 1 failed, 1 skipped tests, 1s\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expect %q in the output:\n%q", want, output)
+		}
+	}
+	// the finished packages are shown in the status line rather than their own lines.
+	if strings.Contains(output, "ok   github.com/Azure/gocover/pkg/bar") {
+		t.Errorf("unexpected line of the passed package in the output:\n%q", output)
+	}
+}
+
+func TestProgressOmitsOutput(t *testing.T) {
+	b := &strings.Builder{}
+	p := newTestProgress(b, false)
+	var events strings.Builder
+	for i := 0; i < maxFailureOutput+5; i++ {
+		events.WriteString(`{"Action":"output","Package":"foo","Test":"TestFoo","Output":"line\n"}` + "\n")
+	}
+	events.WriteString(`{"Action":"fail","Package":"foo","Test":"TestFoo"}` + "\n")
+	if _, err := p.Write([]byte(events.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), "    ... 5 lines omitted\n") || strings.Count(b.String(), "    line\n") != maxFailureOutput {
+		t.Errorf("expect the last %d lines of the output, but get:\n%s", maxFailureOutput, b.String())
+	}
+}
@@ -0,0 +1,141 @@
+package testresult
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions of the test events, refer to `go doc test2json` for all the actions.
+const (
+	PassAction   = "pass"
+	FailAction   = "fail"
+	SkipAction   = "skip"
+	OutputAction = "output"
+)
+
+// maxEventSize limits the size of a single event line.
+const maxEventSize = 4 * 1024 * 1024
+
+// Event is a single event emitted by `go test -json`.
+type Event struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// Package represents the test result of a package.
+type Package struct {
+	// Name is the import path of the package.
+	Name string
+	// Status is the final action of the package, one of pass, fail and skip. It's fail if the package failed to build.
+	Status string
+	// Passed, Failed and Skipped are the number of tests in each state, subtests included.
+	Passed  int
+	Failed  int
+	Skipped int
+	// FailedTests are the names of the failed tests.
+	FailedTests []string
+}
+
+// IsFailed reports whether the package failed, either a test failed or the package failed to build.
+func (p *Package) IsFailed() bool {
+	return p.Status == FailAction || p.Failed > 0
+}
+
+// Parse parses the event stream, lines that are not json events are ignored,
+// as the build errors are printed among the events.
+func Parse(r io.Reader) ([]*Package, error) {
+	packages := make(map[string]*Package)
+	find := func(name string) *Package {
+		p, ok := packages[name]
+		if !ok {
+			p = &Package{Name: name}
+			packages[name] = p
+		}
+		return p
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil || e.Package == "" {
+			continue
+		}
+
+		p := find(e.Package)
+		switch {
+		case e.Test == "":
+			if e.Action == PassAction || e.Action == FailAction || e.Action == SkipAction {
+				p.Status = e.Action
+			}
+		case e.Action == PassAction:
+			p.Passed++
+		case e.Action == FailAction:
+			p.Failed++
+			p.FailedTests = append(p.FailedTests, e.Test)
+		case e.Action == SkipAction:
+			p.Skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read test events: %w", err)
+	}
+
+	return sortPackages(packages), nil
+}
+
+// ParseFiles parses the event streams of the files, results of the same package are merged.
+func ParseFiles(files []string) ([]*Package, error) {
+	packages := make(map[string]*Package)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("open test events: %w", err)
+		}
+		result, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, p := range result {
+			merged, ok := packages[p.Name]
+			if !ok {
+				packages[p.Name] = p
+				continue
+			}
+			if p.Status == FailAction || merged.Status == "" {
+				merged.Status = p.Status
+			}
+			merged.Passed += p.Passed
+			merged.Failed += p.Failed
+			merged.Skipped += p.Skipped
+			merged.FailedTests = append(merged.FailedTests, p.FailedTests...)
+		}
+	}
+	return sortPackages(packages), nil
+}
+
+func sortPackages(packages map[string]*Package) []*Package {
+	result := make([]*Package, 0, len(packages))
+	for _, p := range packages {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
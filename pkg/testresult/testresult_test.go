@@ -0,0 +1,97 @@
+package testresult
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const events = `{"Action":"run","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo"}
+{"Action":"output","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo","Output":"=== RUN   TestFoo\n"}
+{"Action":"pass","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo","Elapsed":0.01}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestFoo/sub","Elapsed":0.01}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestBar","Elapsed":0.01}
+{"Action":"skip","Package":"github.com/Azure/gocover/pkg/foo","Test":"TestSkip","Elapsed":0}
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/foo","Elapsed":0.02}
+# github.com/Azure/gocover/pkg/broken
+pkg/broken/broken.go:3:1: syntax error
+{"Action":"fail","Package":"github.com/Azure/gocover/pkg/broken","Elapsed":0}
+{"Action":"pass","Package":"github.com/Azure/gocover/pkg/bar","Test":"TestBar","Elapsed":0.01}
+{"Action":"pass","Package":"github.com/Azure/gocover/pkg/bar","Elapsed":0.01}
+`
+
+func TestParse(t *testing.T) {
+	packages, err := Parse(strings.NewReader(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 3 {
+		t.Fatalf("expect 3 packages, but get %d", len(packages))
+	}
+
+	bar, broken, foo := packages[0], packages[1], packages[2]
+	if bar.Name != "github.com/Azure/gocover/pkg/bar" || bar.IsFailed() || bar.Passed != 1 {
+		t.Errorf("unexpected package %+v", bar)
+	}
+	if broken.Status != FailAction || !broken.IsFailed() {
+		t.Errorf("build failed package should be failed, but get %+v", broken)
+	}
+	if foo.Passed != 1 || foo.Failed != 2 || foo.Skipped != 1 || strings.Join(foo.FailedTests, ",") != "TestFoo/sub,TestBar" {
+		t.Errorf("unexpected package %+v", foo)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	os.WriteFile(first, []byte(`{"Action":"pass","Package":"foo","Test":"TestA"}
+{"Action":"pass","Package":"foo"}
+`), 0644)
+	os.WriteFile(second, []byte(`{"Action":"fail","Package":"foo","Test":"TestB"}
+{"Action":"fail","Package":"foo"}
+`), 0644)
+
+	packages, err := ParseFiles([]string{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 || packages[0].Status != FailAction || packages[0].Passed != 1 || packages[0].Failed != 1 {
+		t.Errorf("unexpected merged packages %+v", packages[0])
+	}
+
+	if _, err := ParseFiles([]string{filepath.Join(dir, "not-exist.json")}); err == nil {
+		t.Error("should return error for not exist file")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var events, output strings.Builder
+	w := NewWriter(&events, &output)
+
+	stream := `{"Action":"run","Package":"foo","Test":"TestA"}
+{"Action":"output","Package":"foo","Test":"TestA","Output":"=== RUN   TestA\n"}
+# foo
+{"Action":"output","Package":"foo","Output":"ok  \tfoo\t0.01s\n"}`
+	// write in small chunks to split the lines.
+	for i := 0; i < len(stream); i += 7 {
+		end := i + 7
+		if end > len(stream) {
+			end = len(stream)
+		}
+		if _, err := w.Write([]byte(stream[i:end])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if events.String() != stream {
+		t.Errorf("events should be written as is, but get %q", events.String())
+	}
+	if output.String() != "=== RUN   TestA\n# foo\nok  \tfoo\t0.01s\n" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
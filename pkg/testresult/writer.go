@@ -0,0 +1,70 @@
+package testresult
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Writer splits the `go test -json` stream, the events are written to the events writer as is,
+// and the output of the events is written to the output writer, so the test output is still readable.
+// Lines that are not json events are written to both writers.
+type Writer struct {
+	events io.Writer
+	output io.Writer
+	buf    []byte
+	err    error
+}
+
+// NewWriter creates a Writer, nil writers discard the data.
+func NewWriter(events, output io.Writer) *Writer {
+	if events == nil {
+		events = io.Discard
+	}
+	if output == nil {
+		output = io.Discard
+	}
+	return &Writer{events: events, output: output}
+}
+
+// Write writes the complete lines of p, the incomplete line is kept until the next write or flush.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for w.err == nil {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// Flush writes the remaining incomplete line.
+func (w *Writer) Flush() error {
+	if len(w.buf) != 0 && w.err == nil {
+		w.writeLine(w.buf)
+		w.buf = nil
+	}
+	return w.err
+}
+
+func (w *Writer) writeLine(line []byte) {
+	if _, err := w.events.Write(line); err != nil {
+		w.err = err
+		return
+	}
+
+	var e Event
+	if err := json.Unmarshal(line, &e); err != nil {
+		_, w.err = w.output.Write(line)
+		return
+	}
+	if e.Action == OutputAction {
+		_, w.err = io.WriteString(w.output, e.Output)
+	}
+}
@@ -0,0 +1,420 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+var ErrNotTerminal = errors.New("not a terminal")
+
+// defaultHeight is the number of the rows of the screen if the size of the terminal is unknown.
+const defaultHeight = 24
+
+// The escape sequences of ANSI terminals.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+	bold        = "\x1b[1m"
+	red         = "\x1b[31m"
+	green       = "\x1b[32m"
+	resetStyle  = "\x1b[0m"
+)
+
+// Option contains the input of the coverage browser.
+type Option struct {
+	// ModulePath is the path of the go module, the file names of the statistics start with it, and the tree starts from it.
+	ModulePath string
+	// ModuleDir is the directory of the module that the source is read from, if the report doesn't keep the contents
+	// of the violation sections.
+	ModuleDir string
+	// Style is the code style of the highlighted source.
+	Style string
+	// Color enables the colors and the highlighted source.
+	Color bool
+	// Height is the number of the rows of the screen, the size of the terminal is used if it's zero.
+	Height int
+}
+
+// Browser browses the coverage tree of the statistics, and the violation sections of a file.
+type Browser struct {
+	option     *Option
+	statistics *report.Statistics
+	// path are the opened directories from the root, the last one is shown.
+	path []*report.TreeNode
+	// cursors are the selected entries of the opened directories.
+	cursors []int
+	// file is the opened file, the directory is shown if it's nil.
+	file      *fileView
+	highlight func(code string) []string
+	quit      bool
+}
+
+// fileView is the violation sections of an opened file.
+type fileView struct {
+	profile *report.CoverageProfile
+	lines   []string
+	// sections are the indexes of the header lines of the violation sections.
+	sections []int
+	offset   int
+}
+
+// NewBrowser creates a browser of the statistics, the tree is built from the coverage profiles.
+func NewBrowser(statistics *report.Statistics, o *Option) *Browser {
+	tree := report.NewCoverageTree(o.ModulePath)
+	for _, p := range statistics.CoverageProfile {
+		node := tree.FindOrCreate(p.FileName)
+		node.CoverageProfile = p
+		node.TotalLines = int64(p.TotalLines)
+		node.TotalEffectiveLines = int64(p.TotalEffectiveLines)
+		node.TotalIgnoredLines = int64(p.TotalIgnoredLines)
+		node.TotalCoveredLines = int64(p.CoveredLines)
+		node.TotalCoveredButIgnoreLines = int64(p.CoveredButIgnoredLines)
+		node.TotalViolationLines = int64(len(p.TotalViolationLines))
+	}
+	tree.CollectCoverageData()
+
+	b := &Browser{option: o, statistics: statistics, highlight: plainLines}
+	if o.Color {
+		b.highlight = newHighlighter(o.Style)
+	}
+	b.path = []*report.TreeNode{tree.Find(o.ModulePath)}
+	b.cursors = []int{0}
+	return b
+}
+
+// RunTerminal runs the browser in the terminal of the file until it quits, the terminal is restored afterwards.
+func (b *Browser) RunTerminal(tty *os.File, out io.Writer) error {
+	fd := int(tty.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotTerminal, err)
+	}
+	defer restore()
+	if b.option.Height <= 0 {
+		if height, err := terminalHeight(fd); err == nil && height > 0 {
+			b.option.Height = height
+		}
+	}
+	return b.Run(tty, out)
+}
+
+// Run renders the screen to the output after each key press read from the input, until it quits or the input ends.
+func (b *Browser) Run(in io.Reader, out io.Writer) error {
+	if _, err := io.WriteString(out, enterScreen); err != nil {
+		return err
+	}
+	defer io.WriteString(out, leaveScreen)
+
+	reader := bufio.NewReader(in)
+	for !b.quit {
+		if _, err := io.WriteString(out, clearScreen+b.Render()); err != nil {
+			return err
+		}
+		k, err := readKey(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b.handle(k)
+	}
+	return nil
+}
+
+// handle moves the cursor, opens and closes the entries by the key.
+func (b *Browser) handle(k key) {
+	switch k {
+	case "q", keyQuit:
+		b.quit = true
+		return
+	}
+	if b.file != nil {
+		b.handleFile(k)
+		return
+	}
+
+	entries := b.entries()
+	cursor := &b.cursors[len(b.cursors)-1]
+	switch k {
+	case keyUp, "k":
+		if *cursor > 0 {
+			*cursor--
+		}
+	case keyDown, "j":
+		if *cursor < len(entries)-1 {
+			*cursor++
+		}
+	case keyEnter, keyRight, "l":
+		if len(entries) == 0 {
+			return
+		}
+		if entry := entries[*cursor]; entry.CoverageProfile != nil {
+			b.file = b.newFileView(entry.CoverageProfile)
+		} else {
+			b.path, b.cursors = append(b.path, entry), append(b.cursors, 0)
+		}
+	case keyLeft, keyBack, keyEsc, "h":
+		if len(b.path) > 1 {
+			b.path, b.cursors = b.path[:len(b.path)-1], b.cursors[:len(b.cursors)-1]
+		}
+	}
+}
+
+// handleFile scrolls the opened file by the key, n and p jump to the next and the previous violation section.
+func (b *Browser) handleFile(k key) {
+	f := b.file
+	last := len(f.lines) - b.bodyHeight()
+	switch k {
+	case keyUp, "k":
+		f.offset--
+	case keyDown, "j":
+		f.offset++
+	case "n":
+		for _, s := range f.sections {
+			if s > f.offset {
+				f.offset = s
+				break
+			}
+		}
+	case "p":
+		for i := len(f.sections) - 1; i >= 0; i-- {
+			if f.sections[i] < f.offset {
+				f.offset = f.sections[i]
+				break
+			}
+		}
+	case keyLeft, keyBack, keyEsc, "h":
+		b.file = nil
+		return
+	}
+	if f.offset > last {
+		f.offset = last
+	}
+	if f.offset < 0 {
+		f.offset = 0
+	}
+}
+
+// entries returns the entries of the shown directory, the directories come before the files, and both are sorted by the name.
+func (b *Browser) entries() []*report.TreeNode {
+	node := b.path[len(b.path)-1]
+	entries := make([]*report.TreeNode, 0, len(node.Nodes))
+	for _, n := range node.Nodes {
+		entries = append(entries, n)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if isDir, other := entries[i].CoverageProfile == nil, entries[j].CoverageProfile == nil; isDir != other {
+			return isDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// bodyHeight returns the number of the rows between the header and the footer.
+func (b *Browser) bodyHeight() int {
+	height := b.option.Height
+	if height <= 0 {
+		height = defaultHeight
+	}
+	if height -= 5; height < 1 {
+		return 1
+	}
+	return height
+}
+
+// Render returns the screen, the header of the totals, the shown directory or file, and the footer of the keys.
+func (b *Browser) Render() string {
+	var s strings.Builder
+	title := "Diff coverage"
+	if b.statistics.StatisticsType == report.FullStatisticsType {
+		title = "Full coverage"
+	}
+	fmt.Fprintf(&s, "%s%s %.2f%%%s of %d lines, %d not covered\n", b.style(bold), title, b.statistics.TotalCoveragePercent,
+		b.style(resetStyle), b.statistics.TotalEffectiveLines, b.statistics.TotalViolationLines)
+
+	if b.file != nil {
+		b.renderFile(&s)
+		return s.String()
+	}
+
+	names := make([]string, 0, len(b.path))
+	for _, n := range b.path {
+		names = append(names, n.Name)
+	}
+	fmt.Fprintf(&s, "%s\n\n", strings.TrimLeft(strings.Join(names, "/"), "/"))
+
+	entries := b.entries()
+	cursor := b.cursors[len(b.cursors)-1]
+	height := b.bodyHeight()
+	start := 0
+	if cursor >= height {
+		start = cursor - height + 1
+	}
+	for i := start; i < len(entries) && i < start+height; i++ {
+		e := entries[i]
+		name := e.Name
+		if name == "" {
+			// the files in the root of the module are in a directory without a name.
+			name = "."
+		}
+		if e.CoverageProfile == nil {
+			name += "/"
+		}
+		covered := e.TotalCoveredLines - e.TotalCoveredButIgnoreLines
+		percent := 100.0
+		if e.TotalEffectiveLines != 0 {
+			percent = float64(covered) / float64(e.TotalEffectiveLines) * 100
+		}
+		color := green
+		if e.TotalViolationLines != 0 {
+			color = red
+		}
+		line := fmt.Sprintf("%-40s %s%7.2f%%%s %6d/%-6d %d not covered", name, b.style(color), percent, b.style(resetStyle),
+			covered, e.TotalEffectiveLines, e.TotalViolationLines)
+		if i == cursor {
+			fmt.Fprintf(&s, "%s> %s%s\n", b.style(reverse), line, b.style(resetStyle))
+		} else {
+			fmt.Fprintf(&s, "  %s\n", line)
+		}
+	}
+	if len(entries) == 0 {
+		s.WriteString("  no files\n")
+	}
+	s.WriteString("\n↑/↓ move  enter open  ← back  q quit\n")
+	return s.String()
+}
+
+// renderFile writes the shown lines of the violation sections of the opened file.
+func (b *Browser) renderFile(s *strings.Builder) {
+	f := b.file
+	p := f.profile
+	fmt.Fprintf(s, "%s  %.2f%%  lines not covered: %s\n\n", p.FileName,
+		percent(p), report.LineRanges(p.TotalViolationLines))
+	height := b.bodyHeight()
+	for i := f.offset; i < len(f.lines) && i < f.offset+height; i++ {
+		s.WriteString(f.lines[i])
+		s.WriteString("\n")
+	}
+	if len(f.lines) == 0 {
+		s.WriteString("  all lines are covered\n")
+	}
+	s.WriteString("\n↑/↓ scroll  n/p next/previous section  ← back  q quit\n")
+}
+
+// newFileView renders the violation sections of the profile, the source of a section is read from the module directory
+// if the report doesn't keep its contents.
+func (b *Browser) newFileView(p *report.CoverageProfile) *fileView {
+	f := &fileView{profile: p}
+	violations := make(map[int]bool, len(p.TotalViolationLines))
+	for _, l := range p.TotalViolationLines {
+		violations[l] = true
+	}
+	var source []string
+	for _, section := range p.ViolationSections {
+		contents := section.Contents
+		if len(contents) == 0 {
+			if source == nil {
+				source = b.readSource(p.FileName)
+			}
+			if section.StartLine >= 1 && section.EndLine <= len(source) && section.StartLine <= section.EndLine {
+				contents = source[section.StartLine-1 : section.EndLine]
+			}
+		}
+
+		f.sections = append(f.sections, len(f.lines))
+		f.lines = append(f.lines, fmt.Sprintf("%s── lines %d-%d ──%s", b.style(bold), section.StartLine, section.EndLine, b.style(resetStyle)))
+		if len(contents) == 0 {
+			f.lines = append(f.lines, "  source not found, lines not covered: "+report.LineRanges(section.ViolationLines))
+			continue
+		}
+		for i, code := range b.highlight(strings.Join(contents, "\n")) {
+			number := section.StartLine + i
+			marker := "  "
+			if violations[number] {
+				marker = b.style(red) + "✗ " + b.style(resetStyle)
+			}
+			f.lines = append(f.lines, fmt.Sprintf("%s%5d  %s%s", marker, number, code, b.style(resetStyle)))
+		}
+	}
+	return f
+}
+
+// readSource returns the lines of the source file in the module directory, it's empty if the file is not found.
+func (b *Browser) readSource(fileName string) []string {
+	relative := strings.TrimPrefix(strings.TrimPrefix(fileName, b.option.ModulePath), "/")
+	data, err := os.ReadFile(filepath.Join(b.option.ModuleDir, filepath.FromSlash(relative)))
+	if err != nil {
+		return []string{}
+	}
+	return strings.Split(string(data), "\n")
+}
+
+// style returns the escape sequence if the colors are enabled.
+func (b *Browser) style(sequence string) string {
+	if !b.option.Color {
+		return ""
+	}
+	return sequence
+}
+
+// percent returns the coverage of the profile, the covered but ignored lines are not covered.
+func percent(p *report.CoverageProfile) float64 {
+	if p.TotalEffectiveLines == 0 {
+		return 100
+	}
+	return float64(p.CoveredLines-p.CoveredButIgnoredLines) / float64(p.TotalEffectiveLines) * 100
+}
+
+// plainLines splits the code into lines without highlight.
+func plainLines(code string) []string {
+	return strings.Split(code, "\n")
+}
+
+// newHighlighter returns the function that highlights the go code with the style for 256 color terminals,
+// the code is split into lines without highlight if it fails.
+func newHighlighter(style string) func(string) []string {
+	lexer := lexers.Get(report.CodeLanguage)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+	s := styles.Get(style)
+	if s == nil {
+		s = styles.Fallback
+	}
+	formatter := formatters.TTY256
+
+	return func(code string) []string {
+		plain := plainLines(code)
+		iterator, err := lexer.Tokenise(nil, code)
+		if err != nil {
+			return plain
+		}
+		var buf bytes.Buffer
+		if err := formatter.Format(&buf, s, iterator); err != nil {
+			return plain
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != len(plain) {
+			return plain
+		}
+		return lines
+	}
+}
@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+func browserStatistics() *report.Statistics {
+	return &report.Statistics{
+		StatisticsType:       report.DiffStatisticsType,
+		TotalEffectiveLines:  14,
+		TotalCoveredLines:    11,
+		TotalViolationLines:  3,
+		TotalCoveragePercent: 78.57,
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName:            "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalEffectiveLines: 6,
+				CoveredLines:        4,
+				TotalViolationLines: []int{4, 5},
+				ViolationSections: []*report.ViolationSection{
+					{StartLine: 3, EndLine: 6, ViolationLines: []int{4, 5}, Contents: []string{
+						"func foo(a int) int {",
+						"\tif a > 0 {",
+						"\t\treturn a",
+						"\t}",
+					}},
+				},
+			},
+			{
+				FileName:            "github.com/Azure/gocover/pkg/foo/bar/bar.go",
+				TotalEffectiveLines: 4,
+				CoveredLines:        4,
+			},
+			{
+				FileName:            "github.com/Azure/gocover/main.go",
+				TotalEffectiveLines: 4,
+				CoveredLines:        3,
+				TotalViolationLines: []int{2},
+				ViolationSections: []*report.ViolationSection{
+					{StartLine: 1, EndLine: 2, ViolationLines: []int{2}},
+				},
+			},
+		},
+	}
+}
+
+func runKeys(t *testing.T, b *Browser, keys ...key) {
+	t.Helper()
+	for _, k := range keys {
+		b.handle(k)
+	}
+}
+
+func TestBrowserTree(t *testing.T) {
+	b := NewBrowser(browserStatistics(), &Option{ModulePath: "github.com/Azure/gocover", Height: 20})
+
+	screen := b.Render()
+	for _, want := range []string{"Diff coverage 78.57% of 14 lines, 3 not covered", "> .", "  pkg/", "github.com/Azure/gocover\n"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("root screen should contain %q:\n%s", want, screen)
+		}
+	}
+
+	runKeys(t, b, keyDown, keyEnter)
+	screen = b.Render()
+	if !strings.Contains(screen, "github.com/Azure/gocover/pkg\n") || !strings.Contains(screen, "> foo/") {
+		t.Errorf("pkg should be opened:\n%s", screen)
+	}
+
+	runKeys(t, b, "l")
+	screen = b.Render()
+	lines := strings.Split(screen, "\n")
+	var bar, foo int
+	for i, l := range lines {
+		if strings.Contains(l, "bar/") {
+			bar = i
+		}
+		if strings.Contains(l, "foo.go") {
+			foo = i
+			if !strings.Contains(l, "66.67%") || !strings.Contains(l, "4/6") || !strings.Contains(l, "2 not covered") {
+				t.Errorf("foo.go row should show the coverage: %q", l)
+			}
+		}
+	}
+	if bar == 0 || foo == 0 || bar > foo {
+		t.Errorf("directories should come before files:\n%s", screen)
+	}
+	if !strings.Contains(lines[bar], "> bar/") {
+		t.Errorf("first entry should be selected:\n%s", screen)
+	}
+
+	runKeys(t, b, keyUp, keyDown, keyDown, keyDown)
+	if !strings.Contains(b.Render(), "> foo.go") {
+		t.Errorf("cursor should stop at the last entry:\n%s", b.Render())
+	}
+
+	runKeys(t, b, keyLeft, keyBack, keyEsc)
+	if !strings.Contains(b.Render(), "> pkg/") {
+		t.Errorf("should be back to the root with its cursor:\n%s", b.Render())
+	}
+}
+
+func TestBrowserFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBrowser(browserStatistics(), &Option{ModulePath: "github.com/Azure/gocover", ModuleDir: dir, Height: 20})
+
+	runKeys(t, b, keyDown, keyEnter, keyEnter, keyDown, keyEnter)
+	screen := b.Render()
+	for _, want := range []string{
+		"github.com/Azure/gocover/pkg/foo/foo.go  66.67%  lines not covered: 4-5",
+		"── lines 3-6 ──",
+		"      3  func foo(a int) int {",
+		"✗     4  \tif a > 0 {",
+		"✗     5  \t\treturn a",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("file screen should contain %q:\n%s", want, screen)
+		}
+	}
+
+	runKeys(t, b, keyLeft, keyLeft, keyLeft, keyUp, keyEnter, keyEnter)
+	screen = b.Render()
+	if !strings.Contains(screen, "✗     2  func main() {}") {
+		t.Errorf("source should be read from the module directory:\n%s", screen)
+	}
+
+	runKeys(t, b, "q")
+	if !b.quit {
+		t.Errorf("q should quit")
+	}
+}
+
+func TestBrowserFileSections(t *testing.T) {
+	statistics := browserStatistics()
+	p := statistics.CoverageProfile[0]
+	p.ViolationSections = append(p.ViolationSections,
+		&report.ViolationSection{StartLine: 10, EndLine: 11, ViolationLines: []int{10}, Contents: []string{"a", "b"}},
+		&report.ViolationSection{StartLine: 20, EndLine: 20, ViolationLines: []int{20}, Contents: []string{"c"}},
+	)
+	b := NewBrowser(statistics, &Option{ModulePath: "github.com/Azure/gocover", Height: 8})
+	runKeys(t, b, keyDown, keyEnter, keyEnter, keyDown, keyEnter)
+
+	if b.file == nil {
+		t.Fatalf("file should be opened")
+	}
+	runKeys(t, b, "n")
+	if b.file.offset != 5 {
+		t.Errorf("n should jump to the second section, but %d", b.file.offset)
+	}
+	runKeys(t, b, "n", "n")
+	if last := len(b.file.lines) - b.bodyHeight(); b.file.offset != last {
+		t.Errorf("offset should stop at %d, but %d", last, b.file.offset)
+	}
+	runKeys(t, b, "p", "p")
+	if b.file.offset != 0 {
+		t.Errorf("p should jump to the first section, but %d", b.file.offset)
+	}
+}
+
+func TestBrowserRun(t *testing.T) {
+	b := NewBrowser(browserStatistics(), &Option{ModulePath: "github.com/Azure/gocover", Height: 20})
+	var out strings.Builder
+	if err := b.Run(strings.NewReader("j\r"), &out); err != nil {
+		t.Fatal(err)
+	}
+	screen := out.String()
+	if !strings.HasPrefix(screen, enterScreen) || !strings.HasSuffix(screen, leaveScreen) {
+		t.Errorf("screen should be entered and left")
+	}
+	if !strings.Contains(screen, "> foo/") {
+		t.Errorf("pkg should be opened:\n%s", screen)
+	}
+
+	b = NewBrowser(browserStatistics(), &Option{ModulePath: "github.com/Azure/gocover", Color: true, Style: "monokai"})
+	out.Reset()
+	if err := b.Run(strings.NewReader("q"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), reverse) {
+		t.Errorf("selected entry should be highlighted")
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("\x1b[A\x1b[B\x1b[C\x1b[Dx\r\n\x7f\x03"))
+	want := []key{keyUp, keyDown, keyRight, keyLeft, "x", keyEnter, keyEnter, keyBack, keyQuit}
+	for _, w := range want {
+		k, err := readKey(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k != w {
+			t.Errorf("key should be %q, but %q", w, k)
+		}
+	}
+	if _, err := readKey(r); err == nil {
+		t.Errorf("should fail at the end of the input")
+	}
+
+	k, _ := readKey(bufio.NewReader(strings.NewReader("\x1b")))
+	if k != keyEsc {
+		t.Errorf("escape alone should be esc, but %q", k)
+	}
+}
+
+func TestHighlighter(t *testing.T) {
+	lines := newHighlighter("not-a-style")("package main\n\nfunc main() {}")
+	if len(lines) != 3 {
+		t.Fatalf("should keep the lines, but %d", len(lines))
+	}
+	if !strings.Contains(lines[0], "\x1b[") || !strings.Contains(lines[0], "package") {
+		t.Errorf("line should be highlighted: %q", lines[0])
+	}
+}
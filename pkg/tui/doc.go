@@ -0,0 +1,4 @@
+// Package tui browses the coverage of a report in the terminal, the tree of the packages and the files,
+// and the violation sections of each file with the highlighted source, so the diff coverage is explored
+// locally without opening a browser. It only depends on the terminal control of the standard library.
+package tui
@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"bufio"
+)
+
+// key is a key press, the printable keys are their characters.
+type key string
+
+// The special keys of the browser.
+const (
+	keyUp    key = "up"
+	keyDown  key = "down"
+	keyLeft  key = "left"
+	keyRight key = "right"
+	keyEnter key = "enter"
+	keyBack  key = "backspace"
+	keyEsc   key = "esc"
+	keyQuit  key = "ctrl-c"
+)
+
+// readKey reads a key press from the terminal in the raw mode, the arrow keys are the escape sequences of ANSI terminals.
+func readKey(r *bufio.Reader) (key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 0x7f, 0x08:
+		return keyBack, nil
+	case 0x03:
+		return keyQuit, nil
+	case 0x1b:
+		// an escape key alone isn't followed by the rest of a sequence in the same read.
+		if r.Buffered() < 2 {
+			return keyEsc, nil
+		}
+		if next, _ := r.Peek(1); next[0] != '[' && next[0] != 'O' {
+			return keyEsc, nil
+		}
+		sequence := make([]byte, 2)
+		if _, err := r.Read(sequence); err != nil {
+			return "", err
+		}
+		switch sequence[1] {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		}
+		return keyEsc, nil
+	}
+	return key(string(c)), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package tui
+
+// makeRaw returns ErrNotTerminal, the raw mode of the terminal is not supported on the platform.
+func makeRaw(fd int) (func() error, error) {
+	return nil, ErrNotTerminal
+}
+
+// terminalHeight returns ErrNotTerminal, the size of the terminal is not supported on the platform.
+func terminalHeight(fd int) (int, error) {
+	return 0, ErrNotTerminal
+}
@@ -0,0 +1,121 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+const (
+	// DefaultCodecovURL is the url of Codecov, use the url of the self-hosted Codecov instead.
+	DefaultCodecovURL = "https://codecov.io"
+
+	// codecovReportPath is the name of the lcov tracefile in the uploaded report.
+	codecovReportPath = "gocover.info"
+)
+
+// NewCodecovUploader creates the uploader of Codecov, which uploads the coverage as a lcov tracefile by the upload api v4.
+// The token is the upload token of the repository, it's optional for the public repositories of some CI services.
+func NewCodecovUploader(baseURL string, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) Uploader {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &codecovUploader{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		token:    token,
+		client:   httpClient,
+		recorder: recorder,
+	}
+}
+
+type codecovUploader struct {
+	baseURL  string
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ Uploader = (*codecovUploader)(nil)
+
+// Upload requests the storage url of the report with the metadata, then puts the report into the storage.
+func (u *codecovUploader) Upload(ctx context.Context, coverage *Coverage, metadata *Metadata) (string, error) {
+	reportURL, err := u.upload(ctx, coverage, metadata)
+
+	action := audit.NewAction(audit.UploadAction, "codecov:"+metadata.Commit, reportURL, err)
+	action.Details = map[string]string{"branch": metadata.Branch, "files": strconv.Itoa(len(coverage.Statistics.CoverageProfile))}
+	u.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("upload to codecov: %w", err)
+	}
+	return reportURL, nil
+}
+
+func (u *codecovUploader) upload(ctx context.Context, coverage *Coverage, metadata *Metadata) (string, error) {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "# path=%s\n", codecovReportPath)
+	if err := report.WriteLCOV(&body, coverage.Statistics, coverage.Path); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&body, "<<<<<< EOF\n")
+
+	query := url.Values{}
+	query.Set("package", "gocover-"+report.ToolVersion)
+	for key, value := range map[string]string{
+		"commit":    metadata.Commit,
+		"branch":    metadata.Branch,
+		"pr":        metadata.PullRequest,
+		"slug":      metadata.Slug,
+		"service":   metadata.Service,
+		"build":     metadata.Build,
+		"build_url": metadata.BuildURL,
+		"job":       metadata.Job,
+		"flags":     metadata.Flag,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.baseURL+"/upload/v4?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/plain")
+	if u.token != nil {
+		token, err := u.token.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "token "+token)
+	}
+	response, err := send(u.client, req)
+	if err != nil {
+		return "", err
+	}
+
+	// the response has the url of the report, and the url of the storage that the report is put into.
+	reportURL, storageURL, _ := strings.Cut(strings.TrimSpace(string(response)), "\n")
+	storageURL = strings.TrimSpace(storageURL)
+	if storageURL == "" {
+		return "", fmt.Errorf("%w: no storage url of the report", ErrUnexpectedResponse)
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, storageURL, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if _, err := send(u.client, req); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reportURL), nil
+}
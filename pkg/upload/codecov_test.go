@@ -0,0 +1,71 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func TestCodecovUpload(t *testing.T) {
+	var uploaded string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/v4":
+			if r.Header.Get("Authorization") != "token codecovtokenvalue" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("Could not find a repository associated with upload token"))
+				return
+			}
+			query := r.URL.Query()
+			if query.Get("commit") != "abc" || query.Get("branch") != "main" || query.Get("flags") != "unit" || query.Get("pr") != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("https://codecov.io/github/Azure/gocover/commit/abc\n" + server.URL + "/storage/abc.txt?sig=signature\n"))
+		case r.Method == http.MethodPut && r.URL.Path == "/storage/abc.txt":
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_CODECOV_TOKEN", "codecovtokenvalue")
+	token, err := credential.NewProvider("env:GOCOVER_TEST_CODECOV_TOKEN", nil)
+	if err != nil {
+		t.Fatalf("new provider: %s", err)
+	}
+	recorder := audit.NewRecorder("")
+	uploader := NewCodecovUploader(server.URL+"/", token, server.Client(), recorder)
+
+	reportURL, err := uploader.Upload(context.Background(), testCoverage(t), &Metadata{Commit: "abc", Branch: "main", Flag: "unit"})
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if reportURL != "https://codecov.io/github/Azure/gocover/commit/abc" {
+		t.Errorf("unexpected report url %s", reportURL)
+	}
+	expected := "# path=gocover.info\nTN:\nSF:services/foo/foo.go\nDA:3,2\nDA:5,0\nLF:2\nLH:1\nend_of_record\n<<<<<< EOF\n"
+	if uploaded != expected {
+		t.Errorf("expect report %q, but get %q", expected, uploaded)
+	}
+	actions := recorder.Actions()
+	if len(actions) != 1 || actions[0].Type != audit.UploadAction || actions[0].Target != "codecov:abc" || actions[0].ResponseID != reportURL {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	uploader = NewCodecovUploader(server.URL, nil, server.Client(), nil)
+	_, err = uploader.Upload(context.Background(), testCoverage(t), &Metadata{Commit: "abc", Branch: "main"})
+	if !errors.Is(err, ErrUnexpectedResponse) || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
@@ -0,0 +1,199 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// DefaultCoverallsURL is the url of Coveralls, use the url of Coveralls Enterprise instead.
+const DefaultCoverallsURL = "https://coveralls.io"
+
+// coverallsServices are the service names of Coveralls that differ from the services of the metadata.
+var coverallsServices = map[string]string{
+	GitHubActionsService:  "github",
+	AzurePipelinesService: "azure-pipelines",
+	GitLabService:         "gitlab-ci",
+}
+
+// NewCoverallsUploader creates the uploader of Coveralls, which uploads the coverage of each line of the source files as a job.
+// The token is the repo token of the repository, or the GITHUB_TOKEN of GitHub Actions. The jobs of a parallel build
+// are not shown until the build is closed by the parallel webhook of Coveralls.
+func NewCoverallsUploader(baseURL string, parallel bool, token credential.Provider, httpClient *http.Client, recorder audit.Recorder) Uploader {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+	return &coverallsUploader{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		parallel: parallel,
+		token:    token,
+		client:   httpClient,
+		recorder: recorder,
+	}
+}
+
+type coverallsUploader struct {
+	baseURL  string
+	parallel bool
+	token    credential.Provider
+	client   *http.Client
+	recorder audit.Recorder
+}
+
+var _ Uploader = (*coverallsUploader)(nil)
+
+type coverallsGit struct {
+	Head struct {
+		ID string `json:"id"`
+	} `json:"head"`
+	Branch string `json:"branch,omitempty"`
+}
+
+type coverallsSourceFile struct {
+	Name         string `json:"name"`
+	SourceDigest string `json:"source_digest"`
+	// Coverage is the hits of each line of the file, the lines out of statements are null.
+	Coverage []*int `json:"coverage"`
+}
+
+type coverallsJob struct {
+	RepoToken          string                 `json:"repo_token,omitempty"`
+	ServiceName        string                 `json:"service_name,omitempty"`
+	ServiceNumber      string                 `json:"service_number,omitempty"`
+	ServiceJobID       string                 `json:"service_job_id,omitempty"`
+	ServiceBuildURL    string                 `json:"service_build_url,omitempty"`
+	ServicePullRequest string                 `json:"service_pull_request,omitempty"`
+	FlagName           string                 `json:"flag_name,omitempty"`
+	Parallel           bool                   `json:"parallel,omitempty"`
+	RunAt              string                 `json:"run_at"`
+	Git                *coverallsGit          `json:"git"`
+	SourceFiles        []*coverallsSourceFile `json:"source_files"`
+}
+
+// Upload creates a job of the commit with the source files of the coverage.
+func (u *coverallsUploader) Upload(ctx context.Context, coverage *Coverage, metadata *Metadata) (string, error) {
+	jobURL, files, err := u.upload(ctx, coverage, metadata)
+
+	action := audit.NewAction(audit.UploadAction, "coveralls:"+metadata.Commit, jobURL, err)
+	action.Details = map[string]string{"branch": metadata.Branch, "files": strconv.Itoa(files)}
+	u.recorder.Record(action)
+	if err != nil {
+		return "", fmt.Errorf("upload to coveralls: %w", err)
+	}
+	return jobURL, nil
+}
+
+func (u *coverallsUploader) upload(ctx context.Context, coverage *Coverage, metadata *Metadata) (string, int, error) {
+	files, err := coverallsSourceFiles(coverage)
+	if err != nil {
+		return "", 0, err
+	}
+	service := metadata.Service
+	if name, ok := coverallsServices[service]; ok {
+		service = name
+	}
+	job := &coverallsJob{
+		ServiceName:        service,
+		ServiceNumber:      metadata.Build,
+		ServiceJobID:       metadata.Job,
+		ServiceBuildURL:    metadata.BuildURL,
+		ServicePullRequest: metadata.PullRequest,
+		FlagName:           metadata.Flag,
+		Parallel:           u.parallel,
+		RunAt:              time.Now().UTC().Format(time.RFC3339),
+		Git:                &coverallsGit{Branch: metadata.Branch},
+		SourceFiles:        files,
+	}
+	job.Git.Head.ID = metadata.Commit
+	if u.token != nil {
+		token, err := u.token.Token(ctx)
+		if err != nil {
+			return "", 0, fmt.Errorf("get token: %w", err)
+		}
+		job.RepoToken = token
+	}
+
+	// the job is posted as the json_file of a multipart form.
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("json_file", "coveralls.json")
+	if err != nil {
+		return "", 0, err
+	}
+	if err := json.NewEncoder(part).Encode(job); err != nil {
+		return "", 0, err
+	}
+	if err := form.Close(); err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.baseURL+"/api/v1/jobs", &body)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	response, err := send(u.client, req)
+	if err != nil {
+		return "", len(files), err
+	}
+
+	result := &struct {
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	}{}
+	if err := json.Unmarshal(response, result); err != nil {
+		return "", len(files), fmt.Errorf("%w: %s", ErrUnexpectedResponse, err)
+	}
+	return result.URL, len(files), nil
+}
+
+// coverallsSourceFiles returns the source files of the coverage, the files are read from the repository for the digests
+// and the numbers of the lines. The lines of the files that are not counted, such as the unchanged lines of a diff run, are null.
+func coverallsSourceFiles(coverage *Coverage) ([]*coverallsSourceFile, error) {
+	var files []*coverallsSourceFile
+	for _, profile := range coverage.Statistics.CoverageProfile {
+		hits := report.LineHits(profile)
+		if len(hits) == 0 {
+			continue
+		}
+
+		name := coverage.Path(profile.FileName)
+		source, err := os.ReadFile(filepath.Join(coverage.RepositoryPath, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("read source file: %w", err)
+		}
+		digest := md5.Sum(source)
+		lines := bytes.Count(source, []byte("\n"))
+		if len(source) > 0 && source[len(source)-1] != '\n' {
+			lines++
+		}
+		if last := hits[len(hits)-1].Number; last > lines {
+			lines = last
+		}
+
+		file := &coverallsSourceFile{Name: name, SourceDigest: hex.EncodeToString(digest[:]), Coverage: make([]*int, lines)}
+		for _, hit := range hits {
+			h := hit.Hits
+			file.Coverage[hit.Number-1] = &h
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
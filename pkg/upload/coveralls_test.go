@@ -0,0 +1,73 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/credential"
+)
+
+func TestCoverallsUpload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/jobs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f, _, err := r.FormFile("json_file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		job := &coverallsJob{}
+		json.NewDecoder(f).Decode(job)
+		if job.RepoToken != "coverallstokenvalue" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Couldn't find a repository matching this job.", "error": true}`))
+			return
+		}
+		if job.ServiceName != "github" || !job.Parallel || job.Git.Head.ID != "abc" || job.Git.Branch != "main" || len(job.SourceFiles) != 1 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		file := job.SourceFiles[0]
+		// the source has 6 lines, the line 3 is hit twice and the line 5 is missed.
+		if file.Name != "services/foo/foo.go" || len(file.SourceDigest) != 32 || len(file.Coverage) != 6 ||
+			file.Coverage[0] != nil || *file.Coverage[2] != 2 || *file.Coverage[4] != 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte(`{"message": "Job ##1.1", "url": "https://coveralls.io/jobs/1"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("GOCOVER_TEST_COVERALLS_TOKEN", "coverallstokenvalue")
+	token, err := credential.NewProvider("env:GOCOVER_TEST_COVERALLS_TOKEN", nil)
+	if err != nil {
+		t.Fatalf("new provider: %s", err)
+	}
+	recorder := audit.NewRecorder("")
+	uploader := NewCoverallsUploader(server.URL, true, token, server.Client(), recorder)
+
+	metadata := &Metadata{Commit: "abc", Branch: "main", Service: GitHubActionsService}
+	jobURL, err := uploader.Upload(context.Background(), testCoverage(t), metadata)
+	if err != nil {
+		t.Fatalf("should not error, but get %s", err)
+	}
+	if jobURL != "https://coveralls.io/jobs/1" {
+		t.Errorf("unexpected job url %s", jobURL)
+	}
+	actions := recorder.Actions()
+	if len(actions) != 1 || actions[0].Target != "coveralls:abc" || actions[0].Details["files"] != "1" {
+		t.Errorf("unexpected audit actions %+v", actions)
+	}
+
+	uploader = NewCoverallsUploader(server.URL, true, nil, server.Client(), nil)
+	if _, err := uploader.Upload(context.Background(), testCoverage(t), metadata); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("expect %s, but get %v", ErrUnexpectedResponse, err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package upload uploads the coverage of a run to the coverage services, Codecov and Coveralls, with the commit
+// and the branch of the build, so the pipelines don't need a separate uploader step after gocover.
+package upload
@@ -0,0 +1,94 @@
+package upload
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The CI services of the metadata, they are the service names of Codecov.
+const (
+	GitHubActionsService  = "github-actions"
+	AzurePipelinesService = "azure_pipelines"
+	GitLabService         = "gitlab"
+	JenkinsService        = "jenkins"
+)
+
+// DetectMetadata returns the metadata of the build from the predefined variables of the CI service,
+// it's empty if the service is unknown. The getenv is os.Getenv out of the tests.
+func DetectMetadata(getenv func(string) string) Metadata {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		m := Metadata{
+			Service: GitHubActionsService,
+			Commit:  getenv("GITHUB_SHA"),
+			Branch:  getenv("GITHUB_HEAD_REF"),
+			Slug:    getenv("GITHUB_REPOSITORY"),
+			Build:   getenv("GITHUB_RUN_ID"),
+			Job:     getenv("GITHUB_JOB"),
+		}
+		if m.Branch == "" {
+			m.Branch = getenv("GITHUB_REF_NAME")
+		}
+		// the ref of a pull request is refs/pull/{number}/merge.
+		if number, ok := strings.CutPrefix(getenv("GITHUB_REF"), "refs/pull/"); ok {
+			m.PullRequest, _, _ = strings.Cut(number, "/")
+		}
+		if server := getenv("GITHUB_SERVER_URL"); server != "" && m.Slug != "" && m.Build != "" {
+			m.BuildURL = fmt.Sprintf("%s/%s/actions/runs/%s", server, m.Slug, m.Build)
+		}
+		return m
+	case getenv("TF_BUILD") == "True":
+		m := Metadata{
+			Service:     AzurePipelinesService,
+			Commit:      getenv("BUILD_SOURCEVERSION"),
+			Branch:      strings.TrimPrefix(getenv("BUILD_SOURCEBRANCH"), "refs/heads/"),
+			PullRequest: getenv("SYSTEM_PULLREQUEST_PULLREQUESTNUMBER"),
+			Slug:        getenv("BUILD_REPOSITORY_NAME"),
+			Build:       getenv("BUILD_BUILDID"),
+			Job:         getenv("SYSTEM_JOBID"),
+		}
+		if m.PullRequest == "" {
+			m.PullRequest = getenv("SYSTEM_PULLREQUEST_PULLREQUESTID")
+		}
+		// the source commit of a pull request build is the merge commit, the pull request has the head.
+		if commit := getenv("SYSTEM_PULLREQUEST_SOURCECOMMITID"); commit != "" {
+			m.Commit = commit
+		}
+		if branch := getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH"); branch != "" {
+			m.Branch = strings.TrimPrefix(branch, "refs/heads/")
+		}
+		if collection, project := getenv("SYSTEM_COLLECTIONURI"), getenv("SYSTEM_TEAMPROJECT"); collection != "" && project != "" && m.Build != "" {
+			m.BuildURL = fmt.Sprintf("%s/%s/_build/results?buildId=%s", strings.TrimSuffix(collection, "/"), project, m.Build)
+		}
+		return m
+	case getenv("GITLAB_CI") == "true":
+		m := Metadata{
+			Service:     GitLabService,
+			Commit:      getenv("CI_COMMIT_SHA"),
+			Branch:      getenv("CI_COMMIT_REF_NAME"),
+			PullRequest: getenv("CI_MERGE_REQUEST_IID"),
+			Slug:        getenv("CI_PROJECT_PATH"),
+			Build:       getenv("CI_PIPELINE_ID"),
+			BuildURL:    getenv("CI_PIPELINE_URL"),
+			Job:         getenv("CI_JOB_ID"),
+		}
+		if branch := getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"); branch != "" {
+			m.Branch = branch
+		}
+		return m
+	case getenv("JENKINS_URL") != "":
+		m := Metadata{
+			Service:     JenkinsService,
+			Commit:      getenv("GIT_COMMIT"),
+			Branch:      strings.TrimPrefix(getenv("GIT_BRANCH"), "origin/"),
+			PullRequest: getenv("CHANGE_ID"),
+			Build:       getenv("BUILD_NUMBER"),
+			BuildURL:    getenv("BUILD_URL"),
+		}
+		if branch := getenv("CHANGE_BRANCH"); branch != "" {
+			m.Branch = branch
+		}
+		return m
+	}
+	return Metadata{}
+}
@@ -0,0 +1,70 @@
+package upload
+
+import "testing"
+
+func TestDetectMetadata(t *testing.T) {
+	testSuites := []struct {
+		name     string
+		env      map[string]string
+		expected Metadata
+	}{
+		{
+			name: "github actions pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS": "true", "GITHUB_SHA": "abc", "GITHUB_HEAD_REF": "feature", "GITHUB_REF_NAME": "12/merge",
+				"GITHUB_REF": "refs/pull/12/merge", "GITHUB_REPOSITORY": "Azure/gocover", "GITHUB_RUN_ID": "100", "GITHUB_JOB": "test",
+				"GITHUB_SERVER_URL": "https://github.com",
+			},
+			expected: Metadata{
+				Service: GitHubActionsService, Commit: "abc", Branch: "feature", PullRequest: "12", Slug: "Azure/gocover",
+				Build: "100", Job: "test", BuildURL: "https://github.com/Azure/gocover/actions/runs/100",
+			},
+		},
+		{
+			name: "github actions push",
+			env:  map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_SHA": "abc", "GITHUB_REF_NAME": "main", "GITHUB_REF": "refs/heads/main"},
+			expected: Metadata{
+				Service: GitHubActionsService, Commit: "abc", Branch: "main",
+			},
+		},
+		{
+			name: "azure pipelines pull request",
+			env: map[string]string{
+				"TF_BUILD": "True", "BUILD_SOURCEVERSION": "merge", "BUILD_SOURCEBRANCH": "refs/pull/7/merge", "BUILD_BUILDID": "42",
+				"SYSTEM_PULLREQUEST_PULLREQUESTID": "7", "SYSTEM_PULLREQUEST_SOURCECOMMITID": "abc", "SYSTEM_PULLREQUEST_SOURCEBRANCH": "refs/heads/feature",
+				"SYSTEM_COLLECTIONURI": "https://dev.azure.com/org/", "SYSTEM_TEAMPROJECT": "project",
+			},
+			expected: Metadata{
+				Service: AzurePipelinesService, Commit: "abc", Branch: "feature", PullRequest: "7", Build: "42",
+				BuildURL: "https://dev.azure.com/org/project/_build/results?buildId=42",
+			},
+		},
+		{
+			name: "gitlab merge request",
+			env: map[string]string{
+				"GITLAB_CI": "true", "CI_COMMIT_SHA": "abc", "CI_COMMIT_REF_NAME": "feature", "CI_MERGE_REQUEST_IID": "3",
+				"CI_PROJECT_PATH": "group/project", "CI_PIPELINE_ID": "9", "CI_JOB_ID": "10",
+			},
+			expected: Metadata{
+				Service: GitLabService, Commit: "abc", Branch: "feature", PullRequest: "3", Slug: "group/project", Build: "9", Job: "10",
+			},
+		},
+		{
+			name: "jenkins",
+			env: map[string]string{
+				"JENKINS_URL": "https://jenkins.example.com/", "GIT_COMMIT": "abc", "GIT_BRANCH": "origin/main", "BUILD_NUMBER": "5",
+				"BUILD_URL": "https://jenkins.example.com/job/gocover/5/",
+			},
+			expected: Metadata{
+				Service: JenkinsService, Commit: "abc", Branch: "main", Build: "5", BuildURL: "https://jenkins.example.com/job/gocover/5/",
+			},
+		},
+		{name: "unknown"},
+	}
+	for _, testSuite := range testSuites {
+		metadata := DetectMetadata(func(key string) string { return testSuite.env[key] })
+		if metadata != testSuite.expected {
+			t.Errorf("%s: expect %+v, but get %+v", testSuite.name, testSuite.expected, metadata)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gittool"
+	"github.com/Azure/gocover/pkg/redact"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// uploadTimeout bounds the time of uploading the coverage.
+const uploadTimeout = 2 * time.Minute
+
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+// Metadata describes the commit and the build of the uploaded coverage, the empty fields are not sent.
+type Metadata struct {
+	// Commit is the commit of the coverage, HEAD of the repository is resolved if it's empty.
+	Commit string
+	// Branch is the branch of the commit, the branch checked out in the repository is used if it's empty.
+	Branch string
+	// PullRequest is the number of the pull request that is built.
+	PullRequest string
+	// Slug is the repository in the format of owner/name.
+	Slug string
+	// Service is the CI service of the build, one of the services of DetectMetadata.
+	Service string
+	// Build is the number or the id of the build.
+	Build string
+	// BuildURL is the url of the build.
+	BuildURL string
+	// Job is the id of the job of the build.
+	Job string
+	// Flag groups the uploads of the same commit, such as unit and integration, it's optional.
+	Flag string
+}
+
+// Coverage is the coverage of a run to upload.
+type Coverage struct {
+	// Statistics is the coverage of the run, a diff run only uploads the changed lines.
+	Statistics *report.Statistics
+	// RepositoryPath is the root directory of the git repository, the source files are read from it.
+	RepositoryPath string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root.
+	ModuleDir string
+}
+
+// Path returns the path of the file of the statistics relative to the repository root,
+// which is how the coverage services match the files of the commit.
+func (c *Coverage) Path(fileName string) string {
+	relative := strings.TrimPrefix(strings.TrimPrefix(fileName, c.ModulePath), "/")
+	return path.Join(filepath.ToSlash(c.ModuleDir), relative)
+}
+
+// Uploader uploads the coverage to a coverage service.
+type Uploader interface {
+	// Upload uploads the coverage with the metadata, it returns the url of the uploaded report.
+	Upload(ctx context.Context, coverage *Coverage, metadata *Metadata) (string, error)
+}
+
+// Option contains the input for the upload report generator.
+type Option struct {
+	// RepositoryPath is the root directory of the git repository.
+	RepositoryPath string
+	// ModulePath is the path of the go module, the file names of the statistics start with it.
+	ModulePath string
+	// ModuleDir is the directory of the module relative to the repository root.
+	ModuleDir string
+	// Metadata is the commit and the build of the coverage.
+	Metadata Metadata
+}
+
+// NewReportGenerator creates a report generator that uploads the statistics to the coverage service,
+// the failed upload is logged rather than failing the command, as the other reports are written.
+func NewReportGenerator(uploader Uploader, o *Option, logger logrus.FieldLogger) report.ReportGenerator {
+	return &reportGenerator{uploader: uploader, option: o, logger: logger.WithField("source", "Upload")}
+}
+
+type reportGenerator struct {
+	uploader Uploader
+	option   *Option
+	logger   logrus.FieldLogger
+}
+
+var _ report.ReportGenerator = (*reportGenerator)(nil)
+
+// GenerateReport uploads the coverage of the statistics, the commit and the branch are resolved from the repository if they're not set.
+func (g *reportGenerator) GenerateReport(statistics *report.Statistics) error {
+	if len(statistics.CoverageProfile) == 0 {
+		g.logger.Info("no coverage to upload")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	metadata := g.option.Metadata
+	if metadata.Commit == "" {
+		commit, err := gittool.ResolveCommit(ctx, g.option.RepositoryPath, "HEAD")
+		if err != nil {
+			g.logger.WithError(err).Error("resolve the commit of the upload")
+			return nil
+		}
+		metadata.Commit = commit
+	}
+	if metadata.Branch == "" {
+		branch, err := gittool.CurrentBranch(ctx, g.option.RepositoryPath)
+		if err != nil {
+			g.logger.WithError(err).Warn("resolve the branch of the upload")
+		}
+		metadata.Branch = branch
+	}
+
+	coverage := &Coverage{
+		Statistics:     statistics,
+		RepositoryPath: g.option.RepositoryPath,
+		ModulePath:     g.option.ModulePath,
+		ModuleDir:      g.option.ModuleDir,
+	}
+	url, err := g.uploader.Upload(ctx, coverage, &metadata)
+	if err != nil {
+		g.logger.WithError(err).Errorf("upload coverage of %s", metadata.Commit)
+		return nil
+	}
+	g.logger.Infof("upload coverage of %s: %s", metadata.Commit, url)
+	return nil
+}
+
+// send sends the request and returns the body of the response, the error of a response out of 2xx has the body.
+func send(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: %s %s returns %d: %s", ErrUnexpectedResponse, req.Method, req.URL.Path, resp.StatusCode, redact.String(strings.TrimSpace(string(body))))
+	}
+	return body, nil
+}
@@ -0,0 +1,85 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/sirupsen/logrus"
+)
+
+// testCoverage returns the coverage of foo/foo.go in the module of the services directory, whose lines 3 and 5 are counted.
+func testCoverage(t *testing.T) *Coverage {
+	repository := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repository, "services", "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	source := "package foo\n\nfunc Foo() {\n\tbar()\n\tbaz()\n}\n"
+	if err := os.WriteFile(filepath.Join(repository, "services", "foo", "foo.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &Coverage{
+		Statistics: &report.Statistics{
+			CoverageProfile: []*report.CoverageProfile{{
+				FileName: "github.com/Azure/services/foo/foo.go",
+				CountedLines: []*report.CountedLine{
+					{Line: 3, Covered: true, Block: &report.Block{Count: 2}},
+					{Line: 5},
+				},
+			}},
+		},
+		RepositoryPath: repository,
+		ModulePath:     "github.com/Azure/services",
+		ModuleDir:      "services",
+	}
+}
+
+func TestCoveragePath(t *testing.T) {
+	coverage := &Coverage{ModulePath: "github.com/Azure/services", ModuleDir: "./"}
+	if p := coverage.Path("github.com/Azure/services/foo/foo.go"); p != "foo/foo.go" {
+		t.Errorf("expect foo/foo.go, but get %s", p)
+	}
+	coverage.ModuleDir = "services/"
+	if p := coverage.Path("github.com/Azure/services/foo/foo.go"); p != "services/foo/foo.go" {
+		t.Errorf("expect services/foo/foo.go, but get %s", p)
+	}
+}
+
+type fakeUploader struct {
+	metadata *Metadata
+	err      error
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, coverage *Coverage, metadata *Metadata) (string, error) {
+	u.metadata = metadata
+	return "https://coverage.example.com/report", u.err
+}
+
+func TestReportGenerator(t *testing.T) {
+	coverage := testCoverage(t)
+	uploader := &fakeUploader{}
+	g := NewReportGenerator(uploader, &Option{Metadata: Metadata{Commit: "abc", Branch: "main"}}, logrus.New())
+	if err := g.GenerateReport(coverage.Statistics); err != nil {
+		t.Fatal(err)
+	}
+	if uploader.metadata == nil || uploader.metadata.Commit != "abc" || uploader.metadata.Branch != "main" {
+		t.Errorf("unexpected metadata %+v", uploader.metadata)
+	}
+
+	// the failed upload doesn't fail the command.
+	uploader = &fakeUploader{err: errors.New("boom")}
+	g = NewReportGenerator(uploader, &Option{Metadata: Metadata{Commit: "abc", Branch: "main"}}, logrus.New())
+	if err := g.GenerateReport(coverage.Statistics); err != nil {
+		t.Errorf("should not error, but get %s", err)
+	}
+
+	// nothing is uploaded without coverage.
+	uploader = &fakeUploader{}
+	g = NewReportGenerator(uploader, &Option{}, logrus.New())
+	if err := g.GenerateReport(&report.Statistics{}); err != nil || uploader.metadata != nil {
+		t.Errorf("expect no upload, but get %+v, %v", uploader.metadata, err)
+	}
+}
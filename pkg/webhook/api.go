@@ -0,0 +1,357 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/graphql"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// GraphQLPath is the path that serves the GraphQL API over the stored runs.
+const GraphQLPath = "/graphql"
+
+// Schema is the GraphQL schema served on GraphQLPath, the lists are sorted by the latest run first, except trends.
+const Schema = `type Query {
+  runs(owner: String, repository: String, number: Int, headSHA: String, type: String,
+       since: String, until: String, minCoverage: Float, maxCoverage: Float, limit: Int): [Run!]!
+  run(id: String!): Run
+  trends(owner: String, repository: String, number: Int, type: String,
+         since: String, until: String, limit: Int): [TrendPoint!]!
+}
+
+type Run {
+  id: String!
+  owner: String!
+  repository: String!
+  number: Int!
+  headSHA: String!
+  createdAt: String!
+  type: String!
+  comparedBranch: String!
+  coveragePercent: Float!
+  coverageWithoutIgnore: Float!
+  totalLines: Int!
+  effectiveLines: Int!
+  coveredLines: Int!
+  ignoredLines: Int!
+  violationLines: Int!
+  bypassed: Boolean!
+  files(path: String, minCoverage: Float, maxCoverage: Float, hasViolations: Boolean, limit: Int): [File!]!
+  violations(path: String, limit: Int): [Violation!]!
+}
+
+type File {
+  name: String!
+  totalLines: Int!
+  effectiveLines: Int!
+  coveredLines: Int!
+  ignoredLines: Int!
+  coveragePercent: Float!
+  violationLines: [Int!]!
+  unreliable: Boolean!
+  violations: [Violation!]!
+}
+
+type Violation {
+  file: String!
+  startLine: Int!
+  endLine: Int!
+  lines: [Int!]!
+  contents: [String!]!
+}
+
+type TrendPoint {
+  runId: String!
+  headSHA: String!
+  number: Int!
+  createdAt: String!
+  coveragePercent: Float!
+  violationLines: Int!
+  delta: Float!
+}`
+
+// handleGraphQL serves the GraphQL API over the runs of the tenant that the bearer token belongs to.
+// A single tenant without the API token serves the requests without authorization.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	tenant, err := s.authorize(r)
+	if err != nil {
+		s.logger.WithError(err).Error("authorize GraphQL request")
+		http.Error(w, "authorize request", http.StatusInternalServerError)
+		return
+	}
+	if tenant == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if tenant.Store == nil {
+		http.Error(w, fmt.Sprintf("tenant %s doesn't store runs", tenant.Name), http.StatusNotFound)
+		return
+	}
+	graphql.Handle(w, r, queryObject(tenant.Store))
+}
+
+// authorize returns the tenant of the bearer token, it's nil if no tenant matches.
+func (s *Server) authorize(r *http.Request) (*Tenant, error) {
+	tenants := s.currentTenants()
+	if len(tenants) == 1 && tenants[0].APIToken == nil {
+		return tenants[0], nil
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return nil, nil
+	}
+	for _, t := range tenants {
+		if t.APIToken == nil {
+			continue
+		}
+		expected, err := t.APIToken.Token(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("get api token of tenant %s: %w", t.Name, err)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// saveRun stores the statistics of a finished run and returns the id of the stored run,
+// the failure is logged rather than failing the command.
+func (s *Server) saveRun(tenant *Tenant, owner, repository string, number int, headSHA string, statistics *report.Statistics) string {
+	if tenant.Store == nil {
+		return ""
+	}
+	run := &history.Run{Owner: owner, Repository: repository, Number: number, HeadSHA: headSHA, Statistics: statistics}
+	if err := tenant.Store.Save(run); err != nil {
+		s.logger.WithError(err).Errorf("save run of %s/%s#%d", owner, repository, number)
+		return ""
+	}
+	return run.ID
+}
+
+func queryObject(store history.Store) graphql.Object {
+	return graphql.Object{
+		"runs": func(args graphql.Args) (interface{}, error) {
+			filter, err := runFilter(args)
+			if err != nil {
+				return nil, err
+			}
+			// the coverage range is applied after the store query, so the limit is applied afterwards too.
+			limit := filter.Limit
+			filter.Limit = 0
+			runs, err := store.List(filter)
+			if err != nil {
+				return nil, err
+			}
+
+			minCoverage, hasMin := args.Float("minCoverage")
+			maxCoverage, hasMax := args.Float("maxCoverage")
+			result := []graphql.Object{}
+			for _, run := range runs {
+				coverage := run.Statistics.TotalCoveragePercent
+				if (hasMin && coverage < minCoverage) || (hasMax && coverage > maxCoverage) {
+					continue
+				}
+				result = append(result, runObject(run))
+				if limit > 0 && len(result) >= limit {
+					break
+				}
+			}
+			return result, nil
+		},
+		"run": func(args graphql.Args) (interface{}, error) {
+			run, err := store.Get(args.String("id"))
+			if err != nil {
+				return nil, err
+			}
+			return runObject(run), nil
+		},
+		"trends": func(args graphql.Args) (interface{}, error) {
+			filter, err := runFilter(args)
+			if err != nil {
+				return nil, err
+			}
+			points, err := history.Trend(store, filter)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]graphql.Object, 0, len(points))
+			for _, p := range points {
+				result = append(result, trendObject(p))
+			}
+			return result, nil
+		},
+	}
+}
+
+// runFilter builds the store filter from the arguments.
+func runFilter(args graphql.Args) (*history.Filter, error) {
+	filter := &history.Filter{
+		Owner:      args.String("owner"),
+		Repository: args.String("repository"),
+		Number:     args.Int("number"),
+		HeadSHA:    args.String("headSHA"),
+		Type:       report.StatisticsType(strings.ToLower(args.String("type"))),
+		Limit:      args.Int("limit"),
+	}
+	var err error
+	if filter.Since, err = parseTime(args.String("since")); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseTime(args.String("until")); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseTime parses the time in RFC3339 or a date, such as 2022-10-01.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("wrong time '%s', use RFC3339 or yyyy-mm-dd", s)
+	}
+	return t, nil
+}
+
+func runObject(run *history.Run) graphql.Object {
+	s := run.Statistics
+	return graphql.Object{
+		"id":                    constant(run.ID),
+		"owner":                 constant(run.Owner),
+		"repository":            constant(run.Repository),
+		"number":                constant(run.Number),
+		"headSHA":               constant(run.HeadSHA),
+		"createdAt":             constant(run.CreatedAt.UTC().Format(time.RFC3339)),
+		"type":                  constant(string(s.StatisticsType)),
+		"comparedBranch":        constant(s.ComparedBranch),
+		"coveragePercent":       constant(s.TotalCoveragePercent),
+		"coverageWithoutIgnore": constant(s.TotalCoverageWithoutIgnore),
+		"totalLines":            constant(s.TotalLines),
+		"effectiveLines":        constant(s.TotalEffectiveLines),
+		"coveredLines":          constant(s.TotalCoveredLines),
+		"ignoredLines":          constant(s.TotalIgnoredLines),
+		"violationLines":        constant(s.TotalViolationLines),
+		"bypassed":              constant(s.Bypass != nil),
+		"files": func(args graphql.Args) (interface{}, error) {
+			path := args.String("path")
+			minCoverage, hasMin := args.Float("minCoverage")
+			maxCoverage, hasMax := args.Float("maxCoverage")
+			limit := args.Int("limit")
+
+			result := []graphql.Object{}
+			for _, p := range s.CoverageProfile {
+				coverage := fileCoverage(p)
+				switch {
+				case path != "" && !strings.HasPrefix(p.FileName, path):
+					continue
+				case hasMin && coverage < minCoverage, hasMax && coverage > maxCoverage:
+					continue
+				case args.Bool("hasViolations") && len(p.TotalViolationLines) == 0 && len(p.ViolationSections) == 0:
+					continue
+				}
+				result = append(result, fileObject(p))
+				if limit > 0 && len(result) >= limit {
+					break
+				}
+			}
+			return result, nil
+		},
+		"violations": func(args graphql.Args) (interface{}, error) {
+			path := args.String("path")
+			limit := args.Int("limit")
+
+			result := []graphql.Object{}
+			for _, p := range s.CoverageProfile {
+				if path != "" && !strings.HasPrefix(p.FileName, path) {
+					continue
+				}
+				for _, section := range p.ViolationSections {
+					if limit > 0 && len(result) >= limit {
+						return result, nil
+					}
+					result = append(result, violationObject(p.FileName, section))
+				}
+			}
+			return result, nil
+		},
+	}
+}
+
+func fileObject(p *report.CoverageProfile) graphql.Object {
+	return graphql.Object{
+		"name":            constant(p.FileName),
+		"totalLines":      constant(p.TotalLines),
+		"effectiveLines":  constant(p.TotalEffectiveLines),
+		"coveredLines":    constant(p.CoveredLines),
+		"ignoredLines":    constant(p.TotalIgnoredLines),
+		"coveragePercent": constant(fileCoverage(p)),
+		"violationLines":  constant(nonNilInts(p.TotalViolationLines)),
+		"unreliable":      constant(p.Unreliable),
+		"violations": func(args graphql.Args) (interface{}, error) {
+			result := make([]graphql.Object, 0, len(p.ViolationSections))
+			for _, section := range p.ViolationSections {
+				result = append(result, violationObject(p.FileName, section))
+			}
+			return result, nil
+		},
+	}
+}
+
+func violationObject(fileName string, section *report.ViolationSection) graphql.Object {
+	contents := section.Contents
+	if contents == nil {
+		contents = []string{}
+	}
+	return graphql.Object{
+		"file":      constant(fileName),
+		"startLine": constant(section.StartLine),
+		"endLine":   constant(section.EndLine),
+		"lines":     constant(nonNilInts(section.ViolationLines)),
+		"contents":  constant(contents),
+	}
+}
+
+func trendObject(p *history.Point) graphql.Object {
+	return graphql.Object{
+		"runId":           constant(p.RunID),
+		"headSHA":         constant(p.HeadSHA),
+		"number":          constant(p.Number),
+		"createdAt":       constant(p.CreatedAt.UTC().Format(time.RFC3339)),
+		"coveragePercent": constant(p.CoveragePercent),
+		"violationLines":  constant(p.ViolationLines),
+		"delta":           constant(p.Delta),
+	}
+}
+
+// fileCoverage returns the coverage percent of the file with ignorance, it's 100 if there is no effective line.
+func fileCoverage(p *report.CoverageProfile) float64 {
+	if p.TotalEffectiveLines == 0 {
+		return 100
+	}
+	return float64(p.CoveredLines-p.CoveredButIgnoredLines) / float64(p.TotalEffectiveLines) * 100
+}
+
+func nonNilInts(lines []int) []int {
+	if lines == nil {
+		return []int{}
+	}
+	return lines
+}
+
+func constant(v interface{}) graphql.Resolver {
+	return func(graphql.Args) (interface{}, error) {
+		return v, nil
+	}
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/notify"
+	"github.com/sirupsen/logrus"
+)
+
+type staticToken string
+
+func (s staticToken) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+var _ credential.Provider = staticToken("")
+
+func queryGraphQL(server *Server, query string, token string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(string(body)))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestGraphQL(t *testing.T) {
+	store := history.NewMemoryStore()
+	server := NewServer(&ServerOption{
+		Secret:   staticToken(testWebhookSecret),
+		Client:   &mockClient{},
+		Runner:   &mockRunner{},
+		Store:    store,
+		APIToken: staticToken("secret"),
+		Logger:   logrus.New(),
+	})
+	sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun\\n/gocover report full"), "")
+
+	t.Run("unauthorized", func(t *testing.T) {
+		if w := queryGraphQL(server, `{ runs { id } }`, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("expect unauthorized, but get %d", w.Code)
+		}
+	})
+
+	t.Run("runs with filters", func(t *testing.T) {
+		w := queryGraphQL(server, `{
+			runs(repository: "gocover", type: "diff") {
+				number headSHA type coveragePercent
+				files(hasViolations: true) { name coveragePercent violationLines }
+				violations(path: "github.com/Azure/gocover/pkg/foo") { file startLine endLine lines }
+			}
+			full: runs(type: "FULL") { type }
+			none: runs(minCoverage: 80) { id }
+		}`, "secret")
+		expect := `{"data":{"runs":[{"number":12,"headSHA":"abcdef123456","type":"diff","coveragePercent":50,` +
+			`"files":[{"name":"github.com/Azure/gocover/pkg/foo/foo.go","coveragePercent":50,"violationLines":[]}],` +
+			`"violations":[{"file":"github.com/Azure/gocover/pkg/foo/foo.go","startLine":10,"endLine":20,"lines":[12,13]}]}],` +
+			`"full":[{"type":"full"}],"none":[]}}`
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != expect {
+			t.Errorf("expect\n%s\nbut get %d\n%s", expect, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("run and trends", func(t *testing.T) {
+		runs, err := store.List(&history.Filter{Limit: 1})
+		if err != nil || len(runs) != 1 {
+			t.Fatalf("expect stored runs, but get %v %v", runs, err)
+		}
+
+		w := queryGraphQL(server, `{ run(id: "`+runs[0].ID+`") { id } missing: run(id: "x") { id } trends(number: 12) { coveragePercent delta } }`, "secret")
+		body := w.Body.String()
+		if !strings.Contains(body, `"run":{"id":"`+runs[0].ID+`"}`) || !strings.Contains(body, `"missing":null`) ||
+			!strings.Contains(body, `"trends":[{"coveragePercent":50,"delta":0},{"coveragePercent":50,"delta":0}]`) ||
+			!strings.Contains(body, "run not found") {
+			t.Errorf("unexpected response %s", body)
+		}
+	})
+
+	t.Run("bad time filter", func(t *testing.T) {
+		w := queryGraphQL(server, `{ runs(since: "yesterday") { id } }`, "secret")
+		if !strings.Contains(w.Body.String(), "wrong time 'yesterday'") {
+			t.Errorf("unexpected response %s", w.Body.String())
+		}
+	})
+}
+
+func TestGraphQLWithoutStore(t *testing.T) {
+	server, _ := newTestServer(&mockRunner{}, nil)
+	if w := queryGraphQL(server, `{ runs { id } }`, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expect not found without store, but get %d", w.Code)
+	}
+}
+
+func TestResultWebhook(t *testing.T) {
+	var payloads []*notify.Payload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := &notify.Payload{}
+		json.NewDecoder(r.Body).Decode(payload)
+		payloads = append(payloads, payload)
+	}))
+	defer receiver.Close()
+	notifier, err := notify.NewNotifier([]*notify.Endpoint{{URL: receiver.URL}}, nil, nil, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := history.NewMemoryStore()
+	tenant := &Tenant{Name: "azure", Secret: staticToken(testWebhookSecret), Client: &mockClient{}, Runner: &mockRunner{}, Store: store, Notifier: notifier, CoverageBaseline: 80}
+	server := NewServer(&ServerOption{Tenants: []*Tenant{tenant}, Logger: logrus.New()})
+	sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+
+	runs, err := store.List(&history.Filter{})
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expect a stored run, but get %v %v", runs, err)
+	}
+	if len(payloads) != 1 {
+		t.Fatalf("expect a delivery, but get %d", len(payloads))
+	}
+	got := payloads[0]
+	if got.RunID != runs[0].ID || got.Owner != "Azure" || got.Number != 12 || got.HeadSHA != "abcdef123456" || got.Passed || got.CoverageBaseline != 80 {
+		t.Errorf("unexpected payload %+v", got)
+	}
+}
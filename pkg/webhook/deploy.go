@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gate"
+)
+
+// DeployPath is the path of the deploy gate, which answers whether a commit is allowed to deploy.
+const DeployPath = gate.Path
+
+// handleDeploy evaluates the deploy policy over the runs of the tenant that the bearer token belongs to.
+// An allowed commit is replied with 200, and a denied one with gate.DeniedStatusCode, both with the decision.
+func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	tenant, err := s.authorize(r)
+	if err != nil {
+		s.logger.WithError(err).Error("authorize deploy request")
+		http.Error(w, "authorize request", http.StatusInternalServerError)
+		return
+	}
+	if tenant == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	owner, repository, commit := query.Get("owner"), query.Get("repository"), query.Get("commit")
+	if owner == "" || repository == "" {
+		http.Error(w, "owner and repository are required", http.StatusBadRequest)
+		return
+	}
+	if !tenant.Serves(owner, repository) {
+		http.Error(w, "repository is not served by the tenant", http.StatusNotFound)
+		return
+	}
+	if tenant.Store == nil {
+		http.Error(w, "tenant "+tenant.Name+" doesn't store runs", http.StatusNotFound)
+		return
+	}
+
+	decision, err := gate.Evaluate(tenant.Store, owner, repository, commit, tenant.DeployPolicy, time.Now().UTC())
+	if errors.Is(err, gate.ErrInvalidCommit) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Errorf("evaluate deploy gate of %s/%s@%s", owner, repository, commit)
+		http.Error(w, "evaluate deploy gate", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Infof("deploy gate of %s/%s@%s: allowed %t", owner, repository, decision.Commit, decision.Allowed)
+
+	code := http.StatusOK
+	if !decision.Allowed {
+		code = gate.DeniedStatusCode
+	}
+	writeJSON(w, code, decision)
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/gate"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDeployGate(t *testing.T) {
+	store := history.NewMemoryStore()
+	tenant := &Tenant{
+		Name:         "azure",
+		Repositories: []string{"Azure"},
+		Secret:       staticToken(testWebhookSecret),
+		Client:       &mockClient{},
+		Runner:       &mockRunner{},
+		Store:        store,
+		APIToken:     staticToken("secret"),
+		DeployPolicy: &gate.Policy{DiffCoverage: 40},
+	}
+	server := NewServer(&ServerOption{Tenants: []*Tenant{tenant}, Logger: logrus.New()})
+	sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+
+	ask := func(query, token string) (*httptest.ResponseRecorder, *gate.Decision) {
+		req := httptest.NewRequest(http.MethodGet, DeployPath+"?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+		decision := &gate.Decision{}
+		json.Unmarshal(w.Body.Bytes(), decision)
+		return w, decision
+	}
+
+	if w, decision := ask("owner=Azure&repository=gocover&commit=abcdef1", "secret"); w.Code != http.StatusOK || !decision.Allowed || decision.Commit != "abcdef123456" {
+		t.Errorf("expect the commit is allowed, but get %d %s", w.Code, w.Body.String())
+	}
+	tenant.DeployPolicy = &gate.Policy{DiffCoverage: 80}
+	if w, decision := ask("owner=Azure&repository=gocover&commit=abcdef1", "secret"); w.Code != gate.DeniedStatusCode || decision.Allowed {
+		t.Errorf("expect the commit is denied, but get %d %s", w.Code, w.Body.String())
+	}
+
+	for query, code := range map[string]int{
+		"owner=Azure&repository=gocover&commit=abc": http.StatusBadRequest,
+		"commit=abcdef1": http.StatusBadRequest,
+		"owner=other&repository=gocover&commit=abcdef1": http.StatusNotFound,
+	} {
+		if w, _ := ask(query, "secret"); w.Code != code {
+			t.Errorf("expect %d of %s, but get %d", code, query, w.Code)
+		}
+	}
+	if w, _ := ask("owner=Azure&repository=gocover&commit=abcdef1", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized, but get %d", w.Code)
+	}
+}
@@ -0,0 +1,5 @@
+// Package webhook runs gocover as a bot that serves the SCM webhooks,
+// it responds the gocover commands in pull request comments, such as
+// `/gocover rerun`, `/gocover report full` and `/gocover explain file.go:42`,
+// so that coverage results are available without re-triggering the whole CI pipeline.
+package webhook
@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/report"
+)
+
+// helpMessage lists the supported commands.
+const helpMessage = `Supported gocover commands:
+- ` + "`/gocover rerun [verbosity=minimal|summary|detailed|auto]`" + `: rerun the diff coverage analysis
+- ` + "`/gocover report [diff|full] [verbosity=minimal|summary|detailed|auto]`" + `: report the diff coverage or the full coverage
+- ` + "`/gocover explain {file}:{line}`" + `: explain the coverage state of a line
+- ` + "`/gocover skip reason={reason}`" + `: downgrade a failing coverage gate to neutral`
+
+// formatExplanation explains the coverage state of the line in the file.
+func formatExplanation(statistics *report.Statistics, file string, line int) string {
+	for _, p := range statistics.CoverageProfile {
+		if !strings.HasSuffix(p.FileName, file) {
+			continue
+		}
+		for _, section := range p.ViolationSections {
+			for _, l := range section.ViolationLines {
+				if l == line {
+					return fmt.Sprintf("Line %d of `%s` is **not covered**, it's in the uncovered section [%d, %d].",
+						line, p.FileName, section.StartLine, section.EndLine)
+				}
+			}
+		}
+		return fmt.Sprintf("Line %d of `%s` has no uncovered statement, it's either covered, ignored or not a changed statement.", line, p.FileName)
+	}
+	return fmt.Sprintf("`%s` has no coverage information in this diff, it's either excluded, unchanged or has no statements.", file)
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/kube"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// JobCommand is the gocover command that a Job runs, it analyzes the pull request and writes the result into the logs.
+	JobCommand = "webhook-job"
+
+	// jobResultPrefix marks the line of the result in the logs of a Job.
+	jobResultPrefix = "gocover-job-result: "
+	// jobTokenEnv is the environment variable of the GitHub token in a Job.
+	jobTokenEnv = "GITHUB_TOKEN"
+
+	defaultJobPollInterval = 5 * time.Second
+	defaultJobTimeout      = time.Hour
+	// jobTTLSeconds keeps a finished Job for a while in case it's not deleted, such as the webhook pod restarts.
+	jobTTLSeconds = int32(600)
+)
+
+var (
+	ErrJobFailed   = errors.New("analysis job failed")
+	ErrNoJobResult = errors.New("no result found in the job logs")
+)
+
+// JobRunnerOption contains the input for the Kubernetes Job runner.
+type JobRunnerOption struct {
+	// RunnerOption is passed to the analysis in the Job, WorkDir and Token are not used.
+	RunnerOption
+	// Namespace is the namespace that the Jobs are created in.
+	Namespace string
+	// Image is the gocover image that the Jobs run, it should contain the go toolchain the repositories need.
+	Image string
+	// ServiceAccount is the service account of the Job pods, the default one is used if it's empty.
+	ServiceAccount string
+	// Resources are the resource requests and limits of the Job pods.
+	Resources kube.ResourceRequirements
+	// NodeSelector selects the nodes for the Job pods, such as a pool of larger machines.
+	NodeSelector map[string]string
+	// TokenSecret is the secret key that contains the GitHub token in the format of {name}:{key},
+	// the repositories are cloned without token if it's empty.
+	TokenSecret string
+	// Timeout is the deadline of a Job, one hour is used if it's zero.
+	Timeout time.Duration
+	// PollInterval is how often the Job status is checked, five seconds is used if it's zero.
+	PollInterval time.Duration
+}
+
+// Validate validates the option.
+func (o *JobRunnerOption) Validate() error {
+	if o.Namespace == "" {
+		return errors.New("job namespace is required")
+	}
+	if o.Image == "" {
+		return errors.New("job image is required")
+	}
+	if o.TokenSecret != "" {
+		if name, key, ok := strings.Cut(o.TokenSecret, ":"); !ok || name == "" || key == "" {
+			return fmt.Errorf("wrong token secret '%s', the format is {name}:{key}", o.TokenSecret)
+		}
+	}
+	return nil
+}
+
+// NewJobRunner creates a runner that dispatches each analysis as a Kubernetes Job, which clones the repository,
+// runs unit tests and calculates coverage, so heavy analyses don't run inside the webhook pod.
+func NewJobRunner(o *JobRunnerOption, client *kube.Client, logger logrus.FieldLogger) (Runner, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &jobRunner{option: o, client: client, logger: logger.WithField("source", "JobRunner")}, nil
+}
+
+type jobRunner struct {
+	option *JobRunnerOption
+	client *kube.Client
+	logger logrus.FieldLogger
+}
+
+var _ Runner = (*jobRunner)(nil)
+
+func (r *jobRunner) Run(ctx context.Context, pr *scm.PullRequest, mode gocover.CoverageMode) (*report.Statistics, error) {
+	job, err := r.client.CreateJob(ctx, r.option.Namespace, r.job(pr, mode))
+	if err != nil {
+		return nil, err
+	}
+	name := job.Metadata.Name
+	logger := r.logger.WithField("job", name)
+	logger.Infof("dispatch %s coverage of %s/%s#%d", mode, pr.Owner, pr.Repository, pr.Number)
+
+	defer func() {
+		// the job is deleted even if the context is canceled.
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := r.client.DeleteJob(ctx, r.option.Namespace, name); err != nil {
+			logger.WithError(err).Warn("delete job")
+		}
+	}()
+
+	succeeded, err := r.wait(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := r.client.JobLogs(ctx, r.option.Namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if !succeeded {
+		return nil, fmt.Errorf("%w %s: %s", ErrJobFailed, name, lastLine(logs))
+	}
+	return ParseJobResult(strings.NewReader(logs))
+}
+
+// wait waits until the job finishes and returns whether it succeeded.
+func (r *jobRunner) wait(ctx context.Context, name string) (bool, error) {
+	interval := r.option.PollInterval
+	if interval == 0 {
+		interval = defaultJobPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := r.client.GetJob(ctx, r.option.Namespace, name)
+		if err != nil {
+			return false, err
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != "True" {
+				continue
+			}
+			switch c.Type {
+			case "Complete":
+				return true, nil
+			case "Failed":
+				r.logger.WithField("job", name).Warnf("job failed, %s: %s", c.Reason, c.Message)
+				return false, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// job builds the Job that analyzes the pull request.
+func (r *jobRunner) job(pr *scm.PullRequest, mode gocover.CoverageMode) *kube.Job {
+	o := r.option
+	args := []string{
+		JobCommand,
+		"--owner", pr.Owner,
+		"--repository", pr.Repository,
+		"--number", strconv.Itoa(pr.Number),
+		"--clone-url", pr.CloneURL,
+		"--head-sha", pr.HeadSHA,
+		"--base-ref", pr.BaseRef,
+		"--mode", string(mode),
+		"--module-dir", o.ModuleDir,
+		"--coverage-baseline", strconv.FormatFloat(o.CoverageBaseline, 'f', -1, 64),
+	}
+	for _, exclude := range o.Excludes {
+		args = append(args, "--excludes", exclude)
+	}
+
+	container := kube.Container{Name: "gocover", Image: o.Image, Resources: o.Resources}
+	if o.TokenSecret != "" {
+		name, key, _ := strings.Cut(o.TokenSecret, ":")
+		container.Env = []kube.EnvVar{{
+			Name:      jobTokenEnv,
+			ValueFrom: &kube.EnvVarSource{SecretKeyRef: &kube.SecretKeySelector{Name: name, Key: key}},
+		}}
+		args = append(args, "--github-token", "env:"+jobTokenEnv)
+	}
+	container.Args = args
+
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = defaultJobTimeout
+	}
+	deadline := int64(timeout.Seconds())
+	backoffLimit := int32(0)
+	ttl := jobTTLSeconds
+	labels := map[string]string{"app.kubernetes.io/name": "gocover", "app.kubernetes.io/component": "analysis"}
+
+	return &kube.Job{
+		Metadata: kube.ObjectMeta{
+			GenerateName: "gocover-",
+			Labels:       labels,
+			Annotations: map[string]string{
+				"gocover/pull-request": fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repository, pr.Number),
+				"gocover/head-sha":     pr.HeadSHA,
+			},
+		},
+		Spec: kube.JobSpec{
+			// an analysis is not retried, the failure is replied to the command.
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: kube.PodTemplateSpec{
+				Metadata: kube.ObjectMeta{Labels: labels},
+				Spec: kube.PodSpec{
+					RestartPolicy:      "Never",
+					ServiceAccountName: o.ServiceAccount,
+					NodeSelector:       o.NodeSelector,
+					Containers:         []kube.Container{container},
+				},
+			},
+		},
+	}
+}
+
+// WriteJobResult writes the result of a Job as a single marked line, so it can be found among the other logs.
+func WriteJobResult(w io.Writer, statistics *report.Statistics) error {
+	data, err := json.Marshal(statistics)
+	if err != nil {
+		return fmt.Errorf("marshal job result: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", jobResultPrefix, data)
+	return err
+}
+
+// ParseJobResult parses the result in the logs of a Job, the last result wins.
+func ParseJobResult(logs io.Reader) (*report.Statistics, error) {
+	var result string
+	scanner := bufio.NewScanner(logs)
+	// the result is a single line that contains the whole statistics.
+	scanner.Buffer(make([]byte, 64*1024), maxPayloadSize)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, jobResultPrefix) {
+			result = strings.TrimPrefix(line, jobResultPrefix)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read job logs: %w", err)
+	}
+	if result == "" {
+		return nil, ErrNoJobResult
+	}
+
+	statistics := &report.Statistics{}
+	if err := json.Unmarshal([]byte(result), statistics); err != nil {
+		return nil, fmt.Errorf("decode job result: %w", err)
+	}
+	return statistics, nil
+}
+
+// lastLine returns the last non-empty line of the logs, which usually explains the failure.
+func lastLine(logs string) string {
+	lines := strings.Split(strings.TrimSpace(logs), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/kube"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeAPIServer serves a Job that finishes with the condition after the first status check.
+type fakeAPIServer struct {
+	condition string
+	logs      string
+
+	mu      sync.Mutex
+	created *kube.Job
+	checks  int
+	deleted bool
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/apis/batch/v1/namespaces/ci/jobs":
+		f.created = &kube.Job{}
+		json.NewDecoder(r.Body).Decode(f.created)
+		f.created.Metadata.Name = "gocover-abcde"
+		json.NewEncoder(w).Encode(f.created)
+	case r.Method == http.MethodGet && r.URL.Path == "/apis/batch/v1/namespaces/ci/jobs/gocover-abcde":
+		job := &kube.Job{Metadata: kube.ObjectMeta{Name: "gocover-abcde"}}
+		if f.checks > 0 {
+			job.Status.Conditions = []kube.JobCondition{{Type: f.condition, Status: "True"}}
+		}
+		f.checks++
+		json.NewEncoder(w).Encode(job)
+	case r.Method == http.MethodDelete && r.URL.Path == "/apis/batch/v1/namespaces/ci/jobs/gocover-abcde":
+		f.deleted = true
+	case r.URL.Path == "/api/v1/namespaces/ci/pods":
+		w.Write([]byte(`{"items":[{"metadata":{"name":"gocover-abcde-x1"}}]}`))
+	case r.URL.Path == "/api/v1/namespaces/ci/pods/gocover-abcde-x1/log":
+		w.Write([]byte(f.logs))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestJobRunner(t *testing.T, f *fakeAPIServer) Runner {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	client, err := kube.NewClient(&kube.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := NewJobRunner(&JobRunnerOption{
+		RunnerOption: RunnerOption{ModuleDir: "./", Excludes: []string{"**/mock/**"}, CoverageBaseline: 80},
+		Namespace:    "ci",
+		Image:        "gocover:latest",
+		TokenSecret:  "gocover:github-token",
+		PollInterval: time.Millisecond,
+	}, client, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runner
+}
+
+func TestJobRunner(t *testing.T) {
+	pr := &scm.PullRequest{Owner: "Azure", Repository: "gocover", Number: 1, HeadSHA: "abc", BaseRef: "main", CloneURL: "https://github.com/Azure/gocover.git"}
+
+	t.Run("complete", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		buf.WriteString("running tests\n")
+		WriteJobResult(buf, &report.Statistics{TotalCoveragePercent: 75, StatisticsType: report.DiffStatisticsType})
+		f := &fakeAPIServer{condition: "Complete", logs: buf.String()}
+		runner := newTestJobRunner(t, f)
+
+		statistics, err := runner.Run(context.Background(), pr, gocover.DiffCoverage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if statistics.TotalCoveragePercent != 75 {
+			t.Errorf("expect coverage 75, but get %f", statistics.TotalCoveragePercent)
+		}
+		if !f.deleted {
+			t.Error("expect the job is deleted")
+		}
+
+		container := f.created.Spec.Template.Spec.Containers[0]
+		args := strings.Join(container.Args, " ")
+		for _, want := range []string{JobCommand + " ", "--number 1", "--mode diff", "--excludes **/mock/**", "--coverage-baseline 80", "--github-token env:GITHUB_TOKEN"} {
+			if !strings.Contains(args, want) {
+				t.Errorf("expect args %q contain %q", args, want)
+			}
+		}
+		if ref := container.Env[0].ValueFrom.SecretKeyRef; ref.Name != "gocover" || ref.Key != "github-token" {
+			t.Errorf("unexpected token secret %s:%s", ref.Name, ref.Key)
+		}
+		if f.created.Spec.Template.Spec.RestartPolicy != "Never" || *f.created.Spec.BackoffLimit != 0 {
+			t.Error("expect the job is not retried")
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		f := &fakeAPIServer{condition: "Failed", logs: "cloning\nfatal: repository not found\n"}
+		runner := newTestJobRunner(t, f)
+
+		_, err := runner.Run(context.Background(), pr, gocover.FullCoverage)
+		if !errors.Is(err, ErrJobFailed) {
+			t.Fatalf("expect ErrJobFailed, but get %v", err)
+		}
+		if !strings.HasSuffix(err.Error(), "fatal: repository not found") {
+			t.Errorf("expect the last log line in the error, but get %q", err.Error())
+		}
+		if !f.deleted {
+			t.Error("expect the job is deleted")
+		}
+	})
+}
+
+func TestJobRunnerOptionValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		option JobRunnerOption
+		valid  bool
+	}{
+		{name: "valid", option: JobRunnerOption{Namespace: "ci", Image: "gocover"}, valid: true},
+		{name: "no namespace", option: JobRunnerOption{Image: "gocover"}},
+		{name: "no image", option: JobRunnerOption{Namespace: "ci"}},
+		{name: "bad token secret", option: JobRunnerOption{Namespace: "ci", Image: "gocover", TokenSecret: "gocover"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.option.Validate(); (err == nil) != tc.valid {
+				t.Errorf("expect valid %v, but get %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestParseJobResult(t *testing.T) {
+	t.Run("last result wins", func(t *testing.T) {
+		logs := jobResultPrefix + `{"TotalCoveragePercent":10}` + "\nother\n" + jobResultPrefix + `{"TotalCoveragePercent":20}` + "\n"
+		statistics, err := ParseJobResult(strings.NewReader(logs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if statistics.TotalCoveragePercent != 20 {
+			t.Errorf("expect coverage 20, but get %f", statistics.TotalCoveragePercent)
+		}
+	})
+
+	t.Run("no result", func(t *testing.T) {
+		if _, err := ParseJobResult(strings.NewReader("PASS\n")); !errors.Is(err, ErrNoJobResult) {
+			t.Errorf("expect ErrNoJobResult, but get %v", err)
+		}
+	})
+}
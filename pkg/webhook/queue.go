@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/gocover/pkg/chatops"
+	"github.com/Azure/gocover/pkg/queue"
+	"github.com/Azure/gocover/pkg/redact"
+)
+
+const (
+	// QueuePath is the path of the status API over the queued submissions,
+	// QueuePath/{id} returns a submission and QueuePath lists the submissions.
+	QueuePath = "/queue"
+
+	// pollIntervalSeconds is the Retry-After header of the unfinished submissions, which suggests how often to poll.
+	pollIntervalSeconds = 10
+	// pruneInterval is how often the finished submissions out of the retention are removed.
+	pruneInterval = time.Hour
+	// dequeueRetryInterval is how long a worker waits after it fails to dequeue, such as the disk is full.
+	dequeueRetryInterval = 5 * time.Second
+)
+
+// submissionStatus is the response of the status API.
+type submissionStatus struct {
+	*queue.Item
+	// Position is the position of a queued submission, the next submission to process is 1.
+	Position int `json:",omitempty"`
+	// StatusURL is the path of the status of the submission.
+	StatusURL string
+}
+
+// enqueue adds the submission into the queue and replies its status.
+func (s *Server) enqueue(w http.ResponseWriter, item *queue.Item) {
+	if err := s.queue.Enqueue(item); err != nil {
+		s.logger.WithError(err).Errorf("enqueue %s/%s#%d", item.Owner, item.Repository, item.Number)
+		http.Error(w, "enqueue submission", http.StatusServiceUnavailable)
+		return
+	}
+	s.logger.Infof("enqueue %s/%s#%d as %s", item.Owner, item.Repository, item.Number, item.ID)
+
+	status, err := s.status(item)
+	if err != nil {
+		s.logger.WithError(err).Error("get submission status")
+	}
+	w.Header().Set("Location", status.StatusURL)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// startWorkers starts the workers that process the queued submissions until the context is done.
+func (s *Server) startWorkers(ctx context.Context) {
+	if s.queue == nil {
+		return
+	}
+	s.logger.Infof("start %d queue workers", s.workers)
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.work(ctx)
+		}()
+	}
+	if s.retention > 0 {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.pruneQueue(ctx)
+		}()
+	}
+}
+
+// work processes the queued submissions one by one until the context is done,
+// a submission in processing is finished even if the context is done.
+func (s *Server) work(ctx context.Context) {
+	for {
+		item, err := s.queue.Dequeue(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.WithError(err).Error("dequeue submission")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(dequeueRetryInterval):
+			}
+			continue
+		}
+
+		// the failure is served by the status API, so the secrets are redacted.
+		err = redact.Error(s.processItem(context.Background(), item))
+		if err := s.queue.Finish(item.ID, err); err != nil {
+			s.logger.WithError(err).Errorf("finish submission %s", item.ID)
+		}
+	}
+}
+
+// processItem executes the commands of the submission with the tenant in service.
+func (s *Server) processItem(ctx context.Context, item *queue.Item) error {
+	var tenant *Tenant
+	for _, t := range s.currentTenants() {
+		if t.Name == item.Tenant {
+			tenant = t
+			break
+		}
+	}
+	// the tenant may be removed or changed by a reload after the submission is queued.
+	if tenant == nil || !tenant.Serves(item.Owner, item.Repository) {
+		return fmt.Errorf("%w: %s/%s of tenant %s", ErrUnknownTenant, item.Owner, item.Repository, item.Tenant)
+	}
+	sender := &commandSender{login: item.Sender, association: item.SenderAssociation}
+	return s.process(ctx, tenant, item.Owner, item.Repository, item.Number, sender, chatops.Parse(item.Body))
+}
+
+// pruneQueue removes the finished submissions out of the retention periodically until the context is done.
+func (s *Server) pruneQueue(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		pruned, err := s.queue.Prune(time.Now().Add(-s.retention))
+		if err != nil {
+			s.logger.WithError(err).Error("prune queue")
+		} else if pruned > 0 {
+			s.logger.Infof("prune %d finished submissions", pruned)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleQueue serves the status of the submissions of the tenant that the bearer token belongs to.
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	tenant, err := s.authorize(r)
+	if err != nil {
+		s.logger.WithError(err).Error("authorize queue request")
+		http.Error(w, "authorize request", http.StatusInternalServerError)
+		return
+	}
+	if tenant == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if id := strings.Trim(strings.TrimPrefix(r.URL.Path, QueuePath), "/"); id != "" {
+		s.handleSubmission(w, tenant, id)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &queue.Filter{
+		Tenant:     tenant.Name,
+		Owner:      query.Get("owner"),
+		Repository: query.Get("repository"),
+		State:      queue.State(strings.ToLower(query.Get("state"))),
+	}
+	for name, value := range map[string]*int{"number": &filter.Number, "limit": &filter.Limit} {
+		if v := query.Get(name); v != "" {
+			if *value, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("wrong %s '%s'", name, v), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	items, err := s.queue.List(filter)
+	if err != nil {
+		s.logger.WithError(err).Error("list submissions")
+		http.Error(w, "list submissions", http.StatusInternalServerError)
+		return
+	}
+	result := make([]*submissionStatus, 0, len(items))
+	for _, item := range items {
+		status, err := s.status(item)
+		if err != nil {
+			s.logger.WithError(err).Error("get submission status")
+			http.Error(w, "get submission status", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, status)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleSubmission serves the status of a submission, the submissions of the other tenants are not found.
+func (s *Server) handleSubmission(w http.ResponseWriter, tenant *Tenant, id string) {
+	item, err := s.queue.Get(id)
+	if errors.Is(err, queue.ErrItemNotFound) || (err == nil && item.Tenant != tenant.Name) {
+		http.Error(w, fmt.Sprintf("submission %s not found", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Errorf("get submission %s", id)
+		http.Error(w, "get submission", http.StatusInternalServerError)
+		return
+	}
+
+	status, err := s.status(item)
+	if err != nil {
+		s.logger.WithError(err).Error("get submission status")
+		http.Error(w, "get submission status", http.StatusInternalServerError)
+		return
+	}
+	if !item.Finished() {
+		w.Header().Set("Retry-After", strconv.Itoa(pollIntervalSeconds))
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// status returns the status of the submission, the position is counted among the queued submissions of all tenants.
+func (s *Server) status(item *queue.Item) (*submissionStatus, error) {
+	status := &submissionStatus{Item: item, StatusURL: QueuePath + "/" + item.ID}
+	if item.State != queue.Queued {
+		return status, nil
+	}
+	queued, err := s.queue.List(&queue.Filter{State: queue.Queued})
+	if err != nil {
+		return status, err
+	}
+	// the queued submissions are listed by the latest first.
+	for i, q := range queued {
+		if q.ID == item.ID {
+			status.Position = len(queued) - i
+			break
+		}
+	}
+	return status, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/gocover/pkg/queue"
+	"github.com/sirupsen/logrus"
+)
+
+func getStatus(server *Server, path string, token string) (*httptest.ResponseRecorder, *submissionStatus) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	status := &submissionStatus{}
+	json.Unmarshal(w.Body.Bytes(), status)
+	return w, status
+}
+
+func TestQueuedSubmission(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	client := &mockClient{}
+	server := NewServer(&ServerOption{
+		Secret:   staticToken(testWebhookSecret),
+		Client:   client,
+		Runner:   &mockRunner{},
+		Queue:    q,
+		APIToken: staticToken("secret"),
+		Logger:   logrus.New(),
+	})
+
+	w := sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun\\n/gocover report full"), "")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expect accepted, but get %d", w.Code)
+	}
+	accepted := &submissionStatus{}
+	if err := json.Unmarshal(w.Body.Bytes(), accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.State != queue.Queued || accepted.Position != 1 || w.Header().Get("Location") != accepted.StatusURL {
+		t.Errorf("unexpected accepted submission %+v", accepted)
+	}
+	if len(client.comments) != 0 {
+		t.Error("expect the submission is not processed before the workers start")
+	}
+
+	w, status := getStatus(server, accepted.StatusURL, "secret")
+	if w.Code != http.StatusOK || status.State != queue.Queued || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expect a queued submission to poll, but get %d %+v", w.Code, status)
+	}
+	if w, _ := getStatus(server, accepted.StatusURL, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized, but get %d", w.Code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server.startWorkers(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for status.State != queue.Succeeded {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect the submission succeeds, but get %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		_, status = getStatus(server, accepted.StatusURL, "secret")
+	}
+	cancel()
+	server.Wait()
+
+	if len(client.comments) != 2 {
+		t.Errorf("expect 2 replies, but get %d", len(client.comments))
+	}
+	if status.Attempts != 1 || status.FinishedAt == nil || status.Position != 0 {
+		t.Errorf("unexpected finished submission %+v", status)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, QueuePath+"?owner=azure&state=succeeded", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	server.Handler().ServeHTTP(w, req)
+	var list []*submissionStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0].ID != accepted.ID {
+		t.Errorf("expect the submission is listed, but get %s", w.Body.String())
+	}
+	if w, _ := getStatus(server, QueuePath+"?number=abc", "secret"); w.Code != http.StatusBadRequest {
+		t.Errorf("expect bad request, but get %d", w.Code)
+	}
+}
+
+func TestQueuedSubmissionOfTenants(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	azure := &Tenant{Name: "azure", Repositories: []string{"Azure"}, Secret: staticToken(testWebhookSecret), Client: &mockClient{}, Runner: &mockRunner{}, APIToken: staticToken("azure-token")}
+	other := &Tenant{Name: "other", Repositories: []string{"other"}, Secret: staticToken(testWebhookSecret), Client: &mockClient{}, Runner: &mockRunner{}, APIToken: staticToken("other-token")}
+	server := NewServer(&ServerOption{Tenants: []*Tenant{azure, other}, Queue: q, Logger: logrus.New()})
+
+	w := sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+	accepted := &submissionStatus{}
+	json.Unmarshal(w.Body.Bytes(), accepted)
+	if accepted.Tenant != "azure" {
+		t.Fatalf("expect the submission of tenant azure, but get %+v", accepted)
+	}
+	if w, _ := getStatus(server, accepted.StatusURL, "other-token"); w.Code != http.StatusNotFound {
+		t.Errorf("expect the submission of the other tenant is not found, but get %d", w.Code)
+	}
+
+	// the tenant is removed by a reload before the submission is processed.
+	server.SetTenants([]*Tenant{other})
+	item, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.processItem(context.Background(), item); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("expect ErrUnknownTenant, but get %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/dbclient"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	gogit "github.com/go-git/go-git/v5"
+	gogitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/sirupsen/logrus"
+)
+
+// Runner runs the coverage analysis for a pull request.
+type Runner interface {
+	Run(ctx context.Context, pr *scm.PullRequest, mode gocover.CoverageMode) (*report.Statistics, error)
+}
+
+// RunnerOption contains the input for the local runner.
+type RunnerOption struct {
+	// WorkDir is the directory that repositories are cloned into, system temp directory is used if it's empty.
+	WorkDir string
+	// ModuleDir is the directory contains go.mod file that relative to the repository.
+	ModuleDir string
+	// Excludes are the exclude patterns for coverage calculation.
+	Excludes []string
+	// CoverageBaseline is the coverage baseline of the analysis.
+	CoverageBaseline float64
+	// Token is used to clone private repositories.
+	Token credential.Provider
+}
+
+// NewLocalRunner creates a runner that clones the repository,
+// runs unit tests and calculates coverage in the current process.
+func NewLocalRunner(o *RunnerOption, logger logrus.FieldLogger) Runner {
+	return &localRunner{option: o, logger: logger.WithField("source", "LocalRunner")}
+}
+
+type localRunner struct {
+	option *RunnerOption
+	logger logrus.FieldLogger
+}
+
+// runMu serializes the runs of all local runners, such as the runners of different tenants,
+// because resolving packages of the cover profiles depends on the working directory of the process.
+var runMu sync.Mutex
+
+var _ Runner = (*localRunner)(nil)
+
+func (r *localRunner) Run(ctx context.Context, pr *scm.PullRequest, mode gocover.CoverageMode) (*report.Statistics, error) {
+	dir, err := os.MkdirTemp(r.option.WorkDir, "gocover-run")
+	if err != nil {
+		return nil, fmt.Errorf("create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	repositoryPath := filepath.Join(dir, "repository")
+	if err := r.checkout(ctx, repositoryPath, pr); err != nil {
+		return nil, err
+	}
+
+	outputDir := filepath.Join(dir, "output")
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+
+	capture := &statisticsCapture{}
+	o := gocover.NewGoCoverTestOption()
+	o.RepositoryPath = repositoryPath
+	o.ModuleDir = r.option.ModuleDir
+	o.CompareBranch = "origin/" + pr.BaseRef
+	o.CoverageMode = mode
+	o.ExecutorMode = gocover.GoExecutor
+	o.CoverageBaseline = r.option.CoverageBaseline
+	o.Excludes = r.option.Excludes
+	// the failed tests are reported in the comment, rather than failing the command.
+	o.FailedTestPolicy = gocover.WarnOnFailedTests
+	o.OutputDir = outputDir
+	o.ReportName = "coverage"
+	o.Style = "colorful"
+	o.DbOption = &dbclient.DBOption{}
+	o.ReportGenerators = []report.ReportGenerator{capture}
+	o.StdOut = io.Discard
+	o.StdErr = io.Discard
+	o.Logger = r.logger
+
+	executor, err := gocover.NewGoCoverTestExecutor(o)
+	if err != nil {
+		return nil, err
+	}
+
+	runMu.Lock()
+	defer runMu.Unlock()
+	restore, err := chdir(filepath.Join(repositoryPath, r.option.ModuleDir))
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	if err := executor.Run(ctx); err != nil {
+		// the coverage result is still valid when coverage is lower than the baseline.
+		var e *gocover.GoCoverError
+		if !(errors.As(err, &e) && e.ExitCode == gocover.LowCoverageErrorExitCode) || capture.statistics == nil {
+			return nil, err
+		}
+	}
+
+	return capture.statistics, nil
+}
+
+// checkout clones the repository and checks out the head commit of the pull request.
+func (r *localRunner) checkout(ctx context.Context, path string, pr *scm.PullRequest) error {
+	cloneOptions := &gogit.CloneOptions{URL: pr.CloneURL}
+	if r.option.Token != nil {
+		token, err := r.option.Token.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		cloneOptions.Auth = &githttp.BasicAuth{Username: "x-access-token", Password: token}
+	}
+
+	r.logger.Infof("clone %s into %s", pr.CloneURL, path)
+	repository, err := gogit.PlainCloneContext(ctx, path, false, cloneOptions)
+	if err != nil {
+		return fmt.Errorf("clone repository: %w", err)
+	}
+
+	// the head commit may come from a fork, fetch it by the pull request ref.
+	err = repository.FetchContext(ctx, &gogit.FetchOptions{
+		Auth: cloneOptions.Auth,
+		RefSpecs: []gogitconfig.RefSpec{
+			gogitconfig.RefSpec(fmt.Sprintf("+refs/pull/%d/head:refs/remotes/origin/pull/%d", pr.Number, pr.Number)),
+		},
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("fetch pull request: %w", err)
+	}
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return fmt.Errorf("get worktree: %w", err)
+	}
+	if err := worktree.Checkout(&gogit.CheckoutOptions{Hash: plumbing.NewHash(pr.HeadSHA)}); err != nil {
+		return fmt.Errorf("checkout %s: %w", pr.HeadSHA, err)
+	}
+	return nil
+}
+
+// chdir changes the working directory and returns the function that restores it.
+func chdir(dir string) (func(), error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return nil, fmt.Errorf("change working directory: %w", err)
+	}
+	return func() { _ = os.Chdir(wd) }, nil
+}
+
+// statisticsCapture is a report generator that keeps the statistics for later use.
+type statisticsCapture struct {
+	statistics *report.Statistics
+}
+
+var _ report.ReportGenerator = (*statisticsCapture)(nil)
+
+func (c *statisticsCapture) GenerateReport(statistics *report.Statistics) error {
+	c.statistics = statistics
+	return nil
+}
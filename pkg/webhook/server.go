@@ -0,0 +1,579 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/gocover/pkg/audit"
+	"github.com/Azure/gocover/pkg/chatops"
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/notify"
+	"github.com/Azure/gocover/pkg/queue"
+	"github.com/Azure/gocover/pkg/redact"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// WebhookPath is the path that receives the webhook events.
+	WebhookPath = "/webhook"
+	// HealthPath is the path for health probe.
+	HealthPath = "/healthz"
+
+	githubEventHeader     = "X-GitHub-Event"
+	githubSignatureHeader = "X-Hub-Signature-256"
+	signaturePrefix       = "sha256="
+
+	// verbosityParam overrides the comment verbosity of a single command.
+	verbosityParam = "verbosity"
+
+	// maxPayloadSize limits the size of a webhook payload.
+	maxPayloadSize = 25 * 1024 * 1024
+)
+
+// Commands supported by the bot.
+const (
+	RerunCommand   = "rerun"
+	ReportCommand  = "report"
+	ExplainCommand = "explain"
+	SkipCommand    = gocover.SkipCommand
+)
+
+// analysisCommands are the commands that run the analysis on the pull request, only the authorized senders can run them.
+var analysisCommands = map[string]bool{
+	RerunCommand:   true,
+	ReportCommand:  true,
+	ExplainCommand: true,
+}
+
+// writeAssociations are the author associations that have the write permission on the repository.
+var writeAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrMissingSecret    = errors.New("webhook secret is not set")
+)
+
+// ServerOption contains the input for the webhook server.
+// Without tenants, the server serves all repositories as a single tenant built from the other fields.
+type ServerOption struct {
+	// Secret is the webhook secret that verifies the payload signature, the webhook events are rejected if it's nil.
+	Secret credential.Provider
+	// Client is the SCM client.
+	Client scm.Client
+	// Runner runs the coverage analysis.
+	Runner Runner
+	// Recorder records the external actions.
+	Recorder audit.Recorder
+	// Verbosity is the default verbosity of the coverage comments, auto is used if it's empty.
+	Verbosity report.Verbosity
+	// Thresholds limits the detail of the coverage comments on large changes.
+	Thresholds report.Thresholds
+	// CoverageBaseline is shown in the coverage comments if it's greater than zero.
+	CoverageBaseline float64
+	// Store saves the finished runs and enables the GraphQL API over them, the runs are not saved if it's nil.
+	Store history.Store
+	// APIToken is the bearer token that authorizes the GraphQL requests, the requests are not authorized if it's nil.
+	APIToken credential.Provider
+	// Tenants are the tenants served by the server, a repository is served by the first tenant that serves it.
+	Tenants []*Tenant
+	// Queue keeps the submissions to be processed asynchronously by the workers, and enables the status API on QueuePath.
+	// The submissions are processed right after they're received if it's nil.
+	Queue queue.Queue
+	// Workers is the number of the workers that process the queued submissions, one worker is started if it's zero.
+	Workers int
+	// QueueRetention is how long the finished submissions are kept for the status API, they're kept forever if it's zero.
+	QueueRetention time.Duration
+	// Commenters are the users allowed to run the analysis commands besides the ones with the write permission on the repository.
+	Commenters []string
+	Logger     logrus.FieldLogger
+}
+
+// NewServer creates the webhook server.
+func NewServer(o *ServerOption) *Server {
+	recorder := o.Recorder
+	if recorder == nil {
+		recorder = audit.NewRecorder("")
+	}
+
+	tenants := o.Tenants
+	if len(tenants) == 0 {
+		tenants = []*Tenant{{
+			Name:             "default",
+			Secret:           o.Secret,
+			Client:           o.Client,
+			Runner:           o.Runner,
+			Store:            o.Store,
+			APIToken:         o.APIToken,
+			Verbosity:        o.Verbosity,
+			Thresholds:       o.Thresholds,
+			CoverageBaseline: o.CoverageBaseline,
+			Commenters:       o.Commenters,
+		}}
+	}
+
+	workers := o.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	server := &Server{
+		recorder:  recorder,
+		queue:     o.Queue,
+		workers:   workers,
+		retention: o.QueueRetention,
+		results:   make(map[string]*report.Statistics),
+		logger:    o.Logger.WithField("source", "WebhookServer"),
+	}
+	server.SetTenants(tenants)
+	return server
+}
+
+// Server serves the webhook events.
+type Server struct {
+	// tenants can be replaced when the config is reloaded, the commands in processing keep the tenant they started with.
+	tenantsMu sync.RWMutex
+	tenants   []*Tenant
+	recorder  audit.Recorder
+
+	queue     queue.Queue
+	workers   int
+	retention time.Duration
+
+	// results caches the latest diff statistics for each pull request head.
+	mu      sync.Mutex
+	results map[string]*report.Statistics
+
+	wg     sync.WaitGroup
+	logger logrus.FieldLogger
+}
+
+// SetTenants replaces the tenants, such as when the config is reloaded. It's safe to call while serving.
+func (s *Server) SetTenants(tenants []*Tenant) {
+	for _, t := range tenants {
+		if t.Verbosity == "" {
+			t.Verbosity = report.AutoVerbosity
+		}
+	}
+	s.tenantsMu.Lock()
+	defer s.tenantsMu.Unlock()
+	s.tenants = tenants
+}
+
+// currentTenants returns the tenants in service.
+func (s *Server) currentTenants() []*Tenant {
+	s.tenantsMu.RLock()
+	defer s.tenantsMu.RUnlock()
+	return s.tenants
+}
+
+// tenant returns the first tenant that serves the repository.
+func (s *Server) tenant(owner, repository string) (*Tenant, error) {
+	for _, t := range s.currentTenants() {
+		if t.Serves(owner, repository) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s/%s", ErrUnknownTenant, owner, repository)
+}
+
+// issueCommentEvent contains the fields of GitHub issue_comment event that the server cares about.
+type issueCommentEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		Body string `json:"body"`
+		// AuthorAssociation is the association of the commenter with the repository, such as OWNER, MEMBER or CONTRIBUTOR.
+		AuthorAssociation string `json:"author_association"`
+		User              struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	// Organization is the owner of the organization events without repository, such as the ping of an organization webhook.
+	Organization struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+// Handler returns the http handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(WebhookPath, s.handleWebhook)
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(GraphQLPath, s.handleGraphQL)
+	mux.HandleFunc(DeployPath, s.handleDeploy)
+	if s.queue != nil {
+		mux.HandleFunc(QueuePath, s.handleQueue)
+		mux.HandleFunc(QueuePath+"/", s.handleQueue)
+	}
+	return mux
+}
+
+// ListenAndServe serves the webhook on the address until the context is done,
+// the workers of the queue are started and stopped along with the server.
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	s.startWorkers(ctx)
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Infof("listen on %s", address)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		s.Wait()
+		return err
+	}
+}
+
+// Wait waits for all the commands in processing, and the workers of the queue after the context of the server is done.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "read payload", http.StatusBadRequest)
+		return
+	}
+
+	// the payload is decoded before it's verified to find the tenant and its secret, nothing is taken until it's verified.
+	event := &issueCommentEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		http.Error(w, "decode payload", http.StatusBadRequest)
+		return
+	}
+	owner := event.Repository.Owner.Login
+	if owner == "" {
+		owner = event.Organization.Login
+	}
+	// the failures before the payload is verified are replied the same, so they don't tell which repositories are served.
+	tenant, err := s.tenant(owner, event.Repository.Name)
+	if err != nil {
+		s.logger.WithError(err).Warn("find tenant")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err := verifySignature(r.Context(), tenant.Secret, payload, r.Header.Get(githubSignatureHeader)); err != nil {
+		s.logger.WithError(err).Warnf("verify signature of tenant %s", tenant.Name)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get(githubEventHeader) != "issue_comment" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// only handles new comments on pull requests, and ignores bots to avoid replying to itself.
+	if event.Action != "created" || event.Issue.PullRequest == nil || strings.EqualFold(event.Comment.User.Type, "Bot") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	commands := chatops.Parse(event.Comment.Body)
+	if len(commands) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.logger.Infof("receive %d commands from %s on %s/%s#%d", len(commands),
+		event.Comment.User.Login, event.Repository.Owner.Login, event.Repository.Name, event.Issue.Number)
+
+	if s.queue != nil {
+		s.enqueue(w, &queue.Item{
+			Tenant:            tenant.Name,
+			Owner:             event.Repository.Owner.Login,
+			Repository:        event.Repository.Name,
+			Number:            event.Issue.Number,
+			Sender:            event.Comment.User.Login,
+			SenderAssociation: event.Comment.AuthorAssociation,
+			Body:              event.Comment.Body,
+		})
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		sender := &commandSender{login: event.Comment.User.Login, association: event.Comment.AuthorAssociation}
+		s.process(context.Background(), tenant, event.Repository.Owner.Login, event.Repository.Name, event.Issue.Number, sender, commands)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verifySignature verifies the HMAC SHA256 signature of the payload, a payload is never accepted without the secret.
+func verifySignature(ctx context.Context, provider credential.Provider, payload []byte, signature string) error {
+	if provider == nil {
+		return ErrMissingSecret
+	}
+	secret, err := provider.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("get webhook secret: %w", err)
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// commandSender is the user who submitted the commands.
+type commandSender struct {
+	login string
+	// association is the author association of the comment, such as MEMBER.
+	association string
+}
+
+// process executes the commands and replies the results as comments, it returns the failure of getting the pull request,
+// or of the last reply. A failed command is replied rather than returned, and so is a command that the sender isn't allowed to run.
+func (s *Server) process(ctx context.Context, tenant *Tenant, owner, repository string, number int, sender *commandSender, commands []*chatops.Command) error {
+	logger := s.logger.WithField("pullRequest", fmt.Sprintf("%s/%s#%d", owner, repository, number)).WithField("tenant", tenant.Name)
+	defer func() {
+		if err := s.recorder.Flush(); err != nil {
+			logger.WithError(err).Error("flush audit log")
+		}
+	}()
+
+	pr, err := tenant.Client.GetPullRequest(ctx, owner, repository, number)
+	if err != nil {
+		logger.WithError(err).Error("get pull request")
+		return err
+	}
+
+	var replyErr error
+	var authorized *bool
+	for _, command := range commands {
+		var reply string
+		if !analysisCommands[command.Name] {
+			reply = s.execute(ctx, tenant, pr, command, logger)
+		} else {
+			// the permission is checked once for all commands of the submission.
+			if authorized == nil {
+				ok := s.authorized(ctx, tenant, owner, repository, sender, logger)
+				authorized = &ok
+			}
+			if *authorized {
+				reply = s.execute(ctx, tenant, pr, command, logger)
+			} else {
+				logger.Warnf("refuse '%s' of %s", command.Raw, sender.login)
+				reply = fmt.Sprintf("@%s is not allowed to run `%s`, it requires the write permission on the repository or being one of the commenters of the tenant.", sender.login, command.Raw)
+			}
+		}
+		if _, err := tenant.Client.PostComment(ctx, pr, reply); err != nil {
+			logger.WithError(err).Error("reply command")
+			replyErr = fmt.Errorf("reply '%s': %w", command.Raw, err)
+		}
+	}
+	return replyErr
+}
+
+// authorized reports whether the sender is allowed to run the analysis commands: the sender is one of the commenters of the tenant,
+// or has the write permission on the repository. When the client can't check the permission, the author association is used instead.
+func (s *Server) authorized(ctx context.Context, tenant *Tenant, owner, repository string, sender *commandSender, logger logrus.FieldLogger) bool {
+	if sender == nil || sender.login == "" {
+		return false
+	}
+	for _, commenter := range tenant.Commenters {
+		if strings.EqualFold(commenter, sender.login) {
+			return true
+		}
+	}
+
+	checker, ok := tenant.Client.(scm.PermissionChecker)
+	if !ok {
+		// the association comes from the payload, it's only trusted if the payload is signed by the secret of the tenant.
+		return tenant.Secret != nil && writeAssociations[strings.ToUpper(sender.association)]
+	}
+	permission, err := checker.GetPermission(ctx, owner, repository, sender.login)
+	if err != nil {
+		logger.WithError(err).Errorf("get permission of %s", sender.login)
+		return false
+	}
+	return permission == scm.AdminPermission || permission == scm.WritePermission
+}
+
+// formatComment formats the statistics, the verbosity can be overridden by the verbosity parameter of the command.
+func formatComment(tenant *Tenant, statistics *report.Statistics, pr *scm.PullRequest, command *chatops.Command) string {
+	verbosity := tenant.Verbosity
+	if v, ok := command.Params[verbosityParam]; ok {
+		parsed, err := report.ParseVerbosity(v)
+		if err != nil {
+			return fmt.Sprintf("%s.", err)
+		}
+		verbosity = parsed
+	}
+	return report.FormatComment(statistics, &report.CommentOption{
+		Verbosity:        verbosity,
+		HeadSHA:          pr.HeadSHA,
+		CoverageBaseline: tenant.CoverageBaseline,
+		Thresholds:       tenant.Thresholds,
+	})
+}
+
+// execute executes a single command and returns the reply.
+func (s *Server) execute(ctx context.Context, tenant *Tenant, pr *scm.PullRequest, command *chatops.Command, logger logrus.FieldLogger) string {
+	logger.Infof("execute '%s'", command.Raw)
+
+	switch command.Name {
+	case RerunCommand:
+		statistics, err := s.run(ctx, tenant, pr, gocover.DiffCoverage)
+		if err != nil {
+			return failureReply(command, err)
+		}
+		return formatComment(tenant, statistics, pr, command)
+
+	case ReportCommand:
+		mode := gocover.DiffCoverage
+		if len(command.Args) > 0 {
+			mode = gocover.CoverageMode(strings.ToLower(command.Args[0]))
+		}
+		if mode != gocover.DiffCoverage && mode != gocover.FullCoverage {
+			return fmt.Sprintf("Unknown report mode `%s`, use `diff` or `full`.", mode)
+		}
+		statistics, err := s.cachedOrRun(ctx, tenant, pr, mode)
+		if err != nil {
+			return failureReply(command, err)
+		}
+		return formatComment(tenant, statistics, pr, command)
+
+	case ExplainCommand:
+		file, line, err := parseFileLine(command.Args)
+		if err != nil {
+			return fmt.Sprintf("%s, usage: `%s %s {file}:{line}`.", err, chatops.Prefix, ExplainCommand)
+		}
+		statistics, err := s.cachedOrRun(ctx, tenant, pr, gocover.DiffCoverage)
+		if err != nil {
+			return failureReply(command, err)
+		}
+		return formatExplanation(statistics, file, line)
+
+	case SkipCommand:
+		return "Bypass request noted, it takes effect when the coverage gate is evaluated with `--pr-comments` or `--event-path`, and you're allowed to bypass it."
+
+	default:
+		return helpMessage
+	}
+}
+
+// cachedOrRun returns the cached diff result of the pull request head, or runs the analysis.
+func (s *Server) cachedOrRun(ctx context.Context, tenant *Tenant, pr *scm.PullRequest, mode gocover.CoverageMode) (*report.Statistics, error) {
+	if mode == gocover.DiffCoverage {
+		s.mu.Lock()
+		statistics, ok := s.results[resultKey(pr)]
+		s.mu.Unlock()
+		if ok {
+			return statistics, nil
+		}
+	}
+	return s.run(ctx, tenant, pr, mode)
+}
+
+func (s *Server) run(ctx context.Context, tenant *Tenant, pr *scm.PullRequest, mode gocover.CoverageMode) (*report.Statistics, error) {
+	statistics, err := tenant.Runner.Run(ctx, pr, mode)
+	if err != nil {
+		return nil, err
+	}
+	runID := s.saveRun(tenant, pr.Owner, pr.Repository, pr.Number, pr.HeadSHA, statistics)
+	s.notify(tenant, pr, runID, statistics)
+	if mode == gocover.DiffCoverage {
+		s.mu.Lock()
+		s.results[resultKey(pr)] = statistics
+		s.mu.Unlock()
+	}
+	return statistics, nil
+}
+
+// notify posts the finished run to the result webhooks of the tenant in the background,
+// so a slow receiver doesn't delay the reply.
+func (s *Server) notify(tenant *Tenant, pr *scm.PullRequest, runID string, statistics *report.Statistics) {
+	if tenant.Notifier == nil {
+		return
+	}
+	payload := notify.NewPayload(statistics, tenant.CoverageBaseline)
+	payload.RunID = runID
+	payload.Owner, payload.Repository, payload.Number, payload.HeadSHA = pr.Owner, pr.Repository, pr.Number, pr.HeadSHA
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		// the failures are logged and audited by the notifier.
+		_ = tenant.Notifier.Notify(context.Background(), payload)
+	}()
+}
+
+func resultKey(pr *scm.PullRequest) string {
+	return fmt.Sprintf("%s/%s#%d@%s", pr.Owner, pr.Repository, pr.Number, pr.HeadSHA)
+}
+
+func failureReply(command *chatops.Command, err error) string {
+	return fmt.Sprintf("Failed to execute `%s`: %s", command.Raw, redact.String(err.Error()))
+}
+
+// parseFileLine parses the argument in the format of {file}:{line}.
+func parseFileLine(args []string) (string, int, error) {
+	if len(args) == 0 {
+		return "", 0, errors.New("file and line are required")
+	}
+	idx := strings.LastIndex(args[0], ":")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("wrong format '%s'", args[0])
+	}
+	line, err := strconv.Atoi(args[0][idx+1:])
+	if err != nil || line <= 0 {
+		return "", 0, fmt.Errorf("wrong line number '%s'", args[0][idx+1:])
+	}
+	return args[0][:idx], line, nil
+}
@@ -0,0 +1,346 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/gocover"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"github.com/sirupsen/logrus"
+)
+
+type mockClient struct {
+	mu       sync.Mutex
+	comments []string
+}
+
+func (c *mockClient) GetPullRequest(ctx context.Context, owner, repository string, number int) (*scm.PullRequest, error) {
+	return &scm.PullRequest{Owner: owner, Repository: repository, Number: number, HeadSHA: "abcdef123456", BaseRef: "main"}, nil
+}
+
+func (c *mockClient) PostComment(ctx context.Context, pr *scm.PullRequest, body string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.comments = append(c.comments, body)
+	return "1", nil
+}
+
+type mockRunner struct {
+	runs int
+	err  error
+}
+
+func (r *mockRunner) Run(ctx context.Context, pr *scm.PullRequest, mode gocover.CoverageMode) (*report.Statistics, error) {
+	r.runs++
+	if r.err != nil {
+		return nil, r.err
+	}
+	statisticsType := report.DiffStatisticsType
+	if mode == gocover.FullCoverage {
+		statisticsType = report.FullStatisticsType
+	}
+	return &report.Statistics{
+		StatisticsType:       statisticsType,
+		ComparedBranch:       "origin/" + pr.BaseRef,
+		TotalCoveragePercent: 50,
+		CoverageProfile: []*report.CoverageProfile{
+			{
+				FileName:            "github.com/Azure/gocover/pkg/foo/foo.go",
+				TotalLines:          4,
+				TotalEffectiveLines: 4,
+				CoveredLines:        2,
+				ViolationSections: []*report.ViolationSection{
+					{StartLine: 10, EndLine: 20, ViolationLines: []int{12, 13}},
+				},
+			},
+		},
+	}, nil
+}
+
+const commentPayload = `{
+	"action": "created",
+	"issue": {"number": 12, "pull_request": {}},
+	"comment": {"body": "%s", "author_association": "MEMBER", "user": {"login": "octocat", "type": "User"}},
+	"repository": {"name": "gocover", "owner": {"login": "Azure"}}
+}`
+
+// testWebhookSecret is the webhook secret of the test servers, sendEvent signs the payloads with it.
+const testWebhookSecret = "webhooksecret"
+
+func newTestServer(runner Runner, secret credential.Provider) (*Server, *mockClient) {
+	if secret == nil {
+		secret = staticToken(testWebhookSecret)
+	}
+	client := &mockClient{}
+	return NewServer(&ServerOption{
+		Secret: secret,
+		Client: client,
+		Runner: runner,
+		Logger: logrus.New(),
+	}), client
+}
+
+// sign returns the signature of the payload by the secret.
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendEvent sends the event signed by the signature, or by testWebhookSecret if the signature is empty.
+func sendEvent(server *Server, event string, payload string, signature string) *httptest.ResponseRecorder {
+	if signature == "" {
+		signature = sign(testWebhookSecret, payload)
+	}
+	return postEvent(server, event, payload, signature)
+}
+
+// postEvent sends the event with the signature as it is, it's not signed if the signature is empty.
+func postEvent(server *Server, event string, payload string, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, WebhookPath, bytes.NewBufferString(payload))
+	req.Header.Set(githubEventHeader, event)
+	if signature != "" {
+		req.Header.Set(githubSignatureHeader, signature)
+	}
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	server.Wait()
+	return w
+}
+
+func payloadWithComment(body string) string {
+	return strings.Replace(commentPayload, "%s", body, 1)
+}
+
+func TestHandleWebhook(t *testing.T) {
+	t.Run("ignore other events", func(t *testing.T) {
+		server, client := newTestServer(&mockRunner{}, nil)
+		w := sendEvent(server, "push", "{}", "")
+		if w.Code != http.StatusNoContent || len(client.comments) != 0 {
+			t.Errorf("expect no content, but get %d", w.Code)
+		}
+	})
+
+	t.Run("ignore comments without commands", func(t *testing.T) {
+		server, client := newTestServer(&mockRunner{}, nil)
+		w := sendEvent(server, "issue_comment", payloadWithComment("LGTM"), "")
+		if w.Code != http.StatusNoContent || len(client.comments) != 0 {
+			t.Errorf("expect no content, but get %d", w.Code)
+		}
+	})
+
+	t.Run("rerun", func(t *testing.T) {
+		runner := &mockRunner{}
+		server, client := newTestServer(runner, nil)
+		w := sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expect accepted, but get %d", w.Code)
+		}
+		if runner.runs != 1 || len(client.comments) != 1 {
+			t.Fatalf("expect 1 run and 1 comment, but get %d runs and %d comments", runner.runs, len(client.comments))
+		}
+		if !strings.Contains(client.comments[0], "### Diff Coverage") || !strings.Contains(client.comments[0], "12,13") {
+			t.Errorf("unexpected comment %s", client.comments[0])
+		}
+	})
+
+	t.Run("verbosity parameter", func(t *testing.T) {
+		server, client := newTestServer(&mockRunner{}, nil)
+		sendEvent(server, "issue_comment", payloadWithComment("/gocover report verbosity=minimal\\n/gocover report diff verbosity=detailed\\n/gocover rerun verbosity=verbose"), "")
+		if len(client.comments) != 3 {
+			t.Fatalf("expect 3 comments, but get %d", len(client.comments))
+		}
+		if strings.Count(client.comments[0], "\n") != 1 || strings.Contains(client.comments[0], "###") {
+			t.Errorf("expect one-line status, but get %s", client.comments[0])
+		}
+		if !strings.Contains(client.comments[1], "<details>") {
+			t.Errorf("expect annotations, but get %s", client.comments[1])
+		}
+		if !strings.Contains(client.comments[2], "unknown verbosity") {
+			t.Errorf("expect unknown verbosity, but get %s", client.comments[2])
+		}
+	})
+
+	t.Run("explain uses cached result", func(t *testing.T) {
+		runner := &mockRunner{}
+		server, client := newTestServer(runner, nil)
+		sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+		sendEvent(server, "issue_comment", payloadWithComment("/gocover explain pkg/foo/foo.go:12\\n/gocover explain foo.go:11\\n/gocover explain bar.go:1"), "")
+		if runner.runs != 1 {
+			t.Errorf("explain should use cached result, but runs %d times", runner.runs)
+		}
+		if len(client.comments) != 4 {
+			t.Fatalf("expect 4 comments, but get %d", len(client.comments))
+		}
+		if !strings.Contains(client.comments[1], "**not covered**") {
+			t.Errorf("line 12 should be not covered, but get %s", client.comments[1])
+		}
+		if !strings.Contains(client.comments[2], "has no uncovered statement") {
+			t.Errorf("line 11 should have no uncovered statement, but get %s", client.comments[2])
+		}
+		if !strings.Contains(client.comments[3], "has no coverage information") {
+			t.Errorf("bar.go should have no coverage information, but get %s", client.comments[3])
+		}
+	})
+
+	t.Run("report full, wrong mode and help", func(t *testing.T) {
+		server, client := newTestServer(&mockRunner{}, nil)
+		sendEvent(server, "issue_comment", payloadWithComment("/gocover report full\\n/gocover report all\\n/gocover help"), "")
+		if len(client.comments) != 3 {
+			t.Fatalf("expect 3 comments, but get %d", len(client.comments))
+		}
+		if !strings.Contains(client.comments[0], "### Full Coverage") {
+			t.Errorf("expect full coverage, but get %s", client.comments[0])
+		}
+		if !strings.Contains(client.comments[1], "Unknown report mode") {
+			t.Errorf("expect unknown mode, but get %s", client.comments[1])
+		}
+		if client.comments[2] != helpMessage {
+			t.Errorf("expect help message, but get %s", client.comments[2])
+		}
+	})
+
+	t.Run("runner failed", func(t *testing.T) {
+		server, client := newTestServer(&mockRunner{err: errors.New("unit test failed")}, nil)
+		sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+		if len(client.comments) != 1 || !strings.Contains(client.comments[0], "unit test failed") {
+			t.Errorf("expect failure reply, but get %v", client.comments)
+		}
+	})
+}
+
+// permissionClient is a client that checks the permission of the commenters.
+type permissionClient struct {
+	mockClient
+	permissions map[string]string
+}
+
+func (c *permissionClient) GetPermission(ctx context.Context, owner, repository, login string) (string, error) {
+	if permission, ok := c.permissions[login]; ok {
+		return permission, nil
+	}
+	return scm.NonePermission, nil
+}
+
+func TestCommandPermission(t *testing.T) {
+	contributorPayload := func(body string) string {
+		return strings.Replace(payloadWithComment(body), `"MEMBER"`, `"CONTRIBUTOR"`, 1)
+	}
+
+	t.Run("refuse contributor", func(t *testing.T) {
+		runner := &mockRunner{}
+		server, client := newTestServer(runner, nil)
+		sendEvent(server, "issue_comment", contributorPayload(`/gocover rerun\n/gocover explain foo.go:12\n/gocover help`), "")
+		if runner.runs != 0 || len(client.comments) != 3 {
+			t.Fatalf("expect 0 runs and 3 comments, but get %d runs and %d comments", runner.runs, len(client.comments))
+		}
+		if !strings.Contains(client.comments[0], "@octocat is not allowed to run `/gocover rerun`") {
+			t.Errorf("expect refusal, but get %s", client.comments[0])
+		}
+		if !strings.Contains(client.comments[1], "not allowed") {
+			t.Errorf("expect refusal, but get %s", client.comments[1])
+		}
+		if client.comments[2] != helpMessage {
+			t.Errorf("expect help message, but get %s", client.comments[2])
+		}
+	})
+
+	t.Run("allow commenter", func(t *testing.T) {
+		runner := &mockRunner{}
+		client := &mockClient{}
+		server := NewServer(&ServerOption{Secret: staticToken(testWebhookSecret), Client: client, Runner: runner, Commenters: []string{"OctoCat"}, Logger: logrus.New()})
+		sendEvent(server, "issue_comment", contributorPayload("/gocover rerun"), "")
+		if runner.runs != 1 || len(client.comments) != 1 || strings.Contains(client.comments[0], "not allowed") {
+			t.Errorf("expect 1 run of the commenter, but get %d runs and %v", runner.runs, client.comments)
+		}
+	})
+
+	t.Run("check permission", func(t *testing.T) {
+		for permission, allowed := range map[string]bool{
+			scm.AdminPermission: true,
+			scm.WritePermission: true,
+			scm.ReadPermission:  false,
+		} {
+			runner := &mockRunner{}
+			client := &permissionClient{permissions: map[string]string{"octocat": permission}}
+			server := NewServer(&ServerOption{Secret: staticToken(testWebhookSecret), Client: client, Runner: runner, Logger: logrus.New()})
+			// the association is ignored when the permission can be checked.
+			sendEvent(server, "issue_comment", payloadWithComment("/gocover rerun"), "")
+			if (runner.runs == 1) != allowed || len(client.comments) != 1 {
+				t.Errorf("for %s permission, expect allowed %t, but get %d runs and %v", permission, allowed, runner.runs, client.comments)
+			}
+		}
+	})
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Setenv("GOCOVER_TEST_WEBHOOK_SECRET", "webhooksecret")
+	secret, _ := credential.NewProvider("env:GOCOVER_TEST_WEBHOOK_SECRET", nil)
+	payload := payloadWithComment("/gocover rerun")
+
+	signature := sign("webhooksecret", payload)
+
+	t.Run("valid signature", func(t *testing.T) {
+		server, _ := newTestServer(&mockRunner{}, secret)
+		if w := sendEvent(server, "issue_comment", payload, signature); w.Code != http.StatusAccepted {
+			t.Errorf("expect accepted, but get %d", w.Code)
+		}
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		server, _ := newTestServer(&mockRunner{}, secret)
+		if w := sendEvent(server, "issue_comment", payload, signaturePrefix+"00"); w.Code != http.StatusUnauthorized {
+			t.Errorf("expect unauthorized, but get %d", w.Code)
+		}
+		if w := postEvent(server, "issue_comment", payload, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("expect unauthorized, but get %d", w.Code)
+		}
+	})
+
+	t.Run("forged unsigned comment", func(t *testing.T) {
+		// the tenant has no secret, so the association of the payload can't be trusted.
+		runner := &mockRunner{}
+		client := &mockClient{}
+		server := NewServer(&ServerOption{Client: client, Runner: runner, Logger: logrus.New()})
+		forged := strings.Replace(payloadWithComment("/gocover rerun"), `"MEMBER"`, `"OWNER"`, 1)
+		if w := postEvent(server, "issue_comment", forged, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("expect unauthorized, but get %d", w.Code)
+		}
+		if runner.runs != 0 || len(client.comments) != 0 {
+			t.Errorf("expect the forged comment doesn't run, but get %d runs and %d comments", runner.runs, len(client.comments))
+		}
+	})
+}
+
+func TestParseFileLine(t *testing.T) {
+	testSuites := []struct {
+		args []string
+		file string
+		line int
+		err  bool
+	}{
+		{args: []string{"foo.go:42"}, file: "foo.go", line: 42},
+		{args: []string{"C:/foo.go:1"}, file: "C:/foo.go", line: 1},
+		{args: []string{}, err: true},
+		{args: []string{"foo.go"}, err: true},
+		{args: []string{"foo.go:x"}, err: true},
+		{args: []string{"foo.go:0"}, err: true},
+	}
+	for _, testCase := range testSuites {
+		file, line, err := parseFileLine(testCase.args)
+		if (err != nil) != testCase.err || file != testCase.file || line != testCase.line {
+			t.Errorf("for %v, expect (%s, %d, %t), but get (%s, %d, %v)", testCase.args, testCase.file, testCase.line, testCase.err, file, line, err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/gocover/pkg/credential"
+	"github.com/Azure/gocover/pkg/gate"
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/Azure/gocover/pkg/notify"
+	"github.com/Azure/gocover/pkg/report"
+	"github.com/Azure/gocover/pkg/scm"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownTenant  = errors.New("no tenant serves the repository")
+	ErrInvalidTenants = errors.New("invalid tenants config")
+)
+
+// storagePrefixPattern limits the storage prefix to a single safe path element.
+var storagePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Tenant is an organization or a group of repositories served by the server,
+// each tenant has its own credentials, storage and comment config.
+type Tenant struct {
+	// Name is the name of the tenant.
+	Name string
+	// Repositories are the patterns of the repositories that the tenant serves, in the format of
+	// {owner} or {owner}/{repository}, the repository can be a glob such as Azure/go*.
+	// The tenant serves all repositories if it's empty.
+	Repositories []string
+	// Secret is the webhook secret that verifies the payload signature, the webhook events are rejected if it's nil.
+	Secret credential.Provider
+	// Client is the SCM client.
+	Client scm.Client
+	// Runner runs the coverage analysis.
+	Runner Runner
+	// Store saves the finished runs and enables the GraphQL API over them, the runs are not saved if it's nil.
+	Store history.Store
+	// APIToken is the bearer token that authorizes the GraphQL requests of the tenant.
+	APIToken credential.Provider
+	// Verbosity is the default verbosity of the coverage comments, auto is used if it's empty.
+	Verbosity report.Verbosity
+	// Thresholds limits the detail of the coverage comments on large changes.
+	Thresholds report.Thresholds
+	// CoverageBaseline is shown in the coverage comments if it's greater than zero.
+	CoverageBaseline float64
+	// Notifier posts the finished runs to the result webhooks, the runs are not posted if it's nil.
+	Notifier *notify.Notifier
+	// DeployPolicy is the coverage that a commit needs to deploy, a commit only needs a result if it's nil.
+	DeployPolicy *gate.Policy
+	// Commenters are the users allowed to run the analysis commands besides the ones with the write permission on the repository.
+	Commenters []string
+}
+
+// Serves reports whether the tenant serves the repository, the owner and the repository are case insensitive.
+func (t *Tenant) Serves(owner, repository string) bool {
+	if len(t.Repositories) == 0 {
+		return true
+	}
+	full := strings.ToLower(owner + "/" + repository)
+	for _, pattern := range t.Repositories {
+		pattern = strings.ToLower(pattern)
+		if !strings.Contains(pattern, "/") {
+			pattern += "/*"
+		}
+		if ok, _ := path.Match(pattern, full); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TenantConfig is the config of a tenant in the tenants file, the credentials are credential specs.
+type TenantConfig struct {
+	Name         string   `yaml:"name" json:"name"`
+	Repositories []string `yaml:"repositories" json:"repositories"`
+	// WebhookSecret is the credential spec of the webhook secret.
+	WebhookSecret string `yaml:"webhookSecret" json:"webhookSecret"`
+	// GitHubToken is the credential spec of the GitHub token, the server token is used if it's empty.
+	GitHubToken string `yaml:"githubToken" json:"githubToken"`
+	// GitHubAPIURL is the GitHub api url, the server api url is used if it's empty.
+	GitHubAPIURL string `yaml:"githubAPIURL" json:"githubAPIURL"`
+	// APIToken is the credential spec of the bearer token of the GraphQL API.
+	APIToken string `yaml:"apiToken" json:"apiToken"`
+	// JobTokenSecret is the secret key of the GitHub token for the analysis Jobs, in the format of {name}:{key}.
+	JobTokenSecret string `yaml:"jobTokenSecret" json:"jobTokenSecret"`
+	// StoragePrefix isolates the stored runs and the work directory of the tenant, it's the name if it's empty.
+	StoragePrefix string `yaml:"storagePrefix" json:"storagePrefix"`
+
+	CommentVerbosity string   `yaml:"commentVerbosity" json:"commentVerbosity"`
+	CoverageBaseline *float64 `yaml:"coverageBaseline" json:"coverageBaseline"`
+	ModuleDir        string   `yaml:"moduleDir" json:"moduleDir"`
+	Excludes         []string `yaml:"excludes" json:"excludes"`
+	// Commenters are the users allowed to run the analysis commands, the server commenters are used if it's nil.
+	Commenters []string `yaml:"commenters" json:"commenters"`
+
+	// ResultWebhooks are the outbound webhooks that the finished runs are posted to.
+	ResultWebhooks []*ResultWebhookConfig `yaml:"resultWebhooks" json:"resultWebhooks"`
+	// DeployPolicy is the policy of the deploy gate, the server policy is used if it's nil.
+	DeployPolicy *gate.Policy `yaml:"deployPolicy" json:"deployPolicy"`
+}
+
+// ResultWebhookConfig is an outbound webhook of a tenant.
+type ResultWebhookConfig struct {
+	URL string `yaml:"url" json:"url"`
+	// Secret is the credential spec of the secret that signs the payloads, the payloads are not signed if it's empty.
+	Secret string `yaml:"secret" json:"secret"`
+}
+
+// TenantsConfig is the content of the tenants file.
+type TenantsConfig struct {
+	Tenants []*TenantConfig `yaml:"tenants" json:"tenants"`
+}
+
+// LoadTenantsConfig loads the tenants file in YAML or JSON.
+func LoadTenantsConfig(file string) (*TenantsConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read tenants config: %w", err)
+	}
+
+	config := &TenantsConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrInvalidTenants, file, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate validates the tenants and fills the default storage prefixes.
+func (c *TenantsConfig) Validate() error {
+	if len(c.Tenants) == 0 {
+		return fmt.Errorf("%w: no tenant", ErrInvalidTenants)
+	}
+
+	names := make(map[string]bool)
+	prefixes := make(map[string]string)
+	for i, t := range c.Tenants {
+		if t.Name == "" {
+			return fmt.Errorf("%w: tenant %d has no name", ErrInvalidTenants, i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("%w: duplicated tenant %s", ErrInvalidTenants, t.Name)
+		}
+		names[t.Name] = true
+
+		if len(t.Repositories) == 0 {
+			return fmt.Errorf("%w: tenant %s serves no repository", ErrInvalidTenants, t.Name)
+		}
+		for _, pattern := range t.Repositories {
+			if _, err := path.Match(pattern, ""); err != nil || strings.Count(pattern, "/") > 1 {
+				return fmt.Errorf("%w: tenant %s has bad repository pattern %q", ErrInvalidTenants, t.Name, pattern)
+			}
+		}
+
+		if t.StoragePrefix == "" {
+			t.StoragePrefix = t.Name
+		}
+		if !storagePrefixPattern.MatchString(t.StoragePrefix) {
+			return fmt.Errorf("%w: tenant %s has bad storage prefix %q", ErrInvalidTenants, t.Name, t.StoragePrefix)
+		}
+		if other, ok := prefixes[t.StoragePrefix]; ok {
+			return fmt.Errorf("%w: tenants %s and %s share the storage prefix %s", ErrInvalidTenants, other, t.Name, t.StoragePrefix)
+		}
+		prefixes[t.StoragePrefix] = t.Name
+
+		if t.CommentVerbosity != "" {
+			if _, err := report.ParseVerbosity(t.CommentVerbosity); err != nil {
+				return fmt.Errorf("%w: tenant %s: %s", ErrInvalidTenants, t.Name, err)
+			}
+		}
+		// the commands run the analysis and the association of the commenter is taken from the payload, so it must be signed.
+		if t.WebhookSecret == "" {
+			return fmt.Errorf("%w: tenant %s has no webhook secret", ErrInvalidTenants, t.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/gocover/pkg/history"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTenantServes(t *testing.T) {
+	tenant := &Tenant{Repositories: []string{"Azure", "microsoft/go*"}}
+	testSuites := []struct {
+		owner      string
+		repository string
+		expect     bool
+	}{
+		{owner: "azure", repository: "gocover", expect: true},
+		{owner: "microsoft", repository: "gocover", expect: true},
+		{owner: "microsoft", repository: "vscode", expect: false},
+		{owner: "octocat", repository: "gocover", expect: false},
+	}
+	for _, testCase := range testSuites {
+		if actual := tenant.Serves(testCase.owner, testCase.repository); actual != testCase.expect {
+			t.Errorf("for %s/%s, expect %t, but get %t", testCase.owner, testCase.repository, testCase.expect, actual)
+		}
+	}
+	if !(&Tenant{}).Serves("octocat", "gocover") {
+		t.Error("tenant without repositories should serve all repositories")
+	}
+}
+
+func TestLoadTenantsConfig(t *testing.T) {
+	testSuites := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name: "valid",
+			content: `tenants:
+- name: azure
+  repositories: [Azure]
+  webhookSecret: env:AZURE_WEBHOOK_SECRET
+  coverageBaseline: 80
+- name: microsoft
+  repositories: [microsoft/go*]
+  webhookSecret: env:MICROSOFT_WEBHOOK_SECRET
+  storagePrefix: ms
+  commentVerbosity: summary
+`,
+		},
+		{name: "json", content: `{"tenants": [{"name": "azure", "repositories": ["Azure"], "webhookSecret": "env:AZURE_WEBHOOK_SECRET"}]}`},
+		{name: "no tenant", content: `tenants: []`, err: "no tenant"},
+		{name: "no name", content: `tenants: [{repositories: [Azure]}]`, err: "has no name"},
+		{name: "duplicated", content: `tenants: [{name: a, repositories: [A], webhookSecret: env:A}, {name: a, repositories: [B]}]`, err: "duplicated tenant a"},
+		{name: "no repository", content: `tenants: [{name: a}]`, err: "serves no repository"},
+		{name: "bad pattern", content: `tenants: [{name: a, repositories: ["a/b/c"]}]`, err: "bad repository pattern"},
+		{name: "bad prefix", content: `tenants: [{name: a, repositories: [A], storagePrefix: ../b}]`, err: "bad storage prefix"},
+		{name: "shared prefix", content: `tenants: [{name: a, repositories: [A], webhookSecret: env:A}, {name: b, repositories: [B], storagePrefix: a}]`, err: "share the storage prefix a"},
+		{name: "bad verbosity", content: `tenants: [{name: a, repositories: [A], commentVerbosity: verbose}]`, err: "unknown verbosity"},
+		{name: "no webhook secret", content: `tenants: [{name: a, repositories: [A]}]`, err: "tenant a has no webhook secret"},
+		{name: "bad yaml", content: `tenants: [`, err: "invalid tenants config"},
+	}
+
+	for _, testCase := range testSuites {
+		t.Run(testCase.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "tenants.yaml")
+			if err := os.WriteFile(file, []byte(testCase.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadTenantsConfig(file)
+			if testCase.err != "" {
+				if err == nil || !errors.Is(err, ErrInvalidTenants) || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expect error %q, but get %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Tenants[0].StoragePrefix != config.Tenants[0].Name {
+				t.Errorf("storage prefix should default to the name, but get %s", config.Tenants[0].StoragePrefix)
+			}
+		})
+	}
+}
+
+func TestMultiTenantServer(t *testing.T) {
+	azureClient, otherClient := &mockClient{}, &mockClient{}
+	azureStore, otherStore := history.NewMemoryStore(), history.NewMemoryStore()
+	server := NewServer(&ServerOption{
+		Tenants: []*Tenant{
+			{Name: "azure", Repositories: []string{"Azure"}, Secret: staticToken("azure-secret"), Client: azureClient,
+				Runner: &mockRunner{}, Store: azureStore, APIToken: staticToken("azure-token")},
+			{Name: "other", Repositories: []string{"octocat/*"}, Secret: staticToken("other-secret"), Client: otherClient,
+				Runner: &mockRunner{}, Store: otherStore, APIToken: staticToken("other-token")},
+		},
+		Logger: logrus.New(),
+	})
+
+	payload := payloadWithComment("/gocover rerun")
+	if w := postEvent(server, "issue_comment", payload, sign("other-secret", payload)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized by the secret of another tenant, but get %d", w.Code)
+	}
+	if w := postEvent(server, "issue_comment", payload, sign("azure-secret", payload)); w.Code != http.StatusAccepted {
+		t.Fatalf("expect accepted, but get %d", w.Code)
+	}
+	if len(azureClient.comments) != 1 || len(otherClient.comments) != 0 {
+		t.Errorf("expect the comment by the tenant client, but get %d and %d", len(azureClient.comments), len(otherClient.comments))
+	}
+
+	unknown := strings.Replace(payload, `"login": "Azure"`, `"login": "contoso"`, 1)
+	if w := postEvent(server, "issue_comment", unknown, sign("azure-secret", unknown)); w.Code != http.StatusUnauthorized || strings.Contains(w.Body.String(), "contoso") {
+		t.Errorf("expect a generic unauthorized for unknown tenant, but get %d %s", w.Code, w.Body.String())
+	}
+
+	if runs, _ := azureStore.List(nil); len(runs) != 1 {
+		t.Errorf("expect 1 run in the tenant store, but get %d", len(runs))
+	}
+	if runs, _ := otherStore.List(nil); len(runs) != 0 {
+		t.Errorf("expect no run in the other store, but get %d", len(runs))
+	}
+
+	if w := queryGraphQL(server, `{ runs { number } }`, "azure-token"); !strings.Contains(w.Body.String(), `"runs":[{"number":12}]`) {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+	if w := queryGraphQL(server, `{ runs { number } }`, "other-token"); !strings.Contains(w.Body.String(), `"runs":[]`) {
+		t.Errorf("expect the runs of the tenant only, but get %s", w.Body.String())
+	}
+	if w := queryGraphQL(server, `{ runs { number } }`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized, but get %d", w.Code)
+	}
+}